@@ -11,10 +11,11 @@ var UserFilterFields = map[string]FilterField{
 		Operation: OpEqual,
 		Validate: func(value string) (interface{}, bool) {
 			validRoles := map[string]models.Role{
-				"admin":    models.RoleAdmin,
-				"staff":    models.RoleStaff,
-				"driver":   models.RoleDriver,
-				"customer": models.RoleCustomer,
+				"super_admin": models.RoleSuperAdmin,
+				"admin":       models.RoleAdmin,
+				"staff":       models.RoleStaff,
+				"driver":      models.RoleDriver,
+				"customer":    models.RoleCustomer,
 			}
 			if role, ok := validRoles[value]; ok {
 				return role, true
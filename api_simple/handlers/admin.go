@@ -1,14 +1,17 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
 	"ticket-management/api_simple/config"
+	"ticket-management/api_simple/middleware"
 	"ticket-management/api_simple/models"
 	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
 	"ticket-management/api_simple/utils"
 
 	"github.com/gin-gonic/gin"
@@ -17,7 +20,7 @@ import (
 
 // GetUsers returns list of users with optional filters
 func GetUsers(c *gin.Context) {
-	userRepo := repository.NewUserRepository(config.DB)
+	userRepo := operatorUserRepository(c)
 
 	// Get query parameters
 	phone := c.Query("phone")
@@ -47,10 +50,10 @@ func GetUsers(c *gin.Context) {
 // GetStatistics returns system statistics
 func GetStatistics(c *gin.Context) {
 	// Get repositories
-	userRepo := repository.NewUserRepository(config.DB)
-	routeRepo := repository.NewRouteRepository(config.DB)
-	tripRepo := repository.NewTripRepository(config.DB)
-	bookingRepo := repository.NewBookingRepository(config.DB)
+	userRepo := operatorUserRepository(c)
+	routeRepo := repository.NewRouteRepository(operatorDB(c))
+	tripRepo := repository.NewTripRepository(operatorDB(c))
+	bookingRepo := repository.NewBookingRepository(operatorDB(c))
 
 	// Get total users
 	users, err := userRepo.FindAll(nil)
@@ -80,18 +83,17 @@ func GetStatistics(c *gin.Context) {
 	}
 
 	// Get total bookings
-	bookings, total, err := bookingRepo.FindAll(nil, 1, 1000) // Get all bookings for revenue calculation
+	_, total, err := bookingRepo.FindAll(nil, 1, 1)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
 		return
 	}
 
-	// Get total revenue
-	var totalRevenue float64
-	for _, booking := range bookings {
-		if booking.Status == models.BookingStatusConfirmed && booking.PaymentStatus == models.PaymentStatusPaid {
-			totalRevenue += booking.TotalAmount
-		}
+	// Get total revenue from the ledger
+	totalRevenue, err := ledgerRevenue(operatorDB(c), nil, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -109,71 +111,56 @@ func GetStatistics(c *gin.Context) {
 // GetDashboardStats returns dashboard statistics
 func GetDashboardStats(c *gin.Context) {
 	// Get repositories
-	userRepo := repository.NewUserRepository(config.DB)
-	tripRepo := repository.NewTripRepository(config.DB)
-	bookingRepo := repository.NewBookingRepository(config.DB)
+	userRepo := operatorUserRepository(c)
+	tripRepo := repository.NewTripRepository(operatorDB(c))
+	reportRepo := repository.NewReportRepository(operatorDB(c))
 
 	// Get total users
-	users, err := userRepo.FindAll(nil)
+	totalUsers, err := userRepo.Count(nil)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
 		return
 	}
 
 	// Get total trips
-	trips, err := tripRepo.FindAll(nil)
+	totalTrips, err := tripRepo.Count()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
 		return
 	}
 
-	// Get total bookings
-	bookings, totalBookings, err := bookingRepo.FindAll(nil, 1, 1000) // Get all bookings for revenue calculation
+	// Count all bookings and today's, aggregated in the database
+	now := time.Now()
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	allBookings, err := reportRepo.BookingStats(repository.ReportFilter{})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
 		return
 	}
-
-	// Calculate today's revenue
-	today := time.Now().Format("2006-01-02")
-	var todayRevenue float64
-	var todayBookings int
-	var totalRevenue float64
-
-	for _, booking := range bookings {
-		// Debug: Log each booking
-		fmt.Printf("DEBUG: Booking ID %d - Status: %s, PaymentStatus: %s, Amount: %.2f, Date: %s\n",
-			booking.ID, booking.Status, booking.PaymentStatus, booking.TotalAmount, booking.CreatedAt.Format("2006-01-02"))
-
-		// Calculate total revenue
-		if booking.Status == models.BookingStatusConfirmed && booking.PaymentStatus == models.PaymentStatusPaid {
-			totalRevenue += booking.TotalAmount
-			fmt.Printf("DEBUG: Added to total revenue: %.2f\n", booking.TotalAmount)
-		}
-
-		// Calculate today's revenue
-		bookingDate := booking.CreatedAt.Format("2006-01-02")
-		if bookingDate == today && booking.Status == models.BookingStatusConfirmed && booking.PaymentStatus == models.PaymentStatusPaid {
-			todayRevenue += booking.TotalAmount
-			todayBookings++
-			fmt.Printf("DEBUG: Added to today revenue: %.2f\n", booking.TotalAmount)
-		}
+	todayBookings, err := reportRepo.BookingStats(repository.ReportFilter{From: &startOfDay, To: &now})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
 	}
 
-	// Count cancelled bookings
-	var cancelledBookings int
-	for _, booking := range bookings {
-		if booking.Status == models.BookingStatusCancelled {
-			cancelledBookings++
-		}
+	// Revenue comes from the ledger: sales less cancellations, plus cancellation fees
+	totalRevenue, err := ledgerRevenue(operatorDB(c), nil, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+	todayRevenue, err := ledgerRevenue(operatorDB(c), &startOfDay, &now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"total_users":        len(users),
-		"total_trips":        len(trips),
-		"cancelled_bookings": cancelledBookings,
-		"total_bookings":     totalBookings,
-		"today_bookings":     todayBookings,
+		"total_users":        totalUsers,
+		"total_trips":        totalTrips,
+		"cancelled_bookings": allBookings.Cancelled,
+		"total_bookings":     allBookings.Total,
+		"today_bookings":     todayBookings.Confirmed,
 		"today_revenue":      todayRevenue,
 		"total_revenue":      totalRevenue,
 	})
@@ -181,7 +168,7 @@ func GetDashboardStats(c *gin.Context) {
 
 // GetRecentActivity returns recent system activity
 func GetRecentActivity(c *gin.Context) {
-	bookingRepo := repository.NewBookingRepository(config.DB)
+	bookingRepo := repository.NewBookingRepository(operatorDB(c))
 
 	// Get recent bookings (last 10)
 	bookings, _, err := bookingRepo.FindAll(nil, 1, 10)
@@ -212,7 +199,7 @@ func GetRecentActivity(c *gin.Context) {
 
 // GetAdminTrips returns trips with admin filters
 func GetAdminTrips(c *gin.Context) {
-	tripRepo := repository.NewTripRepository(config.DB)
+	tripRepo := repository.NewTripRepository(operatorDB(c))
 
 	// Get query parameters
 	status := c.Query("status")
@@ -246,31 +233,22 @@ func GetAdminTrips(c *gin.Context) {
 
 // GetAdminBookings returns bookings with admin filters
 func GetAdminBookings(c *gin.Context) {
-	bookingRepo := repository.NewBookingRepository(config.DB)
+	bookingRepo := repository.NewBookingRepository(operatorDB(c))
 
 	// Get query parameters
-	status := c.Query("status")
 	pageStr := c.DefaultQuery("page", "1")
 	limitStr := c.DefaultQuery("limit", "10")
-	dateFrom := c.Query("dateFrom")
-	dateTo := c.Query("dateTo")
 
 	page, _ := strconv.Atoi(pageStr)
 	limit, _ := strconv.Atoi(limitStr)
 
 	// Build filter
-	filter := make(map[string]interface{})
-	if status != "" {
-		filter["status"] = status
-	}
-	if dateFrom != "" {
-		filter["date_from"] = dateFrom
-	}
-	if dateTo != "" {
-		filter["date_to"] = dateTo
+	filter, ok := parseBookingFilter(c)
+	if !ok {
+		return
 	}
 
-	bookings, total, err := bookingRepo.FindAll(filter, page, limit)
+	bookings, total, err := bookingRepo.List(filter, page, limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
 		return
@@ -284,12 +262,56 @@ func GetAdminBookings(c *gin.Context) {
 	})
 }
 
+// ExportBookings streams the bookings matching the same filters as GetAdminBookings as
+// a CSV (default) or XLSX file
+func ExportBookings(c *gin.Context) {
+	filter, ok := parseBookingFilter(c)
+	if !ok {
+		return
+	}
+	format, ok := reportFormat(c)
+	if !ok {
+		return
+	}
+	if format == "" {
+		format = reportFormatCSV
+	}
+
+	filename := fmt.Sprintf("bookings-%s.%s", time.Now().Format("20060102-150405"), format)
+	c.Header("Content-Type", services.ReportContentType(models.ReportFormat(format)))
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+
+	err := newReportService(operatorDB(c)).ExportBookings(c.Writer, models.ReportFormat(format), filter)
+	if err != nil {
+		// Headers are already sent, so the failure can only be logged
+		c.Error(err)
+	}
+}
+
+// parseBookingFilter reads the status and booking date range of the admin bookings list
+func parseBookingFilter(c *gin.Context) (repository.BookingFilter, bool) {
+	filter := repository.BookingFilter{Status: models.BookingStatus(c.Query("status"))}
+
+	var err error
+	if filter.From, err = parseTimeBound(c.Query("dateFrom"), false); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "dateFrom không hợp lệ"})
+		return filter, false
+	}
+	if filter.To, err = parseTimeBound(c.Query("dateTo"), true); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "dateTo không hợp lệ"})
+		return filter, false
+	}
+	return filter, true
+}
+
 // UpdateBookingStatus updates booking status
 func UpdateBookingStatus(c *gin.Context) {
 	id := c.Param("id")
 
+	db := operatorDB(c)
+
 	var booking models.Booking
-	if err := config.DB.First(&booking, id).Error; err != nil {
+	if err := db.First(&booking, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
 		return
 	}
@@ -320,25 +342,45 @@ func UpdateBookingStatus(c *gin.Context) {
 		return
 	}
 
+	// Cancelling refunds the customer, releases the seats and posts to the ledger, which
+	// only AdminCancelBooking does; a cancelled booking stays cancelled
+	if req.Status == models.BookingStatusCancelled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng dùng chức năng hủy đơn để hủy vé"})
+		return
+	}
+	if booking.Status == models.BookingStatusCancelled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Đơn đã được hủy"})
+		return
+	}
+
+	before := booking
 	booking.Status = req.Status
-	if err := config.DB.Save(&booking).Error; err != nil {
+	if err := db.Save(&booking).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update booking status"})
 		return
 	}
 
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "booking.update_status",
+		EntityType: "bookings",
+		EntityID:   booking.ID,
+		Before:     before,
+		After:      booking,
+	})
+
 	c.JSON(http.StatusOK, booking)
 }
 
 // ==================== HELPER FUNCTIONS ====================
 
-func calculateTotalRevenue(bookings []models.Booking) float64 {
-	var total float64
-	for _, booking := range bookings {
-		if booking.Status == models.BookingStatusConfirmed && booking.PaymentStatus == models.PaymentStatusPaid {
-			total += booking.TotalAmount
-		}
+// operatorUserRepository returns the users an admin manages: the staff accounts of
+// its operator, or every account for a super admin working platform-wide
+func operatorUserRepository(c *gin.Context) *repository.UserRepository {
+	userRepo := repository.NewUserRepository(config.DB)
+	if operatorID, ok := middleware.OperatorID(c); ok {
+		return userRepo.ForOperator(operatorID)
 	}
-	return total
+	return userRepo
 }
 
 func generateActivityMessage(booking models.Booking) string {
@@ -368,7 +410,7 @@ func generateActivityMessage(booking models.Booking) string {
 
 // UpdateUserRole updates user role
 func UpdateUserRole(c *gin.Context) {
-	userRepo := repository.NewUserRepository(config.DB)
+	userRepo := operatorUserRepository(c)
 
 	// Get user ID from URL parameter
 	userIDStr := c.Param("id")
@@ -387,6 +429,13 @@ func UpdateUserRole(c *gin.Context) {
 		return
 	}
 
+	user, err := userRepo.FindByID(uint(userID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy người dùng"})
+		return
+	}
+	before := *user
+
 	// Update user role
 	err = userRepo.UpdateRole(uint(userID), models.Role(req.Role))
 	if err != nil {
@@ -394,6 +443,15 @@ func UpdateUserRole(c *gin.Context) {
 		return
 	}
 
+	user.Role = models.Role(req.Role)
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "user.update_role",
+		EntityType: "users",
+		EntityID:   user.ID,
+		Before:     before,
+		After:      user,
+	})
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Cập nhật vai trò thành công",
 	})
@@ -401,7 +459,7 @@ func UpdateUserRole(c *gin.Context) {
 
 // UpdateUser updates user information (admin only)
 func UpdateUser(c *gin.Context) {
-	userRepo := repository.NewUserRepository(config.DB)
+	userRepo := operatorUserRepository(c)
 
 	// Get user ID from URL parameter
 	userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
@@ -428,6 +486,8 @@ func UpdateUser(c *gin.Context) {
 		return
 	}
 
+	before := *user
+
 	// Update user fields
 	if req.Name != "" {
 		user.Name = req.Name
@@ -436,6 +496,11 @@ func UpdateUser(c *gin.Context) {
 		user.Phone = req.Phone
 	}
 	if req.Role != "" {
+		// Only the platform can grant platform-wide access
+		if models.Role(req.Role) == models.RoleSuperAdmin && !isSuperAdmin(c) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Chỉ quản trị hệ thống mới có quyền cấp vai trò này"})
+			return
+		}
 		user.Role = models.Role(req.Role)
 	}
 
@@ -446,6 +511,14 @@ func UpdateUser(c *gin.Context) {
 		return
 	}
 
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "user.update",
+		EntityType: "users",
+		EntityID:   user.ID,
+		Before:     before,
+		After:      user,
+	})
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Cập nhật thông tin thành công",
 		"user":    user,
@@ -469,7 +542,7 @@ func CreateGuestBooking(c *gin.Context) {
 		return
 	}
 
-	db := config.DB
+	db := operatorDB(c)
 
 	// Check if trip exists
 	var trip models.Trip
@@ -486,26 +559,52 @@ func CreateGuestBooking(c *gin.Context) {
 	}
 
 	// Calculate total price
-	totalPrice := trip.Price * float64(len(req.SeatIDs))
+	totalPrice := trip.Price * models.Money(len(req.SeatIDs))
 
 	// Create booking with embedded guest info
 	booking := models.Booking{
+		OperatorID:    trip.OperatorID,
 		TripID:        req.TripID,
 		GuestInfo:     &guestInfo,
 		SeatIDs:       req.SeatIDs,
 		TotalAmount:   totalPrice,
 		Status:        models.BookingStatusConfirmed,
+		PaymentType:   models.PaymentTypeCash,
 		PaymentStatus: models.PaymentStatusPaid,
+		Channel:       models.BookingChannelCounter,
 	}
 
-	if err := db.Create(&booking).Error; err != nil {
+	seller := c.MustGet("user").(*models.User)
+	var bookingCode string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&booking).Error; err != nil {
+			return err
+		}
+
+		// Generate booking code
+		bookingCode = fmt.Sprintf("BK%06d", booking.ID)
+		if err := tx.Model(&booking).Update("booking_code", bookingCode).Error; err != nil {
+			return err
+		}
+
+		// Cash taken at the counter goes into the seller's drawer
+		if _, err := newCashShiftService(tx).Record(seller.ID, models.CashTransactionSale, booking.TotalAmount, &booking.ID, bookingCode); err != nil {
+			return err
+		}
+
+		booking.BookingCode = bookingCode
+		return newLedgerService(tx).RecordSale(&booking, time.Now())
+	})
+	if errors.Is(err, services.ErrNoOpenShift) {
+		respondCashShiftError(c, err)
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create booking"})
 		return
 	}
 
-	// Generate booking code
-	bookingCode := fmt.Sprintf("BK%06d", booking.ID)
-	db.Model(&booking).Update("booking_code", bookingCode)
+	tripStatsChanged(booking.TripID)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Booking created successfully",
@@ -523,10 +622,11 @@ func CreateGuestBooking(c *gin.Context) {
 // CreateUser creates a new user (admin only)
 func CreateUser(c *gin.Context) {
 	var req struct {
-		Name     string `json:"name" binding:"required"`
-		Phone    string `json:"phone" binding:"required"`
-		Password string `json:"password" binding:"required"`
-		Role     string `json:"role" binding:"required"`
+		Name       string `json:"name" binding:"required"`
+		Phone      string `json:"phone" binding:"required"`
+		Password   string `json:"password" binding:"required"`
+		Role       string `json:"role" binding:"required"`
+		OperatorID *uint  `json:"operator_id"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -560,6 +660,7 @@ func CreateUser(c *gin.Context) {
 		return
 	}
 
+	// Phone numbers are unique across the whole platform
 	userRepo := repository.NewUserRepository(config.DB)
 
 	// Check if phone already exists
@@ -577,12 +678,36 @@ func CreateUser(c *gin.Context) {
 		Role:     models.Role(req.Role),
 	}
 
+	// Staff accounts belong to the admin's operator; a super admin picks one
+	if user.IsOperatorAccount() {
+		if operatorID, ok := middleware.OperatorID(c); ok {
+			user.OperatorID = &operatorID
+		} else if req.OperatorID != nil {
+			exists, err := repository.NewOperatorRepository(config.DB).Exists(map[string]interface{}{"id": *req.OperatorID})
+			if err != nil || !exists {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Không tìm thấy nhà xe"})
+				return
+			}
+			user.OperatorID = req.OperatorID
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng chọn nhà xe cho tài khoản nhân viên"})
+			return
+		}
+	}
+
 	err = userRepo.Create(user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Không thể tạo người dùng"})
 		return
 	}
 
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "user.create",
+		EntityType: "users",
+		EntityID:   user.ID,
+		After:      user,
+	})
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Tạo người dùng thành công",
 		"user":    user,
@@ -597,7 +722,7 @@ func DeleteUser(c *gin.Context) {
 		return
 	}
 
-	userRepo := repository.NewUserRepository(config.DB)
+	userRepo := operatorUserRepository(c)
 
 	// Ensure user exists
 	user, err := userRepo.FindByID(uint(id))
@@ -620,6 +745,13 @@ func DeleteUser(c *gin.Context) {
 		return
 	}
 
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "user.delete",
+		EntityType: "users",
+		EntityID:   user.ID,
+		Before:     user,
+	})
+
 	c.JSON(http.StatusOK, gin.H{"message": "Xóa người dùng thành công"})
 }
 
@@ -631,12 +763,8 @@ func AdminCancelBooking(c *gin.Context) {
 		return
 	}
 
-	// Get repositories
-	bookingRepo := repository.NewBookingRepository(config.DB)
-	seatRepo := repository.NewSeatRepository(config.DB)
-
 	// Get booking
-	booking, err := bookingRepo.FindByID(uint(id))
+	booking, err := repository.NewBookingRepository(operatorDB(c)).FindByID(uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy đơn đặt vé"})
 		return
@@ -648,21 +776,37 @@ func AdminCancelBooking(c *gin.Context) {
 		return
 	}
 
+	before := *booking
+
 	// Cancel booking in transaction
 	err = config.DB.Transaction(func(tx *gorm.DB) error {
 		// Update booking status
-		if err := bookingRepo.UpdateStatus(uint(id), models.BookingStatusCancelled); err != nil {
+		if err := repository.NewBookingRepository(tx).UpdateStatus(uint(id), models.BookingStatusCancelled); err != nil {
 			return err
 		}
 
 		// Release seats
+		seatRepo := repository.NewSeatRepository(tx)
 		for _, seatID := range booking.SeatIDs {
 			if err := seatRepo.UpdateStatus(uint(seatID), models.SeatStatusAvailable); err != nil {
 				return err
 			}
 		}
 
-		return nil
+		// Refund a paid booking according to the operator's policy, post the cancellation,
+		// adjust its invoice and pay it out of the drawer when the canceller is working a
+		// counter shift
+		refund, err := newOperatorService(tx).Refund(booking, booking.Trip.DepartureTime, time.Now())
+		if err != nil {
+			return err
+		}
+		if err := newLedgerService(tx).RecordCancellation(booking, time.Now()); err != nil {
+			return err
+		}
+		if err := adjustInvoiceForRefund(tx, booking, refund); err != nil {
+			return err
+		}
+		return refundFromDrawer(c, tx, booking, refund)
 	})
 
 	if err != nil {
@@ -670,5 +814,227 @@ func AdminCancelBooking(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Hủy đơn thành công"})
+	notifySeats(booking.TripID, services.SeatEventReleased, models.SeatStatusAvailable, booking.SeatIDs, nil)
+
+	after := *booking
+	after.Status = models.BookingStatusCancelled
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "booking.cancel",
+		EntityType: "bookings",
+		EntityID:   booking.ID,
+		Before:     before,
+		After:      after,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Hủy đơn thành công",
+		"refund_amount": booking.RefundAmount,
+	})
+}
+
+// ==================== ADMIN LOGIN LOCKOUT APIs ====================
+
+// GetLockouts returns accounts and IP addresses that are locked or backing off
+func GetLockouts(c *gin.Context) {
+	throttleRepo := repository.NewLoginThrottleRepository(config.DB)
+
+	scope := models.ThrottleScope(c.Query("scope"))
+	if scope != "" && scope != models.ThrottleScopeAccount && scope != models.ThrottleScopeIP {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Phạm vi không hợp lệ"})
+		return
+	}
+
+	lockouts, err := throttleRepo.FindActive(scope, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"lockouts": lockouts,
+		"total":    len(lockouts),
+	})
+}
+
+// ClearLockout removes a login lockout
+func ClearLockout(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID không hợp lệ"})
+		return
+	}
+
+	throttleRepo := repository.NewLoginThrottleRepository(config.DB)
+
+	if _, err := throttleRepo.FindByID(uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy khóa đăng nhập"})
+		return
+	}
+
+	if err := throttleRepo.Delete(uint(id)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Mở khóa đăng nhập thành công"})
+}
+
+// ==================== ADMIN PARTNER API CLIENT APIs ====================
+
+type APIClientRequest struct {
+	Name               string   `json:"name" binding:"required"`
+	ContactEmail       string   `json:"contact_email"`
+	ContactPhone       string   `json:"contact_phone"`
+	Scopes             []string `json:"scopes" binding:"required,min=1"`
+	RateLimitPerMinute int      `json:"rate_limit_per_minute" binding:"required"`
+	CommissionRate     float64  `json:"commission_rate"`
+}
+
+// GetAPIClients lists partner agencies
+func GetAPIClients(c *gin.Context) {
+	clientRepo := repository.NewAPIClientRepository(config.DB)
+
+	clients, err := clientRepo.FindAll()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"clients": clients,
+		"total":   len(clients),
+	})
+}
+
+// CreateAPIClient registers a partner agency and issues its API key
+func CreateAPIClient(c *gin.Context) {
+	var req APIClientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng điền đầy đủ thông tin"})
+		return
+	}
+
+	client := &models.APIClient{
+		Name:               req.Name,
+		ContactEmail:       req.ContactEmail,
+		ContactPhone:       req.ContactPhone,
+		Scopes:             req.Scopes,
+		RateLimitPerMinute: req.RateLimitPerMinute,
+		CommissionRate:     req.CommissionRate,
+	}
+	if err := client.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	service := services.NewAPIClientService(repository.NewAPIClientRepository(config.DB))
+	key, err := service.Issue(client)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Tạo đại lý thành công, API key chỉ hiển thị một lần",
+		"client":  client,
+		"api_key": key,
+	})
+}
+
+// UpdateAPIClient updates the scopes, rate limit and commission of a partner agency
+func UpdateAPIClient(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID không hợp lệ"})
+		return
+	}
+
+	var req APIClientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng điền đầy đủ thông tin"})
+		return
+	}
+
+	clientRepo := repository.NewAPIClientRepository(config.DB)
+	client, err := clientRepo.FindByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy đại lý"})
+		return
+	}
+
+	client.Name = req.Name
+	client.ContactEmail = req.ContactEmail
+	client.ContactPhone = req.ContactPhone
+	client.Scopes = req.Scopes
+	client.RateLimitPerMinute = req.RateLimitPerMinute
+	client.CommissionRate = req.CommissionRate
+	if err := client.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := clientRepo.Update(client); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Cập nhật đại lý thành công",
+		"client":  client,
+	})
+}
+
+// RotateAPIClientKey issues a new API key and invalidates the old one
+func RotateAPIClientKey(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID không hợp lệ"})
+		return
+	}
+
+	clientRepo := repository.NewAPIClientRepository(config.DB)
+	client, err := clientRepo.FindByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy đại lý"})
+		return
+	}
+
+	key, err := services.NewAPIClientService(clientRepo).Rotate(client)
+	if err != nil {
+		if errors.Is(err, services.ErrAPIClientRevoked) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "API key đã bị thu hồi"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Cấp lại API key thành công, API key chỉ hiển thị một lần",
+		"client":  client,
+		"api_key": key,
+	})
+}
+
+// RevokeAPIClient permanently disables a partner agency's API key
+func RevokeAPIClient(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID không hợp lệ"})
+		return
+	}
+
+	clientRepo := repository.NewAPIClientRepository(config.DB)
+	client, err := clientRepo.FindByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy đại lý"})
+		return
+	}
+
+	if err := services.NewAPIClientService(clientRepo).Revoke(client); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Thu hồi API key thành công"})
 }
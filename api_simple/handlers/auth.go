@@ -1,18 +1,23 @@
 package handlers
 
 import (
+	"errors"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"ticket-management/api_simple/config"
 	"ticket-management/api_simple/middleware"
 	"ticket-management/api_simple/models"
-	"ticket-management/api_simple/providers"
 	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
 	"ticket-management/api_simple/utils"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 type RegisterRequest struct {
@@ -36,12 +41,22 @@ type VerifyOTPRequest struct {
 	OTP   string `json:"otp" binding:"required,len=6"`
 }
 
+type RequestOTPRequest struct {
+	Phone   string            `json:"phone" binding:"required,min=10,max=11"`
+	Purpose models.OTPPurpose `json:"purpose" binding:"required"`
+}
+
 type ResetPasswordRequest struct {
 	Phone       string `json:"phone" binding:"required,min=10,max=11"`
 	OTP         string `json:"otp" binding:"required,len=6"`
 	NewPassword string `json:"new_password" binding:"required,min=6"`
 }
 
+type UnlockAccountRequest struct {
+	Phone string `json:"phone" binding:"required,min=10,max=11"`
+	OTP   string `json:"otp" binding:"required,len=6"`
+}
+
 type ChangePasswordRequest struct {
 	OldPassword string `json:"old_password" binding:"required"`
 	NewPassword string `json:"new_password" binding:"required,min=6,nefield=OldPassword"`
@@ -110,10 +125,9 @@ func Register(c *gin.Context) {
 		return
 	}
 
-	// Send OTP via Twilio
-	twilioProvider := providers.NewTwilioProvider()
-	if err := twilioProvider.SendOTP(req.Phone); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Không thể gửi OTP"})
+	// Send registration OTP
+	if err := newOTPService().Send(req.Phone, models.OTPPurposeRegister); err != nil {
+		respondOTPError(c, err)
 		return
 	}
 
@@ -161,14 +175,8 @@ func VerifyOTP(c *gin.Context) {
 	}
 
 	// Verify OTP
-	twilioProvider := providers.NewTwilioProvider()
-	ok, err := twilioProvider.VerifyOTP(req.Phone, req.Code)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Xác minh OTP thất bại"})
-		return
-	}
-	if !ok {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Mã OTP không chính xác"})
+	if err := newOTPService().Verify(req.Phone, models.OTPPurposeRegister, req.Code); err != nil {
+		respondOTPError(c, err)
 		return
 	}
 
@@ -198,31 +206,45 @@ func VerifyOTP(c *gin.Context) {
 	})
 }
 
+// RequestOtp sends a new OTP for the given purpose
 func RequestOtp(c *gin.Context) {
-    phone := c.PostForm("phone")
-
-    // --- Cooldown check (disabled for testing) ---
-    /*
-    lastSent, found := redisClient.Get("otp_last:" + phone).Result()
-    if found && time.Since(lastSent) < 60*time.Second {
-        c.JSON(http.StatusTooManyRequests, gin.H{"error": "Vui lòng thử lại sau 60 giây"})
-        return
-    }
-    */
-
-   	twilioProvider := providers.NewTwilioProvider()
-    err := twilioProvider.SendOTP(phone)
-    if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Không thể gửi OTP"})
-        return
-    }
-
-    // save cooldown marker (disabled for testing)
-    /*
-    redisClient.Set("otp_last:"+phone, time.Now(), 10*time.Minute)
-    */
-
-    c.JSON(http.StatusOK, gin.H{"message": "OTP đã gửi"})
+	var req RequestOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng điền đầy đủ thông tin"})
+		return
+	}
+
+	if !utils.ValidatePhone(req.Phone) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": utils.ErrPhoneInvalid})
+		return
+	}
+
+	if !req.Purpose.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Mục đích OTP không hợp lệ"})
+		return
+	}
+
+	// Every purpose except those for guests requires a registered account. Unknown
+	// phones get the same answer as known ones so the endpoint cannot be used to
+	// find out which numbers have an account.
+	if req.Purpose.RequiresAccount() {
+		userRepo := repository.NewUserRepository(config.DB)
+		if _, err := userRepo.FindByPhone(req.Phone); err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"message": "OTP đã gửi"})
+			return
+		}
+	}
+
+	if err := newOTPService().Send(req.Phone, req.Purpose); err != nil {
+		respondOTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "OTP đã gửi"})
 }
 
 func Login(c *gin.Context) {
@@ -238,25 +260,52 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	// Refuse attempts while the account or IP is backing off or locked
+	guard := newLoginGuard()
+	ip := c.ClientIP()
+	if wait, err := guard.Check(req.Phone, ip); err != nil {
+		respondLoginGuardError(c, wait, err)
+		return
+	}
+
 	userRepo := repository.NewUserRepository(config.DB)
 
 	user, err := userRepo.FindByPhone(req.Phone)
 	if err != nil {
+		recordLoginFailure(guard, req.Phone, ip)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": utils.ErrInvalidCredentials})
 		return
 	}
 
 	if err := user.ComparePassword(req.Password); err != nil {
+		recordLoginFailure(guard, req.Phone, ip)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": utils.ErrInvalidCredentials})
 		return
 	}
 
+	recordLoginSuccess(guard, req.Phone)
+
 	if user.Status != models.UserStatusVerified {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Tài khoản chưa được xác minh"})
 		c.Abort()
 		return
 	}
 
+	// Step-up: the full token is only issued after the second factor
+	if user.TwoFactorEnabled {
+		challenge, err := middleware.GenerateMFAChallengeToken(user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"two_factor_required": true,
+			"mfa_token":           challenge,
+		})
+		return
+	}
+
 	token, err := middleware.GenerateToken(user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
@@ -274,6 +323,27 @@ func Login(c *gin.Context) {
 	})
 }
 
+// UnlockAccount lifts a login lockout after verifying a login OTP
+func UnlockAccount(c *gin.Context) {
+	var req UnlockAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng điền đầy đủ thông tin"})
+		return
+	}
+
+	if err := newOTPService().Verify(req.Phone, models.OTPPurposeLogin, req.OTP); err != nil {
+		respondOTPError(c, err)
+		return
+	}
+
+	if err := newLoginGuard().Unlock(req.Phone); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Mở khóa tài khoản thành công"})
+}
+
 func Logout(c *gin.Context) {
 	// Temporary comment this
 	
@@ -315,60 +385,26 @@ func ForgotPassword(c *gin.Context) {
 
 	userRepo := repository.NewUserRepository(config.DB)
 
-	// Check if user exists
-	_, err := userRepo.FindByPhone(req.Phone)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": utils.ErrPhoneNotFound})
+	// Unknown phones get the same answer without an SMS, so the endpoint cannot
+	// be used to find out which numbers have an account
+	if _, err := userRepo.FindByPhone(req.Phone); err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Mã OTP đã được gửi thành công"})
 		return
 	}
 
-	// Generate OTP
-	otp := generateOTP()
-
-	// Store OTP in Redis with expiration
-	key := "otp:" + req.Phone
-	err = config.RedisClient.Set(config.Ctx, key, otp, 5*time.Minute).Err()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+	// Send password reset OTP
+	if err := newOTPService().Send(req.Phone, models.OTPPurposeResetPassword); err != nil {
+		respondOTPError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Mã OTP đã được gửi thành công",
-		"otp":     otp, // Remove in production
-	})
+	c.JSON(http.StatusOK, gin.H{"message": "Mã OTP đã được gửi thành công"})
 }
 
-// func VerifyOTP(c *gin.Context) {
-// 	var req VerifyOTPRequest
-// 	if err := c.ShouldBindJSON(&req); err != nil {
-// 		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng điền đầy đủ thông tin"})
-// 		return
-// 	}
-
-// 	// Validate phone
-// 	if !utils.ValidatePhone(req.Phone) {
-// 		c.JSON(http.StatusBadRequest, gin.H{"error": utils.ErrPhoneInvalid})
-// 		return
-// 	}
-
-// 	// Get OTP from Redis
-// 	key := "otp:" + req.Phone
-// 	storedOTP, err := config.RedisClient.Get(config.Ctx, key).Result()
-// 	if err != nil {
-// 		c.JSON(http.StatusBadRequest, gin.H{"error": utils.ErrOTPExpired})
-// 		return
-// 	}
-
-// 	// Verify OTP
-// 	if storedOTP != req.OTP {
-// 		c.JSON(http.StatusBadRequest, gin.H{"error": utils.ErrOTPInvalid})
-// 		return
-// 	}
-
-// 	c.JSON(http.StatusOK, gin.H{"message": "Xác thực OTP thành công"})
-// }
-
 func ResetPassword(c *gin.Context) {
 	var req ResetPasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -388,20 +424,23 @@ func ResetPassword(c *gin.Context) {
 		return
 	}
 
-	// Verify OTP first
-	key := "otp:" + req.Phone
-	storedOTP, err := config.RedisClient.Get(config.Ctx, key).Result()
-	if err != nil || storedOTP != req.OTP {
-		c.JSON(http.StatusBadRequest, gin.H{"error": utils.ErrOTPInvalid})
-		return
-	}
-
 	userRepo := repository.NewUserRepository(config.DB)
 
-	// Get user and update password
+	// Get user and update password. An unknown phone never received a code, so
+	// it is answered like a wrong one rather than revealing it has no account.
 	user, err := userRepo.FindByPhone(req.Phone)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": utils.ErrPhoneNotFound})
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": utils.ErrOTPInvalid})
+		return
+	}
+
+	// Verify OTP (the code is consumed on success)
+	if err := newOTPService().Verify(req.Phone, models.OTPPurposeResetPassword, req.OTP); err != nil {
+		respondOTPError(c, err)
 		return
 	}
 
@@ -411,9 +450,6 @@ func ResetPassword(c *gin.Context) {
 		return
 	}
 
-	// Delete OTP after successful password reset
-	config.RedisClient.Del(config.Ctx, key)
-
 	c.JSON(http.StatusOK, gin.H{"message": "Đặt lại mật khẩu thành công"})
 }
 
@@ -488,10 +524,67 @@ func ChangePassword(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Đổi mật khẩu thành công"})
 }
 
+// newOTPService creates an OTP service with the configured SMS provider
+func newOTPService() *services.OTPService {
+	return services.NewOTPService(
+		repository.NewOTPRepository(config.DB),
+		services.NewSMSServiceFromEnv(),
+		services.DefaultOTPConfig(),
+	)
+}
 
-// Helper function to generate 6-digit OTP
-func generateOTP() string {
-	// For simplicity, we'll generate a random 6-digit number
-	// In production, use a more secure method
-	return "123456"
+// respondOTPError maps OTP service errors to API responses
+func respondOTPError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrOTPInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": utils.ErrOTPInvalid})
+	case errors.Is(err, services.ErrOTPExpired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": utils.ErrOTPExpired})
+	case errors.Is(err, services.ErrOTPLocked):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": utils.ErrOTPLocked})
+	case errors.Is(err, services.ErrOTPCooldown):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": utils.ErrOTPCooldown})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+	}
+}
+
+// newLoginGuard creates a login guard backed by the database
+func newLoginGuard() *services.LoginGuard {
+	return services.NewLoginGuard(
+		repository.NewLoginThrottleRepository(config.DB),
+		services.DefaultLoginGuardConfig(),
+	)
+}
+
+// recordLoginFailure counts a failed login. The attempt is refused either way;
+// Check still fails closed when the throttle store is unavailable.
+func recordLoginFailure(guard *services.LoginGuard, phone string, ip string) {
+	if err := guard.RecordFailure(phone, ip); err != nil {
+		log.Printf("Error recording failed login of %s from %s: %v", phone, ip, err)
+	}
+}
+
+// recordLoginSuccess clears the failures of an account. It fails open: the
+// credentials were correct, and a counter left behind only makes the next wrong
+// attempt back off sooner.
+func recordLoginSuccess(guard *services.LoginGuard, phone string) {
+	if err := guard.RecordSuccess(phone); err != nil {
+		log.Printf("Error clearing login failures of %s: %v", phone, err)
+	}
+}
+
+// respondLoginGuardError maps login guard errors to API responses
+func respondLoginGuardError(c *gin.Context, wait time.Duration, err error) {
+	retryAfter := int(math.Ceil(wait.Seconds()))
+	switch {
+	case errors.Is(err, services.ErrLoginLocked):
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": utils.ErrLoginLocked, "retry_after": retryAfter})
+	case errors.Is(err, services.ErrLoginBackoff):
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": utils.ErrLoginBackoff, "retry_after": retryAfter})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+	}
 }
@@ -5,10 +5,13 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"ticket-management/api_simple/config"
+	"ticket-management/api_simple/middleware"
 	"ticket-management/api_simple/models"
 	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
 	"ticket-management/api_simple/utils"
 
 	"github.com/gin-gonic/gin"
@@ -23,10 +26,20 @@ type CreateBookingRequest struct {
 	PaymentType models.PaymentType `json:"payment_type" binding:"required,oneof=cash"`
 	GuestInfo   *models.GuestInfo  `json:"guest_info"` // Required for non-logged-in users
 	Note        string             `json:"note"`
+
+	PickupPointID  *uint `json:"pickup_point_id"`  // Điểm đón (mặc định: bến đi của tuyến)
+	DropoffPointID *uint `json:"dropoff_point_id"` // Điểm trả (mặc định: bến đến của tuyến)
+}
+
+type GuestLookupRequest struct {
+	Phone string `json:"phone" binding:"required,min=10,max=11"`
+	OTP   string `json:"otp" binding:"required,len=6"`
 }
 
 type UpdatePaymentRequest struct {
-	PaymentStatus models.PaymentStatus `json:"payment_status" binding:"required,oneof=paid refunded"`
+	PaymentStatus    models.PaymentStatus        `json:"payment_status" binding:"required,oneof=paid refunded"`
+	PaymentReference string                      `json:"payment_reference" binding:"max=100"` // Mã giao dịch tại cổng thanh toán (không bắt buộc)
+	PaymentProvider  models.PaymentAccountMethod `json:"payment_provider"`                    // Cổng thanh toán của giao dịch (bắt buộc khi có mã giao dịch)
 }
 
 // CreateBooking creates a new booking
@@ -87,13 +100,19 @@ func CreateBooking(c *gin.Context) {
 	}
 
 	// Calculate total amount
-	var totalAmount float64
+	var totalAmount models.Money
 	for _, seat := range seats {
 		totalAmount += seat.Price
 	}
 
+	selection, ok := selectBoardingPoints(c, trip, req.PickupPointID, req.DropoffPointID, len(req.SeatIDs))
+	if !ok {
+		return
+	}
+
 	// Create booking
 	booking := &models.Booking{
+		OperatorID:    trip.OperatorID,
 		UserID:        req.UserId,
 		User:          req.User,
 		TripID:        req.TripID,
@@ -102,8 +121,10 @@ func CreateBooking(c *gin.Context) {
 		PaymentType:   req.PaymentType,
 		PaymentStatus: models.PaymentStatusPaid,
 		Status:        models.BookingStatusPending,
+		Channel:       models.BookingChannelOnline,
 		Note:          req.Note,
 	}
+	selection.Apply(booking)
 
 	// Set user or guest info
 	if user, exists := c.Get("user"); exists {
@@ -130,11 +151,12 @@ func CreateBooking(c *gin.Context) {
 	// Create booking in transaction
 	err = config.DB.Transaction(func(tx *gorm.DB) error {
 		// Create booking
-		if err := bookingRepo.Create(booking); err != nil {
+		if err := repository.NewBookingRepository(tx).Create(booking); err != nil {
 			return err
 		}
 
 		// Update seat status
+		seatRepo := repository.NewSeatRepository(tx)
 		for _, seatID := range req.SeatIDs {
 			if err := seatRepo.UpdateStatus(uint(seatID), models.SeatStatusBooked); err != nil {
 				return err
@@ -143,11 +165,11 @@ func CreateBooking(c *gin.Context) {
 
 		// Update trip booked seats count
 		trip.BookedSeats += len(req.SeatIDs)
-		if err := tripRepo.Update(trip); err != nil {
+		if err := repository.NewTripRepository(tx).Update(trip); err != nil {
 			return err
 		}
 
-		return nil
+		return newLedgerService(tx).RecordSale(booking, time.Now())
 	})
 
 	if err != nil {
@@ -155,6 +177,9 @@ func CreateBooking(c *gin.Context) {
 		return
 	}
 
+	notifySeats(booking.TripID, services.SeatEventBooked, models.SeatStatusBooked, booking.SeatIDs, nil)
+	booking.SetBoardingTimes(trip.DepartureTime)
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Đặt vé thành công",
 		"booking": booking,
@@ -175,10 +200,42 @@ func GetBookingByCode(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy đơn đặt vé"})
 		return
 	}
+	if booking.Trip != nil {
+		booking.SetBoardingTimes(booking.Trip.DepartureTime)
+	}
 
 	c.JSON(http.StatusOK, booking)
 }
 
+// LookupGuestBookings returns bookings made with a guest phone after OTP verification
+func LookupGuestBookings(c *gin.Context) {
+	var req GuestLookupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng điền đầy đủ thông tin"})
+		return
+	}
+
+	if err := newOTPService().Verify(req.Phone, models.OTPPurposeGuestLookup, req.OTP); err != nil {
+		respondOTPError(c, err)
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	bookingRepo := repository.NewBookingRepository(config.DB)
+	bookings, total, err := bookingRepo.FindByGuestPhone(req.Phone, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"bookings": bookings,
+		"total":    total,
+	})
+}
+
 // GetUserBookings gets all bookings for the authenticated user
 func GetUserBookings(c *gin.Context) {
 	user := c.MustGet("user").(*models.User)
@@ -233,13 +290,8 @@ func CancelBooking(c *gin.Context) {
 		return
 	}
 
-	// Get repositories
-	bookingRepo := repository.NewBookingRepository(config.DB)
-	seatRepo := repository.NewSeatRepository(config.DB)
-	tripRepo := repository.NewTripRepository(config.DB)
-
 	// Get booking
-	booking, err := bookingRepo.FindByID(uint(id))
+	booking, err := repository.NewBookingRepository(config.DB).FindByID(uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy đơn đặt vé"})
 		return
@@ -264,8 +316,11 @@ func CancelBooking(c *gin.Context) {
 
 	// Cancel booking in transaction
 	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		seatRepo := repository.NewSeatRepository(tx)
+		tripRepo := repository.NewTripRepository(tx)
+
 		// Update booking status
-		if err := bookingRepo.UpdateStatus(booking.ID, models.BookingStatusCancelled); err != nil {
+		if err := repository.NewBookingRepository(tx).UpdateStatus(booking.ID, models.BookingStatusCancelled); err != nil {
 			return err
 		}
 
@@ -286,7 +341,16 @@ func CancelBooking(c *gin.Context) {
 			return err
 		}
 
-		return nil
+		// Refund a paid booking according to the operator's policy, post the cancellation
+		// and adjust its invoice
+		refund, err := newOperatorService(tx).Refund(booking, trip.DepartureTime, time.Now())
+		if err != nil {
+			return err
+		}
+		if err := newLedgerService(tx).RecordCancellation(booking, time.Now()); err != nil {
+			return err
+		}
+		return adjustInvoiceForRefund(tx, booking, refund)
 	})
 
 	if err != nil {
@@ -294,7 +358,12 @@ func CancelBooking(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Hủy đơn thành công"})
+	notifySeats(booking.TripID, services.SeatEventReleased, models.SeatStatusAvailable, booking.SeatIDs, nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Hủy đơn thành công",
+		"refund_amount": booking.RefundAmount,
+	})
 }
 
 // ConfirmBooking confirms a booking (admin only)
@@ -305,10 +374,10 @@ func ConfirmBooking(c *gin.Context) {
 		return
 	}
 
-	bookingRepo := repository.NewBookingRepository(config.DB)
+	db := operatorDB(c)
 
 	// Get booking
-	booking, err := bookingRepo.FindByID(uint(id))
+	booking, err := repository.NewBookingRepository(db).FindByID(uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy đơn đặt vé"})
 		return
@@ -321,14 +390,32 @@ func ConfirmBooking(c *gin.Context) {
 	}
 	// Remove payment status check - admin can confirm and mark as paid at the same time
 
-	// Update booking status and payment status
-	if err := bookingRepo.UpdateStatus(booking.ID, models.BookingStatusConfirmed); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// Update booking status and payment status
+		bookingRepo := repository.NewBookingRepository(tx)
+		if err := bookingRepo.UpdateStatus(booking.ID, models.BookingStatusConfirmed); err != nil {
+			return err
+		}
+
+		// Also update payment status to paid when confirming
+		if err := bookingRepo.UpdatePaymentStatus(booking.ID, models.PaymentStatusPaid); err != nil {
+			return err
+		}
+
+		// Cash collected at the counter goes into the drawer of the open shift
+		if booking.PaymentStatus != models.PaymentStatusUnpaid {
+			return nil
+		}
+		if err := recordDrawerCash(c, tx, models.CashTransactionSale, booking, booking.TotalAmount); err != nil {
+			return err
+		}
+		return newLedgerService(tx).RecordPayment(booking, time.Now())
+	})
+	if errors.Is(err, services.ErrNoOpenShift) {
+		respondCashShiftError(c, err)
 		return
 	}
-
-	// Also update payment status to paid when confirming
-	if err := bookingRepo.UpdatePaymentStatus(booking.ID, models.PaymentStatusPaid); err != nil {
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
 		return
 	}
@@ -337,6 +424,17 @@ func ConfirmBooking(c *gin.Context) {
 	fmt.Printf("DEBUG: Updated booking ID %d - Status: %s, PaymentStatus: %s\n",
 		booking.ID, models.BookingStatusConfirmed, models.PaymentStatusPaid)
 
+	after := *booking
+	after.Status = models.BookingStatusConfirmed
+	after.PaymentStatus = models.PaymentStatusPaid
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "booking.confirm",
+		EntityType: "bookings",
+		EntityID:   booking.ID,
+		Before:     booking,
+		After:      after,
+	})
+
 	c.JSON(http.StatusOK, gin.H{"message": "Xác nhận đơn thành công"})
 }
 
@@ -353,22 +451,88 @@ func UpdateBookingPayment(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng điền đầy đủ thông tin"})
 		return
 	}
+	// A reference is only reconciled against the settlement file of its own gateway
+	hasReference := req.PaymentReference != "" && req.PaymentStatus == models.PaymentStatusPaid
+	if hasReference && (!req.PaymentProvider.IsValid() || req.PaymentProvider == models.PaymentAccountBank) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cổng thanh toán không hợp lệ"})
+		return
+	}
 
-	bookingRepo := repository.NewBookingRepository(config.DB)
+	db := operatorDB(c)
 
 	// Get booking
-	booking, err := bookingRepo.FindByID(uint(id))
+	booking, err := repository.NewBookingRepository(db).FindByID(uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy đơn đặt vé"})
 		return
 	}
+	// A cancelled booking's money is settled by the cancellation
+	if booking.Status == models.BookingStatusCancelled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Không thể cập nhật thanh toán của đơn đã hủy"})
+		return
+	}
+	// Only changes the ledger can post are allowed
+	if !booking.PaymentStatus.CanChangeTo(req.PaymentStatus) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Trạng thái thanh toán không hợp lệ"})
+		return
+	}
 
-	// Update payment status
-	if err := bookingRepo.UpdatePaymentStatus(booking.ID, req.PaymentStatus); err != nil {
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// Update payment status
+		bookingRepo := repository.NewBookingRepository(tx)
+		if err := bookingRepo.UpdatePaymentStatus(booking.ID, req.PaymentStatus); err != nil {
+			return err
+		}
+		// The gateway's transaction reference lets the payment be reconciled with its settlement file
+		if hasReference {
+			if err := bookingRepo.SetPaymentReference(booking.ID, req.PaymentProvider, req.PaymentReference); err != nil {
+				return err
+			}
+		}
+
+		// Cash collected at the counter goes into the drawer of the open shift
+		if booking.PaymentStatus == models.PaymentStatusUnpaid && req.PaymentStatus == models.PaymentStatusPaid {
+			if err := recordDrawerCash(c, tx, models.CashTransactionSale, booking, booking.TotalAmount); err != nil {
+				return err
+			}
+		}
+		if err := newLedgerService(tx).RecordPaymentChange(booking, booking.PaymentStatus, req.PaymentStatus, time.Now()); err != nil {
+			return err
+		}
+
+		// A full refund lowers the booking's invoice and is paid out of the drawer like a
+		// refunded cancellation
+		if booking.PaymentStatus == models.PaymentStatusPaid && req.PaymentStatus == models.PaymentStatusRefunded {
+			if err := adjustInvoiceForRefund(tx, booking, booking.TotalAmount); err != nil {
+				return err
+			}
+			return refundFromDrawer(c, tx, booking, booking.TotalAmount)
+		}
+		return nil
+	})
+	if errors.Is(err, services.ErrNoOpenShift) {
+		respondCashShiftError(c, err)
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
 		return
 	}
 
+	after := *booking
+	after.PaymentStatus = req.PaymentStatus
+	if hasReference {
+		after.PaymentProvider = &req.PaymentProvider
+		after.PaymentReference = &req.PaymentReference
+	}
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "booking.update_payment",
+		EntityType: "bookings",
+		EntityID:   booking.ID,
+		Before:     booking,
+		After:      after,
+	})
+
 	c.JSON(http.StatusOK, gin.H{"message": "Cập nhật trạng thái thanh toán thành công"})
 }
 
@@ -5,6 +5,7 @@ import (
 	"strconv"
 
 	"ticket-management/api_simple/config"
+	"ticket-management/api_simple/middleware"
 	"ticket-management/api_simple/models"
 	"ticket-management/api_simple/repository"
 	"ticket-management/api_simple/utils"
@@ -17,6 +18,9 @@ type CreateBusRequest struct {
 	Type        string `json:"type" binding:"required"`
 	SeatCount   int    `json:"seat_count" binding:"required,gt=0"`
 	FloorCount  int    `json:"floor_count" binding:"required,gt=0,lte=2"`
+
+	CargoCapacityKg float64 `json:"cargo_capacity_kg" binding:"gte=0"` // Tải trọng khoang hàng (0: không giới hạn)
+	CargoCapacityM3 float64 `json:"cargo_capacity_m3" binding:"gte=0"` // Thể tích khoang hàng (0: không giới hạn)
 }
 
 type UpdateBusRequest struct {
@@ -24,10 +28,14 @@ type UpdateBusRequest struct {
 	SeatCount  int    `json:"seat_count" binding:"omitempty,gt=0"`
 	FloorCount int    `json:"floor_count" binding:"omitempty,gt=0,lte=2"`
 	IsActive   *bool  `json:"is_active"`
+
+	CargoCapacityKg *float64 `json:"cargo_capacity_kg" binding:"omitempty,gte=0"`
+	CargoCapacityM3 *float64 `json:"cargo_capacity_m3" binding:"omitempty,gte=0"`
 }
 
 type BusResponse struct {
 	ID          uint   `json:"id"`
+	OperatorID  uint   `json:"operator_id"` // Nhà xe sở hữu
 	PlateNumber string `json:"plate_number"`
 	Type        string `json:"type"`
 	SeatCount   int    `json:"seat_count"`
@@ -35,6 +43,13 @@ type BusResponse struct {
 	IsActive    bool   `json:"is_active"`
 	CreatedAt   string `json:"created_at"`
 	UpdatedAt   string `json:"updated_at"`
+
+	OdometerKm            int    `json:"odometer_km"`
+	InspectionExpiresAt   string `json:"inspection_expires_at,omitempty"`
+	RegistrationExpiresAt string `json:"registration_expires_at,omitempty"`
+
+	CargoCapacityKg float64 `json:"cargo_capacity_kg"`
+	CargoCapacityM3 float64 `json:"cargo_capacity_m3"`
 }
 
 // CreateBus creates a new bus
@@ -63,10 +78,13 @@ func CreateBus(c *gin.Context) {
 		return
 	}
 
-	busRepo := repository.NewBusRepository(config.DB)
+	if _, ok := requireOperator(c); !ok {
+		return
+	}
+	busRepo := repository.NewBusRepository(operatorDB(c))
 
-	// Check if plate number exists
-	exists, err := busRepo.Exists(map[string]interface{}{
+	// Plate numbers are unique across all operators
+	exists, err := repository.NewBusRepository(config.DB).Exists(map[string]interface{}{
 		"plate_number": req.PlateNumber,
 	})
 	if err != nil {
@@ -84,6 +102,14 @@ func CreateBus(c *gin.Context) {
 		SeatCount:   req.SeatCount,
 		FloorCount:  req.FloorCount,
 		IsActive:    true,
+
+		CargoCapacityKg: req.CargoCapacityKg,
+		CargoCapacityM3: req.CargoCapacityM3,
+	}
+
+	if err := bus.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationMessage(err)})
+		return
 	}
 
 	if err := busRepo.Create(&bus); err != nil {
@@ -91,6 +117,13 @@ func CreateBus(c *gin.Context) {
 		return
 	}
 
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "bus.create",
+		EntityType: "buses",
+		EntityID:   bus.ID,
+		After:      bus,
+	})
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Tạo xe thành công",
 		"bus":     formatBusResponse(&bus),
@@ -105,6 +138,7 @@ func GetBuses(c *gin.Context) {
 	plateNumber := c.Query("plate_number")
 	busType := c.Query("type")
 	isActive := c.Query("is_active")
+	operatorID, _ := strconv.ParseUint(c.Query("operator_id"), 10, 64)
 
 	// Build filter
 	filter := make(map[string]interface{})
@@ -117,6 +151,9 @@ func GetBuses(c *gin.Context) {
 	if isActive != "" {
 		filter["is_active"] = isActive == "true"
 	}
+	if operatorID > 0 {
+		filter["operator_id"] = operatorID
+	}
 
 	buses, err := busRepo.FindAll(filter)
 	if err != nil {
@@ -175,7 +212,7 @@ func UpdateBus(c *gin.Context) {
 		return
 	}
 
-	busRepo := repository.NewBusRepository(config.DB)
+	busRepo := repository.NewBusRepository(operatorDB(c))
 
 	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
@@ -189,6 +226,8 @@ func UpdateBus(c *gin.Context) {
 		return
 	}
 
+	before := *bus
+
 	// Update fields if provided
 	if req.Type != "" {
 		bus.Type = req.Type
@@ -202,12 +241,31 @@ func UpdateBus(c *gin.Context) {
 	if req.IsActive != nil {
 		bus.IsActive = *req.IsActive
 	}
+	if req.CargoCapacityKg != nil {
+		bus.CargoCapacityKg = *req.CargoCapacityKg
+	}
+	if req.CargoCapacityM3 != nil {
+		bus.CargoCapacityM3 = *req.CargoCapacityM3
+	}
+
+	if err := bus.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationMessage(err)})
+		return
+	}
 
 	if err := busRepo.Update(bus); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
 		return
 	}
 
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "bus.update",
+		EntityType: "buses",
+		EntityID:   bus.ID,
+		Before:     before,
+		After:      bus,
+	})
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Cập nhật xe thành công",
 		"bus":     formatBusResponse(bus),
@@ -216,7 +274,7 @@ func UpdateBus(c *gin.Context) {
 
 // DeleteBus soft deletes a bus
 func DeleteBus(c *gin.Context) {
-	busRepo := repository.NewBusRepository(config.DB)
+	busRepo := repository.NewBusRepository(operatorDB(c))
 
 	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
@@ -225,7 +283,8 @@ func DeleteBus(c *gin.Context) {
 	}
 
 	// Check if bus exists
-	if _, err := busRepo.FindByID(uint(id)); err != nil {
+	bus, err := busRepo.FindByID(uint(id))
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy xe"})
 		return
 	}
@@ -235,13 +294,21 @@ func DeleteBus(c *gin.Context) {
 		return
 	}
 
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "bus.delete",
+		EntityType: "buses",
+		EntityID:   bus.ID,
+		Before:     bus,
+	})
+
 	c.JSON(http.StatusOK, gin.H{"message": "Xóa xe thành công"})
 }
 
 // Helper function to format bus response
 func formatBusResponse(bus *models.Bus) *BusResponse {
-	return &BusResponse{
+	response := &BusResponse{
 		ID:          bus.ID,
+		OperatorID:  bus.OperatorID,
 		PlateNumber: bus.PlateNumber,
 		Type:        bus.Type,
 		SeatCount:   bus.SeatCount,
@@ -249,5 +316,16 @@ func formatBusResponse(bus *models.Bus) *BusResponse {
 		IsActive:    bus.IsActive,
 		CreatedAt:   bus.CreatedAt.Format("2006-01-02 15:04:05"),
 		UpdatedAt:   bus.UpdatedAt.Format("2006-01-02 15:04:05"),
+		OdometerKm:  bus.OdometerKm,
+
+		CargoCapacityKg: bus.CargoCapacityKg,
+		CargoCapacityM3: bus.CargoCapacityM3,
+	}
+	if bus.InspectionExpiresAt != nil {
+		response.InspectionExpiresAt = bus.InspectionExpiresAt.Format("2006-01-02 15:04:05")
+	}
+	if bus.RegistrationExpiresAt != nil {
+		response.RegistrationExpiresAt = bus.RegistrationExpiresAt.Format("2006-01-02 15:04:05")
 	}
+	return response
 }
@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ticket-management/api_simple/middleware"
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+	"ticket-management/api_simple/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type OpenShiftRequest struct {
+	OpeningFloat models.Money `json:"opening_float" binding:"gte=0"` // Tiền lẻ đầu ca
+}
+
+type ShiftPayoutRequest struct {
+	Amount models.Money `json:"amount" binding:"required,gt=0"` // Số tiền chi
+	Note   string       `json:"note" binding:"required"`        // Lý do chi
+}
+
+type CloseShiftRequest struct {
+	CountedCash *models.Money `json:"counted_cash" binding:"required,gte=0"` // Tiền mặt kiểm đếm trong két
+	Note        string        `json:"note"`                                  // Giải trình chênh lệch
+}
+
+type ApproveShiftRequest struct {
+	Note string `json:"note"` // Ghi chú của người duyệt
+}
+
+// OpenShift starts a counter shift for the current staff member with a starting float
+func OpenShift(c *gin.Context) {
+	operatorID, ok := requireOperator(c)
+	if !ok {
+		return
+	}
+
+	var req OpenShiftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tiền đầu ca không hợp lệ"})
+		return
+	}
+
+	user := c.MustGet("user").(*models.User)
+	shift, err := newCashShiftService(operatorDB(c)).Open(user, operatorID, req.OpeningFloat, time.Now())
+	if err != nil {
+		respondCashShiftError(c, err)
+		return
+	}
+
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "shift.open",
+		EntityType: "cash_shifts",
+		EntityID:   shift.ID,
+		After:      shift,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Mở ca thành công",
+		"shift":   shift,
+	})
+}
+
+// GetCurrentShift returns the open shift of the current staff member with its running totals
+func GetCurrentShift(c *gin.Context) {
+	user := c.MustGet("user").(*models.User)
+	service := newCashShiftService(operatorDB(c))
+
+	shift, err := service.Current(user.ID)
+	if err != nil {
+		respondCashShiftError(c, err)
+		return
+	}
+
+	report, err := service.Report(shift)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// CreateShiftPayout records cash paid out of the drawer during the current shift
+func CreateShiftPayout(c *gin.Context) {
+	var req ShiftPayoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng nhập số tiền và lý do chi"})
+		return
+	}
+
+	user := c.MustGet("user").(*models.User)
+	transaction, err := newCashShiftService(operatorDB(c)).Record(user.ID, models.CashTransactionPayout, req.Amount, nil, req.Note)
+	if err != nil {
+		respondCashShiftError(c, err)
+		return
+	}
+
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "shift.payout",
+		EntityType: "cash_shifts",
+		EntityID:   transaction.ShiftID,
+		After:      transaction,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":     "Ghi nhận khoản chi thành công",
+		"transaction": transaction,
+	})
+}
+
+// CloseShift ends the current shift with the counted cash and returns the variance report
+func CloseShift(c *gin.Context) {
+	var req CloseShiftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng nhập số tiền mặt kiểm đếm"})
+		return
+	}
+
+	user := c.MustGet("user").(*models.User)
+	report, err := newCashShiftService(operatorDB(c)).Close(user.ID, *req.CountedCash, req.Note, time.Now())
+	if err != nil {
+		respondCashShiftError(c, err)
+		return
+	}
+
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "shift.close",
+		EntityType: "cash_shifts",
+		EntityID:   report.Shift.ID,
+		After:      report.Shift,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Chốt ca thành công",
+		"report":  report,
+	})
+}
+
+// GetShifts lists counter shifts filtered by status and staff member (admin)
+func GetShifts(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	filters := make(map[string]interface{})
+	if status := models.CashShiftStatus(c.Query("status")); status != "" {
+		if !status.IsValid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Trạng thái ca không hợp lệ"})
+			return
+		}
+		filters["status"] = status
+	}
+	if staffID := c.Query("staff_id"); staffID != "" {
+		id, err := strconv.ParseUint(staffID, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "staff_id không hợp lệ"})
+			return
+		}
+		filters["staff_id"] = uint(id)
+	}
+
+	shifts, total, err := repository.NewCashShiftRepository(operatorDB(c)).FindAll(filters, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"shifts": shifts,
+		"total":  total,
+		"page":   page,
+		"limit":  limit,
+	})
+}
+
+// GetShift returns the cash report of a shift (admin)
+func GetShift(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID không hợp lệ"})
+		return
+	}
+
+	db := operatorDB(c)
+	shift, err := repository.NewCashShiftRepository(db).FindByID(uint(id))
+	if err != nil {
+		respondCashShiftError(c, err)
+		return
+	}
+
+	report, err := newCashShiftService(db).Report(shift)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// ApproveShift lets a supervisor sign off a closed shift (admin)
+func ApproveShift(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID không hợp lệ"})
+		return
+	}
+
+	var req ApproveShiftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dữ liệu không hợp lệ"})
+		return
+	}
+
+	user := c.MustGet("user").(*models.User)
+	shift, err := newCashShiftService(operatorDB(c)).Approve(uint(id), user.ID, req.Note, time.Now())
+	if err != nil {
+		respondCashShiftError(c, err)
+		return
+	}
+
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "shift.approve",
+		EntityType: "cash_shifts",
+		EntityID:   shift.ID,
+		After:      shift,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Duyệt ca thành công",
+		"shift":   shift,
+	})
+}
+
+// recordDrawerCash records cash taken or paid back for a booking in the open shift of
+// the current user. It fails with services.ErrNoOpenShift when no shift is open, so no
+// cash is handled outside a drawer.
+func recordDrawerCash(c *gin.Context, db *gorm.DB, txType models.CashTransactionType, booking *models.Booking, amount models.Money) error {
+	if booking.PaymentType != models.PaymentTypeCash || amount <= 0 {
+		return nil
+	}
+	user := c.MustGet("user").(*models.User)
+	_, err := newCashShiftService(db).Record(user.ID, txType, amount, &booking.ID, booking.BookingCode)
+	return err
+}
+
+// newCashShiftService creates a cash shift service backed by db
+func newCashShiftService(db *gorm.DB) *services.CashShiftService {
+	return services.NewCashShiftService(
+		repository.NewCashShiftRepository(db),
+		services.CashShiftConfigFromEnv(),
+	)
+}
+
+// respondCashShiftError maps cash shift errors to API responses
+func respondCashShiftError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy ca làm việc"})
+	case errors.Is(err, services.ErrShiftAlreadyOpen):
+		c.JSON(http.StatusConflict, gin.H{"error": "Bạn đang có ca chưa chốt"})
+	case errors.Is(err, services.ErrNoOpenShift):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng mở ca trước khi thu chi tại quầy"})
+	case errors.Is(err, services.ErrShiftNotClosed):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Chỉ có thể duyệt ca đã chốt"})
+	case errors.Is(err, services.ErrShiftSelfApproval):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Không thể tự duyệt ca của mình"})
+	case errors.Is(err, services.ErrVarianceNoteRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tiền mặt chênh lệch vượt mức cho phép, vui lòng ghi rõ lý do"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+	}
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ticket-management/api_simple/config"
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+	"ticket-management/api_simple/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetDemandAnalytics returns searches, unmet searches, bookings and conversion for each
+// origin and destination pair, for travel dates in a period (last 30 days by default)
+func GetDemandAnalytics(c *gin.Context) {
+	from, to, ok := ledgerPeriod(c)
+	if !ok {
+		return
+	}
+	today := startOfDay(time.Now())
+	if from == nil {
+		start := today.AddDate(0, 0, -30)
+		from = &start
+	}
+	end := today.AddDate(0, 0, 1)
+	if to != nil {
+		end = startOfDay(*to).AddDate(0, 0, 1)
+	}
+
+	corridors, err := repository.NewDemandRepository(operatorDB(c)).Corridors(startOfDay(*from), end)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":      startOfDay(*from).Format(models.TravelDateFormat),
+		"to":        end.AddDate(0, 0, -1).Format(models.TravelDateFormat),
+		"corridors": corridors,
+	})
+}
+
+// GetSeasonalPopularRoutes ranks origin and destination pairs by bookings and searches
+// for travel in a season (the current one by default)
+func GetSeasonalPopularRoutes(c *gin.Context) {
+	season, year := models.SeasonAt(time.Now())
+	if value := c.Query("season"); value != "" {
+		season = models.Season(value)
+	}
+	if value := c.Query("year"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "year không hợp lệ"})
+			return
+		}
+		year = parsed
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	routes, err := newDemandService(operatorDB(c)).PopularRoutes(season, year, limit)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidSeason) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "season không hợp lệ (spring, summer, autumn, winter)"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"season": season,
+		"year":   year,
+		"routes": routes,
+	})
+}
+
+// GetDepartureRecommendations suggests extra departures on the operator's routes for the
+// coming days where searches found no trips or nearly full ones
+func GetDepartureRecommendations(c *gin.Context) {
+	recommendations, err := newDemandService(operatorDB(c)).Recommend(time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"recommendations": recommendations,
+		"total":           len(recommendations),
+	})
+}
+
+// recordTripSearch stores a trip search for demand analytics. Failures are only logged
+// so searching keeps working.
+func recordTripSearch(origin, destination string, travelDate *time.Time, routeID *uint, trips []models.Trip) {
+	available := 0
+	for _, trip := range trips {
+		if trip.TotalSeats == 0 || trip.BookedSeats < trip.TotalSeats {
+			available++
+		}
+	}
+	if err := newDemandService(config.DB).RecordSearch(origin, destination, travelDate, routeID, available, time.Now()); err != nil {
+		log.Printf("Error recording trip search %s - %s: %v", origin, destination, err)
+	}
+}
+
+// newDemandService creates a demand analytics service backed by db
+func newDemandService(db *gorm.DB) *services.DemandService {
+	return services.NewDemandService(
+		repository.NewDemandRepository(db),
+		repository.NewRouteRepository(db),
+		services.DemandConfigFromEnv(),
+	)
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
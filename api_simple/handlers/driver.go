@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ticket-management/api_simple/config"
+	"ticket-management/api_simple/middleware"
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+	"ticket-management/api_simple/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type DriverProfileRequest struct {
+	LicenceNumber string `json:"licence_number" binding:"required"`
+	LicenceClass  string `json:"licence_class" binding:"required,oneof=B2 C D E"`
+	LicenceExpiry string `json:"licence_expiry" binding:"required"` // YYYY-MM-DD
+}
+
+// UpdateDriverProfile sets the licence details of a driver (admin only)
+func UpdateDriverProfile(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID không hợp lệ"})
+		return
+	}
+
+	var req DriverProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng điền đầy đủ thông tin"})
+		return
+	}
+
+	expiry, err := time.ParseInLocation("2006-01-02", req.LicenceExpiry, time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Ngày hết hạn không hợp lệ (YYYY-MM-DD)"})
+		return
+	}
+
+	userRepo := operatorUserRepository(c)
+	driver, err := userRepo.FindByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy tài xế"})
+		return
+	}
+	if driver.Role != models.RoleDriver {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Người dùng không phải tài xế"})
+		return
+	}
+
+	profileRepo := repository.NewDriverProfileRepository(config.DB)
+	profile, err := profileRepo.FindByUserID(driver.ID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+			return
+		}
+		profile = &models.DriverProfile{UserID: driver.ID}
+	}
+	before := *profile
+
+	profile.LicenceNumber = req.LicenceNumber
+	profile.LicenceClass = models.LicenceClass(req.LicenceClass)
+	// The licence is valid until the end of its expiry date
+	profile.LicenceExpiry = expiry.AddDate(0, 0, 1)
+	if err := profile.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := profileRepo.Save(profile); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "driver.update_profile",
+		EntityType: "drivers",
+		EntityID:   driver.ID,
+		Before:     before,
+		After:      profile,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Cập nhật giấy phép lái xe thành công",
+		"profile": profile,
+	})
+}
+
+// GetDriverRoster returns a driver's upcoming trips with duty-hour and rest warnings
+func GetDriverRoster(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID không hợp lệ"})
+		return
+	}
+
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "7"))
+	if days < 1 || days > 31 {
+		days = 7
+	}
+	from := time.Now()
+	to := from.AddDate(0, 0, days)
+
+	roster, err := newDutyService(operatorDB(c)).Roster(uint(id), from, to)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy tài xế"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, roster)
+}
+
+// newDutyService creates a driver duty service backed by db
+func newDutyService(db *gorm.DB) *services.DutyService {
+	return services.NewDutyService(
+		repository.NewTripRepository(db),
+		repository.NewUserRepository(db),
+		repository.NewDriverProfileRepository(db),
+		repository.NewBusRepository(db),
+		repository.NewRouteRepository(db),
+		services.DutyConfigFromEnv(),
+	)
+}
+
+// respondDutyError maps duty rule violations to API responses
+func respondDutyError(c *gin.Context, err error) {
+	var violations services.DutyViolations
+	if !errors.As(err, &violations) {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy tuyến đường hoặc xe"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":      violations[0].Message,
+		"violations": violations,
+	})
+}
@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ticket-management/api_simple/config"
+	"ticket-management/api_simple/middleware"
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+	"ticket-management/api_simple/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type HolidayRequest struct {
+	Name      string  `json:"name" binding:"required"`       // Tên dịp lễ, giữ nguyên qua các năm
+	StartDate string  `json:"start_date" binding:"required"` // Ngày bắt đầu (YYYY-MM-DD)
+	EndDate   string  `json:"end_date" binding:"required"`   // Ngày kết thúc (YYYY-MM-DD)
+	Uplift    float64 `json:"uplift"`                        // Hệ số nhu cầu mặc định (mặc định: 1.5)
+}
+
+// apply copies the request onto the holiday
+func (req *HolidayRequest) apply(holiday *models.Holiday) {
+	holiday.Name = req.Name
+	holiday.StartDate = req.StartDate
+	holiday.EndDate = req.EndDate
+	holiday.Uplift = req.Uplift
+	if holiday.Uplift == 0 {
+		holiday.Uplift = 1.5
+	}
+}
+
+// GetTripForecasts lists the demand forecasts of upcoming trips against their current
+// bookings, optionally for one route or risk (admin)
+func GetTripForecasts(c *gin.Context) {
+	from, to, ok := ledgerPeriod(c)
+	if !ok {
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	filter := repository.ForecastFilter{From: from, To: to, Risk: models.ForecastRisk(c.Query("risk"))}
+	if filter.Risk != "" && filter.Risk != models.ForecastRiskSellOut && filter.Risk != models.ForecastRiskLowDemand {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "risk không hợp lệ (sell_out hoặc low_demand)"})
+		return
+	}
+	if value := c.Query("route_id"); value != "" {
+		routeID, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "route_id không hợp lệ"})
+			return
+		}
+		filter.RouteID = uint(routeID)
+	}
+
+	forecasts, total, err := repository.NewForecastRepository(operatorDB(c)).List(filter, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"forecasts": forecasts,
+		"total":     total,
+		"page":      page,
+		"limit":     limit,
+	})
+}
+
+// GetTripForecast returns the demand forecast of a trip (admin)
+func GetTripForecast(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID không hợp lệ"})
+		return
+	}
+
+	forecast, err := repository.NewForecastRepository(operatorDB(c)).FindByTrip(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Chưa có dự báo cho chuyến xe này"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"forecast": forecast})
+}
+
+// RefreshForecasts forecasts every upcoming trip again (super admin)
+func RefreshForecasts(c *gin.Context) {
+	count, err := newForecastService().Refresh(time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Cập nhật dự báo thành công",
+		"trips":   count,
+	})
+}
+
+// GetHolidays lists the holidays used by demand forecasting (admin)
+func GetHolidays(c *gin.Context) {
+	holidays, err := repository.NewHolidayRepository(config.DB).FindAll(nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"holidays": holidays,
+		"total":    len(holidays),
+	})
+}
+
+// CreateHoliday adds a holiday to the forecasting calendar (super admin)
+func CreateHoliday(c *gin.Context) {
+	var req HolidayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng điền đầy đủ thông tin"})
+		return
+	}
+
+	holiday := &models.Holiday{}
+	req.apply(holiday)
+	if err := holiday.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := repository.NewHolidayRepository(config.DB).Create(holiday); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "holiday.create",
+		EntityType: "holidays",
+		EntityID:   holiday.ID,
+		After:      holiday,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Thêm dịp lễ thành công",
+		"holiday": holiday,
+	})
+}
+
+// UpdateHoliday replaces the details of a holiday (super admin)
+func UpdateHoliday(c *gin.Context) {
+	holiday, ok := findHoliday(c)
+	if !ok {
+		return
+	}
+
+	var req HolidayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng điền đầy đủ thông tin"})
+		return
+	}
+
+	before := *holiday
+	req.apply(holiday)
+	if err := holiday.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := repository.NewHolidayRepository(config.DB).Update(holiday); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "holiday.update",
+		EntityType: "holidays",
+		EntityID:   holiday.ID,
+		Before:     before,
+		After:      holiday,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Cập nhật dịp lễ thành công",
+		"holiday": holiday,
+	})
+}
+
+// DeleteHoliday removes a holiday from the forecasting calendar (super admin)
+func DeleteHoliday(c *gin.Context) {
+	holiday, ok := findHoliday(c)
+	if !ok {
+		return
+	}
+
+	if err := repository.NewHolidayRepository(config.DB).Delete(holiday.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "holiday.delete",
+		EntityType: "holidays",
+		EntityID:   holiday.ID,
+		Before:     holiday,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Xóa dịp lễ thành công"})
+}
+
+// findHoliday loads the :id holiday, responding with an error if it cannot
+func findHoliday(c *gin.Context) (*models.Holiday, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID không hợp lệ"})
+		return nil, false
+	}
+
+	holiday, err := repository.NewHolidayRepository(config.DB).FindByID(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy dịp lễ"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return nil, false
+	}
+	return holiday, true
+}
+
+// newForecastService creates a forecasting service over every operator's trips
+func newForecastService() *services.ForecastService {
+	return services.NewForecastService(
+		repository.NewForecastRepository(config.DB),
+		repository.NewHolidayRepository(config.DB),
+		services.ForecastConfigFromEnv(),
+	)
+}
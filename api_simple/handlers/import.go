@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"ticket-management/api_simple/middleware"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+	"ticket-management/api_simple/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxImportFileSize limits uploaded import files (5 MB)
+const maxImportFileSize = 5 << 20
+
+// errImportRollback undoes an import that is only checked or has invalid rows
+var errImportRollback = errors.New("import rolled back")
+
+// importErrorMessages explain the import errors that are not schedule or duty conflicts
+var importErrorMessages = map[error]string{
+	services.ErrImportInvalidValue:    "Giá trị không hợp lệ",
+	services.ErrImportRouteExists:     "Tuyến đường đã tồn tại",
+	services.ErrImportRouteNotFound:   "Không tìm thấy tuyến đường của nhà xe",
+	services.ErrImportPlateTaken:      "Biển số xe đã tồn tại",
+	services.ErrImportBusNotFound:     "Không tìm thấy xe của nhà xe",
+	services.ErrImportDriverNotFound:  "Không tìm thấy tài xế của nhà xe theo số điện thoại",
+	services.ErrImportScheduleTooLong: "Lịch chạy không được dài quá 366 ngày",
+	services.ErrImportNoDeparture:     "Lịch chạy không có ngày chạy nào",
+	services.ErrOperatorMismatch:      "Tuyến, xe và tài xế phải thuộc cùng một nhà xe",
+	gorm.ErrRecordNotFound:            "Không tìm thấy tuyến đường hoặc xe",
+}
+
+// ImportRecords creates routes, buses, trips or schedules of trips in bulk from a CSV or
+// XLSX file (admin). Every row is checked as if it were created through the API; unless
+// all rows are valid nothing is saved. With `dry_run=true` the file is only checked.
+func ImportRecords(c *gin.Context) {
+	operatorID, ok := requireOperator(c)
+	if !ok {
+		return
+	}
+
+	kind := services.ImportKind(c.Param("kind"))
+	if !kind.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Loại dữ liệu không hợp lệ (routes, buses, trips, schedules)"})
+		return
+	}
+	dryRun, _ := strconv.ParseBool(c.DefaultQuery("dry_run", c.PostForm("dry_run")))
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng chọn file CSV hoặc XLSX"})
+		return
+	}
+	if header.Size > maxImportFileSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File không được vượt quá 5MB"})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	table, err := services.ParseImportFile(data)
+	if err != nil {
+		respondImportError(c, err)
+		return
+	}
+
+	var result *services.ImportResult
+	err = operatorDB(c).Transaction(func(tx *gorm.DB) error {
+		result, err = newImportService(tx).Import(operatorID, kind, table)
+		if err != nil {
+			return err
+		}
+		if dryRun || len(result.Errors) > 0 {
+			return errImportRollback
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errImportRollback) {
+		respondImportError(c, err)
+		return
+	}
+
+	if len(result.Errors) > 0 {
+		for i := range result.Errors {
+			result.Errors[i].Message = importErrorMessage(result.Errors[i].Err)
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  fmt.Sprintf("File có %d lỗi, chưa có dữ liệu nào được nhập", len(result.Errors)),
+			"kind":   kind,
+			"rows":   result.Rows,
+			"errors": result.Errors,
+		})
+		return
+	}
+	if dryRun {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Dữ liệu hợp lệ, chưa có dữ liệu nào được nhập",
+			"dry_run": true,
+			"kind":    kind,
+			"rows":    result.Rows,
+			"created": result.Created,
+		})
+		return
+	}
+
+	for _, tripID := range result.TripIDs {
+		tripStatsChanged(tripID)
+	}
+
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "import." + string(kind),
+		EntityType: string(kind),
+		After:      gin.H{"file_name": header.Filename, "rows": result.Rows, "created": result.Created},
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Nhập dữ liệu thành công",
+		"kind":    kind,
+		"rows":    result.Rows,
+		"created": result.Created,
+	})
+}
+
+// importErrorMessage explains why a row of an import file cannot be applied
+func importErrorMessage(err error) string {
+	var conflict *services.ScheduleConflict
+	if errors.As(err, &conflict) {
+		return scheduleConflictMessage(conflict)
+	}
+	var violations services.DutyViolations
+	if errors.As(err, &violations) && len(violations) > 0 {
+		return violations[0].Message
+	}
+	for target, message := range importErrorMessages {
+		if errors.Is(err, target) {
+			return message
+		}
+	}
+	// Validation errors of the models
+	return validationMessage(err)
+}
+
+// respondImportError maps errors reading an import file to API responses
+func respondImportError(c *gin.Context, err error) {
+	var missing *services.MissingColumnError
+	switch {
+	case errors.As(err, &missing):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File thiếu cột bắt buộc: " + missing.Column})
+	case errors.Is(err, services.ErrImportEmpty):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File không có dòng dữ liệu nào"})
+	case errors.Is(err, services.ErrImportTooLarge):
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("File không được quá %d dòng", services.MaxImportRows)})
+	case errors.Is(err, services.ErrImportTooManyTrips):
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("File không được sinh ra quá %d chuyến", services.MaxImportTrips)})
+	case errors.Is(err, services.ErrInvalidImportFile):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File không đúng định dạng CSV hoặc XLSX"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+	}
+}
+
+// newImportService creates an import service backed by db
+func newImportService(db *gorm.DB) *services.ImportService {
+	return services.NewImportService(
+		repository.NewRouteRepository(db),
+		repository.NewBusRepository(db),
+		repository.NewBusRepository(repository.WithoutOperator(db)),
+		repository.NewUserRepository(db),
+		repository.NewTripRepository(db),
+		repository.NewSeatRepository(db),
+		newOperatorService(db),
+		newScheduleService(db),
+		newDutyService(db),
+	)
+}
@@ -0,0 +1,366 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ticket-management/api_simple/config"
+	"ticket-management/api_simple/middleware"
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+	"ticket-management/api_simple/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type InvoiceRequest struct {
+	BuyerName    string `json:"buyer_name"`     // Họ tên người mua
+	BuyerCompany string `json:"buyer_company"`  // Tên đơn vị
+	BuyerTaxCode string `json:"buyer_tax_code"` // Mã số thuế đơn vị
+	BuyerAddress string `json:"buyer_address"`  // Địa chỉ đơn vị
+	BuyerEmail   string `json:"buyer_email"`    // Email nhận hóa đơn
+}
+
+// buyer converts the request into the buyer printed on the invoice
+func (req *InvoiceRequest) buyer() models.InvoiceBuyer {
+	return models.InvoiceBuyer{
+		BuyerName:    req.BuyerName,
+		BuyerCompany: req.BuyerCompany,
+		BuyerTaxCode: req.BuyerTaxCode,
+		BuyerAddress: req.BuyerAddress,
+		BuyerEmail:   req.BuyerEmail,
+	}
+}
+
+type ReplaceInvoiceRequest struct {
+	InvoiceRequest
+	Reason string `json:"reason" binding:"required"` // Lý do thay thế
+}
+
+// RequestBookingInvoice issues the VAT invoice of a paid booking to the buyer given by the customer
+func RequestBookingInvoice(c *gin.Context) {
+	var req InvoiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dữ liệu không hợp lệ"})
+		return
+	}
+
+	booking, err := repository.NewBookingRepository(config.DB).FindByCode(c.Param("code"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy đơn đặt vé"})
+		return
+	}
+
+	issueInvoice(c, config.DB, booking, req.buyer())
+}
+
+// GetPublicInvoice returns an invoice by the lookup code printed on it
+func GetPublicInvoice(c *gin.Context) {
+	invoice, err := repository.NewInvoiceRepository(config.DB).FindByLookupCode(c.Param("lookup_code"))
+	if err != nil {
+		respondInvoiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, invoice)
+}
+
+// GetPublicInvoiceXML downloads the e-invoice XML by lookup code
+func GetPublicInvoiceXML(c *gin.Context) {
+	invoice, err := repository.NewInvoiceRepository(config.DB).FindByLookupCode(c.Param("lookup_code"))
+	if err != nil {
+		respondInvoiceError(c, err)
+		return
+	}
+	sendInvoiceXML(c, invoice)
+}
+
+// GetPublicInvoicePDF downloads the printable copy of an invoice by lookup code
+func GetPublicInvoicePDF(c *gin.Context) {
+	invoice, err := repository.NewInvoiceRepository(config.DB).FindByLookupCode(c.Param("lookup_code"))
+	if err != nil {
+		respondInvoiceError(c, err)
+		return
+	}
+	sendInvoicePDF(c, invoice)
+}
+
+// GetInvoices lists invoices filtered by status, type, series and booking (admin)
+func GetInvoices(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	filters := make(map[string]interface{})
+	if status := c.Query("status"); status != "" {
+		filters["status"] = status
+	}
+	if invoiceType := c.Query("type"); invoiceType != "" {
+		filters["type"] = invoiceType
+	}
+	if series := c.Query("series"); series != "" {
+		filters["series"] = series
+	}
+	if bookingID := c.Query("booking_id"); bookingID != "" {
+		id, err := strconv.ParseUint(bookingID, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "booking_id không hợp lệ"})
+			return
+		}
+		filters["booking_id"] = uint(id)
+	}
+
+	invoices, total, err := repository.NewInvoiceRepository(operatorDB(c)).FindAll(filters, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"invoices": invoices,
+		"total":    total,
+		"page":     page,
+		"limit":    limit,
+	})
+}
+
+// GetInvoice returns an invoice with its lines (admin)
+func GetInvoice(c *gin.Context) {
+	invoice, ok := findAdminInvoice(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, invoice)
+}
+
+// GetInvoiceXML downloads the e-invoice XML of an invoice (admin)
+func GetInvoiceXML(c *gin.Context) {
+	invoice, ok := findAdminInvoice(c)
+	if !ok {
+		return
+	}
+	sendInvoiceXML(c, invoice)
+}
+
+// GetInvoicePDF downloads the printable copy of an invoice (admin)
+func GetInvoicePDF(c *gin.Context) {
+	invoice, ok := findAdminInvoice(c)
+	if !ok {
+		return
+	}
+	sendInvoicePDF(c, invoice)
+}
+
+// IssueBookingInvoice issues the VAT invoice of a paid booking from the back office (admin)
+func IssueBookingInvoice(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID không hợp lệ"})
+		return
+	}
+
+	var req InvoiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dữ liệu không hợp lệ"})
+		return
+	}
+
+	db := operatorDB(c)
+	booking, err := repository.NewBookingRepository(db).FindByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy đơn đặt vé"})
+		return
+	}
+
+	issueInvoice(c, db, booking, req.buyer())
+}
+
+// ReplaceInvoice replaces a wrongly made out invoice with one to the corrected buyer (admin)
+func ReplaceInvoice(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID không hợp lệ"})
+		return
+	}
+
+	var req ReplaceInvoiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng nhập lý do thay thế hóa đơn"})
+		return
+	}
+
+	buyer := req.buyer()
+	if err := buyer.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := operatorDB(c)
+	var invoice *models.Invoice
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		invoice, err = newInvoiceService(tx).Replace(uint(id), buyer, req.Reason, time.Now())
+		return err
+	})
+	if err != nil {
+		respondInvoiceError(c, err)
+		return
+	}
+
+	submitInvoice(db, invoice)
+
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "invoice.replace",
+		EntityType: "invoices",
+		EntityID:   invoice.ID,
+		Before:     invoice.OriginalInvoice,
+		After:      invoice,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Lập hóa đơn thay thế thành công",
+		"invoice": invoice,
+	})
+}
+
+// SubmitInvoice sends an invoice to the e-invoice provider again (admin)
+func SubmitInvoice(c *gin.Context) {
+	invoice, ok := findAdminInvoice(c)
+	if !ok {
+		return
+	}
+
+	if err := newInvoiceService(operatorDB(c)).Submit(invoice, time.Now()); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Nhà cung cấp hóa đơn điện tử từ chối hoặc không phản hồi: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Gửi hóa đơn thành công",
+		"invoice": invoice,
+	})
+}
+
+// issueInvoice issues and submits the invoice of a booking and writes the response
+func issueInvoice(c *gin.Context, db *gorm.DB, booking *models.Booking, buyer models.InvoiceBuyer) {
+	if err := buyer.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var invoice *models.Invoice
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		invoice, err = newInvoiceService(tx).Issue(booking, buyer, time.Now())
+		return err
+	})
+	if err != nil {
+		respondInvoiceError(c, err)
+		return
+	}
+
+	submitInvoice(db, invoice)
+
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "invoice.issue",
+		EntityType: "invoices",
+		EntityID:   invoice.ID,
+		After:      invoice,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Xuất hóa đơn thành công",
+		"invoice": invoice,
+	})
+}
+
+// submitInvoice sends a new invoice to the provider right away; failures are left
+// to the retry job
+func submitInvoice(db *gorm.DB, invoice *models.Invoice) {
+	if err := newInvoiceService(db).Submit(invoice, time.Now()); err != nil {
+		log.Printf("Error submitting invoice %s: %v", invoice.Reference(), err)
+	}
+}
+
+// adjustInvoiceForRefund issues the adjustment invoice of a refunded booking inside
+// the cancellation transaction; it is submitted by the retry job
+func adjustInvoiceForRefund(tx *gorm.DB, booking *models.Booking, refund models.Money) error {
+	_, err := newInvoiceService(tx).AdjustForRefund(booking, refund, time.Now())
+	return err
+}
+
+// findAdminInvoice loads the invoice in the :id parameter, writing the error response if needed
+func findAdminInvoice(c *gin.Context) (*models.Invoice, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID không hợp lệ"})
+		return nil, false
+	}
+
+	invoice, err := repository.NewInvoiceRepository(operatorDB(c)).FindByID(uint(id))
+	if err != nil {
+		respondInvoiceError(c, err)
+		return nil, false
+	}
+	return invoice, true
+}
+
+// sendInvoiceXML writes the e-invoice XML as a download
+func sendInvoiceXML(c *gin.Context, invoice *models.Invoice) {
+	document, err := services.BuildInvoiceXML(invoice)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+	c.Header("Content-Disposition", "attachment; filename="+invoiceFilename(invoice, "xml"))
+	c.Data(http.StatusOK, "application/xml; charset=utf-8", document)
+}
+
+// sendInvoicePDF writes the printable copy of an invoice as a download
+func sendInvoicePDF(c *gin.Context, invoice *models.Invoice) {
+	c.Header("Content-Disposition", "attachment; filename="+invoiceFilename(invoice, "pdf"))
+	c.Data(http.StatusOK, "application/pdf", services.RenderInvoicePDF(invoice))
+}
+
+// invoiceFilename names a downloaded invoice after its series and number
+func invoiceFilename(invoice *models.Invoice, ext string) string {
+	return fmt.Sprintf("hoa-don-%s%s-%07d.%s", invoice.TemplateCode, invoice.Series, invoice.Number, ext)
+}
+
+// newInvoiceService creates an invoice service backed by db
+func newInvoiceService(db *gorm.DB) *services.InvoiceService {
+	return services.NewInvoiceService(
+		repository.NewInvoiceRepository(db),
+		repository.NewOperatorRepository(db),
+		services.NewInvoiceProviderFromEnv(),
+		services.InvoiceConfigFromEnv(),
+	)
+}
+
+// respondInvoiceError maps invoice errors to API responses
+func respondInvoiceError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy hóa đơn"})
+	case errors.Is(err, services.ErrInvoiceBookingNotPaid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Chỉ xuất hóa đơn cho đơn đã thanh toán"})
+	case errors.Is(err, services.ErrInvoiceExists):
+		c.JSON(http.StatusConflict, gin.H{"error": "Đơn đặt vé đã được xuất hóa đơn"})
+	case errors.Is(err, services.ErrSellerTaxCodeMissing):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nhà xe chưa đăng ký mã số thuế để xuất hóa đơn"})
+	case errors.Is(err, services.ErrInvoiceNotReplaceable):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Hóa đơn đã bị điều chỉnh hoặc thay thế, không thể thay thế"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+	}
+}
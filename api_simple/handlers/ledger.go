@@ -0,0 +1,302 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ticket-management/api_simple/config"
+	"ticket-management/api_simple/middleware"
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+	"ticket-management/api_simple/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Payout methods: cash from the counter or a bank transfer
+const (
+	payoutCash     = "cash"
+	payoutTransfer = "transfer"
+)
+
+type RefundPayoutRequest struct {
+	BookingID uint   `json:"booking_id" binding:"required"`                  // Đơn đặt vé được hoàn tiền
+	Method    string `json:"method" binding:"omitempty,oneof=cash transfer"` // Hình thức chi, mặc định tiền mặt
+}
+
+type CommissionPayoutRequest struct {
+	APIClientID uint         `json:"api_client_id" binding:"required"`               // Đại lý
+	Amount      models.Money `json:"amount" binding:"gte=0"`                         // Số tiền chi, bỏ trống để chi toàn bộ
+	Method      string       `json:"method" binding:"omitempty,oneof=cash transfer"` // Hình thức chi, mặc định chuyển khoản
+}
+
+// AccountSummary is an account of the chart with its postings over a period
+type AccountSummary struct {
+	models.LedgerAccountInfo
+	Debit   models.Money `json:"debit"`
+	Credit  models.Money `json:"credit"`
+	Balance models.Money `json:"balance"` // Số dư theo bên thông thường của tài khoản
+}
+
+// GetLedgerAccounts returns the trial balance of the chart of accounts over a period (admin)
+func GetLedgerAccounts(c *gin.Context) {
+	from, to, ok := ledgerPeriod(c)
+	if !ok {
+		return
+	}
+
+	ledgerRepo := repository.NewLedgerRepository(operatorDB(c))
+	balances, err := ledgerRepo.Balances(repository.LedgerFilter{From: from, To: to})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	posted := make(map[models.LedgerAccount]repository.AccountBalance, len(balances))
+	for _, balance := range balances {
+		posted[balance.Account] = balance
+	}
+
+	accounts := make([]AccountSummary, 0, len(models.ChartOfAccounts))
+	var revenue models.Money
+	for _, info := range models.ChartOfAccounts {
+		summary := AccountSummary{LedgerAccountInfo: info, Debit: posted[info.Code].Debit, Credit: posted[info.Code].Credit}
+		summary.Balance = summary.Debit - summary.Credit
+		if info.Kind == models.AccountKindLiability || info.Kind == models.AccountKindRevenue {
+			summary.Balance = -summary.Balance
+		}
+		if info.Kind == models.AccountKindRevenue {
+			revenue += summary.Balance
+		}
+		accounts = append(accounts, summary)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"accounts": accounts,
+		"revenue":  revenue,
+		"from":     from,
+		"to":       to,
+	})
+}
+
+// GetLedgerTransactions lists journal entries with their lines (admin)
+func GetLedgerTransactions(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	filters := make(map[string]interface{})
+	if kind := c.Query("kind"); kind != "" {
+		filters["kind"] = kind
+	}
+	if bookingID := c.Query("booking_id"); bookingID != "" {
+		id, err := strconv.ParseUint(bookingID, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "booking_id không hợp lệ"})
+			return
+		}
+		filters["booking_id"] = uint(id)
+	}
+	if clientID := c.Query("api_client_id"); clientID != "" {
+		id, err := strconv.ParseUint(clientID, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "api_client_id không hợp lệ"})
+			return
+		}
+		filters["api_client_id"] = uint(id)
+	}
+	from, to, ok := ledgerPeriod(c)
+	if !ok {
+		return
+	}
+
+	transactions, total, err := repository.NewLedgerRepository(operatorDB(c)).FindTransactions(filters, from, to, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"transactions": transactions,
+		"total":        total,
+		"page":         page,
+		"limit":        limit,
+	})
+}
+
+// CreateRefundPayout records the refund owed on a cancelled booking being paid back (admin)
+func CreateRefundPayout(c *gin.Context) {
+	var req RefundPayoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng điền đầy đủ thông tin"})
+		return
+	}
+	if req.Method == "" {
+		req.Method = payoutCash
+	}
+
+	db := operatorDB(c)
+	booking, err := repository.NewBookingRepository(db).FindByID(req.BookingID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy đơn đặt vé"})
+		return
+	}
+
+	user := c.MustGet("user").(*models.User)
+	var transaction *models.LedgerTransaction
+	err = db.Transaction(func(tx *gorm.DB) error {
+		transaction, err = newLedgerService(tx).PayOutRefund(booking, payoutAccount(req.Method), &user.ID, time.Now())
+		if err != nil || req.Method != payoutCash {
+			return err
+		}
+		// Cash paid back at the counter leaves the drawer of the open shift
+		return newCashShiftService(tx).RecordIfOpen(user.ID, models.CashTransactionRefund, transaction.Entries[0].Debit, &booking.ID, booking.BookingCode)
+	})
+	if err != nil {
+		respondLedgerError(c, err)
+		return
+	}
+
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "ledger.refund_payout",
+		EntityType: "ledger_transactions",
+		EntityID:   transaction.ID,
+		After:      transaction,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":     "Ghi nhận chi hoàn tiền thành công",
+		"transaction": transaction,
+	})
+}
+
+// CreateCommissionPayout records commission being paid to an agency (admin)
+func CreateCommissionPayout(c *gin.Context) {
+	operatorID, ok := requireOperator(c)
+	if !ok {
+		return
+	}
+
+	var req CommissionPayoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng điền đầy đủ thông tin"})
+		return
+	}
+	if req.Method == "" {
+		req.Method = payoutTransfer
+	}
+
+	client, err := repository.NewAPIClientRepository(config.DB).FindByID(req.APIClientID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy đại lý"})
+		return
+	}
+
+	user := c.MustGet("user").(*models.User)
+	transaction, err := newLedgerService(operatorDB(c)).PayOutCommission(operatorID, client, req.Amount, payoutAccount(req.Method), &user.ID, time.Now())
+	if err != nil {
+		respondLedgerError(c, err)
+		return
+	}
+
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "ledger.commission_payout",
+		EntityType: "ledger_transactions",
+		EntityID:   transaction.ID,
+		After:      transaction,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":     "Ghi nhận chi hoa hồng thành công",
+		"transaction": transaction,
+	})
+}
+
+// BackfillLedger posts bookings made before the ledger existed (super admin)
+func BackfillLedger(c *gin.Context) {
+	posted, err := newLedgerService(config.DB).Backfill(500)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Ghi sổ bổ sung thành công",
+		"bookings": posted,
+	})
+}
+
+// refundFromDrawer pays the refund of a cancelled cash booking out of the drawer when the
+// current user is working a counter shift and records the payout in the ledger. Otherwise
+// the refund stays payable until paid back through CreateRefundPayout.
+func refundFromDrawer(c *gin.Context, db *gorm.DB, booking *models.Booking, refund models.Money) error {
+	if booking.PaymentType != models.PaymentTypeCash || refund <= 0 {
+		return nil
+	}
+	user := c.MustGet("user").(*models.User)
+	if _, err := newCashShiftService(db).Current(user.ID); errors.Is(err, services.ErrNoOpenShift) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if err := recordDrawerCash(c, db, models.CashTransactionRefund, booking, refund); err != nil {
+		return err
+	}
+	_, err := newLedgerService(db).PayOutRefund(booking, models.AccountCash, &user.ID, time.Now())
+	return err
+}
+
+// payoutAccount returns the account a payout made with method is paid from
+func payoutAccount(method string) models.LedgerAccount {
+	if method == payoutCash {
+		return models.AccountCash
+	}
+	return models.AccountGatewayClearing
+}
+
+// ledgerPeriod reads the from/to query parameters, responding with an error when invalid
+func ledgerPeriod(c *gin.Context) (*time.Time, *time.Time, bool) {
+	from, err := parseTimeBound(c.Query("from"), false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from không hợp lệ"})
+		return nil, nil, false
+	}
+	to, err := parseTimeBound(c.Query("to"), true)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to không hợp lệ"})
+		return nil, nil, false
+	}
+	return from, to, true
+}
+
+// ledgerRevenue returns the revenue posted to the ledger in a period
+func ledgerRevenue(db *gorm.DB, from, to *time.Time) (models.Money, error) {
+	return repository.NewLedgerRepository(db).Revenue(repository.LedgerFilter{From: from, To: to})
+}
+
+// newLedgerService creates a ledger service backed by db
+func newLedgerService(db *gorm.DB) *services.LedgerService {
+	return services.NewLedgerService(repository.NewLedgerRepository(db))
+}
+
+// respondLedgerError maps ledger errors to API responses
+func respondLedgerError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrNothingToPayOut):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Không còn khoản nào phải trả"})
+	case errors.Is(err, services.ErrPayoutExceedsBalance):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Số tiền chi vượt quá số còn phải trả"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+	}
+}
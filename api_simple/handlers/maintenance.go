@@ -0,0 +1,373 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ticket-management/api_simple/middleware"
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+	"ticket-management/api_simple/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type MaintenanceRequest struct {
+	Type        string       `json:"type" binding:"required,oneof=service inspection registration repair"`
+	Description string       `json:"description"`
+	PerformedAt *time.Time   `json:"performed_at"` // Mặc định là thời điểm hiện tại
+	OdometerKm  int          `json:"odometer_km" binding:"gte=0"`
+	Cost        models.Money `json:"cost" binding:"gte=0"`
+	NextDueDate string       `json:"next_due_date"` // YYYY-MM-DD, bắt buộc với đăng kiểm và đăng ký
+	NextDueKm   int          `json:"next_due_km" binding:"gte=0"`
+}
+
+type BreakdownRequest struct {
+	Description string       `json:"description" binding:"required"`
+	OccurredAt  *time.Time   `json:"occurred_at"` // Mặc định là thời điểm hiện tại
+	OdometerKm  int          `json:"odometer_km" binding:"gte=0"`
+	Cost        models.Money `json:"cost" binding:"gte=0"`
+}
+
+type UnavailabilityRequest struct {
+	StartsAt time.Time  `json:"starts_at" binding:"required"`
+	EndsAt   *time.Time `json:"ends_at"`
+	Reason   string     `json:"reason" binding:"required"`
+}
+
+type ResolveUnavailabilityRequest struct {
+	EndsAt *time.Time `json:"ends_at"` // Mặc định là thời điểm hiện tại
+}
+
+// GetBusMaintenance lists the maintenance records of a bus
+func GetBusMaintenance(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID không hợp lệ"})
+		return
+	}
+
+	filters := make(map[string]interface{})
+	if recordType := c.Query("type"); recordType != "" {
+		filters["type"] = recordType
+	}
+
+	if _, err := repository.NewBusRepository(operatorDB(c)).FindByID(uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy xe"})
+		return
+	}
+
+	maintenanceRepo := repository.NewMaintenanceRepository(operatorDB(c))
+	records, err := maintenanceRepo.FindRecordsByBus(uint(id), filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"records": records,
+		"total":   len(records),
+	})
+}
+
+// CreateBusMaintenance records a service, inspection, registration or repair of a bus
+func CreateBusMaintenance(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID không hợp lệ"})
+		return
+	}
+
+	var req MaintenanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng điền đầy đủ thông tin"})
+		return
+	}
+
+	record := &models.MaintenanceRecord{
+		BusID:       uint(id),
+		Type:        models.MaintenanceType(req.Type),
+		Description: req.Description,
+		PerformedAt: time.Now(),
+		OdometerKm:  req.OdometerKm,
+		Cost:        req.Cost,
+		NextDueKm:   req.NextDueKm,
+		RecordedBy:  currentUserID(c),
+	}
+	if req.PerformedAt != nil {
+		record.PerformedAt = *req.PerformedAt
+	}
+	if req.NextDueDate != "" {
+		due, err := time.ParseInLocation("2006-01-02", req.NextDueDate, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Ngày đến hạn không hợp lệ (YYYY-MM-DD)"})
+			return
+		}
+		// Papers stay valid until the end of their expiry date
+		due = due.AddDate(0, 0, 1)
+		record.NextDueAt = &due
+	}
+
+	if err := record.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	busRepo := repository.NewBusRepository(operatorDB(c))
+	before, err := busRepo.FindByID(record.BusID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy xe"})
+		return
+	}
+
+	err = operatorDB(c).Transaction(func(tx *gorm.DB) error {
+		return newMaintenanceService(tx).Record(record)
+	})
+	if err != nil {
+		respondMaintenanceError(c, err)
+		return
+	}
+
+	after, _ := busRepo.FindByID(record.BusID)
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "bus.record_maintenance",
+		EntityType: "buses",
+		EntityID:   record.BusID,
+		Before:     before,
+		After:      after,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Ghi nhận bảo dưỡng thành công",
+		"record":  record,
+	})
+}
+
+// ReportBusBreakdown records a breakdown and takes the bus out of service until it is resolved
+func ReportBusBreakdown(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID không hợp lệ"})
+		return
+	}
+
+	var req BreakdownRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng mô tả sự cố"})
+		return
+	}
+
+	record := &models.MaintenanceRecord{
+		BusID:       uint(id),
+		Type:        models.MaintenanceTypeBreakdown,
+		Description: req.Description,
+		PerformedAt: time.Now(),
+		OdometerKm:  req.OdometerKm,
+		Cost:        req.Cost,
+		RecordedBy:  currentUserID(c),
+	}
+	if req.OccurredAt != nil {
+		record.PerformedAt = *req.OccurredAt
+	}
+	if err := record.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var window *models.BusUnavailability
+	var affected []models.Trip
+	err = operatorDB(c).Transaction(func(tx *gorm.DB) error {
+		var err error
+		window, affected, err = newMaintenanceService(tx).ReportBreakdown(record)
+		return err
+	})
+	if err != nil {
+		respondMaintenanceError(c, err)
+		return
+	}
+
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "bus.report_breakdown",
+		EntityType: "buses",
+		EntityID:   record.BusID,
+		After:      window,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":        "Đã ghi nhận xe hỏng, xe tạm ngừng hoạt động",
+		"record":         record,
+		"unavailability": window,
+		"affected_trips": affected,
+	})
+}
+
+// CreateBusUnavailability blocks a bus from trip assignment for a planned period
+func CreateBusUnavailability(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID không hợp lệ"})
+		return
+	}
+
+	var req UnavailabilityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng điền đầy đủ thông tin"})
+		return
+	}
+
+	window := &models.BusUnavailability{
+		BusID:    uint(id),
+		StartsAt: req.StartsAt,
+		EndsAt:   req.EndsAt,
+		Reason:   req.Reason,
+	}
+	if err := window.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	affected, err := newMaintenanceService(operatorDB(c)).Schedule(window)
+	if err != nil {
+		respondMaintenanceError(c, err)
+		return
+	}
+
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "bus.schedule_unavailability",
+		EntityType: "buses",
+		EntityID:   window.BusID,
+		After:      window,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":        "Đã lên lịch ngừng hoạt động cho xe",
+		"unavailability": window,
+		"affected_trips": affected,
+	})
+}
+
+// ResolveBusUnavailability ends an unavailability window so the bus can be scheduled again
+func ResolveBusUnavailability(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID không hợp lệ"})
+		return
+	}
+	windowID, err := strconv.ParseUint(c.Param("window_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID không hợp lệ"})
+		return
+	}
+
+	var req ResolveUnavailabilityRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dữ liệu không hợp lệ"})
+		return
+	}
+	endsAt := time.Now()
+	if req.EndsAt != nil {
+		endsAt = *req.EndsAt
+	}
+
+	window, err := newMaintenanceService(operatorDB(c)).Resolve(uint(id), uint(windowID), endsAt)
+	if err != nil {
+		respondMaintenanceError(c, err)
+		return
+	}
+
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "bus.resolve_unavailability",
+		EntityType: "buses",
+		EntityID:   window.BusID,
+		After:      window,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Xe đã hoạt động trở lại",
+		"unavailability": window,
+	})
+}
+
+// GetFleetAlerts lists inspections, registrations and services that are due soon or overdue
+func GetFleetAlerts(c *gin.Context) {
+	alerts, err := newMaintenanceService(operatorDB(c)).Alerts(time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"alerts": alerts,
+		"total":  len(alerts),
+	})
+}
+
+// GetBusStatistics returns trip and revenue statistics of a bus with its downtime
+// and maintenance cost over the last `days` days (default 30)
+func GetBusStatistics(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID không hợp lệ"})
+		return
+	}
+
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if days < 1 || days > 366 {
+		days = 30
+	}
+	now := time.Now()
+
+	report, err := newMaintenanceService(operatorDB(c)).Report(uint(id), now.AddDate(0, 0, -days), now, now)
+	if err != nil {
+		respondMaintenanceError(c, err)
+		return
+	}
+
+	stats, err := repository.NewBusRepository(operatorDB(c)).GetBusStatistics(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+	stats["maintenance"] = report
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// currentUserID returns the ID of the authenticated user, if any
+func currentUserID(c *gin.Context) *uint {
+	if user, exists := c.Get("user"); exists {
+		if u, ok := user.(*models.User); ok {
+			return &u.ID
+		}
+	}
+	return nil
+}
+
+// newMaintenanceService creates a maintenance service backed by db
+func newMaintenanceService(db *gorm.DB) *services.MaintenanceService {
+	return services.NewMaintenanceService(
+		repository.NewMaintenanceRepository(db),
+		repository.NewBusRepository(db),
+		repository.NewTripRepository(db),
+		services.MaintenanceConfigFromEnv(),
+	)
+}
+
+// respondMaintenanceError maps maintenance errors to API responses
+func respondMaintenanceError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy xe hoặc lịch ngừng hoạt động"})
+	case errors.Is(err, services.ErrUnavailabilityEnded):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Lịch ngừng hoạt động đã kết thúc"})
+	case errors.Is(err, services.ErrInvalidWindowEnd):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Thời điểm kết thúc phải sau thời điểm bắt đầu"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+	}
+}
@@ -0,0 +1,431 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"ticket-management/api_simple/config"
+	"ticket-management/api_simple/middleware"
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+	"ticket-management/api_simple/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type OperatorRequest struct {
+	Code                 string   `json:"code"`                                                     // Chỉ quản trị hệ thống được đổi
+	Name                 string   `json:"name" binding:"required"`                                  // Tên nhà xe
+	Phone                string   `json:"phone"`                                                    // Tổng đài
+	Email                string   `json:"email"`                                                    // Email liên hệ
+	Address              string   `json:"address"`                                                  // Địa chỉ văn phòng
+	TaxCode              string   `json:"tax_code"`                                                 // Mã số thuế
+	IsActive             *bool    `json:"is_active"`                                                // Chỉ quản trị hệ thống được đổi
+	LogoURL              string   `json:"logo_url"`                                                 // Logo
+	PrimaryColor         string   `json:"primary_color"`                                            // Màu thương hiệu (#RRGGBB)
+	TicketFooter         string   `json:"ticket_footer"`                                            // Lời nhắn in cuối vé
+	FullRefundHours      *int     `json:"full_refund_hours" binding:"omitempty,gte=0"`              // Hoàn 100% nếu hủy trước số giờ này
+	PartialRefundHours   *int     `json:"partial_refund_hours" binding:"omitempty,gte=0"`           // Hoàn một phần nếu hủy trước số giờ này
+	PartialRefundPercent *float64 `json:"partial_refund_percent" binding:"omitempty,gte=0,lte=100"` // Tỷ lệ hoàn một phần (%)
+}
+
+// apply copies the request onto the operator; the code and status are platform settings
+func (req *OperatorRequest) apply(operator *models.Operator, platform bool) {
+	if platform {
+		if req.Code != "" {
+			operator.Code = req.Code
+		}
+		if req.IsActive != nil {
+			operator.IsActive = *req.IsActive
+		}
+	}
+	operator.Name = req.Name
+	operator.Phone = req.Phone
+	operator.Email = req.Email
+	operator.Address = req.Address
+	operator.TaxCode = req.TaxCode
+	operator.LogoURL = req.LogoURL
+	operator.PrimaryColor = req.PrimaryColor
+	operator.TicketFooter = req.TicketFooter
+	if req.FullRefundHours != nil {
+		operator.FullRefundHours = *req.FullRefundHours
+	}
+	if req.PartialRefundHours != nil {
+		operator.PartialRefundHours = *req.PartialRefundHours
+	}
+	if req.PartialRefundPercent != nil {
+		operator.PartialRefundPercent = *req.PartialRefundPercent
+	}
+}
+
+type PaymentAccountRequest struct {
+	Method        models.PaymentAccountMethod `json:"method" binding:"required,oneof=bank_transfer momo zalopay vnpay"` // Hình thức nhận tiền
+	Provider      string                      `json:"provider"`                                                         // Ngân hàng / nhà cung cấp ví
+	AccountNumber string                      `json:"account_number" binding:"required"`                                // Số tài khoản
+	AccountName   string                      `json:"account_name"`                                                     // Chủ tài khoản
+	IsDefault     bool                        `json:"is_default"`                                                       // Tài khoản mặc định
+	IsActive      *bool                       `json:"is_active"`                                                        // Còn sử dụng (mặc định: có)
+}
+
+// apply copies the request onto the account
+func (req *PaymentAccountRequest) apply(account *models.OperatorPaymentAccount) {
+	account.Method = req.Method
+	account.Provider = req.Provider
+	account.AccountNumber = req.AccountNumber
+	account.AccountName = req.AccountName
+	account.IsDefault = req.IsDefault
+	if req.IsActive != nil {
+		account.IsActive = *req.IsActive
+	}
+}
+
+// GetOperators lists the operators selling tickets on the platform with their branding
+func GetOperators(c *gin.Context) {
+	operators, err := repository.NewOperatorRepository(config.DB).FindActive()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"operators": operators,
+		"total":     len(operators),
+	})
+}
+
+// GetOperator returns the public profile of an operator with its refund policy and payment accounts
+func GetOperator(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID không hợp lệ"})
+		return
+	}
+
+	operator, err := repository.NewOperatorRepository(config.DB).FindWithAccounts(uint(id))
+	if err != nil || !operator.IsActive {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy nhà xe"})
+		return
+	}
+
+	c.JSON(http.StatusOK, operator)
+}
+
+// GetAdminOperators lists every operator, including suspended ones (super admin)
+func GetAdminOperators(c *gin.Context) {
+	operators, err := repository.NewOperatorRepository(config.DB).FindAll(nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"operators": operators,
+		"total":     len(operators),
+	})
+}
+
+// CreateOperator registers a bus operator on the platform (super admin)
+func CreateOperator(c *gin.Context) {
+	var req OperatorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng điền đầy đủ thông tin"})
+		return
+	}
+
+	operator := &models.Operator{
+		IsActive:             true,
+		FullRefundHours:      24,
+		PartialRefundHours:   4,
+		PartialRefundPercent: 50,
+	}
+	req.apply(operator, true)
+	if err := operator.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := newOperatorService(config.DB).Save(operator); err != nil {
+		respondOperatorError(c, err)
+		return
+	}
+
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "operator.create",
+		EntityType: "operators",
+		EntityID:   operator.ID,
+		After:      operator,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Tạo nhà xe thành công",
+		"operator": operator,
+	})
+}
+
+// UpdateOperator changes any setting of an operator, including its code and status (super admin)
+func UpdateOperator(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID không hợp lệ"})
+		return
+	}
+	saveOperator(c, uint(id), true)
+}
+
+// GetCurrentOperator returns the settings of the operator the admin works for
+func GetCurrentOperator(c *gin.Context) {
+	operatorID, ok := requireOperator(c)
+	if !ok {
+		return
+	}
+
+	operator, err := repository.NewOperatorRepository(config.DB).FindByID(operatorID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy nhà xe"})
+		return
+	}
+
+	c.JSON(http.StatusOK, operator)
+}
+
+// UpdateCurrentOperator changes the contact details, branding and refund policy of the admin's operator
+func UpdateCurrentOperator(c *gin.Context) {
+	operatorID, ok := requireOperator(c)
+	if !ok {
+		return
+	}
+	saveOperator(c, operatorID, false)
+}
+
+// saveOperator applies an OperatorRequest to the operator with the given ID
+func saveOperator(c *gin.Context, id uint, platform bool) {
+	var req OperatorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng điền đầy đủ thông tin"})
+		return
+	}
+
+	operator, err := repository.NewOperatorRepository(config.DB).FindByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy nhà xe"})
+		return
+	}
+
+	before := *operator
+	req.apply(operator, platform)
+	if err := operator.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := newOperatorService(config.DB).Save(operator); err != nil {
+		respondOperatorError(c, err)
+		return
+	}
+
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "operator.update",
+		EntityType: "operators",
+		EntityID:   operator.ID,
+		Before:     before,
+		After:      operator,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Cập nhật nhà xe thành công",
+		"operator": operator,
+	})
+}
+
+// GetPaymentAccounts lists the payment accounts of the admin's operator
+func GetPaymentAccounts(c *gin.Context) {
+	operatorID, ok := requireOperator(c)
+	if !ok {
+		return
+	}
+
+	accounts, err := repository.NewPaymentAccountRepository(operatorDB(c)).FindByOperator(operatorID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"accounts": accounts,
+		"total":    len(accounts),
+	})
+}
+
+// CreatePaymentAccount adds an account where the operator receives payments
+func CreatePaymentAccount(c *gin.Context) {
+	operatorID, ok := requireOperator(c)
+	if !ok {
+		return
+	}
+
+	var req PaymentAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng điền đầy đủ thông tin"})
+		return
+	}
+
+	account := &models.OperatorPaymentAccount{OperatorID: operatorID, IsActive: true}
+	req.apply(account)
+	if err := account.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := newOperatorService(operatorDB(c)).SaveAccount(account); err != nil {
+		respondOperatorError(c, err)
+		return
+	}
+
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "payment_account.create",
+		EntityType: "payment_accounts",
+		EntityID:   account.ID,
+		After:      account,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Thêm tài khoản nhận tiền thành công",
+		"account": account,
+	})
+}
+
+// UpdatePaymentAccount replaces the details of a payment account of the operator
+func UpdatePaymentAccount(c *gin.Context) {
+	account, ok := findPaymentAccount(c)
+	if !ok {
+		return
+	}
+
+	var req PaymentAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng điền đầy đủ thông tin"})
+		return
+	}
+
+	before := *account
+	req.apply(account)
+	if err := account.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := newOperatorService(operatorDB(c)).SaveAccount(account); err != nil {
+		respondOperatorError(c, err)
+		return
+	}
+
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "payment_account.update",
+		EntityType: "payment_accounts",
+		EntityID:   account.ID,
+		Before:     before,
+		After:      account,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Cập nhật tài khoản nhận tiền thành công",
+		"account": account,
+	})
+}
+
+// DeletePaymentAccount removes a payment account of the operator
+func DeletePaymentAccount(c *gin.Context) {
+	account, ok := findPaymentAccount(c)
+	if !ok {
+		return
+	}
+
+	if err := repository.NewPaymentAccountRepository(operatorDB(c)).Delete(account.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "payment_account.delete",
+		EntityType: "payment_accounts",
+		EntityID:   account.ID,
+		Before:     account,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Xóa tài khoản nhận tiền thành công"})
+}
+
+// findPaymentAccount loads the :id payment account of the operator the request acts for
+func findPaymentAccount(c *gin.Context) (*models.OperatorPaymentAccount, bool) {
+	if _, ok := requireOperator(c); !ok {
+		return nil, false
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID không hợp lệ"})
+		return nil, false
+	}
+
+	account, err := repository.NewPaymentAccountRepository(operatorDB(c)).FindByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy tài khoản nhận tiền"})
+		return nil, false
+	}
+	return account, true
+}
+
+// operatorDB returns the database restricted to the operator the request acts for.
+// Super admins who have not picked an operator get the whole platform.
+func operatorDB(c *gin.Context) *gorm.DB {
+	if operatorID, ok := middleware.OperatorID(c); ok {
+		return repository.WithOperator(config.DB, operatorID)
+	}
+	return config.DB
+}
+
+// requireOperator returns the operator the request acts for and responds with
+// an error when a super admin has not picked one
+func requireOperator(c *gin.Context) (uint, bool) {
+	operatorID, ok := middleware.OperatorID(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng chọn nhà xe qua header " + middleware.OperatorHeader})
+		return 0, false
+	}
+	return operatorID, true
+}
+
+// isSuperAdmin reports whether the request comes from a platform super admin
+func isSuperAdmin(c *gin.Context) bool {
+	user, exists := c.Get("user")
+	return exists && user.(*models.User).Role == models.RoleSuperAdmin
+}
+
+// newOperatorService creates an operator service backed by db
+func newOperatorService(db *gorm.DB) *services.OperatorService {
+	return services.NewOperatorService(
+		repository.NewOperatorRepository(db),
+		repository.NewPaymentAccountRepository(db),
+		repository.NewRouteRepository(db),
+		repository.NewBusRepository(db),
+		repository.NewUserRepository(db),
+		repository.NewBookingRepository(db),
+	)
+}
+
+// respondOperatorError maps operator errors to API responses
+func respondOperatorError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy tuyến, xe hoặc tài xế của nhà xe"})
+	case errors.Is(err, services.ErrOperatorCodeTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": "Mã nhà xe đã được sử dụng"})
+	case errors.Is(err, services.ErrOperatorMismatch):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tuyến, xe và tài xế phải thuộc cùng một nhà xe"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+	}
+}
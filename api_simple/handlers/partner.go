@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"ticket-management/api_simple/config"
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+	"ticket-management/api_simple/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type PartnerBookingRequest struct {
+	TripID    uint              `json:"trip_id" binding:"required"`
+	SeatIDs   []int64           `json:"seat_ids" binding:"required,min=1"`
+	GuestInfo *models.GuestInfo `json:"guest_info" binding:"required"` // Khách hàng của đại lý
+	Note      string            `json:"note"`
+
+	PickupPointID  *uint `json:"pickup_point_id"`  // Điểm đón
+	DropoffPointID *uint `json:"dropoff_point_id"` // Điểm trả
+}
+
+// CreatePartnerBooking creates a booking on behalf of a partner agency's customer
+func CreatePartnerBooking(c *gin.Context) {
+	var req PartnerBookingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng điền đầy đủ thông tin"})
+		return
+	}
+
+	client := c.MustGet("api_client").(*models.APIClient)
+
+	if err := validateGuestInfo(req.GuestInfo); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	bookingRepo := repository.NewBookingRepository(config.DB)
+	tripRepo := repository.NewTripRepository(config.DB)
+	seatRepo := repository.NewSeatRepository(config.DB)
+
+	trip, err := tripRepo.FindByID(req.TripID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy chuyến đi"})
+		return
+	}
+	if !trip.IsActive || trip.IsCompleted {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Chuyến đi không khả dụng"})
+		return
+	}
+
+	seats, err := seatRepo.FindByIDs(req.TripID, req.SeatIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+	if len(seats) != len(req.SeatIDs) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Một số ghế không tồn tại"})
+		return
+	}
+	for _, seat := range seats {
+		if seat.Status != models.SeatStatusAvailable {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Một số ghế đã được đặt"})
+			return
+		}
+	}
+
+	conflictingBookings, err := bookingRepo.FindConflictingBookings(req.TripID, req.SeatIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+	if len(conflictingBookings) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Một số ghế đã được đặt"})
+		return
+	}
+
+	var totalAmount models.Money
+	for _, seat := range seats {
+		totalAmount += seat.Price
+	}
+
+	selection, ok := selectBoardingPoints(c, trip, req.PickupPointID, req.DropoffPointID, len(req.SeatIDs))
+	if !ok {
+		return
+	}
+
+	clientID := client.ID
+	booking := &models.Booking{
+		OperatorID:       trip.OperatorID,
+		GuestInfo:        req.GuestInfo,
+		TripID:           req.TripID,
+		SeatIDs:          req.SeatIDs,
+		TotalAmount:      totalAmount,
+		PaymentType:      models.PaymentTypeCash,
+		PaymentStatus:    models.PaymentStatusUnpaid,
+		Status:           models.BookingStatusPending,
+		Channel:          models.BookingChannelPartner,
+		Note:             req.Note,
+		APIClientID:      &clientID,
+		CommissionAmount: totalAmount.Mul(client.CommissionRate),
+	}
+	// Commission is paid on the fare only, not on the shuttle surcharge
+	selection.Apply(booking)
+
+	if err := booking.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := repository.NewBookingRepository(tx).Create(booking); err != nil {
+			return err
+		}
+
+		txSeatRepo := repository.NewSeatRepository(tx)
+		for _, seatID := range req.SeatIDs {
+			if err := txSeatRepo.UpdateStatus(uint(seatID), models.SeatStatusBooked); err != nil {
+				return err
+			}
+		}
+
+		trip.BookedSeats += len(req.SeatIDs)
+		if err := repository.NewTripRepository(tx).Update(trip); err != nil {
+			return err
+		}
+
+		// Revenue and the agency's commission are booked when the seats are sold
+		return newLedgerService(tx).RecordSale(booking, time.Now())
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	notifySeats(booking.TripID, services.SeatEventBooked, models.SeatStatusBooked, booking.SeatIDs, nil)
+	booking.SetBoardingTimes(trip.DepartureTime)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Đặt vé thành công",
+		"booking": booking,
+	})
+}
+
+// GetPartnerBookings lists the bookings made by the calling agency
+func GetPartnerBookings(c *gin.Context) {
+	client := c.MustGet("api_client").(*models.APIClient)
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	bookingRepo := repository.NewBookingRepository(config.DB)
+	bookings, total, err := bookingRepo.FindByAPIClientID(client.ID, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	commission, err := bookingRepo.SumCommissionByAPIClient(client.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"bookings":         bookings,
+		"total":            total,
+		"total_commission": commission,
+	})
+}
+
+// PartnerCancelBooking cancels a pending booking made by the calling agency
+func PartnerCancelBooking(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID không hợp lệ"})
+		return
+	}
+
+	client := c.MustGet("api_client").(*models.APIClient)
+
+	bookingRepo := repository.NewBookingRepository(config.DB)
+	booking, err := bookingRepo.FindByID(uint(id))
+	if err != nil || booking.APIClientID == nil || *booking.APIClientID != client.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy đơn đặt vé"})
+		return
+	}
+
+	if booking.Status == models.BookingStatusCancelled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Đơn đã được hủy"})
+		return
+	}
+	if booking.Status == models.BookingStatusConfirmed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Không thể hủy đơn đã xác nhận"})
+		return
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := repository.NewBookingRepository(tx).UpdateStatus(booking.ID, models.BookingStatusCancelled); err != nil {
+			return err
+		}
+
+		seatRepo := repository.NewSeatRepository(tx)
+		for _, seatID := range booking.SeatIDs {
+			if err := seatRepo.UpdateStatus(uint(seatID), models.SeatStatusAvailable); err != nil {
+				return err
+			}
+		}
+
+		tripRepo := repository.NewTripRepository(tx)
+		trip, err := tripRepo.FindByID(booking.TripID)
+		if err != nil {
+			return err
+		}
+		trip.BookedSeats -= len(booking.SeatIDs)
+		if err := tripRepo.Update(trip); err != nil {
+			return err
+		}
+
+		// Refund a paid booking according to the operator's policy, post the cancellation
+		// and adjust its invoice
+		refund, err := newOperatorService(tx).Refund(booking, trip.DepartureTime, time.Now())
+		if err != nil {
+			return err
+		}
+		if err := newLedgerService(tx).RecordCancellation(booking, time.Now()); err != nil {
+			return err
+		}
+		return adjustInvoiceForRefund(tx, booking, refund)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	notifySeats(booking.TripID, services.SeatEventReleased, models.SeatStatusAvailable, booking.SeatIDs, nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Hủy đơn thành công",
+		"refund_amount": booking.RefundAmount,
+	})
+}
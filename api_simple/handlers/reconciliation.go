@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ticket-management/api_simple/middleware"
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+	"ticket-management/api_simple/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxSettlementFileSize limits uploaded settlement files (5 MB)
+const maxSettlementFileSize = 5 << 20
+
+type ResolveSettlementEntryRequest struct {
+	Action    string `json:"action" binding:"required,oneof=accept link"`  // Cách xử lý
+	BookingID uint   `json:"booking_id" binding:"required_if=Action link"` // Đơn đặt vé cần gắn (khi link)
+	Note      string `json:"note" binding:"max=500"`                       // Giải trình
+}
+
+// ImportSettlementReport reconciles a settlement file uploaded as multipart form data
+// with the fields file and provider (admin)
+func ImportSettlementReport(c *gin.Context) {
+	operatorID, ok := requireOperator(c)
+	if !ok {
+		return
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng chọn file quyết toán"})
+		return
+	}
+	if header.Size > maxSettlementFileSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File quyết toán không được vượt quá 5MB"})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	user := c.MustGet("user").(*models.User)
+	provider := models.PaymentAccountMethod(c.PostForm("provider"))
+	report, err := newReconciliationService(operatorDB(c)).Import(operatorID, provider, header.Filename, data, &user.ID)
+	if err != nil {
+		respondReconciliationError(c, err)
+		return
+	}
+
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "reconciliation.import",
+		EntityType: "settlement_reports",
+		EntityID:   report.ID,
+		After:      gin.H{"provider": report.Provider, "file_name": report.FileName, "row_count": report.RowCount, "matched_count": report.MatchedCount, "issue_count": report.IssueCount},
+	})
+
+	report.Entries = nil
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Đối soát file quyết toán thành công",
+		"report":  report,
+	})
+}
+
+// GetSettlementReports lists imported settlement files (admin)
+func GetSettlementReports(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	filters := make(map[string]interface{})
+	if provider := c.Query("provider"); provider != "" {
+		filters["provider"] = provider
+	}
+
+	reports, total, err := repository.NewReconciliationRepository(operatorDB(c)).FindReports(filters, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reports": reports,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
+}
+
+// GetSettlementReport returns the reconciliation report of a settlement file: totals by
+// outcome and the entries, optionally only one status or the open discrepancies (admin)
+func GetSettlementReport(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID không hợp lệ"})
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	reconRepo := repository.NewReconciliationRepository(operatorDB(c))
+	report, err := reconRepo.FindReportByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy file quyết toán"})
+		return
+	}
+
+	filters := make(map[string]interface{})
+	if status := c.Query("status"); status != "" {
+		filters["status"] = status
+	}
+	entries, total, err := reconRepo.FindEntries(report.ID, filters, c.Query("open") == "true", page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+	summary, err := reconRepo.SummarizeReport(report.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"report":  report,
+		"summary": summary,
+		"entries": entries,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
+}
+
+// ResolveSettlementEntry accepts a discrepancy with a note or links a settled
+// transaction to the right booking (admin)
+func ResolveSettlementEntry(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID không hợp lệ"})
+		return
+	}
+
+	var req ResolveSettlementEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng điền đầy đủ thông tin"})
+		return
+	}
+
+	db := operatorDB(c)
+	before, err := repository.NewReconciliationRepository(db).FindEntryByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy giao dịch đối soát"})
+		return
+	}
+
+	user := c.MustGet("user").(*models.User)
+	var entry *models.SettlementEntry
+	err = db.Transaction(func(tx *gorm.DB) error {
+		entry, err = newReconciliationService(tx).Resolve(before.ID, req.Action, req.BookingID, req.Note, user.ID, time.Now())
+		return err
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy đơn đặt vé"})
+		return
+	}
+	if err != nil {
+		respondReconciliationError(c, err)
+		return
+	}
+
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "reconciliation.resolve",
+		EntityType: "settlement_entries",
+		EntityID:   entry.ID,
+		Before:     before,
+		After:      entry,
+	})
+
+	message := "Xử lý chênh lệch thành công"
+	if entry.IsOpen() {
+		message = "Đã gắn đơn đặt vé nhưng giao dịch vẫn chưa khớp"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"entry":   entry,
+	})
+}
+
+// newReconciliationService creates a reconciliation service backed by db
+func newReconciliationService(db *gorm.DB) *services.ReconciliationService {
+	return services.NewReconciliationService(
+		repository.NewReconciliationRepository(db),
+		repository.NewBookingRepository(db),
+	)
+}
+
+// respondReconciliationError maps reconciliation errors to API responses
+func respondReconciliationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidSettlementProvider):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cổng thanh toán không hợp lệ"})
+	case errors.Is(err, services.ErrSettlementAlreadyImported):
+		c.JSON(http.StatusConflict, gin.H{"error": "File quyết toán này đã được nhập"})
+	case errors.Is(err, services.ErrSettlementEmpty):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File quyết toán không có giao dịch nào"})
+	case errors.Is(err, services.ErrInvalidSettlementFile):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File quyết toán không hợp lệ: " + err.Error()})
+	case errors.Is(err, services.ErrEntryNotOpen):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Giao dịch không có chênh lệch cần xử lý"})
+	case errors.Is(err, services.ErrResolutionNoteRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng nhập giải trình khi chấp nhận chênh lệch"})
+	case errors.Is(err, services.ErrLinkRequiresSettlement):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Chỉ giao dịch trong file quyết toán mới có thể gắn với đơn đặt vé"})
+	case errors.Is(err, services.ErrInvalidResolution):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cách xử lý không hợp lệ"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+	}
+}
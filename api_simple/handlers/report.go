@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+	"ticket-management/api_simple/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Export formats of the reports
+const (
+	reportFormatCSV  = "csv"
+	reportFormatXLSX = "xlsx"
+)
+
+// GetRevenueReport returns the revenue posted to the ledger grouped by day, week, month,
+// route, bus or sales channel
+func GetRevenueReport(c *gin.Context) {
+	filter, ok := parseReportFilter(c)
+	if !ok {
+		return
+	}
+	format, ok := reportFormat(c)
+	if !ok {
+		return
+	}
+
+	groupBy := c.DefaultQuery("group_by", string(repository.ReportDaily))
+	rows, err := newReportService(operatorDB(c)).Revenue(filter, groupBy)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidReportGroup) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "group_by không hợp lệ (day, week, month, route, bus, channel)"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	if format != "" {
+		header, cells := services.RevenueTable(groupBy, rows)
+		writeReport(c, format, "revenue-"+groupBy, header, cells)
+		return
+	}
+
+	var total int64
+	for _, row := range rows {
+		total += int64(row.Revenue)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"group_by": groupBy,
+		"rows":     rows,
+		"total":    total,
+	})
+}
+
+// GetOccupancyReport returns the load factor of the trips departing in a period, per trip or per route
+func GetOccupancyReport(c *gin.Context) {
+	filter, ok := parseReportFilter(c)
+	if !ok {
+		return
+	}
+	format, ok := reportFormat(c)
+	if !ok {
+		return
+	}
+
+	reportRepo := repository.NewReportRepository(operatorDB(c))
+	groupBy := c.DefaultQuery("group_by", "trip")
+	switch groupBy {
+	case "trip":
+		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+		if page < 1 {
+			page = 1
+		}
+		if limit < 1 || limit > 100 {
+			limit = 20
+		}
+		// Exports hold every trip of the period
+		if format != "" {
+			page, limit = 1, 0
+		}
+
+		rows, total, err := reportRepo.OccupancyByTrip(filter, page, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+			return
+		}
+		if format != "" {
+			header, cells := services.OccupancyTable(groupBy, rows)
+			writeReport(c, format, "occupancy-trip", header, cells)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"group_by": groupBy,
+			"rows":     rows,
+			"total":    total,
+			"page":     page,
+			"limit":    limit,
+		})
+	case "route":
+		rows, err := reportRepo.OccupancyByRoute(filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+			return
+		}
+		if format != "" {
+			header, cells := services.OccupancyTable(groupBy, rows)
+			writeReport(c, format, "occupancy-route", header, cells)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"group_by": groupBy,
+			"rows":     rows,
+		})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "group_by không hợp lệ (trip, route)"})
+	}
+}
+
+// GetReportSummary returns the revenue, bookings, cancellation rate, average lead time
+// and load factor of a period
+func GetReportSummary(c *gin.Context) {
+	filter, ok := parseReportFilter(c)
+	if !ok {
+		return
+	}
+
+	reportRepo := repository.NewReportRepository(operatorDB(c))
+	stats, err := reportRepo.BookingStats(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+	channels, err := reportRepo.RevenueByChannel(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+	routes, err := reportRepo.OccupancyByRoute(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	var revenue int64
+	for _, channel := range channels {
+		revenue += int64(channel.Revenue)
+	}
+	var trips, seats, booked int64
+	for _, route := range routes {
+		trips += route.Trips
+		seats += route.Seats
+		booked += route.Booked
+	}
+	loadFactor := 0.0
+	if seats > 0 {
+		loadFactor = float64(booked) / float64(seats)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"revenue":            revenue,
+		"revenue_by_channel": channels,
+		"bookings":           stats,
+		"trips":              trips,
+		"seats":              seats,
+		"booked_seats":       booked,
+		"load_factor":        loadFactor,
+	})
+}
+
+// parseReportFilter reads the period and the route and bus a report is restricted to
+func parseReportFilter(c *gin.Context) (repository.ReportFilter, bool) {
+	var filter repository.ReportFilter
+	from, to, ok := ledgerPeriod(c)
+	if !ok {
+		return filter, false
+	}
+	filter.From, filter.To = from, to
+
+	if routeID := c.Query("route_id"); routeID != "" {
+		id, err := strconv.ParseUint(routeID, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "route_id không hợp lệ"})
+			return filter, false
+		}
+		filter.RouteID = uint(id)
+	}
+	if busID := c.Query("bus_id"); busID != "" {
+		id, err := strconv.ParseUint(busID, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "bus_id không hợp lệ"})
+			return filter, false
+		}
+		filter.BusID = uint(id)
+	}
+	return filter, true
+}
+
+// reportFormat reads the export format of a report, empty for JSON
+func reportFormat(c *gin.Context) (string, bool) {
+	format := c.Query("format")
+	switch format {
+	case "", "json":
+		return "", true
+	case reportFormatCSV, reportFormatXLSX:
+		return format, true
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "format không hợp lệ (json, csv, xlsx)"})
+	return "", false
+}
+
+// writeReport sends a report as a CSV or XLSX attachment
+func writeReport(c *gin.Context, format, name string, header []string, rows [][]interface{}) {
+	filename := fmt.Sprintf("%s-%s.%s", name, time.Now().Format("20060102-150405"), format)
+
+	if format == reportFormatXLSX {
+		data, err := utils.RenderXLSX(name, header, rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+			return
+		}
+		c.Header("Content-Disposition", "attachment; filename="+filename)
+		c.Data(http.StatusOK, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", data)
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	writer := csv.NewWriter(c.Writer)
+	writer.Write(header)
+	for _, row := range rows {
+		record := make([]string, len(row))
+		for i, cell := range row {
+			record[i] = fmt.Sprint(cell)
+		}
+		writer.Write(record)
+	}
+	writer.Flush()
+}
+
+// newReportService creates a report service backed by db
+func newReportService(db *gorm.DB) *services.ReportService {
+	return services.NewReportService(repository.NewReportRepository(db), repository.NewBookingRepository(db))
+}
@@ -1,10 +1,12 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"ticket-management/api_simple/config"
+	"ticket-management/api_simple/middleware"
 	"ticket-management/api_simple/models"
 	"ticket-management/api_simple/repository"
 	"ticket-management/api_simple/utils"
@@ -13,36 +15,72 @@ import (
 )
 
 type CreateRouteRequest struct {
-	Origin      string  `json:"origin" binding:"required"`          // Điểm đi
-	Destination string  `json:"destination" binding:"required"`     // Điểm đến
-	Distance    float64 `json:"distance" binding:"required,gt=0"`   // Khoảng cách (km)
-	Duration    string  `json:"duration" binding:"required"`        // Thời gian di chuyển (VD: "4h30m")
-	BasePrice   float64 `json:"base_price" binding:"required,gt=0"` // Giá cơ bản
+	Origin      string       `json:"origin" binding:"required"`          // Điểm đi
+	Destination string       `json:"destination" binding:"required"`     // Điểm đến
+	Distance    float64      `json:"distance" binding:"required,gt=0"`   // Khoảng cách (km)
+	Duration    string       `json:"duration" binding:"required"`        // Thời gian di chuyển (VD: "4h30m")
+	BasePrice   models.Money `json:"base_price" binding:"required,gt=0"` // Giá cơ bản
+	RouteCoordinates
+}
+
+// RouteCoordinates are the optional GPS positions of a route's ends, used for trip ETAs
+type RouteCoordinates struct {
+	OriginLat      *float64 `json:"origin_lat" binding:"omitempty,gte=-90,lte=90"`
+	OriginLng      *float64 `json:"origin_lng" binding:"omitempty,gte=-180,lte=180"`
+	DestinationLat *float64 `json:"destination_lat" binding:"omitempty,gte=-90,lte=90"`
+	DestinationLng *float64 `json:"destination_lng" binding:"omitempty,gte=-180,lte=180"`
+}
+
+// apply copies the coordinates that were provided onto the route
+func (rc *RouteCoordinates) apply(route *models.Route) {
+	if rc.OriginLat != nil {
+		route.OriginLat = rc.OriginLat
+	}
+	if rc.OriginLng != nil {
+		route.OriginLng = rc.OriginLng
+	}
+	if rc.DestinationLat != nil {
+		route.DestinationLat = rc.DestinationLat
+	}
+	if rc.DestinationLng != nil {
+		route.DestinationLng = rc.DestinationLng
+	}
 }
 
 type UpdateRouteRequest struct {
-	Origin      string  `json:"origin"`                              // Điểm đi
-	Destination string  `json:"destination"`                         // Điểm đến
-	Distance    float64 `json:"distance" binding:"omitempty,gt=0"`   // Khoảng cách (km)
-	Duration    string  `json:"duration"`                            // Thời gian di chuyển
-	BasePrice   float64 `json:"base_price" binding:"omitempty,gt=0"` // Giá cơ bản
-	IsActive    *bool   `json:"is_active"`                           // Trạng thái hoạt động
+	Origin      string       `json:"origin"`                              // Điểm đi
+	Destination string       `json:"destination"`                         // Điểm đến
+	Distance    float64      `json:"distance" binding:"omitempty,gt=0"`   // Khoảng cách (km)
+	Duration    string       `json:"duration"`                            // Thời gian di chuyển
+	BasePrice   models.Money `json:"base_price" binding:"omitempty,gt=0"` // Giá cơ bản
+	IsActive    *bool        `json:"is_active"`                           // Trạng thái hoạt động
+	RouteCoordinates
 }
 
 type RouteResponse struct {
-	ID            uint    `json:"id"`
-	Origin        string  `json:"origin"`
-	Destination   string  `json:"destination"`
-	Distance      float64 `json:"distance"`
-	Duration      string  `json:"duration"`
-	BasePrice     float64 `json:"base_price"`
-	IsActive      bool    `json:"is_active"`
-	TotalTrips    int64   `json:"total_trips"`    // Tổng số chuyến
-	UpcomingTrips int64   `json:"upcoming_trips"` // Số chuyến sắp tới
-	MinPrice      float64 `json:"min_price"`      // Giá thấp nhất
-	MaxPrice      float64 `json:"max_price"`      // Giá cao nhất
-	CreatedAt     string  `json:"created_at"`
-	UpdatedAt     string  `json:"updated_at"`
+	ID            uint         `json:"id"`
+	OperatorID    uint         `json:"operator_id"` // Nhà xe khai thác
+	Origin        string       `json:"origin"`
+	Destination   string       `json:"destination"`
+	Distance      float64      `json:"distance"`
+	Duration      string       `json:"duration"`
+	BasePrice     models.Money `json:"base_price"`
+	IsActive      bool         `json:"is_active"`
+	TotalTrips    int64        `json:"total_trips"`    // Tổng số chuyến
+	UpcomingTrips int64        `json:"upcoming_trips"` // Số chuyến sắp tới
+	MinPrice      models.Money `json:"min_price"`      // Giá thấp nhất
+	MaxPrice      models.Money `json:"max_price"`      // Giá cao nhất
+	TotalBookings int64        `json:"total_bookings"` // Số đơn đặt vé chưa hủy
+	LoadFactor    float64      `json:"load_factor"`    // Tỷ lệ lấp đầy (0-1)
+	CreatedAt     string       `json:"created_at"`
+	UpdatedAt     string       `json:"updated_at"`
+
+	OriginLat      *float64 `json:"origin_lat,omitempty"`
+	OriginLng      *float64 `json:"origin_lng,omitempty"`
+	DestinationLat *float64 `json:"destination_lat,omitempty"`
+	DestinationLng *float64 `json:"destination_lng,omitempty"`
+
+	Currency models.Currency `json:"currency"` // Đơn vị tiền tệ của giá vé
 }
 
 // CreateRoute creates a new route
@@ -61,9 +99,17 @@ func CreateRoute(c *gin.Context) {
 		return
 	}
 
-	routeRepo := repository.NewRouteRepository(config.DB)
+	if _, err := models.ParseRouteDuration(req.Duration); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Thời gian di chuyển không hợp lệ (VD: 4h30m)"})
+		return
+	}
 
-	// Check if route exists
+	if _, ok := requireOperator(c); !ok {
+		return
+	}
+	routeRepo := repository.NewRouteRepository(operatorDB(c))
+
+	// Check if the operator already runs the route
 	exists, err := routeRepo.Exists(map[string]interface{}{
 		"origin":      req.Origin,
 		"destination": req.Destination,
@@ -85,12 +131,25 @@ func CreateRoute(c *gin.Context) {
 		BasePrice:   req.BasePrice,
 		IsActive:    true,
 	}
+	req.RouteCoordinates.apply(&route)
+
+	if err := route.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationMessage(err)})
+		return
+	}
 
 	if err := routeRepo.Create(&route); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
 		return
 	}
 
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "route.create",
+		EntityType: "routes",
+		EntityID:   route.ID,
+		After:      route,
+	})
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Tạo tuyến đường thành công",
 		"route":   formatRouteResponse(&route),
@@ -105,6 +164,7 @@ func GetRoutes(c *gin.Context) {
 	origin := c.Query("origin")
 	destination := c.Query("destination")
 	isActive := c.Query("is_active")
+	operatorID, _ := strconv.ParseUint(c.Query("operator_id"), 10, 64)
 
 	// Build filter
 	filter := make(map[string]interface{})
@@ -117,6 +177,9 @@ func GetRoutes(c *gin.Context) {
 	if isActive != "" {
 		filter["is_active"] = isActive == "true"
 	}
+	if operatorID > 0 {
+		filter["operator_id"] = operatorID
+	}
 
 	routes, err := routeRepo.FindAll(filter)
 	if err != nil {
@@ -193,7 +256,7 @@ func UpdateRoute(c *gin.Context) {
 		return
 	}
 
-	routeRepo := repository.NewRouteRepository(config.DB)
+	routeRepo := repository.NewRouteRepository(operatorDB(c))
 
 	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
@@ -207,6 +270,8 @@ func UpdateRoute(c *gin.Context) {
 		return
 	}
 
+	before := *route
+
 	// Update fields if provided
 	if req.Origin != "" {
 		route.Origin = req.Origin
@@ -218,6 +283,10 @@ func UpdateRoute(c *gin.Context) {
 		route.Distance = req.Distance
 	}
 	if req.Duration != "" {
+		if _, err := models.ParseRouteDuration(req.Duration); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Thời gian di chuyển không hợp lệ (VD: 4h30m)"})
+			return
+		}
 		route.Duration = req.Duration
 	}
 	if req.BasePrice != 0 {
@@ -226,12 +295,26 @@ func UpdateRoute(c *gin.Context) {
 	if req.IsActive != nil {
 		route.IsActive = *req.IsActive
 	}
+	req.RouteCoordinates.apply(route)
+
+	if err := route.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationMessage(err)})
+		return
+	}
 
 	if err := routeRepo.Update(route); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
 		return
 	}
 
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "route.update",
+		EntityType: "routes",
+		EntityID:   route.ID,
+		Before:     before,
+		After:      route,
+	})
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Cập nhật tuyến đường thành công",
 		"route":   formatRouteResponse(route),
@@ -240,7 +323,7 @@ func UpdateRoute(c *gin.Context) {
 
 // DeleteRoute soft deletes a route
 func DeleteRoute(c *gin.Context) {
-	routeRepo := repository.NewRouteRepository(config.DB)
+	routeRepo := repository.NewRouteRepository(operatorDB(c))
 
 	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
@@ -249,7 +332,8 @@ func DeleteRoute(c *gin.Context) {
 	}
 
 	// Check if route exists
-	if _, err := routeRepo.FindByID(uint(id)); err != nil {
+	route, err := routeRepo.FindByID(uint(id))
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy tuyến đường"})
 		return
 	}
@@ -259,6 +343,13 @@ func DeleteRoute(c *gin.Context) {
 		return
 	}
 
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "route.delete",
+		EntityType: "routes",
+		EntityID:   route.ID,
+		Before:     route,
+	})
+
 	c.JSON(http.StatusOK, gin.H{"message": "Xóa tuyến đường thành công"})
 }
 
@@ -266,6 +357,7 @@ func DeleteRoute(c *gin.Context) {
 func formatRouteResponse(route *models.Route) *RouteResponse {
 	return &RouteResponse{
 		ID:            route.ID,
+		OperatorID:    route.OperatorID,
 		Origin:        route.Origin,
 		Destination:   route.Destination,
 		Distance:      route.Distance,
@@ -276,7 +368,42 @@ func formatRouteResponse(route *models.Route) *RouteResponse {
 		UpcomingTrips: route.UpcomingTrips,
 		MinPrice:      route.MinPrice,
 		MaxPrice:      route.MaxPrice,
+		TotalBookings: route.TotalBookings,
+		LoadFactor:    route.LoadFactor,
 		CreatedAt:     route.CreatedAt.Format("2006-01-02 15:04:05"),
 		UpdatedAt:     route.UpdatedAt.Format("2006-01-02 15:04:05"),
+
+		OriginLat:      route.OriginLat,
+		OriginLng:      route.OriginLng,
+		DestinationLat: route.DestinationLat,
+		DestinationLng: route.DestinationLng,
+
+		Currency: route.Currency,
+	}
+}
+
+// validationMessages explain the validation errors of routes and buses
+var validationMessages = map[error]string{
+	models.ErrRouteEndpointsRequired:      "Vui lòng nhập điểm đi và điểm đến",
+	models.ErrRouteSameEndpoints:          "Điểm đi và điểm đến phải khác nhau",
+	models.ErrRouteDistance:               "Khoảng cách phải lớn hơn 0",
+	models.ErrRouteBasePrice:              "Giá vé phải lớn hơn 0",
+	models.ErrRouteDuration:               "Thời gian di chuyển không hợp lệ (VD: 4h30m)",
+	models.ErrRouteOriginCoordinates:      "Tọa độ điểm đi không hợp lệ",
+	models.ErrRouteDestinationCoordinates: "Tọa độ điểm đến không hợp lệ",
+	models.ErrBusPlateNumber:              "Biển số xe không hợp lệ",
+	models.ErrBusTypeRequired:             "Vui lòng chọn loại xe",
+	models.ErrBusSeatCount:                "Số ghế phải lớn hơn 0",
+	models.ErrBusFloorCount:               "Số tầng phải là 1 hoặc 2",
+	models.ErrBusCargoCapacity:            "Tải trọng khoang hàng không được âm",
+}
+
+// validationMessage returns the message shown for a validation error of a model
+func validationMessage(err error) string {
+	for target, message := range validationMessages {
+		if errors.Is(err, target) {
+			return message
+		}
 	}
+	return "Dữ liệu không hợp lệ: " + err.Error()
 }
@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"ticket-management/api_simple/config"
+	"ticket-management/api_simple/middleware"
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+	"ticket-management/api_simple/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type RoutePointRequest struct {
+	Type          models.RoutePointType `json:"type" binding:"required,oneof=pickup dropoff"` // Điểm đón hoặc điểm trả
+	Name          string                `json:"name" binding:"required"`                      // Tên điểm
+	Address       string                `json:"address"`                                      // Địa chỉ
+	Latitude      *float64              `json:"latitude"`                                     // Vĩ độ
+	Longitude     *float64              `json:"longitude"`                                    // Kinh độ
+	OffsetMinutes int                   `json:"offset_minutes" binding:"gte=0"`               // Số phút sau giờ khởi hành
+	Shuttle       bool                  `json:"shuttle"`                                      // Xe trung chuyển
+	Surcharge     models.Money          `json:"surcharge" binding:"gte=0"`                    // Phụ phí mỗi hành khách
+	IsActive      *bool                 `json:"is_active"`                                    // Còn sử dụng (mặc định: có)
+}
+
+// apply copies the request onto the point
+func (req *RoutePointRequest) apply(point *models.RoutePoint) {
+	point.Type = req.Type
+	point.Name = req.Name
+	point.Address = req.Address
+	point.Latitude = req.Latitude
+	point.Longitude = req.Longitude
+	point.OffsetMinutes = req.OffsetMinutes
+	point.Shuttle = req.Shuttle
+	point.Surcharge = req.Surcharge
+	if req.IsActive != nil {
+		point.IsActive = *req.IsActive
+	}
+}
+
+// GetRoutePoints lists the active pickup/drop-off points of a route.
+// Use `type=pickup` or `type=dropoff` to get one kind only.
+func GetRoutePoints(c *gin.Context) {
+	listRoutePoints(c, true)
+}
+
+// GetAdminRoutePoints lists all points of a route, including those no longer in use (admin)
+func GetAdminRoutePoints(c *gin.Context) {
+	listRoutePoints(c, false)
+}
+
+// listRoutePoints responds with the points of the :id route
+func listRoutePoints(c *gin.Context, activeOnly bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID không hợp lệ"})
+		return
+	}
+
+	pointType := models.RoutePointType(c.Query("type"))
+	if pointType != "" && !pointType.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Loại điểm không hợp lệ (pickup hoặc dropoff)"})
+		return
+	}
+
+	points, err := newRoutePointService(operatorDB(c)).List(uint(id), pointType, activeOnly)
+	if err != nil {
+		respondRoutePointError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"points": points,
+		"total":  len(points),
+	})
+}
+
+// CreateRoutePoint adds a pickup or drop-off point to a route (admin)
+func CreateRoutePoint(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID không hợp lệ"})
+		return
+	}
+
+	var req RoutePointRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng điền đầy đủ thông tin"})
+		return
+	}
+
+	point := &models.RoutePoint{RouteID: uint(id), IsActive: true}
+	req.apply(point)
+	if err := point.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := newRoutePointService(operatorDB(c)).Create(point); err != nil {
+		respondRoutePointError(c, err)
+		return
+	}
+
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "route_point.create",
+		EntityType: "route_points",
+		EntityID:   point.ID,
+		After:      point,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Thêm điểm đón/trả thành công",
+		"point":   point,
+	})
+}
+
+// UpdateRoutePoint replaces the details of a route point (admin)
+func UpdateRoutePoint(c *gin.Context) {
+	point, ok := findRoutePoint(c)
+	if !ok {
+		return
+	}
+
+	var req RoutePointRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng điền đầy đủ thông tin"})
+		return
+	}
+
+	before := *point
+	req.apply(point)
+	if err := point.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := newRoutePointService(operatorDB(c)).Update(point); err != nil {
+		respondRoutePointError(c, err)
+		return
+	}
+
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "route_point.update",
+		EntityType: "route_points",
+		EntityID:   point.ID,
+		Before:     before,
+		After:      point,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Cập nhật điểm đón/trả thành công",
+		"point":   point,
+	})
+}
+
+// DeleteRoutePoint soft deletes a route point (admin).
+// Bookings that chose the point keep it on their tickets and manifests.
+func DeleteRoutePoint(c *gin.Context) {
+	point, ok := findRoutePoint(c)
+	if !ok {
+		return
+	}
+
+	if err := repository.NewRoutePointRepository(config.DB).Delete(point.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "route_point.delete",
+		EntityType: "route_points",
+		EntityID:   point.ID,
+		Before:     point,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Xóa điểm đón/trả thành công"})
+}
+
+// GetTripManifest returns the passenger list of a trip with pickup and drop-off points (admin)
+func GetTripManifest(c *gin.Context) {
+	trip, ok := findManifestTrip(c)
+	if !ok {
+		return
+	}
+
+	manifest, err := newRoutePointService(operatorDB(c)).Manifest(trip)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, manifest)
+}
+
+// GetDriverTripManifest returns the passenger list of a trip assigned to the current driver
+func GetDriverTripManifest(c *gin.Context) {
+	trip, ok := findManifestTrip(c)
+	if !ok {
+		return
+	}
+
+	driver := c.MustGet("user").(*models.User)
+	if trip.DriverID != driver.ID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Bạn không phải tài xế của chuyến này"})
+		return
+	}
+
+	manifest, err := newRoutePointService(operatorDB(c)).Manifest(trip)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, manifest)
+}
+
+// findRoutePoint loads the point named by :point_id and checks it belongs to the :id route of the operator
+func findRoutePoint(c *gin.Context) (*models.RoutePoint, bool) {
+	routeID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID không hợp lệ"})
+		return nil, false
+	}
+	pointID, err := strconv.ParseUint(c.Param("point_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID không hợp lệ"})
+		return nil, false
+	}
+
+	if _, err := repository.NewRouteRepository(operatorDB(c)).FindByID(uint(routeID)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy tuyến đường"})
+		return nil, false
+	}
+
+	point, err := repository.NewRoutePointRepository(config.DB).FindByID(uint(pointID))
+	if err != nil || point.RouteID != uint(routeID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy điểm đón/trả"})
+		return nil, false
+	}
+	return point, true
+}
+
+// findManifestTrip loads the trip named by the :id parameter within the operator the request acts for
+func findManifestTrip(c *gin.Context) (*models.Trip, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID không hợp lệ"})
+		return nil, false
+	}
+
+	trip, err := repository.NewTripRepository(operatorDB(c)).FindByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy chuyến đi"})
+		return nil, false
+	}
+	return trip, true
+}
+
+// selectBoardingPoints resolves the points chosen for a booking and writes an error response on failure
+func selectBoardingPoints(c *gin.Context, trip *models.Trip, pickupID, dropoffID *uint, passengers int) (*services.BoardingSelection, bool) {
+	selection, err := newRoutePointService(operatorDB(c)).Select(trip, pickupID, dropoffID, passengers)
+	if err != nil {
+		respondRoutePointError(c, err)
+		return nil, false
+	}
+	return selection, true
+}
+
+// newRoutePointService creates a route point service backed by db
+func newRoutePointService(db *gorm.DB) *services.RoutePointService {
+	return services.NewRoutePointService(
+		repository.NewRoutePointRepository(db),
+		repository.NewRouteRepository(db),
+		repository.NewBookingRepository(db),
+	)
+}
+
+// respondRoutePointError maps route point errors to API responses
+func respondRoutePointError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy tuyến đường"})
+	case errors.Is(err, services.ErrPointNotOnRoute):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Điểm đón/trả không thuộc tuyến của chuyến đi"})
+	case errors.Is(err, services.ErrPointWrongType):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Điểm đã chọn không phải điểm đón/trả tương ứng"})
+	case errors.Is(err, services.ErrPointOrder):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Điểm trả phải sau điểm đón"})
+	case errors.Is(err, services.ErrPointBeyondRoute):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Thời gian đến điểm vượt quá thời gian di chuyển của tuyến"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+	}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+	"ticket-management/api_simple/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetScheduleSuggestions lists buses and drivers free for a proposed departure
+func GetScheduleSuggestions(c *gin.Context) {
+	routeID, err := strconv.ParseUint(c.Query("route_id"), 10, 64)
+	if err != nil || routeID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "route_id không hợp lệ"})
+		return
+	}
+
+	departure, err := time.Parse(time.RFC3339, c.Query("departure_time"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "departure_time không hợp lệ (định dạng RFC3339)"})
+		return
+	}
+
+	suggestion, err := newScheduleService(operatorDB(c)).Suggest(uint(routeID), departure)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy tuyến đường"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, suggestion)
+}
+
+// newScheduleService creates a schedule service backed by db
+func newScheduleService(db *gorm.DB) *services.ScheduleService {
+	return services.NewScheduleService(
+		repository.NewTripRepository(db),
+		repository.NewRouteRepository(db),
+		repository.NewBusRepository(db),
+		repository.NewUserRepository(db),
+		repository.NewMaintenanceRepository(db),
+		services.DefaultScheduleConfig(),
+	)
+}
+
+// respondScheduleError maps schedule conflicts to API responses
+func respondScheduleError(c *gin.Context, err error) {
+	var conflict *services.ScheduleConflict
+	if !errors.As(err, &conflict) {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy tuyến đường hoặc xe"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusConflict, gin.H{
+		"error":    scheduleConflictMessage(conflict),
+		"conflict": conflict,
+	})
+}
+
+// scheduleConflictMessage explains a schedule conflict to the user
+func scheduleConflictMessage(conflict *services.ScheduleConflict) string {
+	switch {
+	case conflict.Kind == services.ConflictMaintenance:
+		return "Xe đang ngừng hoạt động để bảo dưỡng hoặc sửa chữa"
+	case conflict.Kind == services.ConflictDocuments && conflict.Reason == "registration":
+		return "Đăng ký xe hết hạn trước khi chuyến đi kết thúc"
+	case conflict.Kind == services.ConflictDocuments:
+		return "Đăng kiểm xe hết hạn trước khi chuyến đi kết thúc"
+	case conflict.Kind == services.ConflictLocation:
+		return "Xe không ở đúng điểm xuất phát (đang ở " + conflict.Location + ")"
+	case conflict.Resource == "driver":
+		return "Tài xế đã có chuyến khác trong khoảng thời gian này"
+	default:
+		return "Xe đã có chuyến khác trong khoảng thời gian này"
+	}
+}
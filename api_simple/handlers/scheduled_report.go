@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ticket-management/api_simple/middleware"
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+	"ticket-management/api_simple/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ScheduledReportRequest struct {
+	Name       string               `json:"name" binding:"required"`       // Tên báo cáo, dùng làm tiêu đề email
+	Report     models.ReportKind    `json:"report" binding:"required"`     // bookings, revenue, occupancy
+	Format     models.ReportFormat  `json:"format"`                        // csv hoặc xlsx (mặc định: xlsx)
+	Filters    models.ReportFilters `json:"filters"`                       // Bộ lọc của báo cáo
+	Period     models.ReportPeriod  `json:"period" binding:"required"`     // day, week, month
+	Recipients []string             `json:"recipients" binding:"required"` // Email người nhận
+	Cron       string               `json:"cron" binding:"required"`       // Lịch gửi, ví dụ "0 8 * * 1"
+	IsActive   *bool                `json:"is_active"`                     // Mặc định: true
+}
+
+// apply copies the request onto the scheduled report
+func (req *ScheduledReportRequest) apply(report *models.ScheduledReport) {
+	report.Name = req.Name
+	report.Report = req.Report
+	report.Format = req.Format
+	if report.Format == "" {
+		report.Format = models.ReportFormatXLSX
+	}
+	report.Filters = req.Filters
+	report.Period = req.Period
+	report.Recipients = req.Recipients
+	report.Cron = req.Cron
+	report.IsActive = req.IsActive == nil || *req.IsActive
+}
+
+// GetScheduledReports lists the reports emailed on a schedule (admin)
+func GetScheduledReports(c *gin.Context) {
+	reports, err := repository.NewScheduledReportRepository(operatorDB(c)).FindAll(nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reports": reports,
+		"total":   len(reports),
+	})
+}
+
+// CreateScheduledReport schedules a report to be emailed to its recipients (admin)
+func CreateScheduledReport(c *gin.Context) {
+	if _, ok := requireOperator(c); !ok {
+		return
+	}
+
+	var req ScheduledReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng điền đầy đủ thông tin"})
+		return
+	}
+
+	report := &models.ScheduledReport{}
+	req.apply(report)
+	if !scheduleReport(c, report) {
+		return
+	}
+
+	if err := repository.NewScheduledReportRepository(operatorDB(c)).Create(report); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "scheduled_report.create",
+		EntityType: "scheduled_reports",
+		EntityID:   report.ID,
+		After:      report,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Tạo báo cáo định kỳ thành công",
+		"report":  report,
+	})
+}
+
+// UpdateScheduledReport replaces the definition of a scheduled report (admin)
+func UpdateScheduledReport(c *gin.Context) {
+	report, ok := findScheduledReport(c)
+	if !ok {
+		return
+	}
+
+	var req ScheduledReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng điền đầy đủ thông tin"})
+		return
+	}
+
+	before := *report
+	req.apply(report)
+	if !scheduleReport(c, report) {
+		return
+	}
+
+	if err := repository.NewScheduledReportRepository(operatorDB(c)).Update(report); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "scheduled_report.update",
+		EntityType: "scheduled_reports",
+		EntityID:   report.ID,
+		Before:     before,
+		After:      report,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Cập nhật báo cáo định kỳ thành công",
+		"report":  report,
+	})
+}
+
+// DeleteScheduledReport stops and removes a scheduled report (admin)
+func DeleteScheduledReport(c *gin.Context) {
+	report, ok := findScheduledReport(c)
+	if !ok {
+		return
+	}
+
+	if err := repository.NewScheduledReportRepository(operatorDB(c)).Delete(report.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "scheduled_report.delete",
+		EntityType: "scheduled_reports",
+		EntityID:   report.ID,
+		Before:     report,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Xóa báo cáo định kỳ thành công"})
+}
+
+// SendScheduledReport emails a scheduled report right away, for the period it would
+// cover if it were sent now (admin)
+func SendScheduledReport(c *gin.Context) {
+	report, ok := findScheduledReport(c)
+	if !ok {
+		return
+	}
+
+	db := operatorDB(c)
+	scheduledReportService := services.NewScheduledReportService(repository.NewScheduledReportRepository(db), services.NewMailServiceFromEnv())
+	if err := scheduledReportService.Deliver(report, newReportService(db), time.Now()); err != nil {
+		if report.LastError != "" {
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Gửi báo cáo thất bại: " + report.LastError})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "scheduled_report.send",
+		EntityType: "scheduled_reports",
+		EntityID:   report.ID,
+		After:      gin.H{"recipients": report.Recipients},
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Đã gửi báo cáo",
+		"report":  report,
+	})
+}
+
+// scheduleReport validates a scheduled report and sets its next delivery, responding
+// with an error if it cannot
+func scheduleReport(c *gin.Context, report *models.ScheduledReport) bool {
+	if err := report.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	scheduledReportService := services.NewScheduledReportService(nil, nil)
+	if err := scheduledReportService.Schedule(report, time.Now()); err != nil {
+		if errors.Is(err, services.ErrReportNeverRuns) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Lịch gửi không bao giờ đến (ngày không tồn tại)"})
+			return false
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Lịch gửi không đúng cú pháp cron"})
+		return false
+	}
+	return true
+}
+
+// findScheduledReport loads the :id scheduled report, responding with an error if it cannot
+func findScheduledReport(c *gin.Context) (*models.ScheduledReport, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID không hợp lệ"})
+		return nil, false
+	}
+
+	report, err := repository.NewScheduledReportRepository(operatorDB(c)).FindByID(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy báo cáo định kỳ"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return nil, false
+	}
+	return report, true
+}
@@ -9,12 +9,16 @@ import (
 	"ticket-management/api_simple/config"
 	"ticket-management/api_simple/models"
 	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
 	"ticket-management/api_simple/utils"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
+// seatLockDuration is how long a seat stays locked while the customer completes a booking
+const seatLockDuration = 15 * time.Minute
+
 // GetTripSeats returns all seats for a trip
 func GetTripSeats(c *gin.Context) {
 	tripRepo := repository.NewTripRepository(config.DB)
@@ -63,6 +67,7 @@ func GetTripSeats(c *gin.Context) {
 			"departure_time": trip.DepartureTime.Format("2006-01-02 15:04:05"),
 			"bus_type":       trip.Bus.Type,
 			"base_price":     trip.Price,
+			"currency":       trip.Currency,
 		},
 		"floors": floors,
 	})
@@ -116,11 +121,49 @@ func GetAvailableSeats(c *gin.Context) {
 			"departure_time": trip.DepartureTime.Format("2006-01-02 15:04:05"),
 			"bus_type":       trip.Bus.Type,
 			"base_price":     trip.Price,
+			"currency":       trip.Currency,
 		},
 		"floors": floors,
 	})
 }
 
+// StreamTripSeats sends the seat map of a trip and then every seat status change
+// as server-sent events, replacing polling of GET /trips/:id/seats
+func StreamTripSeats(c *gin.Context) {
+	tripID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID không hợp lệ"})
+		return
+	}
+
+	if _, err := repository.NewTripRepository(config.DB).FindByID(uint(tripID)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy chuyến đi"})
+		return
+	}
+
+	// Subscribe before loading the seats so no change is missed in between
+	events, unsubscribe := eventBroker.Subscribe(services.SeatTopic(uint(tripID)))
+	defer unsubscribe()
+
+	seats, err := repository.NewSeatRepository(config.DB).FindByTrip(uint(tripID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	changes := make([]services.SeatChange, len(seats))
+	for i, seat := range seats {
+		changes[i] = services.SeatChange{ID: seat.ID, Number: seat.Number, Status: seat.Status, LockedUntil: seat.LockedUntil}
+	}
+
+	streamEvents(c, events, "seats", services.SeatEvent{
+		TripID: uint(tripID),
+		Reason: "snapshot",
+		Seats:  changes,
+		At:     time.Now(),
+	})
+}
+
 // CheckSeatStatus checks if seats are available
 func CheckSeatStatus(c *gin.Context) {
 	tripRepo := repository.NewTripRepository(config.DB)
@@ -198,6 +241,8 @@ func LockSeats(c *gin.Context) {
 	}
 
 	// Lock seats in transaction
+	var locked []int64
+	lockedUntil := time.Now().Add(seatLockDuration)
 	err = config.DB.Transaction(func(tx *gorm.DB) error {
 		// Get seats
 		seats, err := seatRepo.FindByIDs(uint(tripID), seatIDs)
@@ -214,9 +259,10 @@ func LockSeats(c *gin.Context) {
 
 		// Lock seats
 		for _, seat := range seats {
-			if err := seatRepo.LockSeat(seat.ID, userID, 15*time.Minute); err != nil {
+			if err := seatRepo.LockSeat(seat.ID, userID, seatLockDuration); err != nil {
 				return err
 			}
+			locked = append(locked, int64(seat.ID))
 		}
 
 		return nil
@@ -227,6 +273,8 @@ func LockSeats(c *gin.Context) {
 		return
 	}
 
+	notifySeats(uint(tripID), services.SeatEventLocked, models.SeatStatusLocked, locked, &lockedUntil)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Khóa ghế thành công"})
 }
 
@@ -263,6 +311,7 @@ func UnlockSeats(c *gin.Context) {
 	}
 
 	// Unlock seats in transaction
+	var unlocked []int64
 	err = config.DB.Transaction(func(tx *gorm.DB) error {
 		// Get seats
 		seats, err := seatRepo.FindByIDs(uint(tripID), seatIDs)
@@ -285,6 +334,7 @@ func UnlockSeats(c *gin.Context) {
 			if err := seatRepo.UnlockSeat(seat.ID); err != nil {
 				return err
 			}
+			unlocked = append(unlocked, int64(seat.ID))
 		}
 
 		return nil
@@ -295,12 +345,14 @@ func UnlockSeats(c *gin.Context) {
 		return
 	}
 
+	notifySeats(uint(tripID), services.SeatEventUnlocked, models.SeatStatusAvailable, unlocked, nil)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Mở khóa ghế thành công"})
 }
 
 // CreateSeats creates seats for a trip
 func CreateSeats(c *gin.Context) {
-	tripRepo := repository.NewTripRepository(config.DB)
+	tripRepo := repository.NewTripRepository(operatorDB(c))
 	seatRepo := repository.NewSeatRepository(config.DB)
 
 	// Get trip ID from path
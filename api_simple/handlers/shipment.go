@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ticket-management/api_simple/config"
+	"ticket-management/api_simple/middleware"
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+	"ticket-management/api_simple/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CreateShipmentRequest struct {
+	TripID          uint                 `json:"trip_id" binding:"required"`
+	SenderName      string               `json:"sender_name" binding:"required"`
+	SenderPhone     string               `json:"sender_phone" binding:"required"`
+	ReceiverName    string               `json:"receiver_name" binding:"required"`
+	ReceiverPhone   string               `json:"receiver_phone" binding:"required"`
+	ReceiverAddress string               `json:"receiver_address"`
+	Description     string               `json:"description"`
+	WeightKg        float64              `json:"weight_kg" binding:"required,gt=0"`
+	LengthCm        float64              `json:"length_cm" binding:"gte=0"`
+	WidthCm         float64              `json:"width_cm" binding:"gte=0"`
+	HeightCm        float64              `json:"height_cm" binding:"gte=0"`
+	Payer           models.ShipmentPayer `json:"payer" binding:"omitempty,oneof=sender receiver"` // Mặc định: người gửi trả
+	Note            string               `json:"note"`
+}
+
+type UpdateShipmentStatusRequest struct {
+	Status models.ShipmentStatus `json:"status" binding:"required,oneof=received loaded in_transit arrived delivered cancelled"`
+	Note   string                `json:"note"`
+}
+
+// QuoteShipment prices a parcel from its weight and size
+func QuoteShipment(c *gin.Context) {
+	weight, err := strconv.ParseFloat(c.Query("weight_kg"), 64)
+	if err != nil || weight <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Khối lượng không hợp lệ"})
+		return
+	}
+
+	var size [3]float64
+	for i, key := range []string{"length_cm", "width_cm", "height_cm"} {
+		if value := c.Query(key); value != "" {
+			size[i], err = strconv.ParseFloat(value, 64)
+			if err != nil || size[i] < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Kích thước không hợp lệ"})
+				return
+			}
+		}
+	}
+
+	quote := newShipmentService(config.DB).Quote(weight, size[0], size[1], size[2])
+	c.JSON(http.StatusOK, quote)
+}
+
+// TrackShipment returns the status history of a shipment by tracking code (public)
+func TrackShipment(c *gin.Context) {
+	tracking, err := newShipmentService(config.DB).Lookup(c.Param("code"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy vận đơn"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, tracking)
+}
+
+// CreateShipment receives a parcel at the office and books it on a trip (staff)
+func CreateShipment(c *gin.Context) {
+	var req CreateShipmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng điền đầy đủ thông tin"})
+		return
+	}
+
+	shipment := &models.Shipment{
+		TripID:          req.TripID,
+		SenderName:      req.SenderName,
+		SenderPhone:     req.SenderPhone,
+		ReceiverName:    req.ReceiverName,
+		ReceiverPhone:   req.ReceiverPhone,
+		ReceiverAddress: req.ReceiverAddress,
+		Description:     req.Description,
+		WeightKg:        req.WeightKg,
+		LengthCm:        req.LengthCm,
+		WidthCm:         req.WidthCm,
+		HeightCm:        req.HeightCm,
+		Payer:           req.Payer,
+		Note:            req.Note,
+	}
+	if shipment.Payer == "" {
+		shipment.Payer = models.ShipmentPayerSender
+	}
+	if err := shipment.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := operatorDB(c).Transaction(func(tx *gorm.DB) error {
+		return newShipmentService(tx).Create(shipment, currentUserID(c), time.Now())
+	})
+	if err != nil {
+		respondShipmentError(c, err)
+		return
+	}
+
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "shipment.create",
+		EntityType: "shipments",
+		EntityID:   shipment.ID,
+		After:      shipment,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Nhận hàng thành công",
+		"shipment": shipment,
+	})
+}
+
+// GetShipments lists shipments filtered by trip, status or receiver phone (staff)
+func GetShipments(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	filters := make(map[string]interface{})
+	if tripID := c.Query("trip_id"); tripID != "" {
+		filters["trip_id"] = tripID
+	}
+	if status := c.Query("status"); status != "" {
+		filters["status"] = status
+	}
+	if phone := c.Query("receiver_phone"); phone != "" {
+		filters["receiver_phone"] = phone
+	}
+
+	shipments, total, err := repository.NewShipmentRepository(operatorDB(c)).FindAll(filters, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"shipments": shipments,
+		"total":     total,
+	})
+}
+
+// UpdateShipmentStatus moves a shipment along received → loaded → in_transit → arrived → delivered.
+// Staff may also cancel a parcel that has not been loaded; drivers only update parcels on their trips.
+func UpdateShipmentStatus(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID không hợp lệ"})
+		return
+	}
+
+	var req UpdateShipmentStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Trạng thái không hợp lệ"})
+		return
+	}
+
+	user := c.MustGet("user").(*models.User)
+	before, err := repository.NewShipmentRepository(operatorDB(c)).FindByID(uint(id))
+	if err != nil {
+		respondShipmentError(c, err)
+		return
+	}
+
+	var shipment *models.Shipment
+	err = operatorDB(c).Transaction(func(tx *gorm.DB) error {
+		var err error
+		shipment, err = newShipmentService(tx).UpdateStatus(uint(id), req.Status, user, req.Note, time.Now())
+		return err
+	})
+	if err != nil {
+		respondShipmentError(c, err)
+		return
+	}
+
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "shipment.update_status",
+		EntityType: "shipments",
+		EntityID:   shipment.ID,
+		Before:     before,
+		After:      shipment,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Cập nhật trạng thái hàng thành công",
+		"shipment": shipment,
+	})
+}
+
+// GetTripShipments returns the cargo booked on a trip and the capacity of its bus (staff)
+func GetTripShipments(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID không hợp lệ"})
+		return
+	}
+
+	load, err := newShipmentService(operatorDB(c)).Load(uint(id))
+	if err != nil {
+		respondShipmentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, load)
+}
+
+// GetDriverTripShipments returns the cargo on a trip assigned to the current driver
+func GetDriverTripShipments(c *gin.Context) {
+	trip, ok := findManifestTrip(c)
+	if !ok {
+		return
+	}
+
+	driver := c.MustGet("user").(*models.User)
+	if trip.DriverID != driver.ID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Bạn không phải tài xế của chuyến này"})
+		return
+	}
+
+	load, err := newShipmentService(config.DB).Load(trip.ID)
+	if err != nil {
+		respondShipmentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, load)
+}
+
+// newShipmentService creates a shipment service backed by db
+func newShipmentService(db *gorm.DB) *services.ShipmentService {
+	return services.NewShipmentService(
+		repository.NewShipmentRepository(db),
+		repository.NewTripRepository(db),
+		services.ShipmentConfigFromEnv(),
+	)
+}
+
+// respondShipmentError maps shipment errors to API responses
+func respondShipmentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy chuyến đi hoặc vận đơn"})
+	case errors.Is(err, services.ErrShipmentNotAccepted):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Chuyến đi đã khởi hành hoặc không còn nhận hàng"})
+	case errors.Is(err, services.ErrCargoCapacityExceeded):
+		c.JSON(http.StatusConflict, gin.H{"error": "Khoang hàng của xe không đủ chỗ"})
+	case errors.Is(err, services.ErrInvalidShipmentStatus):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Không thể chuyển sang trạng thái này"})
+	case errors.Is(err, services.ErrTripNotAssigned):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Bạn không phải tài xế của chuyến này"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+	}
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"ticket-management/api_simple/config"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+	"ticket-management/api_simple/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// statsTracker collects the trips whose statistics need refreshing
+var statsTracker = services.NewStatsTracker()
+
+// SetStatsTracker replaces the tracker marking changed trips, shared with the job that
+// refreshes their statistics
+func SetStatsTracker(tracker *services.StatsTracker) {
+	statsTracker = tracker
+}
+
+// tripStatsChanged marks the statistics of a trip, its route and its bus as stale
+func tripStatsChanged(tripID uint) {
+	statsTracker.TripChanged(tripID)
+}
+
+// RefreshStats recomputes the statistics of every route, trip and bus (super admin)
+func RefreshStats(c *gin.Context) {
+	statsService := services.NewStatsService(
+		repository.NewTripRepository(config.DB),
+		repository.NewRouteRepository(config.DB),
+		repository.NewBusRepository(config.DB),
+	)
+	if err := statsService.RefreshAll(time.Now()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Cập nhật thống kê thành công"})
+}
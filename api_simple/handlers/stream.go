@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// streamHeartbeat keeps idle SSE connections open through proxies
+const streamHeartbeat = 15 * time.Second
+
+// eventBroker fans out live updates to streaming clients
+var eventBroker services.Broker = services.NewMemoryBroker(64)
+
+// SetEventBroker replaces the broker used for live updates, e.g. with a Redis broker
+// when several API instances run behind a load balancer
+func SetEventBroker(broker services.Broker) {
+	eventBroker = broker
+}
+
+// streamEvents writes initial as the first server-sent event and then relays every
+// message from events under the same event name until the client disconnects
+func streamEvents(c *gin.Context, events <-chan []byte, event string, initial interface{}) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.SSEvent(event, initial)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case payload, open := <-events:
+			if !open {
+				return false
+			}
+			c.SSEvent(event, json.RawMessage(payload))
+			return true
+		case <-heartbeat.C:
+			c.SSEvent("ping", time.Now().Unix())
+			return true
+		}
+	})
+}
+
+// notifySeats publishes seat status changes of a trip. Failures are only logged:
+// the change is already committed and clients can still reload the seat map.
+// Seats booked or released also mark the trip statistics as stale.
+func notifySeats(tripID uint, reason string, status models.SeatStatus, seatIDs []int64, lockedUntil *time.Time) {
+	if reason == services.SeatEventBooked || reason == services.SeatEventReleased {
+		tripStatsChanged(tripID)
+	}
+	if err := services.NewSeatNotifier(eventBroker).NotifyIDs(tripID, reason, status, seatIDs, lockedUntil); err != nil {
+		log.Printf("Error publishing seat changes for trip %d: %v", tripID, err)
+	}
+}
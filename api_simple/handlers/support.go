@@ -0,0 +1,634 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ticket-management/api_simple/config"
+	"ticket-management/api_simple/middleware"
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+	"ticket-management/api_simple/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SupportTicketRequest opens a support ticket, as JSON or as multipart form data with the
+// files in the attachments field
+type SupportTicketRequest struct {
+	Category    models.SupportCategory `json:"category" form:"category" binding:"required,oneof=refund lost_item complaint other"`
+	Subject     string                 `json:"subject" form:"subject" binding:"required,max=200"`
+	Body        string                 `json:"body" form:"body" binding:"max=5000"` // Nội dung tin nhắn đầu tiên
+	BookingCode string                 `json:"booking_code" form:"booking_code"`    // Mã đặt vé liên quan
+	OperatorID  uint                   `json:"operator_id" form:"operator_id"`      // Nhà xe cần hỗ trợ (khi không có mã đặt vé)
+}
+
+// GuestSupportTicketRequest opens a support ticket without an account, the phone being
+// verified by an OTP requested with purpose support
+type GuestSupportTicketRequest struct {
+	SupportTicketRequest
+	Name  string `json:"name" form:"name" binding:"required"`
+	Phone string `json:"phone" form:"phone" binding:"required"`
+	OTP   string `json:"otp" form:"otp" binding:"required"`
+}
+
+type SupportReplyRequest struct {
+	Phone string `json:"phone" form:"phone" binding:"required"` // SĐT liên hệ của yêu cầu
+	Body  string `json:"body" form:"body" binding:"max=5000"`
+}
+
+type StaffSupportReplyRequest struct {
+	Body     string `json:"body" form:"body" binding:"max=5000"`
+	Internal bool   `json:"internal" form:"internal"` // Ghi chú nội bộ, khách hàng không thấy
+}
+
+type CloseSupportTicketRequest struct {
+	Phone string `json:"phone" binding:"required"` // SĐT liên hệ của yêu cầu
+}
+
+type AssignSupportTicketRequest struct {
+	AssigneeID *uint `json:"assignee_id"` // Nhân viên phụ trách (null: bỏ phân công)
+}
+
+type UpdateSupportStatusRequest struct {
+	Status models.SupportStatus `json:"status" binding:"required,oneof=open pending resolved closed"`
+}
+
+// CreateSupportTicket opens a support ticket for the logged-in customer
+func CreateSupportTicket(c *gin.Context) {
+	var req SupportTicketRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng điền đầy đủ thông tin"})
+		return
+	}
+
+	user := c.MustGet("user").(*models.User)
+	ticket := req.ticket()
+	ticket.UserID = &user.ID
+	ticket.ContactName = user.Name
+	ticket.ContactPhone = user.Phone
+	openSupportTicket(c, ticket, &req)
+}
+
+// CreateGuestSupportTicket opens a support ticket for a customer without an account
+func CreateGuestSupportTicket(c *gin.Context) {
+	var req GuestSupportTicketRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng điền đầy đủ thông tin"})
+		return
+	}
+
+	if err := newOTPService().Verify(req.Phone, models.OTPPurposeSupport, req.OTP); err != nil {
+		respondOTPError(c, err)
+		return
+	}
+
+	ticket := req.ticket()
+	ticket.ContactName = req.Name
+	ticket.ContactPhone = req.Phone
+	openSupportTicket(c, ticket, &req.SupportTicketRequest)
+}
+
+// GetUserSupportTickets lists the support tickets of the logged-in customer
+func GetUserSupportTickets(c *gin.Context) {
+	user := c.MustGet("user").(*models.User)
+	page, limit := supportPage(c)
+
+	tickets, total, err := repository.NewSupportRepository(config.DB).FindAll(repository.SupportFilter{UserID: &user.ID}, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+	for i := range tickets {
+		tickets[i].Assignee = nil
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tickets": tickets,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
+}
+
+// GetSupportTicket returns a support ticket and its conversation to the customer, found by
+// code and contact phone
+func GetSupportTicket(c *gin.Context) {
+	ticket, ok := findCustomerTicket(c, c.Query("phone"))
+	if !ok {
+		return
+	}
+
+	messages, err := newSupportService(config.DB).Thread(ticket, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	ticket.Messages = messages
+	c.JSON(http.StatusOK, ticket)
+}
+
+// ReplySupportTicket adds a message from the customer, reopening the ticket if it was
+// waiting on them or resolved
+func ReplySupportTicket(c *gin.Context) {
+	var req SupportReplyRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng điền đầy đủ thông tin"})
+		return
+	}
+
+	ticket, ok := findCustomerTicket(c, req.Phone)
+	if !ok {
+		return
+	}
+	uploads, closeUploads, err := supportUploads(c)
+	if err != nil {
+		respondSupportError(c, err)
+		return
+	}
+	defer closeUploads()
+
+	var message *models.SupportMessage
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		message, err = newSupportService(tx).CustomerReply(ticket, req.Body, uploads, time.Now())
+		return err
+	})
+	if err != nil {
+		respondSupportError(c, err)
+		return
+	}
+	notifySupportReply(ticket, message)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Đã gửi tin nhắn",
+		"reply":   message,
+	})
+}
+
+// CloseSupportTicket lets the customer close a ticket they no longer need help with
+func CloseSupportTicket(c *gin.Context) {
+	var req CloseSupportTicketRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng điền đầy đủ thông tin"})
+		return
+	}
+
+	ticket, ok := findCustomerTicket(c, req.Phone)
+	if !ok {
+		return
+	}
+	if err := newSupportService(config.DB).UpdateStatus(ticket, models.SupportStatusClosed, time.Now()); err != nil {
+		respondSupportError(c, err)
+		return
+	}
+	notifySupport(ticket, services.SupportEventStatusChanged)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Đã đóng yêu cầu hỗ trợ"})
+}
+
+// GetSupportAttachment downloads a file of the conversation of a ticket to the customer
+func GetSupportAttachment(c *gin.Context) {
+	ticket, ok := findCustomerTicket(c, c.Query("phone"))
+	if !ok {
+		return
+	}
+	sendSupportAttachment(c, newSupportService(config.DB), ticket, false)
+}
+
+// GetSupportTickets lists the support tickets of the operator, filtered by status,
+// category, assignee (an ID or none) or SLA breach (staff)
+func GetSupportTickets(c *gin.Context) {
+	page, limit := supportPage(c)
+	filter := repository.SupportFilter{
+		Status:   models.SupportStatus(c.Query("status")),
+		Category: models.SupportCategory(c.Query("category")),
+	}
+	if filter.Status != "" && !filter.Status.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Trạng thái không hợp lệ"})
+		return
+	}
+	if filter.Category != "" && !filter.Category.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Loại yêu cầu không hợp lệ"})
+		return
+	}
+	switch assignee := c.Query("assigned_to"); assignee {
+	case "":
+	case "none":
+		filter.Unassigned = true
+	default:
+		id, err := strconv.ParseUint(assignee, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Người phụ trách không hợp lệ"})
+			return
+		}
+		assignedTo := uint(id)
+		filter.AssignedTo = &assignedTo
+	}
+	filter.Breached, _ = strconv.ParseBool(c.Query("breached"))
+
+	tickets, total, err := repository.NewSupportRepository(operatorDB(c)).FindAll(filter, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tickets": tickets,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
+}
+
+// GetStaffSupportTicket returns a support ticket with its whole conversation, internal
+// notes included (staff)
+func GetStaffSupportTicket(c *gin.Context) {
+	ticket, ok := findStaffTicket(c)
+	if !ok {
+		return
+	}
+
+	messages, err := newSupportService(operatorDB(c)).Thread(ticket, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	ticket.Messages = messages
+	c.JSON(http.StatusOK, ticket)
+}
+
+// ReplyStaffSupportTicket answers the customer, who is notified by SMS, or adds an
+// internal note (staff)
+func ReplyStaffSupportTicket(c *gin.Context) {
+	var req StaffSupportReplyRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dữ liệu không hợp lệ"})
+		return
+	}
+
+	ticket, ok := findStaffTicket(c)
+	if !ok {
+		return
+	}
+	uploads, closeUploads, err := supportUploads(c)
+	if err != nil {
+		respondSupportError(c, err)
+		return
+	}
+	defer closeUploads()
+
+	user := c.MustGet("user").(*models.User)
+	var message *models.SupportMessage
+	err = operatorDB(c).Transaction(func(tx *gorm.DB) error {
+		var err error
+		message, err = newSupportService(tx).StaffReply(ticket, user, req.Body, req.Internal, uploads, time.Now())
+		return err
+	})
+	if err != nil {
+		respondSupportError(c, err)
+		return
+	}
+	notifySupportReply(ticket, message)
+
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "support.reply",
+		EntityType: "support_tickets",
+		EntityID:   ticket.ID,
+		After:      gin.H{"message_id": message.ID, "internal": message.Internal, "attachments": len(message.Attachments)},
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Đã gửi tin nhắn",
+		"reply":   message,
+		"ticket":  ticket,
+	})
+}
+
+// AssignSupportTicket makes a staff member of the operator responsible for a ticket (staff)
+func AssignSupportTicket(c *gin.Context) {
+	var req AssignSupportTicketRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dữ liệu không hợp lệ"})
+		return
+	}
+
+	ticket, ok := findStaffTicket(c)
+	if !ok {
+		return
+	}
+	before := ticket.AssignedTo
+	if err := newSupportService(operatorDB(c)).Assign(ticket, req.AssigneeID); err != nil {
+		respondSupportError(c, err)
+		return
+	}
+	notifySupport(ticket, services.SupportEventAssigned)
+
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "support.assign",
+		EntityType: "support_tickets",
+		EntityID:   ticket.ID,
+		Before:     gin.H{"assigned_to": before},
+		After:      gin.H{"assigned_to": ticket.AssignedTo},
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Phân công yêu cầu hỗ trợ thành công",
+		"ticket":  ticket,
+	})
+}
+
+// UpdateSupportTicketStatus moves a ticket to another status; the customer is told by SMS
+// when it is resolved (staff)
+func UpdateSupportTicketStatus(c *gin.Context) {
+	var req UpdateSupportStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Trạng thái không hợp lệ"})
+		return
+	}
+
+	ticket, ok := findStaffTicket(c)
+	if !ok {
+		return
+	}
+	before := ticket.Status
+	if err := newSupportService(operatorDB(c)).UpdateStatus(ticket, req.Status, time.Now()); err != nil {
+		respondSupportError(c, err)
+		return
+	}
+	if err := newSupportNotifier().StatusChanged(ticket); err != nil {
+		log.Printf("Error notifying status of support ticket %s: %v", ticket.Code, err)
+	}
+
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "support.update_status",
+		EntityType: "support_tickets",
+		EntityID:   ticket.ID,
+		Before:     gin.H{"status": before},
+		After:      gin.H{"status": ticket.Status},
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Cập nhật trạng thái yêu cầu hỗ trợ thành công",
+		"ticket":  ticket,
+	})
+}
+
+// GetStaffSupportAttachment downloads a file of the conversation of a ticket (staff)
+func GetStaffSupportAttachment(c *gin.Context) {
+	ticket, ok := findStaffTicket(c)
+	if !ok {
+		return
+	}
+	sendSupportAttachment(c, newSupportService(operatorDB(c)), ticket, true)
+}
+
+// StreamSupportTickets streams the changes of the operator's support tickets as server-sent
+// events, starting with the number of tickets in each status (staff)
+func StreamSupportTickets(c *gin.Context) {
+	operatorID, ok := requireOperator(c)
+	if !ok {
+		return
+	}
+
+	// Subscribe before the first snapshot so no update is missed in between
+	events, unsubscribe := eventBroker.Subscribe(services.SupportTopic(operatorID))
+	defer unsubscribe()
+
+	counts, err := repository.NewSupportRepository(operatorDB(c)).CountByStatus()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	streamEvents(c, events, "support", gin.H{"counts": counts})
+}
+
+// openSupportTicket opens the ticket with the first message and attachments of req
+func openSupportTicket(c *gin.Context, ticket *models.SupportTicket, req *SupportTicketRequest) {
+	if err := ticket.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	uploads, closeUploads, err := supportUploads(c)
+	if err != nil {
+		respondSupportError(c, err)
+		return
+	}
+	defer closeUploads()
+
+	var message *models.SupportMessage
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		message, err = newSupportService(tx).Open(ticket, req.BookingCode, req.Body, uploads, time.Now())
+		return err
+	})
+	if err != nil {
+		respondSupportError(c, err)
+		return
+	}
+	notifySupport(ticket, services.SupportEventCreated)
+
+	ticket.Messages = []models.SupportMessage{*message}
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Đã gửi yêu cầu hỗ trợ",
+		"ticket":  ticket,
+	})
+}
+
+// ticket builds the ticket a request opens, without its customer
+func (req *SupportTicketRequest) ticket() *models.SupportTicket {
+	return &models.SupportTicket{
+		Category:   req.Category,
+		Subject:    req.Subject,
+		OperatorID: req.OperatorID,
+	}
+}
+
+// findCustomerTicket loads the :code ticket for its customer, who proves it is theirs with
+// the contact phone. Unknown codes and wrong phones get the same answer.
+func findCustomerTicket(c *gin.Context, phone string) (*models.SupportTicket, bool) {
+	ticket, err := repository.NewSupportRepository(config.DB).FindByCode(c.Param("code"))
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return nil, false
+	}
+	if err != nil || phone == "" || ticket.ContactPhone != phone {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy yêu cầu hỗ trợ"})
+		return nil, false
+	}
+
+	// Staff accounts stay private to the operator
+	ticket.Assignee = nil
+	return ticket, true
+}
+
+// findStaffTicket loads the :id ticket of the operator, responding with an error if it cannot
+func findStaffTicket(c *gin.Context) (*models.SupportTicket, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID không hợp lệ"})
+		return nil, false
+	}
+
+	ticket, err := repository.NewSupportRepository(operatorDB(c)).FindByID(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy yêu cầu hỗ trợ"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return nil, false
+	}
+	return ticket, true
+}
+
+// supportUploads opens the files sent in the attachments field of a multipart request.
+// The returned function closes them.
+func supportUploads(c *gin.Context) ([]services.SupportUpload, func(), error) {
+	closeAll := func() {}
+	if c.ContentType() != "multipart/form-data" {
+		return nil, closeAll, nil
+	}
+	form, err := c.MultipartForm()
+	if err != nil {
+		return nil, closeAll, err
+	}
+
+	cfg := services.SupportConfigFromEnv()
+	headers := form.File["attachments"]
+	if len(headers) > cfg.MaxAttachments {
+		return nil, closeAll, services.ErrTooManyAttachments
+	}
+
+	uploads := make([]services.SupportUpload, 0, len(headers))
+	var closers []func() error
+	closeAll = func() {
+		for _, closeFile := range closers {
+			closeFile()
+		}
+	}
+	for _, header := range headers {
+		if header.Size > cfg.MaxAttachmentSize {
+			closeAll()
+			return nil, func() {}, services.ErrAttachmentTooLarge
+		}
+		file, err := header.Open()
+		if err != nil {
+			closeAll()
+			return nil, func() {}, err
+		}
+		closers = append(closers, file.Close)
+		uploads = append(uploads, services.SupportUpload{Name: header.Filename, Content: file})
+	}
+	return uploads, closeAll, nil
+}
+
+// sendSupportAttachment downloads the :attachment_id file of a ticket. Files of internal
+// notes are only sent when internal is set.
+func sendSupportAttachment(c *gin.Context, supportService *services.SupportService, ticket *models.SupportTicket, internal bool) {
+	id, err := strconv.ParseUint(c.Param("attachment_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID không hợp lệ"})
+		return
+	}
+
+	attachment, content, err := supportService.Attachment(ticket, uint(id), internal)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy file đính kèm"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+	defer content.Close()
+
+	c.DataFromReader(http.StatusOK, attachment.Size, attachment.ContentType, content, map[string]string{
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}),
+	})
+}
+
+// supportPage reads the page and limit of a list of tickets (default 20, at most 100)
+func supportPage(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	return page, limit
+}
+
+// notifySupport publishes a change of a ticket to the operator's staff. Failures are only
+// logged: the change is already committed.
+func notifySupport(ticket *models.SupportTicket, reason string) {
+	if err := newSupportNotifier().Notify(ticket, reason); err != nil {
+		log.Printf("Error publishing change of support ticket %s: %v", ticket.Code, err)
+	}
+}
+
+// notifySupportReply tells the other side of the conversation about a new message.
+// Failures are only logged: the message is already saved.
+func notifySupportReply(ticket *models.SupportTicket, message *models.SupportMessage) {
+	if err := newSupportNotifier().Replied(ticket, message); err != nil {
+		log.Printf("Error notifying reply to support ticket %s: %v", ticket.Code, err)
+	}
+}
+
+// newSupportNotifier creates the notifier of support ticket changes
+func newSupportNotifier() *services.SupportNotifier {
+	return services.NewSupportNotifier(eventBroker, services.NewSMSServiceFromEnv())
+}
+
+// newSupportService creates a support service backed by db
+func newSupportService(db *gorm.DB) *services.SupportService {
+	return services.NewSupportService(
+		repository.NewSupportRepository(db),
+		repository.NewBookingRepository(db),
+		repository.NewOperatorRepository(db),
+		repository.NewUserRepository(db),
+		services.NewAttachmentStoreFromEnv(),
+		services.SupportConfigFromEnv(),
+	)
+}
+
+// respondSupportError maps support errors to API responses
+func respondSupportError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy đơn đặt vé hoặc nhà xe"})
+	case errors.Is(err, services.ErrSupportOperatorRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng chọn nhà xe hoặc đơn đặt vé cần hỗ trợ"})
+	case errors.Is(err, services.ErrSupportBookingRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Yêu cầu hoàn tiền cần có mã đặt vé"})
+	case errors.Is(err, services.ErrSupportBookingMismatch):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Đơn đặt vé không thuộc về bạn"})
+	case errors.Is(err, services.ErrSupportTicketClosed):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Yêu cầu hỗ trợ đã đóng"})
+	case errors.Is(err, services.ErrEmptySupportMessage):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng nhập nội dung hoặc đính kèm file"})
+	case errors.Is(err, services.ErrInvalidSupportStatus):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Không thể chuyển sang trạng thái này"})
+	case errors.Is(err, services.ErrInvalidSupportAssignee):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Người phụ trách phải là nhân viên của nhà xe"})
+	case errors.Is(err, services.ErrTooManyAttachments):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Mỗi tin nhắn chỉ được đính kèm tối đa 5 file"})
+	case errors.Is(err, services.ErrAttachmentTooLarge):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File đính kèm không được vượt quá 10MB"})
+	case errors.Is(err, services.ErrAttachmentType):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Chỉ hỗ trợ đính kèm ảnh (JPG, PNG, WEBP) hoặc file PDF"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+	}
+}
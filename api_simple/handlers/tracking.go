@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ticket-management/api_simple/config"
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+	"ticket-management/api_simple/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type PositionPoint struct {
+	Latitude   *float64  `json:"latitude" binding:"required"`
+	Longitude  *float64  `json:"longitude" binding:"required"`
+	SpeedKmh   *float64  `json:"speed_kmh"`
+	Heading    *float64  `json:"heading"`
+	AccuracyM  *float64  `json:"accuracy_m"`
+	RecordedAt time.Time `json:"recorded_at" binding:"required"`
+}
+
+type PostPositionsRequest struct {
+	Points []PositionPoint `json:"points" binding:"required,min=1,dive"`
+}
+
+// GetDriverTrips lists the uncompleted trips assigned to the current driver
+func GetDriverTrips(c *gin.Context) {
+	driver := c.MustGet("user").(*models.User)
+
+	tripRepo := repository.NewTripRepository(config.DB)
+	trips, err := tripRepo.GetTripsByDriver(driver.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	response := make([]TripResponse, len(trips))
+	for i, trip := range trips {
+		response[i] = *formatTripResponse(&trip)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"trips": response,
+		"total": len(response),
+	})
+}
+
+// PostTripPositions stores GPS points sent by the driver's device for their trip.
+// Devices may send several buffered points at once.
+func PostTripPositions(c *gin.Context) {
+	driver := c.MustGet("user").(*models.User)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID không hợp lệ"})
+		return
+	}
+
+	var req PostPositionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dữ liệu vị trí không hợp lệ"})
+		return
+	}
+
+	positions := make([]models.VehiclePosition, len(req.Points))
+	for i, point := range req.Points {
+		positions[i] = models.VehiclePosition{
+			Latitude:   *point.Latitude,
+			Longitude:  *point.Longitude,
+			SpeedKmh:   point.SpeedKmh,
+			Heading:    point.Heading,
+			AccuracyM:  point.AccuracyM,
+			RecordedAt: point.RecordedAt,
+		}
+	}
+
+	tracking, err := newTrackingService().Ingest(driver.ID, uint(id), positions, time.Now())
+	if err != nil {
+		respondTrackingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"accepted": len(positions),
+		"tracking": tracking,
+	})
+}
+
+// GetBookingTracking returns the live position and ETAs of a booking's trip
+func GetBookingTracking(c *gin.Context) {
+	booking, ok := findTrackedBooking(c)
+	if !ok {
+		return
+	}
+
+	tracking, err := newTrackingService().Snapshot(booking.Trip, time.Now())
+	if err != nil {
+		respondTrackingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tracking)
+}
+
+// StreamBookingTracking pushes tracking updates of a booking's trip as server-sent events
+func StreamBookingTracking(c *gin.Context) {
+	booking, ok := findTrackedBooking(c)
+	if !ok {
+		return
+	}
+
+	// Subscribe before the first snapshot so no update is missed in between
+	events, unsubscribe := eventBroker.Subscribe(services.TrackingTopic(booking.TripID))
+	defer unsubscribe()
+
+	tracking, err := newTrackingService().Snapshot(booking.Trip, time.Now())
+	if err != nil {
+		respondTrackingError(c, err)
+		return
+	}
+
+	streamEvents(c, events, "tracking", tracking)
+}
+
+// GetTripTracking returns the live position and ETAs of a trip (admin)
+func GetTripTracking(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID không hợp lệ"})
+		return
+	}
+
+	trip, err := repository.NewTripRepository(operatorDB(c)).FindByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy chuyến đi"})
+		return
+	}
+
+	tracking, err := newTrackingService().Snapshot(trip, time.Now())
+	if err != nil {
+		respondTrackingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tracking)
+}
+
+// GetTripTrack returns the recorded GPS track of a trip (admin).
+// Use `since` (RFC3339) to fetch only new points.
+func GetTripTrack(c *gin.Context) {
+	trip, ok := findManifestTrip(c)
+	if !ok {
+		return
+	}
+
+	var since time.Time
+	var err error
+	if value := c.Query("since"); value != "" {
+		since, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since không hợp lệ (định dạng RFC3339)"})
+			return
+		}
+	}
+
+	positions, err := newTrackingService().Track(trip.ID, since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"positions": positions,
+		"total":     len(positions),
+	})
+}
+
+// findTrackedBooking loads the booking named by the :code parameter and rejects cancelled ones
+func findTrackedBooking(c *gin.Context) (*models.Booking, bool) {
+	bookingRepo := repository.NewBookingRepository(config.DB)
+	booking, err := bookingRepo.FindByCode(c.Param("code"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy đơn đặt vé"})
+		return nil, false
+	}
+	if booking.Status == models.BookingStatusCancelled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Đơn đặt vé đã bị hủy"})
+		return nil, false
+	}
+	if booking.Trip == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy chuyến đi"})
+		return nil, false
+	}
+	return booking, true
+}
+
+// newTrackingService creates a tracking service backed by the database
+func newTrackingService() *services.TrackingService {
+	return services.NewTrackingService(
+		repository.NewVehiclePositionRepository(config.DB),
+		repository.NewTripRepository(config.DB),
+		repository.NewRoutePointRepository(config.DB),
+		eventBroker,
+		services.DefaultTrackingConfig(),
+	)
+}
+
+// respondTrackingError maps tracking errors to API responses
+func respondTrackingError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy chuyến đi"})
+	case errors.Is(err, services.ErrTripNotAssigned):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Bạn không phải tài xế của chuyến này"})
+	case errors.Is(err, services.ErrTripNotTracking):
+		c.JSON(http.StatusConflict, gin.H{"error": "Chuyến đi chưa bắt đầu hoặc đã kết thúc"})
+	case errors.Is(err, services.ErrTooManyPositions):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Số điểm vị trí trong một lần gửi không hợp lệ (tối đa 100)"})
+	case errors.Is(err, services.ErrPositionOutOfRange):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Thời điểm ghi nhận vị trí không hợp lệ"})
+	case errors.Is(err, models.ErrInvalidPosition):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tọa độ không hợp lệ"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+	}
+}
@@ -1,12 +1,12 @@
 package handlers
 
 import (
-	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
 	"ticket-management/api_simple/config"
+	"ticket-management/api_simple/middleware"
 	"ticket-management/api_simple/models"
 	"ticket-management/api_simple/repository"
 	"ticket-management/api_simple/utils"
@@ -16,25 +16,26 @@ import (
 )
 
 type CreateTripRequest struct {
-	RouteID       uint      `json:"route_id" binding:"required"`
-	BusID         uint      `json:"bus_id" binding:"required"`
-	DriverID      uint      `json:"driver_id" binding:"required"`
-	DepartureTime time.Time `json:"departure_time" binding:"required"`
-	Price         float64   `json:"price" binding:"required,gt=0"`
-	Note          string    `json:"note"`
+	RouteID       uint         `json:"route_id" binding:"required"`
+	BusID         uint         `json:"bus_id" binding:"required"`
+	DriverID      uint         `json:"driver_id" binding:"required"`
+	DepartureTime time.Time    `json:"departure_time" binding:"required"`
+	Price         models.Money `json:"price" binding:"required,gt=0"`
+	Note          string       `json:"note"`
 }
 
 type UpdateTripRequest struct {
-	DriverID      uint      `json:"driver_id"`
-	DepartureTime time.Time `json:"departure_time"`
-	Price         float64   `json:"price" binding:"omitempty,gt=0"`
-	IsActive      *bool     `json:"is_active"`
-	IsCompleted   *bool     `json:"is_completed"`
-	Note          string    `json:"note"`
+	DriverID      uint         `json:"driver_id"`
+	DepartureTime time.Time    `json:"departure_time"`
+	Price         models.Money `json:"price" binding:"omitempty,gt=0"`
+	IsActive      *bool        `json:"is_active"`
+	IsCompleted   *bool        `json:"is_completed"`
+	Note          string       `json:"note"`
 }
 
 type TripResponse struct {
 	ID            uint          `json:"id"`
+	OperatorID    uint          `json:"operator_id"` // Nhà xe khai thác
 	RouteID       uint          `json:"route_id"`
 	Route         *models.Route `json:"route,omitempty"`
 	BusID         uint          `json:"bus_id"`
@@ -42,7 +43,7 @@ type TripResponse struct {
 	DriverID      uint          `json:"driver_id"`
 	Driver        *models.User  `json:"driver,omitempty"`
 	DepartureTime string        `json:"departure_time"`
-	Price         float64       `json:"price"`
+	Price         models.Money  `json:"price"`
 	IsActive      bool          `json:"is_active"`
 	IsCompleted   bool          `json:"is_completed"`
 	TotalSeats    int           `json:"total_seats"`
@@ -50,6 +51,8 @@ type TripResponse struct {
 	Note          string        `json:"note"`
 	CreatedAt     string        `json:"created_at"`
 	UpdatedAt     string        `json:"updated_at"`
+
+	Currency models.Currency `json:"currency"` // Đơn vị tiền tệ của giá vé
 }
 
 // SearchTrips searches trips with filters
@@ -58,15 +61,29 @@ func SearchTrips(c *gin.Context) {
 
 	// Get query parameters
 	routeID, _ := strconv.ParseUint(c.Query("route_id"), 10, 64)
+	origin := c.Query("origin")
+	destination := c.Query("destination")
+	date := c.Query("date")
 	fromDate := c.Query("from_date")
 	toDate := c.Query("to_date")
 	minPrice, _ := strconv.ParseFloat(c.Query("min_price"), 64)
 	maxPrice, _ := strconv.ParseFloat(c.Query("max_price"), 64)
+	operatorID, _ := strconv.ParseUint(c.Query("operator_id"), 10, 64)
 
 	// Build filters
 	filters := make(map[string]interface{})
 	if routeID > 0 {
 		filters["route_id"] = routeID
+	} else if origin != "" && destination != "" {
+		routeIDs, err := repository.NewRouteRepository(config.DB).FindIDsByCorridor(origin, destination)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+			return
+		}
+		filters["route_id"] = routeIDs
+	}
+	if operatorID > 0 {
+		filters["operator_id"] = operatorID
 	}
 	if minPrice > 0 {
 		filters["price >= ?"] = minPrice
@@ -89,6 +106,15 @@ func SearchTrips(c *gin.Context) {
 			toTime = &t
 		}
 	}
+	if date != "" {
+		day, err := time.ParseInLocation(models.TravelDateFormat, date, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Ngày đi không hợp lệ (YYYY-MM-DD)"})
+			return
+		}
+		endOfDay := day.Add(24*time.Hour - time.Nanosecond)
+		fromTime, toTime = &day, &endOfDay
+	}
 
 	trips, err := tripRepo.SearchTrips(filters, fromTime, toTime)
 	if err != nil {
@@ -96,6 +122,16 @@ func SearchTrips(c *gin.Context) {
 		return
 	}
 
+	// Searches naming a route or an origin and destination feed demand analytics
+	if routeID > 0 {
+		if route, err := repository.NewRouteRepository(config.DB).FindByID(uint(routeID)); err == nil {
+			id := route.ID
+			recordTripSearch(route.Origin, route.Destination, fromTime, &id, trips)
+		}
+	} else if origin != "" && destination != "" {
+		recordTripSearch(origin, destination, fromTime, nil, trips)
+	}
+
 	// Format response
 	response := make([]TripResponse, len(trips))
 	for i, trip := range trips {
@@ -187,6 +223,11 @@ func CreateTrip(c *gin.Context) {
 		return
 	}
 
+	if _, ok := requireOperator(c); !ok {
+		return
+	}
+	db := operatorDB(c)
+
 	trip := models.Trip{
 		RouteID:       req.RouteID,
 		BusID:         req.BusID,
@@ -203,7 +244,25 @@ func CreateTrip(c *gin.Context) {
 		return
 	}
 
-	tripRepo := repository.NewTripRepository(config.DB)
+	// Route, bus and driver must belong to the operator
+	if err := newOperatorService(db).AssignTrip(&trip); err != nil {
+		respondOperatorError(c, err)
+		return
+	}
+
+	// Bus and driver must be free for the whole trip
+	if err := newScheduleService(db).CheckTrip(&trip); err != nil {
+		respondScheduleError(c, err)
+		return
+	}
+
+	// Driver must hold a valid licence and stay within duty limits
+	if err := newDutyService(db).CheckAssignment(&trip); err != nil {
+		respondDutyError(c, err)
+		return
+	}
+
+	tripRepo := repository.NewTripRepository(db)
 	if err := tripRepo.Create(&trip); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
 		return
@@ -217,6 +276,15 @@ func CreateTrip(c *gin.Context) {
 		return
 	}
 
+	tripStatsChanged(trip.ID)
+
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "trip.create",
+		EntityType: "trips",
+		EntityID:   trip.ID,
+		After:      trip,
+	})
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Tạo chuyến đi thành công",
 		"trip":    formatTripResponse(&trip),
@@ -235,7 +303,8 @@ func UpdateTrip(c *gin.Context) {
 		return
 	}
 
-	tripRepo := repository.NewTripRepository(config.DB)
+	db := operatorDB(c)
+	tripRepo := repository.NewTripRepository(db)
 
 	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
@@ -249,6 +318,8 @@ func UpdateTrip(c *gin.Context) {
 		return
 	}
 
+	before := *trip
+
 	// Update fields if provided
 	if req.DriverID != 0 {
 		trip.DriverID = req.DriverID
@@ -279,11 +350,44 @@ func UpdateTrip(c *gin.Context) {
 		return
 	}
 
+	// A new driver must work for the operator of the trip
+	if trip.DriverID != before.DriverID {
+		if err := newOperatorService(db).AssignTrip(trip); err != nil {
+			respondOperatorError(c, err)
+			return
+		}
+	}
+
+	// Re-check availability when the assignment of a scheduled trip changes, or when
+	// a cancelled or completed trip is put back on the schedule
+	if trip.IsActive && !trip.IsCompleted &&
+		(trip.DriverID != before.DriverID || !trip.DepartureTime.Equal(before.DepartureTime) ||
+			trip.IsActive != before.IsActive || trip.IsCompleted != before.IsCompleted) {
+		if err := newScheduleService(db).CheckTrip(trip); err != nil {
+			respondScheduleError(c, err)
+			return
+		}
+		if err := newDutyService(db).CheckAssignment(trip); err != nil {
+			respondDutyError(c, err)
+			return
+		}
+	}
+
 	if err := tripRepo.Update(trip); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
 		return
 	}
 
+	tripStatsChanged(trip.ID)
+
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "trip.update",
+		EntityType: "trips",
+		EntityID:   trip.ID,
+		Before:     before,
+		After:      trip,
+	})
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Cập nhật chuyến đi thành công",
 		"trip":    formatTripResponse(trip),
@@ -292,7 +396,8 @@ func UpdateTrip(c *gin.Context) {
 
 // DeleteTrip soft deletes a trip and related bookings
 func DeleteTrip(c *gin.Context) {
-	tripRepo := repository.NewTripRepository(config.DB)
+	db := operatorDB(c)
+	tripRepo := repository.NewTripRepository(db)
 
 	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
@@ -308,7 +413,7 @@ func DeleteTrip(c *gin.Context) {
 	}
 
 	// Delete in transaction to ensure data consistency
-	err = config.DB.Transaction(func(tx *gorm.DB) error {
+	err = db.Transaction(func(tx *gorm.DB) error {
 		// First, delete all bookings related to this trip
 		if err := tx.Where("trip_id = ?", id).Delete(&models.Booking{}).Error; err != nil {
 			return err
@@ -332,6 +437,15 @@ func DeleteTrip(c *gin.Context) {
 		return
 	}
 
+	tripStatsChanged(trip.ID)
+
+	middleware.SetAudit(c, middleware.AuditRecord{
+		Action:     "trip.delete",
+		EntityType: "trips",
+		EntityID:   trip.ID,
+		Before:     trip,
+	})
+
 	c.JSON(http.StatusOK, gin.H{
 		"message":         "Xóa chuyến đi và tất cả booking liên quan thành công",
 		"deleted_trip_id": id,
@@ -342,6 +456,7 @@ func DeleteTrip(c *gin.Context) {
 func formatTripResponse(trip *models.Trip) *TripResponse {
 	return &TripResponse{
 		ID:            trip.ID,
+		OperatorID:    trip.OperatorID,
 		RouteID:       trip.RouteID,
 		Route:         trip.Route,
 		BusID:         trip.BusID,
@@ -357,6 +472,8 @@ func formatTripResponse(trip *models.Trip) *TripResponse {
 		Note:          trip.Note,
 		CreatedAt:     trip.CreatedAt.Format("2006-01-02 15:04:05"),
 		UpdatedAt:     trip.UpdatedAt.Format("2006-01-02 15:04:05"),
+
+		Currency: trip.Currency,
 	}
 }
 
@@ -368,66 +485,9 @@ func createSeatsForTrip(trip *models.Trip) error {
 		return err
 	}
 
-	// Calculate seats per floor
-	seatsPerFloor := bus.SeatCount
-	if bus.FloorCount == 2 {
-		seatsPerFloor = bus.SeatCount / 2
-	}
-
-	// Create seats for each floor
-	for floor := 1; floor <= bus.FloorCount; floor++ {
-		if err := createSeatsForFloor(trip.ID, floor, seatsPerFloor, trip.Price); err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
-// createSeatsForFloor creates seats for a specific floor
-func createSeatsForFloor(tripID uint, floor int, seatCount int, basePrice float64) error {
-	floorPrefix := "A"
-	if floor == 2 {
-		floorPrefix = "B"
-	}
-
-	for i := 1; i <= seatCount; i++ {
-		seatNumber := fmt.Sprintf("%s%02d", floorPrefix, i)
-		seatType := models.SeatTypeSingle
-		price := basePrice
-
-		// Special seats (first 4 seats)
-		if i <= 4 {
-			seatType = models.SeatTypeSpecial
-			price = basePrice * 1.2 // +20% for special
-		}
-
-		// Double seats (odd numbers)
-		if i%2 != 0 {
-			seatType = models.SeatTypeDouble
-		}
-
-		// Upstairs premium (+10% for floor 2)
-		if floor == 2 {
-			price = basePrice * 1.1
-			if i <= 4 {
-				price = basePrice * 1.3 // +30% for special upstairs
-			}
-		}
-
-		seat := models.Seat{
-			TripID: tripID,
-			Number: seatNumber,
-			Type:   seatType,
-			Floor:  floor,
-			Status: models.SeatStatusAvailable,
-			Price:  price,
-		}
-
-		if err := config.DB.Create(&seat).Error; err != nil {
-			return err
-		}
+	seats := models.SeatLayout(&bus, trip)
+	if len(seats) == 0 {
+		return nil
 	}
-
-	return nil
+	return config.DB.Create(&seats).Error
 }
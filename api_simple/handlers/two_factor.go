@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"ticket-management/api_simple/config"
+	"ticket-management/api_simple/middleware"
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+	"ticket-management/api_simple/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type TwoFactorLoginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"` // Mã TOTP hoặc mã khôi phục
+}
+
+// SetupTwoFactor starts TOTP enrolment and returns the secret and provisioning URI
+func SetupTwoFactor(c *gin.Context) {
+	user := c.MustGet("user").(*models.User)
+
+	secret, uri, err := newTwoFactorService().BeginEnrollment(user)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":           secret,
+		"provisioning_uri": uri,
+	})
+}
+
+// EnableTwoFactor confirms enrolment with a TOTP code and returns recovery codes
+// together with a token that satisfies the 2FA policy
+func EnableTwoFactor(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng nhập mã xác thực"})
+		return
+	}
+
+	user := c.MustGet("user").(*models.User)
+
+	recoveryCodes, err := newTwoFactorService().ConfirmEnrollment(user, req.Code)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	token, err := middleware.GenerateTwoFactorToken(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Bật xác thực 2 lớp thành công",
+		"recovery_codes": recoveryCodes,
+		"token":          token,
+	})
+}
+
+// DisableTwoFactor turns 2FA off for roles where it is optional
+func DisableTwoFactor(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng nhập mã xác thực"})
+		return
+	}
+
+	user := c.MustGet("user").(*models.User)
+
+	if err := newTwoFactorService().Disable(user, req.Code); err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tắt xác thực 2 lớp thành công"})
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the current user
+func RegenerateRecoveryCodes(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng nhập mã xác thực"})
+		return
+	}
+
+	user := c.MustGet("user").(*models.User)
+
+	recoveryCodes, err := newTwoFactorService().RegenerateRecoveryCodes(user, req.Code)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": recoveryCodes})
+}
+
+// VerifyTwoFactorLogin completes a step-up login and issues the full token
+func VerifyTwoFactorLogin(c *gin.Context) {
+	var req TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Vui lòng điền đầy đủ thông tin"})
+		return
+	}
+
+	userID, err := middleware.ParseMFAChallengeToken(req.MFAToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": utils.ErrTokenInvalid})
+		return
+	}
+
+	userRepo := repository.NewUserRepository(config.DB)
+	user, err := userRepo.FindByID(userID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": utils.ErrTokenInvalid})
+		return
+	}
+
+	// Second factor guesses count towards the same login lockout
+	guard := newLoginGuard()
+	ip := c.ClientIP()
+	if wait, err := guard.Check(user.Phone, ip); err != nil {
+		respondLoginGuardError(c, wait, err)
+		return
+	}
+
+	if err := newTwoFactorService().Verify(user, req.Code); err != nil {
+		if errors.Is(err, services.ErrTwoFactorInvalidCode) {
+			recordLoginFailure(guard, user.Phone, ip)
+		}
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	recordLoginSuccess(guard, user.Phone)
+
+	token, err := middleware.GenerateTwoFactorToken(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token": token,
+		"user": gin.H{
+			"id":    user.ID,
+			"phone": user.Phone,
+			"name":  user.Name,
+			"role":  user.Role,
+		},
+	})
+}
+
+// newTwoFactorService creates a 2FA service backed by the database
+func newTwoFactorService() *services.TwoFactorService {
+	return services.NewTwoFactorService(
+		repository.NewUserRepository(config.DB),
+		repository.NewRecoveryCodeRepository(config.DB),
+	)
+}
+
+// respondTwoFactorError maps 2FA service errors to API responses
+func respondTwoFactorError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrTwoFactorInvalidCode):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Mã xác thực không chính xác"})
+	case errors.Is(err, services.ErrTwoFactorNotEnrolled):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tài khoản chưa đăng ký xác thực 2 lớp"})
+	case errors.Is(err, services.ErrTwoFactorAlreadyEnabled):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Xác thực 2 lớp đã được bật"})
+	case errors.Is(err, services.ErrTwoFactorRequiredForRole):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Vai trò này bắt buộc sử dụng xác thực 2 lớp"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+	}
+}
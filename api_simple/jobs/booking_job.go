@@ -7,6 +7,7 @@ import (
 	"ticket-management/api_simple/config"
 	"ticket-management/api_simple/models"
 	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
 
 	"gorm.io/gorm"
 )
@@ -16,23 +17,44 @@ const (
 	BookingTimeout = 15
 )
 
-// StartBookingJobs starts all booking-related background jobs
-func StartBookingJobs() {
-	go CancelUnpaidBookings()
+// StartBookingJobs starts all booking-related background jobs.
+// Seat changes they make are published on broker and marked on stats.
+func StartBookingJobs(broker services.Broker, stats *services.StatsTracker) {
+	go CancelUnpaidBookings(broker, stats)
+	go UnlockExpiredSeats(broker)
 }
 
-// CancelUnpaidBookings cancels all unpaid bookings that have exceeded the timeout
-func CancelUnpaidBookings() {
+// UnlockExpiredSeats frees seats whose lock ran out before a booking was made
+func UnlockExpiredSeats(broker services.Broker) {
 	ticker := time.NewTicker(1 * time.Minute)
 	defer ticker.Stop()
 
 	for range ticker.C {
-		bookingRepo := repository.NewBookingRepository(config.DB)
 		seatRepo := repository.NewSeatRepository(config.DB)
-		tripRepo := repository.NewTripRepository(config.DB)
 
+		seats, err := seatRepo.UnlockExpiredSeats()
+		if err != nil {
+			log.Printf("Error unlocking expired seats: %v", err)
+			continue
+		}
+		if len(seats) == 0 {
+			continue
+		}
+
+		if err := services.NewSeatNotifier(broker).NotifySeats(services.SeatEventLockExpired, seats); err != nil {
+			log.Printf("Error publishing expired seat locks: %v", err)
+		}
+	}
+}
+
+// CancelUnpaidBookings cancels all unpaid bookings that have exceeded the timeout
+func CancelUnpaidBookings(broker services.Broker, stats *services.StatsTracker) {
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
 		// Find pending bookings that have exceeded the timeout
-		bookings, err := bookingRepo.FindPendingBookings(BookingTimeout)
+		bookings, err := repository.NewBookingRepository(config.DB).FindPendingBookings(BookingTimeout)
 		if err != nil {
 			log.Printf("Error finding pending bookings: %v", err)
 			continue
@@ -41,8 +63,11 @@ func CancelUnpaidBookings() {
 		for _, booking := range bookings {
 			// Cancel booking in transaction
 			err := config.DB.Transaction(func(tx *gorm.DB) error {
+				seatRepo := repository.NewSeatRepository(tx)
+				tripRepo := repository.NewTripRepository(tx)
+
 				// Update booking status
-				if err := bookingRepo.UpdateStatus(booking.ID, models.BookingStatusCancelled); err != nil {
+				if err := repository.NewBookingRepository(tx).UpdateStatus(booking.ID, models.BookingStatusCancelled); err != nil {
 					return err
 				}
 
@@ -63,7 +88,9 @@ func CancelUnpaidBookings() {
 					return err
 				}
 
-				return nil
+				// Reverse the revenue booked for the unpaid seats
+				ledgerService := services.NewLedgerService(repository.NewLedgerRepository(tx))
+				return ledgerService.RecordCancellation(&booking, time.Now())
 			})
 
 			if err != nil {
@@ -72,6 +99,12 @@ func CancelUnpaidBookings() {
 			}
 
 			log.Printf("Successfully cancelled booking %d", booking.ID)
+			stats.TripChanged(booking.TripID)
+
+			notifier := services.NewSeatNotifier(broker)
+			if err := notifier.NotifyIDs(booking.TripID, services.SeatEventReleased, models.SeatStatusAvailable, booking.SeatIDs, nil); err != nil {
+				log.Printf("Error publishing released seats of booking %d: %v", booking.ID, err)
+			}
 		}
 	}
 }
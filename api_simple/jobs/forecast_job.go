@@ -0,0 +1,40 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"ticket-management/api_simple/config"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+)
+
+// StartForecastJobs starts the job forecasting the demand of upcoming trips
+func StartForecastJobs() {
+	go RefreshForecasts()
+}
+
+// RefreshForecasts forecasts the demand of every upcoming trip at startup and then every
+// six hours, so new trips, holidays and bookings are taken into account
+func RefreshForecasts() {
+	ticker := time.NewTicker(6 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		count, err := newForecastService().Refresh(time.Now())
+		if err != nil {
+			log.Printf("Error forecasting trip demand: %v", err)
+		} else {
+			log.Printf("Forecast demand of %d upcoming trips", count)
+		}
+		<-ticker.C
+	}
+}
+
+func newForecastService() *services.ForecastService {
+	return services.NewForecastService(
+		repository.NewForecastRepository(config.DB),
+		repository.NewHolidayRepository(config.DB),
+		services.ForecastConfigFromEnv(),
+	)
+}
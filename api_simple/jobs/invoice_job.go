@@ -0,0 +1,40 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"ticket-management/api_simple/config"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+)
+
+// StartInvoiceJobs starts all e-invoice background jobs
+func StartInvoiceJobs() {
+	go SubmitPendingInvoices()
+}
+
+// SubmitPendingInvoices retries sending invoices the e-invoice provider has not
+// accepted yet, including refund adjustments issued during cancellations
+func SubmitPendingInvoices() {
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		invoiceService := services.NewInvoiceService(
+			repository.NewInvoiceRepository(config.DB),
+			repository.NewOperatorRepository(config.DB),
+			services.NewInvoiceProviderFromEnv(),
+			services.InvoiceConfigFromEnv(),
+		)
+
+		sent, err := invoiceService.SubmitPending(50, time.Now())
+		if err != nil {
+			log.Printf("Error submitting pending invoices: %v", err)
+			continue
+		}
+		if sent > 0 {
+			log.Printf("Submitted %d pending invoices", sent)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"ticket-management/api_simple/config"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+)
+
+// StartMaintenanceJobs starts all fleet maintenance background jobs
+func StartMaintenanceJobs() {
+	go LogFleetAlerts()
+}
+
+// LogFleetAlerts logs inspections, registrations and services that are due soon, once a day
+func LogFleetAlerts() {
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		maintenanceService := services.NewMaintenanceService(
+			repository.NewMaintenanceRepository(config.DB),
+			repository.NewBusRepository(config.DB),
+			repository.NewTripRepository(config.DB),
+			services.MaintenanceConfigFromEnv(),
+		)
+
+		alerts, err := maintenanceService.Alerts(time.Now())
+		if err != nil {
+			log.Printf("Error checking fleet alerts: %v", err)
+			continue
+		}
+
+		for _, alert := range alerts {
+			if alert.Overdue {
+				log.Printf("Bus %s: %s is overdue", alert.PlateNumber, alert.Kind)
+				continue
+			}
+			log.Printf("Bus %s: %s is due soon", alert.PlateNumber, alert.Kind)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"ticket-management/api_simple/config"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+)
+
+// StartOTPJobs starts the job removing old one-time passwords
+func StartOTPJobs() {
+	go DeleteExpiredOTPs()
+}
+
+// DeleteExpiredOTPs removes expired codes every hour. Codes are kept for the lockout
+// duration past their expiry, so deleting them never lifts a lock early.
+func DeleteExpiredOTPs() {
+	ticker := time.NewTicker(1 * time.Hour)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		before := time.Now().Add(-services.DefaultOTPConfig().LockoutDuration)
+		if err := repository.NewOTPRepository(config.DB).DeleteExpired(before); err != nil {
+			log.Printf("Error deleting expired OTP codes: %v", err)
+		}
+	}
+}
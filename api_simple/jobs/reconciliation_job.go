@@ -0,0 +1,78 @@
+package jobs
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"ticket-management/api_simple/config"
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/providers"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+
+	"gorm.io/gorm"
+)
+
+// StartReconciliationJobs starts the payment reconciliation background jobs when a
+// settlement inbox is configured
+func StartReconciliationJobs() {
+	source := services.NewSettlementSourceFromEnv()
+	if source == nil {
+		return
+	}
+	go ImportSettlementFiles(source)
+}
+
+// ImportSettlementFiles reconciles the settlement files payment providers deliver to the
+// inbox, every hour. Files for unknown operators or that cannot be read are set aside.
+func ImportSettlementFiles(source services.SettlementSource) {
+	ticker := time.NewTicker(1 * time.Hour)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		files, err := source.Fetch()
+		if err != nil {
+			log.Printf("Error reading settlement inbox: %v", err)
+			continue
+		}
+
+		for _, file := range files {
+			err := importSettlementFile(file)
+			switch {
+			case err == nil, errors.Is(err, services.ErrSettlementAlreadyImported):
+				err = source.Done(file, false)
+			case errors.Is(err, gorm.ErrRecordNotFound),
+				errors.Is(err, services.ErrInvalidSettlementFile),
+				errors.Is(err, services.ErrSettlementEmpty),
+				errors.Is(err, services.ErrInvalidSettlementProvider):
+				log.Printf("Rejected settlement file %s: %v", file.Path, err)
+				err = source.Done(file, true)
+			}
+			// Other errors leave the file in the inbox for the next run
+			if err != nil {
+				log.Printf("Error importing settlement file %s: %v", file.Path, err)
+			}
+		}
+	}
+}
+
+// importSettlementFile reconciles one file for the operator whose folder it was delivered to
+func importSettlementFile(file providers.SettlementFile) error {
+	operator, err := repository.NewOperatorRepository(config.DB).FindOne(map[string]interface{}{"code": file.OperatorCode})
+	if err != nil {
+		return err
+	}
+
+	db := repository.WithOperator(config.DB, operator.ID)
+	reconciliationService := services.NewReconciliationService(
+		repository.NewReconciliationRepository(db),
+		repository.NewBookingRepository(db),
+	)
+	report, err := reconciliationService.Import(operator.ID, models.PaymentAccountMethod(file.Provider), file.Name, file.Data, nil)
+	if err != nil {
+		return err
+	}
+	log.Printf("Reconciled settlement file %s of %s: %d matched, %d to review", file.Name, file.OperatorCode, report.MatchedCount, report.IssueCount)
+	return nil
+}
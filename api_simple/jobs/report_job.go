@@ -0,0 +1,44 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"ticket-management/api_simple/config"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+)
+
+// StartReportJobs starts the job emailing scheduled reports
+func StartReportJobs() {
+	go DeliverScheduledReports(services.NewMailServiceFromEnv())
+}
+
+// DeliverScheduledReports emails the scheduled reports that are due, checking every minute
+func DeliverScheduledReports(mail services.MailService) {
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		deliverDueReports(mail, time.Now())
+	}
+}
+
+func deliverDueReports(mail services.MailService, now time.Time) {
+	reportRepo := repository.NewScheduledReportRepository(config.DB)
+	reports, err := reportRepo.FindDue(now)
+	if err != nil {
+		log.Printf("Error finding due scheduled reports: %v", err)
+		return
+	}
+
+	scheduledReportService := services.NewScheduledReportService(reportRepo, mail)
+	for i := range reports {
+		// Each report only covers the data of its operator
+		db := repository.WithOperator(config.DB, reports[i].OperatorID)
+		reportService := services.NewReportService(repository.NewReportRepository(db), repository.NewBookingRepository(db))
+		if err := scheduledReportService.Deliver(&reports[i], reportService, now); err != nil {
+			log.Printf("Error delivering scheduled report %d: %v", reports[i].ID, err)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"ticket-management/api_simple/config"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+)
+
+// StartStatsJobs starts the jobs keeping route, trip and bus statistics current.
+// Trips marked on tracker are refreshed shortly after they change.
+func StartStatsJobs(tracker *services.StatsTracker) {
+	go RefreshChangedStats(tracker)
+	go RefreshAllStats()
+}
+
+// RefreshChangedStats recomputes the statistics of the trips marked as changed, and of
+// their routes and buses, every 30 seconds
+func RefreshChangedStats(tracker *services.StatsTracker) {
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		tripIDs := tracker.Drain()
+		if len(tripIDs) == 0 {
+			continue
+		}
+
+		if err := newStatsService().RefreshTrips(tripIDs, time.Now()); err != nil {
+			log.Printf("Error refreshing statistics of %d trips: %v", len(tripIDs), err)
+			// Try again on the next tick
+			tracker.TripChanged(tripIDs...)
+		}
+	}
+}
+
+// RefreshAllStats recomputes the statistics of every route and bus at startup and then
+// every hour, so upcoming trip counts follow the clock and missed changes are caught up
+func RefreshAllStats() {
+	ticker := time.NewTicker(1 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		if err := newStatsService().RefreshAll(time.Now()); err != nil {
+			log.Printf("Error refreshing route and bus statistics: %v", err)
+		}
+		<-ticker.C
+	}
+}
+
+func newStatsService() *services.StatsService {
+	return services.NewStatsService(
+		repository.NewTripRepository(config.DB),
+		repository.NewRouteRepository(config.DB),
+		repository.NewBusRepository(config.DB),
+	)
+}
@@ -0,0 +1,55 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"ticket-management/api_simple/config"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+)
+
+// StartSupportJobs starts the job keeping the SLA timers of support tickets.
+// Breaches are published on broker for the staff of each operator.
+func StartSupportJobs(broker services.Broker) {
+	go CheckSupportTickets(services.NewSupportNotifier(broker, services.NewSMSServiceFromEnv()))
+}
+
+// CheckSupportTickets records the support tickets that missed their SLA and closes the
+// tickets resolved long enough ago, every 5 minutes
+func CheckSupportTickets(notifier *services.SupportNotifier) {
+	ticker := time.NewTicker(5 * time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		supportService := services.NewSupportService(
+			repository.NewSupportRepository(config.DB),
+			repository.NewBookingRepository(config.DB),
+			repository.NewOperatorRepository(config.DB),
+			repository.NewUserRepository(config.DB),
+			services.NewAttachmentStoreFromEnv(),
+			services.SupportConfigFromEnv(),
+		)
+		now := time.Now()
+
+		breached, err := supportService.CheckSLA(now)
+		if err != nil {
+			log.Printf("Error checking support SLA: %v", err)
+		}
+		for i := range breached {
+			log.Printf("Support ticket %s missed its SLA", breached[i].Code)
+			if err := notifier.Notify(&breached[i], services.SupportEventSLABreached); err != nil {
+				log.Printf("Error publishing SLA breach of support ticket %s: %v", breached[i].Code, err)
+			}
+		}
+
+		closed, err := supportService.AutoClose(now)
+		if err != nil {
+			log.Printf("Error closing resolved support tickets: %v", err)
+			continue
+		}
+		if closed > 0 {
+			log.Printf("Closed %d resolved support tickets", closed)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -10,7 +11,9 @@ import (
 	"ticket-management/api_simple/jobs"
 	"ticket-management/api_simple/middleware"
 	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
 	"ticket-management/api_simple/seeders"
+	"ticket-management/api_simple/services"
 
 	"github.com/gin-contrib/cors"
 
@@ -29,23 +32,84 @@ func main() {
 	// Initialize database
 	config.InitDB()
 
+	// Keep each operator's data apart
+	if err := repository.RegisterOperatorScope(config.DB); err != nil {
+		log.Fatalf("Error registering operator scope: %v", err)
+	}
+
 	// config.InitRedis()
 
+	// Amounts are whole đồng; convert columns left as floating point by older versions
+	if err := repository.MigrateMoneyColumns(config.DB); err != nil {
+		log.Fatalf("Error migrating money columns: %v", err)
+	}
+
+	// Bookings made before the sales channel was stored get the channel they used to be reported under
+	if err := repository.MigrateBookingChannels(config.DB); err != nil {
+		log.Fatalf("Error migrating booking channels: %v", err)
+	}
+
 	// Auto migrate database
 	config.DB.AutoMigrate(
+		&models.Operator{},
+		&models.OperatorPaymentAccount{},
 		&models.User{},
 		&models.Route{},
 		&models.Bus{},
 		&models.Trip{},
 		&models.Seat{},
 		&models.Booking{},
+		&models.OTPCode{},
+		&models.LoginThrottle{},
+		&models.RecoveryCode{},
+		&models.APIClient{},
+		&models.AuditLog{},
+		&models.DriverProfile{},
+		&models.MaintenanceRecord{},
+		&models.BusUnavailability{},
+		&models.VehiclePosition{},
+		&models.RoutePoint{},
+		&models.Shipment{},
+		&models.ShipmentEvent{},
+		&models.CashShift{},
+		&models.CashTransaction{},
+		&models.InvoiceSeries{},
+		&models.Invoice{},
+		&models.InvoiceLine{},
+		&models.LedgerTransaction{},
+		&models.LedgerEntry{},
+		&models.SettlementReport{},
+		&models.SettlementEntry{},
+		&models.TripSearch{},
+		&models.Holiday{},
+		&models.TripForecast{},
+		&models.ScheduledReport{},
+		&models.SupportTicket{},
+		&models.SupportMessage{},
+		&models.SupportAttachment{},
 	)
 
 	// Seed database
 	seeders.Seed()
 
+	// Live seat and tracking updates
+	broker := newEventBroker()
+	handlers.SetEventBroker(broker)
+
+	// Trips whose bookings or schedule changed, for the statistics refresh job
+	stats := services.NewStatsTracker()
+	handlers.SetStatsTracker(stats)
+
 	// Start background jobs
-	jobs.StartBookingJobs()
+	jobs.StartBookingJobs(broker, stats)
+	jobs.StartMaintenanceJobs()
+	jobs.StartInvoiceJobs()
+	jobs.StartReconciliationJobs()
+	jobs.StartStatsJobs(stats)
+	jobs.StartForecastJobs()
+	jobs.StartReportJobs()
+	jobs.StartSupportJobs(broker)
+	jobs.StartOTPJobs()
 
 	// Initialize router
 	router := gin.Default()
@@ -54,12 +118,15 @@ func main() {
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", middleware.RequestIDHeader, middleware.OperatorHeader},
+		ExposeHeaders:    []string{"Content-Length", middleware.RequestIDHeader},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 
+	// Request ID for log and audit correlation
+	router.Use(middleware.RequestIDMiddleware())
+
 	// Setup routes
 	api := router.Group("/api/v1")
 	setupRoutes(api)
@@ -73,17 +140,43 @@ func main() {
 	router.Run(fmt.Sprintf(":%s", port))
 }
 
+// newEventBroker returns a Redis pub/sub broker when EVENT_BROKER=redis, so live updates
+// reach clients connected to any API instance, and an in-memory broker otherwise
+func newEventBroker() services.Broker {
+	if os.Getenv("EVENT_BROKER") != "redis" {
+		return services.NewMemoryBroker(64)
+	}
+
+	if config.RedisClient == nil {
+		config.InitRedis()
+	}
+	broker := services.NewRedisBroker(config.RedisClient, "ticket-events:", 64)
+	go func() {
+		if err := broker.Run(context.Background(), nil); err != nil {
+			log.Printf("Event broker stopped: %v", err)
+		}
+	}()
+	return broker
+}
+
 func setupRoutes(api *gin.RouterGroup) {
 	// Public routes
 	api.POST("/auth/register", handlers.Register)
 	api.POST("/auth/login", handlers.Login)
 	api.POST("/auth/forgot-password", handlers.ForgotPassword)
 	api.POST("/auth/verify-otp", handlers.VerifyOTP)
+	api.POST("/auth/request-otp", handlers.RequestOtp)
+	api.POST("/auth/unlock", handlers.UnlockAccount)
+	api.POST("/auth/2fa/verify", handlers.VerifyTwoFactorLogin)
 	api.POST("/auth/reset-password", handlers.ResetPassword)
 
+	api.GET("/operators", handlers.GetOperators)
+	api.GET("/operators/:id", handlers.GetOperator)
+
 	api.GET("/routes", handlers.GetRoutes)
 	api.GET("/routes/popular", handlers.GetPopularRoutes)
 	api.GET("/routes/:id", handlers.GetRoute)
+	api.GET("/routes/:id/points", handlers.GetRoutePoints)
 
 	api.GET("/buses", handlers.GetBuses)
 	api.GET("/buses/:id", handlers.GetBus)
@@ -92,6 +185,7 @@ func setupRoutes(api *gin.RouterGroup) {
 	api.GET("/trips/available", handlers.GetAvailableTrips)
 	api.GET("/trips/:id", handlers.GetTrip)
 	api.GET("/trips/:id/seats", handlers.GetTripSeats)
+	api.GET("/trips/:id/seats/stream", handlers.StreamTripSeats)
 	api.GET("/trips/:id/seats/available", handlers.GetAvailableSeats)
 	api.POST("/trips/:id/seats/check", handlers.CheckSeatStatus)
 	api.POST("/trips/:id/seats/lock", handlers.LockSeats)
@@ -100,8 +194,39 @@ func setupRoutes(api *gin.RouterGroup) {
 	// Booking routes (public)
 	api.POST("/bookings", handlers.CreateBooking)
 	api.GET("/bookings/:code", handlers.GetBookingByCode)
+	api.POST("/bookings/lookup", handlers.LookupGuestBookings)
+	api.GET("/bookings/:code/tracking", handlers.GetBookingTracking)
+	api.GET("/bookings/:code/tracking/stream", handlers.StreamBookingTracking)
+	api.POST("/bookings/:code/invoice", handlers.RequestBookingInvoice)
+
+	// VAT invoice lookup (public)
+	api.GET("/invoices/:lookup_code", handlers.GetPublicInvoice)
+	api.GET("/invoices/:lookup_code/xml", handlers.GetPublicInvoiceXML)
+	api.GET("/invoices/:lookup_code/pdf", handlers.GetPublicInvoicePDF)
+
+	api.GET("/shipments/quote", handlers.QuoteShipment)
+	api.GET("/shipments/:code", handlers.TrackShipment)
+
+	// Customer support (public, by ticket code and contact phone)
+	api.POST("/support/tickets/guest", handlers.CreateGuestSupportTicket)
+	api.GET("/support/tickets/:code", handlers.GetSupportTicket)
+	api.POST("/support/tickets/:code/messages", handlers.ReplySupportTicket)
+	api.POST("/support/tickets/:code/close", handlers.CloseSupportTicket)
+	api.GET("/support/tickets/:code/attachments/:attachment_id", handlers.GetSupportAttachment)
 
 	
+	// Partner agency routes (API key)
+	partner := api.Group("/partner")
+	partner.Use(middleware.APIKeyMiddleware())
+	{
+		partner.GET("/trips", middleware.RequireScope(models.APIScopeSearch), handlers.SearchTrips)
+		partner.GET("/trips/:id", middleware.RequireScope(models.APIScopeSearch), handlers.GetTrip)
+		partner.GET("/trips/:id/seats/available", middleware.RequireScope(models.APIScopeSearch), handlers.GetAvailableSeats)
+		partner.GET("/bookings", middleware.RequireScope(models.APIScopeBook), handlers.GetPartnerBookings)
+		partner.POST("/bookings", middleware.RequireScope(models.APIScopeBook), handlers.CreatePartnerBooking)
+		partner.PUT("/bookings/:id/cancel", middleware.RequireScope(models.APIScopeCancel), handlers.PartnerCancelBooking)
+	}
+
 	// Protected routes
 	protected := api.Group("/")
 	protected.Use(middleware.AuthMiddleware())
@@ -113,52 +238,223 @@ func setupRoutes(api *gin.RouterGroup) {
 		protected.POST("/auth/logout", handlers.Logout)
 		protected.POST("/auth/change-password", handlers.ChangePassword)
 
+		// Two-factor authentication
+		protected.POST("/auth/2fa/setup", handlers.SetupTwoFactor)
+		protected.POST("/auth/2fa/enable", handlers.EnableTwoFactor)
+		protected.POST("/auth/2fa/disable", handlers.DisableTwoFactor)
+		protected.POST("/auth/2fa/recovery-codes", handlers.RegenerateRecoveryCodes)
+
 		// Booking routes (authenticated)
 		protected.GET("/bookings", handlers.GetUserBookings)
 		protected.PUT("/bookings/:id/cancel", handlers.CancelBooking)
 
+		// Customer support (authenticated)
+		protected.GET("/support/tickets", handlers.GetUserSupportTickets)
+		protected.POST("/support/tickets", handlers.CreateSupportTicket)
+
+		// Driver devices
+		driver := protected.Group("/driver")
+		driver.Use(middleware.DriverMiddleware())
+		{
+			driver.GET("/trips", handlers.GetDriverTrips)
+			driver.POST("/trips/:id/positions", handlers.PostTripPositions)
+			driver.GET("/trips/:id/manifest", handlers.GetDriverTripManifest)
+			driver.GET("/trips/:id/shipments", handlers.GetDriverTripShipments)
+			driver.PUT("/shipments/:id/status", handlers.UpdateShipmentStatus)
+		}
+
+		// Staff routes (admins included)
+		staff := protected.Group("/staff")
+		staff.Use(middleware.StaffMiddleware())
+		staff.Use(middleware.TwoFactorMiddleware())
+		staff.Use(middleware.OperatorMiddleware())
+		staff.Use(middleware.AuditMiddleware())
+		{
+			staff.GET("/shipments", handlers.GetShipments)
+			staff.POST("/shipments", handlers.CreateShipment)
+			staff.PUT("/shipments/:id/status", handlers.UpdateShipmentStatus)
+			staff.GET("/trips/:id/shipments", handlers.GetTripShipments)
+
+			// Counter sales and cash drawer
+			staff.POST("/bookings", handlers.CreateGuestBooking)
+			staff.POST("/shifts", handlers.OpenShift)
+			staff.GET("/shifts/current", handlers.GetCurrentShift)
+			staff.POST("/shifts/current/payouts", handlers.CreateShiftPayout)
+			staff.POST("/shifts/current/close", handlers.CloseShift)
+
+			// Customer support
+			staff.GET("/support/tickets", handlers.GetSupportTickets)
+			staff.GET("/support/tickets/:id", handlers.GetStaffSupportTicket)
+			staff.POST("/support/tickets/:id/messages", handlers.ReplyStaffSupportTicket)
+			staff.PUT("/support/tickets/:id/assign", handlers.AssignSupportTicket)
+			staff.PUT("/support/tickets/:id/status", handlers.UpdateSupportTicketStatus)
+			staff.GET("/support/tickets/:id/attachments/:attachment_id", handlers.GetStaffSupportAttachment)
+			staff.GET("/support/stream", handlers.StreamSupportTickets)
+		}
+
 		// Admin routes
 		admin := protected.Group("/admin")
 		admin.Use(middleware.AdminMiddleware())
+		admin.Use(middleware.TwoFactorMiddleware())
+		admin.Use(middleware.OperatorMiddleware())
+		admin.Use(middleware.AuditMiddleware())
 		{
+			// Operator settings
+			admin.GET("/operator", handlers.GetCurrentOperator)
+			admin.PUT("/operator", handlers.UpdateCurrentOperator)
+			admin.GET("/operator/payment-accounts", handlers.GetPaymentAccounts)
+			admin.POST("/operator/payment-accounts", handlers.CreatePaymentAccount)
+			admin.PUT("/operator/payment-accounts/:id", handlers.UpdatePaymentAccount)
+			admin.DELETE("/operator/payment-accounts/:id", handlers.DeletePaymentAccount)
+
 			// Route management
 			admin.POST("/routes", handlers.CreateRoute)
 			admin.PUT("/routes/:id", handlers.UpdateRoute)
 			admin.DELETE("/routes/:id", handlers.DeleteRoute)
+			admin.GET("/routes/:id/points", handlers.GetAdminRoutePoints)
+			admin.POST("/routes/:id/points", handlers.CreateRoutePoint)
+			admin.PUT("/routes/:id/points/:point_id", handlers.UpdateRoutePoint)
+			admin.DELETE("/routes/:id/points/:point_id", handlers.DeleteRoutePoint)
 
 			// Bus management
 			admin.POST("/buses", handlers.CreateBus)
 			admin.PUT("/buses/:id", handlers.UpdateBus)
 			admin.DELETE("/buses/:id", handlers.DeleteBus)
+			admin.GET("/buses/:id/statistics", handlers.GetBusStatistics)
+
+			// Fleet maintenance
+			admin.GET("/buses/:id/maintenance", handlers.GetBusMaintenance)
+			admin.POST("/buses/:id/maintenance", handlers.CreateBusMaintenance)
+			admin.POST("/buses/:id/breakdown", handlers.ReportBusBreakdown)
+			admin.POST("/buses/:id/unavailability", handlers.CreateBusUnavailability)
+			admin.PUT("/buses/:id/unavailability/:window_id/resolve", handlers.ResolveBusUnavailability)
+			admin.GET("/fleet/alerts", handlers.GetFleetAlerts)
 
 			// Trip management
 			admin.POST("/trips", handlers.CreateTrip)
 			admin.PUT("/trips/:id", handlers.UpdateTrip)
 			admin.DELETE("/trips/:id", handlers.DeleteTrip)
 			admin.POST("/trips/:id/seats", handlers.CreateSeats)
+			admin.GET("/schedule/suggestions", handlers.GetScheduleSuggestions)
+			admin.GET("/trips/:id/tracking", handlers.GetTripTracking)
+			admin.GET("/trips/:id/track", handlers.GetTripTrack)
+			admin.GET("/trips/:id/manifest", handlers.GetTripManifest)
 
 			// Booking management
 			admin.GET("/bookings", handlers.GetAdminBookings)
+			admin.GET("/bookings/export", handlers.ExportBookings)
 			admin.POST("/create-booking", handlers.CreateGuestBooking)
 			admin.PUT("/bookings/:id/confirm", handlers.ConfirmBooking)
 			admin.PUT("/bookings/:id/payment", handlers.UpdateBookingPayment)
 			admin.PUT("/bookings/:id/status", handlers.UpdateBookingStatus)
 			admin.PUT("/bookings/:id/cancel", handlers.AdminCancelBooking)
 
+			// Counter shift review
+			admin.GET("/shifts", handlers.GetShifts)
+			admin.GET("/shifts/:id", handlers.GetShift)
+			admin.PUT("/shifts/:id/approve", handlers.ApproveShift)
+
+			// VAT invoices
+			admin.GET("/invoices", handlers.GetInvoices)
+			admin.GET("/invoices/:id", handlers.GetInvoice)
+			admin.GET("/invoices/:id/xml", handlers.GetInvoiceXML)
+			admin.GET("/invoices/:id/pdf", handlers.GetInvoicePDF)
+			admin.POST("/bookings/:id/invoice", handlers.IssueBookingInvoice)
+			admin.POST("/invoices/:id/replace", handlers.ReplaceInvoice)
+			admin.POST("/invoices/:id/submit", handlers.SubmitInvoice)
+
+			// Financial ledger
+			admin.GET("/ledger/accounts", handlers.GetLedgerAccounts)
+			admin.GET("/ledger/transactions", handlers.GetLedgerTransactions)
+			admin.POST("/ledger/refund-payouts", handlers.CreateRefundPayout)
+			admin.POST("/ledger/commission-payouts", handlers.CreateCommissionPayout)
+
+			// Payment gateway reconciliation
+			admin.GET("/reconciliation/reports", handlers.GetSettlementReports)
+			admin.POST("/reconciliation/reports", handlers.ImportSettlementReport)
+			admin.GET("/reconciliation/reports/:id", handlers.GetSettlementReport)
+			admin.PUT("/reconciliation/entries/:id/resolve", handlers.ResolveSettlementEntry)
+
 			// User management
 			admin.GET("/users", handlers.GetUsers)
 			admin.POST("/users/create", handlers.CreateUser)
 			admin.PUT("/users/:id", handlers.UpdateUser)
 			admin.DELETE("/users/:id", handlers.DeleteUser)
 			admin.PUT("/users/:id/role", handlers.UpdateUserRole)
+
+			// Driver licences and duty roster
+			admin.PUT("/drivers/:id/profile", handlers.UpdateDriverProfile)
+			admin.GET("/drivers/:id/roster", handlers.GetDriverRoster)
 			admin.GET("/statistics", handlers.GetStatistics)
 
 			// Dashboard APIs
 			admin.GET("/dashboard/stats", handlers.GetDashboardStats)
 			admin.GET("/dashboard/activity", handlers.GetRecentActivity)
 
+			// Revenue and occupancy reports
+			admin.GET("/reports/revenue", handlers.GetRevenueReport)
+			admin.GET("/reports/occupancy", handlers.GetOccupancyReport)
+			admin.GET("/reports/summary", handlers.GetReportSummary)
+
+			// Demand analytics
+			admin.GET("/analytics/demand", handlers.GetDemandAnalytics)
+			admin.GET("/analytics/popular-routes", handlers.GetSeasonalPopularRoutes)
+			admin.GET("/analytics/recommendations", handlers.GetDepartureRecommendations)
+
+			// Demand forecasting
+			admin.GET("/forecasts", handlers.GetTripForecasts)
+			admin.GET("/trips/:id/forecast", handlers.GetTripForecast)
+			admin.GET("/holidays", handlers.GetHolidays)
+
+			// Bulk import
+			admin.POST("/import/:kind", handlers.ImportRecords)
+
+			// Scheduled reports
+			admin.GET("/scheduled-reports", handlers.GetScheduledReports)
+			admin.POST("/scheduled-reports", handlers.CreateScheduledReport)
+			admin.PUT("/scheduled-reports/:id", handlers.UpdateScheduledReport)
+			admin.DELETE("/scheduled-reports/:id", handlers.DeleteScheduledReport)
+			admin.POST("/scheduled-reports/:id/send", handlers.SendScheduledReport)
+
 			// Admin Trip Management
 			admin.GET("/trips/list", handlers.GetAdminTrips)
+
+			// Audit log
+			admin.GET("/audit", handlers.GetAuditLogs)
+			admin.GET("/audit/export", handlers.ExportAuditLogs)
+
+			// Platform administration (super admin only)
+			platform := admin.Group("/")
+			platform.Use(middleware.SuperAdminMiddleware())
+			{
+				// Bus operators
+				platform.GET("/operators", handlers.GetAdminOperators)
+				platform.POST("/operators", handlers.CreateOperator)
+				platform.PUT("/operators/:id", handlers.UpdateOperator)
+
+				// Login lockouts
+				platform.GET("/lockouts", handlers.GetLockouts)
+				platform.DELETE("/lockouts/:id", handlers.ClearLockout)
+
+				// Partner agency API clients
+				platform.GET("/api-clients", handlers.GetAPIClients)
+				platform.POST("/api-clients", handlers.CreateAPIClient)
+				platform.PUT("/api-clients/:id", handlers.UpdateAPIClient)
+				platform.POST("/api-clients/:id/rotate", handlers.RotateAPIClientKey)
+				platform.DELETE("/api-clients/:id", handlers.RevokeAPIClient)
+
+				// Posting bookings made before the ledger
+				platform.POST("/ledger/backfill", handlers.BackfillLedger)
+
+				// Recomputing route, trip and bus statistics
+				platform.POST("/stats/refresh", handlers.RefreshStats)
+
+				// Demand forecasting
+				platform.POST("/forecasts/refresh", handlers.RefreshForecasts)
+				platform.POST("/holidays", handlers.CreateHoliday)
+				platform.PUT("/holidays/:id", handlers.UpdateHoliday)
+				platform.DELETE("/holidays/:id", handlers.DeleteHoliday)
+			}
 		}
 	}
 }
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ticket-management/api_simple/config"
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+	"ticket-management/api_simple/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const APIKeyHeader = "X-API-Key"
+
+// partnerRateLimiter is shared by all requests so limits hold across handlers
+var partnerRateLimiter = services.NewRateLimiter(time.Minute)
+
+// APIKeyMiddleware authenticates partner agencies by API key and applies
+// the per-client rate limit
+func APIKeyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(APIKeyHeader)
+		if key == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Thiếu API key"})
+			c.Abort()
+			return
+		}
+
+		service := services.NewAPIClientService(repository.NewAPIClientRepository(config.DB))
+		client, err := service.Authenticate(key)
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrAPIKeyInvalid):
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "API key không hợp lệ"})
+			case errors.Is(err, services.ErrAPIClientRevoked):
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "API key đã bị thu hồi"})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": utils.ErrServerError})
+			}
+			c.Abort()
+			return
+		}
+
+		allowed, remaining, reset := partnerRateLimiter.Allow(
+			strconv.FormatUint(uint64(client.ID), 10), client.RateLimitPerMinute, time.Now())
+		c.Header("X-RateLimit-Limit", strconv.Itoa(client.RateLimitPerMinute))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(reset.Seconds())+1))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Vượt quá giới hạn số request, vui lòng thử lại sau"})
+			c.Abort()
+			return
+		}
+
+		c.Set("api_client", client)
+		c.Next()
+	}
+}
+
+// RequireScope rejects partner requests whose key was not granted the scope
+func RequireScope(scope models.APIScope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		client, exists := c.Get("api_client")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Thiếu API key"})
+			c.Abort()
+			return
+		}
+
+		if !client.(*models.APIClient).HasScope(scope) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "API key không có quyền " + string(scope)})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
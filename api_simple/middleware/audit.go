@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"ticket-management/api_simple/config"
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const auditContextKey = "audit_record"
+
+// AuditRecord describes what a handler changed so the audit entry carries a diff
+type AuditRecord struct {
+	Action     string
+	EntityType string
+	EntityID   uint
+	Before     interface{}
+	After      interface{}
+}
+
+// SetAudit attaches the change made by a handler to the current request's audit entry
+func SetAudit(c *gin.Context, record AuditRecord) {
+	c.Set(auditContextKey, record)
+}
+
+// AuditMiddleware writes an audit entry for every mutating call in the group.
+// Handlers that call SetAudit contribute the action and a before/after diff;
+// other calls are recorded with the route and status only.
+func AuditMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		c.Next()
+
+		path := c.FullPath()
+		entry := &models.AuditLog{
+			Method:     c.Request.Method,
+			Path:       path,
+			StatusCode: c.Writer.Status(),
+			IP:         c.ClientIP(),
+			RequestID:  c.GetString("request_id"),
+			Action:     c.Request.Method + " " + path,
+			EntityType: auditEntityType(path),
+			EntityID:   c.Param("id"),
+		}
+
+		if user, exists := c.Get("user"); exists {
+			actor := user.(*models.User)
+			entry.ActorID = &actor.ID
+			entry.ActorPhone = actor.Phone
+			entry.ActorRole = actor.Role
+		}
+
+		if operatorID, ok := OperatorID(c); ok {
+			entry.OperatorID = &operatorID
+		}
+
+		var before, after interface{}
+		if value, exists := c.Get(auditContextKey); exists {
+			record := value.(AuditRecord)
+			entry.Action = record.Action
+			entry.EntityType = record.EntityType
+			entry.EntityID = strconv.FormatUint(uint64(record.EntityID), 10)
+			before, after = record.Before, record.After
+		}
+
+		service := services.NewAuditService(repository.NewAuditLogRepository(config.DB))
+		if err := service.Record(entry, before, after); err != nil {
+			log.Printf("[Audit] Failed to record %s by actor %v: %v", entry.Action, entry.ActorID, err)
+		}
+	}
+}
+
+// auditEntityType derives the entity from the route, e.g. /api/v1/admin/bookings/:id -> bookings
+func auditEntityType(path string) string {
+	_, rest, found := strings.Cut(path, "/admin/")
+	if !found {
+		return ""
+	}
+	entity, _, _ := strings.Cut(rest, "/")
+	return entity
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"errors"
 	"log"
 	"net/http"
 	"strings"
@@ -15,16 +16,62 @@ import (
 )
 
 const (
-	TokenExpiration = 24 * time.Hour // Token expires in 24 hours
-	BlacklistPrefix = "blacklist:"   // Prefix for blacklisted tokens in Redis
+	TokenExpiration        = 24 * time.Hour  // Token expires in 24 hours
+	MFAChallengeExpiration = 5 * time.Minute // Step-up login must finish within 5 minutes
+	BlacklistPrefix        = "blacklist:"    // Prefix for blacklisted tokens in Redis
+	MFAChallengePurpose    = "mfa_challenge" // Purpose claim of step-up login tokens
 )
 
-// GenerateToken generates a new JWT token for a user
+// GenerateToken generates a new JWT token for a user authenticated by password only
 func GenerateToken(user *models.User) (string, error) {
+	return generateToken(user, false)
+}
+
+// GenerateTwoFactorToken generates a JWT token for a user who passed the second factor
+func GenerateTwoFactorToken(user *models.User) (string, error) {
+	return generateToken(user, true)
+}
+
+// GenerateMFAChallengeToken generates a short-lived token that can only be
+// exchanged for a full token by completing the second factor
+func GenerateMFAChallengeToken(user *models.User) (string, error) {
+	claims := jwt.MapClaims{
+		"user_id": user.ID,
+		"purpose": MFAChallengePurpose,
+		"exp":     time.Now().Add(MFAChallengeExpiration).Unix(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(config.JWTSecret))
+}
+
+// ParseMFAChallengeToken validates a step-up login token and returns its user ID
+func ParseMFAChallengeToken(tokenString string) (uint, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		return []byte(config.JWTSecret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil || !token.Valid {
+		return 0, errors.New("invalid mfa challenge token")
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["purpose"] != MFAChallengePurpose {
+		return 0, errors.New("invalid mfa challenge token")
+	}
+
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return 0, errors.New("invalid mfa challenge token")
+	}
+	return uint(userID), nil
+}
+
+func generateToken(user *models.User, mfa bool) (string, error) {
 	// Create claims
 	claims := jwt.MapClaims{
 		"user_id": user.ID,
 		"role":    user.Role,
+		"mfa":     mfa,
 		"exp":     time.Now().Add(TokenExpiration).Unix(),
 	}
 
@@ -102,6 +149,13 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// Step-up login tokens cannot be used to call the API
+		if _, isChallenge := claims["purpose"]; isChallenge {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token không hợp lệ"})
+			c.Abort()
+			return
+		}
+
 		// Check expiration
 		exp, ok := claims["exp"].(float64)
 		if !ok {
@@ -139,16 +193,18 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 		
-		log.Printf(" userID found:", userID)
+		log.Printf(" userID found: %v", userID)
 
 		// Set user in context
 		c.Set("user", user)
 		c.Set("userID", userID)
+		c.Set("mfa", claims["mfa"] == true)
 		
 		c.Next()
 	}
 }
 
+// AdminMiddleware lets operator admins and platform super admins through
 func AdminMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		user, exists := c.Get("user")
@@ -158,7 +214,8 @@ func AdminMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		if user.(*models.User).Role != models.RoleAdmin {
+		role := user.(*models.User).Role
+		if role != models.RoleAdmin && role != models.RoleSuperAdmin {
 			c.JSON(http.StatusForbidden, gin.H{"error": "Không có quyền truy cập"})
 			c.Abort()
 			return
@@ -168,14 +225,68 @@ func AdminMiddleware() gin.HandlerFunc {
 	}
 }
 
+// DriverMiddleware only lets driver accounts through
+func DriverMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Vui lòng đăng nhập"})
+			c.Abort()
+			return
+		}
+
+		if user.(*models.User).Role != models.RoleDriver {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Chỉ tài xế mới có quyền truy cập"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// TwoFactorMiddleware enforces the 2FA policy: roles that require it must
+// present a token issued after the second factor was verified
+func TwoFactorMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Vui lòng đăng nhập"})
+			c.Abort()
+			return
+		}
+
+		if models.RequiresTwoFactor(user.(*models.User).Role) && !c.GetBool("mfa") {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":               "Vui lòng bật và xác thực 2 lớp để truy cập",
+				"two_factor_required": true,
+				"two_factor_enrolled": user.(*models.User).TwoFactorEnabled,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// StaffMiddleware lets staff, admin and super admin accounts through
 func StaffMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		role := c.GetString("role")
-		if role != string(models.RoleStaff) && role != string(models.RoleAdmin) {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Staff access required"})
+		user, exists := c.Get("user")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Vui lòng đăng nhập"})
+			c.Abort()
+			return
+		}
+
+		role := user.(*models.User).Role
+		if role != models.RoleStaff && role != models.RoleAdmin && role != models.RoleSuperAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Không có quyền truy cập"})
 			c.Abort()
 			return
 		}
+
 		c.Next()
 	}
 }
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"ticket-management/api_simple/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OperatorHeader lets a super admin act on behalf of one operator
+const OperatorHeader = "X-Operator-ID"
+
+const operatorContextKey = "operator_id"
+
+// OperatorMiddleware resolves the operator a back-office request acts for.
+// Operator accounts always work inside their own operator; super admins see the
+// whole platform unless they pick an operator with the X-Operator-ID header.
+func OperatorMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, exists := c.Get("user")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Vui lòng đăng nhập"})
+			c.Abort()
+			return
+		}
+		user := value.(*models.User)
+
+		if user.Role == models.RoleSuperAdmin {
+			if header := c.GetHeader(OperatorHeader); header != "" {
+				id, err := strconv.ParseUint(header, 10, 64)
+				if err != nil || id == 0 {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Mã nhà xe không hợp lệ"})
+					c.Abort()
+					return
+				}
+				c.Set(operatorContextKey, uint(id))
+			}
+			c.Next()
+			return
+		}
+
+		if user.OperatorID == nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Tài khoản chưa được gán cho nhà xe nào"})
+			c.Abort()
+			return
+		}
+		c.Set(operatorContextKey, *user.OperatorID)
+		c.Next()
+	}
+}
+
+// OperatorID returns the operator the request acts for; false means the whole platform
+func OperatorID(c *gin.Context) (uint, bool) {
+	value, exists := c.Get(operatorContextKey)
+	if !exists {
+		return 0, false
+	}
+	return value.(uint), true
+}
+
+// SuperAdminMiddleware only lets platform super admins through
+func SuperAdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Vui lòng đăng nhập"})
+			c.Abort()
+			return
+		}
+
+		if user.(*models.User).Role != models.RoleSuperAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Chỉ quản trị hệ thống mới có quyền truy cập"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"ticket-management/api_simple/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// RequestIDMiddleware propagates the caller's request ID or generates one,
+// so logs and audit entries can be correlated with a request
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if requestID == "" || len(requestID) > 64 {
+			requestID = utils.GenerateRandomString(20)
+		}
+
+		c.Set("request_id", requestID)
+		c.Header(RequestIDHeader, requestID)
+
+		c.Next()
+	}
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+type APIScope string
+
+const (
+	APIScopeSearch APIScope = "search" // Tìm chuyến và xem ghế
+	APIScopeBook   APIScope = "book"   // Đặt vé cho khách
+	APIScopeCancel APIScope = "cancel" // Hủy vé đã đặt
+)
+
+// IsValid checks whether the scope is supported
+func (s APIScope) IsValid() bool {
+	switch s {
+	case APIScopeSearch, APIScopeBook, APIScopeCancel:
+		return true
+	}
+	return false
+}
+
+// APIClient is a partner agency allowed to call the partner API with an API key
+type APIClient struct {
+	gorm.Model
+	Name               string         `json:"name" gorm:"not null"`                      // Tên đại lý
+	ContactEmail       string         `json:"contact_email"`                             // Email liên hệ
+	ContactPhone       string         `json:"contact_phone"`                             // Số điện thoại liên hệ
+	KeyPrefix          string         `json:"key_prefix" gorm:"index"`                   // Phần đầu của API key để nhận diện
+	KeyHash            string         `json:"-" gorm:"uniqueIndex;not null"`             // API key đã băm
+	Scopes             pq.StringArray `json:"scopes" gorm:"type:text[];not null"`        // Quyền được cấp
+	RateLimitPerMinute int            `json:"rate_limit_per_minute" gorm:"not null"`     // Số request tối đa mỗi phút
+	CommissionRate     float64        `json:"commission_rate" gorm:"not null;default:0"` // Tỷ lệ hoa hồng (0.05 = 5%)
+	LastUsedAt         *time.Time     `json:"last_used_at,omitempty"`                    // Lần gọi API gần nhất
+	RevokedAt          *time.Time     `json:"revoked_at,omitempty"`                      // Thời điểm thu hồi key
+}
+
+// Validate API client data
+func (c *APIClient) Validate() error {
+	if c.Name == "" {
+		return errors.New("client name is required")
+	}
+	if len(c.Scopes) == 0 {
+		return errors.New("client must have at least one scope")
+	}
+	for _, scope := range c.Scopes {
+		if !APIScope(scope).IsValid() {
+			return errors.New("invalid scope: " + scope)
+		}
+	}
+	if c.RateLimitPerMinute <= 0 {
+		return errors.New("rate limit must be positive")
+	}
+	if c.CommissionRate < 0 || c.CommissionRate >= 1 {
+		return errors.New("commission rate must be between 0 and 1")
+	}
+	return nil
+}
+
+// HasScope checks whether the client was granted a scope
+func (c *APIClient) HasScope(scope APIScope) bool {
+	for _, s := range c.Scopes {
+		if APIScope(s) == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IsRevoked checks whether the client's key was revoked
+func (c *APIClient) IsRevoked() bool {
+	return c.RevokedAt != nil
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrAuditLogImmutable = errors.New("audit log entries cannot be modified")
+
+// AuditChange is the value of a single field before and after an action
+type AuditChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// AuditChanges maps field names to their change, stored as JSON
+type AuditChanges map[string]AuditChange
+
+// Value implements driver.Valuer
+func (a AuditChanges) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(a)
+	return string(b), err
+}
+
+// Scan implements sql.Scanner
+func (a *AuditChanges) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported audit changes type %T", value)
+	}
+	return json.Unmarshal(data, a)
+}
+
+// AuditLog is an append-only record of a mutating administrative call
+type AuditLog struct {
+	ID         uint         `json:"id" gorm:"primarykey"`
+	CreatedAt  time.Time    `json:"created_at" gorm:"index"`                   // Thời điểm thực hiện
+	ActorID    *uint        `json:"actor_id" gorm:"index"`                     // Người thực hiện
+	ActorPhone string       `json:"actor_phone"`                               // Số điện thoại người thực hiện
+	ActorRole  Role         `json:"actor_role"`                                // Vai trò người thực hiện
+	Action     string       `json:"action" gorm:"not null;index"`              // Hành động, ví dụ booking.update_status
+	EntityType string       `json:"entity_type" gorm:"index:idx_audit_entity"` // Loại đối tượng
+	EntityID   string       `json:"entity_id" gorm:"index:idx_audit_entity"`   // ID đối tượng
+	Changes    AuditChanges `json:"changes,omitempty" gorm:"type:text"`        // Giá trị trước/sau của các trường thay đổi
+	Method     string       `json:"method"`                                    // HTTP method
+	Path       string       `json:"path"`                                      // Route được gọi
+	StatusCode int          `json:"status_code"`                               // HTTP status trả về
+	IP         string       `json:"ip"`                                        // Địa chỉ IP
+	RequestID  string       `json:"request_id" gorm:"index"`                   // Mã request (X-Request-ID)
+	OperatorID *uint        `json:"operator_id,omitempty" gorm:"index"`        // Nhà xe mà thao tác thuộc về (trống: toàn hệ thống)
+}
+
+// BeforeUpdate keeps the audit log append-only
+func (a *AuditLog) BeforeUpdate(tx *gorm.DB) error {
+	return ErrAuditLogImmutable
+}
+
+// BeforeDelete keeps the audit log append-only
+func (a *AuditLog) BeforeDelete(tx *gorm.DB) error {
+	return ErrAuditLogImmutable
+}
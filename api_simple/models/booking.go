@@ -33,6 +33,21 @@ const (
 	PaymentStatusRefunded PaymentStatus = "refunded" // Đã hoàn tiền
 )
 
+type BookingChannel string
+
+const (
+	BookingChannelOnline  BookingChannel = "online"  // Khách tự đặt trực tuyến
+	BookingChannelCounter BookingChannel = "counter" // Nhân viên bán tại quầy
+	BookingChannelPartner BookingChannel = "partner" // Đại lý đặt qua Partner API
+)
+
+// CanChangeTo reports whether a payment can be set by hand from s to to: an unpaid
+// booking gets paid, or a paid one is refunded in full
+func (s PaymentStatus) CanChangeTo(to PaymentStatus) bool {
+	return (s == PaymentStatusUnpaid && to == PaymentStatusPaid) ||
+		(s == PaymentStatusPaid && to == PaymentStatusRefunded)
+}
+
 // GuestInfo stores information about non-logged-in customers
 type GuestInfo struct {
 	Name  string `json:"name" gorm:"not null"`  // Tên khách
@@ -43,19 +58,42 @@ type GuestInfo struct {
 // Booking represents a ticket booking
 type Booking struct {
 	gorm.Model
-	UserID        *uint         `json:"user_id"`                                             // ID người dùng (nếu đã đăng nhập)
-	User          *User         `json:"user,omitempty"`                                      // Thông tin người dùng
-	GuestInfo     *GuestInfo    `json:"guest_info,omitempty" gorm:"embedded"`                // Thông tin khách vãng lai
-	TripID        uint          `json:"trip_id" gorm:"not null;constraint:OnDelete:CASCADE"` // ID chuyến đi
-	Trip          *Trip         `json:"trip,omitempty"`                                      // Thông tin chuyến đi
-	SeatIDs       pq.Int64Array `json:"seat_ids" gorm:"type:integer[];not null"`             // Danh sách ID ghế
-	Seats         []Seat        `json:"seats,omitempty" gorm:"many2many:booking_seats;"`     // Thông tin ghế
-	TotalAmount   float64       `json:"total_amount" gorm:"not null"`                        // Tổng tiền
-	Status        BookingStatus `json:"status" gorm:"not null;default:'pending'"`            // Trạng thái đặt vé
-	PaymentType   PaymentType   `json:"payment_type" gorm:"not null;default:'cash'"`         // Hình thức thanh toán
-	PaymentStatus PaymentStatus `json:"payment_status" gorm:"not null;default:'unpaid'"`     // Trạng thái thanh toán
-	BookingCode   string        `json:"booking_code" gorm:"unique;not null"`                 // Mã đặt vé
-	Note          string        `json:"note"`                                                // Ghi chú
+	OperatorID       uint          `json:"operator_id" gorm:"index"`                            // Nhà xe bán vé
+	Operator         *Operator     `json:"operator,omitempty"`                                  // Thông tin nhà xe
+	UserID           *uint         `json:"user_id"`                                             // ID người dùng (nếu đã đăng nhập)
+	User             *User         `json:"user,omitempty"`                                      // Thông tin người dùng
+	GuestInfo        *GuestInfo    `json:"guest_info,omitempty" gorm:"embedded"`                // Thông tin khách vãng lai
+	TripID           uint          `json:"trip_id" gorm:"not null;constraint:OnDelete:CASCADE"` // ID chuyến đi
+	Trip             *Trip         `json:"trip,omitempty"`                                      // Thông tin chuyến đi
+	SeatIDs          pq.Int64Array `json:"seat_ids" gorm:"type:integer[];not null"`             // Danh sách ID ghế
+	Seats            []Seat        `json:"seats,omitempty" gorm:"many2many:booking_seats;"`     // Thông tin ghế
+	TotalAmount      Money         `json:"total_amount" gorm:"not null"`                        // Tổng tiền
+	Currency         Currency      `json:"currency" gorm:"not null;default:'VND'"`              // Đơn vị tiền tệ của các khoản tiền
+	Status           BookingStatus `json:"status" gorm:"not null;default:'pending'"`            // Trạng thái đặt vé
+	PaymentType      PaymentType   `json:"payment_type" gorm:"not null;default:'cash'"`         // Hình thức thanh toán
+	PaymentStatus    PaymentStatus `json:"payment_status" gorm:"not null;default:'unpaid'"`     // Trạng thái thanh toán
+	PaymentReference *string       `json:"payment_reference,omitempty" gorm:"uniqueIndex"`      // Mã giao dịch tại cổng thanh toán, dùng để đối soát
+	PaidAt           *time.Time    `json:"paid_at,omitempty"`                                   // Thời điểm ghi nhận thanh toán
+	BookingCode      string        `json:"booking_code" gorm:"unique;not null"`                 // Mã đặt vé
+	Note             string        `json:"note"`                                                // Ghi chú
+
+	PaymentProvider *PaymentAccountMethod `json:"payment_provider,omitempty" gorm:"index"` // Cổng thanh toán đã thu tiền (vnpay, momo...), dùng để đối soát
+
+	APIClientID      *uint      `json:"api_client_id,omitempty" gorm:"index"` // Đại lý đặt vé qua API
+	APIClient        *APIClient `json:"api_client,omitempty"`                 // Thông tin đại lý
+	CommissionAmount Money      `json:"commission_amount" gorm:"default:0"`   // Hoa hồng cho đại lý
+
+	PickupPointID   *uint       `json:"pickup_point_id,omitempty"`         // Điểm đón đã chọn
+	PickupPoint     *RoutePoint `json:"pickup_point,omitempty"`            // Thông tin điểm đón
+	DropoffPointID  *uint       `json:"dropoff_point_id,omitempty"`        // Điểm trả đã chọn
+	DropoffPoint    *RoutePoint `json:"dropoff_point,omitempty"`           // Thông tin điểm trả
+	SurchargeAmount Money       `json:"surcharge_amount" gorm:"default:0"` // Phụ phí trung chuyển (đã gồm trong tổng tiền)
+	PickupAt        *time.Time  `json:"pickup_at,omitempty" gorm:"-"`      // Giờ đón tại điểm đón (in trên vé)
+	DropoffAt       *time.Time  `json:"dropoff_at,omitempty" gorm:"-"`     // Giờ dự kiến đến điểm trả
+
+	RefundAmount Money `json:"refund_amount" gorm:"default:0"` // Số tiền hoàn theo chính sách của nhà xe
+
+	Channel BookingChannel `json:"channel" gorm:"not null;default:'online';index"` // Kênh bán vé
 }
 
 // BeforeCreate hook to generate booking code
@@ -66,6 +104,13 @@ func (b *Booking) BeforeCreate(tx *gorm.DB) error {
 		return err
 	}
 	b.BookingCode = code
+	if b.Currency == "" {
+		b.Currency = DefaultCurrency
+	}
+	if b.PaymentStatus == PaymentStatusPaid && b.PaidAt == nil {
+		now := time.Now()
+		b.PaidAt = &now
+	}
 	return nil
 }
 
@@ -102,6 +147,18 @@ func (b *Booking) Validate() error {
 	return nil
 }
 
+// SetBoardingTimes fills the scheduled times at the chosen pickup and drop-off points
+func (b *Booking) SetBoardingTimes(departure time.Time) {
+	if b.PickupPoint != nil {
+		at := b.PickupPoint.TimeFrom(departure)
+		b.PickupAt = &at
+	}
+	if b.DropoffPoint != nil {
+		at := b.DropoffPoint.TimeFrom(departure)
+		b.DropoffAt = &at
+	}
+}
+
 // generateBookingCode generates a unique booking code
 func generateBookingCode() (string, error) {
 	// Format: BK-YYYYMMDD-XXXXXX
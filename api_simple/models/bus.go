@@ -1,14 +1,74 @@
 package models
 
 import (
+	"errors"
+	"time"
+
+	"ticket-management/api_simple/utils"
+
 	"gorm.io/gorm"
 )
 
+// Errors returned by Bus.Validate
+var (
+	ErrBusPlateNumber   = errors.New("invalid plate number")
+	ErrBusTypeRequired  = errors.New("bus type is required")
+	ErrBusSeatCount     = errors.New("seat count must be positive")
+	ErrBusFloorCount    = errors.New("floor count must be 1 or 2")
+	ErrBusCargoCapacity = errors.New("cargo capacity must not be negative")
+)
+
 type Bus struct {
 	gorm.Model
+	OperatorID  uint   `json:"operator_id" gorm:"index"`   // Nhà xe sở hữu
 	PlateNumber string `json:"plate_number" gorm:"unique"` // Biển số xe
 	Type        string `json:"type"`                       // Loại xe (Giường nằm, Ghế ngồi, ...)
 	SeatCount   int    `json:"seat_count"`                 // Số ghế
 	FloorCount  int    `json:"floor_count"`                // Số tầng (1 hoặc 2)
 	IsActive    bool   `json:"is_active"`                  // Trạng thái hoạt động
+
+	OdometerKm            int        `json:"odometer_km"`                       // Số km trên đồng hồ gần nhất
+	InspectionExpiresAt   *time.Time `json:"inspection_expires_at,omitempty"`   // Hạn đăng kiểm
+	RegistrationExpiresAt *time.Time `json:"registration_expires_at,omitempty"` // Hạn đăng ký xe
+
+	CargoCapacityKg float64 `json:"cargo_capacity_kg"` // Tải trọng khoang hàng (kg, 0: không giới hạn)
+	CargoCapacityM3 float64 `json:"cargo_capacity_m3"` // Thể tích khoang hàng (m³, 0: không giới hạn)
+
+	TotalTrips     int64      `json:"total_trips"`                // Tổng số chuyến
+	UpcomingTrips  int64      `json:"upcoming_trips"`             // Số chuyến sắp tới
+	TotalBookings  int64      `json:"total_bookings"`             // Số đơn đặt vé chưa hủy
+	LoadFactor     float64    `json:"load_factor"`                // Tỷ lệ lấp đầy (0-1)
+	StatsUpdatedAt *time.Time `json:"stats_updated_at,omitempty"` // Thời điểm tính lại thống kê gần nhất
+}
+
+// Validate validates bus data
+func (b *Bus) Validate() error {
+	if !utils.ValidatePlateNumber(b.PlateNumber) {
+		return ErrBusPlateNumber
+	}
+	if b.Type == "" {
+		return ErrBusTypeRequired
+	}
+	if b.SeatCount <= 0 {
+		return ErrBusSeatCount
+	}
+	if b.FloorCount < 1 || b.FloorCount > 2 {
+		return ErrBusFloorCount
+	}
+	if b.CargoCapacityKg < 0 || b.CargoCapacityM3 < 0 {
+		return ErrBusCargoCapacity
+	}
+	return nil
+}
+
+// DocumentsValidAt reports whether the inspection and registration are still valid at t.
+// Buses without recorded dates are treated as valid.
+func (b *Bus) DocumentsValidAt(t time.Time) bool {
+	if b.InspectionExpiresAt != nil && !t.Before(*b.InspectionExpiresAt) {
+		return false
+	}
+	if b.RegistrationExpiresAt != nil && !t.Before(*b.RegistrationExpiresAt) {
+		return false
+	}
+	return true
 }
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type CashShiftStatus string
+
+const (
+	CashShiftStatusOpen     CashShiftStatus = "open"     // Đang mở ca
+	CashShiftStatusClosed   CashShiftStatus = "closed"   // Đã chốt ca, chờ duyệt
+	CashShiftStatusApproved CashShiftStatus = "approved" // Đã được duyệt
+)
+
+// IsValid checks whether the shift status is supported
+func (s CashShiftStatus) IsValid() bool {
+	switch s {
+	case CashShiftStatusOpen, CashShiftStatusClosed, CashShiftStatusApproved:
+		return true
+	}
+	return false
+}
+
+// CashShift is a counter shift of a staff member. The cash drawer starts with an
+// opening float; sales add to it, refunds and payouts take from it, and at closing
+// the counted cash is compared with what the drawer should hold.
+type CashShift struct {
+	gorm.Model
+	OperatorID   uint              `json:"operator_id" gorm:"index"`                         // Nhà xe
+	StaffID      uint              `json:"staff_id" gorm:"not null;index"`                   // Nhân viên quầy
+	Staff        *User             `json:"staff,omitempty"`                                  // Thông tin nhân viên
+	Status       CashShiftStatus   `json:"status" gorm:"not null;default:'open';index"`      // Trạng thái ca
+	OpeningFloat Money             `json:"opening_float" gorm:"not null"`                    // Tiền lẻ đầu ca
+	OpenedAt     time.Time         `json:"opened_at" gorm:"not null;index"`                  // Giờ mở ca
+	ClosedAt     *time.Time        `json:"closed_at,omitempty"`                              // Giờ chốt ca
+	ExpectedCash Money             `json:"expected_cash"`                                    // Tiền mặt phải có khi chốt ca
+	CountedCash  Money             `json:"counted_cash"`                                     // Tiền mặt kiểm đếm thực tế
+	Variance     Money             `json:"variance"`                                         // Chênh lệch = thực tế - phải có
+	CloseNote    string            `json:"close_note"`                                       // Giải trình khi chốt ca
+	ReviewedBy   *uint             `json:"reviewed_by,omitempty"`                            // Người duyệt
+	ReviewedAt   *time.Time        `json:"reviewed_at,omitempty"`                            // Giờ duyệt
+	ReviewNote   string            `json:"review_note"`                                      // Ghi chú của người duyệt
+	Transactions []CashTransaction `json:"transactions,omitempty" gorm:"foreignKey:ShiftID"` // Các khoản thu chi trong ca
+}
+
+// Validate shift data
+func (s *CashShift) Validate() error {
+	if s.StaffID == 0 {
+		return errors.New("staff is required")
+	}
+	if s.OpeningFloat < 0 {
+		return errors.New("opening float must not be negative")
+	}
+	if s.CountedCash < 0 {
+		return errors.New("counted cash must not be negative")
+	}
+	return nil
+}
+
+type CashTransactionType string
+
+const (
+	CashTransactionSale   CashTransactionType = "sale"   // Thu tiền bán vé
+	CashTransactionRefund CashTransactionType = "refund" // Hoàn tiền cho khách
+	CashTransactionPayout CashTransactionType = "payout" // Chi từ két (tạm ứng, chi phí...)
+)
+
+// IsValid checks whether the transaction type is supported
+func (t CashTransactionType) IsValid() bool {
+	switch t {
+	case CashTransactionSale, CashTransactionRefund, CashTransactionPayout:
+		return true
+	}
+	return false
+}
+
+// CashTransaction is cash taken into or paid out of the drawer during a shift
+type CashTransaction struct {
+	gorm.Model
+	OperatorID uint                `json:"operator_id" gorm:"index"`          // Nhà xe
+	ShiftID    uint                `json:"shift_id" gorm:"not null;index"`    // Ca làm việc
+	Type       CashTransactionType `json:"type" gorm:"not null"`              // Loại thu chi
+	Amount     Money               `json:"amount" gorm:"not null"`            // Số tiền (luôn dương)
+	BookingID  *uint               `json:"booking_id,omitempty" gorm:"index"` // Đơn đặt vé liên quan
+	Note       string              `json:"note"`                              // Ghi chú
+	CreatedBy  uint                `json:"created_by"`                        // Người ghi nhận
+}
+
+// Validate transaction data
+func (t *CashTransaction) Validate() error {
+	if !t.Type.IsValid() {
+		return errors.New("invalid cash transaction type")
+	}
+	if t.Amount <= 0 {
+		return errors.New("amount must be greater than 0")
+	}
+	if t.Type == CashTransactionPayout && t.Note == "" {
+		return errors.New("a reason is required for payouts")
+	}
+	return nil
+}
+
+// SignedAmount is the effect of the transaction on the drawer: sales add cash,
+// refunds and payouts take it out
+func (t *CashTransaction) SignedAmount() Money {
+	if t.Type == CashTransactionSale {
+		return t.Amount
+	}
+	return -t.Amount
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// TravelDateFormat is the layout of TripSearch.TravelDate
+const TravelDateFormat = "2006-01-02"
+
+// TripSearch is one trip search made by a customer, kept to measure demand
+type TripSearch struct {
+	ID          uint      `json:"id" gorm:"primarykey"`
+	Origin      string    `json:"origin" gorm:"not null;index:idx_trip_search_corridor"`      // Điểm đi
+	Destination string    `json:"destination" gorm:"not null;index:idx_trip_search_corridor"` // Điểm đến
+	TravelDate  string    `json:"travel_date,omitempty" gorm:"size:10;index"`                 // Ngày đi (YYYY-MM-DD), trống nếu không chọn ngày
+	RouteID     *uint     `json:"route_id,omitempty"`                                         // Tuyến khách chọn (nếu tìm theo tuyến)
+	Results     int       `json:"results"`                                                    // Số chuyến còn chỗ trả về
+	CreatedAt   time.Time `json:"created_at" gorm:"index"`
+}
+
+// Season is a travel season used to rank routes
+type Season string
+
+const (
+	SeasonSpring Season = "spring" // Tháng 2-4 (Tết, lễ hội đầu năm)
+	SeasonSummer Season = "summer" // Tháng 5-7 (nghỉ hè)
+	SeasonAutumn Season = "autumn" // Tháng 8-10
+	SeasonWinter Season = "winter" // Tháng 11 đến tháng 1 năm sau
+)
+
+var ErrInvalidSeason = errors.New("invalid season")
+
+// seasonStartMonths are the months each season begins in
+var seasonStartMonths = map[Season]time.Month{
+	SeasonSpring: time.February,
+	SeasonSummer: time.May,
+	SeasonAutumn: time.August,
+	SeasonWinter: time.November,
+}
+
+// Range returns the first day of the season starting in year and the first day after it
+func (s Season) Range(year int, loc *time.Location) (time.Time, time.Time, error) {
+	month, ok := seasonStartMonths[s]
+	if !ok {
+		return time.Time{}, time.Time{}, ErrInvalidSeason
+	}
+	from := time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	return from, from.AddDate(0, 3, 0), nil
+}
+
+// SeasonAt returns the season t falls in and the year that season started in
+func SeasonAt(t time.Time) (Season, int) {
+	year := t.Year()
+	switch month := t.Month(); {
+	case month == time.January:
+		return SeasonWinter, year - 1
+	case month < time.May:
+		return SeasonSpring, year
+	case month < time.August:
+		return SeasonSummer, year
+	case month < time.November:
+		return SeasonAutumn, year
+	default:
+		return SeasonWinter, year
+	}
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type LicenceClass string
+
+const (
+	LicenceClassB2 LicenceClass = "B2" // Xe đến 9 chỗ
+	LicenceClassC  LicenceClass = "C"  // Xe tải
+	LicenceClassD  LicenceClass = "D"  // Xe từ 10 đến 30 chỗ
+	LicenceClassE  LicenceClass = "E"  // Xe trên 30 chỗ
+)
+
+var licenceRank = map[LicenceClass]int{
+	LicenceClassB2: 1,
+	LicenceClassC:  2,
+	LicenceClassD:  3,
+	LicenceClassE:  4,
+}
+
+// IsValid checks whether the licence class is supported
+func (l LicenceClass) IsValid() bool {
+	_, ok := licenceRank[l]
+	return ok
+}
+
+// Covers reports whether a licence of this class may drive vehicles requiring another class
+func (l LicenceClass) Covers(required LicenceClass) bool {
+	return licenceRank[l] >= licenceRank[required]
+}
+
+// RequiredLicenceClass returns the licence class needed to drive a bus with the given seat count
+func RequiredLicenceClass(seatCount int) LicenceClass {
+	switch {
+	case seatCount > 30:
+		return LicenceClassE
+	case seatCount > 9:
+		return LicenceClassD
+	default:
+		return LicenceClassB2
+	}
+}
+
+// DriverProfile stores licence details of a driver account
+type DriverProfile struct {
+	gorm.Model
+	UserID        uint         `json:"user_id" gorm:"uniqueIndex;not null"` // Tài xế
+	User          *User        `json:"user,omitempty"`                      // Thông tin tài xế
+	LicenceNumber string       `json:"licence_number" gorm:"not null"`      // Số giấy phép lái xe
+	LicenceClass  LicenceClass `json:"licence_class" gorm:"not null"`       // Hạng giấy phép lái xe
+	LicenceExpiry time.Time    `json:"licence_expiry" gorm:"not null"`      // Ngày hết hạn giấy phép
+}
+
+// Validate driver profile data
+func (p *DriverProfile) Validate() error {
+	if p.LicenceNumber == "" {
+		return errors.New("licence number is required")
+	}
+	if !p.LicenceClass.IsValid() {
+		return errors.New("invalid licence class")
+	}
+	if p.LicenceExpiry.IsZero() {
+		return errors.New("licence expiry is required")
+	}
+	return nil
+}
+
+// LicenceValidAt checks whether the licence is still valid at t
+func (p *DriverProfile) LicenceValidAt(t time.Time) bool {
+	return t.Before(p.LicenceExpiry)
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Holiday is a holiday period (Tết, long weekends) when travel demand differs from usual
+type Holiday struct {
+	gorm.Model
+	Name      string  `json:"name" gorm:"not null"`               // Tên dịp lễ
+	StartDate string  `json:"start_date" gorm:"size:10;not null"` // Ngày bắt đầu (YYYY-MM-DD)
+	EndDate   string  `json:"end_date" gorm:"size:10;not null"`   // Ngày kết thúc (YYYY-MM-DD, tính cả ngày này)
+	Uplift    float64 `json:"uplift" gorm:"not null;default:1.5"` // Hệ số nhu cầu mặc định khi tuyến chưa có dữ liệu dịp lễ
+}
+
+// Validate validates holiday data
+func (h *Holiday) Validate() error {
+	if h.Name == "" {
+		return errors.New("holiday name is required")
+	}
+	start, err := time.Parse(TravelDateFormat, h.StartDate)
+	if err != nil {
+		return errors.New("invalid start date")
+	}
+	end, err := time.Parse(TravelDateFormat, h.EndDate)
+	if err != nil {
+		return errors.New("invalid end date")
+	}
+	if end.Before(start) {
+		return errors.New("end date must not be before start date")
+	}
+	if h.Uplift <= 0 {
+		return errors.New("uplift must be positive")
+	}
+	return nil
+}
+
+// Contains reports whether t falls on a day of the holiday
+func (h *Holiday) Contains(t time.Time) bool {
+	day := t.Format(TravelDateFormat)
+	return day >= h.StartDate && day <= h.EndDate
+}
+
+// ForecastRisk flags a trip whose forecast demand is far from its capacity
+type ForecastRisk string
+
+const (
+	ForecastRiskSellOut   ForecastRisk = "sell_out"   // Dự kiến bán hết chỗ
+	ForecastRiskLowDemand ForecastRisk = "low_demand" // Dự kiến vắng khách
+)
+
+// TripForecast is the seat demand predicted for an upcoming trip
+type TripForecast struct {
+	ID             uint         `json:"id" gorm:"primarykey"`
+	OperatorID     uint         `json:"operator_id" gorm:"index"`
+	TripID         uint         `json:"trip_id" gorm:"uniqueIndex;not null"`
+	RouteID        uint         `json:"route_id" gorm:"index"`
+	DepartureTime  time.Time    `json:"departure_time" gorm:"index"`
+	TotalSeats     int          `json:"total_seats"`
+	BookedSeats    int          `json:"booked_seats"`    // Số ghế đã đặt (lưu khi dự báo, trả về theo chuyến hiện tại)
+	ForecastSeats  int          `json:"forecast_seats"`  // Số ghế dự kiến bán được
+	Baseline       float64      `json:"baseline"`        // Số ghế trung bình của khung giờ, đã loại yếu tố mùa
+	SeasonalFactor float64      `json:"seasonal_factor"` // Hệ số theo tháng và thứ trong tuần
+	HolidayFactor  float64      `json:"holiday_factor"`  // Hệ số dịp lễ (1 nếu không phải dịp lễ)
+	Holiday        string       `json:"holiday,omitempty"`
+	Samples        int          `json:"samples"` // Số chuyến lịch sử dùng để dự báo
+	Risk           ForecastRisk `json:"risk,omitempty" gorm:"size:20;index"`
+	GeneratedAt    time.Time    `json:"generated_at"`
+}
@@ -0,0 +1,134 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var taxCodePattern = regexp.MustCompile(`^\d{10}(-\d{3})?$`)
+
+// ValidTaxCode checks a Vietnamese tax code (mã số thuế): 10 digits, or 13 with a branch suffix
+func ValidTaxCode(code string) bool {
+	return taxCodePattern.MatchString(code)
+}
+
+type InvoiceType string
+
+const (
+	InvoiceTypeOriginal    InvoiceType = "original"    // Hóa đơn gốc
+	InvoiceTypeAdjustment  InvoiceType = "adjustment"  // Hóa đơn điều chỉnh
+	InvoiceTypeReplacement InvoiceType = "replacement" // Hóa đơn thay thế
+)
+
+type InvoiceStatus string
+
+const (
+	InvoiceStatusIssued   InvoiceStatus = "issued"   // Đã lập, chưa gửi nhà cung cấp hóa đơn điện tử
+	InvoiceStatusSent     InvoiceStatus = "sent"     // Đã gửi và được cấp mã tra cứu
+	InvoiceStatusAdjusted InvoiceStatus = "adjusted" // Đã bị điều chỉnh bởi hóa đơn khác
+	InvoiceStatusReplaced InvoiceStatus = "replaced" // Đã bị thay thế bởi hóa đơn khác
+)
+
+// InvoiceSeries keeps the running invoice number of one series of an operator.
+// The series symbol encodes the year, so numbering restarts every year.
+type InvoiceSeries struct {
+	gorm.Model
+	OperatorID   uint   `json:"operator_id" gorm:"uniqueIndex:idx_invoice_series"`            // Nhà xe
+	TemplateCode string `json:"template_code" gorm:"not null;uniqueIndex:idx_invoice_series"` // Ký hiệu mẫu số (1: hóa đơn GTGT)
+	Series       string `json:"series" gorm:"not null;uniqueIndex:idx_invoice_series"`        // Ký hiệu hóa đơn, VD: C26TAA
+	LastNumber   int    `json:"last_number" gorm:"not null;default:0"`                        // Số hóa đơn đã cấp gần nhất
+}
+
+// InvoiceBuyer is who the VAT invoice is made out to
+type InvoiceBuyer struct {
+	BuyerName    string `json:"buyer_name"`     // Họ tên người mua
+	BuyerCompany string `json:"buyer_company"`  // Tên đơn vị
+	BuyerTaxCode string `json:"buyer_tax_code"` // Mã số thuế đơn vị
+	BuyerAddress string `json:"buyer_address"`  // Địa chỉ đơn vị
+	BuyerEmail   string `json:"buyer_email"`    // Email nhận hóa đơn
+}
+
+// Validate buyer data; a company buyer needs its tax code and address
+func (b *InvoiceBuyer) Validate() error {
+	if b.BuyerName == "" && b.BuyerCompany == "" {
+		return errors.New("buyer name or company is required")
+	}
+	if b.BuyerCompany != "" {
+		if !ValidTaxCode(b.BuyerTaxCode) {
+			return errors.New("invalid buyer tax code")
+		}
+		if b.BuyerAddress == "" {
+			return errors.New("buyer address is required for companies")
+		}
+	}
+	if b.BuyerTaxCode != "" && b.BuyerCompany == "" {
+		return errors.New("buyer company is required with a tax code")
+	}
+	return nil
+}
+
+// Invoice is a VAT e-invoice issued for a paid booking. Adjustment invoices carry
+// negative amounts; adjustment and replacement invoices point to the invoice they correct.
+type Invoice struct {
+	gorm.Model
+	OperatorID        uint          `json:"operator_id" gorm:"index"`                // Nhà xe xuất hóa đơn
+	BookingID         uint          `json:"booking_id" gorm:"not null;index"`        // Đơn đặt vé
+	Booking           *Booking      `json:"booking,omitempty"`                       // Thông tin đơn
+	Type              InvoiceType   `json:"type" gorm:"not null;default:'original'"` // Loại hóa đơn
+	Status            InvoiceStatus `json:"status" gorm:"not null;default:'issued'"` // Trạng thái
+	TemplateCode      string        `json:"template_code" gorm:"not null"`           // Ký hiệu mẫu số
+	Series            string        `json:"series" gorm:"not null;index"`            // Ký hiệu hóa đơn
+	Number            int           `json:"number" gorm:"not null"`                  // Số hóa đơn
+	IssuedAt          time.Time     `json:"issued_at" gorm:"not null;index"`         // Ngày lập
+	OriginalInvoiceID *uint         `json:"original_invoice_id,omitempty"`           // Hóa đơn bị điều chỉnh/thay thế
+	OriginalInvoice   *Invoice      `json:"original_invoice,omitempty"`              // Thông tin hóa đơn bị điều chỉnh/thay thế
+	Reason            string        `json:"reason,omitempty"`                        // Lý do điều chỉnh/thay thế
+
+	SellerName    string `json:"seller_name" gorm:"not null"`     // Tên đơn vị bán
+	SellerTaxCode string `json:"seller_tax_code" gorm:"not null"` // Mã số thuế đơn vị bán
+	SellerAddress string `json:"seller_address"`                  // Địa chỉ đơn vị bán
+	InvoiceBuyer  `gorm:"embedded"`
+
+	PaymentMethod string   `json:"payment_method"`                         // Hình thức thanh toán
+	Currency      Currency `json:"currency" gorm:"not null;default:'VND'"` // Đơn vị tiền tệ
+	VATRate       float64  `json:"vat_rate" gorm:"not null"`               // Thuế suất GTGT (%)
+	Subtotal      Money    `json:"subtotal"`                               // Tổng tiền chưa thuế
+	VATAmount     Money    `json:"vat_amount"`                             // Tiền thuế GTGT
+	Total         Money    `json:"total"`                                  // Tổng tiền thanh toán
+
+	LookupCode       string     `json:"lookup_code,omitempty" gorm:"index"` // Mã tra cứu do nhà cung cấp cấp
+	TaxAuthorityCode string     `json:"tax_authority_code,omitempty"`       // Mã của cơ quan thuế
+	SentAt           *time.Time `json:"sent_at,omitempty"`                  // Thời điểm gửi thành công
+	SubmitError      string     `json:"submit_error,omitempty"`             // Lỗi lần gửi gần nhất
+
+	Lines []InvoiceLine `json:"lines,omitempty"` // Hàng hóa, dịch vụ
+}
+
+// IsActive reports whether the invoice still stands, i.e. has not been replaced
+func (i *Invoice) IsActive() bool {
+	return i.Status != InvoiceStatusReplaced
+}
+
+// Reference identifies the invoice to the e-invoice service: operator, template,
+// series and number, e.g. "1/1C26TAA/0000042"
+func (i *Invoice) Reference() string {
+	return fmt.Sprintf("%d/%s%s/%07d", i.OperatorID, i.TemplateCode, i.Series, i.Number)
+}
+
+// InvoiceLine is a good or service on an invoice
+type InvoiceLine struct {
+	gorm.Model
+	InvoiceID   uint    `json:"invoice_id" gorm:"not null;index"` // Hóa đơn
+	LineNo      int     `json:"line_no"`                          // Số thứ tự
+	Description string  `json:"description" gorm:"not null"`      // Tên hàng hóa, dịch vụ
+	Unit        string  `json:"unit"`                             // Đơn vị tính
+	Quantity    int     `json:"quantity"`                         // Số lượng
+	UnitPrice   Money   `json:"unit_price"`                       // Đơn giá chưa thuế
+	Amount      Money   `json:"amount"`                           // Thành tiền chưa thuế
+	VATRate     float64 `json:"vat_rate"`                         // Thuế suất (%)
+	VATAmount   Money   `json:"vat_amount"`                       // Tiền thuế
+}
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LedgerAccount is an account of the chart of accounts. Codes follow the Vietnamese
+// chart of accounts (Circular 200/2014) so entries can be exported to accounting software.
+type LedgerAccount string
+
+const (
+	AccountCash              LedgerAccount = "1111" // Tiền mặt tại quầy
+	AccountGatewayClearing   LedgerAccount = "1131" // Tiền đang chuyển qua cổng thanh toán, chuyển khoản
+	AccountReceivable        LedgerAccount = "131"  // Phải thu khách hàng, đại lý
+	AccountRefundsPayable    LedgerAccount = "3388" // Tiền hoàn vé phải trả khách
+	AccountCommissionPayable LedgerAccount = "3311" // Hoa hồng phải trả đại lý
+	AccountRevenue           LedgerAccount = "5113" // Doanh thu bán vé
+	AccountCancellationFees  LedgerAccount = "5118" // Doanh thu phí hủy vé
+	AccountAgencyCommission  LedgerAccount = "6421" // Chi phí hoa hồng đại lý
+)
+
+// LedgerAccountKind tells on which side an account's balance normally sits
+type LedgerAccountKind string
+
+const (
+	AccountKindAsset     LedgerAccountKind = "asset"     // Tài sản, số dư bên Nợ
+	AccountKindLiability LedgerAccountKind = "liability" // Nợ phải trả, số dư bên Có
+	AccountKindRevenue   LedgerAccountKind = "revenue"   // Doanh thu, số dư bên Có
+	AccountKindExpense   LedgerAccountKind = "expense"   // Chi phí, số dư bên Nợ
+)
+
+// LedgerAccountInfo describes an account of the chart
+type LedgerAccountInfo struct {
+	Code LedgerAccount     `json:"code"`
+	Name string            `json:"name"`
+	Kind LedgerAccountKind `json:"kind"`
+}
+
+// ChartOfAccounts lists every account the ledger posts to
+var ChartOfAccounts = []LedgerAccountInfo{
+	{AccountCash, "Tiền mặt", AccountKindAsset},
+	{AccountGatewayClearing, "Tiền đang chuyển", AccountKindAsset},
+	{AccountReceivable, "Phải thu khách hàng", AccountKindAsset},
+	{AccountRefundsPayable, "Tiền hoàn vé phải trả", AccountKindLiability},
+	{AccountCommissionPayable, "Hoa hồng phải trả đại lý", AccountKindLiability},
+	{AccountRevenue, "Doanh thu bán vé", AccountKindRevenue},
+	{AccountCancellationFees, "Doanh thu phí hủy vé", AccountKindRevenue},
+	{AccountAgencyCommission, "Chi phí hoa hồng đại lý", AccountKindExpense},
+}
+
+// Info returns the chart entry of the account
+func (a LedgerAccount) Info() (LedgerAccountInfo, bool) {
+	for _, info := range ChartOfAccounts {
+		if info.Code == a {
+			return info, true
+		}
+	}
+	return LedgerAccountInfo{}, false
+}
+
+// IsValid checks whether the account is in the chart
+func (a LedgerAccount) IsValid() bool {
+	_, ok := a.Info()
+	return ok
+}
+
+type LedgerTransactionKind string
+
+const (
+	LedgerKindSale             LedgerTransactionKind = "sale"              // Bán vé (ghi nhận doanh thu và hoa hồng)
+	LedgerKindPayment          LedgerTransactionKind = "payment"           // Thu tiền vé
+	LedgerKindCancellation     LedgerTransactionKind = "cancellation"      // Hủy vé: hoàn tiền và phí hủy
+	LedgerKindRefundPayout     LedgerTransactionKind = "refund_payout"     // Chi trả tiền hoàn cho khách
+	LedgerKindCommissionPayout LedgerTransactionKind = "commission_payout" // Chi trả hoa hồng cho đại lý
+)
+
+// LedgerTransaction is a balanced journal entry: the debits of its entries equal the credits
+type LedgerTransaction struct {
+	gorm.Model
+	OperatorID  uint                  `json:"operator_id" gorm:"index"`                // Nhà xe
+	Kind        LedgerTransactionKind `json:"kind" gorm:"not null;index"`              // Loại nghiệp vụ
+	BookingID   *uint                 `json:"booking_id,omitempty" gorm:"index"`       // Đơn đặt vé liên quan
+	APIClientID *uint                 `json:"api_client_id,omitempty" gorm:"index"`    // Đại lý liên quan
+	Description string                `json:"description"`                             // Diễn giải
+	PostedAt    time.Time             `json:"posted_at" gorm:"not null;index"`         // Ngày ghi sổ
+	CreatedBy   *uint                 `json:"created_by,omitempty"`                    // Người ghi sổ (nếu có)
+	Entries     []LedgerEntry         `json:"entries" gorm:"foreignKey:TransactionID"` // Các bút toán Nợ/Có
+}
+
+// Validate checks that the entry posts to known accounts and balances
+func (t *LedgerTransaction) Validate() error {
+	if len(t.Entries) < 2 {
+		return errors.New("a journal entry needs at least two lines")
+	}
+	var debit, credit Money
+	for _, entry := range t.Entries {
+		if !entry.Account.IsValid() {
+			return errors.New("unknown ledger account " + string(entry.Account))
+		}
+		if entry.Debit < 0 || entry.Credit < 0 || (entry.Debit == 0) == (entry.Credit == 0) {
+			return errors.New("each line must have either a positive debit or a positive credit")
+		}
+		debit += entry.Debit
+		credit += entry.Credit
+	}
+	if debit != credit {
+		return errors.New("journal entry is not balanced")
+	}
+	return nil
+}
+
+// LedgerEntry is one debit or credit line of a journal entry
+type LedgerEntry struct {
+	gorm.Model
+	OperatorID    uint          `json:"operator_id" gorm:"index"`             // Nhà xe
+	TransactionID uint          `json:"transaction_id" gorm:"not null;index"` // Bút toán
+	Account       LedgerAccount `json:"account" gorm:"not null;index"`        // Tài khoản
+	Debit         Money         `json:"debit" gorm:"not null;default:0"`      // Số tiền ghi Nợ
+	Credit        Money         `json:"credit" gorm:"not null;default:0"`     // Số tiền ghi Có
+	PostedAt      time.Time     `json:"posted_at" gorm:"not null;index"`      // Ngày ghi sổ (theo bút toán)
+	BookingID     *uint         `json:"booking_id,omitempty" gorm:"index"`    // Đơn đặt vé liên quan (theo bút toán)
+}
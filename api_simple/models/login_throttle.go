@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ThrottleScope string
+
+const (
+	ThrottleScopeAccount ThrottleScope = "account" // Theo số điện thoại đăng nhập
+	ThrottleScopeIP      ThrottleScope = "ip"      // Theo địa chỉ IP
+)
+
+// LoginThrottle tracks failed login attempts for an account or an IP address
+type LoginThrottle struct {
+	gorm.Model
+	Scope         ThrottleScope `json:"scope" gorm:"not null;uniqueIndex:idx_throttle_scope_key"` // Phạm vi đếm
+	Key           string        `json:"key" gorm:"not null;uniqueIndex:idx_throttle_scope_key"`   // Số điện thoại hoặc IP
+	Failures      int           `json:"failures" gorm:"not null;default:0"`                       // Số lần đăng nhập sai liên tiếp
+	LastFailureAt *time.Time    `json:"last_failure_at,omitempty"`                                // Lần sai gần nhất
+	LastIP        string        `json:"last_ip"`                                                  // IP của lần sai gần nhất
+	NextAllowedAt *time.Time    `json:"next_allowed_at,omitempty"`                                // Được thử lại sau thời điểm này
+	LockedUntil   *time.Time    `json:"locked_until,omitempty"`                                   // Khóa tạm thời đến thời điểm này
+}
+
+// IsLocked checks whether the throttle is currently in lockout
+func (t *LoginThrottle) IsLocked(now time.Time) bool {
+	return t.LockedUntil != nil && now.Before(*t.LockedUntil)
+}
+
+// RetryAfter returns how long the caller must wait before the next attempt
+func (t *LoginThrottle) RetryAfter(now time.Time) time.Duration {
+	if t.IsLocked(now) {
+		return t.LockedUntil.Sub(now)
+	}
+	if t.NextAllowedAt != nil && now.Before(*t.NextAllowedAt) {
+		return t.NextAllowedAt.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type MaintenanceType string
+
+const (
+	MaintenanceTypeService      MaintenanceType = "service"      // Bảo dưỡng định kỳ
+	MaintenanceTypeInspection   MaintenanceType = "inspection"   // Đăng kiểm
+	MaintenanceTypeRegistration MaintenanceType = "registration" // Gia hạn đăng ký xe
+	MaintenanceTypeRepair       MaintenanceType = "repair"       // Sửa chữa
+	MaintenanceTypeBreakdown    MaintenanceType = "breakdown"    // Báo hỏng
+)
+
+// IsValid checks whether the maintenance type is supported
+func (t MaintenanceType) IsValid() bool {
+	switch t {
+	case MaintenanceTypeService, MaintenanceTypeInspection, MaintenanceTypeRegistration,
+		MaintenanceTypeRepair, MaintenanceTypeBreakdown:
+		return true
+	}
+	return false
+}
+
+// MaintenanceRecord is a service, inspection, repair or breakdown report of a bus
+type MaintenanceRecord struct {
+	gorm.Model
+	BusID       uint            `json:"bus_id" gorm:"not null;index"`       // Xe
+	Bus         *Bus            `json:"bus,omitempty"`                      // Thông tin xe
+	Type        MaintenanceType `json:"type" gorm:"not null;index"`         // Loại bảo dưỡng
+	Description string          `json:"description"`                        // Nội dung công việc
+	PerformedAt time.Time       `json:"performed_at" gorm:"not null"`       // Thời điểm thực hiện
+	OdometerKm  int             `json:"odometer_km"`                        // Số km trên đồng hồ
+	Cost        Money           `json:"cost"`                               // Chi phí
+	NextDueAt   *time.Time      `json:"next_due_at,omitempty"`              // Hạn lần tiếp theo (hoặc ngày hết hạn đăng kiểm)
+	NextDueKm   int             `json:"next_due_km,omitempty"`              // Số km đến hạn bảo dưỡng tiếp theo
+	RecordedBy  *uint           `json:"recorded_by,omitempty" gorm:"index"` // Người ghi nhận
+}
+
+// Validate maintenance record data
+func (m *MaintenanceRecord) Validate() error {
+	if m.BusID == 0 {
+		return errors.New("bus is required")
+	}
+	if !m.Type.IsValid() {
+		return errors.New("invalid maintenance type")
+	}
+	if m.PerformedAt.IsZero() {
+		return errors.New("performed time is required")
+	}
+	if m.OdometerKm < 0 || m.NextDueKm < 0 {
+		return errors.New("odometer must not be negative")
+	}
+	if m.Cost < 0 {
+		return errors.New("cost must not be negative")
+	}
+	if (m.Type == MaintenanceTypeInspection || m.Type == MaintenanceTypeRegistration) && m.NextDueAt == nil {
+		return errors.New("expiry date is required for inspections and registrations")
+	}
+	if m.NextDueAt != nil && !m.NextDueAt.After(m.PerformedAt) {
+		return errors.New("next due date must be after the performed time")
+	}
+	return nil
+}
+
+// BusUnavailability is a period in which a bus cannot be assigned to trips
+type BusUnavailability struct {
+	gorm.Model
+	BusID               uint       `json:"bus_id" gorm:"not null;index"`    // Xe
+	StartsAt            time.Time  `json:"starts_at" gorm:"not null;index"` // Bắt đầu ngừng hoạt động
+	EndsAt              *time.Time `json:"ends_at,omitempty" gorm:"index"`  // Kết thúc (nil: chưa xác định, ví dụ xe hỏng chưa sửa xong)
+	Reason              string     `json:"reason" gorm:"not null"`          // Lý do
+	MaintenanceRecordID *uint      `json:"maintenance_record_id,omitempty"` // Bản ghi bảo dưỡng liên quan
+}
+
+// Validate unavailability window data
+func (u *BusUnavailability) Validate() error {
+	if u.BusID == 0 {
+		return errors.New("bus is required")
+	}
+	if u.StartsAt.IsZero() {
+		return errors.New("start time is required")
+	}
+	if u.EndsAt != nil && !u.EndsAt.After(u.StartsAt) {
+		return errors.New("end time must be after start time")
+	}
+	if u.Reason == "" {
+		return errors.New("reason is required")
+	}
+	return nil
+}
+
+// Overlaps reports whether the window intersects [start, end)
+func (u *BusUnavailability) Overlaps(start time.Time, end time.Time) bool {
+	return u.StartsAt.Before(end) && (u.EndsAt == nil || start.Before(*u.EndsAt))
+}
+
+// DurationWithin returns how long the window covers [from, to)
+func (u *BusUnavailability) DurationWithin(from time.Time, to time.Time) time.Duration {
+	start := u.StartsAt
+	if start.Before(from) {
+		start = from
+	}
+	end := to
+	if u.EndsAt != nil && u.EndsAt.Before(end) {
+		end = *u.EndsAt
+	}
+	if !end.After(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
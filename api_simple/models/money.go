@@ -0,0 +1,138 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Currency is an ISO 4217 currency code
+type Currency string
+
+const (
+	CurrencyVND Currency = "VND" // Đồng Việt Nam, không có đơn vị lẻ
+)
+
+// DefaultCurrency is the currency fares, fees and payouts are kept in
+const DefaultCurrency = CurrencyVND
+
+// MinorUnits returns the number of decimal digits of the currency's minor unit
+func (c Currency) MinorUnits() int {
+	switch c {
+	case CurrencyVND, "":
+		return 0
+	}
+	return 2
+}
+
+// Money is an exact amount in the minor unit of the currency it belongs to (đồng for
+// VND, which has no subunit). It is stored as BIGINT and written to JSON as an integer.
+//
+// Rounding rules:
+//   - Mul and Percent round half away from zero to the minor unit
+//   - fares derived from a base fare with a multiplier (seat type, upper deck) are
+//     rounded half away from zero to PriceStep by ScalePrice
+//   - refunds are rounded half away from zero to PriceStep (see OperatorService.Refund)
+type Money int64
+
+// PriceStep is the smallest step fares are quoted in (1.000đ)
+const PriceStep Money = 1000
+
+// MoneyFromFloat converts a float amount in minor units, rounding half away from zero
+func MoneyFromFloat(amount float64) Money {
+	return Money(math.Round(amount))
+}
+
+// ParseMoney parses an amount written in a file (CSV import, settlement report), with
+// optional comma thousand separators ("1,250,000"). Decimals are rounded half away
+// from zero to the minor unit.
+func ParseMoney(value string) (Money, error) {
+	value = strings.ReplaceAll(strings.TrimSpace(value), ",", "")
+	if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return Money(v), nil
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsInf(v, 0) || math.IsNaN(v) {
+		return 0, errors.New("amount must be a number")
+	}
+	return MoneyFromFloat(v), nil
+}
+
+// Float64 returns the amount as a float, for ratios and display
+func (m Money) Float64() float64 {
+	return float64(m)
+}
+
+// Mul multiplies the amount by factor, rounding half away from zero to the minor unit
+func (m Money) Mul(factor float64) Money {
+	return MoneyFromFloat(float64(m) * factor)
+}
+
+// Percent returns percent percent of the amount, rounding half away from zero to the minor unit
+func (m Money) Percent(percent float64) Money {
+	return MoneyFromFloat(float64(m) * percent / 100)
+}
+
+// RoundTo rounds the amount half away from zero to a multiple of step
+func (m Money) RoundTo(step Money) Money {
+	if step <= 1 {
+		return m
+	}
+	return Money(math.Round(float64(m)/float64(step))) * step
+}
+
+// ScalePrice applies a fare multiplier and rounds the fare to PriceStep
+func (m Money) ScalePrice(multiplier float64) Money {
+	return Money(math.Round(float64(m)*multiplier/float64(PriceStep))) * PriceStep
+}
+
+// Abs returns the absolute amount
+func (m Money) Abs() Money {
+	if m < 0 {
+		return -m
+	}
+	return m
+}
+
+// String formats the amount with dot thousand separators, e.g. 1.250.000
+func (m Money) String() string {
+	digits := strconv.FormatInt(int64(m.Abs()), 10)
+	var b strings.Builder
+	if m < 0 {
+		b.WriteByte('-')
+	}
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(d)
+	}
+	return b.String()
+}
+
+// UnmarshalJSON accepts integers as well as decimals (e.g. from clients that send
+// 150000.0), which are rounded to the minor unit
+func (m *Money) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var number json.Number
+	if err := json.Unmarshal(data, &number); err != nil {
+		return errors.New("amount must be a number")
+	}
+	if v, err := number.Int64(); err == nil {
+		*m = Money(v)
+		return nil
+	}
+	v, err := number.Float64()
+	if err != nil || math.IsInf(v, 0) || math.IsNaN(v) {
+		return errors.New("amount must be a number")
+	}
+	*m = MoneyFromFloat(v)
+	return nil
+}
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"errors"
+	"regexp"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	operatorCodePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,39}$`)
+	colorPattern        = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+)
+
+// Operator is a bus company selling its trips on the platform. Routes, buses,
+// trips, bookings and staff accounts belong to exactly one operator.
+type Operator struct {
+	gorm.Model
+	Code     string `json:"code" gorm:"unique;not null"`   // Mã nhà xe, VD: "phuong-trang"
+	Name     string `json:"name" gorm:"not null"`          // Tên nhà xe
+	Phone    string `json:"phone"`                         // Tổng đài
+	Email    string `json:"email"`                         // Email liên hệ
+	Address  string `json:"address"`                       // Địa chỉ văn phòng
+	TaxCode  string `json:"tax_code"`                      // Mã số thuế, bắt buộc để xuất hóa đơn GTGT
+	IsActive bool   `json:"is_active" gorm:"default:true"` // Đang bán vé trên hệ thống
+
+	// Thương hiệu
+	LogoURL      string `json:"logo_url"`      // Logo hiển thị trên vé và trang đặt vé
+	PrimaryColor string `json:"primary_color"` // Màu thương hiệu (#RRGGBB)
+	TicketFooter string `json:"ticket_footer"` // Lời nhắn in cuối vé
+
+	// Chính sách hoàn tiền khi hủy vé đã thanh toán
+	FullRefundHours      int     `json:"full_refund_hours" gorm:"default:24"`      // Hủy trước giờ khởi hành ít nhất số giờ này: hoàn 100%
+	PartialRefundHours   int     `json:"partial_refund_hours" gorm:"default:4"`    // Hủy trước ít nhất số giờ này: hoàn một phần
+	PartialRefundPercent float64 `json:"partial_refund_percent" gorm:"default:50"` // Tỷ lệ hoàn một phần (%)
+
+	PaymentAccounts []OperatorPaymentAccount `json:"payment_accounts,omitempty"` // Tài khoản nhận tiền
+}
+
+// Validate operator data
+func (o *Operator) Validate() error {
+	if !operatorCodePattern.MatchString(o.Code) {
+		return errors.New("code must be 2-40 lowercase letters, digits or dashes")
+	}
+	if o.Name == "" {
+		return errors.New("name is required")
+	}
+	if o.TaxCode != "" && !ValidTaxCode(o.TaxCode) {
+		return errors.New("invalid tax code")
+	}
+	if o.PrimaryColor != "" && !colorPattern.MatchString(o.PrimaryColor) {
+		return errors.New("primary color must be in #RRGGBB format")
+	}
+	if o.PartialRefundHours < 0 || o.FullRefundHours < o.PartialRefundHours {
+		return errors.New("full refund hours must not be less than partial refund hours")
+	}
+	if o.PartialRefundPercent < 0 || o.PartialRefundPercent > 100 {
+		return errors.New("partial refund percent must be between 0 and 100")
+	}
+	return nil
+}
+
+// RefundPercent returns the share of the paid amount refunded for a cancellation
+// made at the given time before departure
+func (o *Operator) RefundPercent(departure, cancelledAt time.Time) float64 {
+	notice := departure.Sub(cancelledAt)
+	switch {
+	case notice >= time.Duration(o.FullRefundHours)*time.Hour:
+		return 100
+	case notice >= time.Duration(o.PartialRefundHours)*time.Hour:
+		return o.PartialRefundPercent
+	}
+	return 0
+}
+
+type PaymentAccountMethod string
+
+const (
+	PaymentAccountBank    PaymentAccountMethod = "bank_transfer" // Chuyển khoản ngân hàng
+	PaymentAccountMomo    PaymentAccountMethod = "momo"          // Ví MoMo
+	PaymentAccountZaloPay PaymentAccountMethod = "zalopay"       // Ví ZaloPay
+	PaymentAccountVNPay   PaymentAccountMethod = "vnpay"         // Cổng VNPay
+)
+
+// IsValid checks whether the method is supported
+func (m PaymentAccountMethod) IsValid() bool {
+	switch m {
+	case PaymentAccountBank, PaymentAccountMomo, PaymentAccountZaloPay, PaymentAccountVNPay:
+		return true
+	}
+	return false
+}
+
+// OperatorPaymentAccount is an account where an operator receives payments for its tickets
+type OperatorPaymentAccount struct {
+	gorm.Model
+	OperatorID    uint                 `json:"operator_id" gorm:"not null;index"` // Nhà xe sở hữu
+	Method        PaymentAccountMethod `json:"method" gorm:"not null"`            // Hình thức nhận tiền
+	Provider      string               `json:"provider"`                          // Ngân hàng hoặc nhà cung cấp ví
+	AccountNumber string               `json:"account_number" gorm:"not null"`    // Số tài khoản / số ví / mã merchant
+	AccountName   string               `json:"account_name"`                      // Chủ tài khoản
+	IsDefault     bool                 `json:"is_default"`                        // Tài khoản mặc định hiển thị cho khách
+	IsActive      bool                 `json:"is_active" gorm:"default:true"`     // Còn sử dụng
+}
+
+// Validate payment account data
+func (a *OperatorPaymentAccount) Validate() error {
+	if !a.Method.IsValid() {
+		return errors.New("invalid payment method")
+	}
+	if a.AccountNumber == "" {
+		return errors.New("account number is required")
+	}
+	if a.Method == PaymentAccountBank && (a.Provider == "" || a.AccountName == "") {
+		return errors.New("bank name and account holder are required for bank transfers")
+	}
+	return nil
+}
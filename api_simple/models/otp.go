@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type OTPPurpose string
+
+const (
+	OTPPurposeRegister      OTPPurpose = "register"       // Xác minh đăng ký
+	OTPPurposeResetPassword OTPPurpose = "reset_password" // Đặt lại mật khẩu
+	OTPPurposeLogin         OTPPurpose = "login"          // Đăng nhập / mở khóa tài khoản
+	OTPPurposeGuestLookup   OTPPurpose = "guest_lookup"   // Tra cứu vé của khách vãng lai
+	OTPPurposeSupport       OTPPurpose = "support"        // Khách vãng lai gửi yêu cầu hỗ trợ
+)
+
+// IsValid reports whether the purpose is one of the supported OTP purposes
+func (p OTPPurpose) IsValid() bool {
+	switch p {
+	case OTPPurposeRegister, OTPPurposeResetPassword, OTPPurposeLogin, OTPPurposeGuestLookup, OTPPurposeSupport:
+		return true
+	}
+	return false
+}
+
+// RequiresAccount reports whether codes for the purpose are only sent to registered phones
+func (p OTPPurpose) RequiresAccount() bool {
+	return p != OTPPurposeGuestLookup && p != OTPPurposeSupport
+}
+
+// OTPCode stores a hashed one-time password issued to a phone for a purpose
+type OTPCode struct {
+	gorm.Model
+	Phone       string     `json:"phone" gorm:"not null;index:idx_otp_phone_purpose"`   // Số điện thoại nhận mã
+	Purpose     OTPPurpose `json:"purpose" gorm:"not null;index:idx_otp_phone_purpose"` // Mục đích sử dụng mã
+	CodeHash    string     `json:"-" gorm:"not null"`                                   // Mã OTP đã băm
+	ExpiresAt   time.Time  `json:"expires_at" gorm:"not null"`                          // Thời điểm hết hạn
+	Attempts    int        `json:"attempts" gorm:"not null;default:0"`                  // Số lần nhập sai
+	LockedUntil *time.Time `json:"locked_until,omitempty"`                              // Khóa xác minh đến thời điểm
+	SentAt      time.Time  `json:"sent_at" gorm:"not null"`                             // Thời điểm gửi mã
+	ConsumedAt  *time.Time `json:"consumed_at,omitempty"`                               // Thời điểm đã sử dụng
+}
+
+// IsExpired checks whether the code has passed its expiry time
+func (o *OTPCode) IsExpired(now time.Time) bool {
+	return now.After(o.ExpiresAt)
+}
+
+// IsLocked checks whether verification is temporarily locked
+func (o *OTPCode) IsLocked(now time.Time) bool {
+	return o.LockedUntil != nil && now.Before(*o.LockedUntil)
+}
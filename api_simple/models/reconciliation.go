@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ReconciliationStatus string
+
+const (
+	ReconciliationMatched           ReconciliationStatus = "matched"            // Khớp mã giao dịch và số tiền
+	ReconciliationAmountMismatch    ReconciliationStatus = "amount_mismatch"    // Lệch số tiền
+	ReconciliationUnpaidBooking     ReconciliationStatus = "unpaid_booking"     // Cổng đã thu nhưng đơn chưa ghi nhận thanh toán
+	ReconciliationDuplicate         ReconciliationStatus = "duplicate"          // Giao dịch bị quyết toán nhiều lần
+	ReconciliationMissingBooking    ReconciliationStatus = "missing_booking"    // Có trong file quyết toán, không có trong hệ thống
+	ReconciliationMissingSettlement ReconciliationStatus = "missing_settlement" // Đã thu trong hệ thống, không có trong file quyết toán
+)
+
+// IsIssue reports whether the entry needs to be looked at by staff
+func (s ReconciliationStatus) IsIssue() bool {
+	return s != ReconciliationMatched
+}
+
+// Resolution actions staff can take on a flagged entry
+const (
+	ResolutionAccept = "accept" // Chấp nhận chênh lệch, kèm giải trình
+	ResolutionLink   = "link"   // Gắn giao dịch với đơn đặt vé đúng
+)
+
+// SettlementReport is a settlement file of a payment provider imported for reconciliation.
+// The checksum keeps the same file from being imported twice.
+type SettlementReport struct {
+	gorm.Model
+	OperatorID    uint                 `json:"operator_id" gorm:"uniqueIndex:idx_settlement_file"`                       // Nhà xe
+	Provider      PaymentAccountMethod `json:"provider" gorm:"not null"`                                                 // Cổng thanh toán (vnpay, momo...)
+	FileName      string               `json:"file_name"`                                                                // Tên file quyết toán
+	Checksum      string               `json:"-" gorm:"not null;uniqueIndex:idx_settlement_file"`                        // SHA-256 nội dung file
+	PeriodFrom    *time.Time           `json:"period_from,omitempty"`                                                    // Giao dịch sớm nhất trong file
+	PeriodTo      *time.Time           `json:"period_to,omitempty"`                                                      // Giao dịch muộn nhất trong file
+	RowCount      int                  `json:"row_count"`                                                                // Số dòng giao dịch
+	SettledAmount Money                `json:"settled_amount"`                                                           // Tổng tiền cổng thanh toán quyết toán
+	FeeAmount     Money                `json:"fee_amount"`                                                               // Tổng phí cổng thanh toán
+	MatchedCount  int                  `json:"matched_count"`                                                            // Số giao dịch khớp
+	IssueCount    int                  `json:"issue_count"`                                                              // Số giao dịch cần xử lý
+	ImportedBy    *uint                `json:"imported_by,omitempty"`                                                    // Người nhập (trống khi nhập tự động)
+	Entries       []SettlementEntry    `json:"entries,omitempty" gorm:"foreignKey:ReportID;constraint:OnDelete:CASCADE"` // Kết quả đối soát từng giao dịch
+}
+
+// SettlementEntry is one transaction of a settlement file, or a payment of ours the
+// file is missing, with the outcome of matching it against the booking
+type SettlementEntry struct {
+	gorm.Model
+	OperatorID     uint                 `json:"operator_id" gorm:"index"`           // Nhà xe
+	ReportID       uint                 `json:"report_id" gorm:"not null;index"`    // File quyết toán
+	Line           int                  `json:"line"`                               // Dòng trong file (0: giao dịch thiếu trong file)
+	Reference      string               `json:"reference" gorm:"index"`             // Mã giao dịch
+	Amount         Money                `json:"amount"`                             // Số tiền cổng thanh toán quyết toán
+	Fee            Money                `json:"fee"`                                // Phí cổng thanh toán
+	PaidAt         *time.Time           `json:"paid_at,omitempty"`                  // Thời điểm khách thanh toán
+	BookingID      *uint                `json:"booking_id,omitempty" gorm:"index"`  // Đơn đặt vé tương ứng
+	Booking        *Booking             `json:"booking,omitempty"`                  // Thông tin đơn
+	ExpectedAmount Money                `json:"expected_amount"`                    // Số tiền theo đơn đặt vé
+	Status         ReconciliationStatus `json:"status" gorm:"not null;index"`       // Kết quả đối soát
+	Resolution     string               `json:"resolution,omitempty"`               // Cách xử lý (accept, link)
+	ResolutionNote string               `json:"resolution_note,omitempty"`          // Giải trình
+	ResolvedBy     *uint                `json:"resolved_by,omitempty"`              // Người xử lý
+	ResolvedAt     *time.Time           `json:"resolved_at,omitempty" gorm:"index"` // Thời điểm xử lý
+}
+
+// IsOpen reports whether the entry is a discrepancy nobody has dealt with yet
+func (e *SettlementEntry) IsOpen() bool {
+	return e.Status.IsIssue() && e.ResolvedAt == nil
+}
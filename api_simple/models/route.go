@@ -1,19 +1,113 @@
 package models
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"ticket-management/api_simple/utils"
+
 	"gorm.io/gorm"
 )
 
+// Errors returned by Route.Validate
+var (
+	ErrRouteEndpointsRequired      = errors.New("origin and destination are required")
+	ErrRouteSameEndpoints          = errors.New("origin and destination must differ")
+	ErrRouteDistance               = errors.New("distance must be positive")
+	ErrRouteBasePrice              = errors.New("base price must be positive")
+	ErrRouteDuration               = errors.New("invalid route duration")
+	ErrRouteOriginCoordinates      = errors.New("invalid origin coordinates")
+	ErrRouteDestinationCoordinates = errors.New("invalid destination coordinates")
+)
+
 type Route struct {
 	gorm.Model
-	Origin        string  `json:"origin"`         // Điểm đi
-	Destination   string  `json:"destination"`    // Điểm đến
-	Distance      float64 `json:"distance"`       // Khoảng cách (km)
-	Duration      string  `json:"duration"`       // Thời gian di chuyển (VD: "4h30m")
-	BasePrice     float64 `json:"base_price"`     // Giá cơ bản
-	IsActive      bool    `json:"is_active"`      // Trạng thái hoạt động
-	TotalTrips    int64   `json:"total_trips"`    // Tổng số chuyến
-	UpcomingTrips int64   `json:"upcoming_trips"` // Số chuyến sắp tới
-	MinPrice      float64 `json:"min_price"`      // Giá thấp nhất
-	MaxPrice      float64 `json:"max_price"`      // Giá cao nhất
+	OperatorID    uint    `json:"operator_id" gorm:"index"` // Nhà xe khai thác tuyến
+	Origin        string  `json:"origin"`                   // Điểm đi
+	Destination   string  `json:"destination"`              // Điểm đến
+	Distance      float64 `json:"distance"`                 // Khoảng cách (km)
+	Duration      string  `json:"duration"`                 // Thời gian di chuyển (VD: "4h30m")
+	BasePrice     Money   `json:"base_price"`               // Giá cơ bản
+	IsActive      bool    `json:"is_active"`                // Trạng thái hoạt động
+	TotalTrips    int64   `json:"total_trips"`              // Tổng số chuyến
+	UpcomingTrips int64   `json:"upcoming_trips"`           // Số chuyến sắp tới
+	MinPrice      Money   `json:"min_price"`                // Giá thấp nhất
+	MaxPrice      Money   `json:"max_price"`                // Giá cao nhất
+	TotalBookings int64   `json:"total_bookings"`           // Số đơn đặt vé chưa hủy
+	TotalSeats    int64   `json:"total_seats"`              // Tổng số ghế của các chuyến
+	BookedSeats   int64   `json:"booked_seats"`             // Số ghế đã bán của các chuyến
+	LoadFactor    float64 `json:"load_factor"`              // Tỷ lệ lấp đầy (0-1)
+
+	StatsUpdatedAt *time.Time `json:"stats_updated_at,omitempty"` // Thời điểm tính lại thống kê gần nhất
+
+	OriginLat      *float64 `json:"origin_lat,omitempty"`      // Vĩ độ điểm đi
+	OriginLng      *float64 `json:"origin_lng,omitempty"`      // Kinh độ điểm đi
+	DestinationLat *float64 `json:"destination_lat,omitempty"` // Vĩ độ điểm đến
+	DestinationLng *float64 `json:"destination_lng,omitempty"` // Kinh độ điểm đến
+
+	Currency Currency `json:"currency" gorm:"not null;default:'VND'"` // Đơn vị tiền tệ của giá vé
+}
+
+// BeforeCreate hook to set default values
+func (r *Route) BeforeCreate(tx *gorm.DB) error {
+	if r.Currency == "" {
+		r.Currency = DefaultCurrency
+	}
+	return nil
+}
+
+// Validate validates route data
+func (r *Route) Validate() error {
+	if strings.TrimSpace(r.Origin) == "" || strings.TrimSpace(r.Destination) == "" {
+		return ErrRouteEndpointsRequired
+	}
+	if strings.EqualFold(strings.TrimSpace(r.Origin), strings.TrimSpace(r.Destination)) {
+		return ErrRouteSameEndpoints
+	}
+	if r.Distance <= 0 {
+		return ErrRouteDistance
+	}
+	if r.BasePrice <= 0 {
+		return ErrRouteBasePrice
+	}
+	if _, err := r.ParsedDuration(); err != nil {
+		return err
+	}
+	if r.OriginLat != nil && r.OriginLng != nil && !utils.ValidCoordinates(*r.OriginLat, *r.OriginLng) {
+		return ErrRouteOriginCoordinates
+	}
+	if r.DestinationLat != nil && r.DestinationLng != nil && !utils.ValidCoordinates(*r.DestinationLat, *r.DestinationLng) {
+		return ErrRouteDestinationCoordinates
+	}
+	return nil
+}
+
+// HasCoordinates reports whether both ends of the route have GPS coordinates
+func (r *Route) HasCoordinates() bool {
+	return r.OriginLat != nil && r.OriginLng != nil && r.DestinationLat != nil && r.DestinationLng != nil
+}
+
+// ParsedDuration returns the travel time of the route
+func (r *Route) ParsedDuration() (time.Duration, error) {
+	return ParseRouteDuration(r.Duration)
+}
+
+// ParseRouteDuration parses travel times such as "4h30m", "4h30", "45m" or "4 giờ 30 phút"
+func ParseRouteDuration(value string) (time.Duration, error) {
+	normalized := strings.ToLower(strings.Join(strings.Fields(value), ""))
+	normalized = strings.NewReplacer("giờ", "h", "phút", "m").Replace(normalized)
+
+	// "4h30" means 4 hours 30 minutes
+	if strings.Contains(normalized, "h") && normalized != "" && unicode.IsDigit(rune(normalized[len(normalized)-1])) {
+		normalized += "m"
+	}
+
+	duration, err := time.ParseDuration(normalized)
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("%w: %s", ErrRouteDuration, value)
+	}
+	return duration, nil
 }
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"ticket-management/api_simple/utils"
+
+	"gorm.io/gorm"
+)
+
+type RoutePointType string
+
+const (
+	RoutePointPickup  RoutePointType = "pickup"  // Điểm đón
+	RoutePointDropoff RoutePointType = "dropoff" // Điểm trả
+)
+
+// IsValid checks whether the point type is supported
+func (t RoutePointType) IsValid() bool {
+	return t == RoutePointPickup || t == RoutePointDropoff
+}
+
+// RoutePoint is a pickup or drop-off point of a route (office, roadside stop, hotel...)
+type RoutePoint struct {
+	gorm.Model
+	RouteID       uint           `json:"route_id" gorm:"not null;index"` // Tuyến đường
+	Type          RoutePointType `json:"type" gorm:"not null;index"`     // Điểm đón hoặc điểm trả
+	Name          string         `json:"name" gorm:"not null"`           // Tên điểm (VD: "Văn phòng Mỹ Đình")
+	Address       string         `json:"address"`                        // Địa chỉ
+	Latitude      *float64       `json:"latitude,omitempty"`             // Vĩ độ
+	Longitude     *float64       `json:"longitude,omitempty"`            // Kinh độ
+	OffsetMinutes int            `json:"offset_minutes"`                 // Số phút sau giờ khởi hành
+	Shuttle       bool           `json:"shuttle"`                        // Đón/trả bằng xe trung chuyển
+	Surcharge     Money          `json:"surcharge"`                      // Phụ phí trung chuyển cho mỗi hành khách
+	IsActive      bool           `json:"is_active" gorm:"default:true"`  // Còn sử dụng
+}
+
+// Validate route point data
+func (p *RoutePoint) Validate() error {
+	if p.RouteID == 0 {
+		return errors.New("route is required")
+	}
+	if !p.Type.IsValid() {
+		return errors.New("invalid point type")
+	}
+	if p.Name == "" {
+		return errors.New("point name is required")
+	}
+	if p.OffsetMinutes < 0 {
+		return errors.New("time offset must not be negative")
+	}
+	if (p.Latitude == nil) != (p.Longitude == nil) {
+		return errors.New("both latitude and longitude are required")
+	}
+	if p.Latitude != nil && !utils.ValidCoordinates(*p.Latitude, *p.Longitude) {
+		return errors.New("invalid coordinates")
+	}
+	if p.Surcharge < 0 {
+		return errors.New("surcharge must not be negative")
+	}
+	if p.Surcharge > 0 && !p.Shuttle {
+		return errors.New("surcharge only applies to shuttle points")
+	}
+	return nil
+}
+
+// HasCoordinates reports whether the point has GPS coordinates
+func (p *RoutePoint) HasCoordinates() bool {
+	return p.Latitude != nil && p.Longitude != nil
+}
+
+// TimeFrom returns the scheduled time at the point for a trip leaving at departure
+func (p *RoutePoint) TimeFrom(departure time.Time) time.Time {
+	return departure.Add(time.Duration(p.OffsetMinutes) * time.Minute)
+}
@@ -0,0 +1,167 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"ticket-management/api_simple/utils"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// ReportKind is a report that can be exported or delivered on a schedule
+type ReportKind string
+
+const (
+	ReportKindBookings  ReportKind = "bookings"  // Danh sách đặt vé
+	ReportKindRevenue   ReportKind = "revenue"   // Doanh thu
+	ReportKindOccupancy ReportKind = "occupancy" // Tỷ lệ lấp đầy
+)
+
+// ReportFormat is the file format of an exported report
+type ReportFormat string
+
+const (
+	ReportFormatCSV  ReportFormat = "csv"
+	ReportFormatXLSX ReportFormat = "xlsx"
+)
+
+// ReportPeriod is the span of time before each run that a scheduled report covers
+type ReportPeriod string
+
+const (
+	ReportPeriodDay   ReportPeriod = "day"   // Ngày hôm trước
+	ReportPeriodWeek  ReportPeriod = "week"  // Tuần trước, từ thứ Hai đến Chủ nhật
+	ReportPeriodMonth ReportPeriod = "month" // Tháng trước
+)
+
+// reportGroups are the groupings each report accepts, the first being the default
+var reportGroups = map[ReportKind][]string{
+	ReportKindBookings:  {""},
+	ReportKindRevenue:   {"day", "week", "month", "route", "bus", "channel"},
+	ReportKindOccupancy: {"trip", "route"},
+}
+
+// ReportFilters narrow a scheduled report, stored as JSON
+type ReportFilters struct {
+	Status  BookingStatus `json:"status,omitempty"`   // Trạng thái đặt vé (báo cáo bookings)
+	GroupBy string        `json:"group_by,omitempty"` // Cách nhóm (báo cáo revenue, occupancy)
+	RouteID uint          `json:"route_id,omitempty"` // Chỉ tính một tuyến (báo cáo revenue, occupancy)
+	BusID   uint          `json:"bus_id,omitempty"`   // Chỉ tính một xe (báo cáo revenue, occupancy)
+}
+
+// Grouping returns how a report of kind is grouped, its default grouping when not set
+func (f ReportFilters) Grouping(kind ReportKind) string {
+	if f.GroupBy == "" && len(reportGroups[kind]) > 0 {
+		return reportGroups[kind][0]
+	}
+	return f.GroupBy
+}
+
+// Value implements driver.Valuer
+func (f ReportFilters) Value() (driver.Value, error) {
+	b, err := json.Marshal(f)
+	return string(b), err
+}
+
+// Scan implements sql.Scanner
+func (f *ReportFilters) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*f = ReportFilters{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported report filters type %T", value)
+	}
+	return json.Unmarshal(data, f)
+}
+
+// ScheduledReport is a report emailed to a list of recipients on a cron schedule
+type ScheduledReport struct {
+	gorm.Model
+	OperatorID uint           `json:"operator_id" gorm:"index"`
+	Name       string         `json:"name" gorm:"not null"`                          // Tên báo cáo, dùng làm tiêu đề email
+	Report     ReportKind     `json:"report" gorm:"size:20;not null"`                // Loại báo cáo
+	Format     ReportFormat   `json:"format" gorm:"size:10;not null;default:'xlsx'"` // Định dạng file đính kèm
+	Filters    ReportFilters  `json:"filters" gorm:"type:text"`                      // Bộ lọc của báo cáo
+	Period     ReportPeriod   `json:"period" gorm:"size:10;not null"`                // Khoảng thời gian báo cáo, tính lùi từ lúc gửi
+	Recipients pq.StringArray `json:"recipients" gorm:"type:text[];not null"`        // Email người nhận
+	Cron       string         `json:"cron" gorm:"not null"`                          // Lịch gửi theo cú pháp cron 5 trường, giờ địa phương
+	IsActive   bool           `json:"is_active" gorm:"not null"`                     // Còn gửi theo lịch
+	NextRunAt  *time.Time     `json:"next_run_at,omitempty" gorm:"index"`            // Lần gửi tiếp theo
+	LastRunAt  *time.Time     `json:"last_run_at,omitempty"`                         // Lần gửi gần nhất
+	LastError  string         `json:"last_error,omitempty"`                          // Lỗi của lần gửi gần nhất
+}
+
+// Validate validates scheduled report data
+func (r *ScheduledReport) Validate() error {
+	if r.Name == "" {
+		return errors.New("report name is required")
+	}
+	groups, ok := reportGroups[r.Report]
+	if !ok {
+		return errors.New("invalid report")
+	}
+	validGroup := false
+	for _, group := range groups {
+		validGroup = validGroup || r.Filters.Grouping(r.Report) == group
+	}
+	if !validGroup {
+		return errors.New("invalid report grouping")
+	}
+	if r.Report == ReportKindBookings && (r.Filters.RouteID != 0 || r.Filters.BusID != 0) {
+		return errors.New("bookings report cannot be filtered by route or bus")
+	}
+	switch r.Filters.Status {
+	case "", BookingStatusPending, BookingStatusConfirmed, BookingStatusCancelled:
+	default:
+		return errors.New("invalid booking status")
+	}
+
+	if r.Format != ReportFormatCSV && r.Format != ReportFormatXLSX {
+		return errors.New("invalid report format")
+	}
+	if r.Period != ReportPeriodDay && r.Period != ReportPeriodWeek && r.Period != ReportPeriodMonth {
+		return errors.New("invalid report period")
+	}
+	if len(r.Recipients) == 0 {
+		return errors.New("report must have at least one recipient")
+	}
+	for _, email := range r.Recipients {
+		if !utils.ValidateEmail(email) {
+			return errors.New("invalid recipient email: " + email)
+		}
+	}
+	if _, err := utils.ParseCron(r.Cron); err != nil {
+		return err
+	}
+	return nil
+}
+
+// PeriodBefore returns the first and last instant of the period the report covers when
+// sent at t: the calendar day, week or month before the one t falls in
+func (r *ScheduledReport) PeriodBefore(t time.Time) (time.Time, time.Time) {
+	t = t.Local()
+	end := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+	var start time.Time
+	switch r.Period {
+	case ReportPeriodWeek:
+		end = end.AddDate(0, 0, -(int(end.Weekday())+6)%7)
+		start = end.AddDate(0, 0, -7)
+	case ReportPeriodMonth:
+		end = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.Local)
+		start = end.AddDate(0, -1, 0)
+	default:
+		start = end.AddDate(0, 0, -1)
+	}
+	return start, end.Add(-time.Nanosecond)
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"gorm.io/gorm"
@@ -31,9 +32,11 @@ type Seat struct {
 	Floor       int        `json:"floor" gorm:"not null"`  // Tầng (1 hoặc 2)
 	Type        SeatType   `json:"type" gorm:"not null"`   // Loại ghế
 	Status      SeatStatus `json:"status" gorm:"not null"` // Trạng thái ghế
-	Price       float64    `json:"price"`                  // Giá ghế (có thể khác nhau theo loại)
+	Price       Money      `json:"price"`                  // Giá ghế (có thể khác nhau theo loại)
 	LockedUntil *time.Time `json:"locked_until,omitempty"` // Thời gian khóa ghế
 	LockedBy    *uint      `json:"locked_by,omitempty"`    // ID người khóa ghế
+
+	Currency Currency `json:"currency" gorm:"not null;default:'VND'"` // Đơn vị tiền tệ của giá ghế, theo chuyến
 }
 
 // BeforeCreate hook to set default values
@@ -41,21 +44,29 @@ func (s *Seat) BeforeCreate(tx *gorm.DB) error {
 	if s.Status == "" {
 		s.Status = SeatStatusAvailable
 	}
-	if s.Price == 0 {
-		// Get price from trip
+	if s.Price == 0 || s.Currency == "" {
+		// Get price and currency from trip
 		var trip Trip
 		if err := tx.First(&trip, s.TripID).Error; err != nil {
 			return err
 		}
-		// Apply price multiplier based on seat type
-		multiplier := 1.0
-		switch s.Type {
-		case SeatTypeDouble:
-			multiplier = 1.2
-		case SeatTypeSpecial:
-			multiplier = 1.5
+		if s.Price == 0 {
+			// Apply price multiplier based on seat type
+			multiplier := 1.0
+			switch s.Type {
+			case SeatTypeDouble:
+				multiplier = 1.2
+			case SeatTypeSpecial:
+				multiplier = 1.5
+			}
+			s.Price = trip.Price.ScalePrice(multiplier)
+		}
+		if s.Currency == "" {
+			s.Currency = trip.Currency
 		}
-		s.Price = trip.Price * multiplier
+	}
+	if s.Currency == "" {
+		s.Currency = DefaultCurrency
 	}
 	return nil
 }
@@ -82,3 +93,58 @@ func (s *Seat) Validate() error {
 	}
 	return nil
 }
+
+// SeatLayout returns the seats of a trip run by bus, priced from the trip's price. Seats are
+// split evenly across floors and numbered A01, A02... downstairs and B01... upstairs;
+// the first four of a floor are special seats (+20%, +30% upstairs), odd numbers are
+// double seats and upstairs seats cost 10% more.
+func SeatLayout(bus *Bus, trip *Trip) []Seat {
+	basePrice := trip.Price
+	seatsPerFloor := bus.SeatCount
+	if bus.FloorCount == 2 {
+		seatsPerFloor = bus.SeatCount / 2
+	}
+
+	seats := make([]Seat, 0, seatsPerFloor*bus.FloorCount)
+	for floor := 1; floor <= bus.FloorCount; floor++ {
+		floorPrefix := "A"
+		if floor == 2 {
+			floorPrefix = "B"
+		}
+
+		for i := 1; i <= seatsPerFloor; i++ {
+			seatType := SeatTypeSingle
+			price := basePrice
+
+			// Special seats (first 4 seats)
+			if i <= 4 {
+				seatType = SeatTypeSpecial
+				price = basePrice.ScalePrice(1.2) // +20% for special
+			}
+
+			// Double seats (odd numbers)
+			if i%2 != 0 {
+				seatType = SeatTypeDouble
+			}
+
+			// Upstairs premium (+10% for floor 2)
+			if floor == 2 {
+				price = basePrice.ScalePrice(1.1)
+				if i <= 4 {
+					price = basePrice.ScalePrice(1.3) // +30% for special upstairs
+				}
+			}
+
+			seats = append(seats, Seat{
+				TripID:   trip.ID,
+				Number:   fmt.Sprintf("%s%02d", floorPrefix, i),
+				Type:     seatType,
+				Floor:    floor,
+				Status:   SeatStatusAvailable,
+				Price:    price,
+				Currency: trip.Currency,
+			})
+		}
+	}
+	return seats
+}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"ticket-management/api_simple/utils"
+
+	"gorm.io/gorm"
+)
+
+type ShipmentStatus string
+
+const (
+	ShipmentStatusReceived  ShipmentStatus = "received"   // Đã nhận hàng tại văn phòng
+	ShipmentStatusLoaded    ShipmentStatus = "loaded"     // Đã xếp lên xe
+	ShipmentStatusInTransit ShipmentStatus = "in_transit" // Đang vận chuyển
+	ShipmentStatusArrived   ShipmentStatus = "arrived"    // Đã đến nơi, chờ nhận
+	ShipmentStatusDelivered ShipmentStatus = "delivered"  // Đã giao cho người nhận
+	ShipmentStatusCancelled ShipmentStatus = "cancelled"  // Đã hủy
+)
+
+// shipmentTransitions lists the statuses a shipment may move to from each status
+var shipmentTransitions = map[ShipmentStatus][]ShipmentStatus{
+	ShipmentStatusReceived:  {ShipmentStatusLoaded, ShipmentStatusCancelled},
+	ShipmentStatusLoaded:    {ShipmentStatusInTransit, ShipmentStatusReceived},
+	ShipmentStatusInTransit: {ShipmentStatusArrived},
+	ShipmentStatusArrived:   {ShipmentStatusDelivered},
+}
+
+// CanTransitionTo reports whether a shipment may move from s to next.
+// A loaded parcel may be taken off the bus again before departure.
+func (s ShipmentStatus) CanTransitionTo(next ShipmentStatus) bool {
+	for _, allowed := range shipmentTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValid checks whether the status is supported
+func (s ShipmentStatus) IsValid() bool {
+	switch s {
+	case ShipmentStatusReceived, ShipmentStatusLoaded, ShipmentStatusInTransit,
+		ShipmentStatusArrived, ShipmentStatusDelivered, ShipmentStatusCancelled:
+		return true
+	}
+	return false
+}
+
+type ShipmentPayer string
+
+const (
+	ShipmentPayerSender   ShipmentPayer = "sender"   // Người gửi trả khi gửi hàng
+	ShipmentPayerReceiver ShipmentPayer = "receiver" // Người nhận trả khi nhận hàng
+)
+
+// Shipment is a parcel or cargo consignment carried on a trip
+type Shipment struct {
+	gorm.Model
+	OperatorID      uint           `json:"operator_id" gorm:"index"`                        // Nhà xe nhận hàng
+	TrackingCode    string         `json:"tracking_code" gorm:"unique;not null"`            // Mã vận đơn
+	TripID          uint           `json:"trip_id" gorm:"not null;index"`                   // Chuyến xe chở hàng
+	Trip            *Trip          `json:"trip,omitempty"`                                  // Thông tin chuyến
+	SenderName      string         `json:"sender_name" gorm:"not null"`                     // Người gửi
+	SenderPhone     string         `json:"sender_phone" gorm:"not null"`                    // SĐT người gửi
+	ReceiverName    string         `json:"receiver_name" gorm:"not null"`                   // Người nhận
+	ReceiverPhone   string         `json:"receiver_phone" gorm:"not null;index"`            // SĐT người nhận
+	ReceiverAddress string         `json:"receiver_address"`                                // Địa chỉ người nhận (nếu giao tận nơi)
+	Description     string         `json:"description"`                                     // Mô tả hàng hóa
+	WeightKg        float64        `json:"weight_kg" gorm:"not null"`                       // Khối lượng thực (kg)
+	LengthCm        float64        `json:"length_cm"`                                       // Chiều dài (cm)
+	WidthCm         float64        `json:"width_cm"`                                        // Chiều rộng (cm)
+	HeightCm        float64        `json:"height_cm"`                                       // Chiều cao (cm)
+	ChargeableKg    float64        `json:"chargeable_kg"`                                   // Khối lượng tính cước (lớn hơn giữa khối lượng thực và quy đổi)
+	Price           Money          `json:"price"`                                           // Cước phí
+	Payer           ShipmentPayer  `json:"payer" gorm:"not null;default:'sender'"`          // Người trả cước
+	PaymentStatus   PaymentStatus  `json:"payment_status" gorm:"not null;default:'unpaid'"` // Trạng thái thanh toán
+	Status          ShipmentStatus `json:"status" gorm:"not null;default:'received';index"` // Trạng thái vận chuyển
+	CreatedBy       *uint          `json:"created_by,omitempty"`                            // Nhân viên nhận hàng
+	Note            string         `json:"note"`                                            // Ghi chú
+}
+
+// ShipmentEvent is a status change in the history of a shipment
+type ShipmentEvent struct {
+	ID         uint           `json:"id" gorm:"primaryKey"`
+	CreatedAt  time.Time      `json:"created_at"`
+	ShipmentID uint           `json:"shipment_id" gorm:"not null;index"`
+	Status     ShipmentStatus `json:"status" gorm:"not null"`
+	Note       string         `json:"note,omitempty"`
+	UpdatedBy  *uint          `json:"updated_by,omitempty"` // Nhân viên hoặc tài xế cập nhật
+	At         time.Time      `json:"at" gorm:"not null"`
+}
+
+// BeforeCreate hook to generate the tracking code
+func (s *Shipment) BeforeCreate(tx *gorm.DB) error {
+	if s.TrackingCode == "" {
+		// Format: SH-YYYYMMDD-XXXXXX
+		s.TrackingCode = fmt.Sprintf("SH-%s-%s", time.Now().Format("20060102"), utils.GenerateRandomString(6))
+	}
+	return nil
+}
+
+// Validate shipment data
+func (s *Shipment) Validate() error {
+	if s.TripID == 0 {
+		return errors.New("trip is required")
+	}
+	if s.SenderName == "" || s.ReceiverName == "" {
+		return errors.New("sender and receiver names are required")
+	}
+	if !utils.ValidatePhone(s.SenderPhone) || !utils.ValidatePhone(s.ReceiverPhone) {
+		return errors.New("invalid sender or receiver phone number")
+	}
+	if s.WeightKg <= 0 {
+		return errors.New("weight must be positive")
+	}
+	if s.LengthCm < 0 || s.WidthCm < 0 || s.HeightCm < 0 {
+		return errors.New("dimensions must not be negative")
+	}
+	if s.Payer != ShipmentPayerSender && s.Payer != ShipmentPayerReceiver {
+		return errors.New("invalid payer")
+	}
+	return nil
+}
+
+// VolumeM3 returns the parcel volume in cubic metres
+func (s *Shipment) VolumeM3() float64 {
+	return s.LengthCm * s.WidthCm * s.HeightCm / 1e6
+}
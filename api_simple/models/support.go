@@ -0,0 +1,152 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"ticket-management/api_simple/utils"
+
+	"gorm.io/gorm"
+)
+
+type SupportCategory string
+
+const (
+	SupportCategoryRefund    SupportCategory = "refund"    // Hoàn tiền
+	SupportCategoryLostItem  SupportCategory = "lost_item" // Thất lạc đồ
+	SupportCategoryComplaint SupportCategory = "complaint" // Khiếu nại
+	SupportCategoryOther     SupportCategory = "other"     // Yêu cầu khác
+)
+
+// IsValid checks whether the category is supported
+func (c SupportCategory) IsValid() bool {
+	switch c {
+	case SupportCategoryRefund, SupportCategoryLostItem, SupportCategoryComplaint, SupportCategoryOther:
+		return true
+	}
+	return false
+}
+
+type SupportStatus string
+
+const (
+	SupportStatusOpen     SupportStatus = "open"     // Chờ nhân viên xử lý
+	SupportStatusPending  SupportStatus = "pending"  // Chờ khách hàng phản hồi
+	SupportStatusResolved SupportStatus = "resolved" // Đã giải quyết, khách có thể mở lại bằng cách trả lời
+	SupportStatusClosed   SupportStatus = "closed"   // Đã đóng
+)
+
+// supportTransitions lists the statuses staff may move a ticket to from each status
+var supportTransitions = map[SupportStatus][]SupportStatus{
+	SupportStatusOpen:     {SupportStatusPending, SupportStatusResolved, SupportStatusClosed},
+	SupportStatusPending:  {SupportStatusOpen, SupportStatusResolved, SupportStatusClosed},
+	SupportStatusResolved: {SupportStatusOpen, SupportStatusClosed},
+}
+
+// CanTransitionTo reports whether a ticket may move from s to next. A closed ticket is final.
+func (s SupportStatus) CanTransitionTo(next SupportStatus) bool {
+	for _, allowed := range supportTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValid checks whether the status is supported
+func (s SupportStatus) IsValid() bool {
+	switch s {
+	case SupportStatusOpen, SupportStatusPending, SupportStatusResolved, SupportStatusClosed:
+		return true
+	}
+	return false
+}
+
+// SupportAuthor is who wrote a message of a support ticket
+type SupportAuthor string
+
+const (
+	SupportAuthorCustomer SupportAuthor = "customer" // Khách hàng
+	SupportAuthorStaff    SupportAuthor = "staff"    // Nhân viên nhà xe
+)
+
+// SupportTicket is a support case a customer opened with an operator, optionally about a booking
+type SupportTicket struct {
+	gorm.Model
+	OperatorID            uint             `json:"operator_id" gorm:"index"`                            // Nhà xe tiếp nhận
+	Code                  string           `json:"code" gorm:"unique;not null"`                         // Mã yêu cầu hỗ trợ
+	Category              SupportCategory  `json:"category" gorm:"size:20;not null;index"`              // Loại yêu cầu
+	Subject               string           `json:"subject" gorm:"not null"`                             // Tiêu đề
+	Status                SupportStatus    `json:"status" gorm:"size:20;not null;default:'open';index"` // Trạng thái xử lý
+	UserID                *uint            `json:"user_id,omitempty" gorm:"index"`                      // Khách hàng có tài khoản
+	User                  *User            `json:"user,omitempty"`                                      // Thông tin khách hàng
+	ContactName           string           `json:"contact_name"`                                        // Tên người liên hệ
+	ContactPhone          string           `json:"contact_phone" gorm:"not null;index"`                 // SĐT nhận thông báo, dùng để tra cứu
+	BookingID             *uint            `json:"booking_id,omitempty" gorm:"index"`                   // Đơn đặt vé liên quan
+	Booking               *Booking         `json:"booking,omitempty"`                                   // Thông tin đơn đặt vé
+	AssignedTo            *uint            `json:"assigned_to,omitempty" gorm:"index"`                  // Nhân viên phụ trách
+	Assignee              *User            `json:"assignee,omitempty" gorm:"foreignKey:AssignedTo"`     // Thông tin nhân viên phụ trách
+	LastMessageAt         time.Time        `json:"last_message_at"`                                     // Tin nhắn gần nhất
+	FirstResponseDueAt    time.Time        `json:"first_response_due_at"`                               // Hạn phản hồi đầu tiên (SLA)
+	FirstRespondedAt      *time.Time       `json:"first_responded_at,omitempty"`                        // Lần đầu nhân viên phản hồi
+	ResolutionDueAt       time.Time        `json:"resolution_due_at"`                                   // Hạn giải quyết (SLA)
+	ResolvedAt            *time.Time       `json:"resolved_at,omitempty"`                               // Thời điểm giải quyết
+	ClosedAt              *time.Time       `json:"closed_at,omitempty"`                                 // Thời điểm đóng
+	FirstResponseBreached bool             `json:"first_response_breached"`                             // Đã trễ hạn phản hồi đầu tiên
+	ResolutionBreached    bool             `json:"resolution_breached"`                                 // Đã trễ hạn giải quyết
+	Messages              []SupportMessage `json:"messages,omitempty" gorm:"foreignKey:TicketID"`       // Các tin nhắn
+}
+
+// SupportMessage is a message in the thread of a support ticket. Internal notes are only
+// shown to staff.
+type SupportMessage struct {
+	ID          uint                `json:"id" gorm:"primaryKey"`
+	CreatedAt   time.Time           `json:"created_at"`
+	TicketID    uint                `json:"ticket_id" gorm:"not null;index"`
+	Author      SupportAuthor       `json:"author" gorm:"size:20;not null"`
+	AuthorID    *uint               `json:"author_id,omitempty"`                               // Tài khoản người viết (khách vãng lai: trống)
+	Body        string              `json:"body" gorm:"type:text"`                             // Nội dung
+	Internal    bool                `json:"internal"`                                          // Ghi chú nội bộ, khách hàng không thấy
+	Attachments []SupportAttachment `json:"attachments,omitempty" gorm:"foreignKey:MessageID"` // File đính kèm
+}
+
+// SupportAttachment is a file attached to a message of a support ticket
+type SupportAttachment struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt   time.Time `json:"created_at"`
+	TicketID    uint      `json:"ticket_id" gorm:"not null;index"`
+	MessageID   uint      `json:"message_id" gorm:"not null;index"`
+	FileName    string    `json:"file_name" gorm:"not null"`    // Tên file khách tải lên
+	ContentType string    `json:"content_type" gorm:"not null"` // Loại file
+	Size        int64     `json:"size"`                         // Dung lượng (byte)
+	StorageKey  string    `json:"-" gorm:"not null"`            // Vị trí file trong kho lưu trữ
+}
+
+// BeforeCreate hook to generate the ticket code
+func (t *SupportTicket) BeforeCreate(tx *gorm.DB) error {
+	if t.Code == "" {
+		// Format: HT-YYYYMMDD-XXXXXX
+		t.Code = fmt.Sprintf("HT-%s-%s", time.Now().Format("20060102"), utils.GenerateRandomString(6))
+	}
+	return nil
+}
+
+// Validate support ticket data
+func (t *SupportTicket) Validate() error {
+	if !t.Category.IsValid() {
+		return errors.New("invalid support category")
+	}
+	if t.Subject == "" {
+		return errors.New("subject is required")
+	}
+	if !utils.ValidatePhone(t.ContactPhone) {
+		return errors.New("invalid contact phone number")
+	}
+	return nil
+}
+
+// IsActive reports whether staff still owe the customer a resolution
+func (t *SupportTicket) IsActive() bool {
+	return t.Status == SupportStatusOpen || t.Status == SupportStatusPending
+}
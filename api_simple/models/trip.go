@@ -9,6 +9,7 @@ import (
 
 type Trip struct {
 	gorm.Model
+	OperatorID    uint      `json:"operator_id" gorm:"index"` // Nhà xe khai thác chuyến
 	RouteID       uint      `json:"route_id"`
 	Route         *Route    `json:"route,omitempty"`
 	BusID         uint      `json:"bus_id"`
@@ -16,23 +17,33 @@ type Trip struct {
 	DriverID      uint      `json:"driver_id"`
 	Driver        *User     `json:"driver,omitempty"`
 	DepartureTime time.Time `json:"departure_time"`
-	Price         float64   `json:"price"`
+	Price         Money     `json:"price"`
 	IsActive      bool      `json:"is_active" gorm:"default:true"`     // Trạng thái hoạt động
 	IsCompleted   bool      `json:"is_completed" gorm:"default:false"` // Đã hoàn thành chuyến
 	TotalSeats    int       `json:"total_seats"`                       // Tổng số ghế
 	BookedSeats   int       `json:"booked_seats"`                      // Số ghế đã đặt
 	Note          string    `json:"note"`                              // Ghi chú
+
+	Currency Currency `json:"currency" gorm:"not null;default:'VND'"` // Đơn vị tiền tệ của giá vé, theo tuyến
 }
 
 // BeforeCreate hook to set default values
 func (t *Trip) BeforeCreate(tx *gorm.DB) error {
-	if t.Price == 0 {
-		// Get base price from route
+	if t.Price == 0 || t.Currency == "" {
+		// Get base price and currency from route
 		var route Route
 		if err := tx.First(&route, t.RouteID).Error; err != nil {
 			return err
 		}
-		t.Price = route.BasePrice
+		if t.Price == 0 {
+			t.Price = route.BasePrice
+		}
+		if t.Currency == "" {
+			t.Currency = route.Currency
+		}
+	}
+	if t.Currency == "" {
+		t.Currency = DefaultCurrency
 	}
 
 	// Get total seats from bus
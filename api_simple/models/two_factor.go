@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RequiresTwoFactor reports whether accounts with the role must use 2FA
+func RequiresTwoFactor(role Role) bool {
+	return role == RoleSuperAdmin || role == RoleAdmin || role == RoleStaff
+}
+
+// RecoveryCode is a single-use backup code for two-factor authentication
+type RecoveryCode struct {
+	gorm.Model
+	UserID   uint       `json:"user_id" gorm:"not null;index"` // Người dùng sở hữu mã
+	CodeHash string     `json:"-" gorm:"not null"`             // Mã khôi phục đã băm
+	UsedAt   *time.Time `json:"used_at,omitempty"`             // Thời điểm đã sử dụng
+}
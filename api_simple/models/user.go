@@ -8,10 +8,11 @@ import (
 type Role string
 
 const (
-	RoleAdmin    Role = "admin"
-	RoleStaff    Role = "staff"
-	RoleDriver   Role = "driver"
-	RoleCustomer Role = "customer"
+	RoleSuperAdmin Role = "super_admin" // Quản trị toàn hệ thống, không thuộc nhà xe nào
+	RoleAdmin      Role = "admin"       // Quản trị của một nhà xe
+	RoleStaff      Role = "staff"
+	RoleDriver     Role = "driver"
+	RoleCustomer   Role = "customer"
 )
 
 type UserStatus int
@@ -29,6 +30,17 @@ type User struct {
 	Role     Role   `json:"role" gorm:"default:'customer'"`
 	Status   UserStatus `json:"status" gorm:"default:2"` // 1 = created, 2 = verified
 
+	TwoFactorEnabled  bool   `json:"two_factor_enabled" gorm:"default:false"` // Đã bật xác thực 2 lớp
+	TwoFactorSecret   string `json:"-"`                                       // Khóa bí mật TOTP (base32)
+	TwoFactorLastStep int64  `json:"-"`                                       // Bước thời gian TOTP đã dùng gần nhất (chống dùng lại)
+
+	OperatorID *uint     `json:"operator_id,omitempty" gorm:"index"` // Nhà xe của nhân viên, tài xế, quản trị (khách hàng: trống)
+	Operator   *Operator `json:"operator,omitempty"`                 // Thông tin nhà xe
+}
+
+// IsOperatorAccount reports whether the account works for a single operator
+func (u *User) IsOperatorAccount() bool {
+	return u.Role == RoleAdmin || u.Role == RoleStaff || u.Role == RoleDriver
 }
 
 func (u *User) BeforeCreate(tx *gorm.DB) error {
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"ticket-management/api_simple/utils"
+)
+
+var ErrInvalidPosition = errors.New("invalid position")
+
+// VehiclePosition is a GPS point reported by the driver's device during a trip
+type VehiclePosition struct {
+	ID         uint      `json:"id" gorm:"primarykey"`
+	CreatedAt  time.Time `json:"created_at"`                                               // Thời điểm máy chủ nhận
+	TripID     uint      `json:"trip_id" gorm:"not null;index:idx_position_trip_time"`     // Chuyến đi
+	DriverID   uint      `json:"driver_id" gorm:"not null"`                                // Tài xế gửi vị trí
+	Latitude   float64   `json:"latitude" gorm:"not null"`                                 // Vĩ độ
+	Longitude  float64   `json:"longitude" gorm:"not null"`                                // Kinh độ
+	SpeedKmh   *float64  `json:"speed_kmh,omitempty"`                                      // Tốc độ do thiết bị báo (km/h)
+	Heading    *float64  `json:"heading,omitempty"`                                        // Hướng di chuyển (độ)
+	AccuracyM  *float64  `json:"accuracy_m,omitempty"`                                     // Sai số GPS (mét)
+	RecordedAt time.Time `json:"recorded_at" gorm:"not null;index:idx_position_trip_time"` // Thời điểm thiết bị ghi nhận
+}
+
+// Validate vehicle position data
+func (p *VehiclePosition) Validate() error {
+	if p.TripID == 0 {
+		return fmt.Errorf("%w: trip is required", ErrInvalidPosition)
+	}
+	if !utils.ValidCoordinates(p.Latitude, p.Longitude) {
+		return fmt.Errorf("%w: invalid coordinates", ErrInvalidPosition)
+	}
+	if p.RecordedAt.IsZero() {
+		return fmt.Errorf("%w: recorded time is required", ErrInvalidPosition)
+	}
+	if p.SpeedKmh != nil && *p.SpeedKmh < 0 {
+		return fmt.Errorf("%w: speed must not be negative", ErrInvalidPosition)
+	}
+	return nil
+}
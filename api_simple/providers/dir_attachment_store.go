@@ -0,0 +1,65 @@
+package providers
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrInvalidAttachmentKey = errors.New("invalid attachment key")
+
+// DirAttachmentStore keeps uploaded files under a directory, each at the slash-separated
+// key it was saved with
+type DirAttachmentStore struct {
+	dir string
+}
+
+func NewDirAttachmentStore(dir string) *DirAttachmentStore {
+	return &DirAttachmentStore{dir: dir}
+}
+
+// Save writes the content of r to key. The file only appears once it is fully written.
+func (s *DirAttachmentStore) Save(key string, r io.Reader) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+// Open opens the file saved at key
+func (s *DirAttachmentStore) Open(key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// path resolves key inside the store, refusing keys that would leave it
+func (s *DirAttachmentStore) path(key string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(key))
+	if key == "" || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", ErrInvalidAttachmentKey
+	}
+	return filepath.Join(s.dir, clean), nil
+}
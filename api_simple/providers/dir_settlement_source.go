@@ -0,0 +1,70 @@
+package providers
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SettlementFile is a settlement report a payment provider delivered for an operator
+type SettlementFile struct {
+	OperatorCode string // Mã nhà xe
+	Provider     string // Cổng thanh toán (vnpay, momo...)
+	Name         string // Tên file
+	Path         string
+	Data         []byte
+}
+
+// DirSettlementSource reads settlement files the payment providers deliver (by SFTP
+// or e-mail rules) into an inbox laid out as <inbox>/<operator code>/<provider>/*.csv.
+// Imported files are moved to a processed/ folder next to them, rejected ones to failed/.
+type DirSettlementSource struct {
+	inbox string
+}
+
+func NewDirSettlementSource(inbox string) *DirSettlementSource {
+	return &DirSettlementSource{inbox: inbox}
+}
+
+// Fetch reads the files waiting in the inbox, oldest name first
+func (s *DirSettlementSource) Fetch() ([]SettlementFile, error) {
+	paths, err := filepath.Glob(filepath.Join(s.inbox, "*", "*", "*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	var files []SettlementFile
+	for _, path := range paths {
+		if !strings.EqualFold(filepath.Ext(path), ".csv") {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		providerDir := filepath.Dir(path)
+		files = append(files, SettlementFile{
+			OperatorCode: filepath.Base(filepath.Dir(providerDir)),
+			Provider:     filepath.Base(providerDir),
+			Name:         filepath.Base(path),
+			Path:         path,
+			Data:         data,
+		})
+	}
+	return files, nil
+}
+
+// Done moves a file out of the inbox once it has been imported or rejected
+func (s *DirSettlementSource) Done(file SettlementFile, failed bool) error {
+	folder := "processed"
+	if failed {
+		folder = "failed"
+	}
+	dir := filepath.Join(filepath.Dir(file.Path), folder)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	return os.Rename(file.Path, filepath.Join(dir, file.Name))
+}
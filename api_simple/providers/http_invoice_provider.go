@@ -0,0 +1,63 @@
+package providers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+// HTTPInvoiceProvider submits invoices to an e-invoice service that accepts the
+// invoice XML as the request body and answers with an InvoiceReceipt in JSON
+type HTTPInvoiceProvider struct {
+	client *http.Client
+	url    string
+	token  string
+}
+
+func NewHTTPInvoiceProvider() *HTTPInvoiceProvider {
+	return &HTTPInvoiceProvider{
+		client: &http.Client{Timeout: 30 * time.Second},
+		url:    os.Getenv("INVOICE_GATEWAY_URL"),
+		token:  os.Getenv("INVOICE_GATEWAY_TOKEN"),
+	}
+}
+
+// Submit posts the invoice XML to the configured service
+func (p *HTTPInvoiceProvider) Submit(reference string, document []byte) (*InvoiceReceipt, error) {
+	req, err := http.NewRequest(http.MethodPost, p.url, bytes.NewReader(document))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/xml")
+	req.Header.Set("X-Invoice-Reference", reference)
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		log.Printf("[EINVOICE] Failed to submit invoice %s: %v", reference, err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		log.Printf("[EINVOICE] Service rejected invoice %s: status=%d", reference, resp.StatusCode)
+		return nil, fmt.Errorf("e-invoice service returned status %d", resp.StatusCode)
+	}
+
+	var receipt InvoiceReceipt
+	if err := json.NewDecoder(resp.Body).Decode(&receipt); err != nil {
+		return nil, err
+	}
+	if receipt.LookupCode == "" {
+		return nil, fmt.Errorf("e-invoice service returned no lookup code")
+	}
+
+	log.Printf("[EINVOICE] Invoice %s accepted, lookup=%s", reference, receipt.LookupCode)
+	return &receipt, nil
+}
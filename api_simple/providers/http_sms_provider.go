@@ -0,0 +1,64 @@
+package providers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+// HTTPSMSProvider sends SMS through a generic HTTP gateway that accepts
+// a JSON body of the form {"to": "...", "message": "..."}
+type HTTPSMSProvider struct {
+	client *http.Client
+	url    string
+	token  string
+}
+
+func NewHTTPSMSProvider() *HTTPSMSProvider {
+	return &HTTPSMSProvider{
+		client: &http.Client{Timeout: 10 * time.Second},
+		url:    os.Getenv("SMS_GATEWAY_URL"),
+		token:  os.Getenv("SMS_GATEWAY_TOKEN"),
+	}
+}
+
+// Send posts the message to the configured gateway
+func (p *HTTPSMSProvider) Send(to string, body string) error {
+	to = normalizePhone(to)
+
+	payload, err := json.Marshal(map[string]string{
+		"to":      to,
+		"message": body,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, p.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		log.Printf("[HTTPSMS] Failed to send SMS to %s, body=%q: %v", to, maskCodes(body), err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		log.Printf("[HTTPSMS] Gateway rejected SMS to %s, body=%q: status=%d", to, maskCodes(body), resp.StatusCode)
+		return fmt.Errorf("sms gateway returned status %d", resp.StatusCode)
+	}
+
+	log.Printf("[HTTPSMS] SMS sent to phone=%s", to)
+	return nil
+}
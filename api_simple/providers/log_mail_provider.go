@@ -0,0 +1,43 @@
+package providers
+
+import (
+	"io"
+	"log"
+	"strings"
+)
+
+// Email is a message with optional file attachments
+type Email struct {
+	To          []string
+	Subject     string
+	Body        string // Nội dung dạng văn bản thuần
+	Attachments []Attachment
+}
+
+// Attachment is a file sent with an email. Content is read once while the email is sent.
+type Attachment struct {
+	Name        string
+	ContentType string
+	Content     io.Reader
+}
+
+// LogMailProvider writes emails to the application log instead of sending them.
+// It is intended for local development only.
+type LogMailProvider struct{}
+
+func NewLogMailProvider() *LogMailProvider {
+	return &LogMailProvider{}
+}
+
+// Send logs the recipients, subject and the size of each attachment
+func (p *LogMailProvider) Send(email *Email) error {
+	for _, attachment := range email.Attachments {
+		size, err := io.Copy(io.Discard, attachment.Content)
+		if err != nil {
+			return err
+		}
+		log.Printf("[MAIL] attachment=%s size=%d", attachment.Name, size)
+	}
+	log.Printf("[MAIL] to=%s subject=%q", strings.Join(email.To, ","), email.Subject)
+	return nil
+}
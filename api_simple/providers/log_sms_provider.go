@@ -0,0 +1,17 @@
+package providers
+
+import "log"
+
+// LogSMSProvider writes messages to the application log instead of sending them.
+// It is intended for local development only.
+type LogSMSProvider struct{}
+
+func NewLogSMSProvider() *LogSMSProvider {
+	return &LogSMSProvider{}
+}
+
+// Send logs the message body in full so codes can be read during development
+func (p *LogSMSProvider) Send(to string, body string) error {
+	log.Printf("[SMS-DEBUG] to=%s body=%q", normalizePhone(to), body)
+	return nil
+}
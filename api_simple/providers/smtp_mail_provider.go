@@ -0,0 +1,165 @@
+package providers
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strings"
+)
+
+// SMTPMailProvider sends email through an SMTP server, upgrading to TLS when the
+// server supports STARTTLS
+type SMTPMailProvider struct {
+	host     string
+	port     string
+	username string
+	password string
+	from     string
+}
+
+func NewSMTPMailProvider() *SMTPMailProvider {
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+	return &SMTPMailProvider{
+		host:     os.Getenv("SMTP_HOST"),
+		port:     port,
+		username: os.Getenv("SMTP_USERNAME"),
+		password: os.Getenv("SMTP_PASSWORD"),
+		from:     os.Getenv("MAIL_FROM"),
+	}
+}
+
+// Send delivers the email. Attachments are encoded while they are read, so large files
+// are never held in memory.
+func (p *SMTPMailProvider) Send(email *Email) error {
+	client, err := smtp.Dial(net.JoinHostPort(p.host, p.port))
+	if err != nil {
+		log.Printf("[SMTP] Failed to connect to %s: %v", p.host, err)
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: p.host}); err != nil {
+			return err
+		}
+	}
+	if p.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", p.username, p.password, p.host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(p.from); err != nil {
+		return err
+	}
+	for _, to := range email.To {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if err := writeEmail(w, p.from, email); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		log.Printf("[SMTP] Server rejected email %q: %v", email.Subject, err)
+		return err
+	}
+
+	log.Printf("[SMTP] Email %q sent to %s", email.Subject, strings.Join(email.To, ","))
+	return client.Quit()
+}
+
+// writeEmail writes the email as a MIME multipart message
+func writeEmail(w io.Writer, from string, email *Email) error {
+	body := multipart.NewWriter(w)
+	headers := []string{
+		"From: " + from,
+		"To: " + strings.Join(email.To, ", "),
+		"Subject: " + mime.QEncoding.Encode("utf-8", email.Subject),
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/mixed; boundary=" + body.Boundary(),
+	}
+	if _, err := fmt.Fprintf(w, "%s\r\n\r\n", strings.Join(headers, "\r\n")); err != nil {
+		return err
+	}
+
+	part, err := body.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+	text := quotedprintable.NewWriter(part)
+	if _, err := io.WriteString(text, email.Body); err != nil {
+		return err
+	}
+	if err := text.Close(); err != nil {
+		return err
+	}
+
+	for _, attachment := range email.Attachments {
+		part, err := body.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {attachment.ContentType},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Name})},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return err
+		}
+		encoder := base64.NewEncoder(base64.StdEncoding, &lineWriter{w: part})
+		if _, err := io.Copy(encoder, attachment.Content); err != nil {
+			return err
+		}
+		if err := encoder.Close(); err != nil {
+			return err
+		}
+	}
+	return body.Close()
+}
+
+// lineWriter breaks base64 output into the 76 character lines email requires
+type lineWriter struct {
+	w      io.Writer
+	column int
+}
+
+func (l *lineWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := 76 - l.column
+		if n > len(p) {
+			n = len(p)
+		}
+		if _, err := l.w.Write(p[:n]); err != nil {
+			return written, err
+		}
+		written += n
+		l.column += n
+		p = p[n:]
+		if l.column == 76 {
+			if _, err := io.WriteString(l.w, "\r\n"); err != nil {
+				return written, err
+			}
+			l.column = 0
+		}
+	}
+	return written, nil
+}
@@ -0,0 +1,34 @@
+package providers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"strings"
+)
+
+// InvoiceReceipt is what an e-invoice service returns for an accepted invoice
+type InvoiceReceipt struct {
+	LookupCode       string `json:"lookup_code"`        // Mã tra cứu cho người mua
+	TaxAuthorityCode string `json:"tax_authority_code"` // Mã của cơ quan thuế
+}
+
+// StubInvoiceProvider accepts every invoice and derives its codes from the content
+// instead of calling an e-invoice service. It is intended for local development only.
+type StubInvoiceProvider struct{}
+
+func NewStubInvoiceProvider() *StubInvoiceProvider {
+	return &StubInvoiceProvider{}
+}
+
+// Submit logs the invoice and returns codes that are stable for the same reference
+func (p *StubInvoiceProvider) Submit(reference string, document []byte) (*InvoiceReceipt, error) {
+	sum := sha256.Sum256([]byte(reference))
+	code := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	log.Printf("[EINVOICE-STUB] reference=%s size=%d", reference, len(document))
+	return &InvoiceReceipt{
+		LookupCode:       code[:10],
+		TaxAuthorityCode: "M1-" + code[10:30],
+	}, nil
+}
@@ -3,15 +3,19 @@ package providers
 import (
 	"log"
 	"os"
+	"regexp"
 	"strings"
 
 	"github.com/twilio/twilio-go"
-	verify "github.com/twilio/twilio-go/rest/verify/v2"
+	api "github.com/twilio/twilio-go/rest/api/v2010"
 )
 
+// codePattern matches the numeric codes (OTPs, PINs) that must not reach the log
+var codePattern = regexp.MustCompile(`\d{4,}`)
+
 type TwilioProvider struct {
-	client    *twilio.RestClient
-	verifySid string
+	client     *twilio.RestClient
+	fromNumber string
 }
 
 func NewTwilioProvider() *TwilioProvider {
@@ -21,8 +25,8 @@ func NewTwilioProvider() *TwilioProvider {
 	})
 
 	return &TwilioProvider{
-		client:    client,
-		verifySid: os.Getenv("TWILIO_VERIFY_SID"),
+		client:     client,
+		fromNumber: os.Getenv("TWILIO_FROM_NUMBER"),
 	}
 }
 
@@ -40,61 +44,33 @@ func normalizePhone(phone string) string {
 	return "+84" + phone
 }
 
-// SendOTP sends a verification code to the user via SMS
-func (t *TwilioProvider) SendOTP(to string) error {
-	to = normalizePhone(to) // normalize before sending
-
-    if os.Getenv("APP_ENV") == "local" {
-        log.Printf("[SendOTP-DEBUG] Sending fake OTP=123456 to phone=%s", to)
-        return nil
-    }
-
-	params := &verify.CreateVerificationParams{}
-	params.SetTo(to)
-	params.SetChannel("sms")
-
-	resp, err := t.client.VerifyV2.CreateVerification(t.verifySid, params)
-	if err != nil {
-		log.Printf("[SendOTP] Failed to send OTP to %s: %v", to, err)
-	} else {
-		log.Printf("[SendOTP] OTP request sent to phone=%s, sid=%s, status=%s",
-			to,
-			*resp.Sid,
-			*resp.Status,
-		)
-	}
-	return err
+// maskCodes replaces every run of four or more digits with asterisks so a
+// message that failed to send can be logged without leaking its code
+func maskCodes(body string) string {
+	return codePattern.ReplaceAllStringFunc(body, func(code string) string {
+		return strings.Repeat("*", len(code))
+	})
 }
 
-// VerifyOTP checks if the user-provided code is correct
-func (t *TwilioProvider) VerifyOTP(to string, code string) (bool, error) {
-	to = normalizePhone(to) // normalize before checking
-	
-	// Debug mode: accept "123456" without Twilio call
-	if os.Getenv("APP_ENV") == "local" {
-		if code == "123456" {
-			log.Printf("[VerifyOTP-DEBUG] Accepted fake OTP for phone=%s, code=%s", to, code)
-			return true, nil
-		}
-		log.Printf("[VerifyOTP-DEBUG] Rejected invalid fake OTP for phone=%s, code=%s", to, code)
-		return false, nil
-	}
+// Send sends an SMS message through the Twilio Messaging API
+func (t *TwilioProvider) Send(to string, body string) error {
+	to = normalizePhone(to) // normalize before sending
 
-	params := &verify.CreateVerificationCheckParams{}
+	params := &api.CreateMessageParams{}
 	params.SetTo(to)
-	params.SetCode(code)
+	params.SetFrom(t.fromNumber)
+	params.SetBody(body)
 
-	resp, err := t.client.VerifyV2.CreateVerificationCheck(t.verifySid, params)
+	resp, err := t.client.Api.CreateMessage(params)
 	if err != nil {
-		log.Printf("[VerifyOTP] Error verifying code for phone=%s, code=%s: %v", to, code, err)
-		return false, err
+		log.Printf("[Twilio] Failed to send SMS to %s, body=%q: %v", to, maskCodes(body), err)
+		return err
 	}
 
-	status := "nil"
-	if resp.Status != nil {
-		status = *resp.Status
+	sid := "nil"
+	if resp.Sid != nil {
+		sid = *resp.Sid
 	}
-
-	log.Printf("[VerifyOTP] Phone=%s, code=%s, status=%s", to, code, status)
-	return resp.Status != nil && *resp.Status == "approved", nil
+	log.Printf("[Twilio] SMS sent to phone=%s, sid=%s", to, sid)
+	return nil
 }
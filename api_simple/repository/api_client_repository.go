@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"ticket-management/api_simple/models"
+
+	"gorm.io/gorm"
+)
+
+type APIClientRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIClientRepository(db *gorm.DB) *APIClientRepository {
+	return &APIClientRepository{db: db}
+}
+
+// Create creates a new API client
+func (r *APIClientRepository) Create(client *models.APIClient) error {
+	return r.db.Create(client).Error
+}
+
+// FindByID finds an API client by ID
+func (r *APIClientRepository) FindByID(id uint) (*models.APIClient, error) {
+	var client models.APIClient
+	err := r.db.First(&client, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
+
+// FindByKeyHash finds an API client by the hash of its key
+func (r *APIClientRepository) FindByKeyHash(hash string) (*models.APIClient, error) {
+	var client models.APIClient
+	err := r.db.Where("key_hash = ?", hash).First(&client).Error
+	if err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
+
+// FindAll finds all API clients
+func (r *APIClientRepository) FindAll() ([]models.APIClient, error) {
+	var clients []models.APIClient
+	err := r.db.Order("created_at DESC").Find(&clients).Error
+	return clients, err
+}
+
+// Update updates an API client
+func (r *APIClientRepository) Update(client *models.APIClient) error {
+	return r.db.Save(client).Error
+}
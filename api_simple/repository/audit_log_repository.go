@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"time"
+
+	"ticket-management/api_simple/models"
+
+	"gorm.io/gorm"
+)
+
+// AuditLogRepository only appends and reads; audit entries are never changed
+type AuditLogRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditLogRepository(db *gorm.DB) *AuditLogRepository {
+	return &AuditLogRepository{db: db}
+}
+
+// Create appends an audit entry
+func (r *AuditLogRepository) Create(entry *models.AuditLog) error {
+	return r.db.Create(entry).Error
+}
+
+// FindAll finds audit entries matching filters, newest first
+func (r *AuditLogRepository) FindAll(filters map[string]interface{}, from, to *time.Time, page, limit int) ([]models.AuditLog, int64, error) {
+	var entries []models.AuditLog
+	var total int64
+
+	query := r.filter(filters, from, to)
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("created_at DESC, id DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&entries).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return entries, total, nil
+}
+
+// FindInBatches walks all audit entries matching filters in ID order
+func (r *AuditLogRepository) FindInBatches(filters map[string]interface{}, from, to *time.Time, batchSize int, fn func([]models.AuditLog) error) error {
+	var entries []models.AuditLog
+	return r.filter(filters, from, to).
+		FindInBatches(&entries, batchSize, func(tx *gorm.DB, batch int) error {
+			return fn(entries)
+		}).Error
+}
+
+func (r *AuditLogRepository) filter(filters map[string]interface{}, from, to *time.Time) *gorm.DB {
+	query := r.db.Model(&models.AuditLog{})
+	if len(filters) > 0 {
+		query = query.Where(filters)
+	}
+	if from != nil {
+		query = query.Where("created_at >= ?", *from)
+	}
+	if to != nil {
+		query = query.Where("created_at <= ?", *to)
+	}
+	return query
+}
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"ticket-management/api_simple/models"
+
+	"gorm.io/gorm"
+)
+
+// MigrateBookingChannels adds the channel column to an existing bookings table and fills
+// it the way older versions derived the channel: bookings of an agency came through the
+// partner API, bookings with an account were made online and the rest at the counter.
+// It must run before AutoMigrate, which would otherwise mark every old booking online.
+// Nothing is done once the column exists.
+func MigrateBookingChannels(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(&models.Booking{}) || migrator.HasColumn(&models.Booking{}, "Channel") {
+		return nil
+	}
+
+	if err := migrator.AddColumn(&models.Booking{}, "Channel"); err != nil {
+		return err
+	}
+	return db.Exec("UPDATE bookings SET channel = CASE WHEN api_client_id IS NOT NULL THEN ? WHEN user_id IS NOT NULL THEN ? ELSE ? END",
+		models.BookingChannelPartner, models.BookingChannelOnline, models.BookingChannelCounter).Error
+}
@@ -3,11 +3,18 @@ package repository
 import (
 	"fmt"
 	"ticket-management/api_simple/models"
+	"time"
 
 	"github.com/lib/pq"
 	"gorm.io/gorm"
 )
 
+// withDeleted lets a preload include soft-deleted rows, such as a boarding point
+// removed after bookings chose it
+func withDeleted(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
+}
+
 type BookingRepository struct {
 	db *gorm.DB
 }
@@ -24,7 +31,7 @@ func (r *BookingRepository) Create(booking *models.Booking) error {
 // FindByID finds a booking by ID
 func (r *BookingRepository) FindByID(id uint) (*models.Booking, error) {
 	var booking models.Booking
-	err := r.db.Preload("User").Preload("Trip.Route").Preload("Trip.Bus").Preload("Seats").First(&booking, id).Error
+	err := r.db.Preload("User").Preload("Trip.Route").Preload("Trip.Bus").Preload("PickupPoint", withDeleted).Preload("DropoffPoint", withDeleted).Preload("Seats").First(&booking, id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -34,7 +41,7 @@ func (r *BookingRepository) FindByID(id uint) (*models.Booking, error) {
 // FindByCode finds a booking by booking code
 func (r *BookingRepository) FindByCode(code string) (*models.Booking, error) {
 	var booking models.Booking
-	err := r.db.Preload("User").Preload("Trip.Route").Preload("Trip.Bus").Preload("Seats").Where("booking_code = ?", code).First(&booking).Error
+	err := r.db.Preload("User").Preload("Trip.Route").Preload("Trip.Bus").Preload("PickupPoint", withDeleted).Preload("DropoffPoint", withDeleted).Preload("Seats").Preload("Operator.PaymentAccounts", "is_active = ?", true).Where("booking_code = ?", code).First(&booking).Error
 	if err != nil {
 		return nil, err
 	}
@@ -43,15 +50,58 @@ func (r *BookingRepository) FindByCode(code string) (*models.Booking, error) {
 
 // FindAll finds all bookings with optional filters
 func (r *BookingRepository) FindAll(filters map[string]interface{}, page, limit int) ([]models.Booking, int64, error) {
-	var bookings []models.Booking
-	var total int64
-
 	query := r.db.Model(&models.Booking{})
 
 	// Apply filters
 	if filters != nil {
 		query = query.Where(filters)
 	}
+	return r.findPage(query, page, limit)
+}
+
+// BookingFilter selects the bookings of the admin list and exports
+type BookingFilter struct {
+	Status models.BookingStatus
+	From   *time.Time // Đặt từ thời điểm này
+	To     *time.Time // Đặt đến thời điểm này
+}
+
+func (r *BookingRepository) filter(filter BookingFilter) *gorm.DB {
+	query := r.db.Model(&models.Booking{})
+	if filter.Status != "" {
+		query = query.Where("bookings.status = ?", filter.Status)
+	}
+	if filter.From != nil {
+		query = query.Where("bookings.created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("bookings.created_at <= ?", *filter.To)
+	}
+	return query
+}
+
+// List finds a page of the bookings matching filter, newest first
+func (r *BookingRepository) List(filter BookingFilter, page, limit int) ([]models.Booking, int64, error) {
+	return r.findPage(r.filter(filter).Order("bookings.created_at DESC"), page, limit)
+}
+
+// FindInBatches calls fn with the bookings matching filter, oldest first, batchSize at a
+// time with their customer, trip and seats loaded
+func (r *BookingRepository) FindInBatches(filter BookingFilter, batchSize int, fn func([]models.Booking) error) error {
+	var bookings []models.Booking
+	return r.filter(filter).Preload("User").Preload("Trip.Route").Preload("Trip.Bus").
+		FindInBatches(&bookings, batchSize, func(tx *gorm.DB, batch int) error {
+			if err := r.loadSeats(bookings); err != nil {
+				return err
+			}
+			return fn(bookings)
+		}).Error
+}
+
+// findPage loads a page of the bookings of query with their relations
+func (r *BookingRepository) findPage(query *gorm.DB, page, limit int) ([]models.Booking, int64, error) {
+	var bookings []models.Booking
+	var total int64
 
 	// Get total count
 	err := query.Count(&total).Error
@@ -60,7 +110,7 @@ func (r *BookingRepository) FindAll(filters map[string]interface{}, page, limit
 	}
 
 	// Get paginated results
-	err = query.Preload("User").Preload("Trip.Route").Preload("Trip.Bus").
+	err = query.Preload("User").Preload("Trip.Route").Preload("Trip.Bus").Preload("PickupPoint", withDeleted).Preload("DropoffPoint", withDeleted).
 		Offset((page - 1) * limit).
 		Limit(limit).
 		Find(&bookings).Error
@@ -84,6 +134,35 @@ func (r *BookingRepository) FindAll(filters map[string]interface{}, page, limit
 	return bookings, total, nil
 }
 
+// loadSeats loads the seats of bookings in one query
+func (r *BookingRepository) loadSeats(bookings []models.Booking) error {
+	var seatIDs []int64
+	for _, booking := range bookings {
+		seatIDs = append(seatIDs, booking.SeatIDs...)
+	}
+	if len(seatIDs) == 0 {
+		return nil
+	}
+
+	var seats []models.Seat
+	if err := r.db.Where("id IN ?", seatIDs).Find(&seats).Error; err != nil {
+		return err
+	}
+	byID := make(map[int64]models.Seat, len(seats))
+	for _, seat := range seats {
+		byID[int64(seat.ID)] = seat
+	}
+	for i := range bookings {
+		bookings[i].Seats = nil
+		for _, id := range bookings[i].SeatIDs {
+			if seat, ok := byID[id]; ok {
+				bookings[i].Seats = append(bookings[i].Seats, seat)
+			}
+		}
+	}
+	return nil
+}
+
 // FindByUserID finds all bookings for a user
 func (r *BookingRepository) FindByUserID(userID uint, page, limit int) ([]models.Booking, int64, error) {
 	return r.FindAll(map[string]interface{}{"user_id": userID}, page, limit)
@@ -94,6 +173,21 @@ func (r *BookingRepository) FindByGuestPhone(phone string, page, limit int) ([]m
 	return r.FindAll(map[string]interface{}{"guest_info_phone": phone}, page, limit)
 }
 
+// FindByAPIClientID finds all bookings made by a partner agency
+func (r *BookingRepository) FindByAPIClientID(clientID uint, page, limit int) ([]models.Booking, int64, error) {
+	return r.FindAll(map[string]interface{}{"api_client_id": clientID}, page, limit)
+}
+
+// SumCommissionByAPIClient sums the commission of a partner's non-cancelled bookings
+func (r *BookingRepository) SumCommissionByAPIClient(clientID uint) (models.Money, error) {
+	var total models.Money
+	err := r.db.Model(&models.Booking{}).
+		Where("api_client_id = ? AND status <> ?", clientID, models.BookingStatusCancelled).
+		Select("COALESCE(SUM(commission_amount), 0)").
+		Scan(&total).Error
+	return total, err
+}
+
 // Update updates a booking
 func (r *BookingRepository) Update(booking *models.Booking) error {
 	return r.db.Save(booking).Error
@@ -104,9 +198,49 @@ func (r *BookingRepository) UpdateStatus(id uint, status models.BookingStatus) e
 	return r.db.Model(&models.Booking{}).Where("id = ?", id).Update("status", status).Error
 }
 
-// UpdatePaymentStatus updates payment status
+// UpdatePaymentStatus updates payment status, stamping the payment time when it becomes paid
 func (r *BookingRepository) UpdatePaymentStatus(id uint, status models.PaymentStatus) error {
-	return r.db.Model(&models.Booking{}).Where("id = ?", id).Update("payment_status", status).Error
+	updates := map[string]interface{}{"payment_status": status}
+	if status == models.PaymentStatusPaid {
+		updates["paid_at"] = time.Now()
+	}
+	return r.db.Model(&models.Booking{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// SetPaymentReference records the gateway that took a booking's payment and the
+// transaction reference it gave the payment
+func (r *BookingRepository) SetPaymentReference(id uint, provider models.PaymentAccountMethod, reference string) error {
+	return r.db.Model(&models.Booking{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"payment_provider":  provider,
+		"payment_reference": reference,
+	}).Error
+}
+
+// FindByPaymentReference finds a booking by the transaction reference of its payment
+func (r *BookingRepository) FindByPaymentReference(reference string) (*models.Booking, error) {
+	var booking models.Booking
+	if err := r.db.Where("payment_reference = ?", reference).First(&booking).Error; err != nil {
+		return nil, err
+	}
+	return &booking, nil
+}
+
+// FindPaidWithReference finds bookings paid through the provider between from and to,
+// i.e. those with a payment reference from it, that are not refunded
+func (r *BookingRepository) FindPaidWithReference(provider models.PaymentAccountMethod, from, to time.Time) ([]models.Booking, error) {
+	var bookings []models.Booking
+	err := r.db.Where("payment_reference IS NOT NULL AND payment_provider = ? AND payment_status = ? AND paid_at BETWEEN ? AND ?", provider, models.PaymentStatusPaid, from, to).
+		Order("paid_at ASC, id ASC").
+		Find(&bookings).Error
+	return bookings, err
+}
+
+// MarkRefunded records the amount refunded for a cancelled booking
+func (r *BookingRepository) MarkRefunded(id uint, amount models.Money) error {
+	return r.db.Model(&models.Booking{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"payment_status": models.PaymentStatusRefunded,
+		"refund_amount":  amount,
+	}).Error
 }
 
 // Delete soft deletes a booking
@@ -114,10 +248,11 @@ func (r *BookingRepository) Delete(id uint) error {
 	return r.db.Delete(&models.Booking{}, id).Error
 }
 
-// FindPendingBookings finds all pending bookings that have exceeded the timeout
+// FindPendingBookings finds all pending bookings that have exceeded the timeout. Agency
+// bookings are sold on account and wait for the operator instead, so they never time out.
 func (r *BookingRepository) FindPendingBookings(timeout int) ([]models.Booking, error) {
 	var bookings []models.Booking
-	err := r.db.Where("status = ? AND payment_status = ? AND created_at <= NOW() - INTERVAL ?",
+	err := r.db.Where("status = ? AND payment_status = ? AND api_client_id IS NULL AND created_at <= NOW() - INTERVAL ?",
 		models.BookingStatusPending,
 		models.PaymentStatusUnpaid,
 		fmt.Sprintf("%d minutes", timeout)).
@@ -153,3 +288,26 @@ func (r *BookingRepository) CountBookingsByUser(userID uint) (int64, error) {
 		Count(&count).Error
 	return count, err
 }
+
+// FindActiveByTrip finds the non-cancelled bookings of a trip with their seats and boarding points
+func (r *BookingRepository) FindActiveByTrip(tripID uint) ([]models.Booking, error) {
+	var bookings []models.Booking
+	err := r.db.Preload("User").Preload("PickupPoint", withDeleted).Preload("DropoffPoint", withDeleted).
+		Where("trip_id = ? AND status != ?", tripID, models.BookingStatusCancelled).
+		Order("id ASC").
+		Find(&bookings).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range bookings {
+		if len(bookings[i].SeatIDs) > 0 {
+			var seats []models.Seat
+			if err := r.db.Where("id IN ?", []int64(bookings[i].SeatIDs)).Order("number ASC").Find(&seats).Error; err != nil {
+				return nil, err
+			}
+			bookings[i].Seats = seats
+		}
+	}
+	return bookings, nil
+}
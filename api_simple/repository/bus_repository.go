@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"ticket-management/api_simple/models"
 
 	"gorm.io/gorm"
@@ -81,17 +83,9 @@ func (r *BusRepository) GetBusStatistics(busID uint) (map[string]interface{}, er
 	}
 	stats["upcoming_trips"] = upcomingTrips
 
-	// Get total revenue
-	var totalRevenue float64
-	if err := r.db.Model(&models.Booking{}).
-		Joins("JOIN trips ON trips.id = bookings.trip_id").
-		Where("trips.bus_id = ? AND bookings.status = ? AND bookings.payment_status = ?",
-			busID,
-			models.BookingStatusConfirmed,
-			models.PaymentStatusPaid).
-		Select("COALESCE(SUM(bookings.total_amount), 0)").
-		Row().
-		Scan(&totalRevenue); err != nil {
+	// Get total revenue from the ledger
+	totalRevenue, err := NewLedgerRepository(r.db).Revenue(LedgerFilter{BusID: busID})
+	if err != nil {
 		return nil, err
 	}
 	stats["total_revenue"] = totalRevenue
@@ -99,24 +93,46 @@ func (r *BusRepository) GetBusStatistics(busID uint) (map[string]interface{}, er
 	return stats, nil
 }
 
-// GetAvailableBuses gets buses that are available for a trip
-func (r *BusRepository) GetAvailableBuses(departureTime string) ([]models.Bus, error) {
+// GetPopularBuses gets most used buses
+func (r *BusRepository) GetPopularBuses(limit int) ([]models.Bus, error) {
 	var buses []models.Bus
 	err := r.db.
-		Where("is_active = ? AND id NOT IN (SELECT bus_id FROM trips WHERE departure_time = ? AND is_completed = ?)",
-			true, departureTime, false).
+		Order("total_trips DESC, total_bookings DESC").
+		Limit(limit).
 		Find(&buses).Error
 	return buses, err
 }
 
-// GetPopularBuses gets most used buses
-func (r *BusRepository) GetPopularBuses(limit int) ([]models.Bus, error) {
+// FindIDs returns the IDs of all buses
+func (r *BusRepository) FindIDs() ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&models.Bus{}).Pluck("id", &ids).Error
+	return ids, err
+}
+
+// UpdateStats recomputes the trip, booking and occupancy statistics of a bus
+func (r *BusRepository) UpdateStats(busID uint, now time.Time) error {
+	stats, err := tripStats(r.db, "bus_id", busID, now)
+	if err != nil {
+		return err
+	}
+
+	return r.db.Model(&models.Bus{}).
+		Where("id = ?", busID).
+		UpdateColumns(map[string]interface{}{
+			"total_trips":      stats.TotalTrips,
+			"upcoming_trips":   stats.UpcomingTrips,
+			"total_bookings":   stats.TotalBookings,
+			"load_factor":      stats.LoadFactor(),
+			"stats_updated_at": now,
+		}).Error
+}
+
+// FindDocumentsExpiringBefore finds active buses whose inspection or registration expires before t
+func (r *BusRepository) FindDocumentsExpiringBefore(t time.Time) ([]models.Bus, error) {
 	var buses []models.Bus
-	err := r.db.
-		Joins("LEFT JOIN trips ON trips.bus_id = buses.id").
-		Group("buses.id").
-		Order("COUNT(trips.id) DESC").
-		Limit(limit).
+	err := r.db.Where("is_active = ?", true).
+		Where("inspection_expires_at < ? OR registration_expires_at < ?", t, t).
 		Find(&buses).Error
 	return buses, err
 }
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"ticket-management/api_simple/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CashShiftRepository struct {
+	db *gorm.DB
+}
+
+func NewCashShiftRepository(db *gorm.DB) *CashShiftRepository {
+	return &CashShiftRepository{db: db}
+}
+
+// Create creates a shift
+func (r *CashShiftRepository) Create(shift *models.CashShift) error {
+	return r.db.Create(shift).Error
+}
+
+// Update saves a shift without touching its staff member or transactions
+func (r *CashShiftRepository) Update(shift *models.CashShift) error {
+	return r.db.Omit(clause.Associations).Save(shift).Error
+}
+
+// FindByID finds a shift by ID with its staff member
+func (r *CashShiftRepository) FindByID(id uint) (*models.CashShift, error) {
+	var shift models.CashShift
+	if err := r.db.Preload("Staff").First(&shift, id).Error; err != nil {
+		return nil, err
+	}
+	return &shift, nil
+}
+
+// FindOpenByStaff finds the shift a staff member currently has open
+func (r *CashShiftRepository) FindOpenByStaff(staffID uint) (*models.CashShift, error) {
+	var shift models.CashShift
+	err := r.db.Where("staff_id = ? AND status = ?", staffID, models.CashShiftStatusOpen).First(&shift).Error
+	if err != nil {
+		return nil, err
+	}
+	return &shift, nil
+}
+
+// FindAll finds shifts with optional filters, newest first
+func (r *CashShiftRepository) FindAll(filters map[string]interface{}, page, limit int) ([]models.CashShift, int64, error) {
+	var shifts []models.CashShift
+	var total int64
+
+	query := r.db.Model(&models.CashShift{})
+	if len(filters) > 0 {
+		query = query.Where(filters)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Preload("Staff").
+		Order("opened_at DESC, id DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&shifts).Error
+	return shifts, total, err
+}
+
+// CreateTransaction records cash taken into or paid out of a drawer
+func (r *CashShiftRepository) CreateTransaction(transaction *models.CashTransaction) error {
+	return r.db.Create(transaction).Error
+}
+
+// FindTransactions finds the cash movements of a shift in order
+func (r *CashShiftRepository) FindTransactions(shiftID uint) ([]models.CashTransaction, error) {
+	var transactions []models.CashTransaction
+	err := r.db.Where("shift_id = ?", shiftID).Order("created_at ASC, id ASC").Find(&transactions).Error
+	return transactions, err
+}
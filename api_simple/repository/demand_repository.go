@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"sort"
+	"time"
+
+	"ticket-management/api_simple/models"
+
+	"gorm.io/gorm"
+)
+
+// CorridorDemand is the demand for travel between two places over a travel period
+type CorridorDemand struct {
+	Origin         string  `json:"origin"`
+	Destination    string  `json:"destination"`
+	Searches       int64   `json:"searches"`        // Số lượt tìm chuyến
+	UnmetSearches  int64   `json:"unmet_searches"`  // Số lượt tìm không còn chuyến nào có chỗ
+	Bookings       int64   `json:"bookings"`        // Số đơn chưa hủy (mọi kênh bán)
+	OnlineBookings int64   `json:"online_bookings"` // Số đơn khách tự đặt trực tuyến
+	ConversionRate float64 `json:"conversion_rate"` // Đơn trực tuyến trên lượt tìm
+}
+
+// DaySearchDemand counts the searches for travel between two places on one day
+type DaySearchDemand struct {
+	Origin        string
+	Destination   string
+	TravelDate    string
+	Searches      int64
+	UnmetSearches int64
+}
+
+// RouteDayLoad sums the trips of a route departing on one day
+type RouteDayLoad struct {
+	RouteID uint
+	Day     string
+	Trips   int64
+	Seats   int64
+	Booked  int64
+}
+
+// DemandRepository records trip searches and aggregates them with bookings
+type DemandRepository struct {
+	db *gorm.DB
+}
+
+func NewDemandRepository(db *gorm.DB) *DemandRepository {
+	return &DemandRepository{db: db}
+}
+
+// RecordSearch stores a trip search
+func (r *DemandRepository) RecordSearch(search *models.TripSearch) error {
+	return r.db.Create(search).Error
+}
+
+// Corridors returns the demand for each origin and destination pair for travel in
+// [from, to): searches for a travel date in the period and bookings on trips departing
+// in it, most booked first
+func (r *DemandRepository) Corridors(from, to time.Time) ([]CorridorDemand, error) {
+	var searches []CorridorDemand
+	err := r.db.Model(&models.TripSearch{}).
+		Select("origin, destination, COUNT(*) AS searches, COALESCE(SUM(CASE WHEN results = 0 THEN 1 ELSE 0 END), 0) AS unmet_searches").
+		Where("travel_date >= ? AND travel_date < ?", from.Format(models.TravelDateFormat), to.Format(models.TravelDateFormat)).
+		Group("origin, destination").
+		Scan(&searches).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var bookings []CorridorDemand
+	err = r.db.Model(&models.Booking{}).
+		Select("routes.origin AS origin, routes.destination AS destination, COUNT(*) AS bookings, COALESCE(SUM(CASE WHEN bookings.user_id IS NOT NULL THEN 1 ELSE 0 END), 0) AS online_bookings").
+		Joins("JOIN trips ON trips.id = bookings.trip_id").
+		Joins("JOIN routes ON routes.id = trips.route_id").
+		Where("trips.departure_time >= ? AND trips.departure_time < ? AND bookings.status <> ?", from, to, models.BookingStatusCancelled).
+		Group("routes.origin, routes.destination").
+		Scan(&bookings).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byCorridor := make(map[[2]string]int)
+	for i, demand := range searches {
+		byCorridor[[2]string{demand.Origin, demand.Destination}] = i
+	}
+	for _, b := range bookings {
+		if i, ok := byCorridor[[2]string{b.Origin, b.Destination}]; ok {
+			searches[i].Bookings, searches[i].OnlineBookings = b.Bookings, b.OnlineBookings
+			continue
+		}
+		searches = append(searches, b)
+	}
+
+	for i := range searches {
+		if searches[i].Searches > 0 {
+			searches[i].ConversionRate = float64(searches[i].OnlineBookings) / float64(searches[i].Searches)
+		}
+	}
+	sort.SliceStable(searches, func(i, j int) bool {
+		if searches[i].Bookings != searches[j].Bookings {
+			return searches[i].Bookings > searches[j].Bookings
+		}
+		if searches[i].Searches != searches[j].Searches {
+			return searches[i].Searches > searches[j].Searches
+		}
+		return searches[i].Origin+searches[i].Destination < searches[j].Origin+searches[j].Destination
+	})
+	return searches, nil
+}
+
+// SearchesByDay counts the searches for each origin, destination and travel date in [from, to)
+func (r *DemandRepository) SearchesByDay(from, to time.Time) ([]DaySearchDemand, error) {
+	var rows []DaySearchDemand
+	err := r.db.Model(&models.TripSearch{}).
+		Select("origin, destination, travel_date, COUNT(*) AS searches, COALESCE(SUM(CASE WHEN results = 0 THEN 1 ELSE 0 END), 0) AS unmet_searches").
+		Where("travel_date >= ? AND travel_date < ?", from.Format(models.TravelDateFormat), to.Format(models.TravelDateFormat)).
+		Group("origin, destination, travel_date").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// RouteLoadByDay sums the seats and booked seats of the active trips of each route for
+// each day in [from, to)
+func (r *DemandRepository) RouteLoadByDay(from, to time.Time) ([]RouteDayLoad, error) {
+	day := periodExpr(r.db, "departure_time", ReportDaily)
+	var rows []RouteDayLoad
+	err := r.db.Model(&models.Trip{}).
+		Select("route_id, "+day+" AS day, COUNT(*) AS trips, COALESCE(SUM(total_seats), 0) AS seats, COALESCE(SUM(booked_seats), 0) AS booked").
+		Where("departure_time >= ? AND departure_time < ? AND is_active = ?", from, to, true).
+		Group("route_id, " + day).
+		Scan(&rows).Error
+	return rows, err
+}
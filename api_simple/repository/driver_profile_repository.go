@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"ticket-management/api_simple/models"
+
+	"gorm.io/gorm"
+)
+
+type DriverProfileRepository struct {
+	db *gorm.DB
+}
+
+func NewDriverProfileRepository(db *gorm.DB) *DriverProfileRepository {
+	return &DriverProfileRepository{db: db}
+}
+
+// FindByUserID finds the profile of a driver
+func (r *DriverProfileRepository) FindByUserID(userID uint) (*models.DriverProfile, error) {
+	var profile models.DriverProfile
+	err := r.db.Where("user_id = ?", userID).First(&profile).Error
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// Save creates or updates a driver profile
+func (r *DriverProfileRepository) Save(profile *models.DriverProfile) error {
+	return r.db.Save(profile).Error
+}
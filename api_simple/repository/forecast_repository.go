@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"time"
+
+	"ticket-management/api_simple/models"
+
+	"gorm.io/gorm"
+)
+
+type HolidayRepository struct {
+	*BaseRepository[models.Holiday]
+}
+
+func NewHolidayRepository(db *gorm.DB) *HolidayRepository {
+	return &HolidayRepository{
+		BaseRepository: NewBaseRepository[models.Holiday](db),
+	}
+}
+
+// FindBetween finds the holidays overlapping the days from one date to another (inclusive)
+func (r *HolidayRepository) FindBetween(from, to time.Time) ([]models.Holiday, error) {
+	var holidays []models.Holiday
+	err := r.db.Where("start_date <= ? AND end_date >= ?", to.Format(models.TravelDateFormat), from.Format(models.TravelDateFormat)).
+		Order("start_date ASC").
+		Find(&holidays).Error
+	return holidays, err
+}
+
+// ForecastFilter selects trip forecasts
+type ForecastFilter struct {
+	From    *time.Time
+	To      *time.Time
+	RouteID uint
+	Risk    models.ForecastRisk
+}
+
+// ForecastRepository reads trip history and stores trip forecasts
+type ForecastRepository struct {
+	db *gorm.DB
+}
+
+func NewForecastRepository(db *gorm.DB) *ForecastRepository {
+	return &ForecastRepository{db: db}
+}
+
+// TripsBetween finds the active trips departing in [from, to) with only the columns
+// forecasting needs
+func (r *ForecastRepository) TripsBetween(from, to time.Time) ([]models.Trip, error) {
+	var trips []models.Trip
+	err := r.db.Select("id, operator_id, route_id, departure_time, total_seats, booked_seats").
+		Where("departure_time >= ? AND departure_time < ? AND is_active = ?", from, to, true).
+		Order("departure_time ASC, id ASC").
+		Find(&trips).Error
+	return trips, err
+}
+
+// Replace stores forecasts in place of those of trips departing from a time on
+func (r *ForecastRepository) Replace(from time.Time, forecasts []models.TripForecast) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("departure_time >= ?", from).Delete(&models.TripForecast{}).Error; err != nil {
+			return err
+		}
+		if len(forecasts) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(forecasts, 500).Error
+	})
+}
+
+// List finds the forecasts matching filter by departure time, paginated
+func (r *ForecastRepository) List(filter ForecastFilter, page, limit int) ([]models.TripForecast, int64, error) {
+	query := r.db.Model(&models.TripForecast{})
+	if filter.From != nil {
+		query = query.Where("departure_time >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("departure_time <= ?", *filter.To)
+	}
+	if filter.RouteID != 0 {
+		query = query.Where("route_id = ?", filter.RouteID)
+	}
+	if filter.Risk != "" {
+		query = query.Where("risk = ?", filter.Risk)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var forecasts []models.TripForecast
+	err := query.Order("departure_time ASC, trip_id ASC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&forecasts).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := r.withLiveBookings(forecasts); err != nil {
+		return nil, 0, err
+	}
+	return forecasts, total, nil
+}
+
+// FindByTrip finds the forecast of a trip
+func (r *ForecastRepository) FindByTrip(tripID uint) (*models.TripForecast, error) {
+	var forecast models.TripForecast
+	if err := r.db.Where("trip_id = ?", tripID).First(&forecast).Error; err != nil {
+		return nil, err
+	}
+	forecasts := []models.TripForecast{forecast}
+	if err := r.withLiveBookings(forecasts); err != nil {
+		return nil, err
+	}
+	return &forecasts[0], nil
+}
+
+// withLiveBookings replaces the seats booked when the forecasts were made with those
+// their trips have booked now, so forecasts are read against current sales
+func (r *ForecastRepository) withLiveBookings(forecasts []models.TripForecast) error {
+	if len(forecasts) == 0 {
+		return nil
+	}
+	tripIDs := make([]uint, len(forecasts))
+	for i, forecast := range forecasts {
+		tripIDs[i] = forecast.TripID
+	}
+
+	var trips []models.Trip
+	if err := r.db.Select("id, booked_seats").Where("id IN ?", tripIDs).Find(&trips).Error; err != nil {
+		return err
+	}
+	booked := make(map[uint]int, len(trips))
+	for _, trip := range trips {
+		booked[trip.ID] = trip.BookedSeats
+	}
+	for i := range forecasts {
+		if seats, ok := booked[forecasts[i].TripID]; ok {
+			forecasts[i].BookedSeats = seats
+		}
+	}
+	return nil
+}
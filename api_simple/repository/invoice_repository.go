@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"errors"
+
+	"ticket-management/api_simple/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type InvoiceRepository struct {
+	db *gorm.DB
+}
+
+func NewInvoiceRepository(db *gorm.DB) *InvoiceRepository {
+	return &InvoiceRepository{db: db}
+}
+
+// NextNumber hands out the next invoice number of a series, creating the series on
+// first use. The series row is locked so concurrent issues never share a number;
+// callers must run it inside the transaction that creates the invoice.
+func (r *InvoiceRepository) NextNumber(operatorID uint, templateCode, series string) (int, error) {
+	var row models.InvoiceSeries
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("operator_id = ? AND template_code = ? AND series = ?", operatorID, templateCode, series).
+		First(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		row = models.InvoiceSeries{OperatorID: operatorID, TemplateCode: templateCode, Series: series}
+		err = r.db.Create(&row).Error
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	row.LastNumber++
+	if err := r.db.Model(&row).Update("last_number", row.LastNumber).Error; err != nil {
+		return 0, err
+	}
+	return row.LastNumber, nil
+}
+
+// Create creates an invoice with its lines, leaving the booking and related invoice as they are
+func (r *InvoiceRepository) Create(invoice *models.Invoice) error {
+	return r.db.Omit("Booking", "OriginalInvoice").Create(invoice).Error
+}
+
+// Update saves an invoice without touching its booking, lines or related invoice
+func (r *InvoiceRepository) Update(invoice *models.Invoice) error {
+	return r.db.Omit(clause.Associations).Save(invoice).Error
+}
+
+// FindByID finds an invoice by ID with its lines and the invoice it corrects
+func (r *InvoiceRepository) FindByID(id uint) (*models.Invoice, error) {
+	var invoice models.Invoice
+	err := r.db.Preload("Lines", func(db *gorm.DB) *gorm.DB {
+		return db.Order("line_no ASC")
+	}).Preload("OriginalInvoice").First(&invoice, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// FindByLookupCode finds an invoice by the lookup code given by the e-invoice provider
+func (r *InvoiceRepository) FindByLookupCode(code string) (*models.Invoice, error) {
+	var invoice models.Invoice
+	if err := r.db.Select("id").Where("lookup_code = ?", code).First(&invoice).Error; err != nil {
+		return nil, err
+	}
+	return r.FindByID(invoice.ID)
+}
+
+// FindActiveOriginal finds the invoice currently standing for a booking: the original
+// or the latest replacement, whether or not it has been adjusted
+func (r *InvoiceRepository) FindActiveOriginal(bookingID uint) (*models.Invoice, error) {
+	var invoice models.Invoice
+	err := r.db.Where("booking_id = ? AND type IN ? AND status <> ?",
+		bookingID,
+		[]models.InvoiceType{models.InvoiceTypeOriginal, models.InvoiceTypeReplacement},
+		models.InvoiceStatusReplaced,
+	).Order("id DESC").First(&invoice).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// FindByBooking finds every invoice issued for a booking in issue order
+func (r *InvoiceRepository) FindByBooking(bookingID uint) ([]models.Invoice, error) {
+	var invoices []models.Invoice
+	err := r.db.Where("booking_id = ?", bookingID).Order("id ASC").Find(&invoices).Error
+	return invoices, err
+}
+
+// FindAll finds invoices with optional filters, newest first
+func (r *InvoiceRepository) FindAll(filters map[string]interface{}, page, limit int) ([]models.Invoice, int64, error) {
+	var invoices []models.Invoice
+	var total int64
+
+	query := r.db.Model(&models.Invoice{})
+	if len(filters) > 0 {
+		query = query.Where(filters)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("issued_at DESC, id DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&invoices).Error
+	return invoices, total, err
+}
+
+// FindUnsent finds invoices that have not been accepted by the e-invoice provider yet
+func (r *InvoiceRepository) FindUnsent(limit int) ([]models.Invoice, error) {
+	var invoices []models.Invoice
+	err := r.db.Preload("Lines", func(db *gorm.DB) *gorm.DB {
+		return db.Order("line_no ASC")
+	}).Preload("OriginalInvoice").
+		Where("sent_at IS NULL").
+		Order("id ASC").
+		Limit(limit).
+		Find(&invoices).Error
+	return invoices, err
+}
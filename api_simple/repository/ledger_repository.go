@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"time"
+
+	"ticket-management/api_simple/models"
+
+	"gorm.io/gorm"
+)
+
+// LedgerFilter narrows ledger queries. Zero values mean no restriction.
+type LedgerFilter struct {
+	From      *time.Time // Ghi sổ từ thời điểm (bao gồm)
+	To        *time.Time // Ghi sổ đến thời điểm (bao gồm)
+	BookingID uint       // Đơn đặt vé
+	TripID    uint       // Chuyến đi
+	BusID     uint       // Xe
+}
+
+// AccountBalance is the total posted to an account
+type AccountBalance struct {
+	Account models.LedgerAccount `json:"account"`
+	Debit   models.Money         `json:"debit"`
+	Credit  models.Money         `json:"credit"`
+}
+
+type LedgerRepository struct {
+	db *gorm.DB
+}
+
+func NewLedgerRepository(db *gorm.DB) *LedgerRepository {
+	return &LedgerRepository{db: db}
+}
+
+// Create posts a journal entry with its lines
+func (r *LedgerRepository) Create(transaction *models.LedgerTransaction) error {
+	return r.db.Create(transaction).Error
+}
+
+// FindTransactions finds journal entries with their lines, newest first
+func (r *LedgerRepository) FindTransactions(filters map[string]interface{}, from, to *time.Time, page, limit int) ([]models.LedgerTransaction, int64, error) {
+	var transactions []models.LedgerTransaction
+	var total int64
+
+	query := r.db.Model(&models.LedgerTransaction{})
+	if len(filters) > 0 {
+		query = query.Where(filters)
+	}
+	if from != nil {
+		query = query.Where("posted_at >= ?", *from)
+	}
+	if to != nil {
+		query = query.Where("posted_at <= ?", *to)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Preload("Entries", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id ASC")
+	}).
+		Order("posted_at DESC, id DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&transactions).Error
+	return transactions, total, err
+}
+
+// Balances sums the debits and credits of every account with postings
+func (r *LedgerRepository) Balances(filter LedgerFilter) ([]AccountBalance, error) {
+	var balances []AccountBalance
+	err := r.entries(filter).
+		Select("ledger_entries.account AS account, COALESCE(SUM(ledger_entries.debit), 0) AS debit, COALESCE(SUM(ledger_entries.credit), 0) AS credit").
+		Group("ledger_entries.account").
+		Order("ledger_entries.account ASC").
+		Scan(&balances).Error
+	return balances, err
+}
+
+// Revenue returns the net revenue posted: ticket sales less cancellations, plus cancellation fees
+func (r *LedgerRepository) Revenue(filter LedgerFilter) (models.Money, error) {
+	var revenue models.Money
+	err := r.entries(filter).
+		Where("ledger_entries.account IN ?", []models.LedgerAccount{models.AccountRevenue, models.AccountCancellationFees}).
+		Select("COALESCE(SUM(ledger_entries.credit - ledger_entries.debit), 0)").
+		Row().
+		Scan(&revenue)
+	return revenue, err
+}
+
+// BookingBalances returns the debit balance (debit - credit) of each account for a booking;
+// accounts whose balance sits on the credit side come out negative
+func (r *LedgerRepository) BookingBalances(bookingID uint) (map[models.LedgerAccount]models.Money, error) {
+	balances, err := r.Balances(LedgerFilter{BookingID: bookingID})
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[models.LedgerAccount]models.Money, len(balances))
+	for _, balance := range balances {
+		result[balance.Account] = balance.Debit - balance.Credit
+	}
+	return result, nil
+}
+
+// CommissionOwed returns the commission still owed to an agency
+func (r *LedgerRepository) CommissionOwed(apiClientID uint) (models.Money, error) {
+	var owed models.Money
+	err := r.db.Model(&models.LedgerEntry{}).
+		Joins("JOIN ledger_transactions ON ledger_transactions.id = ledger_entries.transaction_id").
+		Where("ledger_transactions.api_client_id = ? AND ledger_entries.account = ?", apiClientID, models.AccountCommissionPayable).
+		Select("COALESCE(SUM(ledger_entries.credit - ledger_entries.debit), 0)").
+		Row().
+		Scan(&owed)
+	return owed, err
+}
+
+// FindUnpostedBookings finds bookings after afterID, cancelled ones included, that have
+// no ledger postings yet
+func (r *LedgerRepository) FindUnpostedBookings(afterID uint, limit int) ([]models.Booking, error) {
+	var bookings []models.Booking
+	err := r.db.Where("id > ?", afterID).
+		Where("NOT EXISTS (SELECT 1 FROM ledger_transactions WHERE ledger_transactions.booking_id = bookings.id)").
+		Order("id ASC").
+		Limit(limit).
+		Find(&bookings).Error
+	return bookings, err
+}
+
+// entries starts a query on ledger lines restricted by filter
+func (r *LedgerRepository) entries(filter LedgerFilter) *gorm.DB {
+	query := r.db.Model(&models.LedgerEntry{})
+	if filter.From != nil {
+		query = query.Where("ledger_entries.posted_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("ledger_entries.posted_at <= ?", *filter.To)
+	}
+	if filter.BookingID != 0 {
+		query = query.Where("ledger_entries.booking_id = ?", filter.BookingID)
+	}
+	if filter.TripID != 0 || filter.BusID != 0 {
+		query = query.Joins("JOIN bookings ON bookings.id = ledger_entries.booking_id")
+	}
+	if filter.TripID != 0 {
+		query = query.Where("bookings.trip_id = ?", filter.TripID)
+	}
+	if filter.BusID != 0 {
+		query = query.Joins("JOIN trips ON trips.id = bookings.trip_id").Where("trips.bus_id = ?", filter.BusID)
+	}
+	return query
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"time"
+
+	"ticket-management/api_simple/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LoginThrottleRepository struct {
+	db *gorm.DB
+}
+
+func NewLoginThrottleRepository(db *gorm.DB) *LoginThrottleRepository {
+	return &LoginThrottleRepository{db: db}
+}
+
+// FindByID finds a throttle by ID
+func (r *LoginThrottleRepository) FindByID(id uint) (*models.LoginThrottle, error) {
+	var throttle models.LoginThrottle
+	err := r.db.First(&throttle, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &throttle, nil
+}
+
+// Find finds the throttle for a scope and key
+func (r *LoginThrottleRepository) Find(scope models.ThrottleScope, key string) (*models.LoginThrottle, error) {
+	var throttle models.LoginThrottle
+	err := r.db.Where("scope = ? AND key = ?", scope, key).First(&throttle).Error
+	if err != nil {
+		return nil, err
+	}
+	return &throttle, nil
+}
+
+// IncrementFailures counts a failed login for a scope and key in one statement, creating
+// the throttle on the first failure, and returns the throttle as updated. The count
+// starts again from one when the last failure is before resetBefore and the throttle
+// is not locked.
+func (r *LoginThrottleRepository) IncrementFailures(scope models.ThrottleScope, key string, ip string, now time.Time, resetBefore time.Time) (*models.LoginThrottle, error) {
+	throttle := models.LoginThrottle{Scope: scope, Key: key, Failures: 1, LastFailureAt: &now, LastIP: ip}
+	err := r.db.Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{{Name: "scope"}, {Name: "key"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"failures": gorm.Expr("CASE WHEN login_throttles.last_failure_at < ? AND (login_throttles.locked_until IS NULL OR login_throttles.locked_until <= ?) THEN 1 ELSE login_throttles.failures + 1 END",
+					resetBefore, now),
+				"last_failure_at": now,
+				"last_ip":         ip,
+				"updated_at":      now,
+			}),
+		},
+		clause.Returning{},
+	).Create(&throttle).Error
+	if err != nil {
+		return nil, err
+	}
+	return &throttle, nil
+}
+
+// Restrict delays the next attempt of a throttle and, when lockedUntil is set, locks it
+func (r *LoginThrottleRepository) Restrict(id uint, nextAllowedAt time.Time, lockedUntil *time.Time) error {
+	updates := map[string]interface{}{"next_allowed_at": nextAllowedAt}
+	if lockedUntil != nil {
+		updates["locked_until"] = *lockedUntil
+	}
+	return r.db.Model(&models.LoginThrottle{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// Clear permanently removes the throttle for a scope and key
+func (r *LoginThrottleRepository) Clear(scope models.ThrottleScope, key string) error {
+	return r.db.Unscoped().Where("scope = ? AND key = ?", scope, key).Delete(&models.LoginThrottle{}).Error
+}
+
+// Delete permanently removes a throttle by ID
+func (r *LoginThrottleRepository) Delete(id uint) error {
+	return r.db.Unscoped().Delete(&models.LoginThrottle{}, id).Error
+}
+
+// FindActive finds throttles that are locked or still backing off
+func (r *LoginThrottleRepository) FindActive(scope models.ThrottleScope, now time.Time) ([]models.LoginThrottle, error) {
+	var throttles []models.LoginThrottle
+	query := r.db.Where("locked_until > ? OR next_allowed_at > ?", now, now)
+	if scope != "" {
+		query = query.Where("scope = ?", scope)
+	}
+	err := query.Order("last_failure_at DESC").Find(&throttles).Error
+	return throttles, err
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"time"
+
+	"ticket-management/api_simple/models"
+
+	"gorm.io/gorm"
+)
+
+type MaintenanceRepository struct {
+	db *gorm.DB
+}
+
+func NewMaintenanceRepository(db *gorm.DB) *MaintenanceRepository {
+	return &MaintenanceRepository{db: db}
+}
+
+// CreateRecord creates a maintenance record
+func (r *MaintenanceRepository) CreateRecord(record *models.MaintenanceRecord) error {
+	return r.db.Create(record).Error
+}
+
+// FindRecordsByBus finds maintenance records of a bus, newest first
+func (r *MaintenanceRepository) FindRecordsByBus(busID uint, filters map[string]interface{}) ([]models.MaintenanceRecord, error) {
+	var records []models.MaintenanceRecord
+	query := r.db.Where("bus_id = ?", busID)
+	if len(filters) > 0 {
+		query = query.Where(filters)
+	}
+	err := query.Order("performed_at DESC").Find(&records).Error
+	return records, err
+}
+
+// FindLatestRecord finds the most recent record of a type for a bus
+func (r *MaintenanceRepository) FindLatestRecord(busID uint, recordType models.MaintenanceType) (*models.MaintenanceRecord, error) {
+	var record models.MaintenanceRecord
+	err := r.db.Where("bus_id = ? AND type = ?", busID, recordType).
+		Order("performed_at DESC").
+		First(&record).Error
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// SumCostByBus sums maintenance costs of a bus performed within [from, to]
+func (r *MaintenanceRepository) SumCostByBus(busID uint, from, to time.Time) (models.Money, int64, error) {
+	var result struct {
+		Total models.Money
+		Count int64
+	}
+	err := r.db.Model(&models.MaintenanceRecord{}).
+		Select("COALESCE(SUM(cost), 0) AS total, COUNT(*) AS count").
+		Where("bus_id = ? AND performed_at BETWEEN ? AND ?", busID, from, to).
+		Scan(&result).Error
+	return result.Total, result.Count, err
+}
+
+// CreateUnavailability creates an unavailability window
+func (r *MaintenanceRepository) CreateUnavailability(window *models.BusUnavailability) error {
+	return r.db.Create(window).Error
+}
+
+// FindUnavailabilityByID finds an unavailability window by ID
+func (r *MaintenanceRepository) FindUnavailabilityByID(id uint) (*models.BusUnavailability, error) {
+	var window models.BusUnavailability
+	err := r.db.First(&window, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &window, nil
+}
+
+// UpdateUnavailability updates an unavailability window
+func (r *MaintenanceRepository) UpdateUnavailability(window *models.BusUnavailability) error {
+	return r.db.Save(window).Error
+}
+
+// FindUnavailabilityBetween finds windows intersecting [from, to), optionally for a single bus
+func (r *MaintenanceRepository) FindUnavailabilityBetween(busID uint, from, to time.Time) ([]models.BusUnavailability, error) {
+	var windows []models.BusUnavailability
+	query := r.db.Where("starts_at < ? AND (ends_at IS NULL OR ends_at > ?)", to, from)
+	if busID != 0 {
+		query = query.Where("bus_id = ?", busID)
+	}
+	err := query.Order("starts_at ASC").Find(&windows).Error
+	return windows, err
+}
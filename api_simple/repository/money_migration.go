@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// moneyColumns lists the amount columns that used to be stored as floating point
+var moneyColumns = map[string][]string{
+	"bookings":            {"total_amount", "commission_amount", "surcharge_amount", "refund_amount"},
+	"trips":               {"price"},
+	"seats":               {"price"},
+	"routes":              {"base_price", "min_price", "max_price"},
+	"route_points":        {"surcharge"},
+	"cash_shifts":         {"opening_float", "expected_cash", "counted_cash", "variance"},
+	"cash_transactions":   {"amount"},
+	"maintenance_records": {"cost"},
+	"shipments":           {"price"},
+	"invoices":            {"subtotal", "vat_amount", "total"},
+	"invoice_lines":       {"amount", "vat_amount", "unit_price"},
+	"ledger_entries":      {"debit", "credit"},
+}
+
+// MigrateMoneyColumns converts the amount columns of an existing Postgres database from
+// floating point to BIGINT whole đồng, rounding stray fractions (264000.00000001)
+// half away from zero. It must run before AutoMigrate, which would otherwise truncate
+// them. Tables or columns that do not exist yet and columns already converted are skipped.
+func MigrateMoneyColumns(db *gorm.DB) error {
+	if db.Dialector.Name() != "postgres" {
+		return nil
+	}
+
+	migrator := db.Migrator()
+	for table, columns := range moneyColumns {
+		if !migrator.HasTable(table) {
+			continue
+		}
+		columnTypes, err := migrator.ColumnTypes(table)
+		if err != nil {
+			return err
+		}
+		types := make(map[string]string, len(columnTypes))
+		for _, ct := range columnTypes {
+			types[ct.Name()] = strings.ToLower(ct.DatabaseTypeName())
+		}
+
+		for _, column := range columns {
+			current, ok := types[column]
+			if !ok || current == "int8" || current == "bigint" {
+				continue
+			}
+			sql := fmt.Sprintf(`ALTER TABLE %q ALTER COLUMN %q TYPE bigint USING ROUND(%q::numeric)::bigint`, table, column, column)
+			if err := db.Exec(sql).Error; err != nil {
+				return fmt.Errorf("convert %s.%s to bigint: %w", table, column, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"ticket-management/api_simple/models"
+
+	"gorm.io/gorm"
+)
+
+type OperatorRepository struct {
+	*BaseRepository[models.Operator]
+}
+
+func NewOperatorRepository(db *gorm.DB) *OperatorRepository {
+	return &OperatorRepository{
+		BaseRepository: NewBaseRepository[models.Operator](db),
+	}
+}
+
+// FindActive finds operators currently selling tickets, ordered by name
+func (r *OperatorRepository) FindActive() ([]models.Operator, error) {
+	var operators []models.Operator
+	err := r.db.Where("is_active = ?", true).Order("name ASC").Find(&operators).Error
+	return operators, err
+}
+
+// FindWithAccounts finds an operator with its active payment accounts, default account first
+func (r *OperatorRepository) FindWithAccounts(id uint) (*models.Operator, error) {
+	var operator models.Operator
+	err := r.db.Preload("PaymentAccounts", func(db *gorm.DB) *gorm.DB {
+		return db.Where("is_active = ?", true).Order("is_default DESC, id ASC")
+	}).First(&operator, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &operator, nil
+}
+
+type PaymentAccountRepository struct {
+	*BaseRepository[models.OperatorPaymentAccount]
+}
+
+func NewPaymentAccountRepository(db *gorm.DB) *PaymentAccountRepository {
+	return &PaymentAccountRepository{
+		BaseRepository: NewBaseRepository[models.OperatorPaymentAccount](db),
+	}
+}
+
+// FindByOperator finds all payment accounts of an operator, default account first
+func (r *PaymentAccountRepository) FindByOperator(operatorID uint) ([]models.OperatorPaymentAccount, error) {
+	var accounts []models.OperatorPaymentAccount
+	err := r.db.Where("operator_id = ?", operatorID).Order("is_default DESC, id ASC").Find(&accounts).Error
+	return accounts, err
+}
+
+// ClearDefault unsets the default flag on the other accounts of an operator
+func (r *PaymentAccountRepository) ClearDefault(operatorID, exceptID uint) error {
+	return r.db.Model(&models.OperatorPaymentAccount{}).
+		Where("operator_id = ? AND id <> ?", operatorID, exceptID).
+		Update("is_default", false).Error
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+type operatorScopeKey struct{}
+
+// WithOperator returns a handle restricted to the data of one operator. Every query,
+// update and delete it runs on a model with an OperatorID field is filtered by
+// operator_id, and rows it creates are stamped with the operator, so repositories
+// built on it cannot reach another operator's routes, buses, trips or bookings.
+// Models without OperatorID and raw SQL are not affected, nor are models where the
+// operator is optional (*uint), such as users and audit entries: customers belong to
+// the platform and must stay visible through preloads, so callers filter those.
+func WithOperator(db *gorm.DB, operatorID uint) *gorm.DB {
+	return db.WithContext(context.WithValue(db.Statement.Context, operatorScopeKey{}, operatorID))
+}
+
+// WithoutOperator returns a handle on the same connection or transaction as db that is
+// not restricted to an operator, for checks spanning all operators
+func WithoutOperator(db *gorm.DB) *gorm.DB {
+	return db.WithContext(context.WithValue(db.Statement.Context, operatorScopeKey{}, nil))
+}
+
+// ScopedOperator returns the operator db is restricted to, if any
+func ScopedOperator(db *gorm.DB) (uint, bool) {
+	if db.Statement.Context == nil {
+		return 0, false
+	}
+	id, ok := db.Statement.Context.Value(operatorScopeKey{}).(uint)
+	return id, ok
+}
+
+// RegisterOperatorScope installs the callbacks that enforce WithOperator on db
+func RegisterOperatorScope(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Query().Before("gorm:query").Register("operator:query", filterByOperator); err != nil {
+		return err
+	}
+	if err := callbacks.Row().Before("gorm:row").Register("operator:row", filterByOperator); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("operator:update", filterByOperator); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().Before("gorm:delete").Register("operator:delete", filterByOperator); err != nil {
+		return err
+	}
+	return callbacks.Create().Before("gorm:create").Register("operator:create", stampOperator)
+}
+
+// filterByOperator limits a statement on an operator-owned model to rows of the scoped operator
+func filterByOperator(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+	id, ok := ScopedOperator(db)
+	if !ok || db.Statement.Schema == nil {
+		return
+	}
+	field := operatorField(db)
+	if field == nil || field.DBName == "" {
+		return
+	}
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: id},
+	}})
+}
+
+// stampOperator assigns new rows to the scoped operator
+func stampOperator(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+	id, ok := ScopedOperator(db)
+	if !ok || db.Statement.Schema == nil {
+		return
+	}
+	field := operatorField(db)
+	if field == nil {
+		return
+	}
+
+	ctx := db.Statement.Context
+	stamp := func(value reflect.Value) {
+		value = reflect.Indirect(value)
+		if value.Kind() == reflect.Struct {
+			db.AddError(field.Set(ctx, value, id))
+		}
+	}
+	switch value := db.Statement.ReflectValue; value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			stamp(value.Index(i))
+		}
+	default:
+		stamp(value)
+	}
+}
+
+// operatorField returns the required OperatorID field of the statement's model, if any
+func operatorField(db *gorm.DB) *schema.Field {
+	field := db.Statement.Schema.LookUpField("OperatorID")
+	if field == nil || field.FieldType.Kind() == reflect.Ptr {
+		return nil
+	}
+	return field
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"time"
+
+	"ticket-management/api_simple/models"
+
+	"gorm.io/gorm"
+)
+
+type OTPRepository struct {
+	db *gorm.DB
+}
+
+func NewOTPRepository(db *gorm.DB) *OTPRepository {
+	return &OTPRepository{db: db}
+}
+
+// Create creates a new OTP code
+func (r *OTPRepository) Create(otp *models.OTPCode) error {
+	return r.db.Create(otp).Error
+}
+
+// Update updates an OTP code
+func (r *OTPRepository) Update(otp *models.OTPCode) error {
+	return r.db.Save(otp).Error
+}
+
+// RecordFailedAttempt counts a wrong code in one statement, locking the code until
+// lockedUntil once it reaches maxAttempts. It returns false when the code already
+// had maxAttempts, so concurrent guesses cannot go past the limit.
+func (r *OTPRepository) RecordFailedAttempt(id uint, maxAttempts int, lockedUntil time.Time) (bool, error) {
+	result := r.db.Model(&models.OTPCode{}).
+		Where("id = ? AND attempts < ?", id, maxAttempts).
+		Updates(map[string]interface{}{
+			"attempts":     gorm.Expr("attempts + 1"),
+			"locked_until": gorm.Expr("CASE WHEN attempts + 1 >= ? THEN ? ELSE locked_until END", maxAttempts, lockedUntil),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Consume marks a code as used. It returns false when the code was already used.
+func (r *OTPRepository) Consume(id uint, at time.Time) (bool, error) {
+	result := r.db.Model(&models.OTPCode{}).
+		Where("id = ? AND consumed_at IS NULL", id).
+		Update("consumed_at", at)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// FindLatest finds the most recently issued, unconsumed code for a phone and purpose
+func (r *OTPRepository) FindLatest(phone string, purpose models.OTPPurpose) (*models.OTPCode, error) {
+	var otp models.OTPCode
+	err := r.db.Where("phone = ? AND purpose = ? AND consumed_at IS NULL", phone, purpose).
+		Order("sent_at DESC").
+		First(&otp).Error
+	if err != nil {
+		return nil, err
+	}
+	return &otp, nil
+}
+
+// InvalidateAll marks every open code for a phone and purpose as consumed
+func (r *OTPRepository) InvalidateAll(phone string, purpose models.OTPPurpose) error {
+	return r.db.Model(&models.OTPCode{}).
+		Where("phone = ? AND purpose = ? AND consumed_at IS NULL", phone, purpose).
+		Update("consumed_at", time.Now()).Error
+}
+
+// DeleteExpired permanently removes codes that expired before the given time
+func (r *OTPRepository) DeleteExpired(before time.Time) error {
+	return r.db.Unscoped().Where("expires_at < ?", before).Delete(&models.OTPCode{}).Error
+}
@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"ticket-management/api_simple/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReconciliationStatusSummary counts the entries of a report with one outcome
+type ReconciliationStatusSummary struct {
+	Status models.ReconciliationStatus `json:"status"`
+	Count  int64                       `json:"count"`
+	Open   int64                       `json:"open"`   // Chưa xử lý
+	Amount models.Money                `json:"amount"` // Tổng tiền quyết toán
+}
+
+type ReconciliationRepository struct {
+	db *gorm.DB
+}
+
+func NewReconciliationRepository(db *gorm.DB) *ReconciliationRepository {
+	return &ReconciliationRepository{db: db}
+}
+
+// CreateReport creates a settlement report together with its entries
+func (r *ReconciliationRepository) CreateReport(report *models.SettlementReport) error {
+	return r.db.Create(report).Error
+}
+
+// HasChecksum reports whether a file with this content has already been imported
+func (r *ReconciliationRepository) HasChecksum(checksum string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.SettlementReport{}).Where("checksum = ?", checksum).Count(&count).Error
+	return count > 0, err
+}
+
+// FindReportByID finds a settlement report without its entries
+func (r *ReconciliationRepository) FindReportByID(id uint) (*models.SettlementReport, error) {
+	var report models.SettlementReport
+	if err := r.db.First(&report, id).Error; err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// FindReports finds settlement reports with optional filters, newest first
+func (r *ReconciliationRepository) FindReports(filters map[string]interface{}, page, limit int) ([]models.SettlementReport, int64, error) {
+	var reports []models.SettlementReport
+	var total int64
+
+	query := r.db.Model(&models.SettlementReport{})
+	if len(filters) > 0 {
+		query = query.Where(filters)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("created_at DESC, id DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&reports).Error
+	return reports, total, err
+}
+
+// FindEntries finds the entries of a report in file order; openOnly keeps the unresolved discrepancies
+func (r *ReconciliationRepository) FindEntries(reportID uint, filters map[string]interface{}, openOnly bool, page, limit int) ([]models.SettlementEntry, int64, error) {
+	var entries []models.SettlementEntry
+	var total int64
+
+	query := r.db.Model(&models.SettlementEntry{}).Where("report_id = ?", reportID)
+	if len(filters) > 0 {
+		query = query.Where(filters)
+	}
+	if openOnly {
+		query = query.Where("status <> ? AND resolved_at IS NULL", models.ReconciliationMatched)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Preload("Booking").
+		Order("line = 0, line ASC, id ASC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&entries).Error
+	return entries, total, err
+}
+
+// FindEntryByID finds a reconciliation entry
+func (r *ReconciliationRepository) FindEntryByID(id uint) (*models.SettlementEntry, error) {
+	var entry models.SettlementEntry
+	if err := r.db.First(&entry, id).Error; err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// UpdateEntry saves an entry without touching its booking
+func (r *ReconciliationRepository) UpdateEntry(entry *models.SettlementEntry) error {
+	return r.db.Omit(clause.Associations).Save(entry).Error
+}
+
+// FindSettledReferences returns which of the references already appear in an imported settlement file
+func (r *ReconciliationRepository) FindSettledReferences(references []string) (map[string]bool, error) {
+	settled := make(map[string]bool)
+	if len(references) == 0 {
+		return settled, nil
+	}
+	var found []string
+	err := r.db.Model(&models.SettlementEntry{}).
+		Where("line > 0 AND reference IN ?", references).
+		Distinct().
+		Pluck("reference", &found).Error
+	for _, reference := range found {
+		settled[reference] = true
+	}
+	return settled, err
+}
+
+// FindReconciledBookingIDs returns which of the bookings already have a reconciliation entry
+func (r *ReconciliationRepository) FindReconciledBookingIDs(bookingIDs []uint) (map[uint]bool, error) {
+	reconciled := make(map[uint]bool)
+	if len(bookingIDs) == 0 {
+		return reconciled, nil
+	}
+	var found []uint
+	err := r.db.Model(&models.SettlementEntry{}).
+		Where("booking_id IN ?", bookingIDs).
+		Distinct().
+		Pluck("booking_id", &found).Error
+	for _, id := range found {
+		reconciled[id] = true
+	}
+	return reconciled, err
+}
+
+// RefreshReportCounts recounts the matched entries and open discrepancies of a report
+func (r *ReconciliationRepository) RefreshReportCounts(reportID uint) error {
+	var matched, open int64
+	entries := r.db.Model(&models.SettlementEntry{}).Where("report_id = ?", reportID)
+	if err := entries.Session(&gorm.Session{}).Where("status = ?", models.ReconciliationMatched).Count(&matched).Error; err != nil {
+		return err
+	}
+	if err := entries.Session(&gorm.Session{}).Where("status <> ? AND resolved_at IS NULL", models.ReconciliationMatched).Count(&open).Error; err != nil {
+		return err
+	}
+	return r.db.Model(&models.SettlementReport{}).Where("id = ?", reportID).Updates(map[string]interface{}{
+		"matched_count": matched,
+		"issue_count":   open,
+	}).Error
+}
+
+// SummarizeReport counts the entries of a report by outcome
+func (r *ReconciliationRepository) SummarizeReport(reportID uint) ([]ReconciliationStatusSummary, error) {
+	var summary []ReconciliationStatusSummary
+	err := r.db.Model(&models.SettlementEntry{}).
+		Select("status, COUNT(*) AS count, SUM(CASE WHEN resolved_at IS NULL AND status <> ? THEN 1 ELSE 0 END) AS open, COALESCE(SUM(amount), 0) AS amount", models.ReconciliationMatched).
+		Where("report_id = ?", reportID).
+		Group("status").
+		Order("status").
+		Scan(&summary).Error
+	return summary, err
+}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"ticket-management/api_simple/models"
+
+	"gorm.io/gorm"
+)
+
+type RecoveryCodeRepository struct {
+	db *gorm.DB
+}
+
+func NewRecoveryCodeRepository(db *gorm.DB) *RecoveryCodeRepository {
+	return &RecoveryCodeRepository{db: db}
+}
+
+// FindUnused finds the recovery codes of a user that have not been used
+func (r *RecoveryCodeRepository) FindUnused(userID uint) ([]models.RecoveryCode, error) {
+	var codes []models.RecoveryCode
+	err := r.db.Where("user_id = ? AND used_at IS NULL", userID).Find(&codes).Error
+	return codes, err
+}
+
+// Replace deletes all recovery codes of a user and stores the new ones
+func (r *RecoveryCodeRepository) Replace(userID uint, codes []models.RecoveryCode) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+// Update updates a recovery code
+func (r *RecoveryCodeRepository) Update(code *models.RecoveryCode) error {
+	return r.db.Save(code).Error
+}
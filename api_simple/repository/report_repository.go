@@ -0,0 +1,255 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"ticket-management/api_simple/models"
+
+	"gorm.io/gorm"
+)
+
+// ReportGranularity is the length of the periods revenue is grouped by
+type ReportGranularity string
+
+const (
+	ReportDaily   ReportGranularity = "day"
+	ReportWeekly  ReportGranularity = "week"
+	ReportMonthly ReportGranularity = "month"
+)
+
+// ReportFilter narrows a report to a period and optionally to one route or bus
+type ReportFilter struct {
+	From    *time.Time
+	To      *time.Time
+	RouteID uint
+	BusID   uint
+}
+
+// RevenueRow is the revenue of one group of a revenue report: a period, a route, a bus or a channel
+type RevenueRow struct {
+	Period   string       `json:"period,omitempty"` // Ngày đầu kỳ (YYYY-MM-DD)
+	ID       uint         `json:"id,omitempty"`     // ID tuyến hoặc xe
+	Name     string       `json:"name,omitempty"`   // Tên tuyến, biển số xe hoặc kênh bán
+	Revenue  models.Money `json:"revenue"`          // Doanh thu thuần (bán vé + phí hủy)
+	Bookings int64        `json:"bookings"`         // Số đơn có phát sinh doanh thu
+}
+
+// OccupancyRow is the load factor of a trip, or of the trips of a route
+type OccupancyRow struct {
+	ID            uint       `json:"id"`                       // ID chuyến hoặc tuyến
+	Name          string     `json:"name"`                     // Tuyến
+	DepartureTime *time.Time `json:"departure_time,omitempty"` // Giờ khởi hành (theo chuyến)
+	Trips         int64      `json:"trips"`                    // Số chuyến
+	Seats         int64      `json:"seats"`                    // Tổng số ghế
+	Booked        int64      `json:"booked"`                   // Số ghế đã bán
+	LoadFactor    float64    `json:"load_factor"`              // Tỷ lệ lấp đầy (0-1)
+}
+
+// BookingStats sums up the bookings made in a period
+type BookingStats struct {
+	Total            int64   `json:"total"`               // Tổng số đơn
+	Confirmed        int64   `json:"confirmed"`           // Đơn đã xác nhận và thanh toán
+	Cancelled        int64   `json:"cancelled"`           // Đơn đã hủy
+	CancellationRate float64 `json:"cancellation_rate"`   // Tỷ lệ hủy (0-1)
+	AvgLeadHours     float64 `json:"avg_lead_time_hours"` // Thời gian đặt trước giờ khởi hành trung bình (giờ)
+}
+
+// ReportRepository computes revenue, occupancy and booking reports in SQL
+type ReportRepository struct {
+	db *gorm.DB
+}
+
+func NewReportRepository(db *gorm.DB) *ReportRepository {
+	return &ReportRepository{db: db}
+}
+
+// RevenueByPeriod returns the revenue posted to the ledger per day, week (from Monday) or month
+func (r *ReportRepository) RevenueByPeriod(filter ReportFilter, granularity ReportGranularity) ([]RevenueRow, error) {
+	period := periodExpr(r.db, "ledger_entries.posted_at", granularity)
+	var rows []RevenueRow
+	err := r.revenueEntries(filter).
+		Select(period + " AS period, " + revenueColumns).
+		Group(period).
+		Order(period).
+		Scan(&rows).Error
+	return rows, err
+}
+
+// RevenueByRoute returns the revenue of each route, highest first
+func (r *ReportRepository) RevenueByRoute(filter ReportFilter) ([]RevenueRow, error) {
+	var rows []RevenueRow
+	err := r.revenueEntries(filter).
+		Joins("JOIN routes ON routes.id = trips.route_id").
+		Select("routes.id AS id, routes.origin || ' - ' || routes.destination AS name, " + revenueColumns).
+		Group("routes.id, routes.origin, routes.destination").
+		Order("revenue DESC, routes.id").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// RevenueByBus returns the revenue of each bus, highest first
+func (r *ReportRepository) RevenueByBus(filter ReportFilter) ([]RevenueRow, error) {
+	var rows []RevenueRow
+	err := r.revenueEntries(filter).
+		Joins("JOIN buses ON buses.id = trips.bus_id").
+		Select("buses.id AS id, buses.plate_number AS name, " + revenueColumns).
+		Group("buses.id, buses.plate_number").
+		Order("revenue DESC, buses.id").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// RevenueByChannel returns the revenue of each sales channel the bookings were made
+// through, highest first
+func (r *ReportRepository) RevenueByChannel(filter ReportFilter) ([]RevenueRow, error) {
+	var rows []RevenueRow
+	err := r.revenueEntries(filter).
+		Select("bookings.channel AS name, " + revenueColumns).
+		Group("bookings.channel").
+		Order("revenue DESC").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// revenueColumns are the aggregates of a revenue report row
+const revenueColumns = "COALESCE(SUM(ledger_entries.credit - ledger_entries.debit), 0) AS revenue, COUNT(DISTINCT ledger_entries.booking_id) AS bookings"
+
+// revenueEntries selects the ledger lines posted to revenue accounts, with their booking and trip
+func (r *ReportRepository) revenueEntries(filter ReportFilter) *gorm.DB {
+	query := r.db.Model(&models.LedgerEntry{}).
+		Joins("JOIN bookings ON bookings.id = ledger_entries.booking_id").
+		Joins("JOIN trips ON trips.id = bookings.trip_id").
+		Where("ledger_entries.account IN ?", []models.LedgerAccount{models.AccountRevenue, models.AccountCancellationFees})
+	if filter.From != nil {
+		query = query.Where("ledger_entries.posted_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("ledger_entries.posted_at <= ?", *filter.To)
+	}
+	return r.tripFilter(query, filter)
+}
+
+// OccupancyByTrip returns the load factor of the trips departing in the period, in
+// departure order. A limit of 0 returns every trip.
+func (r *ReportRepository) OccupancyByTrip(filter ReportFilter, page, limit int) ([]OccupancyRow, int64, error) {
+	query := r.departures(filter)
+	var total int64
+	if err := query.Session(&gorm.Session{}).Distinct("trips.id").Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	query = query.Select("trips.id AS id, routes.origin || ' - ' || routes.destination AS name, trips.departure_time AS departure_time, 1 AS trips, " + seatColumns).
+		Group("trips.id, routes.origin, routes.destination, trips.departure_time").
+		Order("trips.departure_time, trips.id")
+	if limit > 0 {
+		query = query.Offset((page - 1) * limit).Limit(limit)
+	}
+	var rows []OccupancyRow
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, 0, err
+	}
+	return withLoadFactor(rows), total, nil
+}
+
+// OccupancyByRoute returns the load factor of the trips of each route departing in the period
+func (r *ReportRepository) OccupancyByRoute(filter ReportFilter) ([]OccupancyRow, error) {
+	var rows []OccupancyRow
+	err := r.departures(filter).
+		Select("routes.id AS id, routes.origin || ' - ' || routes.destination AS name, COUNT(DISTINCT trips.id) AS trips, " + seatColumns).
+		Group("routes.id, routes.origin, routes.destination").
+		Order("routes.id").
+		Scan(&rows).Error
+	return withLoadFactor(rows), err
+}
+
+// seatColumns are the aggregates of an occupancy report row
+var seatColumns = fmt.Sprintf("COUNT(seats.id) AS seats, COALESCE(SUM(CASE WHEN seats.status = '%s' THEN 1 ELSE 0 END), 0) AS booked", models.SeatStatusBooked)
+
+// departures selects the trips departing in the period with their route and seats
+func (r *ReportRepository) departures(filter ReportFilter) *gorm.DB {
+	query := r.db.Model(&models.Trip{}).
+		Joins("JOIN routes ON routes.id = trips.route_id").
+		Joins("LEFT JOIN seats ON seats.trip_id = trips.id AND seats.deleted_at IS NULL")
+	if filter.From != nil {
+		query = query.Where("trips.departure_time >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("trips.departure_time <= ?", *filter.To)
+	}
+	return r.tripFilter(query, filter)
+}
+
+func withLoadFactor(rows []OccupancyRow) []OccupancyRow {
+	for i := range rows {
+		if rows[i].Seats > 0 {
+			rows[i].LoadFactor = float64(rows[i].Booked) / float64(rows[i].Seats)
+		}
+	}
+	return rows
+}
+
+// BookingStats counts the bookings made in the period, how many were cancelled, and how
+// long before departure the bookings that were not cancelled were made on average
+func (r *ReportRepository) BookingStats(filter ReportFilter) (*BookingStats, error) {
+	query := r.db.Model(&models.Booking{}).
+		Joins("JOIN trips ON trips.id = bookings.trip_id")
+	if filter.From != nil {
+		query = query.Where("bookings.created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("bookings.created_at <= ?", *filter.To)
+	}
+
+	var stats BookingStats
+	err := r.tripFilter(query, filter).
+		Select(fmt.Sprintf(`COUNT(*) AS total,
+			COALESCE(SUM(CASE WHEN bookings.status = '%s' AND bookings.payment_status = '%s' THEN 1 ELSE 0 END), 0) AS confirmed,
+			COALESCE(SUM(CASE WHEN bookings.status = '%s' THEN 1 ELSE 0 END), 0) AS cancelled,
+			COALESCE(AVG(CASE WHEN bookings.status <> '%s' THEN %s END), 0) AS avg_lead_hours`,
+			models.BookingStatusConfirmed, models.PaymentStatusPaid,
+			models.BookingStatusCancelled,
+			models.BookingStatusCancelled, r.hoursBetween("bookings.created_at", "trips.departure_time"))).
+		Scan(&stats).Error
+	if err != nil {
+		return nil, err
+	}
+	if stats.Total > 0 {
+		stats.CancellationRate = float64(stats.Cancelled) / float64(stats.Total)
+	}
+	return &stats, nil
+}
+
+// tripFilter restricts a query joined with trips to a route or bus
+func (r *ReportRepository) tripFilter(query *gorm.DB, filter ReportFilter) *gorm.DB {
+	if filter.RouteID != 0 {
+		query = query.Where("trips.route_id = ?", filter.RouteID)
+	}
+	if filter.BusID != 0 {
+		query = query.Where("trips.bus_id = ?", filter.BusID)
+	}
+	return query
+}
+
+// periodExpr returns the SQL expression of the first day (YYYY-MM-DD) of the period a timestamp falls in
+func periodExpr(db *gorm.DB, column string, granularity ReportGranularity) string {
+	if db.Dialector.Name() == "postgres" {
+		return fmt.Sprintf("to_char(date_trunc('%s', %s), 'YYYY-MM-DD')", granularity, column)
+	}
+	switch granularity {
+	case ReportWeekly:
+		return fmt.Sprintf("date(%s, 'weekday 0', '-6 days')", column)
+	case ReportMonthly:
+		return fmt.Sprintf("strftime('%%Y-%%m-01', %s)", column)
+	default:
+		return fmt.Sprintf("strftime('%%Y-%%m-%%d', %s)", column)
+	}
+}
+
+// hoursBetween returns the SQL expression of the hours from one timestamp to another
+func (r *ReportRepository) hoursBetween(from, to string) string {
+	if r.db.Dialector.Name() == "postgres" {
+		return fmt.Sprintf("EXTRACT(EPOCH FROM (%s - %s)) / 3600", to, from)
+	}
+	return fmt.Sprintf("(julianday(%s) - julianday(%s)) * 24", to, from)
+}
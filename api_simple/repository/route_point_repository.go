@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"ticket-management/api_simple/models"
+
+	"gorm.io/gorm"
+)
+
+type RoutePointRepository struct {
+	*BaseRepository[models.RoutePoint]
+}
+
+func NewRoutePointRepository(db *gorm.DB) *RoutePointRepository {
+	return &RoutePointRepository{
+		BaseRepository: NewBaseRepository[models.RoutePoint](db),
+	}
+}
+
+// FindByRoute finds the points of a route ordered by time offset.
+// An empty pointType returns both pickup and drop-off points.
+func (r *RoutePointRepository) FindByRoute(routeID uint, pointType models.RoutePointType, activeOnly bool) ([]models.RoutePoint, error) {
+	var points []models.RoutePoint
+	query := r.db.Where("route_id = ?", routeID)
+	if pointType != "" {
+		query = query.Where("type = ?", pointType)
+	}
+	if activeOnly {
+		query = query.Where("is_active = ?", true)
+	}
+	err := query.Order("offset_minutes ASC, id ASC").Find(&points).Error
+	return points, err
+}
@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"ticket-management/api_simple/models"
 
 	"gorm.io/gorm"
@@ -20,7 +22,7 @@ func NewRouteRepository(db *gorm.DB) *RouteRepository {
 func (r *RouteRepository) FindPopularRoutes(limit int) ([]models.Route, error) {
 	var routes []models.Route
 	err := r.db.
-		Order("total_trips DESC").
+		Order("total_bookings DESC, load_factor DESC, total_trips DESC").
 		Where("is_active = ?", true).
 		Limit(limit).
 		Find(&routes).Error
@@ -37,43 +39,85 @@ func (r *RouteRepository) FindByOriginAndDestination(origin, destination string)
 	return &route, nil
 }
 
-// UpdateStats updates route statistics
-func (r *RouteRepository) UpdateStats(routeID uint) error {
-	// Get total trips
-	var totalTrips int64
-	err := r.db.Model(&models.Trip{}).
-		Where("route_id = ?", routeID).
-		Count(&totalTrips).Error
-	if err != nil {
-		return err
-	}
+// FindIDsByCorridor returns the IDs of the active routes from origin to destination
+func (r *RouteRepository) FindIDsByCorridor(origin, destination string) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&models.Route{}).
+		Where("origin = ? AND destination = ? AND is_active = ?", origin, destination, true).
+		Pluck("id", &ids).Error
+	return ids, err
+}
 
-	// Get upcoming trips
-	var upcomingTrips int64
-	err = r.db.Model(&models.Trip{}).
-		Where("route_id = ? AND departure_time > NOW()", routeID).
-		Count(&upcomingTrips).Error
-	if err != nil {
-		return err
-	}
+// FindIDs returns the IDs of all routes
+func (r *RouteRepository) FindIDs() ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&models.Route{}).Pluck("id", &ids).Error
+	return ids, err
+}
 
-	// Get min and max prices
-	var minPrice, maxPrice float64
-	err = r.db.Model(&models.Trip{}).
-		Select("MIN(price) as min_price, MAX(price) as max_price").
-		Where("route_id = ?", routeID).
-		Row().Scan(&minPrice, &maxPrice)
+// UpdateStats recomputes the trip, price, booking and occupancy statistics of a route
+func (r *RouteRepository) UpdateStats(routeID uint, now time.Time) error {
+	stats, err := tripStats(r.db, "route_id", routeID, now)
 	if err != nil {
 		return err
 	}
 
-	// Update route stats
 	return r.db.Model(&models.Route{}).
 		Where("id = ?", routeID).
-		Updates(map[string]interface{}{
-			"total_trips":    totalTrips,
-			"upcoming_trips": upcomingTrips,
-			"min_price":      minPrice,
-			"max_price":      maxPrice,
+		UpdateColumns(map[string]interface{}{
+			"total_trips":      stats.TotalTrips,
+			"upcoming_trips":   stats.UpcomingTrips,
+			"min_price":        stats.MinPrice,
+			"max_price":        stats.MaxPrice,
+			"total_bookings":   stats.TotalBookings,
+			"total_seats":      stats.TotalSeats,
+			"booked_seats":     stats.BookedSeats,
+			"load_factor":      stats.LoadFactor(),
+			"stats_updated_at": now,
 		}).Error
 }
+
+// tripAggregate holds the statistics of the trips of a route or bus
+type tripAggregate struct {
+	TotalTrips    int64
+	UpcomingTrips int64
+	MinPrice      models.Money
+	MaxPrice      models.Money
+	TotalSeats    int64
+	BookedSeats   int64
+	TotalBookings int64
+}
+
+func (a tripAggregate) LoadFactor() float64 {
+	if a.TotalSeats == 0 {
+		return 0
+	}
+	return float64(a.BookedSeats) / float64(a.TotalSeats)
+}
+
+// tripStats aggregates the trips whose column (route_id or bus_id) is id, and the
+// bookings on them that were not cancelled
+func tripStats(db *gorm.DB, column string, id uint, now time.Time) (*tripAggregate, error) {
+	var stats tripAggregate
+	err := db.Model(&models.Trip{}).
+		Select(`COUNT(*) AS total_trips,
+			COALESCE(SUM(CASE WHEN departure_time > ? AND is_active = ? AND is_completed = ? THEN 1 ELSE 0 END), 0) AS upcoming_trips,
+			COALESCE(MIN(price), 0) AS min_price,
+			COALESCE(MAX(price), 0) AS max_price,
+			COALESCE(SUM(total_seats), 0) AS total_seats,
+			COALESCE(SUM(booked_seats), 0) AS booked_seats`, now, true, false).
+		Where("trips."+column+" = ?", id).
+		Scan(&stats).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Model(&models.Booking{}).
+		Joins("JOIN trips ON trips.id = bookings.trip_id AND trips.deleted_at IS NULL").
+		Where("trips."+column+" = ? AND bookings.status <> ?", id, models.BookingStatusCancelled).
+		Count(&stats.TotalBookings).Error
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"time"
+
+	"ticket-management/api_simple/models"
+
+	"gorm.io/gorm"
+)
+
+type ScheduledReportRepository struct {
+	*BaseRepository[models.ScheduledReport]
+}
+
+func NewScheduledReportRepository(db *gorm.DB) *ScheduledReportRepository {
+	return &ScheduledReportRepository{
+		BaseRepository: NewBaseRepository[models.ScheduledReport](db),
+	}
+}
+
+// FindDue finds the active reports whose next delivery is at or before now
+func (r *ScheduledReportRepository) FindDue(now time.Time) ([]models.ScheduledReport, error) {
+	var reports []models.ScheduledReport
+	err := r.db.Where("is_active = ? AND next_run_at <= ?", true, now).
+		Order("next_run_at ASC").
+		Find(&reports).Error
+	return reports, err
+}
+
+// RecordRun saves the outcome of a delivery and when the report is due next
+func (r *ScheduledReportRepository) RecordRun(report *models.ScheduledReport) error {
+	return r.db.Model(report).Select("last_run_at", "last_error", "next_run_at").Updates(report).Error
+}
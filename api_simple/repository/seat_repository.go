@@ -6,6 +6,7 @@ import (
 	"ticket-management/api_simple/models"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type SeatRepository struct {
@@ -21,6 +22,11 @@ func (r *SeatRepository) Create(seat *models.Seat) error {
 	return r.db.Create(seat).Error
 }
 
+// CreateBatch creates the seats of a trip
+func (r *SeatRepository) CreateBatch(seats []models.Seat) error {
+	return r.db.Create(&seats).Error
+}
+
 // FindByID finds a seat by ID
 func (r *SeatRepository) FindByID(id uint) (*models.Seat, error) {
 	var seat models.Seat
@@ -76,15 +82,22 @@ func (r *SeatRepository) UnlockSeat(id uint) error {
 	}).Error
 }
 
-// UnlockExpiredSeats unlocks all seats that have expired locks
-func (r *SeatRepository) UnlockExpiredSeats() error {
-	return r.db.Model(&models.Seat{}).
+// UnlockExpiredSeats unlocks all seats that have expired locks and returns them.
+// Only the rows the update changed are returned, so a seat booked in the meantime
+// is never reported as available.
+func (r *SeatRepository) UnlockExpiredSeats() ([]models.Seat, error) {
+	var seats []models.Seat
+	err := r.db.Model(&seats).Clauses(clause.Returning{}).
 		Where("status = ? AND locked_until < ?", models.SeatStatusLocked, time.Now()).
 		Updates(map[string]interface{}{
 			"status":       models.SeatStatusAvailable,
 			"locked_until": nil,
 			"locked_by":    nil,
 		}).Error
+	if err != nil {
+		return nil, err
+	}
+	return seats, nil
 }
 
 // CountByStatus counts seats by status for a trip
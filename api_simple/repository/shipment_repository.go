@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"ticket-management/api_simple/models"
+
+	"gorm.io/gorm"
+)
+
+type ShipmentRepository struct {
+	db *gorm.DB
+}
+
+func NewShipmentRepository(db *gorm.DB) *ShipmentRepository {
+	return &ShipmentRepository{db: db}
+}
+
+// Create creates a shipment
+func (r *ShipmentRepository) Create(shipment *models.Shipment) error {
+	return r.db.Create(shipment).Error
+}
+
+// FindByID finds a shipment by ID with its trip
+func (r *ShipmentRepository) FindByID(id uint) (*models.Shipment, error) {
+	var shipment models.Shipment
+	err := r.db.Preload("Trip.Route").Preload("Trip.Bus").First(&shipment, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &shipment, nil
+}
+
+// FindByCode finds a shipment by tracking code with its trip
+func (r *ShipmentRepository) FindByCode(code string) (*models.Shipment, error) {
+	var shipment models.Shipment
+	err := r.db.Preload("Trip.Route").Where("tracking_code = ?", code).First(&shipment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &shipment, nil
+}
+
+// FindAll finds shipments with optional filters, newest first
+func (r *ShipmentRepository) FindAll(filters map[string]interface{}, page, limit int) ([]models.Shipment, int64, error) {
+	var shipments []models.Shipment
+	var total int64
+
+	query := r.db.Model(&models.Shipment{})
+	if len(filters) > 0 {
+		query = query.Where(filters)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Preload("Trip.Route").
+		Order("created_at DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&shipments).Error
+	return shipments, total, err
+}
+
+// FindByTrip finds the shipments carried on a trip, excluding cancelled ones
+func (r *ShipmentRepository) FindByTrip(tripID uint) ([]models.Shipment, error) {
+	var shipments []models.Shipment
+	err := r.db.Where("trip_id = ? AND status != ?", tripID, models.ShipmentStatusCancelled).
+		Order("id ASC").
+		Find(&shipments).Error
+	return shipments, err
+}
+
+// SumLoadByTrip sums the weight (kg) and volume (m³) of the non-cancelled shipments of a trip
+func (r *ShipmentRepository) SumLoadByTrip(tripID uint) (float64, float64, error) {
+	var result struct {
+		WeightKg float64
+		VolumeM3 float64
+	}
+	err := r.db.Model(&models.Shipment{}).
+		Select("COALESCE(SUM(weight_kg), 0) AS weight_kg, COALESCE(SUM(length_cm * width_cm * height_cm), 0) / 1000000.0 AS volume_m3").
+		Where("trip_id = ? AND status != ?", tripID, models.ShipmentStatusCancelled).
+		Scan(&result).Error
+	return result.WeightKg, result.VolumeM3, err
+}
+
+// UpdateStatus updates the transport and payment status of a shipment
+func (r *ShipmentRepository) UpdateStatus(id uint, status models.ShipmentStatus, paymentStatus models.PaymentStatus) error {
+	return r.db.Model(&models.Shipment{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":         status,
+		"payment_status": paymentStatus,
+	}).Error
+}
+
+// CreateEvent records a status change of a shipment
+func (r *ShipmentRepository) CreateEvent(event *models.ShipmentEvent) error {
+	return r.db.Create(event).Error
+}
+
+// FindEvents finds the status history of a shipment in order
+func (r *ShipmentRepository) FindEvents(shipmentID uint) ([]models.ShipmentEvent, error) {
+	var events []models.ShipmentEvent
+	err := r.db.Where("shipment_id = ?", shipmentID).Order("at ASC, id ASC").Find(&events).Error
+	return events, err
+}
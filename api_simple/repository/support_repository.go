@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"time"
+
+	"ticket-management/api_simple/models"
+
+	"gorm.io/gorm"
+)
+
+// SupportFilter narrows a list of support tickets
+type SupportFilter struct {
+	Status     models.SupportStatus
+	Category   models.SupportCategory
+	UserID     *uint // Yêu cầu của một khách hàng
+	AssignedTo *uint // Yêu cầu của một nhân viên
+	Unassigned bool  // Yêu cầu chưa có người phụ trách
+	Breached   bool  // Yêu cầu đã trễ hạn SLA
+}
+
+type SupportRepository struct {
+	db *gorm.DB
+}
+
+func NewSupportRepository(db *gorm.DB) *SupportRepository {
+	return &SupportRepository{db: db}
+}
+
+// Create creates a support ticket
+func (r *SupportRepository) Create(ticket *models.SupportTicket) error {
+	return r.db.Create(ticket).Error
+}
+
+// FindByID finds a support ticket by ID with its customer, booking and assignee
+func (r *SupportRepository) FindByID(id uint) (*models.SupportTicket, error) {
+	var ticket models.SupportTicket
+	err := r.preload(r.db).First(&ticket, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &ticket, nil
+}
+
+// FindByCode finds a support ticket by code with its customer, booking and assignee
+func (r *SupportRepository) FindByCode(code string) (*models.SupportTicket, error) {
+	var ticket models.SupportTicket
+	err := r.preload(r.db).Where("code = ?", code).First(&ticket).Error
+	if err != nil {
+		return nil, err
+	}
+	return &ticket, nil
+}
+
+// FindAll finds a page of the tickets matching filter, most recently active first
+func (r *SupportRepository) FindAll(filter SupportFilter, page, limit int) ([]models.SupportTicket, int64, error) {
+	var tickets []models.SupportTicket
+	var total int64
+
+	query := r.db.Model(&models.SupportTicket{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Category != "" {
+		query = query.Where("category = ?", filter.Category)
+	}
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+	if filter.AssignedTo != nil {
+		query = query.Where("assigned_to = ?", *filter.AssignedTo)
+	}
+	if filter.Unassigned {
+		query = query.Where("assigned_to IS NULL")
+	}
+	if filter.Breached {
+		query = query.Where("first_response_breached OR resolution_breached")
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Preload("Assignee").
+		Order("last_message_at DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&tickets).Error
+	return tickets, total, err
+}
+
+// CountByStatus counts the tickets in each status
+func (r *SupportRepository) CountByStatus() (map[models.SupportStatus]int64, error) {
+	var rows []struct {
+		Status models.SupportStatus
+		Count  int64
+	}
+	err := r.db.Model(&models.SupportTicket{}).
+		Select("status, COUNT(*) AS count").
+		Group("status").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[models.SupportStatus]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
+// Update saves a support ticket
+func (r *SupportRepository) Update(ticket *models.SupportTicket) error {
+	return r.db.Omit("User", "Booking", "Assignee", "Messages").Save(ticket).Error
+}
+
+// CreateMessage adds a message to the thread of a ticket
+func (r *SupportRepository) CreateMessage(message *models.SupportMessage) error {
+	return r.db.Omit("Attachments").Create(message).Error
+}
+
+// CreateAttachment records a file attached to a message
+func (r *SupportRepository) CreateAttachment(attachment *models.SupportAttachment) error {
+	return r.db.Create(attachment).Error
+}
+
+// FindMessages finds the thread of a ticket in order with its attachments. Internal notes
+// are only included when internal is set.
+func (r *SupportRepository) FindMessages(ticketID uint, internal bool) ([]models.SupportMessage, error) {
+	var messages []models.SupportMessage
+	query := r.db.Where("ticket_id = ?", ticketID)
+	if !internal {
+		query = query.Where("internal = ?", false)
+	}
+	err := query.Preload("Attachments", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id ASC")
+	}).Order("created_at ASC, id ASC").Find(&messages).Error
+	return messages, err
+}
+
+// FindAttachment finds a file attached to a ticket. Files of internal notes are only
+// found when internal is set.
+func (r *SupportRepository) FindAttachment(ticketID, id uint, internal bool) (*models.SupportAttachment, error) {
+	var attachment models.SupportAttachment
+	query := r.db.Where("support_attachments.ticket_id = ? AND support_attachments.id = ?", ticketID, id)
+	if !internal {
+		query = query.Joins("JOIN support_messages ON support_messages.id = support_attachments.message_id").
+			Where("support_messages.internal = ?", false)
+	}
+	if err := query.First(&attachment).Error; err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// FindOverdue finds the open or pending tickets that have passed a deadline without the
+// breach being recorded yet
+func (r *SupportRepository) FindOverdue(now time.Time) ([]models.SupportTicket, error) {
+	var tickets []models.SupportTicket
+	err := r.db.Where("status IN ?", []models.SupportStatus{models.SupportStatusOpen, models.SupportStatusPending}).
+		Where("(first_responded_at IS NULL AND NOT first_response_breached AND first_response_due_at < ?) OR (NOT resolution_breached AND resolution_due_at < ?)", now, now).
+		Order("id ASC").
+		Find(&tickets).Error
+	return tickets, err
+}
+
+// UpdateBreaches saves the SLA breach flags of a ticket, leaving changes staff make at the
+// same time untouched
+func (r *SupportRepository) UpdateBreaches(ticket *models.SupportTicket) error {
+	return r.db.Model(ticket).Select("first_response_breached", "resolution_breached").Updates(ticket).Error
+}
+
+// CloseResolved closes the tickets resolved before the given time
+func (r *SupportRepository) CloseResolved(before, now time.Time) (int64, error) {
+	result := r.db.Model(&models.SupportTicket{}).
+		Where("status = ? AND resolved_at < ?", models.SupportStatusResolved, before).
+		Updates(map[string]interface{}{"status": models.SupportStatusClosed, "closed_at": now})
+	return result.RowsAffected, result.Error
+}
+
+// preload loads the customer, booking and assignee of tickets
+func (r *SupportRepository) preload(db *gorm.DB) *gorm.DB {
+	return db.Preload("User").Preload("Booking.Trip.Route").Preload("Assignee")
+}
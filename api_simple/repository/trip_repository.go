@@ -92,6 +92,13 @@ func (r *TripRepository) SearchTrips(filters map[string]interface{}, fromDate, t
 	return trips, err
 }
 
+// Count counts all trips
+func (r *TripRepository) Count() (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Trip{}).Count(&count).Error
+	return count, err
+}
+
 // CountTripsByRoute counts trips by route
 func (r *TripRepository) CountTripsByRoute(routeID uint) (int64, error) {
 	var count int64
@@ -110,8 +117,8 @@ func (r *TripRepository) CountUpcomingTripsByRoute(routeID uint) (int64, error)
 }
 
 // GetTripPriceRange gets min and max price for a route
-func (r *TripRepository) GetTripPriceRange(routeID uint) (float64, float64, error) {
-	var minPrice, maxPrice float64
+func (r *TripRepository) GetTripPriceRange(routeID uint) (models.Money, models.Money, error) {
+	var minPrice, maxPrice models.Money
 	err := r.db.Model(&models.Trip{}).
 		Where("route_id = ?", routeID).
 		Select("COALESCE(MIN(price), 0) as min_price, COALESCE(MAX(price), 0) as max_price").
@@ -120,6 +127,33 @@ func (r *TripRepository) GetTripPriceRange(routeID uint) (float64, float64, erro
 	return minPrice, maxPrice, err
 }
 
+// FindWithDeleted returns the trips with the given IDs, including deleted ones
+func (r *TripRepository) FindWithDeleted(ids []uint) ([]models.Trip, error) {
+	var trips []models.Trip
+	err := r.db.Unscoped().Where("id IN ?", ids).Find(&trips).Error
+	return trips, err
+}
+
+// UpdateSeatCounts recounts the seats and booked seats of the given trips from their
+// seat maps. Trips without a seat map keep the seat count of their bus.
+func (r *TripRepository) UpdateSeatCounts(ids []uint) error {
+	return r.updateSeatCounts(r.db.Where("id IN ?", ids))
+}
+
+// UpdateOpenSeatCounts recounts the seats and booked seats of every trip not completed yet
+func (r *TripRepository) UpdateOpenSeatCounts() error {
+	return r.updateSeatCounts(r.db.Where("is_completed = ?", false))
+}
+
+func (r *TripRepository) updateSeatCounts(query *gorm.DB) error {
+	return query.Model(&models.Trip{}).
+		Where("EXISTS (SELECT 1 FROM seats WHERE seats.trip_id = trips.id AND seats.deleted_at IS NULL)").
+		UpdateColumns(map[string]interface{}{
+			"total_seats":  gorm.Expr("(SELECT COUNT(*) FROM seats WHERE seats.trip_id = trips.id AND seats.deleted_at IS NULL)"),
+			"booked_seats": gorm.Expr("(SELECT COUNT(*) FROM seats WHERE seats.trip_id = trips.id AND seats.deleted_at IS NULL AND seats.status = ?)", models.SeatStatusBooked),
+		}).Error
+}
+
 // UpdateTripStatus updates trip status based on time
 func (r *TripRepository) UpdateTripStatus() error {
 	now := time.Now()
@@ -148,16 +182,9 @@ func (r *TripRepository) GetTripStatistics(tripID uint) (map[string]interface{},
 	}
 	stats["total_bookings"] = totalBookings
 
-	// Get total revenue
-	var totalRevenue float64
-	if err := r.db.Model(&models.Booking{}).
-		Where("trip_id = ? AND status = ? AND payment_status = ?",
-			tripID,
-			models.BookingStatusConfirmed,
-			models.PaymentStatusPaid).
-		Select("COALESCE(SUM(total_amount), 0)").
-		Row().
-		Scan(&totalRevenue); err != nil {
+	// Get total revenue from the ledger
+	totalRevenue, err := NewLedgerRepository(r.db).Revenue(LedgerFilter{TripID: tripID})
+	if err != nil {
 		return nil, err
 	}
 	stats["total_revenue"] = totalRevenue
@@ -185,6 +212,31 @@ func (r *TripRepository) GetTripsByDriver(driverID uint) ([]models.Trip, error)
 	return trips, err
 }
 
+// FindScheduledForBus finds active trips of a bus departing within [from, to]
+func (r *TripRepository) FindScheduledForBus(busID uint, from, to time.Time, excludeTripID uint) ([]models.Trip, error) {
+	return r.FindScheduled(map[string]interface{}{"bus_id": busID}, from, to, excludeTripID)
+}
+
+// FindScheduledForDriver finds active trips of a driver departing within [from, to]
+func (r *TripRepository) FindScheduledForDriver(driverID uint, from, to time.Time, excludeTripID uint) ([]models.Trip, error) {
+	return r.FindScheduled(map[string]interface{}{"driver_id": driverID}, from, to, excludeTripID)
+}
+
+// FindScheduled finds active trips departing within [from, to] ordered by departure time
+func (r *TripRepository) FindScheduled(filters map[string]interface{}, from, to time.Time, excludeTripID uint) ([]models.Trip, error) {
+	var trips []models.Trip
+	query := r.db.Preload("Route").Preload("Bus").
+		Where("is_active = ? AND departure_time BETWEEN ? AND ?", true, from, to)
+	if len(filters) > 0 {
+		query = query.Where(filters)
+	}
+	if excludeTripID != 0 {
+		query = query.Where("id <> ?", excludeTripID)
+	}
+	err := query.Order("departure_time ASC").Find(&trips).Error
+	return trips, err
+}
+
 // GetTripsByBus gets trips assigned to a bus
 func (r *TripRepository) GetTripsByBus(busID uint) ([]models.Trip, error) {
 	var trips []models.Trip
@@ -16,6 +16,11 @@ func NewUserRepository(db *gorm.DB) *UserRepository {
 	}
 }
 
+// ForOperator restricts the repository to the staff accounts of one operator
+func (r *UserRepository) ForOperator(operatorID uint) *UserRepository {
+	return NewUserRepository(r.db.Where("users.operator_id = ?", operatorID).Session(&gorm.Session{}))
+}
+
 // FindByPhone finds a user by phone number
 func (r *UserRepository) FindByPhone(phone string) (*models.User, error) {
 	return r.FindOne(map[string]interface{}{"phone": phone})
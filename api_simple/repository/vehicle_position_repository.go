@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"time"
+
+	"ticket-management/api_simple/models"
+
+	"gorm.io/gorm"
+)
+
+// VehiclePositionRepository stores the GPS track of trips
+type VehiclePositionRepository struct {
+	db *gorm.DB
+}
+
+func NewVehiclePositionRepository(db *gorm.DB) *VehiclePositionRepository {
+	return &VehiclePositionRepository{db: db}
+}
+
+// CreateBatch stores a batch of positions
+func (r *VehiclePositionRepository) CreateBatch(positions []models.VehiclePosition) error {
+	if len(positions) == 0 {
+		return nil
+	}
+	return r.db.Create(&positions).Error
+}
+
+// FindLatest finds the most recently recorded position of a trip
+func (r *VehiclePositionRepository) FindLatest(tripID uint) (*models.VehiclePosition, error) {
+	var position models.VehiclePosition
+	err := r.db.Where("trip_id = ?", tripID).
+		Order("recorded_at DESC, id DESC").
+		First(&position).Error
+	if err != nil {
+		return nil, err
+	}
+	return &position, nil
+}
+
+// FindByTrip finds the positions of a trip recorded after since, oldest first
+func (r *VehiclePositionRepository) FindByTrip(tripID uint, since time.Time) ([]models.VehiclePosition, error) {
+	var positions []models.VehiclePosition
+	err := r.db.Where("trip_id = ? AND recorded_at >= ?", tripID, since).
+		Order("recorded_at ASC, id ASC").
+		Find(&positions).Error
+	return positions, err
+}
@@ -0,0 +1,28 @@
+package seeders
+
+import (
+	"ticket-management/api_simple/config"
+	"ticket-management/api_simple/models"
+)
+
+// seedHolidays adds the public holidays used by demand forecasting. The same name is
+// used every year so the effect of a holiday is learned from previous years.
+func seedHolidays() error {
+	holidays := []models.Holiday{
+		{Name: "Tết Dương lịch", StartDate: "2026-01-01", EndDate: "2026-01-04", Uplift: 1.5},
+		{Name: "Tết Nguyên Đán", StartDate: "2026-02-14", EndDate: "2026-02-22", Uplift: 2},
+		{Name: "Giỗ Tổ Hùng Vương", StartDate: "2026-04-25", EndDate: "2026-04-27", Uplift: 1.5},
+		{Name: "30/4 - 1/5", StartDate: "2026-04-30", EndDate: "2026-05-03", Uplift: 1.8},
+		{Name: "Quốc khánh 2/9", StartDate: "2026-08-29", EndDate: "2026-09-02", Uplift: 1.8},
+		{Name: "Tết Dương lịch", StartDate: "2027-01-01", EndDate: "2027-01-03", Uplift: 1.5},
+		{Name: "Tết Nguyên Đán", StartDate: "2027-02-03", EndDate: "2027-02-11", Uplift: 2},
+	}
+
+	for _, holiday := range holidays {
+		if err := config.DB.Create(&holiday).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package seeders
+
+import (
+	"ticket-management/api_simple/config"
+	"ticket-management/api_simple/models"
+)
+
+func seedOperators() error {
+	operator := models.Operator{
+		Code:                 "default",
+		Name:                 "Nhà xe Mặc định",
+		Phone:                "19001234",
+		Email:                "lienhe@nhaxe.vn",
+		Address:              "01 Đường số 1, Quận 1, TP. Hồ Chí Minh",
+		TaxCode:              "0312345678",
+		IsActive:             true,
+		PrimaryColor:         "#1E88E5",
+		TicketFooter:         "Cảm ơn quý khách đã đi xe!",
+		FullRefundHours:      24,
+		PartialRefundHours:   4,
+		PartialRefundPercent: 50,
+		PaymentAccounts: []models.OperatorPaymentAccount{
+			{
+				Method:        models.PaymentAccountBank,
+				Provider:      "Vietcombank",
+				AccountNumber: "0011001234567",
+				AccountName:   "NHA XE MAC DINH",
+				IsDefault:     true,
+				IsActive:      true,
+			},
+		},
+	}
+
+	return config.DB.Create(&operator).Error
+}
+
+// assignDefaultOperator gives the seeded fleet, trips, bookings and staff accounts to the default operator
+func assignDefaultOperator() error {
+	var operator models.Operator
+	if err := config.DB.Where("code = ?", "default").First(&operator).Error; err != nil {
+		return err
+	}
+
+	for _, table := range []string{"routes", "buses", "trips", "bookings"} {
+		if err := config.DB.Exec("UPDATE "+table+" SET operator_id = ?", operator.ID).Error; err != nil {
+			return err
+		}
+	}
+
+	return config.DB.Model(&models.User{}).
+		Where("role IN ?", []models.Role{models.RoleAdmin, models.RoleStaff, models.RoleDriver}).
+		Update("operator_id", operator.ID).Error
+}
@@ -7,7 +7,7 @@ import (
 	"gorm.io/gorm"
 )
 
-func SeedSeats(db *gorm.DB, tripID uint, basePrice float64) error {
+func SeedSeats(db *gorm.DB, tripID uint, basePrice models.Money) error {
 	// Create seats for floor 1 (downstairs)
 	for i := 1; i <= 20; i++ {
 		seatNumber := fmt.Sprintf("A%02d", i)
@@ -17,7 +17,7 @@ func SeedSeats(db *gorm.DB, tripID uint, basePrice float64) error {
 		// Special seats (first 4 seats)
 		if i <= 4 {
 			seatType = models.SeatTypeSpecial
-			price = basePrice * 1.2 // +20% for special
+			price = basePrice.ScalePrice(1.2) // +20% for special
 		}
 
 		// Double seats (odd numbers)
@@ -43,12 +43,12 @@ func SeedSeats(db *gorm.DB, tripID uint, basePrice float64) error {
 	for i := 1; i <= 20; i++ {
 		seatNumber := fmt.Sprintf("B%02d", i)
 		seatType := models.SeatTypeSingle
-		price := basePrice * 1.1 // +10% for upstairs
+		price := basePrice.ScalePrice(1.1) // +10% for upstairs
 
 		// Special seats (first 4 seats)
 		if i <= 4 {
 			seatType = models.SeatTypeSpecial
-			price = basePrice * 1.3 // +30% for special upstairs
+			price = basePrice.ScalePrice(1.3) // +30% for special upstairs
 		}
 
 		// Double seats (odd numbers)
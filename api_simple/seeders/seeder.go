@@ -8,12 +8,28 @@ import (
 
 func Seed() {
 	// Clean up old data
+	config.DB.Exec("DELETE FROM support_attachments")
+	config.DB.Exec("DELETE FROM support_messages")
+	config.DB.Exec("DELETE FROM support_tickets")
+	config.DB.Exec("DELETE FROM scheduled_reports")
+	config.DB.Exec("DELETE FROM trip_forecasts")
+	config.DB.Exec("DELETE FROM holidays")
+	config.DB.Exec("DELETE FROM trip_searches")
+	config.DB.Exec("DELETE FROM settlement_entries")
+	config.DB.Exec("DELETE FROM settlement_reports")
+	config.DB.Exec("DELETE FROM ledger_entries")
+	config.DB.Exec("DELETE FROM ledger_transactions")
+	config.DB.Exec("DELETE FROM invoice_lines")
+	config.DB.Exec("DELETE FROM invoices")
+	config.DB.Exec("DELETE FROM invoice_series")
 	config.DB.Exec("DELETE FROM seats")
 	config.DB.Exec("DELETE FROM bookings")
 	config.DB.Exec("DELETE FROM trips")
 	config.DB.Exec("DELETE FROM buses")
 	config.DB.Exec("DELETE FROM routes")
 	config.DB.Exec("DELETE FROM users")
+	config.DB.Exec("DELETE FROM operator_payment_accounts")
+	config.DB.Exec("DELETE FROM operators")
 
 	// Reset auto increment
 	config.DB.Exec("ALTER SEQUENCE users_id_seq RESTART WITH 1")
@@ -22,6 +38,12 @@ func Seed() {
 	config.DB.Exec("ALTER SEQUENCE trips_id_seq RESTART WITH 1")
 	config.DB.Exec("ALTER SEQUENCE seats_id_seq RESTART WITH 1")
 	config.DB.Exec("ALTER SEQUENCE bookings_id_seq RESTART WITH 1")
+	config.DB.Exec("ALTER SEQUENCE operators_id_seq RESTART WITH 1")
+
+	// Seed operators
+	if err := seedOperators(); err != nil {
+		log.Fatal("Error seeding operators:", err)
+	}
 
 	// Seed users
 	if err := seedUsers(); err != nil {
@@ -38,6 +60,11 @@ func Seed() {
 		log.Fatal("Error seeding buses:", err)
 	}
 
+	// Seed holidays
+	if err := seedHolidays(); err != nil {
+		log.Fatal("Error seeding holidays:", err)
+	}
+
 	// Seed trips
 	if err := seedTrips(); err != nil {
 		log.Fatal("Error seeding trips:", err)
@@ -47,6 +74,10 @@ func Seed() {
 		log.Fatal("Error seeding trips:", err)
 	}
 
+	if err := assignDefaultOperator(); err != nil {
+		log.Fatal("Error assigning operator:", err)
+	}
+
 	// Get all trips
 	var trips []models.Trip
 	if err := config.DB.Find(&trips).Error; err != nil {
@@ -31,6 +31,12 @@ func seedUsers() error {
 			Name:     "Driver",
 			Role:     models.RoleDriver,
 		},
+		{
+			Phone:    "0987654320",
+			Password: "Password123!",
+			Name:     "Super Admin",
+			Role:     models.RoleSuperAdmin,
+		},
 	}
 
 	for _, user := range users {
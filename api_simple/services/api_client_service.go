@@ -0,0 +1,113 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	APIKeyPrefix    = "tmk_" // Prefix of every partner API key
+	apiKeyLength    = 40
+	keyPrefixLength = 12
+)
+
+var (
+	ErrAPIKeyInvalid    = errors.New("api key is invalid")
+	ErrAPIClientRevoked = errors.New("api client is revoked")
+)
+
+// APIClientService issues, rotates, revokes and authenticates partner API keys.
+// Only a SHA-256 hash of each key is stored; the plain key is shown once.
+type APIClientService struct {
+	repo *repository.APIClientRepository
+}
+
+func NewAPIClientService(repo *repository.APIClientRepository) *APIClientService {
+	return &APIClientService{repo: repo}
+}
+
+// Issue creates a client and returns its plain API key
+func (s *APIClientService) Issue(client *models.APIClient) (string, error) {
+	if err := client.Validate(); err != nil {
+		return "", err
+	}
+
+	key := assignAPIKey(client)
+	if err := s.repo.Create(client); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// Rotate replaces the client's key and returns the new plain key
+func (s *APIClientService) Rotate(client *models.APIClient) (string, error) {
+	if client.IsRevoked() {
+		return "", ErrAPIClientRevoked
+	}
+
+	key := assignAPIKey(client)
+	if err := s.repo.Update(client); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// Revoke permanently disables the client's key
+func (s *APIClientService) Revoke(client *models.APIClient) error {
+	if client.IsRevoked() {
+		return nil
+	}
+	now := time.Now()
+	client.RevokedAt = &now
+	return s.repo.Update(client)
+}
+
+// Authenticate resolves the client owning an API key
+func (s *APIClientService) Authenticate(key string) (*models.APIClient, error) {
+	if len(key) != len(APIKeyPrefix)+apiKeyLength || key[:len(APIKeyPrefix)] != APIKeyPrefix {
+		return nil, ErrAPIKeyInvalid
+	}
+
+	client, err := s.repo.FindByKeyHash(HashAPIKey(key))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAPIKeyInvalid
+		}
+		return nil, err
+	}
+	if client.IsRevoked() {
+		return nil, ErrAPIClientRevoked
+	}
+
+	// Usage timestamp is informational, so only refresh it once a minute
+	now := time.Now()
+	if client.LastUsedAt == nil || now.Sub(*client.LastUsedAt) > time.Minute {
+		client.LastUsedAt = &now
+		if err := s.repo.Update(client); err != nil {
+			return nil, err
+		}
+	}
+
+	return client, nil
+}
+
+// HashAPIKey returns the stored form of an API key
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func assignAPIKey(client *models.APIClient) string {
+	key := APIKeyPrefix + utils.GenerateRandomString(apiKeyLength)
+	client.KeyPrefix = key[:keyPrefixLength]
+	client.KeyHash = HashAPIKey(key)
+	return key
+}
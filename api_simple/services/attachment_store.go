@@ -0,0 +1,24 @@
+package services
+
+import (
+	"io"
+	"os"
+
+	"ticket-management/api_simple/providers"
+)
+
+// AttachmentStore keeps the files customers and staff attach to support tickets
+type AttachmentStore interface {
+	Save(key string, r io.Reader) error
+	Open(key string) (io.ReadCloser, error)
+}
+
+// NewAttachmentStoreFromEnv returns the attachment directory at SUPPORT_ATTACHMENT_DIR,
+// uploads/support by default
+func NewAttachmentStoreFromEnv() AttachmentStore {
+	dir := os.Getenv("SUPPORT_ATTACHMENT_DIR")
+	if dir == "" {
+		dir = "uploads/support"
+	}
+	return providers.NewDirAttachmentStore(dir)
+}
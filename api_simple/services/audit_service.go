@@ -0,0 +1,84 @@
+package services
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+)
+
+// auditIgnoredFields change on every save and would only add noise to diffs
+var auditIgnoredFields = map[string]bool{
+	"UpdatedAt":  true,
+	"updated_at": true,
+}
+
+// AuditService writes entries to the append-only audit log
+type AuditService struct {
+	repo *repository.AuditLogRepository
+}
+
+func NewAuditService(repo *repository.AuditLogRepository) *AuditService {
+	return &AuditService{repo: repo}
+}
+
+// Record stores an audit entry with the diff between before and after.
+// Either snapshot may be nil for creations and deletions.
+func (s *AuditService) Record(entry *models.AuditLog, before interface{}, after interface{}) error {
+	changes, err := DiffSnapshots(before, after)
+	if err != nil {
+		return err
+	}
+	if len(changes) > 0 {
+		entry.Changes = changes
+	}
+	return s.repo.Create(entry)
+}
+
+// DiffSnapshots compares the JSON form of two snapshots and returns the
+// top-level fields whose values differ. Fields hidden from JSON are never included.
+func DiffSnapshots(before interface{}, after interface{}) (models.AuditChanges, error) {
+	beforeFields, err := snapshotFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := snapshotFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := models.AuditChanges{}
+	for key, from := range beforeFields {
+		to, ok := afterFields[key]
+		if !ok || !reflect.DeepEqual(from, to) {
+			changes[key] = models.AuditChange{From: from, To: to}
+		}
+	}
+	for key, to := range afterFields {
+		if _, ok := beforeFields[key]; !ok {
+			changes[key] = models.AuditChange{From: nil, To: to}
+		}
+	}
+
+	for key := range auditIgnoredFields {
+		delete(changes, key)
+	}
+	return changes, nil
+}
+
+func snapshotFields(snapshot interface{}) (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+	if snapshot == nil || reflect.ValueOf(snapshot).Kind() == reflect.Ptr && reflect.ValueOf(snapshot).IsNil() {
+		return fields, nil
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
@@ -0,0 +1,66 @@
+package services
+
+import "sync"
+
+// Broker fans out messages published on a topic to every current subscriber
+type Broker interface {
+	Publish(topic string, payload []byte) error
+	// Subscribe returns a channel of messages for topic and a function that ends the subscription
+	Subscribe(topic string) (<-chan []byte, func())
+}
+
+// MemoryBroker is a Broker for a single API instance
+type MemoryBroker struct {
+	mu          sync.RWMutex
+	subscribers map[string]map[chan []byte]struct{}
+	buffer      int
+}
+
+// NewMemoryBroker creates a broker whose subscribers buffer up to buffer messages.
+// Messages for a subscriber whose buffer is full are dropped rather than blocking publishers.
+func NewMemoryBroker(buffer int) *MemoryBroker {
+	return &MemoryBroker{
+		subscribers: make(map[string]map[chan []byte]struct{}),
+		buffer:      buffer,
+	}
+}
+
+// Publish delivers payload to the subscribers of topic
+func (b *MemoryBroker) Publish(topic string, payload []byte) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for ch := range b.subscribers[topic] {
+		select {
+		case ch <- payload:
+		default:
+		}
+	}
+	return nil
+}
+
+// Subscribe registers a subscriber for topic
+func (b *MemoryBroker) Subscribe(topic string) (<-chan []byte, func()) {
+	ch := make(chan []byte, b.buffer)
+
+	b.mu.Lock()
+	if b.subscribers[topic] == nil {
+		b.subscribers[topic] = make(map[chan []byte]struct{})
+	}
+	b.subscribers[topic][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers[topic], ch)
+			if len(b.subscribers[topic]) == 0 {
+				delete(b.subscribers, topic)
+			}
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+	return ch, unsubscribe
+}
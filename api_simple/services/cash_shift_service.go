@@ -0,0 +1,242 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrShiftAlreadyOpen     = errors.New("staff member already has an open shift")
+	ErrNoOpenShift          = errors.New("staff member has no open shift")
+	ErrShiftNotClosed       = errors.New("only closed shifts can be approved")
+	ErrShiftSelfApproval    = errors.New("staff cannot approve their own shift")
+	ErrVarianceNoteRequired = errors.New("a note is required when the cash variance exceeds the tolerance")
+)
+
+// CashShiftConfig controls shift closing
+type CashShiftConfig struct {
+	VarianceTolerance models.Money // Variance allowed at closing without an explanation
+}
+
+// DefaultCashShiftConfig returns the settings used by the API
+func DefaultCashShiftConfig() CashShiftConfig {
+	return CashShiftConfig{
+		VarianceTolerance: 10000,
+	}
+}
+
+// CashShiftConfigFromEnv returns DefaultCashShiftConfig overridden by CASH_VARIANCE_TOLERANCE
+func CashShiftConfigFromEnv() CashShiftConfig {
+	cfg := DefaultCashShiftConfig()
+	if v, err := strconv.ParseInt(os.Getenv("CASH_VARIANCE_TOLERANCE"), 10, 64); err == nil && v >= 0 {
+		cfg.VarianceTolerance = models.Money(v)
+	}
+	return cfg
+}
+
+// CashShiftReport sums up the cash movements of a shift against what was counted
+type CashShiftReport struct {
+	Shift        *models.CashShift        `json:"shift"`
+	OpeningFloat models.Money             `json:"opening_float"`    // Tiền lẻ đầu ca
+	SalesTotal   models.Money             `json:"sales_total"`      // Tổng thu bán vé
+	SalesCount   int                      `json:"sales_count"`      // Số lần thu
+	RefundsTotal models.Money             `json:"refunds_total"`    // Tổng hoàn tiền
+	RefundsCount int                      `json:"refunds_count"`    // Số lần hoàn
+	PayoutsTotal models.Money             `json:"payouts_total"`    // Tổng chi
+	PayoutsCount int                      `json:"payouts_count"`    // Số lần chi
+	ExpectedCash models.Money             `json:"expected_cash"`    // Tiền mặt phải có
+	CountedCash  *models.Money            `json:"counted_cash"`     // Tiền kiểm đếm (khi đã chốt ca)
+	Variance     *models.Money            `json:"variance"`         // Chênh lệch (khi đã chốt ca)
+	WithinLimit  bool                     `json:"within_tolerance"` // Chênh lệch trong mức cho phép
+	Transactions []models.CashTransaction `json:"transactions"`
+}
+
+// CashShiftService runs counter shifts: opening with a float, recording every cash
+// movement of the drawer, closing with a count and supervisor approval
+type CashShiftService struct {
+	shiftRepo *repository.CashShiftRepository
+	cfg       CashShiftConfig
+}
+
+func NewCashShiftService(
+	shiftRepo *repository.CashShiftRepository,
+	cfg CashShiftConfig,
+) *CashShiftService {
+	return &CashShiftService{
+		shiftRepo: shiftRepo,
+		cfg:       cfg,
+	}
+}
+
+// Open starts a shift for a staff member; only one shift may be open at a time
+func (s *CashShiftService) Open(staff *models.User, operatorID uint, openingFloat models.Money, now time.Time) (*models.CashShift, error) {
+	if _, err := s.shiftRepo.FindOpenByStaff(staff.ID); err == nil {
+		return nil, ErrShiftAlreadyOpen
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	shift := &models.CashShift{
+		OperatorID:   operatorID,
+		StaffID:      staff.ID,
+		Status:       models.CashShiftStatusOpen,
+		OpeningFloat: openingFloat,
+		OpenedAt:     now,
+	}
+	if err := shift.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.shiftRepo.Create(shift); err != nil {
+		return nil, err
+	}
+	return shift, nil
+}
+
+// Current returns the open shift of a staff member
+func (s *CashShiftService) Current(staffID uint) (*models.CashShift, error) {
+	shift, err := s.shiftRepo.FindOpenByStaff(staffID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNoOpenShift
+	}
+	return shift, err
+}
+
+// Record adds a cash movement to the open shift of a staff member
+func (s *CashShiftService) Record(staffID uint, txType models.CashTransactionType, amount models.Money, bookingID *uint, note string) (*models.CashTransaction, error) {
+	shift, err := s.Current(staffID)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction := &models.CashTransaction{
+		OperatorID: shift.OperatorID,
+		ShiftID:    shift.ID,
+		Type:       txType,
+		Amount:     amount,
+		BookingID:  bookingID,
+		Note:       note,
+		CreatedBy:  staffID,
+	}
+	if err := transaction.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.shiftRepo.CreateTransaction(transaction); err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
+// RecordIfOpen records a cash movement when the staff member has a shift open.
+// Cash handled outside a shift (e.g. back-office refunds by bank transfer) is not tracked.
+func (s *CashShiftService) RecordIfOpen(staffID uint, txType models.CashTransactionType, amount models.Money, bookingID *uint, note string) error {
+	if amount <= 0 {
+		return nil
+	}
+	_, err := s.Record(staffID, txType, amount, bookingID, note)
+	if errors.Is(err, ErrNoOpenShift) {
+		return nil
+	}
+	return err
+}
+
+// Report sums up the cash movements of a shift
+func (s *CashShiftService) Report(shift *models.CashShift) (*CashShiftReport, error) {
+	transactions, err := s.shiftRepo.FindTransactions(shift.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &CashShiftReport{
+		Shift:        shift,
+		OpeningFloat: shift.OpeningFloat,
+		ExpectedCash: shift.OpeningFloat,
+		Transactions: transactions,
+	}
+	for i := range transactions {
+		transaction := &transactions[i]
+		switch transaction.Type {
+		case models.CashTransactionSale:
+			report.SalesTotal += transaction.Amount
+			report.SalesCount++
+		case models.CashTransactionRefund:
+			report.RefundsTotal += transaction.Amount
+			report.RefundsCount++
+		case models.CashTransactionPayout:
+			report.PayoutsTotal += transaction.Amount
+			report.PayoutsCount++
+		}
+		report.ExpectedCash += transaction.SignedAmount()
+	}
+
+	report.WithinLimit = true
+	if shift.Status != models.CashShiftStatusOpen {
+		counted := shift.CountedCash
+		variance := counted - report.ExpectedCash
+		report.CountedCash = &counted
+		report.Variance = &variance
+		report.WithinLimit = variance.Abs() <= s.cfg.VarianceTolerance
+	}
+	return report, nil
+}
+
+// Close ends the open shift of a staff member with the cash counted in the drawer.
+// A variance beyond the tolerance must be explained in the note.
+func (s *CashShiftService) Close(staffID uint, countedCash models.Money, note string, now time.Time) (*CashShiftReport, error) {
+	shift, err := s.Current(staffID)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := s.Report(shift)
+	if err != nil {
+		return nil, err
+	}
+	variance := countedCash - report.ExpectedCash
+	if variance.Abs() > s.cfg.VarianceTolerance && note == "" {
+		return nil, ErrVarianceNoteRequired
+	}
+
+	shift.Status = models.CashShiftStatusClosed
+	shift.ClosedAt = &now
+	shift.ExpectedCash = report.ExpectedCash
+	shift.CountedCash = countedCash
+	shift.Variance = variance
+	shift.CloseNote = note
+	if err := shift.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.shiftRepo.Update(shift); err != nil {
+		return nil, err
+	}
+	return s.Report(shift)
+}
+
+// Approve signs off a closed shift; supervisors cannot approve their own shifts
+func (s *CashShiftService) Approve(shiftID, reviewerID uint, note string, now time.Time) (*models.CashShift, error) {
+	shift, err := s.shiftRepo.FindByID(shiftID)
+	if err != nil {
+		return nil, err
+	}
+	if shift.Status != models.CashShiftStatusClosed {
+		return nil, ErrShiftNotClosed
+	}
+	if shift.StaffID == reviewerID {
+		return nil, ErrShiftSelfApproval
+	}
+
+	shift.Status = models.CashShiftStatusApproved
+	shift.ReviewedBy = &reviewerID
+	shift.ReviewedAt = &now
+	shift.ReviewNote = note
+	if err := s.shiftRepo.Update(shift); err != nil {
+		return nil, err
+	}
+	return shift, nil
+}
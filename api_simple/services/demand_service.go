@@ -0,0 +1,201 @@
+package services
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+)
+
+// Reasons for recommending an extra departure
+const (
+	RecommendNoDeparture = "no_departure" // Khách tìm nhưng tuyến không có chuyến trong ngày
+	RecommendSoldOut     = "sold_out"     // Nhiều lượt tìm không còn chuyến nào có chỗ
+	RecommendHighLoad    = "high_load"    // Các chuyến trong ngày gần kín chỗ
+)
+
+// DemandConfig controls when extra departures are recommended
+type DemandConfig struct {
+	Horizon             time.Duration // Recommend departures for travel dates within this period
+	MinSearches         int64         // Searches a day needs before its demand is considered
+	MinUnmetSearches    int64         // Searches finding no seats that call for a departure on their own
+	LoadFactorThreshold float64       // Load factor from which the trips of a day count as nearly full
+}
+
+// DefaultDemandConfig returns the settings used by the API
+func DefaultDemandConfig() DemandConfig {
+	return DemandConfig{
+		Horizon:             14 * 24 * time.Hour,
+		MinSearches:         20,
+		MinUnmetSearches:    5,
+		LoadFactorThreshold: 0.85,
+	}
+}
+
+// DemandConfigFromEnv returns DefaultDemandConfig overridden by DEMAND_HORIZON_DAYS and
+// DEMAND_MIN_SEARCHES
+func DemandConfigFromEnv() DemandConfig {
+	cfg := DefaultDemandConfig()
+	if v, err := strconv.Atoi(os.Getenv("DEMAND_HORIZON_DAYS")); err == nil && v > 0 {
+		cfg.Horizon = time.Duration(v) * 24 * time.Hour
+	}
+	if v, err := strconv.ParseInt(os.Getenv("DEMAND_MIN_SEARCHES"), 10, 64); err == nil && v > 0 {
+		cfg.MinSearches = v
+	}
+	return cfg
+}
+
+// DepartureRecommendation suggests adding a departure on a route for a travel date
+type DepartureRecommendation struct {
+	RouteID       uint    `json:"route_id"`
+	Origin        string  `json:"origin"`
+	Destination   string  `json:"destination"`
+	TravelDate    string  `json:"travel_date"`    // Ngày đi (YYYY-MM-DD)
+	Reason        string  `json:"reason"`         // no_departure, sold_out hoặc high_load
+	Searches      int64   `json:"searches"`       // Số lượt tìm cho ngày đi
+	UnmetSearches int64   `json:"unmet_searches"` // Số lượt tìm không còn chuyến có chỗ
+	Trips         int64   `json:"trips"`          // Số chuyến hiện có của tuyến trong ngày
+	Seats         int64   `json:"seats"`
+	BookedSeats   int64   `json:"booked_seats"`
+	LoadFactor    float64 `json:"load_factor"`
+}
+
+// DemandService analyses trip searches and bookings
+type DemandService struct {
+	demandRepo *repository.DemandRepository
+	routeRepo  *repository.RouteRepository
+	config     DemandConfig
+}
+
+func NewDemandService(
+	demandRepo *repository.DemandRepository,
+	routeRepo *repository.RouteRepository,
+	config DemandConfig,
+) *DemandService {
+	return &DemandService{
+		demandRepo: demandRepo,
+		routeRepo:  routeRepo,
+		config:     config,
+	}
+}
+
+// RecordSearch stores a search for travel between origin and destination on travelDate
+// (nil when the customer did not pick a date) that found results trips with free seats
+func (s *DemandService) RecordSearch(origin, destination string, travelDate *time.Time, routeID *uint, results int, now time.Time) error {
+	search := models.TripSearch{
+		Origin:      origin,
+		Destination: destination,
+		RouteID:     routeID,
+		Results:     results,
+		CreatedAt:   now,
+	}
+	if travelDate != nil {
+		search.TravelDate = travelDate.Format(models.TravelDateFormat)
+	}
+	return s.demandRepo.RecordSearch(&search)
+}
+
+// PopularRoutes ranks origin and destination pairs by bookings, then searches, for
+// travel in a season
+func (s *DemandService) PopularRoutes(season models.Season, year int, limit int) ([]repository.CorridorDemand, error) {
+	from, to, err := season.Range(year, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	corridors, err := s.demandRepo.Corridors(from, to)
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && len(corridors) > limit {
+		corridors = corridors[:limit]
+	}
+	return corridors, nil
+}
+
+// Recommend suggests extra departures on the active routes of the repository for the
+// travel dates from today until the configured horizon, where customers searched for
+// trips that did not exist or were full
+func (s *DemandService) Recommend(now time.Time) ([]DepartureRecommendation, error) {
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	to := from.Add(s.config.Horizon)
+
+	routes, err := s.routeRepo.FindAll(map[string]interface{}{"is_active": true})
+	if err != nil {
+		return nil, err
+	}
+	searches, err := s.demandRepo.SearchesByDay(from, to)
+	if err != nil {
+		return nil, err
+	}
+	loads, err := s.demandRepo.RouteLoadByDay(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	searchesByCorridor := make(map[[3]string]repository.DaySearchDemand)
+	for _, search := range searches {
+		searchesByCorridor[[3]string{search.Origin, search.Destination, search.TravelDate}] = search
+	}
+	loadsByRoute := make(map[uint]map[string]repository.RouteDayLoad)
+	for _, load := range loads {
+		if loadsByRoute[load.RouteID] == nil {
+			loadsByRoute[load.RouteID] = make(map[string]repository.RouteDayLoad)
+		}
+		loadsByRoute[load.RouteID][load.Day] = load
+	}
+
+	var recommendations []DepartureRecommendation
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		date := day.Format(models.TravelDateFormat)
+		for _, route := range routes {
+			search, ok := searchesByCorridor[[3]string{route.Origin, route.Destination, date}]
+			if !ok {
+				continue
+			}
+			load := loadsByRoute[route.ID][date]
+			recommendation := DepartureRecommendation{
+				RouteID:       route.ID,
+				Origin:        route.Origin,
+				Destination:   route.Destination,
+				TravelDate:    date,
+				Searches:      search.Searches,
+				UnmetSearches: search.UnmetSearches,
+				Trips:         load.Trips,
+				Seats:         load.Seats,
+				BookedSeats:   load.Booked,
+			}
+			if load.Seats > 0 {
+				recommendation.LoadFactor = float64(load.Booked) / float64(load.Seats)
+			}
+
+			recommendation.Reason = s.reason(recommendation)
+			if recommendation.Reason != "" {
+				recommendations = append(recommendations, recommendation)
+			}
+		}
+	}
+
+	sort.SliceStable(recommendations, func(i, j int) bool {
+		if recommendations[i].TravelDate != recommendations[j].TravelDate {
+			return recommendations[i].TravelDate < recommendations[j].TravelDate
+		}
+		return recommendations[i].Searches > recommendations[j].Searches
+	})
+	return recommendations, nil
+}
+
+// reason returns why a route needs another departure on a day, or "" if it does not
+func (s *DemandService) reason(r DepartureRecommendation) string {
+	switch {
+	case r.Trips == 0 && (r.Searches >= s.config.MinSearches || r.UnmetSearches >= s.config.MinUnmetSearches):
+		return RecommendNoDeparture
+	case r.Trips > 0 && r.UnmetSearches >= s.config.MinUnmetSearches:
+		return RecommendSoldOut
+	case r.Trips > 0 && r.Searches >= s.config.MinSearches && r.LoadFactor >= s.config.LoadFactorThreshold:
+		return RecommendHighLoad
+	}
+	return ""
+}
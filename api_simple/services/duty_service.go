@@ -0,0 +1,379 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+
+	"gorm.io/gorm"
+)
+
+const (
+	DutyRuleNotDriver       = "not_driver"       // Người được phân công không phải tài xế
+	DutyRuleNoLicence       = "no_licence"       // Chưa khai báo giấy phép lái xe
+	DutyRuleLicenceExpired  = "licence_expired"  // Giấy phép hết hạn trước chuyến
+	DutyRuleLicenceClass    = "licence_class"    // Hạng giấy phép không phù hợp với xe
+	DutyRuleLicenceExpiring = "licence_expiring" // Giấy phép sắp hết hạn (chỉ cảnh báo)
+	DutyRuleRest            = "rest"             // Nghỉ giữa hai chuyến không đủ
+	DutyRuleDailyHours      = "daily_hours"      // Vượt số giờ lái trong ngày
+	DutyRuleWeeklyHours     = "weekly_hours"     // Vượt số giờ lái trong tuần
+)
+
+// DutyConfig holds driving-time limits for drivers
+type DutyConfig struct {
+	MaxDailyDriving      time.Duration // Per calendar day
+	MaxWeeklyDriving     time.Duration // Per calendar week starting Monday
+	MinRestBetweenTrips  time.Duration // From arrival to the next departure
+	LicenceWarningPeriod time.Duration // Warn when the licence expires within this period
+	FallbackTripDuration time.Duration // Used when a route's duration cannot be parsed
+}
+
+// DefaultDutyConfig returns the limits used by the API
+func DefaultDutyConfig() DutyConfig {
+	return DutyConfig{
+		MaxDailyDriving:      10 * time.Hour,
+		MaxWeeklyDriving:     48 * time.Hour,
+		MinRestBetweenTrips:  2 * time.Hour,
+		LicenceWarningPeriod: 30 * 24 * time.Hour,
+		FallbackTripDuration: DefaultScheduleConfig().FallbackTripDuration,
+	}
+}
+
+// DutyConfigFromEnv returns DefaultDutyConfig overridden by DRIVER_MAX_DAILY_HOURS,
+// DRIVER_MAX_WEEKLY_HOURS, DRIVER_MIN_REST_MINUTES and DRIVER_LICENCE_WARNING_DAYS
+func DutyConfigFromEnv() DutyConfig {
+	cfg := DefaultDutyConfig()
+	if v, err := strconv.Atoi(os.Getenv("DRIVER_MAX_DAILY_HOURS")); err == nil && v > 0 {
+		cfg.MaxDailyDriving = time.Duration(v) * time.Hour
+	}
+	if v, err := strconv.Atoi(os.Getenv("DRIVER_MAX_WEEKLY_HOURS")); err == nil && v > 0 {
+		cfg.MaxWeeklyDriving = time.Duration(v) * time.Hour
+	}
+	if v, err := strconv.Atoi(os.Getenv("DRIVER_MIN_REST_MINUTES")); err == nil && v >= 0 {
+		cfg.MinRestBetweenTrips = time.Duration(v) * time.Minute
+	}
+	if v, err := strconv.Atoi(os.Getenv("DRIVER_LICENCE_WARNING_DAYS")); err == nil && v >= 0 {
+		cfg.LicenceWarningPeriod = time.Duration(v) * 24 * time.Hour
+	}
+	return cfg
+}
+
+// DutyViolation is a broken duty rule for a trip
+type DutyViolation struct {
+	Rule    string `json:"rule"`
+	TripID  uint   `json:"trip_id,omitempty"`
+	Message string `json:"message"`
+}
+
+// DutyViolations is returned when a trip assignment breaks duty rules
+type DutyViolations []DutyViolation
+
+func (v DutyViolations) Error() string {
+	rules := make([]string, len(v))
+	for i, violation := range v {
+		rules[i] = violation.Rule
+	}
+	return "driver duty rules violated: " + strings.Join(rules, ", ")
+}
+
+// RosterEntry is an upcoming trip of a driver with its duty warnings
+type RosterEntry struct {
+	Trip        models.Trip     `json:"trip"`
+	ArrivalTime time.Time       `json:"arrival_time"`
+	Driving     float64         `json:"driving_hours"`
+	Warnings    []DutyViolation `json:"warnings"`
+}
+
+// DriverRoster summarises the upcoming duty of a driver
+type DriverRoster struct {
+	Driver      models.User           `json:"driver"`
+	Profile     *models.DriverProfile `json:"profile"`
+	From        time.Time             `json:"from"`
+	To          time.Time             `json:"to"`
+	Trips       []RosterEntry         `json:"trips"`
+	DailyHours  map[string]float64    `json:"daily_hours"` // Giờ lái theo ngày (YYYY-MM-DD)
+	Warnings    []DutyViolation       `json:"warnings"`    // Cảnh báo chung như giấy phép sắp hết hạn
+	IsCompliant bool                  `json:"is_compliant"`
+	Limits      map[string]float64    `json:"limits"`
+}
+
+// DutyService enforces driving-time, rest and licence rules for drivers
+type DutyService struct {
+	tripRepo    *repository.TripRepository
+	userRepo    *repository.UserRepository
+	profileRepo *repository.DriverProfileRepository
+	busRepo     *repository.BusRepository
+	routeRepo   *repository.RouteRepository
+	cfg         DutyConfig
+}
+
+func NewDutyService(
+	tripRepo *repository.TripRepository,
+	userRepo *repository.UserRepository,
+	profileRepo *repository.DriverProfileRepository,
+	busRepo *repository.BusRepository,
+	routeRepo *repository.RouteRepository,
+	cfg DutyConfig,
+) *DutyService {
+	return &DutyService{
+		tripRepo:    tripRepo,
+		userRepo:    userRepo,
+		profileRepo: profileRepo,
+		busRepo:     busRepo,
+		routeRepo:   routeRepo,
+		cfg:         cfg,
+	}
+}
+
+// CheckAssignment returns DutyViolations when the trip's driver may not drive it
+func (s *DutyService) CheckAssignment(trip *models.Trip) error {
+	driver, err := s.userRepo.FindByID(trip.DriverID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return DutyViolations{{Rule: DutyRuleNotDriver, Message: "Không tìm thấy tài xế"}}
+		}
+		return err
+	}
+	if driver.Role != models.RoleDriver {
+		return DutyViolations{{Rule: DutyRuleNotDriver, Message: "Người được phân công không phải tài xế"}}
+	}
+
+	if trip.Route == nil || trip.Route.ID != trip.RouteID {
+		if trip.Route, err = s.routeRepo.FindByID(trip.RouteID); err != nil {
+			return err
+		}
+	}
+	if trip.Bus == nil || trip.Bus.ID != trip.BusID {
+		if trip.Bus, err = s.busRepo.FindByID(trip.BusID); err != nil {
+			return err
+		}
+	}
+
+	profile, err := s.findProfile(driver.ID)
+	if err != nil {
+		return err
+	}
+
+	violations := s.licenceViolations(trip, profile, false)
+
+	others, err := s.driverTrips(driver.ID, trip.DepartureTime, trip.DepartureTime, trip.ID)
+	if err != nil {
+		return err
+	}
+	violations = append(violations, s.timeViolations(trip, others)...)
+
+	if len(violations) > 0 {
+		return DutyViolations(violations)
+	}
+	return nil
+}
+
+// Roster returns a driver's trips departing within [from, to] with compliance warnings
+func (s *DutyService) Roster(driverID uint, from, to time.Time) (*DriverRoster, error) {
+	driver, err := s.userRepo.FindByID(driverID)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := s.findProfile(driverID)
+	if err != nil {
+		return nil, err
+	}
+
+	trips, err := s.driverTrips(driverID, from, to, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	roster := &DriverRoster{
+		Driver:      *driver,
+		Profile:     profile,
+		From:        from,
+		To:          to,
+		Trips:       []RosterEntry{},
+		DailyHours:  map[string]float64{},
+		Warnings:    []DutyViolation{},
+		IsCompliant: true,
+		Limits: map[string]float64{
+			"max_daily_hours":   s.cfg.MaxDailyDriving.Hours(),
+			"max_weekly_hours":  s.cfg.MaxWeeklyDriving.Hours(),
+			"min_rest_hours":    s.cfg.MinRestBetweenTrips.Hours(),
+			"licence_warn_days": s.cfg.LicenceWarningPeriod.Hours() / 24,
+		},
+	}
+
+	if profile == nil {
+		roster.Warnings = append(roster.Warnings, DutyViolation{Rule: DutyRuleNoLicence, Message: "Tài xế chưa khai báo giấy phép lái xe"})
+		roster.IsCompliant = false
+	} else if !profile.LicenceValidAt(time.Now()) {
+		roster.Warnings = append(roster.Warnings, DutyViolation{Rule: DutyRuleLicenceExpired, Message: "Giấy phép lái xe đã hết hạn"})
+		roster.IsCompliant = false
+	} else if profile.LicenceExpiry.Before(time.Now().Add(s.cfg.LicenceWarningPeriod)) {
+		roster.Warnings = append(roster.Warnings, DutyViolation{
+			Rule:    DutyRuleLicenceExpiring,
+			Message: "Giấy phép lái xe hết hạn ngày " + profile.LicenceExpiry.Format("02/01/2006"),
+		})
+	}
+
+	for i := range trips {
+		if trips[i].DepartureTime.Before(from) || trips[i].DepartureTime.After(to) {
+			continue
+		}
+
+		trip := trips[i]
+		others := make([]models.Trip, 0, len(trips)-1)
+		others = append(others, trips[:i]...)
+		others = append(others, trips[i+1:]...)
+
+		warnings := s.licenceViolations(&trip, profile, true)
+		warnings = append(warnings, s.timeViolations(&trip, others)...)
+		if len(warnings) > 0 {
+			roster.IsCompliant = false
+		} else {
+			warnings = []DutyViolation{}
+		}
+
+		driving := drivingDuration(&trip, s.cfg.FallbackTripDuration)
+		roster.DailyHours[trip.DepartureTime.Format("2006-01-02")] += driving.Hours()
+		roster.Trips = append(roster.Trips, RosterEntry{
+			Trip:        trip,
+			ArrivalTime: trip.DepartureTime.Add(driving),
+			Driving:     driving.Hours(),
+			Warnings:    warnings,
+		})
+	}
+
+	return roster, nil
+}
+
+// driverTrips loads the driver's trips in the weeks around [from, to] so weekly totals are complete
+func (s *DutyService) driverTrips(driverID uint, from, to time.Time, excludeTripID uint) ([]models.Trip, error) {
+	start := startOfWeek(from).Add(-24 * time.Hour)
+	end := startOfWeek(to).AddDate(0, 0, 7)
+	return s.tripRepo.FindScheduledForDriver(driverID, start, end, excludeTripID)
+}
+
+func (s *DutyService) findProfile(driverID uint) (*models.DriverProfile, error) {
+	profile, err := s.profileRepo.FindByUserID(driverID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return profile, nil
+}
+
+// licenceViolations checks the licence against the trip date and bus size.
+// A missing profile is reported per trip only when skipMissing is false.
+func (s *DutyService) licenceViolations(trip *models.Trip, profile *models.DriverProfile, skipMissing bool) []DutyViolation {
+	if profile == nil {
+		if skipMissing {
+			return nil
+		}
+		return []DutyViolation{{Rule: DutyRuleNoLicence, TripID: trip.ID, Message: "Tài xế chưa khai báo giấy phép lái xe"}}
+	}
+
+	var violations []DutyViolation
+	if !profile.LicenceValidAt(trip.DepartureTime) {
+		violations = append(violations, DutyViolation{
+			Rule:    DutyRuleLicenceExpired,
+			TripID:  trip.ID,
+			Message: "Giấy phép lái xe hết hạn trước ngày khởi hành",
+		})
+	}
+	if trip.Bus != nil {
+		required := models.RequiredLicenceClass(trip.Bus.SeatCount)
+		if !profile.LicenceClass.Covers(required) {
+			violations = append(violations, DutyViolation{
+				Rule:    DutyRuleLicenceClass,
+				TripID:  trip.ID,
+				Message: fmt.Sprintf("Xe %d chỗ yêu cầu giấy phép hạng %s", trip.Bus.SeatCount, required),
+			})
+		}
+	}
+	return violations
+}
+
+// timeViolations checks rest before and after the trip and the daily and weekly driving totals
+func (s *DutyService) timeViolations(trip *models.Trip, others []models.Trip) []DutyViolation {
+	var violations []DutyViolation
+
+	driving := drivingDuration(trip, s.cfg.FallbackTripDuration)
+	arrival := trip.DepartureTime.Add(driving)
+
+	sorted := append([]models.Trip(nil), others...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].DepartureTime.Before(sorted[j].DepartureTime) })
+
+	if prev := previousTrip(trip, sorted); prev != nil {
+		prevArrival := prev.DepartureTime.Add(drivingDuration(prev, s.cfg.FallbackTripDuration))
+		if rest := trip.DepartureTime.Sub(prevArrival); rest < s.cfg.MinRestBetweenTrips {
+			violations = append(violations, DutyViolation{
+				Rule:    DutyRuleRest,
+				TripID:  trip.ID,
+				Message: fmt.Sprintf("Chỉ nghỉ %s sau chuyến #%d (tối thiểu %s)", formatHours(rest), prev.ID, formatHours(s.cfg.MinRestBetweenTrips)),
+			})
+		}
+	}
+	if next := nextTrip(trip, sorted); next != nil {
+		if rest := next.DepartureTime.Sub(arrival); rest < s.cfg.MinRestBetweenTrips {
+			violations = append(violations, DutyViolation{
+				Rule:    DutyRuleRest,
+				TripID:  trip.ID,
+				Message: fmt.Sprintf("Chỉ nghỉ %s trước chuyến #%d (tối thiểu %s)", formatHours(rest), next.ID, formatHours(s.cfg.MinRestBetweenTrips)),
+			})
+		}
+	}
+
+	dayStart := startOfDay(trip.DepartureTime)
+	weekStart := startOfWeek(trip.DepartureTime)
+	daily, weekly := driving, driving
+	for i := range sorted {
+		d := drivingDuration(&sorted[i], s.cfg.FallbackTripDuration)
+		if startOfDay(sorted[i].DepartureTime).Equal(dayStart) {
+			daily += d
+		}
+		if startOfWeek(sorted[i].DepartureTime).Equal(weekStart) {
+			weekly += d
+		}
+	}
+	if daily > s.cfg.MaxDailyDriving {
+		violations = append(violations, DutyViolation{
+			Rule:    DutyRuleDailyHours,
+			TripID:  trip.ID,
+			Message: fmt.Sprintf("Lái %s trong ngày (tối đa %s)", formatHours(daily), formatHours(s.cfg.MaxDailyDriving)),
+		})
+	}
+	if weekly > s.cfg.MaxWeeklyDriving {
+		violations = append(violations, DutyViolation{
+			Rule:    DutyRuleWeeklyHours,
+			TripID:  trip.ID,
+			Message: fmt.Sprintf("Lái %s trong tuần (tối đa %s)", formatHours(weekly), formatHours(s.cfg.MaxWeeklyDriving)),
+		})
+	}
+
+	return violations
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// startOfWeek returns Monday 00:00 of the week containing t
+func startOfWeek(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	return startOfDay(t).AddDate(0, 0, -offset)
+}
+
+func formatHours(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	return fmt.Sprintf("%.1f giờ", d.Hours())
+}
@@ -0,0 +1,285 @@
+package services
+
+import (
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+)
+
+// ForecastConfig controls how trip demand is forecast
+type ForecastConfig struct {
+	Horizon        time.Duration // Forecast trips departing within this period
+	Lookback       time.Duration // History of departed trips the forecast learns from
+	MinSamples     int           // Trips a departure slot, month, weekday or holiday needs before its own figures are used
+	SellOutRatio   float64       // Share of the seats from which a trip is flagged as likely to sell out
+	LowDemandRatio float64       // Share of the seats up to which a trip is flagged as likely to run nearly empty
+}
+
+// DefaultForecastConfig returns the settings used by the forecasting job
+func DefaultForecastConfig() ForecastConfig {
+	return ForecastConfig{
+		Horizon:        30 * 24 * time.Hour,
+		Lookback:       2 * 365 * 24 * time.Hour,
+		MinSamples:     3,
+		SellOutRatio:   0.95,
+		LowDemandRatio: 0.3,
+	}
+}
+
+// ForecastConfigFromEnv returns DefaultForecastConfig overridden by FORECAST_HORIZON_DAYS
+// and FORECAST_LOOKBACK_DAYS
+func ForecastConfigFromEnv() ForecastConfig {
+	cfg := DefaultForecastConfig()
+	if v, err := strconv.Atoi(os.Getenv("FORECAST_HORIZON_DAYS")); err == nil && v > 0 {
+		cfg.Horizon = time.Duration(v) * 24 * time.Hour
+	}
+	if v, err := strconv.Atoi(os.Getenv("FORECAST_LOOKBACK_DAYS")); err == nil && v > 0 {
+		cfg.Lookback = time.Duration(v) * 24 * time.Hour
+	}
+	return cfg
+}
+
+// ForecastService predicts the seats upcoming trips will sell from the bookings of past
+// trips on the same route and departure slot
+type ForecastService struct {
+	forecastRepo *repository.ForecastRepository
+	holidayRepo  *repository.HolidayRepository
+	config       ForecastConfig
+}
+
+func NewForecastService(
+	forecastRepo *repository.ForecastRepository,
+	holidayRepo *repository.HolidayRepository,
+	config ForecastConfig,
+) *ForecastService {
+	return &ForecastService{
+		forecastRepo: forecastRepo,
+		holidayRepo:  holidayRepo,
+		config:       config,
+	}
+}
+
+// Refresh forecasts every trip departing within the horizon and replaces the stored
+// forecasts. Trips on routes without history are left out. It returns the number of
+// trips forecast.
+func (s *ForecastService) Refresh(now time.Time) (int, error) {
+	from := now.Add(-s.config.Lookback)
+	to := now.Add(s.config.Horizon)
+
+	history, err := s.forecastRepo.TripsBetween(from, now)
+	if err != nil {
+		return 0, err
+	}
+	upcoming, err := s.forecastRepo.TripsBetween(now, to)
+	if err != nil {
+		return 0, err
+	}
+	holidays, err := s.holidayRepo.FindBetween(from, to)
+	if err != nil {
+		return 0, err
+	}
+
+	model := newDemandModel(history, holidays, s.config.MinSamples)
+	forecasts := make([]models.TripForecast, 0, len(upcoming))
+	for _, trip := range upcoming {
+		forecast, ok := model.forecast(trip)
+		if !ok {
+			continue
+		}
+		forecast.Risk = s.risk(forecast)
+		forecast.GeneratedAt = now
+		forecasts = append(forecasts, forecast)
+	}
+
+	if err := s.forecastRepo.Replace(now, forecasts); err != nil {
+		return 0, err
+	}
+	return len(forecasts), nil
+}
+
+// risk flags a forecast far above or below the seats of its trip
+func (s *ForecastService) risk(f models.TripForecast) models.ForecastRisk {
+	if f.TotalSeats == 0 {
+		return ""
+	}
+	switch seats := float64(f.TotalSeats); {
+	case float64(f.ForecastSeats) >= s.config.SellOutRatio*seats:
+		return models.ForecastRiskSellOut
+	case float64(f.ForecastSeats) <= s.config.LowDemandRatio*seats:
+		return models.ForecastRiskLowDemand
+	}
+	return ""
+}
+
+// average is a running mean
+type average struct {
+	sum float64
+	n   int
+}
+
+func (a *average) add(v float64) {
+	a.sum += v
+	a.n++
+}
+
+// value returns the mean, or fallback when fewer than min values were added
+func (a *average) value(min int, fallback float64) float64 {
+	if a.n == 0 || a.n < min {
+		return fallback
+	}
+	return a.sum / float64(a.n)
+}
+
+// routeDemand is what the history of one route says about its demand
+type routeDemand struct {
+	booked   average             // Booked seats of ordinary trips
+	months   [13]average         // Booked seats of ordinary trips by month
+	weekdays [7]average          // Booked seats of ordinary trips by weekday
+	slots    map[int]*average    // Deseasonalized booked seats by departure hour
+	all      average             // Deseasonalized booked seats of all ordinary trips
+	holidays map[string]*average // Booked seats over the ordinary forecast, by holiday name
+}
+
+// demandModel forecasts trip demand as a seasonal baseline of the route and departure
+// slot, scaled by the holiday effect seen on the route in past years
+type demandModel struct {
+	routes     map[uint]*routeDemand
+	holidays   []models.Holiday
+	minSamples int
+}
+
+func newDemandModel(history []models.Trip, holidays []models.Holiday, minSamples int) *demandModel {
+	m := &demandModel{routes: make(map[uint]*routeDemand), holidays: holidays, minSamples: minSamples}
+
+	// Seasonal indexes come from ordinary days only, so holidays do not inflate them
+	var ordinary, festive []models.Trip
+	for _, trip := range history {
+		if m.holiday(trip.DepartureTime) != nil {
+			festive = append(festive, trip)
+			continue
+		}
+		ordinary = append(ordinary, trip)
+
+		route := m.route(trip.RouteID)
+		departure := trip.DepartureTime.Local()
+		booked := float64(trip.BookedSeats)
+		route.booked.add(booked)
+		route.months[departure.Month()].add(booked)
+		route.weekdays[departure.Weekday()].add(booked)
+	}
+
+	for _, trip := range ordinary {
+		route := m.routes[trip.RouteID]
+		departure := trip.DepartureTime.Local()
+		booked := float64(trip.BookedSeats) / m.seasonalFactor(route, departure)
+		if route.slots[departure.Hour()] == nil {
+			route.slots[departure.Hour()] = &average{}
+		}
+		route.slots[departure.Hour()].add(booked)
+		route.all.add(booked)
+	}
+
+	for _, trip := range festive {
+		route, ok := m.routes[trip.RouteID]
+		if !ok {
+			continue
+		}
+		expected, _ := m.baseline(route, trip.DepartureTime.Local())
+		expected *= m.seasonalFactor(route, trip.DepartureTime.Local())
+		if expected <= 0 {
+			continue
+		}
+		name := m.holiday(trip.DepartureTime).Name
+		if route.holidays[name] == nil {
+			route.holidays[name] = &average{}
+		}
+		route.holidays[name].add(float64(trip.BookedSeats) / expected)
+	}
+	return m
+}
+
+func (m *demandModel) route(routeID uint) *routeDemand {
+	route, ok := m.routes[routeID]
+	if !ok {
+		route = &routeDemand{slots: make(map[int]*average), holidays: make(map[string]*average)}
+		m.routes[routeID] = route
+	}
+	return route
+}
+
+// holiday returns the holiday t falls in, or nil
+func (m *demandModel) holiday(t time.Time) *models.Holiday {
+	for i := range m.holidays {
+		if m.holidays[i].Contains(t.Local()) {
+			return &m.holidays[i]
+		}
+	}
+	return nil
+}
+
+// seasonalFactor returns how much busier the month and weekday of t are than the route
+// on average
+func (m *demandModel) seasonalFactor(route *routeDemand, t time.Time) float64 {
+	mean := route.booked.value(1, 0)
+	if mean <= 0 {
+		return 1
+	}
+	month := route.months[t.Month()].value(m.minSamples, mean) / mean
+	weekday := route.weekdays[t.Weekday()].value(m.minSamples, mean) / mean
+	if month <= 0 || weekday <= 0 {
+		return 1
+	}
+	return month * weekday
+}
+
+// baseline returns the deseasonalized demand of the departure slot of t, or of the whole
+// route when the slot has too little history, with the number of trips it comes from
+func (m *demandModel) baseline(route *routeDemand, t time.Time) (float64, int) {
+	if slot, ok := route.slots[t.Hour()]; ok && slot.n >= m.minSamples {
+		return slot.value(1, 0), slot.n
+	}
+	return route.all.value(1, 0), route.all.n
+}
+
+// forecast predicts the demand of an upcoming trip, or false when its route has no
+// ordinary trips in the history
+func (m *demandModel) forecast(trip models.Trip) (models.TripForecast, bool) {
+	route, ok := m.routes[trip.RouteID]
+	if !ok || route.all.n == 0 {
+		return models.TripForecast{}, false
+	}
+
+	departure := trip.DepartureTime.Local()
+	baseline, samples := m.baseline(route, departure)
+	forecast := models.TripForecast{
+		OperatorID:     trip.OperatorID,
+		TripID:         trip.ID,
+		RouteID:        trip.RouteID,
+		DepartureTime:  trip.DepartureTime,
+		TotalSeats:     trip.TotalSeats,
+		BookedSeats:    trip.BookedSeats,
+		Baseline:       baseline,
+		SeasonalFactor: m.seasonalFactor(route, departure),
+		HolidayFactor:  1,
+		Samples:        samples,
+	}
+	if holiday := m.holiday(departure); holiday != nil {
+		forecast.Holiday = holiday.Name
+		forecast.HolidayFactor = holiday.Uplift
+		if effect, ok := route.holidays[holiday.Name]; ok {
+			forecast.HolidayFactor = effect.value(m.minSamples, holiday.Uplift)
+		}
+	}
+
+	seats := int(math.Round(baseline * forecast.SeasonalFactor * forecast.HolidayFactor))
+	// Seats already sold are demand that showed up
+	if seats < trip.BookedSeats {
+		seats = trip.BookedSeats
+	}
+	forecast.ForecastSeats = seats
+	return forecast, true
+}
@@ -0,0 +1,635 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/utils"
+
+	"gorm.io/gorm"
+)
+
+// ImportKind is the kind of records an import file creates
+type ImportKind string
+
+const (
+	ImportRoutes    ImportKind = "routes"    // Tuyến đường
+	ImportBuses     ImportKind = "buses"     // Xe
+	ImportTrips     ImportKind = "trips"     // Chuyến xe
+	ImportSchedules ImportKind = "schedules" // Lịch chạy, sinh một chuyến cho mỗi ngày chạy
+)
+
+// importColumns are the columns each kind of file must have
+var importColumns = map[ImportKind][]string{
+	ImportRoutes:    {"origin", "destination", "distance", "duration", "base_price"},
+	ImportBuses:     {"plate_number", "type", "seat_count"},
+	ImportTrips:     {"origin", "destination", "plate_number", "driver_phone", "departure_time"},
+	ImportSchedules: {"origin", "destination", "plate_number", "driver_phone", "departure", "start_date", "end_date"},
+}
+
+// IsValid reports whether k is a kind of record that can be imported
+func (k ImportKind) IsValid() bool {
+	_, ok := importColumns[k]
+	return ok
+}
+
+const (
+	MaxImportRows   = 2000 // Số dòng tối đa của một file
+	MaxScheduleDays = 366  // Số ngày tối đa của một lịch chạy
+	MaxImportTrips  = 5000 // Số chuyến tối đa một file được sinh ra
+)
+
+var (
+	ErrInvalidImportFile  = errors.New("invalid import file")
+	ErrImportEmpty        = errors.New("import file has no rows")
+	ErrImportTooLarge     = errors.New("import file has too many rows")
+	ErrImportTooManyTrips = errors.New("import file generates too many trips")
+
+	ErrImportInvalidValue    = errors.New("invalid value")
+	ErrImportRouteExists     = errors.New("route already exists")
+	ErrImportRouteNotFound   = errors.New("route not found")
+	ErrImportPlateTaken      = errors.New("plate number already exists")
+	ErrImportBusNotFound     = errors.New("bus not found")
+	ErrImportDriverNotFound  = errors.New("driver not found")
+	ErrImportScheduleTooLong = errors.New("schedule spans too many days")
+	ErrImportNoDeparture     = errors.New("schedule has no departure")
+)
+
+// MissingColumnError is returned when an import file lacks a required column
+type MissingColumnError struct {
+	Column string
+}
+
+func (e *MissingColumnError) Error() string {
+	return "import file is missing column " + e.Column
+}
+
+// ImportTable is the content of an import file: the column names, lowercased, and the
+// data rows
+type ImportTable struct {
+	Columns []string
+	Records []ImportRecord
+}
+
+// ImportRecord is a data row of an import file
+type ImportRecord struct {
+	Line   int               // Dòng trong file, tính cả dòng tiêu đề
+	Values map[string]string // Giá trị theo tên cột
+}
+
+// ImportRowError is a row of an import file that cannot be applied
+type ImportRowError struct {
+	Line    int    `json:"line"`
+	Column  string `json:"column,omitempty"` // Cột có giá trị không hợp lệ
+	Date    string `json:"date,omitempty"`   // Ngày chạy của lịch không tạo được chuyến
+	Message string `json:"message"`
+	Err     error  `json:"-"`
+}
+
+// ImportResult sums up an import
+type ImportResult struct {
+	Kind    ImportKind       `json:"kind"`
+	Rows    int              `json:"rows"`    // Số dòng dữ liệu
+	Created int              `json:"created"` // Số tuyến, xe hoặc chuyến được tạo
+	Errors  []ImportRowError `json:"errors"`
+	TripIDs []uint           `json:"-"`
+}
+
+func (r *ImportResult) fail(line int, column, date string, err error) {
+	r.Errors = append(r.Errors, ImportRowError{Line: line, Column: column, Date: date, Err: err})
+}
+
+// ParseImportFile reads an import file, XLSX when it is a zip archive and CSV otherwise.
+// The first non-empty row names the columns; blank rows are skipped.
+func ParseImportFile(data []byte) (*ImportTable, error) {
+	var rows [][]string
+	var lines []int // Dòng trong file của mỗi hàng
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		var err error
+		if rows, err = utils.ReadXLSX(data); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+		}
+		for i := range rows {
+			lines = append(lines, i+1)
+		}
+	} else {
+		reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+		reader.TrimLeadingSpace = true
+		reader.FieldsPerRecord = -1
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+			}
+			line, _ := reader.FieldPos(0)
+			rows = append(rows, record)
+			lines = append(lines, line)
+		}
+	}
+
+	table := &ImportTable{}
+	for i, row := range rows {
+		if strings.TrimSpace(strings.Join(row, "")) == "" {
+			continue
+		}
+		if table.Columns == nil {
+			for _, name := range row {
+				table.Columns = append(table.Columns, strings.ToLower(strings.TrimSpace(name)))
+			}
+			continue
+		}
+
+		record := ImportRecord{Line: lines[i], Values: make(map[string]string)}
+		for j, value := range row {
+			if j < len(table.Columns) && table.Columns[j] != "" {
+				record.Values[table.Columns[j]] = strings.TrimSpace(value)
+			}
+		}
+		table.Records = append(table.Records, record)
+	}
+
+	if len(table.Records) == 0 {
+		return nil, ErrImportEmpty
+	}
+	if len(table.Records) > MaxImportRows {
+		return nil, ErrImportTooLarge
+	}
+	return table, nil
+}
+
+// ImportService creates the routes, buses and trips of an operator from import files,
+// checking every row as the API does when they are created one at a time
+type ImportService struct {
+	routeRepo       *repository.RouteRepository
+	busRepo         *repository.BusRepository
+	allBusRepo      *repository.BusRepository // Xe của mọi nhà xe, để kiểm tra trùng biển số
+	userRepo        *repository.UserRepository
+	tripRepo        *repository.TripRepository
+	seatRepo        *repository.SeatRepository
+	operatorService *OperatorService
+	scheduleService *ScheduleService
+	dutyService     *DutyService
+}
+
+func NewImportService(
+	routeRepo *repository.RouteRepository,
+	busRepo *repository.BusRepository,
+	allBusRepo *repository.BusRepository,
+	userRepo *repository.UserRepository,
+	tripRepo *repository.TripRepository,
+	seatRepo *repository.SeatRepository,
+	operatorService *OperatorService,
+	scheduleService *ScheduleService,
+	dutyService *DutyService,
+) *ImportService {
+	return &ImportService{
+		routeRepo:       routeRepo,
+		busRepo:         busRepo,
+		allBusRepo:      allBusRepo,
+		userRepo:        userRepo,
+		tripRepo:        tripRepo,
+		seatRepo:        seatRepo,
+		operatorService: operatorService,
+		scheduleService: scheduleService,
+		dutyService:     dutyService,
+	}
+}
+
+// Import creates the records of every row of table for the operator. Rows that cannot
+// be applied are reported in the result and the others are still created, so callers
+// run it in a transaction they roll back when the result has errors. Trips are created
+// in departure order, so each is checked against the trips before it whatever the order
+// of the rows. The error is only set when the file lacks a column, would generate more
+// than MaxImportTrips trips, or the database fails.
+func (s *ImportService) Import(operatorID uint, kind ImportKind, table *ImportTable) (*ImportResult, error) {
+	columns, ok := importColumns[kind]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidImportFile, kind)
+	}
+	present := make(map[string]bool)
+	for _, column := range table.Columns {
+		present[column] = true
+	}
+	for _, column := range columns {
+		if !present[column] {
+			return nil, &MissingColumnError{Column: column}
+		}
+	}
+
+	result := &ImportResult{Kind: kind, Rows: len(table.Records), Errors: []ImportRowError{}}
+	var trips []pendingTrip
+	for _, record := range table.Records {
+		var pending []pendingTrip
+		var err error
+		switch kind {
+		case ImportRoutes:
+			err = s.importRoute(record, result)
+		case ImportBuses:
+			err = s.importBus(record, result)
+		case ImportTrips:
+			pending, err = s.importTrip(operatorID, record, result)
+		case ImportSchedules:
+			pending, err = s.importSchedule(operatorID, record, result)
+		}
+		if err != nil {
+			return nil, err
+		}
+		trips = append(trips, pending...)
+		// Schedules can expand a small file into many trips; stop before creating any
+		if len(trips) > MaxImportTrips {
+			return nil, ErrImportTooManyTrips
+		}
+	}
+
+	sort.SliceStable(trips, func(i, j int) bool {
+		return trips[i].trip.DepartureTime.Before(trips[j].trip.DepartureTime)
+	})
+	for _, pending := range trips {
+		if err := s.createTrip(pending, result); err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(result.Errors, func(i, j int) bool { return result.Errors[i].Line < result.Errors[j].Line })
+	return result, nil
+}
+
+// pendingTrip is a trip of an import file waiting to be created
+type pendingTrip struct {
+	trip *models.Trip
+	bus  *models.Bus
+	line int
+	date string // Ngày chạy, với chuyến sinh từ lịch chạy
+}
+
+func (s *ImportService) importRoute(record ImportRecord, result *ImportResult) error {
+	values := record.Values
+	route := models.Route{
+		Origin:      values["origin"],
+		Destination: values["destination"],
+		Duration:    values["duration"],
+		IsActive:    true,
+	}
+
+	var err error
+	if route.Distance, err = strconv.ParseFloat(values["distance"], 64); err != nil {
+		result.fail(record.Line, "distance", "", ErrImportInvalidValue)
+		return nil
+	}
+	if route.BasePrice, err = models.ParseMoney(values["base_price"]); err != nil {
+		result.fail(record.Line, "base_price", "", ErrImportInvalidValue)
+		return nil
+	}
+	for column, target := range map[string]**float64{
+		"origin_lat":      &route.OriginLat,
+		"origin_lng":      &route.OriginLng,
+		"destination_lat": &route.DestinationLat,
+		"destination_lng": &route.DestinationLng,
+	} {
+		if values[column] == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(values[column], 64)
+		if err != nil {
+			result.fail(record.Line, column, "", ErrImportInvalidValue)
+			return nil
+		}
+		*target = &v
+	}
+	if err := route.Validate(); err != nil {
+		result.fail(record.Line, "", "", err)
+		return nil
+	}
+
+	// The operator runs each origin and destination pair once
+	exists, err := s.routeRepo.Exists(map[string]interface{}{"origin": route.Origin, "destination": route.Destination})
+	if err != nil {
+		return err
+	}
+	if exists {
+		result.fail(record.Line, "", "", ErrImportRouteExists)
+		return nil
+	}
+
+	if err := s.routeRepo.Create(&route); err != nil {
+		return err
+	}
+	result.Created++
+	return nil
+}
+
+func (s *ImportService) importBus(record ImportRecord, result *ImportResult) error {
+	values := record.Values
+	bus := models.Bus{
+		PlateNumber: values["plate_number"],
+		Type:        values["type"],
+		FloorCount:  1,
+		IsActive:    true,
+	}
+
+	var err error
+	if bus.SeatCount, err = strconv.Atoi(values["seat_count"]); err != nil {
+		result.fail(record.Line, "seat_count", "", ErrImportInvalidValue)
+		return nil
+	}
+	if values["floor_count"] != "" {
+		if bus.FloorCount, err = strconv.Atoi(values["floor_count"]); err != nil {
+			result.fail(record.Line, "floor_count", "", ErrImportInvalidValue)
+			return nil
+		}
+	}
+	for column, target := range map[string]*float64{
+		"cargo_capacity_kg": &bus.CargoCapacityKg,
+		"cargo_capacity_m3": &bus.CargoCapacityM3,
+	} {
+		if values[column] == "" {
+			continue
+		}
+		if *target, err = strconv.ParseFloat(values[column], 64); err != nil {
+			result.fail(record.Line, column, "", ErrImportInvalidValue)
+			return nil
+		}
+	}
+	if err := bus.Validate(); err != nil {
+		result.fail(record.Line, "", "", err)
+		return nil
+	}
+
+	// Plate numbers are unique across all operators
+	exists, err := s.allBusRepo.Exists(map[string]interface{}{"plate_number": bus.PlateNumber})
+	if err != nil {
+		return err
+	}
+	if exists {
+		result.fail(record.Line, "plate_number", "", ErrImportPlateTaken)
+		return nil
+	}
+
+	if err := s.busRepo.Create(&bus); err != nil {
+		return err
+	}
+	result.Created++
+	return nil
+}
+
+func (s *ImportService) importTrip(operatorID uint, record ImportRecord, result *ImportResult) ([]pendingTrip, error) {
+	trip, bus, ok, err := s.resolveTrip(operatorID, record, result)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	departure, err := parseImportTime(record.Values["departure_time"])
+	if err != nil {
+		result.fail(record.Line, "departure_time", "", ErrImportInvalidValue)
+		return nil, nil
+	}
+	trip.DepartureTime = departure
+
+	return []pendingTrip{{trip: trip, bus: bus, line: record.Line}}, nil
+}
+
+// importSchedule expands a schedules row into a trip for each day it runs
+func (s *ImportService) importSchedule(operatorID uint, record ImportRecord, result *ImportResult) ([]pendingTrip, error) {
+	values := record.Values
+	template, bus, ok, err := s.resolveTrip(operatorID, record, result)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	clock, err := parseImportClock(values["departure"])
+	if err != nil {
+		result.fail(record.Line, "departure", "", ErrImportInvalidValue)
+		return nil, nil
+	}
+	start, err := parseImportDate(values["start_date"])
+	if err != nil {
+		result.fail(record.Line, "start_date", "", ErrImportInvalidValue)
+		return nil, nil
+	}
+	end, err := parseImportDate(values["end_date"])
+	if err != nil || end.Before(start) {
+		result.fail(record.Line, "end_date", "", ErrImportInvalidValue)
+		return nil, nil
+	}
+	if end.Sub(start) >= MaxScheduleDays*24*time.Hour {
+		result.fail(record.Line, "end_date", "", ErrImportScheduleTooLong)
+		return nil, nil
+	}
+	weekdays, err := parseImportWeekdays(values["days"])
+	if err != nil {
+		result.fail(record.Line, "days", "", ErrImportInvalidValue)
+		return nil, nil
+	}
+
+	var trips []pendingTrip
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		if !weekdays[day.Weekday()] {
+			continue
+		}
+		trip := *template
+		trip.DepartureTime = day.Add(clock)
+		trips = append(trips, pendingTrip{trip: &trip, bus: bus, line: record.Line, date: day.Format(models.TravelDateFormat)})
+	}
+	if len(trips) == 0 {
+		result.fail(record.Line, "days", "", ErrImportNoDeparture)
+	}
+	return trips, nil
+}
+
+// resolveTrip builds the trip of a trips or schedules row from the operator's route,
+// bus and driver it names. It returns false when the row names something that does not
+// exist, after reporting it.
+func (s *ImportService) resolveTrip(operatorID uint, record ImportRecord, result *ImportResult) (*models.Trip, *models.Bus, bool, error) {
+	values := record.Values
+
+	route, err := s.routeRepo.FindOne(map[string]interface{}{"origin": values["origin"], "destination": values["destination"]})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		result.fail(record.Line, "", "", ErrImportRouteNotFound)
+		return nil, nil, false, nil
+	}
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	buses, err := s.busRepo.FindAll(map[string]interface{}{"plate_number": values["plate_number"]})
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if len(buses) == 0 {
+		result.fail(record.Line, "plate_number", "", ErrImportBusNotFound)
+		return nil, nil, false, nil
+	}
+
+	driver, err := s.userRepo.ForOperator(operatorID).FindByPhone(values["driver_phone"])
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, false, err
+	}
+	if err != nil || driver.Role != models.RoleDriver {
+		result.fail(record.Line, "driver_phone", "", ErrImportDriverNotFound)
+		return nil, nil, false, nil
+	}
+
+	trip := &models.Trip{
+		RouteID:  route.ID,
+		BusID:    buses[0].ID,
+		DriverID: driver.ID,
+		Price:    route.BasePrice,
+		IsActive: true,
+		Note:     values["note"],
+	}
+	if values["price"] != "" {
+		if trip.Price, err = models.ParseMoney(values["price"]); err != nil {
+			result.fail(record.Line, "price", "", ErrImportInvalidValue)
+			return nil, nil, false, nil
+		}
+	}
+	return trip, &buses[0], true, nil
+}
+
+// createTrip checks a trip as CreateTrip does and saves it with its seats, reporting
+// a trip that cannot run
+func (s *ImportService) createTrip(pending pendingTrip, result *ImportResult) error {
+	trip, line, date := pending.trip, pending.line, pending.date
+	if err := trip.Validate(); err != nil {
+		result.fail(line, "", date, err)
+		return nil
+	}
+
+	err := s.operatorService.AssignTrip(trip)
+	if err == nil {
+		err = s.scheduleService.CheckTrip(trip)
+	}
+	if err == nil {
+		err = s.dutyService.CheckAssignment(trip)
+	}
+	if err != nil {
+		var conflict *ScheduleConflict
+		var violations DutyViolations
+		if errors.As(err, &conflict) || errors.As(err, &violations) ||
+			errors.Is(err, ErrOperatorMismatch) || errors.Is(err, gorm.ErrRecordNotFound) {
+			result.fail(line, "", date, err)
+			return nil
+		}
+		return err
+	}
+
+	if err := s.tripRepo.Create(trip); err != nil {
+		return err
+	}
+	if seats := models.SeatLayout(pending.bus, trip); len(seats) > 0 {
+		if err := s.seatRepo.CreateBatch(seats); err != nil {
+			return err
+		}
+	}
+	result.Created++
+	result.TripIDs = append(result.TripIDs, trip.ID)
+	return nil
+}
+
+// importTimeLayouts are the departure time formats accepted in import files
+var importTimeLayouts = []string{
+	"2006-01-02 15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"02/01/2006 15:04",
+	"02/01/2006 15:04:05",
+	time.RFC3339,
+}
+
+// importDateLayouts are the date formats accepted in import files
+var importDateLayouts = []string{
+	models.TravelDateFormat,
+	"02/01/2006",
+}
+
+// importWeekdays are the names accepted for the days a schedule runs
+var importWeekdays = map[string]time.Weekday{
+	"t2": time.Monday, "t3": time.Tuesday, "t4": time.Wednesday, "t5": time.Thursday,
+	"t6": time.Friday, "t7": time.Saturday, "cn": time.Sunday,
+	"mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday, "thu": time.Thursday,
+	"fri": time.Friday, "sat": time.Saturday, "sun": time.Sunday,
+}
+
+// parseImportTime parses a departure time in local time, written as text or as the
+// date serial number spreadsheets store
+func parseImportTime(value string) (time.Time, error) {
+	for _, layout := range importTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	if serial, err := strconv.ParseFloat(value, 64); err == nil && serial > 1 {
+		return excelSerialTime(serial), nil
+	}
+	return time.Time{}, ErrImportInvalidValue
+}
+
+// parseImportDate parses a date in local time, written as text or as a spreadsheet serial number
+func parseImportDate(value string) (time.Time, error) {
+	for _, layout := range importDateLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	if serial, err := strconv.ParseFloat(value, 64); err == nil && serial > 1 {
+		t := excelSerialTime(math.Floor(serial))
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local), nil
+	}
+	return time.Time{}, ErrImportInvalidValue
+}
+
+// parseImportClock parses a time of day ("07:30") into the time since midnight. Spreadsheets
+// store times as fractions of a day.
+func parseImportClock(value string) (time.Duration, error) {
+	for _, layout := range []string{"15:04", "15:04:05"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second, nil
+		}
+	}
+	if fraction, err := strconv.ParseFloat(value, 64); err == nil && fraction >= 0 && fraction < 1 {
+		return time.Duration(math.Round(fraction*86400)) * time.Second, nil
+	}
+	return 0, ErrImportInvalidValue
+}
+
+// parseImportWeekdays parses the days a schedule runs, such as "T2,T4,T6" or "sat sun".
+// An empty value or "daily" means every day.
+func parseImportWeekdays(value string) (map[time.Weekday]bool, error) {
+	days := make(map[time.Weekday]bool)
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" || value == "daily" || value == "hằng ngày" || value == "hàng ngày" {
+		for day := time.Sunday; day <= time.Saturday; day++ {
+			days[day] = true
+		}
+		return days, nil
+	}
+
+	for _, name := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ';' || r == ' ' }) {
+		day, ok := importWeekdays[name]
+		if !ok {
+			return nil, ErrImportInvalidValue
+		}
+		days[day] = true
+	}
+	return days, nil
+}
+
+// excelSerialTime converts a spreadsheet date serial number (days since 1899-12-30,
+// with the time of day as the fraction) to local time
+func excelSerialTime(serial float64) time.Time {
+	days := math.Floor(serial)
+	seconds := math.Round((serial - days) * 86400)
+	return time.Date(1899, 12, 30, 0, 0, 0, 0, time.Local).AddDate(0, 0, int(days)).Add(time.Duration(seconds) * time.Second)
+}
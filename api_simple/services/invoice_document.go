@@ -0,0 +1,296 @@
+package services
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/utils"
+)
+
+// invoiceXMLVersion is the version of the tax authority's e-invoice data format
+const invoiceXMLVersion = "2.0.1"
+
+// The XML types follow the invoice data format of the General Department of Taxation
+// (Decree 123/2020, Circular 78/2021). Element names are the official abbreviations.
+type invoiceXML struct {
+	XMLName xml.Name       `xml:"HDon"`
+	Data    invoiceDataXML `xml:"DLHDon"`
+}
+
+type invoiceDataXML struct {
+	ID      string            `xml:"Id,attr"`
+	General invoiceGeneralXML `xml:"TTChung"`
+	Content invoiceContentXML `xml:"NDHDon"`
+}
+
+type invoiceGeneralXML struct {
+	Version       string             `xml:"PBan"`
+	Title         string             `xml:"THDon"`
+	TemplateCode  string             `xml:"KHMSHDon"`
+	Series        string             `xml:"KHHDon"`
+	Number        int                `xml:"SHDon"`
+	IssuedDate    string             `xml:"NLap"`
+	Currency      models.Currency    `xml:"DVTTe"`
+	ExchangeRate  string             `xml:"TGia"`
+	PaymentMethod string             `xml:"HTTToan,omitempty"`
+	Related       *invoiceRelatedXML `xml:"TTHDLQuan,omitempty"`
+}
+
+type invoiceRelatedXML struct {
+	Nature       int    `xml:"TCHDon"` // 1: thay thế, 2: điều chỉnh
+	Kind         int    `xml:"LHDCLQuan"`
+	TemplateCode string `xml:"KHMSHDCLQuan"`
+	Series       string `xml:"KHHDCLQuan"`
+	Number       int    `xml:"SHDCLQuan"`
+	IssuedDate   string `xml:"NLHDCLQuan"`
+	Note         string `xml:"GChu,omitempty"`
+}
+
+type invoiceContentXML struct {
+	Seller invoicePartyXML  `xml:"NBan"`
+	Buyer  invoicePartyXML  `xml:"NMua"`
+	Lines  []invoiceLineXML `xml:"DSHHDVu>HHDVu"`
+	Totals invoiceTotalsXML `xml:"TToan"`
+}
+
+type invoicePartyXML struct {
+	Name      string `xml:"Ten,omitempty"`
+	TaxCode   string `xml:"MST,omitempty"`
+	Address   string `xml:"DChi,omitempty"`
+	BuyerName string `xml:"HVTNMHang,omitempty"`
+	Email     string `xml:"DCTDTu,omitempty"`
+}
+
+type invoiceLineXML struct {
+	Nature      int    `xml:"TChat"` // 1: hàng hóa, dịch vụ
+	LineNo      int    `xml:"STT"`
+	Description string `xml:"THHDVu"`
+	Unit        string `xml:"DVTinh,omitempty"`
+	Quantity    string `xml:"SLuong"`
+	UnitPrice   string `xml:"DGia"`
+	Amount      string `xml:"ThTien"`
+	VATRate     string `xml:"TSuat"`
+}
+
+type invoiceTotalsXML struct {
+	ByRate      []invoiceRateTotalXML `xml:"THTTLTSuat>LTSuat"`
+	Subtotal    string                `xml:"TgTCThue"`
+	VATAmount   string                `xml:"TgTThue"`
+	Total       string                `xml:"TgTTTBSo"`
+	TotalInWord string                `xml:"TgTTTBChu"`
+}
+
+type invoiceRateTotalXML struct {
+	VATRate   string `xml:"TSuat"`
+	Amount    string `xml:"ThTien"`
+	VATAmount string `xml:"TThue"`
+}
+
+// BuildInvoiceXML renders an invoice in the tax authority's e-invoice XML format,
+// ready to be signed and submitted by the e-invoice provider
+func BuildInvoiceXML(invoice *models.Invoice) ([]byte, error) {
+	doc := invoiceXML{Data: invoiceDataXML{
+		ID: fmt.Sprintf("%s%s-%07d", invoice.TemplateCode, invoice.Series, invoice.Number),
+		General: invoiceGeneralXML{
+			Version:       invoiceXMLVersion,
+			Title:         "HÓA ĐƠN GIÁ TRỊ GIA TĂNG",
+			TemplateCode:  invoice.TemplateCode,
+			Series:        invoice.Series,
+			Number:        invoice.Number,
+			IssuedDate:    invoice.IssuedAt.Format("2006-01-02"),
+			Currency:      invoice.Currency,
+			ExchangeRate:  "1",
+			PaymentMethod: invoice.PaymentMethod,
+		},
+		Content: invoiceContentXML{
+			Seller: invoicePartyXML{
+				Name:    invoice.SellerName,
+				TaxCode: invoice.SellerTaxCode,
+				Address: invoice.SellerAddress,
+			},
+			Buyer: invoicePartyXML{
+				Name:      invoice.BuyerCompany,
+				TaxCode:   invoice.BuyerTaxCode,
+				Address:   invoice.BuyerAddress,
+				BuyerName: invoice.BuyerName,
+				Email:     invoice.BuyerEmail,
+			},
+			Totals: invoiceTotalsXML{
+				ByRate: []invoiceRateTotalXML{{
+					VATRate:   formatVATRate(invoice.VATRate),
+					Amount:    formatAmount(invoice.Subtotal),
+					VATAmount: formatAmount(invoice.VATAmount),
+				}},
+				Subtotal:    formatAmount(invoice.Subtotal),
+				VATAmount:   formatAmount(invoice.VATAmount),
+				Total:       formatAmount(invoice.Total),
+				TotalInWord: AmountInWords(invoice.Total),
+			},
+		},
+	}}
+
+	if original := invoice.OriginalInvoice; original != nil {
+		nature := 2
+		if invoice.Type == models.InvoiceTypeReplacement {
+			nature = 1
+		}
+		doc.Data.General.Related = &invoiceRelatedXML{
+			Nature:       nature,
+			Kind:         1,
+			TemplateCode: original.TemplateCode,
+			Series:       original.Series,
+			Number:       original.Number,
+			IssuedDate:   original.IssuedAt.Format("2006-01-02"),
+			Note:         invoice.Reason,
+		}
+	}
+
+	for _, line := range invoice.Lines {
+		doc.Data.Content.Lines = append(doc.Data.Content.Lines, invoiceLineXML{
+			Nature:      1,
+			LineNo:      line.LineNo,
+			Description: line.Description,
+			Unit:        line.Unit,
+			Quantity:    strconv.Itoa(line.Quantity),
+			UnitPrice:   formatAmount(line.UnitPrice),
+			Amount:      formatAmount(line.Amount),
+			VATRate:     formatVATRate(line.VATRate),
+		})
+	}
+
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+// RenderInvoicePDF renders a printable copy of an invoice. The XML stays the legal
+// document; the PDF uses standard fonts, so Vietnamese tone marks are dropped.
+func RenderInvoicePDF(invoice *models.Invoice) []byte {
+	page := utils.PDFPage{}
+	y := 800.0
+	text := func(x float64, size float64, bold bool, s string) {
+		page.Texts = append(page.Texts, utils.PDFText{X: x, Y: y, Size: size, Bold: bold, Text: s})
+	}
+	rule := func() {
+		page.Rules = append(page.Rules, utils.PDFRule{X1: 40, Y1: y, X2: 555, Y2: y})
+	}
+
+	title := "HÓA ĐƠN GIÁ TRỊ GIA TĂNG"
+	switch invoice.Type {
+	case models.InvoiceTypeAdjustment:
+		title = "HÓA ĐƠN ĐIỀU CHỈNH"
+	case models.InvoiceTypeReplacement:
+		title = "HÓA ĐƠN THAY THẾ"
+	}
+	text(170, 16, true, title)
+	y -= 20
+	text(40, 10, false, fmt.Sprintf("Ký hiệu: %s%s    Số: %07d    Ngày: %s",
+		invoice.TemplateCode, invoice.Series, invoice.Number, invoice.IssuedAt.Format("02/01/2006")))
+	if invoice.TaxAuthorityCode != "" {
+		y -= 14
+		text(40, 10, false, "Mã của cơ quan thuế: "+invoice.TaxAuthorityCode)
+	}
+	if invoice.OriginalInvoice != nil {
+		y -= 14
+		text(40, 10, false, fmt.Sprintf("Cho hóa đơn ký hiệu %s%s số %07d ngày %s. Lý do: %s",
+			invoice.OriginalInvoice.TemplateCode, invoice.OriginalInvoice.Series, invoice.OriginalInvoice.Number,
+			invoice.OriginalInvoice.IssuedAt.Format("02/01/2006"), invoice.Reason))
+	}
+
+	y -= 20
+	rule()
+	y -= 16
+	text(40, 10, true, "Đơn vị bán: "+invoice.SellerName)
+	y -= 14
+	text(40, 10, false, "Mã số thuế: "+invoice.SellerTaxCode)
+	y -= 14
+	text(40, 10, false, "Địa chỉ: "+invoice.SellerAddress)
+
+	y -= 20
+	text(40, 10, false, "Họ tên người mua: "+invoice.BuyerName)
+	y -= 14
+	text(40, 10, true, "Tên đơn vị: "+invoice.BuyerCompany)
+	y -= 14
+	text(40, 10, false, "Mã số thuế: "+invoice.BuyerTaxCode)
+	y -= 14
+	text(40, 10, false, "Địa chỉ: "+invoice.BuyerAddress)
+	y -= 14
+	text(40, 10, false, "Hình thức thanh toán: "+invoice.PaymentMethod)
+
+	y -= 20
+	rule()
+	y -= 14
+	text(40, 9, true, "STT")
+	text(70, 9, true, "Tên hàng hóa, dịch vụ")
+	text(330, 9, true, "ĐVT")
+	text(365, 9, true, "SL")
+	text(395, 9, true, "Đơn giá")
+	text(475, 9, true, "Thành tiền")
+	y -= 6
+	rule()
+	for _, line := range invoice.Lines {
+		y -= 14
+		text(40, 9, false, strconv.Itoa(line.LineNo))
+		text(70, 9, false, truncateRunes(line.Description, 48))
+		text(330, 9, false, line.Unit)
+		text(365, 9, false, strconv.Itoa(line.Quantity))
+		text(395, 9, false, line.UnitPrice.String())
+		text(475, 9, false, line.Amount.String())
+	}
+	y -= 8
+	rule()
+
+	y -= 16
+	text(330, 10, false, "Cộng tiền hàng:")
+	text(475, 10, false, invoice.Subtotal.String())
+	y -= 14
+	text(330, 10, false, fmt.Sprintf("Tiền thuế GTGT (%s):", formatVATRate(invoice.VATRate)))
+	text(475, 10, false, invoice.VATAmount.String())
+	y -= 14
+	text(330, 10, true, "Tổng tiền thanh toán:")
+	text(475, 10, true, invoice.Total.String())
+	y -= 18
+	text(40, 10, false, "Số tiền viết bằng chữ: "+AmountInWords(invoice.Total))
+
+	if invoice.LookupCode != "" {
+		y -= 30
+		text(40, 9, false, "Mã tra cứu hóa đơn: "+invoice.LookupCode)
+	}
+	y -= 14
+	text(40, 8, false, "Bản thể hiện của hóa đơn điện tử. Hóa đơn gốc là tệp XML.")
+
+	return utils.RenderPDF(page)
+}
+
+// AmountInWords writes a VND amount in Vietnamese words as required on invoices,
+// e.g. 1250000 -> "Một triệu hai trăm năm mươi nghìn đồng"
+func AmountInWords(amount models.Money) string {
+	words := utils.NumberToVietnameseWords(int64(amount)) + " đồng"
+	first, size := utf8.DecodeRuneInString(words)
+	return string(unicode.ToUpper(first)) + words[size:]
+}
+
+// formatAmount writes an amount the way the XML format expects: plain digits, no grouping
+func formatAmount(amount models.Money) string {
+	return strconv.FormatInt(int64(amount), 10)
+}
+
+// formatVATRate writes a VAT rate as a percentage, e.g. "10%"
+func formatVATRate(rate float64) string {
+	return strconv.FormatFloat(rate, 'f', -1, 64) + "%"
+}
+
+// truncateRunes shortens s to at most n characters
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-3]) + "..."
+}
@@ -0,0 +1,24 @@
+package services
+
+import (
+	"os"
+
+	"ticket-management/api_simple/providers"
+)
+
+// InvoiceProvider submits a signed-off invoice to an e-invoice service, which
+// forwards it to the tax authority and returns the codes printed on the invoice
+type InvoiceProvider interface {
+	Submit(reference string, document []byte) (*providers.InvoiceReceipt, error)
+}
+
+// NewInvoiceProviderFromEnv returns the e-invoice provider selected by INVOICE_PROVIDER
+// (http or stub). Without a configured service the stub provider is used.
+func NewInvoiceProviderFromEnv() InvoiceProvider {
+	switch os.Getenv("INVOICE_PROVIDER") {
+	case "http":
+		return providers.NewHTTPInvoiceProvider()
+	default:
+		return providers.NewStubInvoiceProvider()
+	}
+}
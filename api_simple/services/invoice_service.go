@@ -0,0 +1,340 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvoiceBookingNotPaid = errors.New("only paid bookings can be invoiced")
+	ErrInvoiceExists         = errors.New("booking already has an invoice")
+	ErrSellerTaxCodeMissing  = errors.New("operator has no tax code to issue invoices with")
+	ErrInvoiceNotReplaceable = errors.New("only standing invoices that have not been adjusted can be replaced")
+)
+
+// InvoiceConfig controls how VAT invoices are numbered and taxed
+type InvoiceConfig struct {
+	VATRate      float64 // VAT rate in percent; ticket prices include it
+	TemplateCode string  // Ký hiệu mẫu số, 1 = hóa đơn giá trị gia tăng
+	SeriesSuffix string  // Last letters of the series symbol registered with the tax authority
+}
+
+// DefaultInvoiceConfig returns the settings used by the API
+func DefaultInvoiceConfig() InvoiceConfig {
+	return InvoiceConfig{
+		VATRate:      10,
+		TemplateCode: "1",
+		SeriesSuffix: "TAA",
+	}
+}
+
+// InvoiceConfigFromEnv returns DefaultInvoiceConfig overridden by INVOICE_VAT_RATE
+// and INVOICE_SERIES_SUFFIX
+func InvoiceConfigFromEnv() InvoiceConfig {
+	cfg := DefaultInvoiceConfig()
+	if v, err := strconv.ParseFloat(os.Getenv("INVOICE_VAT_RATE"), 64); err == nil && v >= 0 {
+		cfg.VATRate = v
+	}
+	if v := os.Getenv("INVOICE_SERIES_SUFFIX"); v != "" {
+		cfg.SeriesSuffix = v
+	}
+	return cfg
+}
+
+// InvoiceService issues VAT invoices for paid bookings, corrects them with
+// adjustment and replacement invoices and submits them to the e-invoice provider
+type InvoiceService struct {
+	invoiceRepo  *repository.InvoiceRepository
+	operatorRepo *repository.OperatorRepository
+	provider     InvoiceProvider
+	cfg          InvoiceConfig
+}
+
+func NewInvoiceService(
+	invoiceRepo *repository.InvoiceRepository,
+	operatorRepo *repository.OperatorRepository,
+	provider InvoiceProvider,
+	cfg InvoiceConfig,
+) *InvoiceService {
+	return &InvoiceService{
+		invoiceRepo:  invoiceRepo,
+		operatorRepo: operatorRepo,
+		provider:     provider,
+		cfg:          cfg,
+	}
+}
+
+// Series returns the series symbol for invoices issued at the given time: C for
+// invoices carrying a tax authority code, the two-digit year and the registered suffix
+func (s *InvoiceService) Series(now time.Time) string {
+	return "C" + now.Format("06") + s.cfg.SeriesSuffix
+}
+
+// Issue makes out the VAT invoice of a paid booking to the buyer. A booking gets one
+// standing invoice; corrections go through Replace. The booking must come with its
+// trip and route. Run it in a transaction so the invoice number is not lost on failure.
+func (s *InvoiceService) Issue(booking *models.Booking, buyer models.InvoiceBuyer, now time.Time) (*models.Invoice, error) {
+	if booking.PaymentStatus != models.PaymentStatusPaid {
+		return nil, ErrInvoiceBookingNotPaid
+	}
+	if err := buyer.Validate(); err != nil {
+		return nil, err
+	}
+
+	_, err := s.invoiceRepo.FindActiveOriginal(booking.ID)
+	if err == nil {
+		return nil, ErrInvoiceExists
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	invoice, err := s.newInvoice(booking.OperatorID, booking.ID, models.InvoiceTypeOriginal, now)
+	if err != nil {
+		return nil, err
+	}
+	invoice.InvoiceBuyer = buyer
+	invoice.PaymentMethod = paymentMethodCode(booking.PaymentType)
+	if booking.Currency != "" {
+		invoice.Currency = booking.Currency
+	}
+	invoice.Lines = s.bookingLines(booking)
+
+	if err := s.create(invoice); err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// AdjustForRefund issues an adjustment invoice lowering the standing invoice of a
+// booking by the refunded amount. Bookings without an invoice need no adjustment.
+func (s *InvoiceService) AdjustForRefund(booking *models.Booking, refund models.Money, now time.Time) (*models.Invoice, error) {
+	if refund <= 0 {
+		return nil, nil
+	}
+
+	original, err := s.invoiceRepo.FindActiveOriginal(booking.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	invoice, err := s.newInvoice(original.OperatorID, original.BookingID, models.InvoiceTypeAdjustment, now)
+	if err != nil {
+		return nil, err
+	}
+	invoice.InvoiceBuyer = original.InvoiceBuyer
+	invoice.PaymentMethod = original.PaymentMethod
+	invoice.OriginalInvoiceID = &original.ID
+	invoice.OriginalInvoice = original
+	invoice.Reason = fmt.Sprintf("Hoàn tiền hủy vé đơn %s", booking.BookingCode)
+	invoice.Lines = []models.InvoiceLine{
+		s.line(fmt.Sprintf("Điều chỉnh giảm tiền vé do hủy đơn %s", booking.BookingCode), "Lần", 1, -refund),
+	}
+
+	if err := s.create(invoice); err != nil {
+		return nil, err
+	}
+
+	original.Status = models.InvoiceStatusAdjusted
+	if err := s.invoiceRepo.Update(original); err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// Replace cancels a wrongly made out invoice by issuing a new one with the same
+// goods and amounts to the corrected buyer
+func (s *InvoiceService) Replace(originalID uint, buyer models.InvoiceBuyer, reason string, now time.Time) (*models.Invoice, error) {
+	if err := buyer.Validate(); err != nil {
+		return nil, err
+	}
+
+	original, err := s.invoiceRepo.FindByID(originalID)
+	if err != nil {
+		return nil, err
+	}
+	if original.Type == models.InvoiceTypeAdjustment ||
+		original.Status == models.InvoiceStatusAdjusted ||
+		original.Status == models.InvoiceStatusReplaced {
+		return nil, ErrInvoiceNotReplaceable
+	}
+
+	invoice, err := s.newInvoice(original.OperatorID, original.BookingID, models.InvoiceTypeReplacement, now)
+	if err != nil {
+		return nil, err
+	}
+	invoice.InvoiceBuyer = buyer
+	invoice.PaymentMethod = original.PaymentMethod
+	invoice.OriginalInvoiceID = &original.ID
+	invoice.OriginalInvoice = original
+	invoice.Reason = reason
+	for _, line := range original.Lines {
+		line.Model = gorm.Model{}
+		line.InvoiceID = 0
+		invoice.Lines = append(invoice.Lines, line)
+	}
+
+	if err := s.create(invoice); err != nil {
+		return nil, err
+	}
+
+	original.Status = models.InvoiceStatusReplaced
+	if err := s.invoiceRepo.Update(original); err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// Submit sends an invoice to the e-invoice provider and stores the codes it returns.
+// A failed attempt is recorded on the invoice and retried later.
+func (s *InvoiceService) Submit(invoice *models.Invoice, now time.Time) error {
+	if invoice.SentAt != nil {
+		return nil
+	}
+
+	document, err := BuildInvoiceXML(invoice)
+	if err != nil {
+		return err
+	}
+
+	receipt, err := s.provider.Submit(invoice.Reference(), document)
+	if err != nil {
+		invoice.SubmitError = err.Error()
+		if saveErr := s.invoiceRepo.Update(invoice); saveErr != nil {
+			return saveErr
+		}
+		return err
+	}
+
+	invoice.LookupCode = receipt.LookupCode
+	invoice.TaxAuthorityCode = receipt.TaxAuthorityCode
+	invoice.SentAt = &now
+	invoice.SubmitError = ""
+	if invoice.Status == models.InvoiceStatusIssued {
+		invoice.Status = models.InvoiceStatusSent
+	}
+	return s.invoiceRepo.Update(invoice)
+}
+
+// SubmitPending submits invoices the provider has not accepted yet, oldest first,
+// and returns how many went through
+func (s *InvoiceService) SubmitPending(limit int, now time.Time) (int, error) {
+	invoices, err := s.invoiceRepo.FindUnsent(limit)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for i := range invoices {
+		if err := s.Submit(&invoices[i], now); err != nil {
+			continue
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// newInvoice prepares an invoice with the seller's details and the next number of
+// the current series
+func (s *InvoiceService) newInvoice(operatorID, bookingID uint, invoiceType models.InvoiceType, now time.Time) (*models.Invoice, error) {
+	operator, err := s.operatorRepo.FindByID(operatorID)
+	if err != nil {
+		return nil, err
+	}
+	if operator.TaxCode == "" {
+		return nil, ErrSellerTaxCodeMissing
+	}
+
+	series := s.Series(now)
+	number, err := s.invoiceRepo.NextNumber(operatorID, s.cfg.TemplateCode, series)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Invoice{
+		OperatorID:    operatorID,
+		BookingID:     bookingID,
+		Type:          invoiceType,
+		Status:        models.InvoiceStatusIssued,
+		TemplateCode:  s.cfg.TemplateCode,
+		Series:        series,
+		Number:        number,
+		IssuedAt:      now,
+		SellerName:    operator.Name,
+		SellerTaxCode: operator.TaxCode,
+		SellerAddress: operator.Address,
+		Currency:      models.DefaultCurrency,
+		VATRate:       s.cfg.VATRate,
+	}, nil
+}
+
+// create sums up the lines and stores the invoice
+func (s *InvoiceService) create(invoice *models.Invoice) error {
+	invoice.Subtotal, invoice.VATAmount, invoice.Total = 0, 0, 0
+	for i := range invoice.Lines {
+		invoice.Lines[i].LineNo = i + 1
+		invoice.Subtotal += invoice.Lines[i].Amount
+		invoice.VATAmount += invoice.Lines[i].VATAmount
+	}
+	invoice.Total = invoice.Subtotal + invoice.VATAmount
+	return s.invoiceRepo.Create(invoice)
+}
+
+// bookingLines lists the tickets of a booking and its transfer surcharge
+func (s *InvoiceService) bookingLines(booking *models.Booking) []models.InvoiceLine {
+	description := "Vé xe khách"
+	if booking.Trip != nil && booking.Trip.Route != nil {
+		description = fmt.Sprintf("Vé xe khách %s - %s, khởi hành %s",
+			booking.Trip.Route.Origin,
+			booking.Trip.Route.Destination,
+			booking.Trip.DepartureTime.Format("15:04 02/01/2006"),
+		)
+	}
+
+	seats := len(booking.SeatIDs)
+	if seats == 0 {
+		seats = 1
+	}
+
+	lines := []models.InvoiceLine{
+		s.line(description, "Vé", seats, booking.TotalAmount-booking.SurchargeAmount),
+	}
+	if booking.SurchargeAmount > 0 {
+		lines = append(lines, s.line("Phụ phí trung chuyển", "Lần", 1, booking.SurchargeAmount))
+	}
+	return lines
+}
+
+// line splits a VAT-inclusive amount into the net amount and the tax of an invoice line
+func (s *InvoiceService) line(description, unit string, quantity int, gross models.Money) models.InvoiceLine {
+	amount := gross.Mul(1 / (1 + s.cfg.VATRate/100))
+	return models.InvoiceLine{
+		Description: description,
+		Unit:        unit,
+		Quantity:    quantity,
+		UnitPrice:   amount.Mul(1 / float64(quantity)),
+		Amount:      amount,
+		VATRate:     s.cfg.VATRate,
+		VATAmount:   gross - amount,
+	}
+}
+
+// paymentMethodCode returns the payment method as written on e-invoices: TM for cash,
+// CK for transfers and wallets
+func paymentMethodCode(paymentType models.PaymentType) string {
+	if paymentType == models.PaymentTypeCash {
+		return "TM"
+	}
+	return "CK"
+}
@@ -0,0 +1,260 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+)
+
+var (
+	ErrNothingToPayOut      = errors.New("nothing is owed")
+	ErrPayoutExceedsBalance = errors.New("payout exceeds the amount owed")
+	ErrInvalidPaymentChange = errors.New("payment status cannot change this way")
+)
+
+// LedgerService posts balanced journal entries for the money movements of bookings:
+// sales, payments, cancellations and payouts. Revenue reports are computed from
+// these postings.
+type LedgerService struct {
+	ledgerRepo *repository.LedgerRepository
+}
+
+func NewLedgerService(ledgerRepo *repository.LedgerRepository) *LedgerService {
+	return &LedgerService{ledgerRepo: ledgerRepo}
+}
+
+// SettlementAccount returns the account money paid with the given method lands in
+func SettlementAccount(method models.PaymentType) models.LedgerAccount {
+	if method == models.PaymentTypeCash {
+		return models.AccountCash
+	}
+	return models.AccountGatewayClearing
+}
+
+// RecordSale recognizes the revenue of a new booking against the customer's receivable,
+// accrues the agency commission and, when the booking was paid upfront, records the payment
+func (s *LedgerService) RecordSale(booking *models.Booking, now time.Time) error {
+	entries := []models.LedgerEntry{
+		debit(models.AccountReceivable, booking.TotalAmount),
+		credit(models.AccountRevenue, booking.TotalAmount),
+	}
+	if booking.CommissionAmount > 0 {
+		entries = append(entries,
+			debit(models.AccountAgencyCommission, booking.CommissionAmount),
+			credit(models.AccountCommissionPayable, booking.CommissionAmount),
+		)
+	}
+	if err := s.post(booking.OperatorID, models.LedgerKindSale, booking, "Bán vé "+booking.BookingCode, now, entries); err != nil {
+		return err
+	}
+
+	if booking.PaymentStatus == models.PaymentStatusPaid || booking.PaymentStatus == models.PaymentStatusRefunded {
+		return s.RecordPayment(booking, now)
+	}
+	return nil
+}
+
+// RecordPayment records the customer paying for a booking
+func (s *LedgerService) RecordPayment(booking *models.Booking, now time.Time) error {
+	return s.post(booking.OperatorID, models.LedgerKindPayment, booking, "Thu tiền vé "+booking.BookingCode, now, []models.LedgerEntry{
+		debit(SettlementAccount(booking.PaymentType), booking.TotalAmount),
+		credit(models.AccountReceivable, booking.TotalAmount),
+	})
+}
+
+// RecordPaymentChange posts the entries for a payment status set by hand: a payment,
+// or a full refund of a paid booking that stays on the books, which also takes back
+// the agency commission. Other transitions cannot be posted and are rejected.
+func (s *LedgerService) RecordPaymentChange(booking *models.Booking, from, to models.PaymentStatus, now time.Time) error {
+	if !from.CanChangeTo(to) {
+		return ErrInvalidPaymentChange
+	}
+	if to == models.PaymentStatusPaid {
+		return s.RecordPayment(booking, now)
+	}
+
+	balances, err := s.ledgerRepo.BookingBalances(booking.ID)
+	if err != nil {
+		return err
+	}
+	commission := -balances[models.AccountCommissionPayable]
+	return s.post(booking.OperatorID, models.LedgerKindCancellation, booking, "Hoàn tiền vé "+booking.BookingCode, now, []models.LedgerEntry{
+		debit(models.AccountRevenue, -balances[models.AccountRevenue]),
+		credit(models.AccountRefundsPayable, -balances[models.AccountRevenue]),
+		debit(models.AccountCommissionPayable, commission),
+		credit(models.AccountAgencyCommission, commission),
+	})
+}
+
+// RecordCancellation reverses the revenue of a cancelled booking. The customer's open
+// receivable is written off, the refund (booking.RefundAmount) becomes payable to the
+// customer and what was paid but is not refunded stays as a cancellation fee. The
+// agency commission accrued on the sale is reversed as well.
+func (s *LedgerService) RecordCancellation(booking *models.Booking, now time.Time) error {
+	balances, err := s.ledgerRepo.BookingBalances(booking.ID)
+	if err != nil {
+		return err
+	}
+
+	recognized := -balances[models.AccountRevenue]
+	open := max(balances[models.AccountReceivable], 0)
+	refund := min(booking.RefundAmount, max(recognized-open, 0))
+	commission := -balances[models.AccountCommissionPayable]
+
+	return s.post(booking.OperatorID, models.LedgerKindCancellation, booking, "Hủy vé "+booking.BookingCode, now, []models.LedgerEntry{
+		debit(models.AccountRevenue, recognized),
+		credit(models.AccountReceivable, open),
+		credit(models.AccountRefundsPayable, refund),
+		credit(models.AccountCancellationFees, recognized-open-refund),
+		debit(models.AccountCommissionPayable, commission),
+		credit(models.AccountAgencyCommission, commission),
+	})
+}
+
+// PayOutRefund records the refund owed on a booking being paid back to the customer
+// from the given cash or clearing account
+func (s *LedgerService) PayOutRefund(booking *models.Booking, from models.LedgerAccount, userID *uint, now time.Time) (*models.LedgerTransaction, error) {
+	balances, err := s.ledgerRepo.BookingBalances(booking.ID)
+	if err != nil {
+		return nil, err
+	}
+	owed := -balances[models.AccountRefundsPayable]
+	if owed <= 0 {
+		return nil, ErrNothingToPayOut
+	}
+
+	transaction := &models.LedgerTransaction{
+		OperatorID:  booking.OperatorID,
+		Kind:        models.LedgerKindRefundPayout,
+		BookingID:   &booking.ID,
+		Description: "Chi hoàn tiền vé " + booking.BookingCode,
+		PostedAt:    now,
+		CreatedBy:   userID,
+		Entries: []models.LedgerEntry{
+			debit(models.AccountRefundsPayable, owed),
+			credit(from, owed),
+		},
+	}
+	if err := s.create(transaction); err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
+// PayOutCommission records commission being paid to an agency from the given cash or
+// clearing account. A zero amount pays out everything owed.
+func (s *LedgerService) PayOutCommission(operatorID uint, client *models.APIClient, amount models.Money, from models.LedgerAccount, userID *uint, now time.Time) (*models.LedgerTransaction, error) {
+	owed, err := s.ledgerRepo.CommissionOwed(client.ID)
+	if err != nil {
+		return nil, err
+	}
+	if owed <= 0 {
+		return nil, ErrNothingToPayOut
+	}
+	if amount == 0 {
+		amount = owed
+	}
+	if amount > owed {
+		return nil, ErrPayoutExceedsBalance
+	}
+
+	transaction := &models.LedgerTransaction{
+		OperatorID:  operatorID,
+		Kind:        models.LedgerKindCommissionPayout,
+		APIClientID: &client.ID,
+		Description: "Chi hoa hồng đại lý " + client.Name,
+		PostedAt:    now,
+		CreatedBy:   userID,
+		Entries: []models.LedgerEntry{
+			debit(models.AccountCommissionPayable, amount),
+			credit(from, amount),
+		},
+	}
+	if err := s.create(transaction); err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
+// Backfill posts bookings made before the ledger existed, replaying their current
+// state: the sale and payment when booked, the cancellation or refund when last updated.
+// Refunds already paid back are not known and stay payable. Returns the number of
+// bookings posted.
+func (s *LedgerService) Backfill(batch int) (int, error) {
+	posted := 0
+	var afterID uint
+	for {
+		bookings, err := s.ledgerRepo.FindUnpostedBookings(afterID, batch)
+		if err != nil {
+			return posted, err
+		}
+		if len(bookings) == 0 {
+			return posted, nil
+		}
+
+		for i := range bookings {
+			booking := &bookings[i]
+			afterID = booking.ID
+			if err := s.RecordSale(booking, booking.CreatedAt); err != nil {
+				return posted, err
+			}
+			switch {
+			case booking.Status == models.BookingStatusCancelled:
+				err = s.RecordCancellation(booking, booking.UpdatedAt)
+			case booking.PaymentStatus == models.PaymentStatusRefunded:
+				err = s.RecordPaymentChange(booking, models.PaymentStatusPaid, models.PaymentStatusRefunded, booking.UpdatedAt)
+			}
+			if err != nil {
+				return posted, err
+			}
+			posted++
+		}
+	}
+}
+
+// post creates a journal entry for a booking, dropping zero lines. Nothing is posted
+// when every line is zero.
+func (s *LedgerService) post(operatorID uint, kind models.LedgerTransactionKind, booking *models.Booking, description string, now time.Time, entries []models.LedgerEntry) error {
+	lines := make([]models.LedgerEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Debit > 0 || entry.Credit > 0 {
+			lines = append(lines, entry)
+		}
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+
+	return s.create(&models.LedgerTransaction{
+		OperatorID:  operatorID,
+		Kind:        kind,
+		BookingID:   &booking.ID,
+		APIClientID: booking.APIClientID,
+		Description: description,
+		PostedAt:    now,
+		Entries:     lines,
+	})
+}
+
+// create validates a journal entry and stores it with its lines stamped from the header
+func (s *LedgerService) create(transaction *models.LedgerTransaction) error {
+	for i := range transaction.Entries {
+		transaction.Entries[i].OperatorID = transaction.OperatorID
+		transaction.Entries[i].PostedAt = transaction.PostedAt
+		transaction.Entries[i].BookingID = transaction.BookingID
+	}
+	if err := transaction.Validate(); err != nil {
+		return err
+	}
+	return s.ledgerRepo.Create(transaction)
+}
+
+func debit(account models.LedgerAccount, amount models.Money) models.LedgerEntry {
+	return models.LedgerEntry{Account: account, Debit: amount}
+}
+
+func credit(account models.LedgerAccount, amount models.Money) models.LedgerEntry {
+	return models.LedgerEntry{Account: account, Credit: amount}
+}
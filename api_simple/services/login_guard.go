@@ -0,0 +1,146 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrLoginLocked  = errors.New("login is temporarily locked")
+	ErrLoginBackoff = errors.New("login attempted too soon after a failure")
+)
+
+// LoginGuardConfig controls failure thresholds and backoff for login attempts
+type LoginGuardConfig struct {
+	BackoffAfter       int           // Failures before exponential backoff starts
+	BaseBackoff        time.Duration // First backoff delay, doubled for every further failure
+	MaxBackoff         time.Duration
+	MaxAccountFailures int // Failures before the account is locked
+	MaxIPFailures      int // Failures before the IP address is locked
+	LockoutDuration    time.Duration
+	FailureWindow      time.Duration // Counters reset when the last failure is older than this
+}
+
+// DefaultLoginGuardConfig returns the settings used by the API
+func DefaultLoginGuardConfig() LoginGuardConfig {
+	return LoginGuardConfig{
+		BackoffAfter:       3,
+		BaseBackoff:        2 * time.Second,
+		MaxBackoff:         5 * time.Minute,
+		MaxAccountFailures: 10,
+		MaxIPFailures:      30,
+		LockoutDuration:    30 * time.Minute,
+		FailureWindow:      time.Hour,
+	}
+}
+
+// LoginGuard throttles password logins per account and per IP address
+type LoginGuard struct {
+	repo *repository.LoginThrottleRepository
+	cfg  LoginGuardConfig
+}
+
+func NewLoginGuard(repo *repository.LoginThrottleRepository, cfg LoginGuardConfig) *LoginGuard {
+	return &LoginGuard{
+		repo: repo,
+		cfg:  cfg,
+	}
+}
+
+// Check returns ErrLoginLocked or ErrLoginBackoff with the remaining wait time
+// when either the account or the IP address may not attempt a login yet
+func (g *LoginGuard) Check(phone string, ip string) (time.Duration, error) {
+	now := time.Now()
+
+	for _, target := range []struct {
+		scope models.ThrottleScope
+		key   string
+	}{
+		{models.ThrottleScopeAccount, phone},
+		{models.ThrottleScopeIP, ip},
+	} {
+		throttle, err := g.repo.Find(target.scope, target.key)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return 0, err
+		}
+		if throttle.IsLocked(now) {
+			return throttle.RetryAfter(now), ErrLoginLocked
+		}
+		if wait := throttle.RetryAfter(now); wait > 0 {
+			return wait, ErrLoginBackoff
+		}
+	}
+
+	return 0, nil
+}
+
+// RecordFailure increments the account and IP counters and applies backoff or lockout
+func (g *LoginGuard) RecordFailure(phone string, ip string) error {
+	if err := g.recordFailure(models.ThrottleScopeAccount, phone, ip, g.cfg.MaxAccountFailures); err != nil {
+		return err
+	}
+	return g.recordFailure(models.ThrottleScopeIP, ip, ip, g.cfg.MaxIPFailures)
+}
+
+// RecordSuccess resets the account counters after a successful login.
+// IP counters are kept so one valid account cannot be used to reset them.
+func (g *LoginGuard) RecordSuccess(phone string) error {
+	return g.repo.Clear(models.ThrottleScopeAccount, phone)
+}
+
+// Unlock clears the lockout of an account
+func (g *LoginGuard) Unlock(phone string) error {
+	return g.repo.Clear(models.ThrottleScopeAccount, phone)
+}
+
+func (g *LoginGuard) recordFailure(scope models.ThrottleScope, key string, ip string, maxFailures int) error {
+	now := time.Now()
+
+	// The counter is incremented by the database so concurrent failures are all counted,
+	// and backoff and lockout follow the count it returns
+	throttle, err := g.repo.IncrementFailures(scope, key, ip, now, now.Add(-g.cfg.FailureWindow))
+	if err != nil {
+		return err
+	}
+	if throttle.Failures < g.cfg.BackoffAfter && throttle.Failures < maxFailures {
+		return nil
+	}
+
+	nextAllowedAt := now
+	if throttle.Failures >= g.cfg.BackoffAfter {
+		nextAllowedAt = now.Add(g.backoff(throttle.Failures))
+		log.Printf("[LoginGuard] Suspicious login activity: scope=%s key=%s ip=%s failures=%d",
+			scope, key, ip, throttle.Failures)
+	}
+
+	var lockedUntil *time.Time
+	if throttle.Failures >= maxFailures {
+		until := now.Add(g.cfg.LockoutDuration)
+		lockedUntil = &until
+		log.Printf("[LoginGuard] Locked login: scope=%s key=%s ip=%s until=%s",
+			scope, key, ip, until.Format(time.RFC3339))
+	}
+
+	return g.repo.Restrict(throttle.ID, nextAllowedAt, lockedUntil)
+}
+
+// backoff returns BaseBackoff doubled for every failure past BackoffAfter, capped at MaxBackoff
+func (g *LoginGuard) backoff(failures int) time.Duration {
+	delay := g.cfg.BaseBackoff
+	for i := g.cfg.BackoffAfter; i < failures; i++ {
+		delay *= 2
+		if delay >= g.cfg.MaxBackoff {
+			return g.cfg.MaxBackoff
+		}
+	}
+	return delay
+}
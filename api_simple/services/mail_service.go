@@ -0,0 +1,28 @@
+package services
+
+import (
+	"os"
+
+	"ticket-management/api_simple/providers"
+)
+
+// MailService delivers emails such as scheduled reports
+type MailService interface {
+	Send(email *providers.Email) error
+}
+
+// NewMailServiceFromEnv returns the email delivery provider selected by MAIL_PROVIDER
+// (smtp or log). Without an SMTP server configured the log provider is used.
+func NewMailServiceFromEnv() MailService {
+	provider := os.Getenv("MAIL_PROVIDER")
+	if provider == "" && os.Getenv("SMTP_HOST") != "" {
+		provider = "smtp"
+	}
+
+	switch provider {
+	case "smtp":
+		return providers.NewSMTPMailProvider()
+	default:
+		return providers.NewLogMailProvider()
+	}
+}
@@ -0,0 +1,327 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnavailabilityEnded = errors.New("unavailability window has already ended")
+	ErrInvalidWindowEnd    = errors.New("end time must be after start time")
+)
+
+const (
+	FleetAlertInspection   = "inspection"   // Đăng kiểm sắp hết hạn
+	FleetAlertRegistration = "registration" // Đăng ký xe sắp hết hạn
+	FleetAlertService      = "service"      // Đến hạn bảo dưỡng định kỳ
+)
+
+// MaintenanceConfig controls fleet alerts and how far ahead affected trips are listed
+type MaintenanceConfig struct {
+	AlertPeriod        time.Duration // Alert when papers or a service are due within this period
+	ServiceKmMargin    int           // Alert when the odometer is within this distance of the next service
+	AffectedTripPeriod time.Duration // List trips departing within this period after a bus goes down
+}
+
+// DefaultMaintenanceConfig returns the settings used by the API
+func DefaultMaintenanceConfig() MaintenanceConfig {
+	return MaintenanceConfig{
+		AlertPeriod:        30 * 24 * time.Hour,
+		ServiceKmMargin:    1000,
+		AffectedTripPeriod: 7 * 24 * time.Hour,
+	}
+}
+
+// MaintenanceConfigFromEnv returns DefaultMaintenanceConfig overridden by FLEET_ALERT_DAYS
+func MaintenanceConfigFromEnv() MaintenanceConfig {
+	cfg := DefaultMaintenanceConfig()
+	if v, err := strconv.Atoi(os.Getenv("FLEET_ALERT_DAYS")); err == nil && v >= 0 {
+		cfg.AlertPeriod = time.Duration(v) * 24 * time.Hour
+	}
+	return cfg
+}
+
+// FleetAlert is an upcoming or overdue inspection, registration or service of a bus
+type FleetAlert struct {
+	BusID       uint       `json:"bus_id"`
+	PlateNumber string     `json:"plate_number"`
+	Kind        string     `json:"kind"`                // inspection, registration hoặc service
+	DueAt       *time.Time `json:"due_at,omitempty"`    // Hạn theo ngày
+	DueKm       int        `json:"due_km,omitempty"`    // Hạn theo số km
+	OdometerKm  int        `json:"odometer_km"`         // Số km hiện tại
+	Overdue     bool       `json:"overdue"`             // Đã quá hạn
+	DaysLeft    *int       `json:"days_left,omitempty"` // Số ngày còn lại (âm nếu quá hạn)
+}
+
+// BusMaintenanceReport sums downtime and maintenance spending of a bus over a period
+type BusMaintenanceReport struct {
+	BusID            uint         `json:"bus_id"`
+	From             time.Time    `json:"from"`
+	To               time.Time    `json:"to"`
+	DowntimeHours    float64      `json:"downtime_hours"`    // Tổng số giờ ngừng hoạt động
+	MaintenanceCost  models.Money `json:"maintenance_cost"`  // Tổng chi phí bảo dưỡng, sửa chữa
+	MaintenanceCount int64        `json:"maintenance_count"` // Số lần bảo dưỡng, sửa chữa
+	UnavailableNow   bool         `json:"unavailable_now"`   // Xe đang ngừng hoạt động
+}
+
+// MaintenanceService records bus maintenance, keeps downtime windows and raises fleet alerts
+type MaintenanceService struct {
+	maintenanceRepo *repository.MaintenanceRepository
+	busRepo         *repository.BusRepository
+	tripRepo        *repository.TripRepository
+	cfg             MaintenanceConfig
+}
+
+func NewMaintenanceService(
+	maintenanceRepo *repository.MaintenanceRepository,
+	busRepo *repository.BusRepository,
+	tripRepo *repository.TripRepository,
+	cfg MaintenanceConfig,
+) *MaintenanceService {
+	return &MaintenanceService{
+		maintenanceRepo: maintenanceRepo,
+		busRepo:         busRepo,
+		tripRepo:        tripRepo,
+		cfg:             cfg,
+	}
+}
+
+// Record stores a maintenance record and updates the bus's odometer and paper expiry dates
+func (s *MaintenanceService) Record(record *models.MaintenanceRecord) error {
+	if err := record.Validate(); err != nil {
+		return err
+	}
+
+	bus, err := s.busRepo.FindByID(record.BusID)
+	if err != nil {
+		return err
+	}
+	if err := s.maintenanceRepo.CreateRecord(record); err != nil {
+		return err
+	}
+
+	// Older records may be entered late, so the odometer only moves forward
+	changed := false
+	if record.OdometerKm > bus.OdometerKm {
+		bus.OdometerKm = record.OdometerKm
+		changed = true
+	}
+	switch record.Type {
+	case models.MaintenanceTypeInspection:
+		if bus.InspectionExpiresAt == nil || record.NextDueAt.After(*bus.InspectionExpiresAt) {
+			bus.InspectionExpiresAt = record.NextDueAt
+			changed = true
+		}
+	case models.MaintenanceTypeRegistration:
+		if bus.RegistrationExpiresAt == nil || record.NextDueAt.After(*bus.RegistrationExpiresAt) {
+			bus.RegistrationExpiresAt = record.NextDueAt
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return s.busRepo.Update(bus)
+}
+
+// ReportBreakdown records a breakdown and takes the bus out of service until the
+// window is resolved. It returns the trips departing soon that need another bus.
+func (s *MaintenanceService) ReportBreakdown(record *models.MaintenanceRecord) (*models.BusUnavailability, []models.Trip, error) {
+	record.Type = models.MaintenanceTypeBreakdown
+	if err := s.Record(record); err != nil {
+		return nil, nil, err
+	}
+
+	window := &models.BusUnavailability{
+		BusID:               record.BusID,
+		StartsAt:            record.PerformedAt,
+		Reason:              "Xe hỏng: " + record.Description,
+		MaintenanceRecordID: &record.ID,
+	}
+	trips, err := s.Schedule(window)
+	if err != nil {
+		return nil, nil, err
+	}
+	return window, trips, nil
+}
+
+// Schedule creates an unavailability window and returns the trips that overlap it
+func (s *MaintenanceService) Schedule(window *models.BusUnavailability) ([]models.Trip, error) {
+	if err := window.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := s.busRepo.FindByID(window.BusID); err != nil {
+		return nil, err
+	}
+	if err := s.maintenanceRepo.CreateUnavailability(window); err != nil {
+		return nil, err
+	}
+	return s.AffectedTrips(window)
+}
+
+// Resolve ends an unavailability window at the given time
+func (s *MaintenanceService) Resolve(busID uint, windowID uint, at time.Time) (*models.BusUnavailability, error) {
+	if _, err := s.busRepo.FindByID(busID); err != nil {
+		return nil, err
+	}
+	window, err := s.maintenanceRepo.FindUnavailabilityByID(windowID)
+	if err != nil {
+		return nil, err
+	}
+	if window.BusID != busID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if window.EndsAt != nil && !window.EndsAt.After(at) {
+		return nil, ErrUnavailabilityEnded
+	}
+
+	if !at.After(window.StartsAt) {
+		return nil, ErrInvalidWindowEnd
+	}
+
+	window.EndsAt = &at
+	if err := s.maintenanceRepo.UpdateUnavailability(window); err != nil {
+		return nil, err
+	}
+	return window, nil
+}
+
+// AffectedTrips lists scheduled trips of the bus departing during the window.
+// Open-ended windows look ahead AffectedTripPeriod from their start.
+func (s *MaintenanceService) AffectedTrips(window *models.BusUnavailability) ([]models.Trip, error) {
+	end := window.StartsAt.Add(s.cfg.AffectedTripPeriod)
+	if window.EndsAt != nil {
+		end = *window.EndsAt
+	}
+	trips, err := s.tripRepo.FindScheduledForBus(window.BusID, window.StartsAt, end, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	affected := []models.Trip{}
+	for _, trip := range trips {
+		if !trip.IsCompleted {
+			affected = append(affected, trip)
+		}
+	}
+	return affected, nil
+}
+
+// Alerts lists inspections, registrations and services that are overdue or due within AlertPeriod
+func (s *MaintenanceService) Alerts(now time.Time) ([]FleetAlert, error) {
+	deadline := now.Add(s.cfg.AlertPeriod)
+	alerts := []FleetAlert{}
+
+	expiring, err := s.busRepo.FindDocumentsExpiringBefore(deadline)
+	if err != nil {
+		return nil, err
+	}
+	for _, bus := range expiring {
+		if bus.InspectionExpiresAt != nil && bus.InspectionExpiresAt.Before(deadline) {
+			alerts = append(alerts, newFleetAlert(bus, FleetAlertInspection, bus.InspectionExpiresAt, 0, now))
+		}
+		if bus.RegistrationExpiresAt != nil && bus.RegistrationExpiresAt.Before(deadline) {
+			alerts = append(alerts, newFleetAlert(bus, FleetAlertRegistration, bus.RegistrationExpiresAt, 0, now))
+		}
+	}
+
+	buses, err := s.busRepo.FindAll(map[string]interface{}{"is_active": true})
+	if err != nil {
+		return nil, err
+	}
+	for _, bus := range buses {
+		service, err := s.maintenanceRepo.FindLatestRecord(bus.ID, models.MaintenanceTypeService)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		dueByDate := service.NextDueAt != nil && service.NextDueAt.Before(deadline)
+		dueByKm := service.NextDueKm > 0 && bus.OdometerKm+s.cfg.ServiceKmMargin >= service.NextDueKm
+		if dueByDate || dueByKm {
+			alert := newFleetAlert(bus, FleetAlertService, service.NextDueAt, service.NextDueKm, now)
+			if service.NextDueKm > 0 && bus.OdometerKm >= service.NextDueKm {
+				alert.Overdue = true
+			}
+			alerts = append(alerts, alert)
+		}
+	}
+
+	sort.SliceStable(alerts, func(i, j int) bool {
+		if alerts[i].Overdue != alerts[j].Overdue {
+			return alerts[i].Overdue
+		}
+		if alerts[i].DueAt == nil || alerts[j].DueAt == nil {
+			return alerts[j].DueAt == nil && alerts[i].DueAt != nil
+		}
+		return alerts[i].DueAt.Before(*alerts[j].DueAt)
+	})
+	return alerts, nil
+}
+
+// Report sums downtime and maintenance cost of a bus within [from, to]
+func (s *MaintenanceService) Report(busID uint, from time.Time, to time.Time, now time.Time) (*BusMaintenanceReport, error) {
+	if _, err := s.busRepo.FindByID(busID); err != nil {
+		return nil, err
+	}
+
+	cost, count, err := s.maintenanceRepo.SumCostByBus(busID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	windows, err := s.maintenanceRepo.FindUnavailabilityBetween(busID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	// Open-ended windows only count as downtime until now
+	until := to
+	if now.Before(until) {
+		until = now
+	}
+	report := &BusMaintenanceReport{
+		BusID:            busID,
+		From:             from,
+		To:               to,
+		MaintenanceCost:  cost,
+		MaintenanceCount: count,
+	}
+	var downtime time.Duration
+	for i := range windows {
+		downtime += windows[i].DurationWithin(from, until)
+	}
+	report.DowntimeHours = downtime.Hours()
+
+	current, err := s.maintenanceRepo.FindUnavailabilityBetween(busID, now, now.Add(time.Second))
+	if err != nil {
+		return nil, err
+	}
+	report.UnavailableNow = len(current) > 0
+	return report, nil
+}
+
+func newFleetAlert(bus models.Bus, kind string, dueAt *time.Time, dueKm int, now time.Time) FleetAlert {
+	alert := FleetAlert{
+		BusID:       bus.ID,
+		PlateNumber: bus.PlateNumber,
+		Kind:        kind,
+		DueAt:       dueAt,
+		DueKm:       dueKm,
+		OdometerKm:  bus.OdometerKm,
+	}
+	if dueAt != nil {
+		days := int(dueAt.Sub(startOfDay(now)).Hours() / 24)
+		alert.DaysLeft = &days
+		alert.Overdue = !now.Before(*dueAt)
+	}
+	return alert
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+)
+
+var (
+	ErrOperatorCodeTaken = errors.New("operator code is already in use")
+	ErrOperatorMismatch  = errors.New("route, bus and driver must belong to the same operator")
+)
+
+// OperatorService manages bus operators, their settings and the rules that keep
+// each operator's data together
+type OperatorService struct {
+	operatorRepo *repository.OperatorRepository
+	accountRepo  *repository.PaymentAccountRepository
+	routeRepo    *repository.RouteRepository
+	busRepo      *repository.BusRepository
+	userRepo     *repository.UserRepository
+	bookingRepo  *repository.BookingRepository
+}
+
+func NewOperatorService(
+	operatorRepo *repository.OperatorRepository,
+	accountRepo *repository.PaymentAccountRepository,
+	routeRepo *repository.RouteRepository,
+	busRepo *repository.BusRepository,
+	userRepo *repository.UserRepository,
+	bookingRepo *repository.BookingRepository,
+) *OperatorService {
+	return &OperatorService{
+		operatorRepo: operatorRepo,
+		accountRepo:  accountRepo,
+		routeRepo:    routeRepo,
+		busRepo:      busRepo,
+		userRepo:     userRepo,
+		bookingRepo:  bookingRepo,
+	}
+}
+
+// Save creates or updates an operator after checking its settings and code
+func (s *OperatorService) Save(operator *models.Operator) error {
+	if err := operator.Validate(); err != nil {
+		return err
+	}
+
+	existing, err := s.operatorRepo.FindOne(map[string]interface{}{"code": operator.Code})
+	if err == nil && existing.ID != operator.ID {
+		return ErrOperatorCodeTaken
+	}
+
+	if operator.ID == 0 {
+		return s.operatorRepo.Create(operator)
+	}
+	return s.operatorRepo.Update(operator)
+}
+
+// SaveAccount creates or updates a payment account; a new default replaces the previous one
+func (s *OperatorService) SaveAccount(account *models.OperatorPaymentAccount) error {
+	if err := account.Validate(); err != nil {
+		return err
+	}
+
+	var err error
+	if account.ID == 0 {
+		err = s.accountRepo.Create(account)
+	} else {
+		err = s.accountRepo.Update(account)
+	}
+	if err != nil || !account.IsDefault {
+		return err
+	}
+	return s.accountRepo.ClearDefault(account.OperatorID, account.ID)
+}
+
+// AssignTrip checks that the route, bus and driver of a trip belong to one
+// operator and makes the trip part of it
+func (s *OperatorService) AssignTrip(trip *models.Trip) error {
+	route, err := s.routeRepo.FindByID(trip.RouteID)
+	if err != nil {
+		return err
+	}
+	bus, err := s.busRepo.FindByID(trip.BusID)
+	if err != nil {
+		return err
+	}
+	driver, err := s.userRepo.FindByID(trip.DriverID)
+	if err != nil {
+		return err
+	}
+
+	if bus.OperatorID != route.OperatorID || driver.OperatorID == nil || *driver.OperatorID != route.OperatorID {
+		return ErrOperatorMismatch
+	}
+	trip.OperatorID = route.OperatorID
+	return nil
+}
+
+// Refund applies the operator's refund policy to a paid booking being cancelled
+// and returns the amount refunded. Unpaid bookings are not refunded.
+func (s *OperatorService) Refund(booking *models.Booking, departure, now time.Time) (models.Money, error) {
+	if booking.PaymentStatus != models.PaymentStatusPaid {
+		return 0, nil
+	}
+
+	operator, err := s.operatorRepo.FindByID(booking.OperatorID)
+	if err != nil {
+		return 0, err
+	}
+
+	amount := booking.TotalAmount.Percent(operator.RefundPercent(departure, now)).RoundTo(models.PriceStep)
+	if err := s.bookingRepo.MarkRefunded(booking.ID, amount); err != nil {
+		return 0, err
+	}
+	booking.PaymentStatus = models.PaymentStatusRefunded
+	booking.RefundAmount = amount
+	return amount, nil
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"ticket-management/api_simple/config"
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrOTPInvalid  = errors.New("otp is invalid")
+	ErrOTPExpired  = errors.New("otp has expired")
+	ErrOTPLocked   = errors.New("otp verification is locked")
+	ErrOTPCooldown = errors.New("otp was sent too recently")
+)
+
+// OTPConfig controls code lifetime, attempt limits and resend throttling
+type OTPConfig struct {
+	CodeLength      int
+	TTL             time.Duration
+	MaxAttempts     int
+	LockoutDuration time.Duration
+	ResendCooldown  time.Duration
+}
+
+// DefaultOTPConfig returns the settings used by the API
+func DefaultOTPConfig() OTPConfig {
+	return OTPConfig{
+		CodeLength:      6,
+		TTL:             5 * time.Minute,
+		MaxAttempts:     5,
+		LockoutDuration: 15 * time.Minute,
+		ResendCooldown:  60 * time.Second,
+	}
+}
+
+// OTPService issues and verifies purpose-scoped one-time passwords
+type OTPService struct {
+	repo   *repository.OTPRepository
+	sender SMSService
+	cfg    OTPConfig
+}
+
+func NewOTPService(repo *repository.OTPRepository, sender SMSService, cfg OTPConfig) *OTPService {
+	return &OTPService{
+		repo:   repo,
+		sender: sender,
+		cfg:    cfg,
+	}
+}
+
+// Send generates a new code for the phone and purpose, delivers it by SMS and
+// stores its hash once sent. Any previously issued code for the same purpose is invalidated.
+func (s *OTPService) Send(phone string, purpose models.OTPPurpose) error {
+	now := time.Now()
+
+	latest, err := s.repo.FindLatest(phone, purpose)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if latest != nil {
+		if latest.IsLocked(now) {
+			return ErrOTPLocked
+		}
+		if now.Sub(latest.SentAt) < s.cfg.ResendCooldown {
+			return ErrOTPCooldown
+		}
+	}
+
+	code, err := generateNumericCode(s.cfg.CodeLength)
+	if err != nil {
+		return err
+	}
+
+	// Only a delivered code is stored, so a failed SMS neither starts the resend
+	// cooldown nor invalidates the code the user already has
+	if err := s.sender.Send(phone, otpMessage(purpose, code, s.cfg.TTL)); err != nil {
+		return err
+	}
+
+	if err := s.repo.InvalidateAll(phone, purpose); err != nil {
+		return err
+	}
+
+	return s.repo.Create(&models.OTPCode{
+		Phone:     phone,
+		Purpose:   purpose,
+		CodeHash:  hashOTP(phone, purpose, code),
+		ExpiresAt: now.Add(s.cfg.TTL),
+		SentAt:    now,
+	})
+}
+
+// Verify checks a code and consumes it on success. Wrong codes increase the
+// attempt counter and lock verification once MaxAttempts is reached.
+func (s *OTPService) Verify(phone string, purpose models.OTPPurpose, code string) error {
+	now := time.Now()
+
+	otp, err := s.repo.FindLatest(phone, purpose)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrOTPInvalid
+		}
+		return err
+	}
+
+	// A code that used up its attempts stays unusable after the lockout ends; the
+	// user has to request a new one
+	if otp.IsLocked(now) || otp.Attempts >= s.cfg.MaxAttempts {
+		return ErrOTPLocked
+	}
+	if otp.IsExpired(now) {
+		return ErrOTPExpired
+	}
+
+	if !hmac.Equal([]byte(otp.CodeHash), []byte(hashOTP(phone, purpose, code))) {
+		counted, err := s.repo.RecordFailedAttempt(otp.ID, s.cfg.MaxAttempts, now.Add(s.cfg.LockoutDuration))
+		if err != nil {
+			return err
+		}
+		if !counted || otp.Attempts+1 >= s.cfg.MaxAttempts {
+			return ErrOTPLocked
+		}
+		return ErrOTPInvalid
+	}
+
+	consumed, err := s.repo.Consume(otp.ID, now)
+	if err != nil {
+		return err
+	}
+	if !consumed {
+		return ErrOTPInvalid
+	}
+	return nil
+}
+
+// hashOTP binds the code to its phone and purpose so a hash cannot be replayed elsewhere
+func hashOTP(phone string, purpose models.OTPPurpose, code string) string {
+	secret := os.Getenv("OTP_SECRET")
+	if secret == "" {
+		secret = config.JWTSecret
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(phone + ":" + string(purpose) + ":" + code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// generateNumericCode generates a random numeric code of the given length
+func generateNumericCode(length int) (string, error) {
+	code := make([]byte, length)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		code[i] = byte('0' + n.Int64())
+	}
+	return string(code), nil
+}
+
+// otpMessage builds the SMS body for a purpose
+func otpMessage(purpose models.OTPPurpose, code string, ttl time.Duration) string {
+	action := map[models.OTPPurpose]string{
+		models.OTPPurposeRegister:      "xác minh đăng ký",
+		models.OTPPurposeResetPassword: "đặt lại mật khẩu",
+		models.OTPPurposeLogin:         "đăng nhập",
+		models.OTPPurposeGuestLookup:   "tra cứu vé",
+		models.OTPPurposeSupport:       "gửi yêu cầu hỗ trợ",
+	}[purpose]
+	return fmt.Sprintf("Mã OTP %s của bạn là %s. Mã có hiệu lực trong %d phút. Không chia sẻ mã này với bất kỳ ai.",
+		action, code, int(ttl.Minutes()))
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter is an in-memory fixed window limiter keyed by caller
+type RateLimiter struct {
+	mu      sync.Mutex
+	window  time.Duration
+	windows map[string]*rateWindow
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+func NewRateLimiter(window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		window:  window,
+		windows: make(map[string]*rateWindow),
+	}
+}
+
+// Allow counts a request for key and reports whether it is within limit.
+// It also returns the remaining requests and the time until the window resets.
+func (l *RateLimiter) Allow(key string, limit int, now time.Time) (bool, int, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= l.window {
+		w = &rateWindow{start: now}
+		l.windows[key] = w
+	}
+
+	reset := w.start.Add(l.window).Sub(now)
+	if w.count >= limit {
+		return false, 0, reset
+	}
+
+	w.count++
+	return true, limit - w.count, reset
+}
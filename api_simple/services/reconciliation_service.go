@@ -0,0 +1,364 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidSettlementFile     = errors.New("invalid settlement file")
+	ErrSettlementEmpty           = errors.New("settlement file has no transactions")
+	ErrSettlementAlreadyImported = errors.New("settlement file has already been imported")
+	ErrInvalidSettlementProvider = errors.New("unknown payment provider")
+	ErrEntryNotOpen              = errors.New("entry has no open discrepancy")
+	ErrResolutionNoteRequired    = errors.New("a note is required to accept a discrepancy")
+	ErrInvalidResolution         = errors.New("unknown resolution action")
+	ErrLinkRequiresSettlement    = errors.New("only transactions from a settlement file can be linked to a booking")
+)
+
+// settlementColumns maps the columns of a settlement file to the header names providers use
+var settlementColumns = map[string][]string{
+	"reference": {"reference", "transaction_ref", "txn_ref", "order_id", "ma_giao_dich"},
+	"amount":    {"amount", "gross_amount", "so_tien"},
+	"fee":       {"fee", "fee_amount", "phi"},
+	"paid_at":   {"paid_at", "transaction_time", "pay_date", "thoi_gian"},
+}
+
+// settlementTimeLayouts are the timestamp formats accepted in settlement files
+var settlementTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"02/01/2006 15:04:05",
+	"20060102150405",
+}
+
+// SettlementRow is one transaction of a settlement file
+type SettlementRow struct {
+	Line      int
+	Reference string
+	Amount    models.Money // Số tiền khách thanh toán, chưa trừ phí
+	Fee       models.Money
+	PaidAt    *time.Time
+}
+
+// ParseSettlementCSV reads the transactions of a settlement file. The header row names
+// the columns: a transaction reference and amount are required, fee and payment time
+// are optional. Amounts are plain numbers in đồng, optionally with comma separators.
+func ParseSettlementCSV(data []byte) ([]SettlementRow, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, ErrSettlementEmpty
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSettlementFile, err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		for column, aliases := range settlementColumns {
+			for _, alias := range aliases {
+				if name == alias {
+					columns[column] = i
+				}
+			}
+		}
+	}
+	if _, ok := columns["reference"]; !ok {
+		return nil, fmt.Errorf("%w: missing transaction reference column", ErrInvalidSettlementFile)
+	}
+	if _, ok := columns["amount"]; !ok {
+		return nil, fmt.Errorf("%w: missing amount column", ErrInvalidSettlementFile)
+	}
+
+	var rows []SettlementRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSettlementFile, err)
+		}
+		line, _ := reader.FieldPos(0)
+		field := func(column string) string {
+			if i, ok := columns[column]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		if strings.Join(record, "") == "" {
+			continue
+		}
+
+		row := SettlementRow{Line: line, Reference: field("reference")}
+		if row.Reference == "" {
+			return nil, fmt.Errorf("%w: line %d: missing transaction reference", ErrInvalidSettlementFile, line)
+		}
+		if row.Amount, err = models.ParseMoney(field("amount")); err != nil {
+			return nil, fmt.Errorf("%w: line %d: invalid amount", ErrInvalidSettlementFile, line)
+		}
+		if fee := field("fee"); fee != "" {
+			if row.Fee, err = models.ParseMoney(fee); err != nil {
+				return nil, fmt.Errorf("%w: line %d: invalid fee", ErrInvalidSettlementFile, line)
+			}
+		}
+		if paidAt := field("paid_at"); paidAt != "" {
+			t, ok := parseSettlementTime(paidAt)
+			if !ok {
+				return nil, fmt.Errorf("%w: line %d: invalid payment time", ErrInvalidSettlementFile, line)
+			}
+			row.PaidAt = &t
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return nil, ErrSettlementEmpty
+	}
+	return rows, nil
+}
+
+func parseSettlementTime(value string) (time.Time, bool) {
+	for _, layout := range settlementTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// ReconciliationService matches the settlement files of payment gateways against the
+// payments recorded on bookings, by transaction reference and amount, and tracks how
+// staff resolve the discrepancies
+type ReconciliationService struct {
+	reconRepo   *repository.ReconciliationRepository
+	bookingRepo *repository.BookingRepository
+}
+
+func NewReconciliationService(
+	reconRepo *repository.ReconciliationRepository,
+	bookingRepo *repository.BookingRepository,
+) *ReconciliationService {
+	return &ReconciliationService{
+		reconRepo:   reconRepo,
+		bookingRepo: bookingRepo,
+	}
+}
+
+// Import reconciles a settlement file of an operator. Every transaction of the file is
+// matched to the booking carrying its reference; paid bookings of the period the file
+// covers that it does not mention are flagged as missing from the settlement.
+func (s *ReconciliationService) Import(operatorID uint, provider models.PaymentAccountMethod, fileName string, data []byte, importedBy *uint) (*models.SettlementReport, error) {
+	if !provider.IsValid() || provider == models.PaymentAccountBank {
+		return nil, ErrInvalidSettlementProvider
+	}
+
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+	if imported, err := s.reconRepo.HasChecksum(checksum); err != nil {
+		return nil, err
+	} else if imported {
+		return nil, ErrSettlementAlreadyImported
+	}
+
+	rows, err := ParseSettlementCSV(data)
+	if err != nil {
+		return nil, err
+	}
+
+	references := make([]string, 0, len(rows))
+	for _, row := range rows {
+		references = append(references, row.Reference)
+	}
+	settled, err := s.reconRepo.FindSettledReferences(references)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.SettlementReport{
+		OperatorID: operatorID,
+		Provider:   provider,
+		FileName:   fileName,
+		Checksum:   checksum,
+		RowCount:   len(rows),
+		ImportedBy: importedBy,
+	}
+	seen := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		entry := models.SettlementEntry{
+			OperatorID: operatorID,
+			Line:       row.Line,
+			Reference:  row.Reference,
+			Amount:     row.Amount,
+			Fee:        row.Fee,
+			PaidAt:     row.PaidAt,
+		}
+		booking, err := s.bookingRepo.FindByPaymentReference(row.Reference)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		switch {
+		case seen[row.Reference] || settled[row.Reference]:
+			entry.Status = models.ReconciliationDuplicate
+			if booking != nil {
+				entry.BookingID = &booking.ID
+				entry.ExpectedAmount = booking.TotalAmount
+			}
+		case booking == nil:
+			entry.Status = models.ReconciliationMissingBooking
+		default:
+			matchEntry(&entry, booking)
+		}
+		seen[row.Reference] = true
+		report.Entries = append(report.Entries, entry)
+
+		report.SettledAmount += row.Amount
+		report.FeeAmount += row.Fee
+		if row.PaidAt != nil {
+			if report.PeriodFrom == nil || row.PaidAt.Before(*report.PeriodFrom) {
+				report.PeriodFrom = row.PaidAt
+			}
+			if report.PeriodTo == nil || row.PaidAt.After(*report.PeriodTo) {
+				report.PeriodTo = row.PaidAt
+			}
+		}
+	}
+
+	if report.PeriodFrom != nil {
+		missing, err := s.missingFromSettlement(provider, *report.PeriodFrom, *report.PeriodTo, seen)
+		if err != nil {
+			return nil, err
+		}
+		report.Entries = append(report.Entries, missing...)
+	}
+
+	for i := range report.Entries {
+		if report.Entries[i].Status.IsIssue() {
+			report.IssueCount++
+		} else {
+			report.MatchedCount++
+		}
+	}
+	if err := s.reconRepo.CreateReport(report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// missingFromSettlement flags the bookings paid through the provider between from and
+// to that the file does not list and no earlier reconciliation has accounted for
+func (s *ReconciliationService) missingFromSettlement(provider models.PaymentAccountMethod, from, to time.Time, listed map[string]bool) ([]models.SettlementEntry, error) {
+	bookings, err := s.bookingRepo.FindPaidWithReference(provider, from, to)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, 0, len(bookings))
+	for _, booking := range bookings {
+		ids = append(ids, booking.ID)
+	}
+	reconciled, err := s.reconRepo.FindReconciledBookingIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []models.SettlementEntry
+	for i := range bookings {
+		booking := &bookings[i]
+		if listed[*booking.PaymentReference] || reconciled[booking.ID] {
+			continue
+		}
+		entries = append(entries, models.SettlementEntry{
+			OperatorID:     booking.OperatorID,
+			Reference:      *booking.PaymentReference,
+			PaidAt:         booking.PaidAt,
+			BookingID:      &booking.ID,
+			ExpectedAmount: booking.TotalAmount,
+			Status:         models.ReconciliationMissingSettlement,
+		})
+	}
+	return entries, nil
+}
+
+// matchEntry compares a settled transaction with the booking it belongs to
+func matchEntry(entry *models.SettlementEntry, booking *models.Booking) {
+	entry.BookingID = &booking.ID
+	entry.ExpectedAmount = booking.TotalAmount
+	switch {
+	case booking.PaymentStatus == models.PaymentStatusUnpaid:
+		entry.Status = models.ReconciliationUnpaidBooking
+	case entry.Amount != booking.TotalAmount:
+		entry.Status = models.ReconciliationAmountMismatch
+	default:
+		entry.Status = models.ReconciliationMatched
+	}
+}
+
+// Resolve closes a discrepancy. Accepting it requires a note explaining it; linking
+// attaches a settled transaction to the right booking and matches it again, the entry
+// staying open when the booking still does not agree with the settlement.
+func (s *ReconciliationService) Resolve(entryID uint, action string, bookingID uint, note string, userID uint, now time.Time) (*models.SettlementEntry, error) {
+	entry, err := s.reconRepo.FindEntryByID(entryID)
+	if err != nil {
+		return nil, err
+	}
+	if !entry.IsOpen() {
+		return nil, ErrEntryNotOpen
+	}
+
+	switch action {
+	case models.ResolutionAccept:
+		if strings.TrimSpace(note) == "" {
+			return nil, ErrResolutionNoteRequired
+		}
+	case models.ResolutionLink:
+		if entry.Line == 0 {
+			return nil, ErrLinkRequiresSettlement
+		}
+		booking, err := s.bookingRepo.FindByID(bookingID)
+		if err != nil {
+			return nil, err
+		}
+		// A transaction the system did not know about becomes the booking's payment reference
+		if entry.Status == models.ReconciliationMissingBooking && booking.PaymentReference == nil {
+			report, err := s.reconRepo.FindReportByID(entry.ReportID)
+			if err != nil {
+				return nil, err
+			}
+			if err := s.bookingRepo.SetPaymentReference(booking.ID, report.Provider, entry.Reference); err != nil {
+				return nil, err
+			}
+		}
+		matchEntry(entry, booking)
+	default:
+		return nil, ErrInvalidResolution
+	}
+
+	entry.ResolutionNote = note
+	if !entry.Status.IsIssue() || action == models.ResolutionAccept {
+		entry.Resolution = action
+		entry.ResolvedBy = &userID
+		entry.ResolvedAt = &now
+	}
+	if err := s.reconRepo.UpdateEntry(entry); err != nil {
+		return nil, err
+	}
+	if err := s.reconRepo.RefreshReportCounts(entry.ReportID); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
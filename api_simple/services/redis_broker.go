@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisBroker fans messages out across API instances through Redis pub/sub.
+// Each instance keeps one pattern subscription and hands messages to local subscribers.
+type RedisBroker struct {
+	client *redis.Client
+	prefix string
+	local  *MemoryBroker
+}
+
+// NewRedisBroker creates a broker publishing on Redis channels named prefix+topic
+func NewRedisBroker(client *redis.Client, prefix string, buffer int) *RedisBroker {
+	return &RedisBroker{
+		client: client,
+		prefix: prefix,
+		local:  NewMemoryBroker(buffer),
+	}
+}
+
+// Publish sends payload to every instance subscribed to topic
+func (b *RedisBroker) Publish(topic string, payload []byte) error {
+	return b.client.Publish(context.Background(), b.prefix+topic, payload).Err()
+}
+
+// Subscribe registers a local subscriber; messages arrive once Run is receiving
+func (b *RedisBroker) Subscribe(topic string) (<-chan []byte, func()) {
+	return b.local.Subscribe(topic)
+}
+
+// Run relays messages from Redis to local subscribers until ctx is cancelled.
+// ready, if not nil, is closed once the subscription is active.
+func (b *RedisBroker) Run(ctx context.Context, ready chan<- struct{}) error {
+	pubsub := b.client.PSubscribe(ctx, b.prefix+"*")
+	defer pubsub.Close()
+
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return err
+	}
+	if ready != nil {
+		close(ready)
+	}
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-messages:
+			if !ok {
+				return nil
+			}
+			if err := b.local.Publish(strings.TrimPrefix(msg.Channel, b.prefix), []byte(msg.Payload)); err != nil {
+				log.Printf("Error relaying event on %s: %v", msg.Channel, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/utils"
+)
+
+// bookingExportBatchSize is the number of bookings an export loads at a time
+const bookingExportBatchSize = 500
+
+var ErrInvalidReportGroup = errors.New("invalid report grouping")
+
+// bookingExportHeader are the columns of a bookings export
+var bookingExportHeader = []string{
+	"id", "booking_code", "created_at", "status", "payment_status", "payment_type", "paid_at",
+	"customer_name", "customer_phone", "customer_email", "route", "departure_time", "plate_number",
+	"seats", "total_amount", "refund_amount", "commission_amount",
+}
+
+// ReportService builds the revenue, occupancy and bookings reports of an operator and
+// writes them as CSV or XLSX files
+type ReportService struct {
+	reportRepo  *repository.ReportRepository
+	bookingRepo *repository.BookingRepository
+}
+
+func NewReportService(reportRepo *repository.ReportRepository, bookingRepo *repository.BookingRepository) *ReportService {
+	return &ReportService{
+		reportRepo:  reportRepo,
+		bookingRepo: bookingRepo,
+	}
+}
+
+// Revenue returns the revenue posted to the ledger grouped by day, week, month, route,
+// bus or sales channel
+func (s *ReportService) Revenue(filter repository.ReportFilter, groupBy string) ([]repository.RevenueRow, error) {
+	switch groupBy {
+	case string(repository.ReportDaily), string(repository.ReportWeekly), string(repository.ReportMonthly):
+		return s.reportRepo.RevenueByPeriod(filter, repository.ReportGranularity(groupBy))
+	case "route":
+		return s.reportRepo.RevenueByRoute(filter)
+	case "bus":
+		return s.reportRepo.RevenueByBus(filter)
+	case "channel":
+		return s.reportRepo.RevenueByChannel(filter)
+	}
+	return nil, ErrInvalidReportGroup
+}
+
+// RevenueTable lays out a revenue report as the columns and rows of a file
+func RevenueTable(groupBy string, rows []repository.RevenueRow) ([]string, [][]interface{}) {
+	header := []string{"period", "revenue", "bookings"}
+	switch groupBy {
+	case "route", "bus":
+		header = []string{groupBy + "_id", groupBy, "revenue", "bookings"}
+	case "channel":
+		header = []string{"channel", "revenue", "bookings"}
+	}
+	cells := make([][]interface{}, len(rows))
+	for i, row := range rows {
+		switch groupBy {
+		case "route", "bus":
+			cells[i] = []interface{}{int64(row.ID), row.Name, int64(row.Revenue), row.Bookings}
+		case "channel":
+			cells[i] = []interface{}{row.Name, int64(row.Revenue), row.Bookings}
+		default:
+			cells[i] = []interface{}{row.Period, int64(row.Revenue), row.Bookings}
+		}
+	}
+	return header, cells
+}
+
+// OccupancyTable lays out an occupancy report per trip or per route as the columns and
+// rows of a file
+func OccupancyTable(groupBy string, rows []repository.OccupancyRow) ([]string, [][]interface{}) {
+	cells := make([][]interface{}, len(rows))
+	if groupBy == "route" {
+		for i, row := range rows {
+			cells[i] = []interface{}{int64(row.ID), row.Name, row.Trips, row.Seats, row.Booked, row.LoadFactor}
+		}
+		return []string{"route_id", "route", "trips", "seats", "booked", "load_factor"}, cells
+	}
+
+	for i, row := range rows {
+		departure := ""
+		if row.DepartureTime != nil {
+			departure = row.DepartureTime.Format("2006-01-02 15:04")
+		}
+		cells[i] = []interface{}{int64(row.ID), row.Name, departure, row.Seats, row.Booked, row.LoadFactor}
+	}
+	return []string{"trip_id", "route", "departure_time", "seats", "booked", "load_factor"}, cells
+}
+
+// NewTableWriter starts a file of the given format on w. The sheet name of XLSX files is name.
+func NewTableWriter(w io.Writer, format models.ReportFormat, name string, header []string) (utils.TableWriter, error) {
+	if format == models.ReportFormatXLSX {
+		return utils.NewXLSXWriter(w, name, header)
+	}
+	return utils.NewCSVWriter(w, header)
+}
+
+// ExportBookings writes the bookings matching filter to w, loading them a batch at a
+// time so that exports of any size use little memory
+func (s *ReportService) ExportBookings(w io.Writer, format models.ReportFormat, filter repository.BookingFilter) error {
+	table, err := NewTableWriter(w, format, "bookings", bookingExportHeader)
+	if err != nil {
+		return err
+	}
+	err = s.bookingRepo.FindInBatches(filter, bookingExportBatchSize, func(bookings []models.Booking) error {
+		for i := range bookings {
+			if err := table.WriteRow(bookingExportRow(&bookings[i])); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return table.Close()
+}
+
+// Export writes a report covering from to to (inclusive) to w
+func (s *ReportService) Export(w io.Writer, format models.ReportFormat, kind models.ReportKind, filters models.ReportFilters, from, to time.Time) error {
+	groupBy := filters.Grouping(kind)
+	reportFilter := repository.ReportFilter{From: &from, To: &to, RouteID: filters.RouteID, BusID: filters.BusID}
+
+	var header []string
+	var cells [][]interface{}
+	switch kind {
+	case models.ReportKindBookings:
+		return s.ExportBookings(w, format, repository.BookingFilter{Status: filters.Status, From: &from, To: &to})
+	case models.ReportKindRevenue:
+		rows, err := s.Revenue(reportFilter, groupBy)
+		if err != nil {
+			return err
+		}
+		header, cells = RevenueTable(groupBy, rows)
+	case models.ReportKindOccupancy:
+		var rows []repository.OccupancyRow
+		var err error
+		switch groupBy {
+		case "trip":
+			rows, _, err = s.reportRepo.OccupancyByTrip(reportFilter, 1, 0)
+		case "route":
+			rows, err = s.reportRepo.OccupancyByRoute(reportFilter)
+		default:
+			err = ErrInvalidReportGroup
+		}
+		if err != nil {
+			return err
+		}
+		header, cells = OccupancyTable(groupBy, rows)
+	default:
+		return ErrInvalidReportGroup
+	}
+
+	table, err := NewTableWriter(w, format, string(kind)+"-"+groupBy, header)
+	if err != nil {
+		return err
+	}
+	for _, row := range cells {
+		if err := table.WriteRow(row); err != nil {
+			return err
+		}
+	}
+	return table.Close()
+}
+
+// bookingExportRow lays out a booking as a row of a bookings export
+func bookingExportRow(b *models.Booking) []interface{} {
+	var name, phone, email string
+	if b.GuestInfo != nil && b.GuestInfo.Phone != "" {
+		name, phone, email = b.GuestInfo.Name, b.GuestInfo.Phone, b.GuestInfo.Email
+	} else if b.User != nil {
+		name, phone = b.User.Name, b.User.Phone
+	}
+
+	var route, departure, plate string
+	if b.Trip != nil {
+		departure = b.Trip.DepartureTime.Local().Format("2006-01-02 15:04")
+		if b.Trip.Route != nil {
+			route = b.Trip.Route.Origin + " - " + b.Trip.Route.Destination
+		}
+		if b.Trip.Bus != nil {
+			plate = b.Trip.Bus.PlateNumber
+		}
+	}
+
+	seats := make([]string, len(b.Seats))
+	for i, seat := range b.Seats {
+		seats[i] = seat.Number
+	}
+
+	paidAt := ""
+	if b.PaidAt != nil {
+		paidAt = b.PaidAt.Local().Format("2006-01-02 15:04:05")
+	}
+
+	return []interface{}{
+		int64(b.ID), b.BookingCode, b.CreatedAt.Local().Format("2006-01-02 15:04:05"),
+		string(b.Status), string(b.PaymentStatus), string(b.PaymentType), paidAt,
+		name, phone, email, route, departure, plate,
+		strings.Join(seats, ", "), int64(b.TotalAmount), int64(b.RefundAmount), int64(b.CommissionAmount),
+	}
+}
@@ -0,0 +1,302 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrPointNotOnRoute  = errors.New("point is not an active point of the trip's route")
+	ErrPointWrongType   = errors.New("point type does not match")
+	ErrPointOrder       = errors.New("drop-off point must come after the pickup point")
+	ErrPointBeyondRoute = errors.New("point offset exceeds the route duration")
+)
+
+// BoardingSelection is the pickup and drop-off points chosen for a booking
+type BoardingSelection struct {
+	Pickup    *models.RoutePoint
+	Dropoff   *models.RoutePoint
+	Surcharge models.Money // Phụ phí trung chuyển cho toàn bộ hành khách của đơn
+}
+
+// Apply stores the selected points on the booking and adds the surcharge to its total
+func (sel *BoardingSelection) Apply(booking *models.Booking) {
+	if sel.Pickup != nil {
+		booking.PickupPointID = &sel.Pickup.ID
+		booking.PickupPoint = sel.Pickup
+	}
+	if sel.Dropoff != nil {
+		booking.DropoffPointID = &sel.Dropoff.ID
+		booking.DropoffPoint = sel.Dropoff
+	}
+	booking.SurchargeAmount = sel.Surcharge
+	booking.TotalAmount += sel.Surcharge
+}
+
+// ManifestStop is a place where the bus picks up or drops off passengers
+type ManifestStop struct {
+	PointID     *uint                 `json:"point_id,omitempty"` // Trống: bến đầu/cuối của tuyến
+	Type        models.RoutePointType `json:"type"`
+	Name        string                `json:"name"`
+	Address     string                `json:"address,omitempty"`
+	Shuttle     bool                  `json:"shuttle"`
+	ScheduledAt time.Time             `json:"scheduled_at"`
+	Passengers  int                   `json:"passengers"` // Số khách lên/xuống tại điểm
+}
+
+// ManifestPassenger is a booking on the passenger list of a trip
+type ManifestPassenger struct {
+	BookingID     uint                 `json:"booking_id"`
+	BookingCode   string               `json:"booking_code"`
+	Name          string               `json:"name"`
+	Phone         string               `json:"phone"`
+	Seats         []string             `json:"seats"`
+	PickupName    string               `json:"pickup_name"`
+	PickupAt      time.Time            `json:"pickup_at"`
+	PickupShuttle bool                 `json:"pickup_shuttle"`
+	DropoffName   string               `json:"dropoff_name"`
+	DropoffAt     time.Time            `json:"dropoff_at"`
+	Status        models.BookingStatus `json:"status"`
+	PaymentStatus models.PaymentStatus `json:"payment_status"`
+	TotalAmount   models.Money         `json:"total_amount"`
+	Note          string               `json:"note,omitempty"`
+}
+
+// TripManifest is the passenger list of a trip grouped by boarding point
+type TripManifest struct {
+	TripID          uint                `json:"trip_id"`
+	Origin          string              `json:"origin"`
+	Destination     string              `json:"destination"`
+	DepartureTime   time.Time           `json:"departure_time"`
+	PlateNumber     string              `json:"plate_number"`
+	DriverName      string              `json:"driver_name"`
+	TotalPassengers int                 `json:"total_passengers"`
+	Pickups         []ManifestStop      `json:"pickups"`
+	Dropoffs        []ManifestStop      `json:"dropoffs"`
+	Passengers      []ManifestPassenger `json:"passengers"`
+}
+
+// RoutePointService manages the pickup/drop-off catalog of routes and their use in bookings
+type RoutePointService struct {
+	pointRepo   *repository.RoutePointRepository
+	routeRepo   *repository.RouteRepository
+	bookingRepo *repository.BookingRepository
+}
+
+func NewRoutePointService(
+	pointRepo *repository.RoutePointRepository,
+	routeRepo *repository.RouteRepository,
+	bookingRepo *repository.BookingRepository,
+) *RoutePointService {
+	return &RoutePointService{
+		pointRepo:   pointRepo,
+		routeRepo:   routeRepo,
+		bookingRepo: bookingRepo,
+	}
+}
+
+// List returns the points of a route; an empty pointType returns both kinds
+func (s *RoutePointService) List(routeID uint, pointType models.RoutePointType, activeOnly bool) ([]models.RoutePoint, error) {
+	if _, err := s.routeRepo.FindByID(routeID); err != nil {
+		return nil, err
+	}
+	return s.pointRepo.FindByRoute(routeID, pointType, activeOnly)
+}
+
+// Create adds a point to the catalog of its route
+func (s *RoutePointService) Create(point *models.RoutePoint) error {
+	if err := s.check(point); err != nil {
+		return err
+	}
+	return s.pointRepo.Create(point)
+}
+
+// Update saves changes to a point. Bookings keep referring to the point,
+// so a point in use should be deactivated rather than deleted.
+func (s *RoutePointService) Update(point *models.RoutePoint) error {
+	if err := s.check(point); err != nil {
+		return err
+	}
+	return s.pointRepo.Update(point)
+}
+
+// check validates a point and its offset against the route duration
+func (s *RoutePointService) check(point *models.RoutePoint) error {
+	if err := point.Validate(); err != nil {
+		return err
+	}
+	route, err := s.routeRepo.FindByID(point.RouteID)
+	if err != nil {
+		return err
+	}
+	if duration, err := route.ParsedDuration(); err == nil && time.Duration(point.OffsetMinutes)*time.Minute > duration {
+		return ErrPointBeyondRoute
+	}
+	return nil
+}
+
+// Select resolves the points chosen for a booking of the trip and computes the shuttle surcharge.
+// Passengers without a chosen point board at the origin and leave at the destination.
+func (s *RoutePointService) Select(trip *models.Trip, pickupID, dropoffID *uint, passengers int) (*BoardingSelection, error) {
+	selection := &BoardingSelection{}
+
+	var err error
+	if pickupID != nil {
+		if selection.Pickup, err = s.findPoint(trip, *pickupID, models.RoutePointPickup); err != nil {
+			return nil, err
+		}
+		selection.Surcharge += selection.Pickup.Surcharge
+	}
+	if dropoffID != nil {
+		if selection.Dropoff, err = s.findPoint(trip, *dropoffID, models.RoutePointDropoff); err != nil {
+			return nil, err
+		}
+		selection.Surcharge += selection.Dropoff.Surcharge
+	}
+	if selection.Pickup != nil && selection.Dropoff != nil && selection.Dropoff.OffsetMinutes <= selection.Pickup.OffsetMinutes {
+		return nil, ErrPointOrder
+	}
+
+	selection.Surcharge *= models.Money(passengers)
+	return selection, nil
+}
+
+// findPoint loads an active point of the trip's route with the expected type
+func (s *RoutePointService) findPoint(trip *models.Trip, id uint, pointType models.RoutePointType) (*models.RoutePoint, error) {
+	point, err := s.pointRepo.FindByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPointNotOnRoute
+		}
+		return nil, err
+	}
+	if point.RouteID != trip.RouteID || !point.IsActive {
+		return nil, ErrPointNotOnRoute
+	}
+	if point.Type != pointType {
+		return nil, ErrPointWrongType
+	}
+	return point, nil
+}
+
+// Manifest builds the passenger list of a trip with pickup and drop-off times
+func (s *RoutePointService) Manifest(trip *models.Trip) (*TripManifest, error) {
+	bookings, err := s.bookingRepo.FindActiveByTrip(trip.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &TripManifest{
+		TripID:        trip.ID,
+		DepartureTime: trip.DepartureTime,
+		Pickups:       []ManifestStop{},
+		Dropoffs:      []ManifestStop{},
+		Passengers:    []ManifestPassenger{},
+	}
+	arrival := trip.DepartureTime.Add(drivingDuration(trip, DefaultScheduleConfig().FallbackTripDuration))
+	origin := ManifestStop{Type: models.RoutePointPickup, ScheduledAt: trip.DepartureTime}
+	destination := ManifestStop{Type: models.RoutePointDropoff, ScheduledAt: arrival}
+	if trip.Route != nil {
+		manifest.Origin, manifest.Destination = trip.Route.Origin, trip.Route.Destination
+		origin.Name, destination.Name = trip.Route.Origin, trip.Route.Destination
+	}
+	if trip.Bus != nil {
+		manifest.PlateNumber = trip.Bus.PlateNumber
+	}
+	if trip.Driver != nil {
+		manifest.DriverName = trip.Driver.Name
+	}
+
+	pickups := map[uint]*ManifestStop{}
+	dropoffs := map[uint]*ManifestStop{}
+	stopFor := func(stops map[uint]*ManifestStop, point *models.RoutePoint, terminal *ManifestStop) *ManifestStop {
+		if point == nil {
+			return terminal
+		}
+		if stop, ok := stops[point.ID]; ok {
+			return stop
+		}
+		id := point.ID
+		stop := &ManifestStop{
+			PointID:     &id,
+			Type:        point.Type,
+			Name:        point.Name,
+			Address:     point.Address,
+			Shuttle:     point.Shuttle,
+			ScheduledAt: point.TimeFrom(trip.DepartureTime),
+		}
+		stops[point.ID] = stop
+		return stop
+	}
+
+	for _, booking := range bookings {
+		count := len(booking.SeatIDs)
+		pickup := stopFor(pickups, booking.PickupPoint, &origin)
+		dropoff := stopFor(dropoffs, booking.DropoffPoint, &destination)
+		pickup.Passengers += count
+		dropoff.Passengers += count
+		manifest.TotalPassengers += count
+
+		passenger := ManifestPassenger{
+			BookingID:     booking.ID,
+			BookingCode:   booking.BookingCode,
+			Seats:         make([]string, 0, len(booking.Seats)),
+			PickupName:    pickup.Name,
+			PickupAt:      pickup.ScheduledAt,
+			PickupShuttle: pickup.Shuttle,
+			DropoffName:   dropoff.Name,
+			DropoffAt:     dropoff.ScheduledAt,
+			Status:        booking.Status,
+			PaymentStatus: booking.PaymentStatus,
+			TotalAmount:   booking.TotalAmount,
+			Note:          booking.Note,
+		}
+		if booking.GuestInfo != nil && booking.GuestInfo.Name != "" {
+			passenger.Name, passenger.Phone = booking.GuestInfo.Name, booking.GuestInfo.Phone
+		} else if booking.User != nil {
+			passenger.Name, passenger.Phone = booking.User.Name, booking.User.Phone
+		}
+		for _, seat := range booking.Seats {
+			passenger.Seats = append(passenger.Seats, seat.Number)
+		}
+		manifest.Passengers = append(manifest.Passengers, passenger)
+	}
+
+	manifest.Pickups = collectStops(pickups, &origin)
+	manifest.Dropoffs = collectStops(dropoffs, &destination)
+	sort.SliceStable(manifest.Passengers, func(i, j int) bool {
+		a, b := manifest.Passengers[i], manifest.Passengers[j]
+		if !a.PickupAt.Equal(b.PickupAt) {
+			return a.PickupAt.Before(b.PickupAt)
+		}
+		if a.PickupName != b.PickupName {
+			return a.PickupName < b.PickupName
+		}
+		return a.BookingCode < b.BookingCode
+	})
+	return manifest, nil
+}
+
+// collectStops returns the stops with passengers ordered by scheduled time
+func collectStops(points map[uint]*ManifestStop, terminal *ManifestStop) []ManifestStop {
+	stops := make([]ManifestStop, 0, len(points)+1)
+	if terminal.Passengers > 0 {
+		stops = append(stops, *terminal)
+	}
+	for _, stop := range points {
+		stops = append(stops, *stop)
+	}
+	sort.SliceStable(stops, func(i, j int) bool {
+		if !stops[i].ScheduledAt.Equal(stops[j].ScheduledAt) {
+			return stops[i].ScheduledAt.Before(stops[j].ScheduledAt)
+		}
+		return stops[i].Name < stops[j].Name
+	})
+	return stops
+}
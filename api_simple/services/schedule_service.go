@@ -0,0 +1,356 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+)
+
+const (
+	ConflictOverlap     = "overlap"     // Khoảng thời gian bị trùng
+	ConflictLocation    = "location"    // Xe không ở đúng thành phố
+	ConflictMaintenance = "maintenance" // Xe đang bảo dưỡng hoặc hỏng
+	ConflictDocuments   = "documents"   // Đăng kiểm hoặc đăng ký xe hết hạn
+)
+
+// ScheduleConfig controls how long a bus or driver is considered occupied by a trip
+type ScheduleConfig struct {
+	TurnaroundBuffer     time.Duration // Cleaning, refuelling and boarding time after arrival
+	FallbackTripDuration time.Duration // Used when a route's duration cannot be parsed
+	Lookback             time.Duration // How far around a departure to look for other trips
+}
+
+// DefaultScheduleConfig returns the settings used by the API
+func DefaultScheduleConfig() ScheduleConfig {
+	return ScheduleConfig{
+		TurnaroundBuffer:     time.Hour,
+		FallbackTripDuration: 8 * time.Hour,
+		Lookback:             48 * time.Hour,
+	}
+}
+
+// ScheduleConflict describes why a bus or driver cannot take a trip
+type ScheduleConflict struct {
+	Resource string    `json:"resource"` // bus hoặc driver
+	Kind     string    `json:"kind"`     // overlap, location, maintenance hoặc documents
+	TripID   uint      `json:"trip_id"`  // Chuyến gây xung đột
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Location string    `json:"location,omitempty"` // Thành phố của xe khi xung đột vị trí
+
+	UnavailabilityID uint   `json:"unavailability_id,omitempty"` // Lịch ngừng hoạt động gây xung đột
+	Reason           string `json:"reason,omitempty"`            // Lý do ngừng hoạt động hoặc giấy tờ hết hạn
+}
+
+func (e *ScheduleConflict) Error() string {
+	switch e.Kind {
+	case ConflictLocation:
+		return fmt.Sprintf("%s is at %s around trip %d", e.Resource, e.Location, e.TripID)
+	case ConflictMaintenance:
+		return fmt.Sprintf("%s is unavailable from %s: %s", e.Resource, e.Start.Format(time.RFC3339), e.Reason)
+	case ConflictDocuments:
+		return fmt.Sprintf("%s %s expires at %s", e.Resource, e.Reason, e.End.Format(time.RFC3339))
+	}
+	return fmt.Sprintf("%s is occupied by trip %d from %s to %s",
+		e.Resource, e.TripID, e.Start.Format(time.RFC3339), e.End.Format(time.RFC3339))
+}
+
+// BusSuggestion is a free bus for a proposed departure
+type BusSuggestion struct {
+	Bus             models.Bus `json:"bus"`
+	CurrentLocation string     `json:"current_location,omitempty"` // Nơi xe kết thúc chuyến trước
+	AtOrigin        bool       `json:"at_origin"`                  // Xe đang ở điểm đi của tuyến
+}
+
+// ScheduleSuggestion lists buses and drivers free for a proposed departure
+type ScheduleSuggestion struct {
+	DepartureTime time.Time       `json:"departure_time"`
+	ArrivalTime   time.Time       `json:"arrival_time"`
+	AvailableAt   time.Time       `json:"available_at"` // Xe và tài xế rảnh lại sau thời điểm này
+	Buses         []BusSuggestion `json:"buses"`
+	Drivers       []models.User   `json:"drivers"`
+}
+
+// ScheduleService detects bus and driver double-booking using each trip's
+// route duration plus a turnaround buffer, and keeps buses under maintenance off the schedule
+type ScheduleService struct {
+	tripRepo        *repository.TripRepository
+	routeRepo       *repository.RouteRepository
+	busRepo         *repository.BusRepository
+	userRepo        *repository.UserRepository
+	maintenanceRepo *repository.MaintenanceRepository
+	cfg             ScheduleConfig
+}
+
+func NewScheduleService(
+	tripRepo *repository.TripRepository,
+	routeRepo *repository.RouteRepository,
+	busRepo *repository.BusRepository,
+	userRepo *repository.UserRepository,
+	maintenanceRepo *repository.MaintenanceRepository,
+	cfg ScheduleConfig,
+) *ScheduleService {
+	return &ScheduleService{
+		tripRepo:        tripRepo,
+		routeRepo:       routeRepo,
+		busRepo:         busRepo,
+		userRepo:        userRepo,
+		maintenanceRepo: maintenanceRepo,
+		cfg:             cfg,
+	}
+}
+
+// Interval returns when a trip departs and when its bus and driver are free again
+func (s *ScheduleService) Interval(trip *models.Trip) (time.Time, time.Time) {
+	duration := drivingDuration(trip, s.cfg.FallbackTripDuration)
+	return trip.DepartureTime, trip.DepartureTime.Add(duration + s.cfg.TurnaroundBuffer)
+}
+
+// CheckTrip returns a *ScheduleConflict when the trip's bus or driver is
+// already occupied, the bus is out of service or its papers expire before arrival,
+// or the bus would not be in the route's origin city
+func (s *ScheduleService) CheckTrip(trip *models.Trip) error {
+	if trip.Route == nil || trip.Route.ID != trip.RouteID {
+		route, err := s.routeRepo.FindByID(trip.RouteID)
+		if err != nil {
+			return err
+		}
+		trip.Route = route
+	}
+
+	bus, err := s.busRepo.FindByID(trip.BusID)
+	if err != nil {
+		return err
+	}
+	start, end := s.Interval(trip)
+	windows, err := s.maintenanceRepo.FindUnavailabilityBetween(bus.ID, start, end)
+	if err != nil {
+		return err
+	}
+	if conflict := s.findUnavailability(bus, trip, windows); conflict != nil {
+		return conflict
+	}
+
+	from, to := s.window(trip)
+
+	busTrips, err := s.tripRepo.FindScheduledForBus(trip.BusID, from, to, trip.ID)
+	if err != nil {
+		return err
+	}
+	if conflict := s.findConflict("bus", trip, busTrips, true); conflict != nil {
+		return conflict
+	}
+
+	driverTrips, err := s.tripRepo.FindScheduledForDriver(trip.DriverID, from, to, trip.ID)
+	if err != nil {
+		return err
+	}
+	if conflict := s.findConflict("driver", trip, driverTrips, false); conflict != nil {
+		return conflict
+	}
+
+	return nil
+}
+
+// Suggest lists active buses and drivers that can take a trip on the route at departure.
+// Buses already waiting at the origin are listed first.
+func (s *ScheduleService) Suggest(routeID uint, departure time.Time) (*ScheduleSuggestion, error) {
+	route, err := s.routeRepo.FindByID(routeID)
+	if err != nil {
+		return nil, err
+	}
+
+	candidate := &models.Trip{RouteID: routeID, Route: route, DepartureTime: departure}
+	from, to := s.window(candidate)
+
+	scheduled, err := s.tripRepo.FindScheduled(nil, from, to, 0)
+	if err != nil {
+		return nil, err
+	}
+	tripsByBus := make(map[uint][]models.Trip)
+	tripsByDriver := make(map[uint][]models.Trip)
+	for _, trip := range scheduled {
+		tripsByBus[trip.BusID] = append(tripsByBus[trip.BusID], trip)
+		tripsByDriver[trip.DriverID] = append(tripsByDriver[trip.DriverID], trip)
+	}
+
+	buses, err := s.busRepo.FindAll(map[string]interface{}{"is_active": true})
+	if err != nil {
+		return nil, err
+	}
+	start, end := s.Interval(candidate)
+	windows, err := s.maintenanceRepo.FindUnavailabilityBetween(0, start, end)
+	if err != nil {
+		return nil, err
+	}
+	windowsByBus := make(map[uint][]models.BusUnavailability)
+	for _, window := range windows {
+		windowsByBus[window.BusID] = append(windowsByBus[window.BusID], window)
+	}
+	drivers, err := s.userRepo.FindByRole(models.RoleDriver)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestion := &ScheduleSuggestion{
+		DepartureTime: start,
+		ArrivalTime:   end.Add(-s.cfg.TurnaroundBuffer),
+		AvailableAt:   end,
+		Buses:         []BusSuggestion{},
+		Drivers:       []models.User{},
+	}
+
+	for _, bus := range buses {
+		if s.findUnavailability(&bus, candidate, windowsByBus[bus.ID]) != nil {
+			continue
+		}
+		trips := tripsByBus[bus.ID]
+		if s.findConflict("bus", candidate, trips, true) != nil {
+			continue
+		}
+		item := BusSuggestion{Bus: bus}
+		if prev := previousTrip(candidate, trips); prev != nil && prev.Route != nil {
+			item.CurrentLocation = prev.Route.Destination
+			item.AtOrigin = sameCity(prev.Route.Destination, route.Origin)
+		}
+		suggestion.Buses = append(suggestion.Buses, item)
+	}
+	sort.SliceStable(suggestion.Buses, func(i, j int) bool {
+		return suggestion.Buses[i].AtOrigin && !suggestion.Buses[j].AtOrigin
+	})
+
+	for _, driver := range drivers {
+		if s.findConflict("driver", candidate, tripsByDriver[driver.ID], false) == nil {
+			suggestion.Drivers = append(suggestion.Drivers, driver)
+		}
+	}
+
+	return suggestion, nil
+}
+
+// window returns the departure range in which other trips can affect the candidate
+func (s *ScheduleService) window(trip *models.Trip) (time.Time, time.Time) {
+	start, end := s.Interval(trip)
+	return start.Add(-s.cfg.Lookback), end.Add(s.cfg.Lookback)
+}
+
+// findConflict checks the candidate against other trips of the same bus or driver
+func (s *ScheduleService) findConflict(resource string, candidate *models.Trip, others []models.Trip, checkLocation bool) *ScheduleConflict {
+	start, end := s.Interval(candidate)
+
+	for i := range others {
+		otherStart, otherEnd := s.Interval(&others[i])
+		if otherStart.Before(end) && start.Before(otherEnd) {
+			return &ScheduleConflict{
+				Resource: resource,
+				Kind:     ConflictOverlap,
+				TripID:   others[i].ID,
+				Start:    otherStart,
+				End:      otherEnd,
+			}
+		}
+	}
+
+	if !checkLocation || candidate.Route == nil {
+		return nil
+	}
+
+	// The bus must already be where the trip starts and must reach the start of its next trip
+	if prev := previousTrip(candidate, others); prev != nil && prev.Route != nil &&
+		!sameCity(prev.Route.Destination, candidate.Route.Origin) {
+		prevStart, prevEnd := s.Interval(prev)
+		return &ScheduleConflict{
+			Resource: resource,
+			Kind:     ConflictLocation,
+			TripID:   prev.ID,
+			Start:    prevStart,
+			End:      prevEnd,
+			Location: prev.Route.Destination,
+		}
+	}
+	if next := nextTrip(candidate, others); next != nil && next.Route != nil &&
+		!sameCity(candidate.Route.Destination, next.Route.Origin) {
+		nextStart, nextEnd := s.Interval(next)
+		return &ScheduleConflict{
+			Resource: resource,
+			Kind:     ConflictLocation,
+			TripID:   next.ID,
+			Start:    nextStart,
+			End:      nextEnd,
+			Location: candidate.Route.Destination,
+		}
+	}
+
+	return nil
+}
+
+// findUnavailability checks the candidate against the bus's papers and downtime windows
+func (s *ScheduleService) findUnavailability(bus *models.Bus, candidate *models.Trip, windows []models.BusUnavailability) *ScheduleConflict {
+	start, end := s.Interval(candidate)
+	arrival := end.Add(-s.cfg.TurnaroundBuffer)
+
+	if bus.InspectionExpiresAt != nil && !arrival.Before(*bus.InspectionExpiresAt) {
+		return &ScheduleConflict{Resource: "bus", Kind: ConflictDocuments, Start: start, End: *bus.InspectionExpiresAt, Reason: "inspection"}
+	}
+	if bus.RegistrationExpiresAt != nil && !arrival.Before(*bus.RegistrationExpiresAt) {
+		return &ScheduleConflict{Resource: "bus", Kind: ConflictDocuments, Start: start, End: *bus.RegistrationExpiresAt, Reason: "registration"}
+	}
+
+	for i := range windows {
+		if !windows[i].Overlaps(start, end) {
+			continue
+		}
+		conflict := &ScheduleConflict{
+			Resource:         "bus",
+			Kind:             ConflictMaintenance,
+			Start:            windows[i].StartsAt,
+			UnavailabilityID: windows[i].ID,
+			Reason:           windows[i].Reason,
+		}
+		if windows[i].EndsAt != nil {
+			conflict.End = *windows[i].EndsAt
+		}
+		return conflict
+	}
+	return nil
+}
+
+func previousTrip(candidate *models.Trip, others []models.Trip) *models.Trip {
+	var prev *models.Trip
+	for i := range others {
+		if others[i].DepartureTime.Before(candidate.DepartureTime) &&
+			(prev == nil || others[i].DepartureTime.After(prev.DepartureTime)) {
+			prev = &others[i]
+		}
+	}
+	return prev
+}
+
+func nextTrip(candidate *models.Trip, others []models.Trip) *models.Trip {
+	var next *models.Trip
+	for i := range others {
+		if others[i].DepartureTime.After(candidate.DepartureTime) &&
+			(next == nil || others[i].DepartureTime.Before(next.DepartureTime)) {
+			next = &others[i]
+		}
+	}
+	return next
+}
+
+func sameCity(a string, b string) bool {
+	return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+}
+
+// drivingDuration returns the travel time of a trip's route, or fallback when it is unknown
+func drivingDuration(trip *models.Trip, fallback time.Duration) time.Duration {
+	if trip.Route != nil {
+		if parsed, err := trip.Route.ParsedDuration(); err == nil {
+			return parsed
+		}
+	}
+	return fallback
+}
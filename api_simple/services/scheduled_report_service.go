@@ -0,0 +1,106 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/providers"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/utils"
+)
+
+var ErrReportNeverRuns = errors.New("report schedule never runs")
+
+// ReportContentType returns the MIME type of a report file
+func ReportContentType(format models.ReportFormat) string {
+	if format == models.ReportFormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// ScheduledReportService emails scheduled reports to their recipients
+type ScheduledReportService struct {
+	reportRepo *repository.ScheduledReportRepository
+	mail       MailService
+}
+
+func NewScheduledReportService(reportRepo *repository.ScheduledReportRepository, mail MailService) *ScheduledReportService {
+	return &ScheduledReportService{
+		reportRepo: reportRepo,
+		mail:       mail,
+	}
+}
+
+// Schedule sets when the report is next due after now
+func (s *ScheduledReportService) Schedule(report *models.ScheduledReport, now time.Time) error {
+	cron, err := utils.ParseCron(report.Cron)
+	if err != nil {
+		return err
+	}
+	next := cron.Next(now)
+	if next.IsZero() {
+		return ErrReportNeverRuns
+	}
+	report.NextRunAt = &next
+	return nil
+}
+
+// Deliver emails the report for the period before now, using reports to build it, and
+// records the outcome and the next delivery. A failed delivery is retried at the next
+// scheduled time.
+func (s *ScheduledReportService) Deliver(report *models.ScheduledReport, reports *ReportService, now time.Time) error {
+	err := s.send(report, reports, now)
+	report.LastRunAt = &now
+	report.LastError = ""
+	if err != nil {
+		report.LastError = err.Error()
+	}
+	if s.Schedule(report, now) != nil {
+		report.NextRunAt = nil
+	}
+
+	if recordErr := s.reportRepo.RecordRun(report); recordErr != nil {
+		return recordErr
+	}
+	return err
+}
+
+// send builds the report into a temporary file, so that large reports are never held
+// in memory, and emails it as an attachment
+func (s *ScheduledReportService) send(report *models.ScheduledReport, reports *ReportService, now time.Time) error {
+	from, to := report.PeriodBefore(now)
+
+	file, err := os.CreateTemp("", "report-*."+string(report.Format))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if err := reports.Export(file, report.Format, report.Report, report.Filters, from, to); err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	period := from.Format("02/01/2006")
+	if last := to.Format("02/01/2006"); last != period {
+		period += " - " + last
+	}
+	return s.mail.Send(&providers.Email{
+		To:      report.Recipients,
+		Subject: fmt.Sprintf("%s (%s)", report.Name, period),
+		Body:    fmt.Sprintf("Báo cáo \"%s\" cho kỳ %s được đính kèm trong email này.\n", report.Name, period),
+		Attachments: []providers.Attachment{{
+			Name:        fmt.Sprintf("%s-%s.%s", report.Report, from.Format("20060102"), report.Format),
+			ContentType: ReportContentType(report.Format),
+			Content:     file,
+		}},
+	})
+}
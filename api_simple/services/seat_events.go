@@ -0,0 +1,96 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"ticket-management/api_simple/models"
+)
+
+const (
+	SeatEventLocked      = "locked"       // Ghế được khóa để đặt
+	SeatEventUnlocked    = "unlocked"     // Người dùng bỏ khóa ghế
+	SeatEventBooked      = "booked"       // Ghế đã được đặt
+	SeatEventReleased    = "released"     // Đơn bị hủy, ghế trống lại
+	SeatEventLockExpired = "lock_expired" // Hết thời gian khóa ghế
+)
+
+// SeatTopic is the broker topic carrying seat status changes of a trip
+func SeatTopic(tripID uint) string {
+	return fmt.Sprintf("trips:%d:seats", tripID)
+}
+
+// SeatChange is the new status of one seat
+type SeatChange struct {
+	ID          uint              `json:"id"`
+	Number      string            `json:"number,omitempty"`
+	Status      models.SeatStatus `json:"status"`
+	LockedUntil *time.Time        `json:"locked_until,omitempty"`
+}
+
+// SeatEvent is a batch of seat status changes caused by one action
+type SeatEvent struct {
+	TripID uint         `json:"trip_id"`
+	Reason string       `json:"reason"`
+	Seats  []SeatChange `json:"seats"`
+	At     time.Time    `json:"at"`
+}
+
+// SeatNotifier publishes seat status changes so open seat maps update without polling
+type SeatNotifier struct {
+	broker Broker
+}
+
+func NewSeatNotifier(broker Broker) *SeatNotifier {
+	return &SeatNotifier{broker: broker}
+}
+
+// NotifyIDs publishes that the given seats of a trip now have status
+func (n *SeatNotifier) NotifyIDs(tripID uint, reason string, status models.SeatStatus, seatIDs []int64, lockedUntil *time.Time) error {
+	changes := make([]SeatChange, len(seatIDs))
+	for i, id := range seatIDs {
+		changes[i] = SeatChange{ID: uint(id), Status: status, LockedUntil: lockedUntil}
+	}
+	return n.publish(tripID, reason, changes)
+}
+
+// NotifySeats publishes the current status of the given seats, grouped by trip
+func (n *SeatNotifier) NotifySeats(reason string, seats []models.Seat) error {
+	byTrip := make(map[uint][]SeatChange)
+	var order []uint
+	for _, seat := range seats {
+		if _, ok := byTrip[seat.TripID]; !ok {
+			order = append(order, seat.TripID)
+		}
+		byTrip[seat.TripID] = append(byTrip[seat.TripID], SeatChange{
+			ID:          seat.ID,
+			Number:      seat.Number,
+			Status:      seat.Status,
+			LockedUntil: seat.LockedUntil,
+		})
+	}
+
+	for _, tripID := range order {
+		if err := n.publish(tripID, reason, byTrip[tripID]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *SeatNotifier) publish(tripID uint, reason string, changes []SeatChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	payload, err := json.Marshal(SeatEvent{
+		TripID: tripID,
+		Reason: reason,
+		Seats:  changes,
+		At:     time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	return n.broker.Publish(SeatTopic(tripID), payload)
+}
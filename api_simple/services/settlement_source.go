@@ -0,0 +1,23 @@
+package services
+
+import (
+	"os"
+
+	"ticket-management/api_simple/providers"
+)
+
+// SettlementSource delivers the settlement files of payment providers for reconciliation
+type SettlementSource interface {
+	Fetch() ([]providers.SettlementFile, error)
+	Done(file providers.SettlementFile, failed bool) error
+}
+
+// NewSettlementSourceFromEnv returns the settlement inbox at SETTLEMENT_INBOX_DIR, or nil
+// when none is configured and settlement files are only uploaded through the admin API
+func NewSettlementSourceFromEnv() SettlementSource {
+	inbox := os.Getenv("SETTLEMENT_INBOX_DIR")
+	if inbox == "" {
+		return nil
+	}
+	return providers.NewDirSettlementSource(inbox)
+}
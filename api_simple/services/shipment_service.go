@@ -0,0 +1,284 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/utils"
+)
+
+var (
+	ErrShipmentNotAccepted   = errors.New("trip no longer accepts shipments")
+	ErrCargoCapacityExceeded = errors.New("cargo capacity of the bus exceeded")
+	ErrInvalidShipmentStatus = errors.New("invalid shipment status transition")
+)
+
+// ShipmentConfig controls parcel pricing
+type ShipmentConfig struct {
+	BasePrice         models.Money // Flat fee per consignment
+	PricePerKg        models.Money // Price per chargeable kilogram
+	VolumetricDivisor float64      // cm³ per kilogram when converting size to weight
+	WeightStepKg      float64      // Chargeable weight is rounded up to this step
+}
+
+// DefaultShipmentConfig returns the settings used by the API
+func DefaultShipmentConfig() ShipmentConfig {
+	return ShipmentConfig{
+		BasePrice:         20000,
+		PricePerKg:        5000,
+		VolumetricDivisor: 6000,
+		WeightStepKg:      0.5,
+	}
+}
+
+// ShipmentConfigFromEnv returns DefaultShipmentConfig overridden by SHIPMENT_BASE_PRICE and SHIPMENT_PRICE_PER_KG
+func ShipmentConfigFromEnv() ShipmentConfig {
+	cfg := DefaultShipmentConfig()
+	if v, err := strconv.ParseInt(os.Getenv("SHIPMENT_BASE_PRICE"), 10, 64); err == nil && v >= 0 {
+		cfg.BasePrice = models.Money(v)
+	}
+	if v, err := strconv.ParseInt(os.Getenv("SHIPMENT_PRICE_PER_KG"), 10, 64); err == nil && v >= 0 {
+		cfg.PricePerKg = models.Money(v)
+	}
+	return cfg
+}
+
+// ShipmentQuote is the price of a parcel
+type ShipmentQuote struct {
+	ChargeableKg float64      `json:"chargeable_kg"` // Khối lượng tính cước
+	Price        models.Money `json:"price"`         // Cước phí
+}
+
+// CargoLoad is the cargo carried on a trip against the capacity of its bus
+type CargoLoad struct {
+	TripID     uint              `json:"trip_id"`
+	CapacityKg float64           `json:"capacity_kg"` // 0: không giới hạn
+	CapacityM3 float64           `json:"capacity_m3"` // 0: không giới hạn
+	WeightKg   float64           `json:"weight_kg"`
+	VolumeM3   float64           `json:"volume_m3"`
+	Shipments  []models.Shipment `json:"shipments"`
+}
+
+// ShipmentTrackingEvent is a step of the public shipment history
+type ShipmentTrackingEvent struct {
+	Status models.ShipmentStatus `json:"status"`
+	Note   string                `json:"note,omitempty"`
+	At     time.Time             `json:"at"`
+}
+
+// ShipmentTracking is what the public lookup by tracking code shows
+type ShipmentTracking struct {
+	TrackingCode     string                  `json:"tracking_code"`
+	Status           models.ShipmentStatus   `json:"status"`
+	Origin           string                  `json:"origin"`
+	Destination      string                  `json:"destination"`
+	DepartureTime    time.Time               `json:"departure_time"`
+	EstimatedArrival time.Time               `json:"estimated_arrival"`
+	SenderName       string                  `json:"sender_name"`
+	ReceiverName     string                  `json:"receiver_name"`
+	ReceiverPhone    string                  `json:"receiver_phone"` // Đã che bớt số
+	WeightKg         float64                 `json:"weight_kg"`
+	Price            models.Money            `json:"price"`
+	Payer            models.ShipmentPayer    `json:"payer"`
+	PaymentStatus    models.PaymentStatus    `json:"payment_status"`
+	Events           []ShipmentTrackingEvent `json:"events"`
+}
+
+// ShipmentService prices parcels, books them on trips within the cargo capacity of the bus
+// and moves them through their delivery statuses
+type ShipmentService struct {
+	shipmentRepo *repository.ShipmentRepository
+	tripRepo     *repository.TripRepository
+	cfg          ShipmentConfig
+}
+
+func NewShipmentService(
+	shipmentRepo *repository.ShipmentRepository,
+	tripRepo *repository.TripRepository,
+	cfg ShipmentConfig,
+) *ShipmentService {
+	return &ShipmentService{
+		shipmentRepo: shipmentRepo,
+		tripRepo:     tripRepo,
+		cfg:          cfg,
+	}
+}
+
+// Quote prices a parcel by the greater of its actual and volumetric weight
+func (s *ShipmentService) Quote(weightKg, lengthCm, widthCm, heightCm float64) ShipmentQuote {
+	chargeable := math.Max(weightKg, lengthCm*widthCm*heightCm/s.cfg.VolumetricDivisor)
+	if s.cfg.WeightStepKg > 0 {
+		chargeable = math.Ceil(chargeable/s.cfg.WeightStepKg-1e-9) * s.cfg.WeightStepKg
+	}
+	price := s.cfg.BasePrice + s.cfg.PricePerKg.Mul(chargeable)
+	return ShipmentQuote{
+		ChargeableKg: chargeable,
+		Price:        price.RoundTo(models.PriceStep),
+	}
+}
+
+// Create receives a parcel for a trip that has not departed yet
+func (s *ShipmentService) Create(shipment *models.Shipment, staffID *uint, now time.Time) error {
+	if err := shipment.Validate(); err != nil {
+		return err
+	}
+
+	trip, err := s.tripRepo.FindByID(shipment.TripID)
+	if err != nil {
+		return err
+	}
+	if !trip.IsActive || trip.IsCompleted || !trip.DepartureTime.After(now) {
+		return ErrShipmentNotAccepted
+	}
+	if err := s.checkCapacity(trip, shipment.WeightKg, shipment.VolumeM3()); err != nil {
+		return err
+	}
+
+	quote := s.Quote(shipment.WeightKg, shipment.LengthCm, shipment.WidthCm, shipment.HeightCm)
+	shipment.OperatorID = trip.OperatorID
+	shipment.ChargeableKg = quote.ChargeableKg
+	shipment.Price = quote.Price
+	shipment.Status = models.ShipmentStatusReceived
+	shipment.PaymentStatus = models.PaymentStatusUnpaid
+	if shipment.Payer == models.ShipmentPayerSender {
+		shipment.PaymentStatus = models.PaymentStatusPaid
+	}
+	shipment.CreatedBy = staffID
+
+	if err := s.shipmentRepo.Create(shipment); err != nil {
+		return err
+	}
+	return s.shipmentRepo.CreateEvent(&models.ShipmentEvent{
+		ShipmentID: shipment.ID,
+		Status:     models.ShipmentStatusReceived,
+		UpdatedBy:  staffID,
+		At:         now,
+	})
+}
+
+// UpdateStatus moves a shipment to its next status. Drivers may only update
+// parcels on their own trips; receivers pay on delivery when they are the payer.
+func (s *ShipmentService) UpdateStatus(id uint, next models.ShipmentStatus, actor *models.User, note string, now time.Time) (*models.Shipment, error) {
+	shipment, err := s.shipmentRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if actor.Role == models.RoleDriver {
+		if shipment.Trip == nil || shipment.Trip.DriverID != actor.ID {
+			return nil, ErrTripNotAssigned
+		}
+		// Only the office can cancel a consignment and refund the sender
+		if next == models.ShipmentStatusCancelled {
+			return nil, ErrInvalidShipmentStatus
+		}
+	}
+	if !shipment.Status.CanTransitionTo(next) {
+		return nil, ErrInvalidShipmentStatus
+	}
+
+	shipment.Status = next
+	if next == models.ShipmentStatusDelivered {
+		shipment.PaymentStatus = models.PaymentStatusPaid
+	}
+	if next == models.ShipmentStatusCancelled && shipment.PaymentStatus == models.PaymentStatusPaid {
+		shipment.PaymentStatus = models.PaymentStatusRefunded
+	}
+	if err := s.shipmentRepo.UpdateStatus(shipment.ID, shipment.Status, shipment.PaymentStatus); err != nil {
+		return nil, err
+	}
+
+	actorID := actor.ID
+	err = s.shipmentRepo.CreateEvent(&models.ShipmentEvent{
+		ShipmentID: shipment.ID,
+		Status:     next,
+		Note:       note,
+		UpdatedBy:  &actorID,
+		At:         now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return shipment, nil
+}
+
+// Load returns the cargo booked on a trip
+func (s *ShipmentService) Load(tripID uint) (*CargoLoad, error) {
+	trip, err := s.tripRepo.FindByID(tripID)
+	if err != nil {
+		return nil, err
+	}
+	shipments, err := s.shipmentRepo.FindByTrip(trip.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	load := &CargoLoad{TripID: trip.ID, Shipments: shipments}
+	if trip.Bus != nil {
+		load.CapacityKg, load.CapacityM3 = trip.Bus.CargoCapacityKg, trip.Bus.CargoCapacityM3
+	}
+	for i := range shipments {
+		load.WeightKg += shipments[i].WeightKg
+		load.VolumeM3 += shipments[i].VolumeM3()
+	}
+	load.VolumeM3 = math.Round(load.VolumeM3*1000) / 1000
+	return load, nil
+}
+
+// Lookup returns the public view of a shipment by tracking code
+func (s *ShipmentService) Lookup(code string) (*ShipmentTracking, error) {
+	shipment, err := s.shipmentRepo.FindByCode(code)
+	if err != nil {
+		return nil, err
+	}
+	events, err := s.shipmentRepo.FindEvents(shipment.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	tracking := &ShipmentTracking{
+		TrackingCode:  shipment.TrackingCode,
+		Status:        shipment.Status,
+		SenderName:    shipment.SenderName,
+		ReceiverName:  shipment.ReceiverName,
+		ReceiverPhone: utils.MaskPhone(shipment.ReceiverPhone),
+		WeightKg:      shipment.WeightKg,
+		Price:         shipment.Price,
+		Payer:         shipment.Payer,
+		PaymentStatus: shipment.PaymentStatus,
+		Events:        make([]ShipmentTrackingEvent, len(events)),
+	}
+	if trip := shipment.Trip; trip != nil {
+		tracking.DepartureTime = trip.DepartureTime
+		tracking.EstimatedArrival = trip.DepartureTime.Add(drivingDuration(trip, DefaultScheduleConfig().FallbackTripDuration))
+		if trip.Route != nil {
+			tracking.Origin, tracking.Destination = trip.Route.Origin, trip.Route.Destination
+		}
+	}
+	for i, event := range events {
+		tracking.Events[i] = ShipmentTrackingEvent{Status: event.Status, Note: event.Note, At: event.At}
+	}
+	return tracking, nil
+}
+
+// checkCapacity rejects a parcel that would overload the cargo hold of the trip's bus
+func (s *ShipmentService) checkCapacity(trip *models.Trip, weightKg, volumeM3 float64) error {
+	if trip.Bus == nil || (trip.Bus.CargoCapacityKg == 0 && trip.Bus.CargoCapacityM3 == 0) {
+		return nil
+	}
+	loadedKg, loadedM3, err := s.shipmentRepo.SumLoadByTrip(trip.ID)
+	if err != nil {
+		return err
+	}
+	if trip.Bus.CargoCapacityKg > 0 && loadedKg+weightKg > trip.Bus.CargoCapacityKg {
+		return ErrCargoCapacityExceeded
+	}
+	if trip.Bus.CargoCapacityM3 > 0 && loadedM3+volumeM3 > trip.Bus.CargoCapacityM3+1e-9 {
+		return ErrCargoCapacityExceeded
+	}
+	return nil
+}
@@ -1,5 +1,33 @@
 package services
 
+import (
+	"os"
+
+	"ticket-management/api_simple/providers"
+)
+
 type SMSService interface {
 	Send(to string, body string) error
-}
\ No newline at end of file
+}
+
+// NewSMSServiceFromEnv returns the SMS delivery provider selected by SMS_PROVIDER
+// (twilio, http or log). Local environments default to the log provider.
+func NewSMSServiceFromEnv() SMSService {
+	provider := os.Getenv("SMS_PROVIDER")
+	if provider == "" {
+		if os.Getenv("APP_ENV") == "local" {
+			provider = "log"
+		} else {
+			provider = "twilio"
+		}
+	}
+
+	switch provider {
+	case "http":
+		return providers.NewHTTPSMSProvider()
+	case "log":
+		return providers.NewLogSMSProvider()
+	default:
+		return providers.NewTwilioProvider()
+	}
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"ticket-management/api_simple/repository"
+)
+
+// StatsTracker collects the trips whose bookings, seats, price or schedule changed
+// since their statistics were last refreshed
+type StatsTracker struct {
+	mu    sync.Mutex
+	trips map[uint]struct{}
+}
+
+func NewStatsTracker() *StatsTracker {
+	return &StatsTracker{trips: make(map[uint]struct{})}
+}
+
+// TripChanged marks the statistics of a trip, its route and its bus as stale
+func (t *StatsTracker) TripChanged(tripIDs ...uint) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, id := range tripIDs {
+		t.trips[id] = struct{}{}
+	}
+}
+
+// Drain returns the trips marked since the last call, in ID order, and clears them
+func (t *StatsTracker) Drain() []uint {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ids := make([]uint, 0, len(t.trips))
+	for id := range t.trips {
+		ids = append(ids, id)
+	}
+	t.trips = make(map[uint]struct{})
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// StatsService keeps the materialized statistics of trips, routes and buses current
+type StatsService struct {
+	tripRepo  *repository.TripRepository
+	routeRepo *repository.RouteRepository
+	busRepo   *repository.BusRepository
+}
+
+func NewStatsService(
+	tripRepo *repository.TripRepository,
+	routeRepo *repository.RouteRepository,
+	busRepo *repository.BusRepository,
+) *StatsService {
+	return &StatsService{
+		tripRepo:  tripRepo,
+		routeRepo: routeRepo,
+		busRepo:   busRepo,
+	}
+}
+
+// RefreshTrips recounts the seats of the given trips and recomputes the statistics of
+// their routes and buses. Deleted trips still refresh the route and bus they belonged to.
+func (s *StatsService) RefreshTrips(tripIDs []uint, now time.Time) error {
+	if len(tripIDs) == 0 {
+		return nil
+	}
+	if err := s.tripRepo.UpdateSeatCounts(tripIDs); err != nil {
+		return err
+	}
+
+	trips, err := s.tripRepo.FindWithDeleted(tripIDs)
+	if err != nil {
+		return err
+	}
+	routes := make(map[uint]bool)
+	buses := make(map[uint]bool)
+	for _, trip := range trips {
+		if !routes[trip.RouteID] {
+			routes[trip.RouteID] = true
+			if err := s.routeRepo.UpdateStats(trip.RouteID, now); err != nil {
+				return err
+			}
+		}
+		if !buses[trip.BusID] {
+			buses[trip.BusID] = true
+			if err := s.busRepo.UpdateStats(trip.BusID, now); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// RefreshAll recounts the seats of every open trip and recomputes the statistics of
+// every route and bus. Upcoming trip counts change as time passes, so this runs
+// periodically besides the refreshes triggered by changes.
+func (s *StatsService) RefreshAll(now time.Time) error {
+	if err := s.tripRepo.UpdateOpenSeatCounts(); err != nil {
+		return err
+	}
+
+	routeIDs, err := s.routeRepo.FindIDs()
+	if err != nil {
+		return err
+	}
+	for _, id := range routeIDs {
+		if err := s.routeRepo.UpdateStats(id, now); err != nil {
+			return err
+		}
+	}
+
+	busIDs, err := s.busRepo.FindIDs()
+	if err != nil {
+		return err
+	}
+	for _, id := range busIDs {
+		if err := s.busRepo.UpdateStats(id, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
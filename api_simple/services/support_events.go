@@ -0,0 +1,100 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"ticket-management/api_simple/models"
+)
+
+const (
+	SupportEventCreated         = "created"          // Khách hàng gửi yêu cầu mới
+	SupportEventCustomerReplied = "customer_replied" // Khách hàng trả lời
+	SupportEventStaffReplied    = "staff_replied"    // Nhân viên trả lời khách hàng
+	SupportEventAssigned        = "assigned"         // Đổi nhân viên phụ trách
+	SupportEventStatusChanged   = "status_changed"   // Đổi trạng thái
+	SupportEventSLABreached     = "sla_breached"     // Trễ hạn phản hồi hoặc giải quyết
+)
+
+// supportPreviewLength is the number of characters of a reply quoted in SMS notifications
+const supportPreviewLength = 80
+
+// SupportTopic is the broker topic carrying support ticket changes of an operator
+func SupportTopic(operatorID uint) string {
+	return fmt.Sprintf("operators:%d:support", operatorID)
+}
+
+// SupportEvent is a change of a support ticket shown live to the operator's staff
+type SupportEvent struct {
+	TicketID   uint                 `json:"ticket_id"`
+	Code       string               `json:"code"`
+	Reason     string               `json:"reason"`
+	Status     models.SupportStatus `json:"status"`
+	AssignedTo *uint                `json:"assigned_to,omitempty"`
+	At         time.Time            `json:"at"`
+}
+
+// SupportNotifier tells staff about ticket changes through the broker and customers about
+// replies by SMS
+type SupportNotifier struct {
+	broker Broker
+	sms    SMSService
+}
+
+func NewSupportNotifier(broker Broker, sms SMSService) *SupportNotifier {
+	return &SupportNotifier{broker: broker, sms: sms}
+}
+
+// Notify publishes a change of a ticket to the staff of its operator
+func (n *SupportNotifier) Notify(ticket *models.SupportTicket, reason string) error {
+	payload, err := json.Marshal(SupportEvent{
+		TicketID:   ticket.ID,
+		Code:       ticket.Code,
+		Reason:     reason,
+		Status:     ticket.Status,
+		AssignedTo: ticket.AssignedTo,
+		At:         time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	return n.broker.Publish(SupportTopic(ticket.OperatorID), payload)
+}
+
+// Replied tells the other side of the conversation about a new message. Internal notes
+// notify nobody.
+func (n *SupportNotifier) Replied(ticket *models.SupportTicket, message *models.SupportMessage) error {
+	if message.Internal {
+		return nil
+	}
+	if message.Author == models.SupportAuthorCustomer {
+		return n.Notify(ticket, SupportEventCustomerReplied)
+	}
+
+	if err := n.Notify(ticket, SupportEventStaffReplied); err != nil {
+		return err
+	}
+	preview := []rune(message.Body)
+	if len(preview) > supportPreviewLength {
+		preview = append(preview[:supportPreviewLength], '…')
+	}
+	body := fmt.Sprintf("Yêu cầu hỗ trợ %s có phản hồi mới từ nhà xe", ticket.Code)
+	if len(preview) > 0 {
+		body += fmt.Sprintf(": \"%s\"", string(preview))
+	}
+	return n.sms.Send(ticket.ContactPhone, body+". Tra cứu mã yêu cầu để xem chi tiết và trả lời.")
+}
+
+// StatusChanged publishes a new status of a ticket and tells the customer when the ticket
+// has been resolved
+func (n *SupportNotifier) StatusChanged(ticket *models.SupportTicket) error {
+	if err := n.Notify(ticket, SupportEventStatusChanged); err != nil {
+		return err
+	}
+	if ticket.Status != models.SupportStatusResolved {
+		return nil
+	}
+	return n.sms.Send(ticket.ContactPhone, fmt.Sprintf(
+		"Yêu cầu hỗ trợ %s đã được giải quyết. Nếu vẫn cần hỗ trợ, vui lòng trả lời yêu cầu để mở lại.", ticket.Code))
+}
@@ -0,0 +1,426 @@
+package services
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/utils"
+)
+
+var (
+	ErrSupportTicketClosed     = errors.New("support ticket is closed")
+	ErrSupportOperatorRequired = errors.New("support ticket must be addressed to an operator")
+	ErrSupportBookingRequired  = errors.New("refund requests must be about a booking")
+	ErrSupportBookingMismatch  = errors.New("booking does not belong to the customer")
+	ErrEmptySupportMessage     = errors.New("support message is empty")
+	ErrInvalidSupportStatus    = errors.New("invalid support ticket status transition")
+	ErrInvalidSupportAssignee  = errors.New("assignee is not staff of the operator")
+	ErrTooManyAttachments      = errors.New("too many attachments")
+	ErrAttachmentTooLarge      = errors.New("attachment too large")
+	ErrAttachmentType          = errors.New("attachment type not allowed")
+)
+
+// attachmentTypes are the file types that may be attached to tickets, by detected MIME
+// type, with the extension they are stored under
+var attachmentTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+// SupportConfig controls the SLA of support tickets and the files attached to them
+type SupportConfig struct {
+	FirstResponse     time.Duration                            // Time staff have to answer a new ticket
+	Resolution        map[models.SupportCategory]time.Duration // Time staff have to resolve a ticket, by category
+	AutoClose         time.Duration                            // Resolved tickets close after this long without a reply
+	MaxAttachments    int                                      // Files per message
+	MaxAttachmentSize int64                                    // Bytes per file
+}
+
+// DefaultSupportConfig returns the settings used by the API
+func DefaultSupportConfig() SupportConfig {
+	return SupportConfig{
+		FirstResponse: 4 * time.Hour,
+		Resolution: map[models.SupportCategory]time.Duration{
+			models.SupportCategoryRefund:    72 * time.Hour,
+			models.SupportCategoryLostItem:  24 * time.Hour,
+			models.SupportCategoryComplaint: 48 * time.Hour,
+			models.SupportCategoryOther:     72 * time.Hour,
+		},
+		AutoClose:         7 * 24 * time.Hour,
+		MaxAttachments:    5,
+		MaxAttachmentSize: 10 << 20,
+	}
+}
+
+// SupportConfigFromEnv returns DefaultSupportConfig overridden by SUPPORT_FIRST_RESPONSE_HOURS
+// and SUPPORT_AUTO_CLOSE_DAYS
+func SupportConfigFromEnv() SupportConfig {
+	cfg := DefaultSupportConfig()
+	if v, err := strconv.Atoi(os.Getenv("SUPPORT_FIRST_RESPONSE_HOURS")); err == nil && v > 0 {
+		cfg.FirstResponse = time.Duration(v) * time.Hour
+	}
+	if v, err := strconv.Atoi(os.Getenv("SUPPORT_AUTO_CLOSE_DAYS")); err == nil && v > 0 {
+		cfg.AutoClose = time.Duration(v) * 24 * time.Hour
+	}
+	return cfg
+}
+
+// SupportUpload is a file a customer or staff member attaches to a message
+type SupportUpload struct {
+	Name    string // Tên file gốc
+	Content io.Reader
+}
+
+// SupportService opens support tickets, threads the messages between customers and staff
+// with their attachments, and keeps the SLA timers of tickets
+type SupportService struct {
+	supportRepo  *repository.SupportRepository
+	bookingRepo  *repository.BookingRepository
+	operatorRepo *repository.OperatorRepository
+	userRepo     *repository.UserRepository
+	store        AttachmentStore
+	cfg          SupportConfig
+}
+
+func NewSupportService(
+	supportRepo *repository.SupportRepository,
+	bookingRepo *repository.BookingRepository,
+	operatorRepo *repository.OperatorRepository,
+	userRepo *repository.UserRepository,
+	store AttachmentStore,
+	cfg SupportConfig,
+) *SupportService {
+	return &SupportService{
+		supportRepo:  supportRepo,
+		bookingRepo:  bookingRepo,
+		operatorRepo: operatorRepo,
+		userRepo:     userRepo,
+		store:        store,
+		cfg:          cfg,
+	}
+}
+
+// Open opens a ticket with the customer's first message. A ticket about a booking goes to
+// the operator of the booking, which must have been made by the customer; other tickets
+// go to the operator the customer chose. Refunds can only be asked for a booking.
+func (s *SupportService) Open(ticket *models.SupportTicket, bookingCode, body string, uploads []SupportUpload, now time.Time) (*models.SupportMessage, error) {
+	if ticket.Category == models.SupportCategoryRefund && bookingCode == "" {
+		return nil, ErrSupportBookingRequired
+	}
+	if bookingCode != "" {
+		booking, err := s.bookingRepo.FindByCode(bookingCode)
+		if err != nil {
+			return nil, err
+		}
+		if !bookedBy(booking, ticket) {
+			return nil, ErrSupportBookingMismatch
+		}
+		ticket.BookingID = &booking.ID
+		ticket.OperatorID = booking.OperatorID
+	} else {
+		if ticket.OperatorID == 0 {
+			return nil, ErrSupportOperatorRequired
+		}
+		operator, err := s.operatorRepo.FindByID(ticket.OperatorID)
+		if err != nil {
+			return nil, err
+		}
+		if !operator.IsActive {
+			return nil, ErrSupportOperatorRequired
+		}
+	}
+
+	if err := ticket.Validate(); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(body) == "" && len(uploads) == 0 {
+		return nil, ErrEmptySupportMessage
+	}
+	if len(uploads) > s.cfg.MaxAttachments {
+		return nil, ErrTooManyAttachments
+	}
+
+	ticket.Status = models.SupportStatusOpen
+	ticket.LastMessageAt = now
+	ticket.FirstResponseDueAt = now.Add(s.cfg.FirstResponse)
+	ticket.ResolutionDueAt = now.Add(s.cfg.Resolution[ticket.Category])
+	if err := s.supportRepo.Create(ticket); err != nil {
+		return nil, err
+	}
+
+	message := &models.SupportMessage{
+		TicketID: ticket.ID,
+		Author:   models.SupportAuthorCustomer,
+		AuthorID: ticket.UserID,
+		Body:     strings.TrimSpace(body),
+	}
+	if err := s.addMessage(ticket, message, uploads); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// CustomerReply adds a message from the customer, reopening a ticket that was waiting on
+// them or resolved
+func (s *SupportService) CustomerReply(ticket *models.SupportTicket, body string, uploads []SupportUpload, now time.Time) (*models.SupportMessage, error) {
+	message := &models.SupportMessage{
+		TicketID: ticket.ID,
+		Author:   models.SupportAuthorCustomer,
+		AuthorID: ticket.UserID,
+		Body:     strings.TrimSpace(body),
+	}
+	if err := s.reply(ticket, message, uploads); err != nil {
+		return nil, err
+	}
+
+	ticket.LastMessageAt = now
+	if ticket.Status != models.SupportStatusOpen {
+		ticket.Status = models.SupportStatusOpen
+		ticket.ResolvedAt = nil
+	}
+	if err := s.supportRepo.Update(ticket); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// StaffReply adds a message from staff. A reply the customer sees answers the ticket, which
+// then waits on the customer; an internal note leaves the ticket as it is.
+func (s *SupportService) StaffReply(ticket *models.SupportTicket, staff *models.User, body string, internal bool, uploads []SupportUpload, now time.Time) (*models.SupportMessage, error) {
+	message := &models.SupportMessage{
+		TicketID: ticket.ID,
+		Author:   models.SupportAuthorStaff,
+		AuthorID: &staff.ID,
+		Body:     strings.TrimSpace(body),
+		Internal: internal,
+	}
+	if err := s.reply(ticket, message, uploads); err != nil {
+		return nil, err
+	}
+	if internal {
+		return message, nil
+	}
+
+	ticket.LastMessageAt = now
+	if ticket.FirstRespondedAt == nil {
+		ticket.FirstRespondedAt = &now
+		ticket.FirstResponseBreached = ticket.FirstResponseBreached || now.After(ticket.FirstResponseDueAt)
+	}
+	if ticket.Status == models.SupportStatusOpen {
+		ticket.Status = models.SupportStatusPending
+	}
+	if err := s.supportRepo.Update(ticket); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// UpdateStatus moves a ticket to another status, stopping the resolution timer when it is
+// resolved or closed
+func (s *SupportService) UpdateStatus(ticket *models.SupportTicket, status models.SupportStatus, now time.Time) error {
+	if !ticket.Status.CanTransitionTo(status) {
+		return ErrInvalidSupportStatus
+	}
+
+	switch status {
+	case models.SupportStatusResolved:
+		ticket.ResolvedAt = &now
+		ticket.ResolutionBreached = ticket.ResolutionBreached || now.After(ticket.ResolutionDueAt)
+	case models.SupportStatusClosed:
+		ticket.ClosedAt = &now
+		if ticket.ResolvedAt == nil {
+			ticket.ResolutionBreached = ticket.ResolutionBreached || now.After(ticket.ResolutionDueAt)
+		}
+	default:
+		ticket.ResolvedAt = nil
+	}
+	ticket.Status = status
+	return s.supportRepo.Update(ticket)
+}
+
+// Assign makes a staff member or admin of the ticket's operator responsible for it, or
+// leaves it unassigned when assigneeID is nil
+func (s *SupportService) Assign(ticket *models.SupportTicket, assigneeID *uint) error {
+	ticket.AssignedTo = nil
+	ticket.Assignee = nil
+	if assigneeID != nil {
+		assignee, err := s.userRepo.FindByID(*assigneeID)
+		if err != nil {
+			return ErrInvalidSupportAssignee
+		}
+		if assignee.Role != models.RoleStaff && assignee.Role != models.RoleAdmin {
+			return ErrInvalidSupportAssignee
+		}
+		if assignee.OperatorID == nil || *assignee.OperatorID != ticket.OperatorID {
+			return ErrInvalidSupportAssignee
+		}
+		ticket.AssignedTo = &assignee.ID
+		ticket.Assignee = assignee
+	}
+	return s.supportRepo.Update(ticket)
+}
+
+// Thread returns the messages of a ticket, with internal notes only when internal is set
+func (s *SupportService) Thread(ticket *models.SupportTicket, internal bool) ([]models.SupportMessage, error) {
+	return s.supportRepo.FindMessages(ticket.ID, internal)
+}
+
+// Attachment opens a file attached to a ticket. Files of internal notes are only found
+// when internal is set.
+func (s *SupportService) Attachment(ticket *models.SupportTicket, id uint, internal bool) (*models.SupportAttachment, io.ReadCloser, error) {
+	attachment, err := s.supportRepo.FindAttachment(ticket.ID, id, internal)
+	if err != nil {
+		return nil, nil, err
+	}
+	content, err := s.store.Open(attachment.StorageKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return attachment, content, nil
+}
+
+// CheckSLA records the breaches of tickets that passed their first response or resolution
+// deadline and returns the tickets newly breached
+func (s *SupportService) CheckSLA(now time.Time) ([]models.SupportTicket, error) {
+	tickets, err := s.supportRepo.FindOverdue(now)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range tickets {
+		ticket := &tickets[i]
+		if ticket.FirstRespondedAt == nil && now.After(ticket.FirstResponseDueAt) {
+			ticket.FirstResponseBreached = true
+		}
+		if now.After(ticket.ResolutionDueAt) {
+			ticket.ResolutionBreached = true
+		}
+		if err := s.supportRepo.UpdateBreaches(ticket); err != nil {
+			return nil, err
+		}
+	}
+	return tickets, nil
+}
+
+// AutoClose closes the tickets resolved longer ago than the auto-close delay
+func (s *SupportService) AutoClose(now time.Time) (int64, error) {
+	return s.supportRepo.CloseResolved(now.Add(-s.cfg.AutoClose), now)
+}
+
+// reply checks that a message can be added to the ticket and adds it
+func (s *SupportService) reply(ticket *models.SupportTicket, message *models.SupportMessage, uploads []SupportUpload) error {
+	if ticket.Status == models.SupportStatusClosed {
+		return ErrSupportTicketClosed
+	}
+	if message.Body == "" && len(uploads) == 0 {
+		return ErrEmptySupportMessage
+	}
+	if len(uploads) > s.cfg.MaxAttachments {
+		return ErrTooManyAttachments
+	}
+	return s.addMessage(ticket, message, uploads)
+}
+
+// addMessage saves a message and its attachments. Files already stored when a later one
+// fails are left in the store.
+func (s *SupportService) addMessage(ticket *models.SupportTicket, message *models.SupportMessage, uploads []SupportUpload) error {
+	if err := s.supportRepo.CreateMessage(message); err != nil {
+		return err
+	}
+	for _, upload := range uploads {
+		attachment, err := s.saveAttachment(ticket, message, upload)
+		if err != nil {
+			return err
+		}
+		message.Attachments = append(message.Attachments, *attachment)
+	}
+	return nil
+}
+
+// saveAttachment stores an uploaded file after checking its type from its content
+func (s *SupportService) saveAttachment(ticket *models.SupportTicket, message *models.SupportMessage, upload SupportUpload) (*models.SupportAttachment, error) {
+	reader := bufio.NewReader(upload.Content)
+	head, _ := reader.Peek(512)
+	contentType := http.DetectContentType(head)
+	ext, ok := attachmentTypes[contentType]
+	if len(head) == 0 || !ok {
+		return nil, ErrAttachmentType
+	}
+
+	key := fmt.Sprintf("%d/%s%s", ticket.ID, strings.ToLower(utils.GenerateRandomString(16)), ext)
+	content := &sizeLimitReader{r: reader, max: s.cfg.MaxAttachmentSize}
+	if err := s.store.Save(key, content); err != nil {
+		return nil, err
+	}
+
+	attachment := &models.SupportAttachment{
+		TicketID:    ticket.ID,
+		MessageID:   message.ID,
+		FileName:    attachmentName(upload.Name, ext),
+		ContentType: contentType,
+		Size:        content.n,
+		StorageKey:  key,
+	}
+	if err := s.supportRepo.CreateAttachment(attachment); err != nil {
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// bookedBy reports whether the customer opening ticket made booking
+func bookedBy(booking *models.Booking, ticket *models.SupportTicket) bool {
+	if ticket.UserID != nil && booking.UserID != nil && *booking.UserID == *ticket.UserID {
+		return true
+	}
+	if booking.GuestInfo != nil && booking.GuestInfo.Phone == ticket.ContactPhone {
+		return true
+	}
+	return booking.User != nil && booking.User.Phone == ticket.ContactPhone
+}
+
+// attachmentName keeps the base name of an uploaded file without control characters,
+// falling back to a generic name
+func attachmentName(name, ext string) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' {
+			return -1
+		}
+		return r
+	}, filepath.Base(strings.ReplaceAll(name, "\\", "/")))
+	if name == "" || name == "." || name == "/" {
+		return "attachment" + ext
+	}
+	if runes := []rune(name); len(runes) > 255 {
+		name = string(runes[:255])
+	}
+	return name
+}
+
+// sizeLimitReader counts the bytes read and fails once more than max have been read
+type sizeLimitReader struct {
+	r   io.Reader
+	max int64
+	n   int64
+}
+
+func (l *sizeLimitReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.n += int64(n)
+	if l.n > l.max {
+		return n, ErrAttachmentTooLarge
+	}
+	return n, err
+}
@@ -0,0 +1,340 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/utils"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTripNotAssigned    = errors.New("trip is not assigned to this driver")
+	ErrTripNotTracking    = errors.New("trip is not accepting positions")
+	ErrTooManyPositions   = errors.New("too many positions in one batch")
+	ErrPositionOutOfRange = errors.New("position time is outside the trip")
+)
+
+const (
+	TrackingStatusScheduled = "scheduled"  // Chưa có vị trí, ETA theo lịch
+	TrackingStatusInTransit = "in_transit" // Xe đang chạy
+	TrackingStatusArrived   = "arrived"    // Xe đã đến điểm cuối
+	TrackingStatusCompleted = "completed"  // Chuyến đã hoàn thành
+)
+
+// TrackingConfig controls which GPS points are accepted and how ETAs are computed
+type TrackingConfig struct {
+	MaxBatchSize    int           // Points per request, so devices can flush an offline buffer
+	StartWindow     time.Duration // Accept points from this long before departure
+	MaxClockSkew    time.Duration // Accept device times this far ahead of the server clock
+	SpeedWindow     time.Duration // Average speed over the points of this recent period
+	MinMovingSpeed  float64       // Below this observed speed (km/h) the planned speed is used
+	ArrivalRadiusKm float64       // A stop within this distance counts as reached
+	RoadFactor      float64       // Road distance per straight-line km when the route has no distance
+	StaleAfter      time.Duration // A position older than this is flagged as stale
+}
+
+// DefaultTrackingConfig returns the settings used by the API
+func DefaultTrackingConfig() TrackingConfig {
+	return TrackingConfig{
+		MaxBatchSize:    100,
+		StartWindow:     time.Hour,
+		MaxClockSkew:    2 * time.Minute,
+		SpeedWindow:     10 * time.Minute,
+		MinMovingSpeed:  5,
+		ArrivalRadiusKm: 1,
+		RoadFactor:      1.3,
+		StaleAfter:      5 * time.Minute,
+	}
+}
+
+// TrackingTopic is the broker topic carrying live updates of a trip
+func TrackingTopic(tripID uint) string {
+	return fmt.Sprintf("trips:%d:tracking", tripID)
+}
+
+// StopETA is the scheduled and estimated arrival at a stop of the trip
+type StopETA struct {
+	PointID     *uint                 `json:"point_id,omitempty"` // Điểm đón/trả (trống: bến đầu/cuối của tuyến)
+	Type        models.RoutePointType `json:"type,omitempty"`
+	Name        string                `json:"name"`
+	Latitude    *float64              `json:"latitude,omitempty"`
+	Longitude   *float64              `json:"longitude,omitempty"`
+	ScheduledAt time.Time             `json:"scheduled_at"`           // Giờ đến theo lịch
+	EstimatedAt time.Time             `json:"estimated_at"`           // Giờ đến dự kiến
+	RemainingKm *float64              `json:"remaining_km,omitempty"` // Quãng đường còn lại
+	Passed      bool                  `json:"passed"`                 // Xe đã qua điểm này
+}
+
+// TripTracking is the live state of a trip shown to passengers
+type TripTracking struct {
+	TripID       uint                    `json:"trip_id"`
+	Status       string                  `json:"status"`
+	Position     *models.VehiclePosition `json:"position,omitempty"` // Vị trí gần nhất
+	Stale        bool                    `json:"stale"`              // Vị trí đã cũ, thiết bị có thể mất kết nối
+	SpeedKmh     float64                 `json:"speed_kmh"`          // Tốc độ trung bình gần đây
+	DelayMinutes int                     `json:"delay_minutes"`      // Trễ so với lịch (âm nếu sớm)
+	Stops        []StopETA               `json:"stops"`
+	UpdatedAt    time.Time               `json:"updated_at"`
+}
+
+// TrackingService ingests GPS points from drivers and computes trip ETAs
+type TrackingService struct {
+	positionRepo *repository.VehiclePositionRepository
+	tripRepo     *repository.TripRepository
+	pointRepo    *repository.RoutePointRepository
+	broker       Broker
+	cfg          TrackingConfig
+}
+
+func NewTrackingService(
+	positionRepo *repository.VehiclePositionRepository,
+	tripRepo *repository.TripRepository,
+	pointRepo *repository.RoutePointRepository,
+	broker Broker,
+	cfg TrackingConfig,
+) *TrackingService {
+	return &TrackingService{
+		positionRepo: positionRepo,
+		tripRepo:     tripRepo,
+		pointRepo:    pointRepo,
+		broker:       broker,
+		cfg:          cfg,
+	}
+}
+
+// Ingest stores positions reported by the driver of a trip and publishes the new tracking state
+func (s *TrackingService) Ingest(driverID uint, tripID uint, positions []models.VehiclePosition, now time.Time) (*TripTracking, error) {
+	if len(positions) == 0 || len(positions) > s.cfg.MaxBatchSize {
+		return nil, ErrTooManyPositions
+	}
+
+	trip, err := s.tripRepo.FindByID(tripID)
+	if err != nil {
+		return nil, err
+	}
+	if trip.DriverID != driverID {
+		return nil, ErrTripNotAssigned
+	}
+	earliest := trip.DepartureTime.Add(-s.cfg.StartWindow)
+	if !trip.IsActive || trip.IsCompleted || now.Before(earliest) {
+		return nil, ErrTripNotTracking
+	}
+
+	for i := range positions {
+		positions[i].ID = 0
+		positions[i].TripID = trip.ID
+		positions[i].DriverID = driverID
+		if err := positions[i].Validate(); err != nil {
+			return nil, err
+		}
+		if positions[i].RecordedAt.Before(earliest) || positions[i].RecordedAt.After(now.Add(s.cfg.MaxClockSkew)) {
+			return nil, ErrPositionOutOfRange
+		}
+	}
+	sort.SliceStable(positions, func(i, j int) bool {
+		return positions[i].RecordedAt.Before(positions[j].RecordedAt)
+	})
+
+	if err := s.positionRepo.CreateBatch(positions); err != nil {
+		return nil, err
+	}
+
+	tracking, err := s.Snapshot(trip, now)
+	if err != nil {
+		return nil, err
+	}
+	if s.broker != nil {
+		payload, err := json.Marshal(tracking)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.broker.Publish(TrackingTopic(trip.ID), payload); err != nil {
+			return nil, err
+		}
+	}
+	return tracking, nil
+}
+
+// Track returns the positions of a trip recorded since the given time
+func (s *TrackingService) Track(tripID uint, since time.Time) ([]models.VehiclePosition, error) {
+	return s.positionRepo.FindByTrip(tripID, since)
+}
+
+// Snapshot computes the last known position and stop ETAs of a trip.
+// Without positions or route coordinates the ETAs follow the timetable.
+func (s *TrackingService) Snapshot(trip *models.Trip, now time.Time) (*TripTracking, error) {
+	if trip.Route == nil || trip.Route.ID != trip.RouteID {
+		loaded, err := s.tripRepo.FindByID(trip.ID)
+		if err != nil {
+			return nil, err
+		}
+		trip = loaded
+	}
+
+	route := trip.Route
+	arrival := trip.DepartureTime.Add(drivingDuration(trip, DefaultScheduleConfig().FallbackTripDuration))
+	points, err := s.pointRepo.FindByRoute(route.ID, "", true)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pickup and drop-off points sit between the two ends of the route in timetable order
+	tracking := &TripTracking{
+		TripID:    trip.ID,
+		Status:    TrackingStatusScheduled,
+		UpdatedAt: now,
+		Stops:     make([]StopETA, 0, len(points)+2),
+	}
+	tracking.Stops = append(tracking.Stops, StopETA{Name: route.Origin, Latitude: route.OriginLat, Longitude: route.OriginLng, ScheduledAt: trip.DepartureTime, EstimatedAt: trip.DepartureTime})
+	for i := range points {
+		point := &points[i]
+		at := point.TimeFrom(trip.DepartureTime)
+		tracking.Stops = append(tracking.Stops, StopETA{
+			PointID:     &point.ID,
+			Type:        point.Type,
+			Name:        point.Name,
+			Latitude:    point.Latitude,
+			Longitude:   point.Longitude,
+			ScheduledAt: at,
+			EstimatedAt: at,
+		})
+	}
+	tracking.Stops = append(tracking.Stops, StopETA{Name: route.Destination, Latitude: route.DestinationLat, Longitude: route.DestinationLng, ScheduledAt: arrival, EstimatedAt: arrival})
+	if trip.IsCompleted {
+		tracking.Status = TrackingStatusCompleted
+	}
+
+	latest, err := s.positionRepo.FindLatest(trip.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return tracking, nil
+		}
+		return nil, err
+	}
+	tracking.Position = latest
+	tracking.Stale = now.Sub(latest.RecordedAt) > s.cfg.StaleAfter
+	if !trip.IsCompleted {
+		tracking.Status = TrackingStatusInTransit
+	}
+	if !route.HasCoordinates() {
+		return tracking, nil
+	}
+
+	recent, err := s.positionRepo.FindByTrip(trip.ID, latest.RecordedAt.Add(-s.cfg.SpeedWindow))
+	if err != nil {
+		return nil, err
+	}
+
+	straightKm := utils.HaversineKm(*route.OriginLat, *route.OriginLng, *route.DestinationLat, *route.DestinationLng)
+	roadKm := route.Distance
+	if roadKm <= 0 {
+		roadKm = straightKm * s.cfg.RoadFactor
+	}
+	roadFactor := 1.0
+	if straightKm > 0 {
+		roadFactor = roadKm / straightKm
+	}
+
+	plannedSpeed := roadKm / arrival.Sub(trip.DepartureTime).Hours()
+	speed := observedSpeed(recent, s.cfg.MinMovingSpeed) * roadFactor
+	tracking.SpeedKmh = math.Round(speed*10) / 10
+	if speed < s.cfg.MinMovingSpeed {
+		speed = plannedSpeed
+	}
+	if speed <= 0 {
+		return tracking, nil
+	}
+
+	// Progress along the road is estimated from the remaining straight-line distance
+	toDestination := utils.HaversineKm(latest.Latitude, latest.Longitude, *route.DestinationLat, *route.DestinationLng)
+	remaining := math.Min(toDestination*roadFactor, roadKm)
+	travelled := roadKm - remaining
+	stopKm := make([]float64, len(tracking.Stops))
+	for i, stop := range tracking.Stops {
+		stopKm[i] = stopDistance(stop, route, trip.DepartureTime, arrival, roadKm, roadFactor)
+	}
+
+	// ETAs count from now: a bus stuck without moving keeps slipping
+	from := now
+	if latest.RecordedAt.After(now) {
+		from = latest.RecordedAt
+	}
+	last := len(tracking.Stops) - 1
+	for i := range tracking.Stops {
+		stop := &tracking.Stops[i]
+		left := stopKm[i] - travelled
+		if i == last {
+			left = remaining
+		}
+
+		switch {
+		case i == last && left <= s.cfg.ArrivalRadiusKm:
+			stop.Passed = true
+			stop.EstimatedAt = latest.RecordedAt
+			continue
+		case i < last && left < -s.cfg.ArrivalRadiusKm:
+			stop.Passed = true
+			continue
+		}
+
+		km := math.Round(math.Max(left, 0)*10) / 10
+		stop.RemainingKm = &km
+		stop.EstimatedAt = from.Add(time.Duration(math.Max(left, 0) / speed * float64(time.Hour))).Truncate(time.Second)
+		// Coaches do not leave a boarding stop before its scheduled time
+		if i < last && stop.Type != models.RoutePointDropoff && stop.EstimatedAt.Before(stop.ScheduledAt) {
+			stop.EstimatedAt = stop.ScheduledAt
+		}
+	}
+
+	destination := tracking.Stops[len(tracking.Stops)-1]
+	tracking.DelayMinutes = int(math.Round(destination.EstimatedAt.Sub(destination.ScheduledAt).Minutes()))
+	if destination.Passed && !trip.IsCompleted {
+		tracking.Status = TrackingStatusArrived
+	}
+	return tracking, nil
+}
+
+// stopDistance estimates how far along the road a stop lies: from its coordinates when known,
+// otherwise in proportion to its scheduled time
+func stopDistance(stop StopETA, route *models.Route, departure, arrival time.Time, roadKm, roadFactor float64) float64 {
+	var km float64
+	switch {
+	case stop.ScheduledAt.Equal(departure) && stop.PointID == nil:
+		return 0
+	case stop.Latitude != nil && stop.Longitude != nil:
+		km = utils.HaversineKm(*route.OriginLat, *route.OriginLng, *stop.Latitude, *stop.Longitude) * roadFactor
+	default:
+		km = roadKm * stop.ScheduledAt.Sub(departure).Hours() / arrival.Sub(departure).Hours()
+	}
+	return math.Max(0, math.Min(km, roadKm))
+}
+
+// observedSpeed returns the average straight-line speed in km/h over the segments between
+// consecutive positions in which the bus was moving, so a stop does not drag the average down
+func observedSpeed(positions []models.VehiclePosition, minMoving float64) float64 {
+	var distance, elapsed float64
+	for i := 1; i < len(positions); i++ {
+		hours := positions[i].RecordedAt.Sub(positions[i-1].RecordedAt).Hours()
+		if hours <= 0 {
+			continue
+		}
+		km := utils.HaversineKm(positions[i-1].Latitude, positions[i-1].Longitude, positions[i].Latitude, positions[i].Longitude)
+		if km/hours < minMoving {
+			continue
+		}
+		distance += km
+		elapsed += hours
+	}
+	if elapsed == 0 {
+		return 0
+	}
+	return distance / elapsed
+}
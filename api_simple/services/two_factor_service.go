@@ -0,0 +1,169 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/utils"
+)
+
+const RecoveryCodeCount = 10
+
+var (
+	ErrTwoFactorNotEnrolled     = errors.New("two-factor authentication is not enrolled")
+	ErrTwoFactorAlreadyEnabled  = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorInvalidCode     = errors.New("two-factor code is invalid")
+	ErrTwoFactorRequiredForRole = errors.New("two-factor authentication is required for this role")
+)
+
+// TwoFactorService manages TOTP enrolment, verification and recovery codes
+type TwoFactorService struct {
+	userRepo *repository.UserRepository
+	codeRepo *repository.RecoveryCodeRepository
+	issuer   string
+}
+
+func NewTwoFactorService(userRepo *repository.UserRepository, codeRepo *repository.RecoveryCodeRepository) *TwoFactorService {
+	issuer := os.Getenv("TOTP_ISSUER")
+	if issuer == "" {
+		issuer = "Ticket Management"
+	}
+	return &TwoFactorService{
+		userRepo: userRepo,
+		codeRepo: codeRepo,
+		issuer:   issuer,
+	}
+}
+
+// BeginEnrollment generates a new secret for the user and returns it with its provisioning URI.
+// 2FA stays disabled until ConfirmEnrollment succeeds.
+func (s *TwoFactorService) BeginEnrollment(user *models.User) (string, string, error) {
+	if user.TwoFactorEnabled {
+		return "", "", ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return "", "", err
+	}
+
+	user.TwoFactorSecret = secret
+	user.TwoFactorLastStep = 0
+	if err := s.userRepo.Update(user); err != nil {
+		return "", "", err
+	}
+
+	return secret, utils.TOTPProvisioningURI(secret, user.Phone, s.issuer), nil
+}
+
+// ConfirmEnrollment enables 2FA once the user proves they can generate codes
+// and returns a fresh set of recovery codes
+func (s *TwoFactorService) ConfirmEnrollment(user *models.User, code string) ([]string, error) {
+	if user.TwoFactorEnabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if user.TwoFactorSecret == "" {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+
+	step, ok := utils.ValidateTOTP(user.TwoFactorSecret, code, time.Now())
+	if !ok {
+		return nil, ErrTwoFactorInvalidCode
+	}
+
+	user.TwoFactorEnabled = true
+	user.TwoFactorLastStep = step
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+
+	return s.issueRecoveryCodes(user.ID)
+}
+
+// Verify accepts either a current TOTP code or an unused recovery code
+func (s *TwoFactorService) Verify(user *models.User, code string) error {
+	if !user.TwoFactorEnabled {
+		return ErrTwoFactorNotEnrolled
+	}
+
+	code = strings.TrimSpace(code)
+
+	// A TOTP code is only accepted once, so a later step than the last one is required
+	if step, ok := utils.ValidateTOTP(user.TwoFactorSecret, code, time.Now()); ok && step > user.TwoFactorLastStep {
+		user.TwoFactorLastStep = step
+		return s.userRepo.Update(user)
+	}
+
+	return s.useRecoveryCode(user.ID, code)
+}
+
+// Disable turns 2FA off for roles that do not require it
+func (s *TwoFactorService) Disable(user *models.User, code string) error {
+	if models.RequiresTwoFactor(user.Role) {
+		return ErrTwoFactorRequiredForRole
+	}
+	if err := s.Verify(user, code); err != nil {
+		return err
+	}
+
+	user.TwoFactorEnabled = false
+	user.TwoFactorSecret = ""
+	user.TwoFactorLastStep = 0
+	if err := s.userRepo.Update(user); err != nil {
+		return err
+	}
+
+	return s.codeRepo.Replace(user.ID, nil)
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after verifying a second factor
+func (s *TwoFactorService) RegenerateRecoveryCodes(user *models.User, code string) ([]string, error) {
+	if err := s.Verify(user, code); err != nil {
+		return nil, err
+	}
+	return s.issueRecoveryCodes(user.ID)
+}
+
+func (s *TwoFactorService) issueRecoveryCodes(userID uint) ([]string, error) {
+	plain := make([]string, RecoveryCodeCount)
+	records := make([]models.RecoveryCode, RecoveryCodeCount)
+	for i := range plain {
+		raw := utils.GenerateRandomString(8)
+		hash, err := utils.HashPassword(raw)
+		if err != nil {
+			return nil, err
+		}
+		plain[i] = raw[:4] + "-" + raw[4:]
+		records[i] = models.RecoveryCode{UserID: userID, CodeHash: hash}
+	}
+
+	if err := s.codeRepo.Replace(userID, records); err != nil {
+		return nil, err
+	}
+	return plain, nil
+}
+
+func (s *TwoFactorService) useRecoveryCode(userID uint, code string) error {
+	normalized := strings.ToUpper(strings.ReplaceAll(code, "-", ""))
+	if len(normalized) != 8 {
+		return ErrTwoFactorInvalidCode
+	}
+
+	codes, err := s.codeRepo.FindUnused(userID)
+	if err != nil {
+		return err
+	}
+
+	for i := range codes {
+		if utils.CheckPasswordHash(normalized, codes[i].CodeHash) {
+			now := time.Now()
+			codes[i].UsedAt = &now
+			return s.codeRepo.Update(&codes[i])
+		}
+	}
+	return ErrTwoFactorInvalidCode
+}
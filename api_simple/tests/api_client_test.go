@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type APIClientTestSuite struct {
+	ServiceTestSuite
+	service *services.APIClientService
+}
+
+func (suite *APIClientTestSuite) SetupTest() {
+	suite.ServiceTestSuite.SetupTest()
+	suite.service = services.NewAPIClientService(repository.NewAPIClientRepository(suite.db))
+}
+
+func newTestAPIClient() *models.APIClient {
+	return &models.APIClient{
+		Name:               "Đại lý Sài Gòn",
+		Scopes:             []string{string(models.APIScopeSearch), string(models.APIScopeBook)},
+		RateLimitPerMinute: 60,
+		CommissionRate:     0.05,
+	}
+}
+
+func (suite *APIClientTestSuite) TestIssueAndAuthenticate() {
+	client := newTestAPIClient()
+	key, err := suite.service.Issue(client)
+	require.NoError(suite.T(), err)
+	assert.NotContains(suite.T(), client.KeyHash, key)
+
+	authenticated, err := suite.service.Authenticate(key)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), client.ID, authenticated.ID)
+	assert.True(suite.T(), authenticated.HasScope(models.APIScopeBook))
+	assert.False(suite.T(), authenticated.HasScope(models.APIScopeCancel))
+
+	_, err = suite.service.Authenticate(key[:len(key)-1] + "X")
+	assert.ErrorIs(suite.T(), err, services.ErrAPIKeyInvalid)
+}
+
+func (suite *APIClientTestSuite) TestRotateInvalidatesOldKey() {
+	client := newTestAPIClient()
+	oldKey, err := suite.service.Issue(client)
+	require.NoError(suite.T(), err)
+
+	newKey, err := suite.service.Rotate(client)
+	require.NoError(suite.T(), err)
+
+	_, err = suite.service.Authenticate(oldKey)
+	assert.ErrorIs(suite.T(), err, services.ErrAPIKeyInvalid)
+	_, err = suite.service.Authenticate(newKey)
+	assert.NoError(suite.T(), err)
+}
+
+func (suite *APIClientTestSuite) TestRevoke() {
+	client := newTestAPIClient()
+	key, err := suite.service.Issue(client)
+	require.NoError(suite.T(), err)
+	require.NoError(suite.T(), suite.service.Revoke(client))
+
+	_, err = suite.service.Authenticate(key)
+	assert.ErrorIs(suite.T(), err, services.ErrAPIClientRevoked)
+}
+
+func (suite *APIClientTestSuite) TestInvalidScope() {
+	client := newTestAPIClient()
+	client.Scopes = append(client.Scopes, "refund")
+	_, err := suite.service.Issue(client)
+	assert.Error(suite.T(), err)
+}
+
+func (suite *APIClientTestSuite) TestRateLimiter() {
+	limiter := services.NewRateLimiter(time.Minute)
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		allowed, remaining, _ := limiter.Allow("client-1", 3, now)
+		assert.True(suite.T(), allowed)
+		assert.Equal(suite.T(), 2-i, remaining)
+	}
+
+	allowed, _, reset := limiter.Allow("client-1", 3, now)
+	assert.False(suite.T(), allowed)
+	assert.Equal(suite.T(), time.Minute, reset)
+
+	// Limits are tracked per client
+	allowed, _, _ = limiter.Allow("client-2", 3, now)
+	assert.True(suite.T(), allowed)
+
+	// A new window starts once the previous one has elapsed
+	allowed, _, _ = limiter.Allow("client-1", 3, now.Add(time.Minute))
+	assert.True(suite.T(), allowed)
+}
+
+func TestAPIClientTestSuite(t *testing.T) {
+	suite.Run(t, new(APIClientTestSuite))
+}
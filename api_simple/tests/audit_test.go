@@ -0,0 +1,62 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type AuditTestSuite struct {
+	ServiceTestSuite
+}
+
+func (suite *AuditTestSuite) TestDiffSnapshots() {
+	before := models.User{Phone: "0987654321", Name: "Nguyễn Văn A", Role: models.RoleCustomer, Password: "secret"}
+	after := before
+	after.Role = models.RoleStaff
+	after.Password = "changed"
+	after.UpdatedAt = time.Now()
+
+	changes, err := services.DiffSnapshots(before, after)
+	require.NoError(suite.T(), err)
+
+	assert.Len(suite.T(), changes, 1)
+	assert.Equal(suite.T(), models.AuditChange{From: "customer", To: "staff"}, changes["role"])
+
+	// Deletions record every visible field as removed
+	changes, err = services.DiffSnapshots(&before, nil)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "0987654321", changes["phone"].From)
+	assert.Nil(suite.T(), changes["phone"].To)
+	assert.NotContains(suite.T(), changes, "Password")
+}
+
+func (suite *AuditTestSuite) TestAuditLogAppendOnly() {
+	repo := repository.NewAuditLogRepository(suite.db)
+	service := services.NewAuditService(repo)
+
+	before := models.Booking{Status: models.BookingStatusPending}
+	after := models.Booking{Status: models.BookingStatusConfirmed}
+	entry := &models.AuditLog{Action: "booking.update_status", EntityType: "bookings", EntityID: "1"}
+	require.NoError(suite.T(), service.Record(entry, before, after))
+
+	entries, total, err := repo.FindAll(map[string]interface{}{"entity_type": "bookings"}, nil, nil, 1, 10)
+	require.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), 1, total)
+	assert.Equal(suite.T(), "confirmed", entries[0].Changes["status"].To)
+
+	entry.Action = "tampered"
+	assert.ErrorIs(suite.T(), suite.db.Save(entry).Error, models.ErrAuditLogImmutable)
+	assert.ErrorIs(suite.T(), suite.db.Delete(entry).Error, models.ErrAuditLogImmutable)
+}
+
+func TestAuditTestSuite(t *testing.T) {
+	suite.Run(t, new(AuditTestSuite))
+}
@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type CashShiftTestSuite struct {
+	ServiceTestSuite
+	service    *services.CashShiftService
+	cashier    models.User
+	supervisor models.User
+	now        time.Time
+	bookingID  uint
+}
+
+func (suite *CashShiftTestSuite) SetupTest() {
+	suite.ServiceTestSuite.SetupTest()
+
+	suite.cashier = models.User{Phone: "0922222222", Name: "Thu ngân A", Role: models.RoleStaff}
+	suite.supervisor = models.User{Phone: "0933333333", Name: "Quản lý B", Role: models.RoleAdmin}
+	require.NoError(suite.T(), suite.db.Create(&suite.cashier).Error)
+	require.NoError(suite.T(), suite.db.Create(&suite.supervisor).Error)
+
+	suite.service = services.NewCashShiftService(repository.NewCashShiftRepository(suite.db), services.DefaultCashShiftConfig())
+	suite.now = time.Date(2026, 3, 10, 7, 0, 0, 0, time.Local)
+	suite.bookingID = 42
+}
+
+func (suite *CashShiftTestSuite) TestOnlyOneOpenShift() {
+	_, err := suite.service.Open(&suite.cashier, 1, 500000, suite.now)
+	require.NoError(suite.T(), err)
+
+	_, err = suite.service.Open(&suite.cashier, 1, 500000, suite.now)
+	assert.ErrorIs(suite.T(), err, services.ErrShiftAlreadyOpen)
+}
+
+func (suite *CashShiftTestSuite) TestRecordingNeedsOpenShift() {
+	_, err := suite.service.Record(suite.cashier.ID, models.CashTransactionSale, 150000, &suite.bookingID, "BK000042")
+	assert.ErrorIs(suite.T(), err, services.ErrNoOpenShift)
+
+	// Back-office cash outside a shift is simply not tracked
+	require.NoError(suite.T(), suite.service.RecordIfOpen(suite.cashier.ID, models.CashTransactionRefund, 150000, &suite.bookingID, "BK000042"))
+}
+
+func (suite *CashShiftTestSuite) TestCloseComputesVariance() {
+	_, err := suite.service.Open(&suite.cashier, 1, 500000, suite.now)
+	require.NoError(suite.T(), err)
+
+	_, err = suite.service.Record(suite.cashier.ID, models.CashTransactionSale, 300000, &suite.bookingID, "BK000042")
+	require.NoError(suite.T(), err)
+	_, err = suite.service.Record(suite.cashier.ID, models.CashTransactionSale, 150000, nil, "BK000043")
+	require.NoError(suite.T(), err)
+	require.NoError(suite.T(), suite.service.RecordIfOpen(suite.cashier.ID, models.CashTransactionRefund, 75000, &suite.bookingID, "BK000042"))
+	_, err = suite.service.Record(suite.cashier.ID, models.CashTransactionPayout, 50000, nil, "Mua nước cho xe")
+	require.NoError(suite.T(), err)
+
+	// 500.000 + 450.000 - 75.000 - 50.000 = 825.000, counted 5.000 short
+	report, err := suite.service.Close(suite.cashier.ID, 820000, "", suite.now.Add(8*time.Hour))
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.Money(450000), report.SalesTotal)
+	assert.Equal(suite.T(), 2, report.SalesCount)
+	assert.Equal(suite.T(), models.Money(75000), report.RefundsTotal)
+	assert.Equal(suite.T(), models.Money(50000), report.PayoutsTotal)
+	assert.Equal(suite.T(), models.Money(825000), report.ExpectedCash)
+	require.NotNil(suite.T(), report.Variance)
+	assert.Equal(suite.T(), models.Money(-5000), *report.Variance)
+	assert.True(suite.T(), report.WithinLimit)
+	assert.Equal(suite.T(), models.CashShiftStatusClosed, report.Shift.Status)
+
+	_, err = suite.service.Current(suite.cashier.ID)
+	assert.ErrorIs(suite.T(), err, services.ErrNoOpenShift)
+}
+
+func (suite *CashShiftTestSuite) TestLargeVarianceNeedsNote() {
+	_, err := suite.service.Open(&suite.cashier, 1, 500000, suite.now)
+	require.NoError(suite.T(), err)
+
+	_, err = suite.service.Close(suite.cashier.ID, 400000, "", suite.now.Add(8*time.Hour))
+	assert.ErrorIs(suite.T(), err, services.ErrVarianceNoteRequired)
+
+	report, err := suite.service.Close(suite.cashier.ID, 400000, "Trả nhầm tiền thừa cho khách", suite.now.Add(8*time.Hour))
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.Money(-100000), *report.Variance)
+	assert.False(suite.T(), report.WithinLimit)
+}
+
+func (suite *CashShiftTestSuite) TestPayoutNeedsReason() {
+	_, err := suite.service.Open(&suite.cashier, 1, 500000, suite.now)
+	require.NoError(suite.T(), err)
+
+	_, err = suite.service.Record(suite.cashier.ID, models.CashTransactionPayout, 50000, nil, "")
+	assert.Error(suite.T(), err)
+}
+
+func (suite *CashShiftTestSuite) TestSupervisorApprovesClosedShift() {
+	shift, err := suite.service.Open(&suite.cashier, 1, 500000, suite.now)
+	require.NoError(suite.T(), err)
+
+	_, err = suite.service.Approve(shift.ID, suite.supervisor.ID, "", suite.now)
+	assert.ErrorIs(suite.T(), err, services.ErrShiftNotClosed)
+
+	_, err = suite.service.Close(suite.cashier.ID, 500000, "", suite.now.Add(8*time.Hour))
+	require.NoError(suite.T(), err)
+
+	_, err = suite.service.Approve(shift.ID, suite.cashier.ID, "", suite.now)
+	assert.ErrorIs(suite.T(), err, services.ErrShiftSelfApproval)
+
+	approved, err := suite.service.Approve(shift.ID, suite.supervisor.ID, "Khớp sổ", suite.now.Add(9*time.Hour))
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.CashShiftStatusApproved, approved.Status)
+	assert.Equal(suite.T(), suite.supervisor.ID, *approved.ReviewedBy)
+
+	// Saving the shift must not touch the preloaded staff account
+	var cashier models.User
+	require.NoError(suite.T(), suite.db.First(&cashier, suite.cashier.ID).Error)
+	assert.Equal(suite.T(), suite.cashier.Password, cashier.Password)
+}
+
+func TestCashShiftTestSuite(t *testing.T) {
+	suite.Run(t, new(CashShiftTestSuite))
+}
@@ -0,0 +1,149 @@
+package tests
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type DemandTestSuite struct {
+	ServiceTestSuite
+	service *services.DemandService
+	config  services.DemandConfig
+}
+
+func (suite *DemandTestSuite) SetupTest() {
+	suite.ServiceTestSuite.SetupTest()
+
+	suite.config = services.DefaultDemandConfig()
+	suite.config.Horizon = 7 * 24 * time.Hour
+	suite.config.MinSearches = 3
+	suite.config.MinUnmetSearches = 2
+	suite.service = services.NewDemandService(repository.NewDemandRepository(suite.db), repository.NewRouteRepository(suite.db), suite.config)
+}
+
+// search records count searches between origin and destination for travel on day
+func (suite *DemandTestSuite) search(origin, destination string, day time.Time, results, count int) {
+	for i := 0; i < count; i++ {
+		require.NoError(suite.T(), suite.service.RecordSearch(origin, destination, &day, nil, results, day.Add(-48*time.Hour)))
+	}
+}
+
+func (suite *DemandTestSuite) book(trip models.Trip, online bool, status models.BookingStatus) {
+	booking := models.Booking{OperatorID: suite.own.ID, BookingCode: fmt.Sprintf("BK-DEMAND-%d", time.Now().UnixNano()), GuestInfo: &models.GuestInfo{Name: "Nguyễn Văn A", Phone: "0987654321"}, TripID: trip.ID, SeatIDs: pq.Int64Array{1}, TotalAmount: 150000, Status: status}
+	if online {
+		booking.UserID = &suite.driver.ID
+	}
+	require.NoError(suite.T(), suite.db.Create(&booking).Error)
+}
+
+func (suite *DemandTestSuite) TestSeasons() {
+	from, to, err := models.SeasonWinter.Range(2026, time.UTC)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), from)
+	assert.Equal(suite.T(), time.Date(2027, 2, 1, 0, 0, 0, 0, time.UTC), to)
+
+	season, year := models.SeasonAt(time.Date(2027, 1, 20, 0, 0, 0, 0, time.UTC))
+	assert.Equal(suite.T(), models.SeasonWinter, season)
+	assert.Equal(suite.T(), 2026, year)
+	season, year = models.SeasonAt(time.Date(2026, 7, 31, 0, 0, 0, 0, time.UTC))
+	assert.Equal(suite.T(), models.SeasonSummer, season)
+	assert.Equal(suite.T(), 2026, year)
+
+	_, _, err = models.Season("monsoon").Range(2026, time.UTC)
+	assert.ErrorIs(suite.T(), err, models.ErrInvalidSeason)
+}
+
+func (suite *DemandTestSuite) TestCorridors() {
+	day := time.Date(2026, 6, 10, 0, 0, 0, 0, time.Local)
+
+	trip := suite.createTrip(suite.outbound, day.Add(8*time.Hour))
+	suite.search("Hà Nội", "Hải Phòng", day, 1, 4)
+	suite.search("Hà Nội", "Hải Phòng", day, 0, 1)
+	suite.search("Hà Nội", "Sa Pa", day, 0, 3)
+	suite.search("Hà Nội", "Hải Phòng", day.AddDate(0, 3, 0), 1, 5) // Mùa khác
+	suite.book(trip, true, models.BookingStatusConfirmed)
+	suite.book(trip, true, models.BookingStatusPending)
+	suite.book(trip, false, models.BookingStatusConfirmed)
+	suite.book(trip, true, models.BookingStatusCancelled)
+
+	routes, err := suite.service.PopularRoutes(models.SeasonSummer, 2026, 10)
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), routes, 2)
+	assert.Equal(suite.T(), "Hải Phòng", routes[0].Destination)
+	assert.EqualValues(suite.T(), 5, routes[0].Searches)
+	assert.EqualValues(suite.T(), 1, routes[0].UnmetSearches)
+	assert.EqualValues(suite.T(), 3, routes[0].Bookings)
+	assert.EqualValues(suite.T(), 2, routes[0].OnlineBookings)
+	assert.InDelta(suite.T(), 0.4, routes[0].ConversionRate, 1e-9)
+	assert.Equal(suite.T(), "Sa Pa", routes[1].Destination)
+	assert.EqualValues(suite.T(), 3, routes[1].UnmetSearches)
+	assert.Zero(suite.T(), routes[1].Bookings)
+
+	routes, err = suite.service.PopularRoutes(models.SeasonSummer, 2026, 1)
+	require.NoError(suite.T(), err)
+	assert.Len(suite.T(), routes, 1)
+
+	routes, err = suite.service.PopularRoutes(models.SeasonAutumn, 2026, 10)
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), routes, 1)
+	assert.EqualValues(suite.T(), 5, routes[0].Searches)
+	assert.Zero(suite.T(), routes[0].Bookings)
+}
+
+func (suite *DemandTestSuite) TestDepartureRecommendations() {
+	now := time.Date(2026, 6, 10, 9, 0, 0, 0, time.Local)
+	today := time.Date(2026, 6, 10, 0, 0, 0, 0, time.Local)
+
+	full := suite.createTrip(suite.outbound, today.AddDate(0, 0, 1).Add(8*time.Hour))
+	require.NoError(suite.T(), suite.db.Model(&full).Updates(map[string]interface{}{"total_seats": 40, "booked_seats": 36}).Error)
+	quiet := suite.createTrip(suite.outbound, today.AddDate(0, 0, 2).Add(8*time.Hour))
+	require.NoError(suite.T(), suite.db.Model(&quiet).Updates(map[string]interface{}{"total_seats": 40, "booked_seats": 10}).Error)
+	soldOut := suite.createTrip(suite.inbound, today.AddDate(0, 0, 1).Add(8*time.Hour))
+	require.NoError(suite.T(), suite.db.Model(&soldOut).Updates(map[string]interface{}{"total_seats": 40, "booked_seats": 40}).Error)
+
+	require.NoError(suite.T(), suite.db.Exec("UPDATE trips SET operator_id = ?", suite.own.ID).Error)
+
+	suite.search("Hà Nội", "Hải Phòng", today.AddDate(0, 0, 1), 1, 3) // Gần kín chỗ
+	suite.search("Hà Nội", "Hải Phòng", today.AddDate(0, 0, 2), 1, 3) // Còn nhiều chỗ
+	suite.search("Hải Phòng", "Hà Nội", today.AddDate(0, 0, 1), 0, 2) // Hết chỗ
+	suite.search("Hải Phòng", "Hà Nội", today.AddDate(0, 0, 3), 0, 3) // Không có chuyến
+	suite.search("Hải Phòng", "Hà Nội", today.AddDate(0, 0, 4), 0, 1) // Quá ít lượt tìm
+	suite.search("Hải Phòng", "Hà Nội", today.AddDate(0, 0, 9), 0, 5) // Ngoài khoảng đề xuất
+	suite.search("Đà Nẵng", "Huế", today.AddDate(0, 0, 1), 0, 5)      // Tuyến của nhà xe khác
+
+	service := services.NewDemandService(
+		repository.NewDemandRepository(repository.WithOperator(suite.db, suite.own.ID)),
+		repository.NewRouteRepository(repository.WithOperator(suite.db, suite.own.ID)),
+		suite.config,
+	)
+	recommendations, err := service.Recommend(now)
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), recommendations, 3)
+
+	assert.Equal(suite.T(), "2026-06-11", recommendations[0].TravelDate)
+	assert.Equal(suite.T(), suite.outbound.ID, recommendations[0].RouteID)
+	assert.Equal(suite.T(), services.RecommendHighLoad, recommendations[0].Reason)
+	assert.InDelta(suite.T(), 0.9, recommendations[0].LoadFactor, 1e-9)
+
+	assert.Equal(suite.T(), "2026-06-11", recommendations[1].TravelDate)
+	assert.Equal(suite.T(), suite.inbound.ID, recommendations[1].RouteID)
+	assert.Equal(suite.T(), services.RecommendSoldOut, recommendations[1].Reason)
+
+	assert.Equal(suite.T(), "2026-06-13", recommendations[2].TravelDate)
+	assert.Equal(suite.T(), services.RecommendNoDeparture, recommendations[2].Reason)
+	assert.Zero(suite.T(), recommendations[2].Trips)
+}
+
+func TestDemandTestSuite(t *testing.T) {
+	suite.Run(t, new(DemandTestSuite))
+}
@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type DutyTestSuite struct {
+	ServiceTestSuite
+	service *services.DutyService
+	monday  time.Time
+}
+
+func (suite *DutyTestSuite) SetupTest() {
+	suite.ServiceTestSuite.SetupTest()
+	suite.service = newDutyService(suite.db)
+
+	// Monday 06:00 next week keeps every trip inside one calendar day and week
+	now := time.Now()
+	suite.monday = time.Date(now.Year(), now.Month(), now.Day(), 6, 0, 0, 0, time.Local).
+		AddDate(0, 0, 7-(int(now.Weekday())+6)%7)
+}
+
+// licence gives the sample driver a licence of class expiring at expiry
+func (suite *DutyTestSuite) licence(class models.LicenceClass, expiry time.Time) {
+	require.NoError(suite.T(), suite.db.Create(&models.DriverProfile{
+		UserID: suite.driver.ID, LicenceNumber: "790123456789", LicenceClass: class, LicenceExpiry: expiry,
+	}).Error)
+}
+
+// rules returns the duty rules err reports as broken
+func (suite *DutyTestSuite) rules(err error) []string {
+	var violations services.DutyViolations
+	require.ErrorAs(suite.T(), err, &violations)
+	rules := make([]string, len(violations))
+	for i, v := range violations {
+		rules[i] = v.Rule
+	}
+	return rules
+}
+
+func (suite *DutyTestSuite) TestLicenceClassAndExpiry() {
+	suite.licence(models.LicenceClassD, suite.monday)
+
+	// 40-seat bus needs class E, and the licence expires at departure
+	trip := &models.Trip{RouteID: suite.outbound.ID, BusID: suite.bus.ID, DriverID: suite.driver.ID, DepartureTime: suite.monday}
+	assert.ElementsMatch(suite.T(), []string{services.DutyRuleLicenceClass, services.DutyRuleLicenceExpired},
+		suite.rules(suite.service.CheckAssignment(trip)))
+}
+
+func (suite *DutyTestSuite) TestMinimumRestBetweenTrips() {
+	suite.licence(models.LicenceClassE, suite.monday.AddDate(1, 0, 0))
+	suite.createTrip(suite.outbound, suite.monday)
+
+	// Arrives 08:00, only 1h30m rest before 09:30
+	trip := &models.Trip{RouteID: suite.inbound.ID, BusID: suite.bus.ID, DriverID: suite.driver.ID, DepartureTime: suite.monday.Add(210 * time.Minute)}
+	assert.Equal(suite.T(), []string{services.DutyRuleRest}, suite.rules(suite.service.CheckAssignment(trip)))
+
+	trip.DepartureTime = suite.monday.Add(4 * time.Hour)
+	assert.NoError(suite.T(), suite.service.CheckAssignment(trip))
+}
+
+func (suite *DutyTestSuite) TestDailyDrivingLimit() {
+	suite.licence(models.LicenceClassE, suite.monday.AddDate(1, 0, 0))
+	longRoute := models.Route{OperatorID: suite.own.ID, Origin: "Hà Nội", Destination: "Vinh", Duration: "5h", BasePrice: 300000, IsActive: true}
+	backRoute := models.Route{OperatorID: suite.own.ID, Origin: "Vinh", Destination: "Hà Nội", Duration: "5h", BasePrice: 300000, IsActive: true}
+	require.NoError(suite.T(), suite.db.Create(&longRoute).Error)
+	require.NoError(suite.T(), suite.db.Create(&backRoute).Error)
+	suite.createTrip(longRoute, suite.monday)
+
+	// 5h + 5h reaches the 10h limit exactly, a third leg would exceed it
+	trip := &models.Trip{RouteID: backRoute.ID, BusID: suite.bus.ID, DriverID: suite.driver.ID, DepartureTime: suite.monday.Add(7 * time.Hour)}
+	require.NoError(suite.T(), suite.service.CheckAssignment(trip))
+	require.NoError(suite.T(), suite.db.Create(trip).Error)
+
+	third := &models.Trip{RouteID: suite.outbound.ID, BusID: suite.bus.ID, DriverID: suite.driver.ID, DepartureTime: suite.monday.Add(14 * time.Hour)}
+	assert.Equal(suite.T(), []string{services.DutyRuleDailyHours}, suite.rules(suite.service.CheckAssignment(third)))
+}
+
+func (suite *DutyTestSuite) TestRosterWarnings() {
+	suite.licence(models.LicenceClassE, time.Now().Add(10*24*time.Hour))
+	first := suite.createTrip(suite.outbound, suite.monday)
+	suite.createTrip(suite.inbound, suite.monday.Add(3*time.Hour))
+
+	roster, err := suite.service.Roster(suite.driver.ID, time.Now(), suite.monday.AddDate(0, 0, 1))
+	require.NoError(suite.T(), err)
+
+	assert.False(suite.T(), roster.IsCompliant)
+	require.Len(suite.T(), roster.Trips, 2)
+	assert.Equal(suite.T(), first.ID, roster.Trips[0].Trip.ID)
+	assert.Equal(suite.T(), services.DutyRuleRest, roster.Trips[0].Warnings[0].Rule)
+	assert.Equal(suite.T(), 4.0, roster.DailyHours[suite.monday.Format("2006-01-02")])
+	assert.Equal(suite.T(), services.DutyRuleLicenceExpiring, roster.Warnings[0].Rule)
+}
+
+func TestDutyTestSuite(t *testing.T) {
+	suite.Run(t, new(DutyTestSuite))
+}
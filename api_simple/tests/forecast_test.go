@@ -0,0 +1,188 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type ForecastTestSuite struct {
+	ServiceTestSuite
+	service *services.ForecastService
+	repo    *repository.ForecastRepository
+}
+
+func (suite *ForecastTestSuite) SetupTest() {
+	suite.ServiceTestSuite.SetupTest()
+	suite.repo = repository.NewForecastRepository(suite.db)
+	suite.service = services.NewForecastService(suite.repo, repository.NewHolidayRepository(suite.db), services.DefaultForecastConfig())
+}
+
+// trip creates a trip on route departing at departure with booked of its 40 seats taken
+func (suite *ForecastTestSuite) trip(route models.Route, departure time.Time, booked int) models.Trip {
+	trip := suite.createTrip(route, departure)
+	require.NoError(suite.T(), suite.db.Model(&trip).Updates(map[string]interface{}{"total_seats": 40, "booked_seats": booked}).Error)
+	trip.TotalSeats, trip.BookedSeats = 40, booked
+	return trip
+}
+
+func (suite *ForecastTestSuite) holiday(name, start, end string, uplift float64) {
+	holiday := models.Holiday{Name: name, StartDate: start, EndDate: end, Uplift: uplift}
+	require.NoError(suite.T(), holiday.Validate())
+	require.NoError(suite.T(), suite.db.Create(&holiday).Error)
+}
+
+func (suite *ForecastTestSuite) TestHolidayValidate() {
+	holiday := models.Holiday{Name: "Tết Nguyên Đán", StartDate: "2027-02-03", EndDate: "2027-02-11", Uplift: 2}
+	require.NoError(suite.T(), holiday.Validate())
+	assert.True(suite.T(), holiday.Contains(time.Date(2027, 2, 11, 23, 0, 0, 0, time.Local)))
+	assert.False(suite.T(), holiday.Contains(time.Date(2027, 2, 12, 0, 0, 0, 0, time.Local)))
+
+	holiday.EndDate = "2027-02-01"
+	assert.Error(suite.T(), holiday.Validate())
+	holiday.EndDate = "11/02/2027"
+	assert.Error(suite.T(), holiday.Validate())
+}
+
+func (suite *ForecastTestSuite) TestRefresh() {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.Local)
+
+	// Three months of history: 20 seats sold in the morning and 10 in the evening, and
+	// 80% more over the 30/4 - 1/5 holiday
+	suite.holiday("30/4 - 1/5", "2026-04-30", "2026-05-03", 1.5)
+	for day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.Local); day.Before(now); day = day.AddDate(0, 0, 1) {
+		morning, evening := 20, 10
+		if day.Format(models.TravelDateFormat) >= "2026-04-30" && day.Format(models.TravelDateFormat) <= "2026-05-03" {
+			morning, evening = 36, 18
+		}
+		suite.trip(suite.outbound, day.Add(8*time.Hour), morning)
+		suite.trip(suite.outbound, day.Add(20*time.Hour), evening)
+	}
+
+	ordinary := suite.trip(suite.outbound, now.AddDate(0, 0, 2).Add(8*time.Hour), 5)
+	quiet := suite.trip(suite.outbound, now.AddDate(0, 0, 2).Add(20*time.Hour), 2)
+	busy := suite.trip(suite.outbound, now.AddDate(0, 0, 3).Add(8*time.Hour), 25)
+	suite.holiday("30/4 - 1/5", "2026-06-20", "2026-06-21", 1.5)
+	learned := suite.trip(suite.outbound, now.AddDate(0, 0, 19).Add(8*time.Hour), 0)
+	suite.holiday("Lễ hội biển", "2026-06-10", "2026-06-10", 2)
+	festival := suite.trip(suite.outbound, now.AddDate(0, 0, 9).Add(8*time.Hour), 0)
+	suite.trip(suite.inbound, now.AddDate(0, 0, 2).Add(8*time.Hour), 0) // Tuyến chưa có lịch sử
+	suite.trip(suite.outbound, now.AddDate(0, 0, 45).Add(8*time.Hour), 0)
+
+	count, err := suite.service.Refresh(now)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 5, count)
+
+	forecast := func(trip models.Trip) *models.TripForecast {
+		found, err := suite.repo.FindByTrip(trip.ID)
+		require.NoError(suite.T(), err)
+		return found
+	}
+
+	suite.Run("OrdinaryDay", func() {
+		got := forecast(ordinary)
+		assert.Equal(suite.T(), 20, got.ForecastSeats)
+		assert.Equal(suite.T(), 5, got.BookedSeats)
+		assert.InDelta(suite.T(), 1, got.SeasonalFactor, 1e-9)
+		assert.Equal(suite.T(), 1.0, got.HolidayFactor)
+		assert.Empty(suite.T(), got.Risk)
+
+		got = forecast(quiet)
+		assert.Equal(suite.T(), 10, got.ForecastSeats)
+		assert.Equal(suite.T(), models.ForecastRiskLowDemand, got.Risk)
+	})
+
+	suite.Run("NeverBelowBookings", func() {
+		assert.Equal(suite.T(), 25, forecast(busy).ForecastSeats)
+	})
+
+	suite.Run("HolidayEffect", func() {
+		got := forecast(learned)
+		assert.Equal(suite.T(), "30/4 - 1/5", got.Holiday)
+		assert.InDelta(suite.T(), 1.8, got.HolidayFactor, 1e-9)
+		assert.Equal(suite.T(), 36, got.ForecastSeats)
+		assert.Empty(suite.T(), got.Risk)
+
+		// No history for this holiday yet, so its default uplift applies
+		got = forecast(festival)
+		assert.Equal(suite.T(), 2.0, got.HolidayFactor)
+		assert.Equal(suite.T(), 40, got.ForecastSeats)
+		assert.Equal(suite.T(), models.ForecastRiskSellOut, got.Risk)
+	})
+
+	suite.Run("ListByRisk", func() {
+		forecasts, total, err := suite.repo.List(repository.ForecastFilter{Risk: models.ForecastRiskSellOut}, 1, 20)
+		require.NoError(suite.T(), err)
+		assert.EqualValues(suite.T(), 1, total)
+		require.Len(suite.T(), forecasts, 1)
+		assert.Equal(suite.T(), festival.ID, forecasts[0].TripID)
+	})
+
+	suite.Run("RefreshReplaces", func() {
+		count, err := suite.service.Refresh(now)
+		require.NoError(suite.T(), err)
+		assert.Equal(suite.T(), 5, count)
+
+		_, total, err := suite.repo.List(repository.ForecastFilter{}, 1, 20)
+		require.NoError(suite.T(), err)
+		assert.EqualValues(suite.T(), 5, total)
+	})
+}
+
+func (suite *ForecastTestSuite) TestSeasonality() {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.Local) // Thứ hai
+
+	// Saturdays sell 34 seats, other days 20
+	for day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.Local); day.Before(now); day = day.AddDate(0, 0, 1) {
+		booked := 20
+		if day.Weekday() == time.Saturday {
+			booked = 34
+		}
+		suite.trip(suite.outbound, day.Add(8*time.Hour), booked)
+	}
+	saturday := suite.trip(suite.outbound, now.AddDate(0, 0, 5).Add(8*time.Hour), 0)
+	wednesday := suite.trip(suite.outbound, now.AddDate(0, 0, 2).Add(8*time.Hour), 0)
+
+	_, err := suite.service.Refresh(now)
+	require.NoError(suite.T(), err)
+
+	got, err := suite.repo.FindByTrip(saturday.ID)
+	require.NoError(suite.T(), err)
+	assert.InDelta(suite.T(), 34, got.ForecastSeats, 1)
+	assert.Greater(suite.T(), got.SeasonalFactor, 1.0)
+
+	got, err = suite.repo.FindByTrip(wednesday.ID)
+	require.NoError(suite.T(), err)
+	assert.InDelta(suite.T(), 20, got.ForecastSeats, 1)
+}
+
+func (suite *ForecastTestSuite) TestLiveBookings() {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.Local)
+	suite.trip(suite.outbound, now.AddDate(0, 0, -7).Add(8*time.Hour), 20)
+	trip := suite.trip(suite.outbound, now.AddDate(0, 0, 2).Add(8*time.Hour), 5)
+	_, err := suite.service.Refresh(now)
+	require.NoError(suite.T(), err)
+
+	// Seats sold after the forecast was made show up without refreshing it
+	require.NoError(suite.T(), suite.db.Model(&trip).Update("booked_seats", 12).Error)
+
+	got, err := suite.repo.FindByTrip(trip.ID)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 12, got.BookedSeats)
+
+	forecasts, _, err := suite.repo.List(repository.ForecastFilter{}, 1, 20)
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), forecasts, 1)
+	assert.Equal(suite.T(), 12, forecasts[0].BookedSeats)
+}
+
+func TestForecastTestSuite(t *testing.T) {
+	suite.Run(t, new(ForecastTestSuite))
+}
@@ -0,0 +1,229 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+	"ticket-management/api_simple/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type ImportTestSuite struct {
+	ServiceTestSuite
+	service *services.ImportService
+}
+
+func (suite *ImportTestSuite) SetupTest() {
+	suite.ServiceTestSuite.SetupTest()
+	require.NoError(suite.T(), suite.db.Create(&models.DriverProfile{
+		UserID: suite.driver.ID, LicenceNumber: "790123456789", LicenceClass: models.LicenceClassE,
+		LicenceExpiry: time.Date(2035, 1, 1, 0, 0, 0, 0, time.Local),
+	}).Error)
+
+	scoped := repository.WithOperator(suite.db, suite.own.ID)
+	suite.service = services.NewImportService(
+		repository.NewRouteRepository(scoped),
+		repository.NewBusRepository(scoped),
+		repository.NewBusRepository(suite.db),
+		repository.NewUserRepository(scoped),
+		repository.NewTripRepository(scoped),
+		repository.NewSeatRepository(scoped),
+		newOperatorService(scoped),
+		newScheduleService(scoped),
+		newDutyService(scoped),
+	)
+}
+
+// importCSV imports a CSV file for the sample operator
+func (suite *ImportTestSuite) importCSV(kind services.ImportKind, content string) *services.ImportResult {
+	table, err := services.ParseImportFile([]byte(content))
+	require.NoError(suite.T(), err)
+	result, err := suite.service.Import(suite.own.ID, kind, table)
+	require.NoError(suite.T(), err)
+	return result
+}
+
+// rowErrors maps the failed lines of an import to their errors
+func rowErrors(result *services.ImportResult) map[int]error {
+	errs := make(map[int]error)
+	for _, rowError := range result.Errors {
+		errs[rowError.Line] = rowError.Err
+	}
+	return errs
+}
+
+func (suite *ImportTestSuite) TestParseImportFile() {
+	suite.Run("CSV", func() {
+		table, err := services.ParseImportFile([]byte("\xef\xbb\xbfOrigin, Destination\n\nHà Nội,Huế\n,\nHuế,Đà Nẵng,extra\n"))
+		require.NoError(suite.T(), err)
+		assert.Equal(suite.T(), []string{"origin", "destination"}, table.Columns)
+		require.Len(suite.T(), table.Records, 2)
+		assert.Equal(suite.T(), 3, table.Records[0].Line)
+		assert.Equal(suite.T(), map[string]string{"origin": "Hà Nội", "destination": "Huế"}, table.Records[0].Values)
+		assert.Equal(suite.T(), 5, table.Records[1].Line)
+	})
+
+	suite.Run("XLSX", func() {
+		data, err := utils.RenderXLSX("routes", []string{"origin", "destination", "base_price"},
+			[][]interface{}{{"Hà Nội", "Hải Phòng", int64(150000)}, {"Huế", "Đà Nẵng & Hội An", 1.5}})
+		require.NoError(suite.T(), err)
+
+		table, err := services.ParseImportFile(data)
+		require.NoError(suite.T(), err)
+		require.Len(suite.T(), table.Records, 2)
+		assert.Equal(suite.T(), "150000", table.Records[0].Values["base_price"])
+		assert.Equal(suite.T(), "Đà Nẵng & Hội An", table.Records[1].Values["destination"])
+	})
+
+	suite.Run("Invalid", func() {
+		_, err := services.ParseImportFile([]byte("origin\n"))
+		assert.ErrorIs(suite.T(), err, services.ErrImportEmpty)
+
+		_, err = services.ParseImportFile([]byte("PK\x03\x04broken"))
+		assert.ErrorIs(suite.T(), err, services.ErrInvalidImportFile)
+	})
+}
+
+func (suite *ImportTestSuite) TestRoutesAndBuses() {
+	suite.Run("MissingColumn", func() {
+		table, err := services.ParseImportFile([]byte("origin,destination\nHà Nội,Vinh\n"))
+		require.NoError(suite.T(), err)
+		_, err = suite.service.Import(suite.own.ID, services.ImportRoutes, table)
+		var missing *services.MissingColumnError
+		require.ErrorAs(suite.T(), err, &missing)
+		assert.Equal(suite.T(), "distance", missing.Column)
+	})
+
+	suite.Run("Routes", func() {
+		result := suite.importCSV(services.ImportRoutes, "origin,destination,distance,duration,base_price\n"+
+			"Hà Nội,Hải Phòng,120,2h,150000\n"+ // Already run by the operator
+			"Đà Nẵng,Huế,100,2h,\"120,000\"\n"+ // Only run by the rival operator
+			"Hà Nội,Vinh,abc,5h,250000\n"+
+			"Hà Nội,Hà Nội,10,1h,50000\n")
+
+		assert.Equal(suite.T(), 4, result.Rows)
+		assert.Equal(suite.T(), 1, result.Created)
+		errs := rowErrors(result)
+		require.Len(suite.T(), errs, 3)
+		assert.ErrorIs(suite.T(), errs[2], services.ErrImportRouteExists)
+		assert.ErrorIs(suite.T(), errs[4], services.ErrImportInvalidValue)
+		assert.Equal(suite.T(), "distance", result.Errors[1].Column)
+		assert.Error(suite.T(), errs[5])
+
+		route, err := repository.NewRouteRepository(repository.WithOperator(suite.db, suite.own.ID)).
+			FindOne(map[string]interface{}{"origin": "Đà Nẵng", "destination": "Huế"})
+		require.NoError(suite.T(), err)
+		assert.Equal(suite.T(), models.Money(120000), route.BasePrice)
+	})
+
+	suite.Run("Buses", func() {
+		require.NoError(suite.T(), suite.db.Create(&models.Bus{PlateNumber: "51B-99999", Type: "Giường nằm", SeatCount: 34, FloorCount: 2, IsActive: true, OperatorID: suite.rival.ID}).Error)
+
+		result := suite.importCSV(services.ImportBuses, "plate_number,type,seat_count,floor_count\n"+
+			"29B-55555,Limousine,9,\n"+
+			"51B-99999,Giường nằm,34,2\n"+ // Registered by the rival operator
+			"29B-66666,Ghế ngồi,0,1\n")
+
+		assert.Equal(suite.T(), 1, result.Created)
+		errs := rowErrors(result)
+		assert.ErrorIs(suite.T(), errs[3], services.ErrImportPlateTaken)
+		assert.Error(suite.T(), errs[4])
+
+		buses, err := repository.NewBusRepository(suite.db).FindAll(map[string]interface{}{"plate_number": "29B-55555"})
+		require.NoError(suite.T(), err)
+		require.Len(suite.T(), buses, 1)
+		assert.Equal(suite.T(), suite.own.ID, buses[0].OperatorID)
+		assert.Equal(suite.T(), 1, buses[0].FloorCount)
+	})
+}
+
+func (suite *ImportTestSuite) TestTrips() {
+	// Trips are priced in the currency of their route and seats in that of their trip
+	require.NoError(suite.T(), suite.db.Model(&suite.outbound).Update("currency", "USD").Error)
+
+	result := suite.importCSV(services.ImportTrips, "origin,destination,plate_number,driver_phone,departure_time,price\n"+
+		"Hà Nội,Hải Phòng,29B-12345,0911111111,2027-03-01 07:00,\n"+
+		"Hải Phòng,Hà Nội,29B-12345,0911111111,01/03/2027 07:30,180000\n"+ // Bus still on the first trip
+		"Hà Nội,Hải Phòng,29B-12345,0900000000,2027-03-02 07:00,\n"+
+		"Hà Nội,Đà Nẵng,29B-12345,0911111111,2027-03-03 07:00,\n"+
+		"Hải Phòng,Hà Nội,29B-12345,0911111111,2027-03-01 13:00,180000\n")
+
+	assert.Equal(suite.T(), 2, result.Created)
+	errs := rowErrors(result)
+	require.Len(suite.T(), errs, 3)
+	var conflict *services.ScheduleConflict
+	assert.ErrorAs(suite.T(), errs[3], &conflict)
+	assert.ErrorIs(suite.T(), errs[4], services.ErrImportDriverNotFound)
+	assert.ErrorIs(suite.T(), errs[5], services.ErrImportRouteNotFound)
+
+	require.Len(suite.T(), result.TripIDs, 2)
+	trip, err := repository.NewTripRepository(suite.db).FindByID(result.TripIDs[1])
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), suite.own.ID, trip.OperatorID)
+	assert.Equal(suite.T(), models.Money(180000), trip.Price)
+	assert.Equal(suite.T(), 40, trip.TotalSeats)
+
+	assert.Equal(suite.T(), models.CurrencyVND, trip.Currency)
+
+	var seats int64
+	require.NoError(suite.T(), suite.db.Model(&models.Seat{}).Where("trip_id = ?", result.TripIDs[0]).Count(&seats).Error)
+	assert.EqualValues(suite.T(), 40, seats)
+	require.NoError(suite.T(), suite.db.Model(&models.Seat{}).Where("trip_id = ? AND currency = ?", result.TripIDs[0], "USD").Count(&seats).Error)
+	assert.EqualValues(suite.T(), 40, seats)
+}
+
+func (suite *ImportTestSuite) TestSchedules() {
+	// The return leg comes first: trips are checked in departure order, not row order
+	result := suite.importCSV(services.ImportSchedules, "origin,destination,plate_number,driver_phone,departure,start_date,end_date,days\n"+
+		"Hải Phòng,Hà Nội,29B-12345,0911111111,13:00,2027-03-01,2027-03-14,\"T2,T4\"\n"+
+		"Hà Nội,Hải Phòng,29B-12345,0911111111,07:00,2027-03-01,14/03/2027,T2 T4\n"+
+		"Hải Phòng,Hà Nội,29B-12345,0911111111,07:30,2027-03-01,2027-03-01,\n"+ // Bus still on the Monday trip
+		"Hà Nội,Hải Phòng,29B-12345,0911111111,07:00,2027-03-02,2027-03-02,CN\n"+
+		"Hà Nội,Hải Phòng,29B-12345,0911111111,07:00,2027-03-02,2027-03-02,T9\n")
+
+	assert.Equal(suite.T(), 8, result.Created)
+	require.Len(suite.T(), result.Errors, 3)
+	assert.Equal(suite.T(), 4, result.Errors[0].Line)
+	assert.Equal(suite.T(), "2027-03-01", result.Errors[0].Date)
+	var conflict *services.ScheduleConflict
+	assert.ErrorAs(suite.T(), result.Errors[0].Err, &conflict)
+	errs := rowErrors(result)
+	assert.ErrorIs(suite.T(), errs[5], services.ErrImportNoDeparture)
+	assert.ErrorIs(suite.T(), errs[6], services.ErrImportInvalidValue)
+
+	var trips []models.Trip
+	require.NoError(suite.T(), suite.db.Where("id IN ?", result.TripIDs).Order("departure_time").Find(&trips).Error)
+	require.Len(suite.T(), trips, 8)
+	for i, day := range []int{1, 3, 8, 10} {
+		assert.True(suite.T(), time.Date(2027, 3, day, 7, 0, 0, 0, time.Local).Equal(trips[2*i].DepartureTime), trips[2*i].DepartureTime)
+		assert.Equal(suite.T(), suite.outbound.ID, trips[2*i].RouteID)
+		assert.True(suite.T(), time.Date(2027, 3, day, 13, 0, 0, 0, time.Local).Equal(trips[2*i+1].DepartureTime), trips[2*i+1].DepartureTime)
+		assert.Equal(suite.T(), suite.inbound.ID, trips[2*i+1].RouteID)
+	}
+}
+
+func (suite *ImportTestSuite) TestTooManyTrips() {
+	// 14 daily schedules over a year expand to more trips than a file may create
+	content := "origin,destination,plate_number,driver_phone,departure,start_date,end_date\n" +
+		strings.Repeat("Hà Nội,Hải Phòng,29B-12345,0911111111,07:00,2027-01-01,2027-12-31\n", 14)
+	table, err := services.ParseImportFile([]byte(content))
+	require.NoError(suite.T(), err)
+
+	_, err = suite.service.Import(suite.own.ID, services.ImportSchedules, table)
+	assert.ErrorIs(suite.T(), err, services.ErrImportTooManyTrips)
+
+	var trips int64
+	require.NoError(suite.T(), suite.db.Model(&models.Trip{}).Count(&trips).Error)
+	assert.Zero(suite.T(), trips)
+}
+
+func TestImportTestSuite(t *testing.T) {
+	suite.Run(t, new(ImportTestSuite))
+}
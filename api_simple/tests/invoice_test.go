@@ -0,0 +1,243 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/providers"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+	"ticket-management/api_simple/utils"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// fakeInvoiceProvider records submitted invoices and can be told to fail
+type fakeInvoiceProvider struct {
+	fail       bool
+	references []string
+}
+
+func (p *fakeInvoiceProvider) Submit(reference string, document []byte) (*providers.InvoiceReceipt, error) {
+	if p.fail {
+		return nil, errors.New("service unavailable")
+	}
+	p.references = append(p.references, reference)
+	return &providers.InvoiceReceipt{LookupCode: "TC" + reference[len(reference)-3:], TaxAuthorityCode: "M1-TEST"}, nil
+}
+
+type InvoiceTestSuite struct {
+	ServiceTestSuite
+	service  *services.InvoiceService
+	provider *fakeInvoiceProvider
+	trip     models.Trip
+	now      time.Time
+}
+
+func (suite *InvoiceTestSuite) SetupTest() {
+	suite.ServiceTestSuite.SetupTest()
+	require.NoError(suite.T(), suite.db.Model(&suite.own).Updates(map[string]interface{}{"tax_code": "0312345678", "address": "12 Giải Phóng, Hà Nội"}).Error)
+
+	suite.trip = suite.createTrip(suite.outbound, time.Date(2026, 3, 12, 8, 0, 0, 0, time.Local))
+	suite.provider = &fakeInvoiceProvider{}
+	suite.service = services.NewInvoiceService(
+		repository.NewInvoiceRepository(suite.db),
+		repository.NewOperatorRepository(suite.db),
+		suite.provider,
+		services.DefaultInvoiceConfig(),
+	)
+	suite.now = time.Date(2026, 3, 10, 9, 0, 0, 0, time.Local)
+}
+
+func (suite *InvoiceTestSuite) paidBooking(operatorID uint, total models.Money) *models.Booking {
+	booking := models.Booking{OperatorID: operatorID, GuestInfo: &models.GuestInfo{Name: "Nguyễn Văn A", Phone: "0987654321"}, TripID: suite.trip.ID, SeatIDs: pq.Int64Array{1, 2}, TotalAmount: total, Status: models.BookingStatusConfirmed, PaymentStatus: models.PaymentStatusPaid}
+	require.NoError(suite.T(), suite.db.Create(&booking).Error)
+
+	found, err := repository.NewBookingRepository(suite.db).FindByID(booking.ID)
+	require.NoError(suite.T(), err)
+	return found
+}
+
+var companyBuyer = models.InvoiceBuyer{
+	BuyerName:    "Trần Thị B",
+	BuyerCompany: "Công ty TNHH Du lịch Biển Xanh",
+	BuyerTaxCode: "0109876543",
+	BuyerAddress: "45 Lê Lợi, Hải Phòng",
+	BuyerEmail:   "ketoan@bienxanh.vn",
+}
+
+func (suite *InvoiceTestSuite) TestIssuesWithVATAndSequentialNumbers() {
+	invoice, err := suite.service.Issue(suite.paidBooking(suite.own.ID, 330000), companyBuyer, suite.now)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "C26TAA", invoice.Series)
+	assert.Equal(suite.T(), 1, invoice.Number)
+	assert.Equal(suite.T(), "0312345678", invoice.SellerTaxCode)
+	assert.Equal(suite.T(), models.Money(300000), invoice.Subtotal)
+	assert.Equal(suite.T(), models.Money(30000), invoice.VATAmount)
+	assert.Equal(suite.T(), models.Money(330000), invoice.Total)
+	require.Len(suite.T(), invoice.Lines, 1)
+	assert.Equal(suite.T(), 2, invoice.Lines[0].Quantity)
+	assert.Equal(suite.T(), models.Money(150000), invoice.Lines[0].UnitPrice)
+	assert.Contains(suite.T(), invoice.Lines[0].Description, "Hà Nội - Hải Phòng")
+
+	second, err := suite.service.Issue(suite.paidBooking(suite.own.ID, 150000), models.InvoiceBuyer{BuyerName: "Lê Văn C"}, suite.now)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 2, second.Number)
+
+	// A new year starts a new series
+	next, err := suite.service.Issue(suite.paidBooking(suite.own.ID, 150000), models.InvoiceBuyer{BuyerName: "Lê Văn C"}, suite.now.AddDate(1, 0, 0))
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "C27TAA", next.Series)
+	assert.Equal(suite.T(), 1, next.Number)
+}
+
+func (suite *InvoiceTestSuite) TestIssueRules() {
+	unpaid := suite.paidBooking(suite.own.ID, 150000)
+	unpaid.PaymentStatus = models.PaymentStatusUnpaid
+	_, err := suite.service.Issue(unpaid, companyBuyer, suite.now)
+	assert.ErrorIs(suite.T(), err, services.ErrInvoiceBookingNotPaid)
+
+	_, err = suite.service.Issue(suite.paidBooking(suite.rival.ID, 150000), companyBuyer, suite.now)
+	assert.ErrorIs(suite.T(), err, services.ErrSellerTaxCodeMissing)
+
+	booking := suite.paidBooking(suite.own.ID, 150000)
+	_, err = suite.service.Issue(booking, models.InvoiceBuyer{BuyerCompany: "Công ty A", BuyerTaxCode: "123"}, suite.now)
+	assert.Error(suite.T(), err)
+
+	_, err = suite.service.Issue(booking, companyBuyer, suite.now)
+	require.NoError(suite.T(), err)
+	_, err = suite.service.Issue(booking, companyBuyer, suite.now)
+	assert.ErrorIs(suite.T(), err, services.ErrInvoiceExists)
+}
+
+func (suite *InvoiceTestSuite) TestRefundIssuesAdjustment() {
+	booking := suite.paidBooking(suite.own.ID, 330000)
+	original, err := suite.service.Issue(booking, companyBuyer, suite.now)
+	require.NoError(suite.T(), err)
+
+	adjustment, err := suite.service.AdjustForRefund(booking, 165000, suite.now.Add(time.Hour))
+	require.NoError(suite.T(), err)
+	require.NotNil(suite.T(), adjustment)
+	assert.Equal(suite.T(), models.InvoiceTypeAdjustment, adjustment.Type)
+	assert.Equal(suite.T(), original.ID, *adjustment.OriginalInvoiceID)
+	assert.Equal(suite.T(), 2, adjustment.Number)
+	assert.Equal(suite.T(), models.Money(-150000), adjustment.Subtotal)
+	assert.Equal(suite.T(), models.Money(-15000), adjustment.VATAmount)
+	assert.Equal(suite.T(), models.Money(-165000), adjustment.Total)
+
+	stored, err := repository.NewInvoiceRepository(suite.db).FindByID(original.ID)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.InvoiceStatusAdjusted, stored.Status)
+
+	// Bookings without an invoice are refunded without one
+	none, err := suite.service.AdjustForRefund(suite.paidBooking(suite.own.ID, 150000), 150000, suite.now)
+	require.NoError(suite.T(), err)
+	assert.Nil(suite.T(), none)
+}
+
+func (suite *InvoiceTestSuite) TestReplaceCorrectsBuyer() {
+	original, err := suite.service.Issue(suite.paidBooking(suite.own.ID, 330000), companyBuyer, suite.now)
+	require.NoError(suite.T(), err)
+
+	corrected := companyBuyer
+	corrected.BuyerTaxCode = "0109876544"
+	replacement, err := suite.service.Replace(original.ID, corrected, "Sai mã số thuế người mua", suite.now)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.InvoiceTypeReplacement, replacement.Type)
+	assert.Equal(suite.T(), 2, replacement.Number)
+	assert.Equal(suite.T(), original.Total, replacement.Total)
+	assert.Equal(suite.T(), "0109876544", replacement.BuyerTaxCode)
+	require.Len(suite.T(), replacement.Lines, 1)
+
+	_, err = suite.service.Replace(original.ID, corrected, "Lần nữa", suite.now)
+	assert.ErrorIs(suite.T(), err, services.ErrInvoiceNotReplaceable)
+
+	standing, err := repository.NewInvoiceRepository(suite.db).FindActiveOriginal(original.BookingID)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), replacement.ID, standing.ID)
+}
+
+func (suite *InvoiceTestSuite) TestSubmitStoresCodesAndRetries() {
+	invoice, err := suite.service.Issue(suite.paidBooking(suite.own.ID, 330000), companyBuyer, suite.now)
+	require.NoError(suite.T(), err)
+
+	suite.provider.fail = true
+	assert.Error(suite.T(), suite.service.Submit(invoice, suite.now))
+	stored, err := repository.NewInvoiceRepository(suite.db).FindByID(invoice.ID)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "service unavailable", stored.SubmitError)
+	assert.Nil(suite.T(), stored.SentAt)
+
+	suite.provider.fail = false
+	sent, err := suite.service.SubmitPending(10, suite.now.Add(time.Minute))
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, sent)
+
+	stored, err = repository.NewInvoiceRepository(suite.db).FindByLookupCode("TC001")
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.InvoiceStatusSent, stored.Status)
+	assert.Equal(suite.T(), "M1-TEST", stored.TaxAuthorityCode)
+	assert.Empty(suite.T(), stored.SubmitError)
+	assert.Equal(suite.T(), []string{stored.Reference()}, suite.provider.references)
+}
+
+func (suite *InvoiceTestSuite) TestDocuments() {
+	booking := suite.paidBooking(suite.own.ID, 330000)
+	original, err := suite.service.Issue(booking, companyBuyer, suite.now)
+	require.NoError(suite.T(), err)
+	adjustment, err := suite.service.AdjustForRefund(booking, 165000, suite.now)
+	require.NoError(suite.T(), err)
+
+	suite.Run("XML", func() {
+		document, err := services.BuildInvoiceXML(original)
+		require.NoError(suite.T(), err)
+		assert.Contains(suite.T(), string(document), "<KHHDon>C26TAA</KHHDon>")
+		assert.Contains(suite.T(), string(document), "<MST>0109876543</MST>")
+		assert.Contains(suite.T(), string(document), "<TgTThue>30000</TgTThue>")
+		assert.Contains(suite.T(), string(document), "<TgTTTBChu>Ba trăm ba mươi nghìn đồng</TgTTTBChu>")
+		assert.NotContains(suite.T(), string(document), "TTHDLQuan")
+
+		document, err = services.BuildInvoiceXML(adjustment)
+		require.NoError(suite.T(), err)
+		assert.Contains(suite.T(), string(document), "<TCHDon>2</TCHDon>")
+		assert.Contains(suite.T(), string(document), "<SHDCLQuan>1</SHDCLQuan>")
+		assert.Contains(suite.T(), string(document), "<TgTTTBSo>-165000</TgTTTBSo>")
+	})
+
+	suite.Run("PDF", func() {
+		document := services.RenderInvoicePDF(original)
+		assert.True(suite.T(), bytes.HasPrefix(document, []byte("%PDF-1.4")))
+		assert.True(suite.T(), bytes.HasSuffix(document, []byte("%%EOF\n")))
+		assert.Contains(suite.T(), string(document), "HOA DON GIA TRI GIA TANG")
+		assert.Contains(suite.T(), string(document), "330.000")
+	})
+}
+
+func (suite *InvoiceTestSuite) TestNumberToVietnameseWords() {
+	cases := map[int64]string{
+		0:             "không",
+		15:            "mười lăm",
+		21:            "hai mươi mốt",
+		105:           "một trăm linh năm",
+		1250000:       "một triệu hai trăm năm mươi nghìn",
+		1005000:       "một triệu không trăm linh năm nghìn",
+		2000000500:    "hai tỷ năm trăm",
+		1000000000000: "một nghìn tỷ",
+		-165000:       "âm một trăm sáu mươi lăm nghìn",
+	}
+	for value, expected := range cases {
+		assert.Equal(suite.T(), expected, utils.NumberToVietnameseWords(value), value)
+	}
+
+	assert.Equal(suite.T(), "Hoa don GTGT - Duong so 1", utils.RemoveDiacritics("Hóa đơn GTGT - Đường số 1"))
+}
+
+func TestInvoiceTestSuite(t *testing.T) {
+	suite.Run(t, new(InvoiceTestSuite))
+}
@@ -0,0 +1,262 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type LedgerTestSuite struct {
+	ServiceTestSuite
+	repo    *repository.LedgerRepository
+	service *services.LedgerService
+	trip    models.Trip
+	now     time.Time
+}
+
+func (suite *LedgerTestSuite) SetupTest() {
+	suite.ServiceTestSuite.SetupTest()
+	suite.trip = suite.createTrip(suite.outbound, time.Date(2026, 3, 12, 8, 0, 0, 0, time.Local))
+	suite.repo = repository.NewLedgerRepository(suite.db)
+	suite.service = services.NewLedgerService(suite.repo)
+	suite.now = time.Date(2026, 3, 10, 9, 0, 0, 0, time.Local)
+}
+
+func (suite *LedgerTestSuite) booking(total models.Money, status models.PaymentStatus) *models.Booking {
+	booking := models.Booking{OperatorID: suite.own.ID, BookingCode: "BK-LEDGER", GuestInfo: &models.GuestInfo{Name: "Nguyễn Văn A", Phone: "0987654321"}, TripID: suite.trip.ID, SeatIDs: pq.Int64Array{1, 2}, TotalAmount: total, Status: models.BookingStatusPending, PaymentType: models.PaymentTypeCash, PaymentStatus: status}
+	require.NoError(suite.T(), suite.db.Create(&booking).Error)
+	return &booking
+}
+
+// balance returns the balance of an account on its normal side
+func (suite *LedgerTestSuite) balance(filter repository.LedgerFilter, account models.LedgerAccount) models.Money {
+	balances, err := suite.repo.Balances(filter)
+	require.NoError(suite.T(), err)
+	for _, b := range balances {
+		if b.Account == account {
+			info, _ := account.Info()
+			if info.Kind == models.AccountKindAsset || info.Kind == models.AccountKindExpense {
+				return b.Debit - b.Credit
+			}
+			return b.Credit - b.Debit
+		}
+	}
+	return 0
+}
+
+func (suite *LedgerTestSuite) TestSaleThenPayment() {
+	booking := suite.booking(300000, models.PaymentStatusUnpaid)
+
+	require.NoError(suite.T(), suite.service.RecordSale(booking, suite.now))
+	assert.Equal(suite.T(), models.Money(300000), suite.balance(repository.LedgerFilter{}, models.AccountReceivable))
+	assert.Equal(suite.T(), models.Money(300000), suite.balance(repository.LedgerFilter{}, models.AccountRevenue))
+
+	require.NoError(suite.T(), suite.service.RecordPaymentChange(booking, models.PaymentStatusUnpaid, models.PaymentStatusPaid, suite.now))
+	assert.Equal(suite.T(), models.Money(0), suite.balance(repository.LedgerFilter{}, models.AccountReceivable))
+	assert.Equal(suite.T(), models.Money(300000), suite.balance(repository.LedgerFilter{}, models.AccountCash))
+
+	revenue, err := suite.repo.Revenue(repository.LedgerFilter{})
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.Money(300000), revenue)
+
+	transactions, total, err := suite.repo.FindTransactions(map[string]interface{}{"booking_id": booking.ID}, nil, nil, 1, 20)
+	require.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), 2, total)
+	assert.Equal(suite.T(), models.LedgerKindPayment, transactions[0].Kind)
+	require.Len(suite.T(), transactions[0].Entries, 2)
+	assert.Equal(suite.T(), suite.own.ID, transactions[0].Entries[0].OperatorID)
+}
+
+func (suite *LedgerTestSuite) TestCancellationSplitsRefundAndFee() {
+	booking := suite.booking(300000, models.PaymentStatusPaid)
+	require.NoError(suite.T(), suite.service.RecordSale(booking, suite.now))
+
+	booking.PaymentStatus = models.PaymentStatusRefunded
+	booking.RefundAmount = 150000
+	require.NoError(suite.T(), suite.service.RecordCancellation(booking, suite.now.Add(time.Hour)))
+
+	assert.Equal(suite.T(), models.Money(0), suite.balance(repository.LedgerFilter{}, models.AccountRevenue))
+	assert.Equal(suite.T(), models.Money(150000), suite.balance(repository.LedgerFilter{}, models.AccountCancellationFees))
+	assert.Equal(suite.T(), models.Money(150000), suite.balance(repository.LedgerFilter{}, models.AccountRefundsPayable))
+
+	payout, err := suite.service.PayOutRefund(booking, models.AccountCash, nil, suite.now.Add(2*time.Hour))
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.LedgerKindRefundPayout, payout.Kind)
+	assert.Equal(suite.T(), models.Money(150000), suite.balance(repository.LedgerFilter{}, models.AccountCash))
+	assert.Equal(suite.T(), models.Money(0), suite.balance(repository.LedgerFilter{}, models.AccountRefundsPayable))
+
+	_, err = suite.service.PayOutRefund(booking, models.AccountCash, nil, suite.now)
+	assert.ErrorIs(suite.T(), err, services.ErrNothingToPayOut)
+
+	// Revenue of the day the booking was sold and of the day it was cancelled
+	from, to := suite.now.Add(-time.Minute), suite.now.Add(time.Minute)
+	revenue, err := suite.repo.Revenue(repository.LedgerFilter{From: &from, To: &to})
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.Money(300000), revenue)
+	revenue, err = suite.repo.Revenue(repository.LedgerFilter{})
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.Money(150000), revenue)
+}
+
+func (suite *LedgerTestSuite) TestUnpaidCancellationWritesOffReceivable() {
+	booking := suite.booking(300000, models.PaymentStatusUnpaid)
+	require.NoError(suite.T(), suite.service.RecordSale(booking, suite.now))
+	require.NoError(suite.T(), suite.service.RecordCancellation(booking, suite.now))
+
+	balances, err := suite.repo.BookingBalances(booking.ID)
+	require.NoError(suite.T(), err)
+	for account, balance := range balances {
+		assert.Zero(suite.T(), balance, account)
+	}
+}
+
+func (suite *LedgerTestSuite) TestAgencyCommission() {
+	client := &models.APIClient{Name: "Đại lý Vexere"}
+	client.ID = 7
+
+	booking := suite.booking(300000, models.PaymentStatusUnpaid)
+	booking.APIClientID = &client.ID
+	booking.CommissionAmount = 30000
+	require.NoError(suite.T(), suite.service.RecordSale(booking, suite.now))
+	require.NoError(suite.T(), suite.service.RecordSale(suite.booking(150000, models.PaymentStatusPaid), suite.now))
+	other := suite.booking(200000, models.PaymentStatusUnpaid)
+	other.APIClientID = &client.ID
+	other.CommissionAmount = 20000
+	require.NoError(suite.T(), suite.service.RecordSale(other, suite.now))
+
+	owed, err := suite.repo.CommissionOwed(client.ID)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.Money(50000), owed)
+
+	// Cancelling a booking takes its commission back
+	require.NoError(suite.T(), suite.service.RecordCancellation(other, suite.now))
+	owed, err = suite.repo.CommissionOwed(client.ID)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.Money(30000), owed)
+	assert.Equal(suite.T(), models.Money(30000), suite.balance(repository.LedgerFilter{}, models.AccountAgencyCommission))
+
+	_, err = suite.service.PayOutCommission(suite.own.ID, client, 40000, models.AccountGatewayClearing, nil, suite.now)
+	assert.ErrorIs(suite.T(), err, services.ErrPayoutExceedsBalance)
+
+	payout, err := suite.service.PayOutCommission(suite.own.ID, client, 0, models.AccountGatewayClearing, nil, suite.now)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.Money(30000), payout.Entries[0].Debit)
+	owed, err = suite.repo.CommissionOwed(client.ID)
+	require.NoError(suite.T(), err)
+	assert.Zero(suite.T(), owed)
+
+	_, err = suite.service.PayOutCommission(suite.own.ID, client, 0, models.AccountGatewayClearing, nil, suite.now)
+	assert.ErrorIs(suite.T(), err, services.ErrNothingToPayOut)
+}
+
+func (suite *LedgerTestSuite) TestRefundReversesCommission() {
+	client := &models.APIClient{Name: "Đại lý Vexere"}
+	client.ID = 7
+
+	booking := suite.booking(300000, models.PaymentStatusPaid)
+	booking.APIClientID = &client.ID
+	booking.CommissionAmount = 30000
+	require.NoError(suite.T(), suite.service.RecordSale(booking, suite.now))
+	require.NoError(suite.T(), suite.service.RecordPaymentChange(booking, models.PaymentStatusPaid, models.PaymentStatusRefunded, suite.now))
+
+	assert.Zero(suite.T(), suite.balance(repository.LedgerFilter{}, models.AccountRevenue))
+	assert.Equal(suite.T(), models.Money(300000), suite.balance(repository.LedgerFilter{}, models.AccountRefundsPayable))
+	assert.Zero(suite.T(), suite.balance(repository.LedgerFilter{}, models.AccountAgencyCommission))
+	owed, err := suite.repo.CommissionOwed(client.ID)
+	require.NoError(suite.T(), err)
+	assert.Zero(suite.T(), owed)
+}
+
+func (suite *LedgerTestSuite) TestRejectsPaymentChangesItCannotPost() {
+	booking := suite.booking(300000, models.PaymentStatusUnpaid)
+	require.NoError(suite.T(), suite.service.RecordSale(booking, suite.now))
+
+	for _, change := range [][2]models.PaymentStatus{
+		{models.PaymentStatusRefunded, models.PaymentStatusPaid},
+		{models.PaymentStatusUnpaid, models.PaymentStatusRefunded},
+		{models.PaymentStatusPaid, models.PaymentStatusUnpaid},
+		{models.PaymentStatusPaid, models.PaymentStatusPaid},
+	} {
+		assert.ErrorIs(suite.T(), suite.service.RecordPaymentChange(booking, change[0], change[1], suite.now), services.ErrInvalidPaymentChange, change)
+	}
+	assert.Equal(suite.T(), models.Money(300000), suite.balance(repository.LedgerFilter{}, models.AccountReceivable))
+}
+
+func (suite *LedgerTestSuite) TestRevenueByTripBusAndOperator() {
+	require.NoError(suite.T(), suite.service.RecordSale(suite.booking(300000, models.PaymentStatusPaid), suite.now))
+
+	otherTrip := suite.createTrip(suite.inbound, time.Date(2026, 3, 13, 8, 0, 0, 0, time.Local))
+	booking := suite.booking(150000, models.PaymentStatusPaid)
+	booking.TripID = otherTrip.ID
+	require.NoError(suite.T(), suite.db.Model(booking).Update("trip_id", otherTrip.ID).Error)
+	require.NoError(suite.T(), suite.service.RecordSale(booking, suite.now))
+
+	revenue, err := suite.repo.Revenue(repository.LedgerFilter{TripID: suite.trip.ID})
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.Money(300000), revenue)
+
+	stats, err := repository.NewBusRepository(suite.db).GetBusStatistics(suite.bus.ID)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.Money(450000), stats["total_revenue"])
+
+	revenue, err = repository.NewLedgerRepository(repository.WithOperator(suite.db, suite.rival.ID)).Revenue(repository.LedgerFilter{})
+	require.NoError(suite.T(), err)
+	assert.Zero(suite.T(), revenue)
+}
+
+func (suite *LedgerTestSuite) TestBackfillReplaysExistingBookings() {
+	suite.booking(300000, models.PaymentStatusPaid)
+	cancelled := suite.booking(200000, models.PaymentStatusRefunded)
+	require.NoError(suite.T(), suite.db.Model(cancelled).Updates(map[string]interface{}{"status": models.BookingStatusCancelled, "refund_amount": 200000}).Error)
+
+	posted, err := suite.service.Backfill(1)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 2, posted)
+
+	revenue, err := suite.repo.Revenue(repository.LedgerFilter{})
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.Money(300000), revenue)
+	assert.Equal(suite.T(), models.Money(200000), suite.balance(repository.LedgerFilter{}, models.AccountRefundsPayable))
+
+	posted, err = suite.service.Backfill(1)
+	require.NoError(suite.T(), err)
+	assert.Zero(suite.T(), posted)
+}
+
+func (suite *LedgerTestSuite) TestTransactionValidate() {
+	unbalanced := models.LedgerTransaction{Entries: []models.LedgerEntry{
+		{Account: models.AccountReceivable, Debit: 100},
+		{Account: models.AccountRevenue, Credit: 90},
+	}}
+	assert.Error(suite.T(), unbalanced.Validate())
+
+	unknown := models.LedgerTransaction{Entries: []models.LedgerEntry{
+		{Account: "9999", Debit: 100},
+		{Account: models.AccountRevenue, Credit: 100},
+	}}
+	assert.Error(suite.T(), unknown.Validate())
+
+	bothSides := models.LedgerTransaction{Entries: []models.LedgerEntry{
+		{Account: models.AccountReceivable, Debit: 100, Credit: 100},
+		{Account: models.AccountRevenue, Credit: 0},
+	}}
+	assert.Error(suite.T(), bothSides.Validate())
+
+	balanced := models.LedgerTransaction{Entries: []models.LedgerEntry{
+		{Account: models.AccountReceivable, Debit: 100},
+		{Account: models.AccountRevenue, Credit: 100},
+	}}
+	assert.NoError(suite.T(), balanced.Validate())
+}
+
+func TestLedgerTestSuite(t *testing.T) {
+	suite.Run(t, new(LedgerTestSuite))
+}
@@ -0,0 +1,110 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type LoginGuardTestSuite struct {
+	ServiceTestSuite
+	phone string
+	ip    string
+}
+
+func (suite *LoginGuardTestSuite) SetupTest() {
+	suite.ServiceTestSuite.SetupTest()
+	suite.phone = "0987654321"
+	suite.ip = "10.0.0.1"
+}
+
+func (suite *LoginGuardTestSuite) guard(cfg services.LoginGuardConfig) *services.LoginGuard {
+	return services.NewLoginGuard(repository.NewLoginThrottleRepository(suite.db), cfg)
+}
+
+func (suite *LoginGuardTestSuite) TestBackoffAfterRepeatedFailures() {
+	guard := suite.guard(services.DefaultLoginGuardConfig())
+
+	for i := 0; i < 2; i++ {
+		require.NoError(suite.T(), guard.RecordFailure(suite.phone, suite.ip))
+		_, err := guard.Check(suite.phone, suite.ip)
+		assert.NoError(suite.T(), err)
+	}
+
+	require.NoError(suite.T(), guard.RecordFailure(suite.phone, suite.ip))
+	wait, err := guard.Check(suite.phone, suite.ip)
+	assert.ErrorIs(suite.T(), err, services.ErrLoginBackoff)
+	assert.Greater(suite.T(), wait, time.Duration(0))
+}
+
+func (suite *LoginGuardTestSuite) TestLockoutAndUnlock() {
+	cfg := services.DefaultLoginGuardConfig()
+	cfg.BaseBackoff = 0
+	cfg.MaxAccountFailures = 3
+	guard := suite.guard(cfg)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(suite.T(), guard.RecordFailure(suite.phone, suite.ip))
+	}
+	_, err := guard.Check(suite.phone, "10.0.0.2")
+	assert.ErrorIs(suite.T(), err, services.ErrLoginLocked)
+
+	require.NoError(suite.T(), guard.Unlock(suite.phone))
+	_, err = guard.Check(suite.phone, "10.0.0.2")
+	assert.NoError(suite.T(), err)
+}
+
+func (suite *LoginGuardTestSuite) TestIPLockoutAcrossAccounts() {
+	cfg := services.DefaultLoginGuardConfig()
+	cfg.BaseBackoff = 0
+	cfg.MaxIPFailures = 3
+	guard := suite.guard(cfg)
+
+	require.NoError(suite.T(), guard.RecordFailure("0900000001", suite.ip))
+	require.NoError(suite.T(), guard.RecordFailure("0900000002", suite.ip))
+	require.NoError(suite.T(), guard.RecordFailure("0900000003", suite.ip))
+
+	_, err := guard.Check("0900000004", suite.ip)
+	assert.ErrorIs(suite.T(), err, services.ErrLoginLocked)
+
+	// A successful login does not reset the IP counter
+	require.NoError(suite.T(), guard.RecordSuccess("0900000004"))
+	_, err = guard.Check("0900000004", suite.ip)
+	assert.ErrorIs(suite.T(), err, services.ErrLoginLocked)
+}
+
+func (suite *LoginGuardTestSuite) TestFailuresResetAfterWindow() {
+	guard := suite.guard(services.DefaultLoginGuardConfig())
+	repo := repository.NewLoginThrottleRepository(suite.db)
+
+	for i := 0; i < 2; i++ {
+		require.NoError(suite.T(), guard.RecordFailure(suite.phone, suite.ip))
+	}
+	throttle, err := repo.Find(models.ThrottleScopeAccount, suite.phone)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 2, throttle.Failures)
+
+	// Failures older than the window are forgotten
+	require.NoError(suite.T(), suite.db.Model(&models.LoginThrottle{}).Where("id = ?", throttle.ID).
+		Update("last_failure_at", time.Now().Add(-2*time.Hour)).Error)
+	require.NoError(suite.T(), guard.RecordFailure(suite.phone, suite.ip))
+	throttle, err = repo.Find(models.ThrottleScopeAccount, suite.phone)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, throttle.Failures)
+
+	// The IP counter was not reset
+	throttle, err = repo.Find(models.ThrottleScopeIP, suite.ip)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 3, throttle.Failures)
+}
+
+func TestLoginGuardTestSuite(t *testing.T) {
+	suite.Run(t, new(LoginGuardTestSuite))
+}
@@ -0,0 +1,144 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type MaintenanceTestSuite struct {
+	ServiceTestSuite
+	schedule    *services.ScheduleService
+	maintenance *services.MaintenanceService
+	departure   time.Time
+}
+
+func (suite *MaintenanceTestSuite) SetupTest() {
+	suite.ServiceTestSuite.SetupTest()
+	suite.schedule = newScheduleService(suite.db)
+	suite.maintenance = services.NewMaintenanceService(
+		repository.NewMaintenanceRepository(suite.db),
+		repository.NewBusRepository(suite.db),
+		repository.NewTripRepository(suite.db),
+		services.DefaultMaintenanceConfig(),
+	)
+	suite.departure = time.Now().Add(24 * time.Hour).Truncate(time.Hour)
+}
+
+func (suite *MaintenanceTestSuite) TestBreakdownBlocksAssignmentUntilResolved() {
+	existing := suite.createTrip(suite.outbound, suite.departure)
+
+	window, affected, err := suite.maintenance.ReportBreakdown(&models.MaintenanceRecord{
+		BusID:       suite.bus.ID,
+		Description: "Hỏng hộp số",
+		PerformedAt: time.Now(),
+		OdometerKm:  120000,
+	})
+	require.NoError(suite.T(), err)
+	assert.Nil(suite.T(), window.EndsAt)
+	require.Len(suite.T(), affected, 1)
+	assert.Equal(suite.T(), existing.ID, affected[0].ID)
+
+	candidate := &models.Trip{RouteID: suite.inbound.ID, BusID: suite.bus.ID, DriverID: suite.driver.ID, DepartureTime: suite.departure.Add(48 * time.Hour)}
+	err = suite.schedule.CheckTrip(candidate)
+	var conflict *services.ScheduleConflict
+	require.ErrorAs(suite.T(), err, &conflict)
+	assert.Equal(suite.T(), services.ConflictMaintenance, conflict.Kind)
+	assert.Equal(suite.T(), window.ID, conflict.UnavailabilityID)
+
+	suggestion, err := suite.schedule.Suggest(suite.inbound.ID, candidate.DepartureTime)
+	require.NoError(suite.T(), err)
+	assert.Empty(suite.T(), suggestion.Buses)
+
+	_, err = suite.maintenance.Resolve(suite.bus.ID, window.ID, time.Now().Add(time.Minute))
+	require.NoError(suite.T(), err)
+	assert.NoError(suite.T(), suite.schedule.CheckTrip(candidate))
+
+	_, err = suite.maintenance.Resolve(suite.bus.ID, window.ID, time.Now().Add(time.Hour))
+	assert.ErrorIs(suite.T(), err, services.ErrUnavailabilityEnded)
+}
+
+func (suite *MaintenanceTestSuite) TestPlannedWindowOnlyBlocksOverlappingTrips() {
+	end := suite.departure.Add(6 * time.Hour)
+	_, err := suite.maintenance.Schedule(&models.BusUnavailability{
+		BusID:    suite.bus.ID,
+		StartsAt: suite.departure.Add(4 * time.Hour),
+		EndsAt:   &end,
+		Reason:   "Bảo dưỡng định kỳ",
+	})
+	require.NoError(suite.T(), err)
+
+	// 2h trip plus 1h turnaround ends before the window starts
+	before := &models.Trip{RouteID: suite.outbound.ID, BusID: suite.bus.ID, DriverID: suite.driver.ID, DepartureTime: suite.departure}
+	assert.NoError(suite.T(), suite.schedule.CheckTrip(before))
+
+	during := &models.Trip{RouteID: suite.outbound.ID, BusID: suite.bus.ID, DriverID: suite.driver.ID, DepartureTime: suite.departure.Add(2 * time.Hour)}
+	var conflict *services.ScheduleConflict
+	require.ErrorAs(suite.T(), suite.schedule.CheckTrip(during), &conflict)
+	assert.Equal(suite.T(), services.ConflictMaintenance, conflict.Kind)
+}
+
+func (suite *MaintenanceTestSuite) TestInspectionMustCoverTrip() {
+	expiry := suite.departure.Add(time.Hour)
+	require.NoError(suite.T(), suite.maintenance.Record(&models.MaintenanceRecord{
+		BusID:       suite.bus.ID,
+		Type:        models.MaintenanceTypeInspection,
+		PerformedAt: time.Now().AddDate(0, -6, 0),
+		OdometerKm:  95000,
+		NextDueAt:   &expiry,
+	}))
+
+	bus, err := repository.NewBusRepository(suite.db).FindByID(suite.bus.ID)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 95000, bus.OdometerKm)
+	require.NotNil(suite.T(), bus.InspectionExpiresAt)
+
+	candidate := &models.Trip{RouteID: suite.outbound.ID, BusID: suite.bus.ID, DriverID: suite.driver.ID, DepartureTime: suite.departure}
+	var conflict *services.ScheduleConflict
+	require.ErrorAs(suite.T(), suite.schedule.CheckTrip(candidate), &conflict)
+	assert.Equal(suite.T(), services.ConflictDocuments, conflict.Kind)
+	assert.Equal(suite.T(), "inspection", conflict.Reason)
+}
+
+func (suite *MaintenanceTestSuite) TestAlertsAndReport() {
+	now := time.Now()
+
+	expiry := now.AddDate(0, 0, 10)
+	require.NoError(suite.T(), suite.maintenance.Record(&models.MaintenanceRecord{
+		BusID: suite.bus.ID, Type: models.MaintenanceTypeRegistration, PerformedAt: now.AddDate(-1, 0, 0), NextDueAt: &expiry,
+	}))
+	require.NoError(suite.T(), suite.maintenance.Record(&models.MaintenanceRecord{
+		BusID: suite.bus.ID, Type: models.MaintenanceTypeService, PerformedAt: now.AddDate(0, 0, -5),
+		OdometerKm: 100000, NextDueKm: 100500, Cost: 2500000,
+	}))
+
+	alerts, err := suite.maintenance.Alerts(now)
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), alerts, 2)
+	kinds := []string{alerts[0].Kind, alerts[1].Kind}
+	assert.ElementsMatch(suite.T(), []string{services.FleetAlertRegistration, services.FleetAlertService}, kinds)
+
+	end := now.Add(-time.Hour)
+	_, err = suite.maintenance.Schedule(&models.BusUnavailability{
+		BusID: suite.bus.ID, StartsAt: now.Add(-4 * time.Hour), EndsAt: &end, Reason: "Thay lốp",
+	})
+	require.NoError(suite.T(), err)
+
+	report, err := suite.maintenance.Report(suite.bus.ID, now.AddDate(0, 0, -30), now, now)
+	require.NoError(suite.T(), err)
+	assert.InDelta(suite.T(), 3.0, report.DowntimeHours, 0.01)
+	assert.Equal(suite.T(), models.Money(2500000), report.MaintenanceCost)
+	assert.Equal(suite.T(), int64(1), report.MaintenanceCount)
+	assert.False(suite.T(), report.UnavailableNow)
+}
+
+func TestMaintenanceTestSuite(t *testing.T) {
+	suite.Run(t, new(MaintenanceTestSuite))
+}
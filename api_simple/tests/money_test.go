@@ -0,0 +1,72 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+
+	"ticket-management/api_simple/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type MoneyTestSuite struct {
+	suite.Suite
+}
+
+func (suite *MoneyTestSuite) TestScalePriceRoundsToPriceStep() {
+	assert.Equal(suite.T(), models.Money(264000), models.Money(220000).ScalePrice(1.2))
+	assert.Equal(suite.T(), models.Money(242000), models.Money(220000).ScalePrice(1.1))
+	assert.Equal(suite.T(), models.Money(286000), models.Money(220000).ScalePrice(1.3))
+	assert.Equal(suite.T(), models.Money(138000), models.Money(125000).ScalePrice(1.1))
+}
+
+func (suite *MoneyTestSuite) TestMulAndPercentRoundHalfAwayFromZero() {
+	assert.Equal(suite.T(), models.Money(15001), models.Money(150005).Mul(0.1))
+	assert.Equal(suite.T(), models.Money(-15001), models.Money(-150005).Mul(0.1))
+	assert.Equal(suite.T(), models.Money(87500), models.Money(175000).Percent(50))
+	assert.Equal(suite.T(), models.Money(176000), models.Money(175500).RoundTo(models.PriceStep))
+}
+
+func (suite *MoneyTestSuite) TestJSON() {
+	var body struct {
+		Price models.Money `json:"price"`
+	}
+	require.NoError(suite.T(), json.Unmarshal([]byte(`{"price": 264000.00000001}`), &body))
+	assert.Equal(suite.T(), models.Money(264000), body.Price)
+
+	out, err := json.Marshal(body)
+	require.NoError(suite.T(), err)
+	assert.JSONEq(suite.T(), `{"price": 264000}`, string(out))
+
+	assert.Error(suite.T(), json.Unmarshal([]byte(`{"price": "abc"}`), &body))
+}
+
+func (suite *MoneyTestSuite) TestParseMoney() {
+	for value, want := range map[string]models.Money{
+		"150000":          150000,
+		" 1,250,000 ":     1250000,
+		"264000.00000001": 264000,
+		"-5000":           -5000,
+	} {
+		got, err := models.ParseMoney(value)
+		require.NoError(suite.T(), err, value)
+		assert.Equal(suite.T(), want, got, value)
+	}
+
+	for _, value := range []string{"", "abc", "NaN", "1.250.000"} {
+		_, err := models.ParseMoney(value)
+		assert.Error(suite.T(), err, value)
+	}
+}
+
+func (suite *MoneyTestSuite) TestString() {
+	assert.Equal(suite.T(), "1.250.000", models.Money(1250000).String())
+	assert.Equal(suite.T(), "-5.000", models.Money(-5000).String())
+	assert.Equal(suite.T(), "0", models.Money(0).String())
+}
+
+func TestMoneyTestSuite(t *testing.T) {
+	suite.Run(t, new(MoneyTestSuite))
+}
@@ -0,0 +1,139 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type OperatorTestSuite struct {
+	ServiceTestSuite
+	service   *services.OperatorService
+	departure time.Time
+}
+
+func (suite *OperatorTestSuite) SetupTest() {
+	suite.ServiceTestSuite.SetupTest()
+	suite.service = newOperatorService(suite.db)
+	suite.departure = time.Now().Add(24 * time.Hour).Truncate(time.Hour)
+}
+
+func (suite *OperatorTestSuite) TestScope() {
+	suite.Run("QueriesOnlySeeOwnRows", func() {
+		routes, err := repository.NewRouteRepository(repository.WithOperator(suite.db, suite.own.ID)).FindAll(nil)
+		require.NoError(suite.T(), err)
+		assert.Len(suite.T(), routes, 2)
+
+		_, err = repository.NewRouteRepository(repository.WithOperator(suite.db, suite.own.ID)).FindByID(suite.other.ID)
+		assert.Error(suite.T(), err)
+
+		// The unscoped handle still sees the whole platform
+		routes, err = repository.NewRouteRepository(suite.db).FindAll(nil)
+		require.NoError(suite.T(), err)
+		assert.Len(suite.T(), routes, 3)
+	})
+
+	suite.Run("CreatesAreStamped", func() {
+		route := models.Route{Origin: "Hà Nội", Destination: "Lào Cai", Duration: "5h", BasePrice: 300000, IsActive: true}
+		require.NoError(suite.T(), repository.NewRouteRepository(repository.WithOperator(suite.db, suite.rival.ID)).Create(&route))
+		assert.Equal(suite.T(), suite.rival.ID, route.OperatorID)
+	})
+
+	suite.Run("CannotChangeOtherOperatorsRows", func() {
+		scoped := repository.WithOperator(suite.db, suite.own.ID)
+		require.NoError(suite.T(), scoped.Model(&models.Route{}).Where("id = ?", suite.other.ID).Update("base_price", 1).Error)
+		require.NoError(suite.T(), scoped.Delete(&models.Route{}, suite.other.ID).Error)
+
+		var route models.Route
+		require.NoError(suite.T(), suite.db.First(&route, suite.other.ID).Error)
+		assert.Equal(suite.T(), models.Money(120000), route.BasePrice)
+	})
+
+	suite.Run("CustomersStayVisibleThroughPreloads", func() {
+		customer := models.User{Phone: "0933333333", Name: "Khách hàng", Role: models.RoleCustomer}
+		require.NoError(suite.T(), suite.db.Create(&customer).Error)
+		trip := suite.createTrip(suite.outbound, time.Now().Add(48*time.Hour))
+		require.NoError(suite.T(), suite.db.Model(&trip).Update("operator_id", suite.own.ID).Error)
+		booking := models.Booking{OperatorID: suite.own.ID, UserID: &customer.ID, GuestInfo: &models.GuestInfo{Name: customer.Name, Phone: customer.Phone}, TripID: trip.ID, SeatIDs: pq.Int64Array{1}, TotalAmount: 150000, Status: models.BookingStatusPending}
+		require.NoError(suite.T(), suite.db.Create(&booking).Error)
+
+		found, err := repository.NewBookingRepository(repository.WithOperator(suite.db, suite.own.ID)).FindByID(booking.ID)
+		require.NoError(suite.T(), err)
+		require.NotNil(suite.T(), found.User)
+		assert.Equal(suite.T(), customer.ID, found.User.ID)
+	})
+}
+
+func (suite *OperatorTestSuite) TestRefundPercent() {
+	operator := models.Operator{FullRefundHours: 24, PartialRefundHours: 4, PartialRefundPercent: 50}
+	departure := time.Date(2026, 3, 10, 20, 0, 0, 0, time.Local)
+
+	assert.Equal(suite.T(), 100.0, operator.RefundPercent(departure, departure.Add(-48*time.Hour)))
+	assert.Equal(suite.T(), 100.0, operator.RefundPercent(departure, departure.Add(-24*time.Hour)))
+	assert.Equal(suite.T(), 50.0, operator.RefundPercent(departure, departure.Add(-5*time.Hour)))
+	assert.Equal(suite.T(), 0.0, operator.RefundPercent(departure, departure.Add(-time.Hour)))
+	assert.Equal(suite.T(), 0.0, operator.RefundPercent(departure, departure.Add(time.Hour)))
+}
+
+func (suite *OperatorTestSuite) TestRefundFollowsOperatorPolicy() {
+	trip := suite.createTrip(suite.outbound, suite.departure)
+	booking := models.Booking{OperatorID: suite.own.ID, GuestInfo: &models.GuestInfo{Name: "Nguyễn Văn A", Phone: "0987654321"}, TripID: trip.ID, SeatIDs: pq.Int64Array{1, 2}, TotalAmount: 350000, Status: models.BookingStatusConfirmed, PaymentStatus: models.PaymentStatusPaid}
+	require.NoError(suite.T(), suite.db.Create(&booking).Error)
+
+	amount, err := suite.service.Refund(&booking, suite.departure, suite.departure.Add(-6*time.Hour))
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.Money(175000), amount)
+
+	var stored models.Booking
+	require.NoError(suite.T(), suite.db.First(&stored, booking.ID).Error)
+	assert.Equal(suite.T(), models.PaymentStatusRefunded, stored.PaymentStatus)
+	assert.Equal(suite.T(), models.Money(175000), stored.RefundAmount)
+}
+
+func (suite *OperatorTestSuite) TestUnpaidBookingsAreNotRefunded() {
+	booking := models.Booking{OperatorID: suite.own.ID, TotalAmount: 350000, PaymentStatus: models.PaymentStatusUnpaid}
+	amount, err := suite.service.Refund(&booking, suite.departure, suite.departure.Add(-48*time.Hour))
+	require.NoError(suite.T(), err)
+	assert.Zero(suite.T(), amount)
+	assert.Equal(suite.T(), models.PaymentStatusUnpaid, booking.PaymentStatus)
+}
+
+func (suite *OperatorTestSuite) TestAssignTripRequiresOneOperator() {
+	trip := models.Trip{RouteID: suite.outbound.ID, BusID: suite.bus.ID, DriverID: suite.driver.ID}
+	require.NoError(suite.T(), suite.service.AssignTrip(&trip))
+	assert.Equal(suite.T(), suite.own.ID, trip.OperatorID)
+
+	trip = models.Trip{RouteID: suite.other.ID, BusID: suite.bus.ID, DriverID: suite.driver.ID}
+	assert.ErrorIs(suite.T(), suite.service.AssignTrip(&trip), services.ErrOperatorMismatch)
+}
+
+func (suite *OperatorTestSuite) TestCodesAreUnique() {
+	operator := models.Operator{Code: "sao-viet", Name: "Sao Việt 2", FullRefundHours: 24, PartialRefundHours: 4, PartialRefundPercent: 50}
+	assert.ErrorIs(suite.T(), suite.service.Save(&operator), services.ErrOperatorCodeTaken)
+}
+
+func (suite *OperatorTestSuite) TestNewDefaultAccountReplacesPrevious() {
+	first := models.OperatorPaymentAccount{OperatorID: suite.own.ID, Method: models.PaymentAccountMomo, AccountNumber: "0911111111", IsDefault: true, IsActive: true}
+	second := models.OperatorPaymentAccount{OperatorID: suite.own.ID, Method: models.PaymentAccountVNPay, AccountNumber: "SAOVIET01", IsDefault: true, IsActive: true}
+	require.NoError(suite.T(), suite.service.SaveAccount(&first))
+	require.NoError(suite.T(), suite.service.SaveAccount(&second))
+
+	accounts, err := repository.NewPaymentAccountRepository(suite.db).FindByOperator(suite.own.ID)
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), accounts, 2)
+	assert.Equal(suite.T(), second.ID, accounts[0].ID)
+	assert.True(suite.T(), accounts[0].IsDefault)
+	assert.False(suite.T(), accounts[1].IsDefault)
+}
+
+func TestOperatorTestSuite(t *testing.T) {
+	suite.Run(t, new(OperatorTestSuite))
+}
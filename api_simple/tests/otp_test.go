@@ -0,0 +1,156 @@
+package tests
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// fakeSMSSender records the last message instead of delivering it, or fails with err
+type fakeSMSSender struct {
+	to   string
+	body string
+	err  error
+}
+
+func (f *fakeSMSSender) Send(to string, body string) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.to = to
+	f.body = body
+	return nil
+}
+
+func (f *fakeSMSSender) code() string {
+	return regexp.MustCompile(`\d{6}`).FindString(f.body)
+}
+
+type OTPTestSuite struct {
+	ServiceTestSuite
+	sender *fakeSMSSender
+	phone  string
+}
+
+func (suite *OTPTestSuite) SetupTest() {
+	suite.ServiceTestSuite.SetupTest()
+	suite.sender = &fakeSMSSender{}
+	suite.phone = "0987654321"
+}
+
+func (suite *OTPTestSuite) service(cfg services.OTPConfig) *services.OTPService {
+	return services.NewOTPService(repository.NewOTPRepository(suite.db), suite.sender, cfg)
+}
+
+func (suite *OTPTestSuite) TestSendAndVerify() {
+	svc := suite.service(services.DefaultOTPConfig())
+
+	require.NoError(suite.T(), svc.Send(suite.phone, models.OTPPurposeRegister))
+	assert.Equal(suite.T(), suite.phone, suite.sender.to)
+	assert.Len(suite.T(), suite.sender.code(), 6)
+
+	assert.NoError(suite.T(), svc.Verify(suite.phone, models.OTPPurposeRegister, suite.sender.code()))
+	// A consumed code cannot be reused
+	assert.ErrorIs(suite.T(), svc.Verify(suite.phone, models.OTPPurposeRegister, suite.sender.code()), services.ErrOTPInvalid)
+}
+
+func (suite *OTPTestSuite) TestPurposeScoped() {
+	svc := suite.service(services.DefaultOTPConfig())
+
+	require.NoError(suite.T(), svc.Send(suite.phone, models.OTPPurposeResetPassword))
+	assert.ErrorIs(suite.T(), svc.Verify(suite.phone, models.OTPPurposeLogin, suite.sender.code()), services.ErrOTPInvalid)
+	assert.NoError(suite.T(), svc.Verify(suite.phone, models.OTPPurposeResetPassword, suite.sender.code()))
+}
+
+func (suite *OTPTestSuite) TestResendCooldown() {
+	svc := suite.service(services.DefaultOTPConfig())
+
+	require.NoError(suite.T(), svc.Send(suite.phone, models.OTPPurposeLogin))
+	assert.ErrorIs(suite.T(), svc.Send(suite.phone, models.OTPPurposeLogin), services.ErrOTPCooldown)
+}
+
+func (suite *OTPTestSuite) TestFailedSendIsNotStored() {
+	svc := suite.service(services.DefaultOTPConfig())
+
+	require.NoError(suite.T(), svc.Send(suite.phone, models.OTPPurposeLogin))
+	delivered := suite.sender.code()
+
+	// The failed resend neither replaces the delivered code nor starts a cooldown
+	suite.sender.err = errors.New("gateway unavailable")
+	require.NoError(suite.T(), suite.db.Model(&models.OTPCode{}).Where("phone = ?", suite.phone).
+		Update("sent_at", time.Now().Add(-time.Hour)).Error)
+	assert.Error(suite.T(), svc.Send(suite.phone, models.OTPPurposeLogin))
+	var stored int64
+	require.NoError(suite.T(), suite.db.Model(&models.OTPCode{}).Where("phone = ?", suite.phone).Count(&stored).Error)
+	assert.EqualValues(suite.T(), 1, stored)
+	assert.NoError(suite.T(), svc.Verify(suite.phone, models.OTPPurposeLogin, delivered))
+
+	suite.sender.err = nil
+	assert.NoError(suite.T(), svc.Send(suite.phone, models.OTPPurposeLogin))
+}
+
+func (suite *OTPTestSuite) TestExpired() {
+	cfg := services.DefaultOTPConfig()
+	cfg.TTL = -time.Second
+	svc := suite.service(cfg)
+
+	require.NoError(suite.T(), svc.Send(suite.phone, models.OTPPurposeGuestLookup))
+	assert.ErrorIs(suite.T(), svc.Verify(suite.phone, models.OTPPurposeGuestLookup, suite.sender.code()), services.ErrOTPExpired)
+}
+
+func (suite *OTPTestSuite) TestLockoutAfterMaxAttempts() {
+	cfg := services.DefaultOTPConfig()
+	cfg.MaxAttempts = 3
+	cfg.ResendCooldown = 0
+	svc := suite.service(cfg)
+
+	require.NoError(suite.T(), svc.Send(suite.phone, models.OTPPurposeLogin))
+	code := suite.sender.code()
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+
+	assert.ErrorIs(suite.T(), svc.Verify(suite.phone, models.OTPPurposeLogin, wrong), services.ErrOTPInvalid)
+	assert.ErrorIs(suite.T(), svc.Verify(suite.phone, models.OTPPurposeLogin, wrong), services.ErrOTPInvalid)
+	assert.ErrorIs(suite.T(), svc.Verify(suite.phone, models.OTPPurposeLogin, wrong), services.ErrOTPLocked)
+
+	// Even the correct code and a resend are refused while locked
+	assert.ErrorIs(suite.T(), svc.Verify(suite.phone, models.OTPPurposeLogin, code), services.ErrOTPLocked)
+	assert.ErrorIs(suite.T(), svc.Send(suite.phone, models.OTPPurposeLogin), services.ErrOTPLocked)
+}
+
+func (suite *OTPTestSuite) TestCodeStaysUnusableAfterLockout() {
+	cfg := services.DefaultOTPConfig()
+	cfg.MaxAttempts = 1
+	cfg.ResendCooldown = 0
+	svc := suite.service(cfg)
+
+	require.NoError(suite.T(), svc.Send(suite.phone, models.OTPPurposeLogin))
+	code := suite.sender.code()
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+	assert.ErrorIs(suite.T(), svc.Verify(suite.phone, models.OTPPurposeLogin, wrong), services.ErrOTPLocked)
+
+	// Once the lockout ends the spent code still cannot be guessed; a new one can be sent
+	require.NoError(suite.T(), suite.db.Model(&models.OTPCode{}).Where("phone = ?", suite.phone).
+		Update("locked_until", time.Now().Add(-time.Minute)).Error)
+	assert.ErrorIs(suite.T(), svc.Verify(suite.phone, models.OTPPurposeLogin, code), services.ErrOTPLocked)
+	require.NoError(suite.T(), svc.Send(suite.phone, models.OTPPurposeLogin))
+	assert.NoError(suite.T(), svc.Verify(suite.phone, models.OTPPurposeLogin, suite.sender.code()))
+}
+
+func TestOTPTestSuite(t *testing.T) {
+	suite.Run(t, new(OTPTestSuite))
+}
@@ -0,0 +1,208 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type ReconciliationTestSuite struct {
+	ServiceTestSuite
+	repo    *repository.ReconciliationRepository
+	service *services.ReconciliationService
+	trip    models.Trip
+	day     time.Time
+}
+
+func (suite *ReconciliationTestSuite) SetupTest() {
+	suite.ServiceTestSuite.SetupTest()
+	suite.trip = suite.createTrip(suite.outbound, time.Date(2026, 3, 12, 8, 0, 0, 0, time.Local))
+	db := repository.WithOperator(suite.db, suite.own.ID)
+	suite.repo = repository.NewReconciliationRepository(db)
+	suite.service = services.NewReconciliationService(suite.repo, repository.NewBookingRepository(db))
+	suite.day = time.Date(2026, 3, 10, 0, 0, 0, 0, time.Local)
+}
+
+// booking creates a booking paid through provider under reference, at paidAt
+func (suite *ReconciliationTestSuite) booking(provider models.PaymentAccountMethod, reference string, total models.Money, status models.PaymentStatus, paidAt time.Time) *models.Booking {
+	booking := models.Booking{OperatorID: suite.own.ID, GuestInfo: &models.GuestInfo{Name: "Nguyễn Văn A", Phone: "0987654321"}, TripID: suite.trip.ID, SeatIDs: pq.Int64Array{1}, TotalAmount: total, Status: models.BookingStatusConfirmed, PaymentType: models.PaymentTypeCash, PaymentStatus: status}
+	if reference != "" {
+		booking.PaymentProvider = &provider
+		booking.PaymentReference = &reference
+	}
+	if status == models.PaymentStatusPaid {
+		booking.PaidAt = &paidAt
+	}
+	require.NoError(suite.T(), suite.db.Create(&booking).Error)
+	return &booking
+}
+
+func (suite *ReconciliationTestSuite) entriesByReference(reportID uint) map[string][]models.SettlementEntry {
+	entries, _, err := suite.repo.FindEntries(reportID, nil, false, 1, 100)
+	require.NoError(suite.T(), err)
+	byReference := make(map[string][]models.SettlementEntry)
+	for _, entry := range entries {
+		byReference[entry.Reference] = append(byReference[entry.Reference], entry)
+	}
+	return byReference
+}
+
+const settlementFile = `reference,amount,fee,paid_at
+TXN1,300000,3300,2026-03-10 08:00:00
+TXN2,"180,000",1980,2026-03-10 09:00:00
+TXN3,150000,1650,2026-03-10 10:00:00
+TXN9,100000,1100,2026-03-10 11:00:00
+TXN1,300000,3300,2026-03-10 12:00:00
+`
+
+func (suite *ReconciliationTestSuite) TestImport() {
+	suite.booking(models.PaymentAccountVNPay, "TXN1", 300000, models.PaymentStatusPaid, suite.day.Add(8*time.Hour))
+	suite.booking(models.PaymentAccountVNPay, "TXN2", 200000, models.PaymentStatusPaid, suite.day.Add(9*time.Hour))
+	suite.booking(models.PaymentAccountVNPay, "TXN3", 150000, models.PaymentStatusUnpaid, suite.day)
+	missing := suite.booking(models.PaymentAccountVNPay, "TXN4", 250000, models.PaymentStatusPaid, suite.day.Add(10*time.Hour+30*time.Minute))
+	// Paid outside the period the file covers, so not expected in it
+	suite.booking(models.PaymentAccountVNPay, "TXN5", 250000, models.PaymentStatusPaid, suite.day.Add(20*time.Hour))
+
+	report, err := suite.service.Import(suite.own.ID, models.PaymentAccountVNPay, "vnpay-20260310.csv", []byte(settlementFile), nil)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 5, report.RowCount)
+	assert.Equal(suite.T(), models.Money(1030000), report.SettledAmount)
+	assert.Equal(suite.T(), models.Money(11330), report.FeeAmount)
+	assert.Equal(suite.T(), 1, report.MatchedCount)
+	assert.Equal(suite.T(), 5, report.IssueCount)
+
+	entries := suite.entriesByReference(report.ID)
+	require.Len(suite.T(), entries["TXN1"], 2)
+	assert.Equal(suite.T(), models.ReconciliationMatched, entries["TXN1"][0].Status)
+	assert.Equal(suite.T(), models.ReconciliationDuplicate, entries["TXN1"][1].Status)
+	assert.Equal(suite.T(), models.ReconciliationAmountMismatch, entries["TXN2"][0].Status)
+	assert.Equal(suite.T(), models.Money(200000), entries["TXN2"][0].ExpectedAmount)
+	assert.Equal(suite.T(), models.ReconciliationUnpaidBooking, entries["TXN3"][0].Status)
+	assert.Equal(suite.T(), models.ReconciliationMissingBooking, entries["TXN9"][0].Status)
+	require.Len(suite.T(), entries["TXN4"], 1)
+	assert.Equal(suite.T(), models.ReconciliationMissingSettlement, entries["TXN4"][0].Status)
+	assert.Equal(suite.T(), missing.ID, *entries["TXN4"][0].BookingID)
+	assert.Zero(suite.T(), entries["TXN4"][0].Line)
+	assert.Empty(suite.T(), entries["TXN5"])
+
+	summary, err := suite.repo.SummarizeReport(report.ID)
+	require.NoError(suite.T(), err)
+	assert.Len(suite.T(), summary, 6)
+
+	_, err = suite.service.Import(suite.own.ID, models.PaymentAccountVNPay, "copy.csv", []byte(settlementFile), nil)
+	assert.ErrorIs(suite.T(), err, services.ErrSettlementAlreadyImported)
+
+	// A transaction settled again in a later file is a duplicate; a booking already
+	// flagged as missing is not flagged again
+	later, err := suite.service.Import(suite.own.ID, models.PaymentAccountVNPay, "vnpay-20260311.csv", []byte("reference,amount,paid_at\nTXN1,300000,2026-03-10 10:00:00\n"), nil)
+	require.NoError(suite.T(), err)
+	entries = suite.entriesByReference(later.ID)
+	assert.Len(suite.T(), entries, 1)
+	assert.Equal(suite.T(), models.ReconciliationDuplicate, entries["TXN1"][0].Status)
+
+	_, err = suite.service.Import(suite.own.ID, models.PaymentAccountBank, "bank.csv", []byte("reference,amount\nX,1\n"), nil)
+	assert.ErrorIs(suite.T(), err, services.ErrInvalidSettlementProvider)
+
+	// Other operators do not see the reports
+	reports, total, err := repository.NewReconciliationRepository(repository.WithOperator(suite.db, suite.rival.ID)).FindReports(nil, 1, 20)
+	require.NoError(suite.T(), err)
+	assert.Zero(suite.T(), total)
+	assert.Empty(suite.T(), reports)
+}
+
+func (suite *ReconciliationTestSuite) TestMissingSettlementPerProvider() {
+	suite.booking(models.PaymentAccountVNPay, "TXN1", 300000, models.PaymentStatusPaid, suite.day.Add(8*time.Hour))
+	missing := suite.booking(models.PaymentAccountVNPay, "TXN2", 200000, models.PaymentStatusPaid, suite.day.Add(9*time.Hour))
+	// Paid through MoMo in the same period, so settled in MoMo's file instead
+	suite.booking(models.PaymentAccountMomo, "MM1", 150000, models.PaymentStatusPaid, suite.day.Add(9*time.Hour))
+
+	report, err := suite.service.Import(suite.own.ID, models.PaymentAccountVNPay, "vnpay.csv", []byte("reference,amount,paid_at\nTXN1,300000,2026-03-10 08:00:00\nTXN3,100000,2026-03-10 10:00:00\n"), nil)
+	require.NoError(suite.T(), err)
+	entries := suite.entriesByReference(report.ID)
+	require.Len(suite.T(), entries["TXN2"], 1)
+	assert.Equal(suite.T(), models.ReconciliationMissingSettlement, entries["TXN2"][0].Status)
+	assert.Equal(suite.T(), missing.ID, *entries["TXN2"][0].BookingID)
+	assert.Empty(suite.T(), entries["MM1"])
+	assert.Equal(suite.T(), 2, report.IssueCount)
+}
+
+func (suite *ReconciliationTestSuite) TestResolve() {
+	now := suite.day.Add(30 * time.Hour)
+
+	suite.booking(models.PaymentAccountMomo, "TXN1", 300000, models.PaymentStatusPaid, suite.day.Add(8*time.Hour))
+	suite.booking(models.PaymentAccountMomo, "TXN4", 250000, models.PaymentStatusPaid, suite.day.Add(8*time.Hour+30*time.Minute))
+	unreferenced := suite.booking(models.PaymentAccountMomo, "", 100000, models.PaymentStatusPaid, suite.day.Add(9*time.Hour))
+
+	report, err := suite.service.Import(suite.own.ID, models.PaymentAccountMomo, "momo.csv", []byte("reference,amount,paid_at\nTXN1,300000,2026-03-10 08:00:00\nTXN9,100000,2026-03-10 09:00:00\n"), nil)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 2, report.IssueCount)
+	entries := suite.entriesByReference(report.ID)
+	unknown, missing := entries["TXN9"][0], entries["TXN4"][0]
+
+	suite.Run("AcceptNeedsNote", func() {
+		_, err := suite.service.Resolve(missing.ID, models.ResolutionAccept, 0, "", 1, now)
+		assert.ErrorIs(suite.T(), err, services.ErrResolutionNoteRequired)
+
+		_, err = suite.service.Resolve(missing.ID, models.ResolutionLink, unreferenced.ID, "", 1, now)
+		assert.ErrorIs(suite.T(), err, services.ErrLinkRequiresSettlement)
+
+		entry, err := suite.service.Resolve(missing.ID, models.ResolutionAccept, 0, "Cổng quyết toán vào ngày hôm sau", 1, now)
+		require.NoError(suite.T(), err)
+		assert.False(suite.T(), entry.IsOpen())
+		assert.Equal(suite.T(), models.ResolutionAccept, entry.Resolution)
+
+		_, err = suite.service.Resolve(missing.ID, models.ResolutionAccept, 0, "Lần nữa", 1, now)
+		assert.ErrorIs(suite.T(), err, services.ErrEntryNotOpen)
+	})
+
+	suite.Run("LinkMatchesBooking", func() {
+		entry, err := suite.service.Resolve(unknown.ID, models.ResolutionLink, unreferenced.ID, "Khách nhập sai mã đơn", 1, now)
+		require.NoError(suite.T(), err)
+		assert.Equal(suite.T(), models.ReconciliationMatched, entry.Status)
+		assert.False(suite.T(), entry.IsOpen())
+		assert.Equal(suite.T(), unreferenced.ID, *entry.BookingID)
+
+		var booking models.Booking
+		require.NoError(suite.T(), suite.db.First(&booking, unreferenced.ID).Error)
+		require.NotNil(suite.T(), booking.PaymentReference)
+		assert.Equal(suite.T(), "TXN9", *booking.PaymentReference)
+		require.NotNil(suite.T(), booking.PaymentProvider)
+		assert.Equal(suite.T(), models.PaymentAccountMomo, *booking.PaymentProvider)
+
+		stored, err := suite.repo.FindReportByID(report.ID)
+		require.NoError(suite.T(), err)
+		assert.Equal(suite.T(), 2, stored.MatchedCount)
+		assert.Zero(suite.T(), stored.IssueCount)
+	})
+}
+
+func (suite *ReconciliationTestSuite) TestParseSettlementCSV() {
+	rows, err := services.ParseSettlementCSV([]byte("\xef\xbb\xbfMa_Giao_Dich,So_Tien,Thoi_Gian\nTXN1,\"1,250,000\",10/03/2026 08:15:00\n\nTXN2,99999.5,\n"))
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), rows, 2)
+	assert.Equal(suite.T(), "TXN1", rows[0].Reference)
+	assert.Equal(suite.T(), models.Money(1250000), rows[0].Amount)
+	assert.Equal(suite.T(), time.Date(2026, 3, 10, 8, 15, 0, 0, time.Local), *rows[0].PaidAt)
+	assert.Equal(suite.T(), 4, rows[1].Line)
+	assert.Equal(suite.T(), models.Money(100000), rows[1].Amount)
+	assert.Nil(suite.T(), rows[1].PaidAt)
+
+	_, err = services.ParseSettlementCSV([]byte("reference,total\nTXN1,100\n"))
+	assert.ErrorIs(suite.T(), err, services.ErrInvalidSettlementFile)
+	_, err = services.ParseSettlementCSV([]byte("reference,amount\nTXN1,abc\n"))
+	assert.ErrorIs(suite.T(), err, services.ErrInvalidSettlementFile)
+	_, err = services.ParseSettlementCSV([]byte("reference,amount\n"))
+	assert.ErrorIs(suite.T(), err, services.ErrSettlementEmpty)
+}
+
+func TestReconciliationTestSuite(t *testing.T) {
+	suite.Run(t, new(ReconciliationTestSuite))
+}
@@ -0,0 +1,246 @@
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+	"ticket-management/api_simple/utils"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type ReportTestSuite struct {
+	ServiceTestSuite
+	repo   *repository.ReportRepository
+	ledger *services.LedgerService
+	trip   models.Trip
+	later  models.Trip
+	day    time.Time
+}
+
+func (suite *ReportTestSuite) SetupTest() {
+	suite.ServiceTestSuite.SetupTest()
+	suite.trip = suite.createTrip(suite.outbound, time.Date(2026, 3, 12, 8, 0, 0, 0, time.Local))
+	suite.later = suite.createTrip(suite.inbound, time.Date(2026, 3, 20, 8, 0, 0, 0, time.Local))
+	require.NoError(suite.T(), suite.db.Model(&models.Trip{}).Where("id IN ?", []uint{suite.trip.ID, suite.later.ID}).Update("operator_id", suite.own.ID).Error)
+	suite.seats(suite.trip, 4, 3)
+	suite.seats(suite.later, 4, 1)
+	suite.repo = repository.NewReportRepository(repository.WithOperator(suite.db, suite.own.ID))
+	suite.ledger = services.NewLedgerService(repository.NewLedgerRepository(suite.db))
+	suite.day = time.Date(2026, 3, 2, 0, 0, 0, 0, time.Local) // Thứ hai
+}
+
+// seats creates count seats on a trip, the first booked of them sold
+func (suite *ReportTestSuite) seats(trip models.Trip, count, booked int) {
+	for i := 0; i < count; i++ {
+		status := models.SeatStatusAvailable
+		if i < booked {
+			status = models.SeatStatusBooked
+		}
+		seat := models.Seat{TripID: trip.ID, Number: fmt.Sprintf("A%02d", i+1), Floor: 1, Type: models.SeatTypeSingle, Status: status, Price: 150000}
+		require.NoError(suite.T(), suite.db.Create(&seat).Error)
+	}
+}
+
+// sale creates a booking on trip made at createdAt and posts its sale to the ledger
+func (suite *ReportTestSuite) sale(trip models.Trip, total models.Money, createdAt time.Time, channel func(*models.Booking)) *models.Booking {
+	booking := models.Booking{OperatorID: suite.own.ID, BookingCode: fmt.Sprintf("BK-%d", createdAt.UnixNano()), GuestInfo: &models.GuestInfo{Name: "Nguyễn Văn A", Phone: "0987654321"}, TripID: trip.ID, SeatIDs: pq.Int64Array{1}, TotalAmount: total, Status: models.BookingStatusConfirmed, PaymentType: models.PaymentTypeCash, PaymentStatus: models.PaymentStatusPaid}
+	booking.CreatedAt = createdAt
+	if channel != nil {
+		channel(&booking)
+	}
+	require.NoError(suite.T(), suite.db.Create(&booking).Error)
+	require.NoError(suite.T(), suite.ledger.RecordSale(&booking, createdAt))
+	return &booking
+}
+
+func (suite *ReportTestSuite) TestRevenue() {
+	userID, clientID := uint(7), uint(3)
+	suite.sale(suite.trip, 300000, suite.day.Add(9*time.Hour), func(b *models.Booking) { b.Channel = models.BookingChannelCounter })
+	suite.sale(suite.trip, 200000, suite.day.Add(33*time.Hour), func(b *models.Booking) { b.UserID = &userID })
+	suite.sale(suite.later, 150000, suite.day.Add(8*24*time.Hour), func(b *models.Booking) {
+		b.APIClientID = &clientID
+		b.Channel = models.BookingChannelPartner
+	})
+	// A guest booking online, without an account
+	cancelled := suite.sale(suite.later, 100000, suite.day.Add(8*24*time.Hour+time.Hour), nil)
+	cancelled.Status = models.BookingStatusCancelled
+	cancelled.PaymentStatus = models.PaymentStatusRefunded
+	cancelled.RefundAmount = 60000
+	require.NoError(suite.T(), suite.db.Save(cancelled).Error)
+	require.NoError(suite.T(), suite.ledger.RecordCancellation(cancelled, suite.day.Add(9*24*time.Hour)))
+
+	all := repository.ReportFilter{}
+	suite.Run("ByPeriod", func() {
+		rows, err := suite.repo.RevenueByPeriod(all, repository.ReportDaily)
+		require.NoError(suite.T(), err)
+		require.Len(suite.T(), rows, 4)
+		assert.Equal(suite.T(), "2026-03-02", rows[0].Period)
+		assert.Equal(suite.T(), models.Money(300000), rows[0].Revenue)
+		assert.Equal(suite.T(), "2026-03-11", rows[3].Period)
+		assert.Equal(suite.T(), models.Money(-60000), rows[3].Revenue)
+
+		rows, err = suite.repo.RevenueByPeriod(all, repository.ReportWeekly)
+		require.NoError(suite.T(), err)
+		require.Len(suite.T(), rows, 2)
+		assert.Equal(suite.T(), "2026-03-02", rows[0].Period)
+		assert.Equal(suite.T(), models.Money(500000), rows[0].Revenue)
+		assert.EqualValues(suite.T(), 2, rows[0].Bookings)
+		assert.Equal(suite.T(), "2026-03-09", rows[1].Period)
+		assert.Equal(suite.T(), models.Money(190000), rows[1].Revenue)
+
+		rows, err = suite.repo.RevenueByPeriod(all, repository.ReportMonthly)
+		require.NoError(suite.T(), err)
+		require.Len(suite.T(), rows, 1)
+		assert.Equal(suite.T(), "2026-03-01", rows[0].Period)
+		assert.Equal(suite.T(), models.Money(690000), rows[0].Revenue)
+		assert.EqualValues(suite.T(), 4, rows[0].Bookings)
+	})
+
+	suite.Run("ByRouteBusAndChannel", func() {
+		rows, err := suite.repo.RevenueByRoute(all)
+		require.NoError(suite.T(), err)
+		require.Len(suite.T(), rows, 2)
+		assert.Equal(suite.T(), suite.outbound.ID, rows[0].ID)
+		assert.Equal(suite.T(), "Hà Nội - Hải Phòng", rows[0].Name)
+		assert.Equal(suite.T(), models.Money(500000), rows[0].Revenue)
+		assert.Equal(suite.T(), models.Money(190000), rows[1].Revenue)
+
+		rows, err = suite.repo.RevenueByBus(all)
+		require.NoError(suite.T(), err)
+		require.Len(suite.T(), rows, 1)
+		assert.Equal(suite.T(), "29B-12345", rows[0].Name)
+
+		rows, err = suite.repo.RevenueByChannel(all)
+		require.NoError(suite.T(), err)
+		revenue := make(map[string]models.Money)
+		for _, row := range rows {
+			revenue[row.Name] = row.Revenue
+		}
+		assert.Equal(suite.T(), map[string]models.Money{
+			string(models.BookingChannelCounter): 300000,
+			string(models.BookingChannelOnline):  240000,
+			string(models.BookingChannelPartner): 150000,
+		}, revenue)
+	})
+
+	suite.Run("Filtered", func() {
+		from, to := suite.day, suite.day.Add(24*time.Hour-time.Second)
+		rows, err := suite.repo.RevenueByRoute(repository.ReportFilter{From: &from, To: &to})
+		require.NoError(suite.T(), err)
+		require.Len(suite.T(), rows, 1)
+		assert.Equal(suite.T(), models.Money(300000), rows[0].Revenue)
+
+		rows, err = suite.repo.RevenueByRoute(repository.ReportFilter{RouteID: suite.inbound.ID})
+		require.NoError(suite.T(), err)
+		require.Len(suite.T(), rows, 1)
+		assert.Equal(suite.T(), suite.inbound.ID, rows[0].ID)
+
+		// Other operators see none of it
+		rows, err = repository.NewReportRepository(repository.WithOperator(suite.db, suite.rival.ID)).RevenueByChannel(all)
+		require.NoError(suite.T(), err)
+		assert.Empty(suite.T(), rows)
+	})
+
+	suite.Run("BookingStats", func() {
+		stats, err := suite.repo.BookingStats(all)
+		require.NoError(suite.T(), err)
+		assert.EqualValues(suite.T(), 4, stats.Total)
+		assert.EqualValues(suite.T(), 3, stats.Confirmed)
+		assert.EqualValues(suite.T(), 1, stats.Cancelled)
+		assert.InDelta(suite.T(), 0.25, stats.CancellationRate, 1e-9)
+		// 9 days 23 hours, 8 days 23 hours and 10 days 8 hours before departure
+		assert.InDelta(suite.T(), (239.0+215.0+248.0)/3, stats.AvgLeadHours, 0.01)
+	})
+}
+
+func (suite *ReportTestSuite) TestOccupancy() {
+	rows, total, err := suite.repo.OccupancyByTrip(repository.ReportFilter{}, 1, 1)
+	require.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), 2, total)
+	require.Len(suite.T(), rows, 1)
+	assert.Equal(suite.T(), suite.trip.ID, rows[0].ID)
+	assert.EqualValues(suite.T(), 4, rows[0].Seats)
+	assert.EqualValues(suite.T(), 3, rows[0].Booked)
+	assert.InDelta(suite.T(), 0.75, rows[0].LoadFactor, 1e-9)
+
+	rows, _, err = suite.repo.OccupancyByTrip(repository.ReportFilter{}, 1, 0)
+	require.NoError(suite.T(), err)
+	assert.Len(suite.T(), rows, 2)
+
+	// Trips without seats still count, with no load factor
+	suite.createTrip(suite.outbound, time.Date(2026, 3, 25, 8, 0, 0, 0, time.Local))
+	require.NoError(suite.T(), suite.db.Model(&models.Trip{}).Where("operator_id IS NULL OR operator_id = 0").Update("operator_id", suite.own.ID).Error)
+
+	rows, err = suite.repo.OccupancyByRoute(repository.ReportFilter{})
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), rows, 2)
+	assert.Equal(suite.T(), suite.outbound.ID, rows[0].ID)
+	assert.EqualValues(suite.T(), 2, rows[0].Trips)
+	assert.InDelta(suite.T(), 0.75, rows[0].LoadFactor, 1e-9)
+	assert.InDelta(suite.T(), 0.25, rows[1].LoadFactor, 1e-9)
+
+	from := time.Date(2026, 3, 15, 0, 0, 0, 0, time.Local)
+	rows, err = suite.repo.OccupancyByRoute(repository.ReportFilter{From: &from, RouteID: suite.outbound.ID})
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), rows, 1)
+	assert.EqualValues(suite.T(), 1, rows[0].Trips)
+	assert.Zero(suite.T(), rows[0].LoadFactor)
+}
+
+func (suite *ReportTestSuite) TestRenderXLSX() {
+	data, err := utils.RenderXLSX("revenue-day", []string{"period", "revenue"}, [][]interface{}{{"2026-03-02", int64(300000)}, {"Hà Nội & <Huế>", 1.5}})
+	require.NoError(suite.T(), err)
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(suite.T(), err)
+	parts := make(map[string]string)
+	for _, file := range archive.File {
+		r, err := file.Open()
+		require.NoError(suite.T(), err)
+		content, err := io.ReadAll(r)
+		require.NoError(suite.T(), err)
+		parts[file.Name] = string(content)
+	}
+	require.Contains(suite.T(), parts, "[Content_Types].xml")
+	assert.Contains(suite.T(), parts["xl/workbook.xml"], `name="revenue-day"`)
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	assert.Contains(suite.T(), sheet, `<c r="B2"><v>300000</v></c>`)
+	assert.Contains(suite.T(), sheet, `<c r="B3"><v>1.5</v></c>`)
+	assert.Contains(suite.T(), sheet, "Hà Nội &amp; &lt;Huế&gt;")
+}
+
+func (suite *ReportTestSuite) TestMigrateBookingChannels() {
+	userID, clientID := uint(7), uint(3)
+	counter := suite.sale(suite.trip, 300000, suite.day, nil)
+	online := suite.sale(suite.trip, 200000, suite.day, func(b *models.Booking) { b.UserID = &userID })
+	partner := suite.sale(suite.trip, 150000, suite.day, func(b *models.Booking) { b.APIClientID = &clientID })
+
+	// A database from before the channel was stored
+	require.NoError(suite.T(), suite.db.Migrator().DropColumn(&models.Booking{}, "Channel"))
+	require.NoError(suite.T(), repository.MigrateBookingChannels(suite.db))
+
+	for booking, channel := range map[*models.Booking]models.BookingChannel{
+		counter: models.BookingChannelCounter,
+		online:  models.BookingChannelOnline,
+		partner: models.BookingChannelPartner,
+	} {
+		var migrated models.Booking
+		require.NoError(suite.T(), suite.db.First(&migrated, booking.ID).Error)
+		assert.Equal(suite.T(), channel, migrated.Channel, booking.BookingCode)
+	}
+}
+
+func TestReportTestSuite(t *testing.T) {
+	suite.Run(t, new(ReportTestSuite))
+}
@@ -0,0 +1,207 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type RoutePointTestSuite struct {
+	ServiceTestSuite
+	service *services.RoutePointService
+	office  models.RoutePoint // Văn phòng Mỹ Đình, lúc khởi hành
+	hotel   models.RoutePoint // Khách sạn phố cổ, xe trung chuyển 50.000đ
+	bigC    models.RoutePoint // Điểm trả Big C Hải Phòng
+}
+
+func (suite *RoutePointTestSuite) SetupTest() {
+	suite.ServiceTestSuite.SetupTest()
+	suite.service = services.NewRoutePointService(
+		repository.NewRoutePointRepository(suite.db),
+		repository.NewRouteRepository(suite.db),
+		repository.NewBookingRepository(suite.db),
+	)
+
+	suite.office = models.RoutePoint{RouteID: suite.outbound.ID, Type: models.RoutePointPickup, Name: "Văn phòng Mỹ Đình", IsActive: true}
+	suite.hotel = models.RoutePoint{RouteID: suite.outbound.ID, Type: models.RoutePointPickup, Name: "Khách sạn phố cổ", OffsetMinutes: 20, Shuttle: true, Surcharge: 50000, IsActive: true}
+	suite.bigC = models.RoutePoint{RouteID: suite.outbound.ID, Type: models.RoutePointDropoff, Name: "Big C Hải Phòng", OffsetMinutes: 105, IsActive: true}
+	require.NoError(suite.T(), suite.service.Create(&suite.office))
+	require.NoError(suite.T(), suite.service.Create(&suite.hotel))
+	require.NoError(suite.T(), suite.service.Create(&suite.bigC))
+}
+
+func (suite *RoutePointTestSuite) book(trip models.Trip, name string, seats []string, pickup, dropoff *models.RoutePoint) models.Booking {
+	var seatIDs pq.Int64Array
+	for _, number := range seats {
+		seat := models.Seat{TripID: trip.ID, Number: number, Floor: 1, Type: models.SeatTypeSingle, Status: models.SeatStatusBooked, Price: 150000}
+		require.NoError(suite.T(), suite.db.Create(&seat).Error)
+		seatIDs = append(seatIDs, int64(seat.ID))
+	}
+
+	booking := models.Booking{
+		TripID:      trip.ID,
+		GuestInfo:   &models.GuestInfo{Name: name, Phone: "0987654321"},
+		SeatIDs:     seatIDs,
+		TotalAmount: 150000 * models.Money(len(seats)),
+		Status:      models.BookingStatusPending,
+		PaymentType: models.PaymentTypeCash,
+	}
+	if pickup != nil {
+		booking.PickupPointID = &pickup.ID
+	}
+	if dropoff != nil {
+		booking.DropoffPointID = &dropoff.ID
+	}
+	require.NoError(suite.T(), suite.db.Create(&booking).Error)
+	return booking
+}
+
+func (suite *RoutePointTestSuite) TestValidation() {
+	point := models.RoutePoint{RouteID: suite.outbound.ID, Type: models.RoutePointPickup, Name: "Bến xe Nước Ngầm", Surcharge: 20000}
+	assert.Error(suite.T(), point.Validate(), "surcharge without shuttle")
+
+	lat := 21.0
+	point = models.RoutePoint{RouteID: suite.outbound.ID, Type: models.RoutePointPickup, Name: "Cầu Thanh Trì", Latitude: &lat}
+	assert.Error(suite.T(), point.Validate(), "latitude without longitude")
+
+	// The route takes 2h, so a point cannot be 3h after departure
+	point = models.RoutePoint{RouteID: suite.outbound.ID, Type: models.RoutePointDropoff, Name: "Đồ Sơn", OffsetMinutes: 180}
+	assert.ErrorIs(suite.T(), suite.service.Create(&point), services.ErrPointBeyondRoute)
+
+	points, err := suite.service.List(suite.outbound.ID, models.RoutePointPickup, true)
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), points, 2)
+	assert.Equal(suite.T(), "Văn phòng Mỹ Đình", points[0].Name)
+	assert.Equal(suite.T(), "Khách sạn phố cổ", points[1].Name)
+}
+
+func (suite *RoutePointTestSuite) TestSelectBoardingPoints() {
+	trip := suite.createTrip(suite.outbound, time.Now().Add(24*time.Hour))
+
+	selection, err := suite.service.Select(&trip, &suite.hotel.ID, &suite.bigC.ID, 2)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.Money(100000), selection.Surcharge)
+
+	booking := &models.Booking{TotalAmount: 300000}
+	selection.Apply(booking)
+	assert.Equal(suite.T(), models.Money(400000), booking.TotalAmount)
+	assert.Equal(suite.T(), models.Money(100000), booking.SurchargeAmount)
+	assert.Equal(suite.T(), suite.hotel.ID, *booking.PickupPointID)
+
+	// Passengers may keep the default terminals
+	selection, err = suite.service.Select(&trip, nil, nil, 2)
+	require.NoError(suite.T(), err)
+	assert.Zero(suite.T(), selection.Surcharge)
+
+	_, err = suite.service.Select(&trip, &suite.bigC.ID, nil, 1)
+	assert.ErrorIs(suite.T(), err, services.ErrPointWrongType)
+
+	otherTrip := suite.createTrip(suite.other, time.Now().Add(48*time.Hour))
+	_, err = suite.service.Select(&otherTrip, &suite.office.ID, nil, 1)
+	assert.ErrorIs(suite.T(), err, services.ErrPointNotOnRoute)
+
+	early := models.RoutePoint{RouteID: suite.outbound.ID, Type: models.RoutePointDropoff, Name: "Cầu Thanh Trì", OffsetMinutes: 10, IsActive: true}
+	require.NoError(suite.T(), suite.service.Create(&early))
+	_, err = suite.service.Select(&trip, &suite.hotel.ID, &early.ID, 1)
+	assert.ErrorIs(suite.T(), err, services.ErrPointOrder)
+
+	suite.hotel.IsActive = false
+	require.NoError(suite.T(), suite.service.Update(&suite.hotel))
+	_, err = suite.service.Select(&trip, &suite.hotel.ID, nil, 1)
+	assert.ErrorIs(suite.T(), err, services.ErrPointNotOnRoute)
+}
+
+func (suite *RoutePointTestSuite) TestTripManifest() {
+	departure := time.Date(2024, 3, 15, 7, 0, 0, 0, time.UTC)
+	trip := suite.createTrip(suite.outbound, departure)
+
+	suite.book(trip, "Nguyễn Văn B", []string{"A05", "A06"}, &suite.hotel, &suite.bigC)
+	suite.book(trip, "Trần Thị C", []string{"A01"}, &suite.office, nil)
+	suite.book(trip, "Lê Văn D", []string{"A02"}, nil, &suite.bigC)
+	cancelled := suite.book(trip, "Phạm Văn E", []string{"A03"}, &suite.hotel, nil)
+	require.NoError(suite.T(), repository.NewBookingRepository(suite.db).UpdateStatus(cancelled.ID, models.BookingStatusCancelled))
+
+	loaded, err := repository.NewTripRepository(suite.db).FindByID(trip.ID)
+	require.NoError(suite.T(), err)
+	manifest, err := suite.service.Manifest(loaded)
+	require.NoError(suite.T(), err)
+
+	assert.Equal(suite.T(), 4, manifest.TotalPassengers)
+	assert.Equal(suite.T(), "29B-12345", manifest.PlateNumber)
+	assert.Equal(suite.T(), "Tài xế A", manifest.DriverName)
+
+	// Passengers are listed in pickup order; those without a point board at the origin
+	require.Len(suite.T(), manifest.Passengers, 3)
+	assert.Equal(suite.T(), "Hà Nội", manifest.Passengers[0].PickupName)
+	assert.Equal(suite.T(), []string{"A01"}, manifest.Passengers[1].Seats)
+	assert.Equal(suite.T(), "Khách sạn phố cổ", manifest.Passengers[2].PickupName)
+	assert.Equal(suite.T(), departure.Add(20*time.Minute), manifest.Passengers[2].PickupAt)
+	assert.True(suite.T(), manifest.Passengers[2].PickupShuttle)
+	assert.Equal(suite.T(), []string{"A05", "A06"}, manifest.Passengers[2].Seats)
+
+	require.Len(suite.T(), manifest.Pickups, 3)
+	assert.Equal(suite.T(), "Hà Nội", manifest.Pickups[0].Name)
+	assert.Equal(suite.T(), "Văn phòng Mỹ Đình", manifest.Pickups[1].Name)
+	assert.Equal(suite.T(), 2, manifest.Pickups[2].Passengers)
+
+	require.Len(suite.T(), manifest.Dropoffs, 2)
+	assert.Equal(suite.T(), "Big C Hải Phòng", manifest.Dropoffs[0].Name)
+	assert.Equal(suite.T(), 3, manifest.Dropoffs[0].Passengers)
+	assert.Equal(suite.T(), "Hải Phòng", manifest.Dropoffs[1].Name)
+	assert.Equal(suite.T(), departure.Add(2*time.Hour), manifest.Dropoffs[1].ScheduledAt)
+}
+
+func (suite *RoutePointTestSuite) TestBookingBoardingTimes() {
+	departure := time.Date(2024, 3, 15, 7, 0, 0, 0, time.UTC)
+	trip := suite.createTrip(suite.outbound, departure)
+	created := suite.book(trip, "Nguyễn Văn B", []string{"A05"}, &suite.hotel, &suite.bigC)
+
+	var booking models.Booking
+	require.NoError(suite.T(), suite.db.Preload("PickupPoint").Preload("DropoffPoint").First(&booking, created.ID).Error)
+	booking.SetBoardingTimes(departure)
+	require.NotNil(suite.T(), booking.PickupAt)
+	assert.Equal(suite.T(), departure.Add(20*time.Minute), *booking.PickupAt)
+	assert.Equal(suite.T(), departure.Add(105*time.Minute), *booking.DropoffAt)
+}
+
+func (suite *RoutePointTestSuite) TestDeletedPointStaysOnBookings() {
+	departure := time.Date(2024, 3, 15, 7, 0, 0, 0, time.UTC)
+	trip := suite.createTrip(suite.outbound, departure)
+	created := suite.book(trip, "Nguyễn Văn B", []string{"A05"}, &suite.hotel, &suite.bigC)
+	require.NoError(suite.T(), repository.NewRoutePointRepository(suite.db).Delete(suite.hotel.ID))
+
+	// New bookings can no longer choose the point
+	_, err := suite.service.Select(&trip, &suite.hotel.ID, nil, 1)
+	assert.Error(suite.T(), err)
+
+	// Existing bookings still show where their passengers are picked up
+	bookingRepo := repository.NewBookingRepository(suite.db)
+	booking, err := bookingRepo.FindByID(created.ID)
+	require.NoError(suite.T(), err)
+	require.NotNil(suite.T(), booking.PickupPoint)
+	assert.Equal(suite.T(), "Khách sạn phố cổ", booking.PickupPoint.Name)
+
+	booking, err = bookingRepo.FindByCode(created.BookingCode)
+	require.NoError(suite.T(), err)
+	require.NotNil(suite.T(), booking.PickupPoint)
+
+	loaded, err := repository.NewTripRepository(suite.db).FindByID(trip.ID)
+	require.NoError(suite.T(), err)
+	manifest, err := suite.service.Manifest(loaded)
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), manifest.Passengers, 1)
+	assert.Equal(suite.T(), "Khách sạn phố cổ", manifest.Passengers[0].PickupName)
+	assert.Equal(suite.T(), departure.Add(20*time.Minute), manifest.Passengers[0].PickupAt)
+}
+
+func TestRoutePointTestSuite(t *testing.T) {
+	suite.Run(t, new(RoutePointTestSuite))
+}
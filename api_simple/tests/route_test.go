@@ -232,6 +232,34 @@ func TestRoute(t *testing.T) {
 			assert.Contains(t, response, "error")
 			assert.Equal(t, "Giá vé phải lớn hơn 0", response["error"])
 		})
+
+		t.Run("SameOriginAndDestination", func(t *testing.T) {
+			// Get token with unique phone number
+			adminToken := getAdminToken(t, router, "0987654330")
+
+			body := map[string]interface{}{
+				"origin":      "Hà Nội",
+				"destination": "hà nội",
+				"distance":    10,
+				"duration":    "1h",
+				"base_price":  50000,
+			}
+			jsonBody, _ := json.Marshal(body)
+
+			req := httptest.NewRequest("POST", "/api/v1/admin/routes", bytes.NewBuffer(jsonBody))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+adminToken)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			var response map[string]interface{}
+			err := json.Unmarshal(w.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			assert.Equal(t, "Điểm đi và điểm đến phải khác nhau", response["error"])
+		})
 	})
 
 	t.Run("GetRoutes", func(t *testing.T) {
@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type ScheduleTestSuite struct {
+	ServiceTestSuite
+	service   *services.ScheduleService
+	departure time.Time
+}
+
+func (suite *ScheduleTestSuite) SetupTest() {
+	suite.ServiceTestSuite.SetupTest()
+	suite.service = newScheduleService(suite.db)
+	suite.departure = time.Now().Add(24 * time.Hour).Truncate(time.Hour)
+}
+
+func (suite *ScheduleTestSuite) TestParseRouteDuration() {
+	cases := map[string]time.Duration{
+		"4h30m":         4*time.Hour + 30*time.Minute,
+		"4h30":          4*time.Hour + 30*time.Minute,
+		"45m":           45 * time.Minute,
+		"2 giờ 30 phút": 2*time.Hour + 30*time.Minute,
+	}
+	for value, expected := range cases {
+		duration, err := models.ParseRouteDuration(value)
+		require.NoError(suite.T(), err, value)
+		assert.Equal(suite.T(), expected, duration, value)
+	}
+
+	_, err := models.ParseRouteDuration("khoảng 4 tiếng")
+	assert.Error(suite.T(), err)
+}
+
+func (suite *ScheduleTestSuite) TestOverlapWithinDurationAndBuffer() {
+	existing := suite.createTrip(suite.outbound, suite.departure)
+
+	// Arrives after 2h, plus 1h turnaround, so 2h30m later the bus is still busy
+	candidate := &models.Trip{RouteID: suite.inbound.ID, BusID: suite.bus.ID, DriverID: suite.driver.ID, DepartureTime: suite.departure.Add(150 * time.Minute)}
+	err := suite.service.CheckTrip(candidate)
+
+	var conflict *services.ScheduleConflict
+	require.ErrorAs(suite.T(), err, &conflict)
+	assert.Equal(suite.T(), "bus", conflict.Resource)
+	assert.Equal(suite.T(), services.ConflictOverlap, conflict.Kind)
+	assert.Equal(suite.T(), existing.ID, conflict.TripID)
+
+	candidate.DepartureTime = suite.departure.Add(3 * time.Hour)
+	assert.NoError(suite.T(), suite.service.CheckTrip(candidate))
+}
+
+func (suite *ScheduleTestSuite) TestBusMustStartWhereItArrived() {
+	suite.createTrip(suite.outbound, suite.departure)
+
+	candidate := &models.Trip{RouteID: suite.other.ID, BusID: suite.bus.ID, DriverID: suite.driver.ID, DepartureTime: suite.departure.Add(6 * time.Hour)}
+	err := suite.service.CheckTrip(candidate)
+
+	var conflict *services.ScheduleConflict
+	require.ErrorAs(suite.T(), err, &conflict)
+	assert.Equal(suite.T(), services.ConflictLocation, conflict.Kind)
+	assert.Equal(suite.T(), "Hải Phòng", conflict.Location)
+}
+
+func (suite *ScheduleTestSuite) TestSuggestFreeResources() {
+	suite.createTrip(suite.outbound, suite.departure)
+
+	idle := models.Bus{OperatorID: suite.own.ID, PlateNumber: "51B-54321", SeatCount: 30, FloorCount: 1, IsActive: true}
+	require.NoError(suite.T(), suite.db.Create(&idle).Error)
+
+	// Return leg: both buses are free, the one already in Hải Phòng comes first
+	suggestion, err := suite.service.Suggest(suite.inbound.ID, suite.departure.Add(4*time.Hour))
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), suggestion.Buses, 2)
+	assert.Equal(suite.T(), suite.bus.ID, suggestion.Buses[0].Bus.ID)
+	assert.True(suite.T(), suggestion.Buses[0].AtOrigin)
+	assert.Len(suite.T(), suggestion.Drivers, 1)
+
+	// During the outbound trip only the idle bus is free and no driver is
+	suggestion, err = suite.service.Suggest(suite.inbound.ID, suite.departure.Add(time.Hour))
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), suggestion.Buses, 1)
+	assert.Equal(suite.T(), idle.ID, suggestion.Buses[0].Bus.ID)
+	assert.Empty(suite.T(), suggestion.Drivers)
+}
+
+func TestScheduleTestSuite(t *testing.T) {
+	suite.Run(t, new(ScheduleTestSuite))
+}
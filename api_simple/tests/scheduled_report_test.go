@@ -0,0 +1,261 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/providers"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+	"ticket-management/api_simple/utils"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMail records the emails it is asked to send, reading their attachments
+type fakeMail struct {
+	sent        []providers.Email
+	attachments [][]byte
+	err         error
+}
+
+func (m *fakeMail) Send(email *providers.Email) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, *email)
+	for _, attachment := range email.Attachments {
+		content, err := io.ReadAll(attachment.Content)
+		if err != nil {
+			return err
+		}
+		m.attachments = append(m.attachments, content)
+	}
+	return nil
+}
+
+func (suite *ReportTestSuite) TestParseCron() {
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 3, day, hour, minute, 0, 0, time.Local)
+	}
+
+	suite.Run("Weekly", func() {
+		cron, err := utils.ParseCron("0 8 * * 1")
+		require.NoError(suite.T(), err)
+		assert.Equal(suite.T(), at(9, 8, 0), cron.Next(at(2, 8, 0))) // Thứ hai 02/03 -> thứ hai 09/03
+		assert.Equal(suite.T(), at(2, 8, 0), cron.Next(at(1, 23, 0)))
+	})
+
+	suite.Run("StepsListsAndRanges", func() {
+		cron, err := utils.ParseCron("*/15 9-17 * * *")
+		require.NoError(suite.T(), err)
+		assert.Equal(suite.T(), at(2, 9, 15), cron.Next(at(2, 9, 0)))
+		assert.Equal(suite.T(), at(3, 9, 0), cron.Next(at(2, 17, 45)))
+
+		cron, err = utils.ParseCron("30 6,18 * * *")
+		require.NoError(suite.T(), err)
+		assert.Equal(suite.T(), at(2, 18, 30), cron.Next(at(2, 6, 30)))
+	})
+
+	suite.Run("DayOfMonthOrDayOfWeek", func() {
+		// Ngày 15 hoặc Chủ nhật, như cron chuẩn
+		cron, err := utils.ParseCron("0 0 15 * 7")
+		require.NoError(suite.T(), err)
+		assert.Equal(suite.T(), at(8, 0, 0), cron.Next(at(2, 0, 0)))
+		assert.Equal(suite.T(), at(15, 0, 0), cron.Next(at(8, 0, 0)))
+	})
+
+	suite.Run("Macros", func() {
+		cron, err := utils.ParseCron("@monthly")
+		require.NoError(suite.T(), err)
+		assert.Equal(suite.T(), time.Date(2026, 4, 1, 0, 0, 0, 0, time.Local), cron.Next(at(2, 0, 0)))
+	})
+
+	suite.Run("Invalid", func() {
+		for _, expr := range []string{"", "0 8 * *", "60 * * * *", "0 8 * * 8", "*/0 * * * *", "5-1 * * * *", "@yearly"} {
+			_, err := utils.ParseCron(expr)
+			assert.ErrorIs(suite.T(), err, utils.ErrInvalidCron, expr)
+		}
+	})
+
+	suite.Run("NeverRuns", func() {
+		cron, err := utils.ParseCron("0 0 31 2 *")
+		require.NoError(suite.T(), err)
+		assert.True(suite.T(), cron.Next(at(2, 0, 0)).IsZero())
+	})
+}
+
+func (suite *ReportTestSuite) TestScheduledValidate() {
+	valid := func() *models.ScheduledReport {
+		return &models.ScheduledReport{
+			Name:       "Doanh thu tuần",
+			Report:     models.ReportKindRevenue,
+			Format:     models.ReportFormatXLSX,
+			Filters:    models.ReportFilters{GroupBy: "route"},
+			Period:     models.ReportPeriodWeek,
+			Recipients: pq.StringArray{"ketoan@nhaxe.vn"},
+			Cron:       "0 8 * * 1",
+		}
+	}
+	require.NoError(suite.T(), valid().Validate())
+
+	invalid := map[string]func(*models.ScheduledReport){
+		"report":   func(r *models.ScheduledReport) { r.Report = "customers" },
+		"grouping": func(r *models.ScheduledReport) { r.Filters.GroupBy = "trip" },
+		"bookings": func(r *models.ScheduledReport) {
+			r.Report = models.ReportKindBookings
+			r.Filters = models.ReportFilters{RouteID: 1}
+		},
+		"status":     func(r *models.ScheduledReport) { r.Filters.Status = "lost" },
+		"format":     func(r *models.ScheduledReport) { r.Format = "pdf" },
+		"period":     func(r *models.ScheduledReport) { r.Period = "year" },
+		"recipients": func(r *models.ScheduledReport) { r.Recipients = nil },
+		"email":      func(r *models.ScheduledReport) { r.Recipients = pq.StringArray{"ketoan"} },
+		"cron":       func(r *models.ScheduledReport) { r.Cron = "every monday" },
+	}
+	for name, change := range invalid {
+		report := valid()
+		change(report)
+		assert.Error(suite.T(), report.Validate(), name)
+	}
+}
+
+func (suite *ReportTestSuite) TestScheduledPeriodBefore() {
+	now := time.Date(2026, 3, 11, 8, 0, 0, 0, time.Local) // Thứ tư
+	cases := map[models.ReportPeriod][2]time.Time{
+		models.ReportPeriodDay:   {time.Date(2026, 3, 10, 0, 0, 0, 0, time.Local), time.Date(2026, 3, 11, 0, 0, 0, 0, time.Local)},
+		models.ReportPeriodWeek:  {time.Date(2026, 3, 2, 0, 0, 0, 0, time.Local), time.Date(2026, 3, 9, 0, 0, 0, 0, time.Local)},
+		models.ReportPeriodMonth: {time.Date(2026, 2, 1, 0, 0, 0, 0, time.Local), time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local)},
+	}
+	for period, want := range cases {
+		report := models.ScheduledReport{Period: period}
+		from, to := report.PeriodBefore(now)
+		assert.Equal(suite.T(), want[0], from, period)
+		assert.Equal(suite.T(), want[1].Add(-time.Nanosecond), to, period)
+	}
+}
+
+func (suite *ReportTestSuite) TestExportBookings() {
+	suite.sale(suite.trip, 300000, suite.day.Add(9*time.Hour), nil)
+	suite.sale(suite.trip, 200000, suite.day.Add(33*time.Hour), nil)
+	cancelled := suite.sale(suite.later, 150000, suite.day.Add(8*24*time.Hour), nil)
+	require.NoError(suite.T(), suite.db.Model(cancelled).Update("status", models.BookingStatusCancelled).Error)
+
+	db := repository.WithOperator(suite.db, suite.own.ID)
+	reports := services.NewReportService(repository.NewReportRepository(db), repository.NewBookingRepository(db))
+
+	suite.Run("CSV", func() {
+		var buf bytes.Buffer
+		require.NoError(suite.T(), reports.ExportBookings(&buf, models.ReportFormatCSV, repository.BookingFilter{}))
+
+		rows, err := csv.NewReader(&buf).ReadAll()
+		require.NoError(suite.T(), err)
+		require.Len(suite.T(), rows, 4)
+		assert.Equal(suite.T(), "booking_code", rows[0][1])
+		assert.Equal(suite.T(), "Nguyễn Văn A", rows[1][7])
+		assert.Equal(suite.T(), string(models.BookingStatusCancelled), rows[3][3]) // Cũ nhất trước
+		assert.Equal(suite.T(), "Hải Phòng - Hà Nội", rows[3][10])
+		assert.Equal(suite.T(), "Hà Nội - Hải Phòng", rows[2][10])
+		assert.Equal(suite.T(), "29B-12345", rows[2][12])
+		assert.Equal(suite.T(), "A01", rows[2][13])
+		assert.Equal(suite.T(), "200000", rows[2][14])
+	})
+
+	suite.Run("FilteredXLSX", func() {
+		from, to := suite.day, suite.day.Add(24*time.Hour-time.Nanosecond)
+		var buf bytes.Buffer
+		filter := repository.BookingFilter{Status: models.BookingStatusConfirmed, From: &from, To: &to}
+		require.NoError(suite.T(), reports.ExportBookings(&buf, models.ReportFormatXLSX, filter))
+
+		rows, err := utils.ReadXLSX(buf.Bytes())
+		require.NoError(suite.T(), err)
+		require.Len(suite.T(), rows, 2)
+		assert.Equal(suite.T(), "300000", rows[1][14])
+	})
+
+	suite.Run("OtherOperator", func() {
+		db := repository.WithOperator(suite.db, suite.rival.ID)
+		reports := services.NewReportService(repository.NewReportRepository(db), repository.NewBookingRepository(db))
+		var buf bytes.Buffer
+		require.NoError(suite.T(), reports.ExportBookings(&buf, models.ReportFormatCSV, repository.BookingFilter{}))
+
+		rows, err := csv.NewReader(&buf).ReadAll()
+		require.NoError(suite.T(), err)
+		assert.Len(suite.T(), rows, 1)
+	})
+}
+
+func (suite *ReportTestSuite) TestScheduledDeliver() {
+	now := time.Date(2026, 3, 9, 8, 0, 0, 0, time.Local)
+
+	suite.sale(suite.trip, 300000, suite.day.Add(9*time.Hour), nil)
+	suite.sale(suite.later, 150000, suite.day.Add(8*24*time.Hour), nil) // Tuần này, không nằm trong báo cáo
+
+	db := repository.WithOperator(suite.db, suite.own.ID)
+	reportRepo := repository.NewScheduledReportRepository(db)
+	reports := services.NewReportService(repository.NewReportRepository(db), repository.NewBookingRepository(db))
+	report := &models.ScheduledReport{
+		Name:       "Doanh thu tuần",
+		Report:     models.ReportKindRevenue,
+		Format:     models.ReportFormatXLSX,
+		Filters:    models.ReportFilters{GroupBy: "route"},
+		Period:     models.ReportPeriodWeek,
+		Recipients: pq.StringArray{"ketoan@nhaxe.vn"},
+		Cron:       "0 8 * * 1",
+		IsActive:   true,
+	}
+	require.NoError(suite.T(), report.Validate())
+	require.NoError(suite.T(), services.NewScheduledReportService(reportRepo, nil).Schedule(report, suite.day))
+	require.NoError(suite.T(), reportRepo.Create(report))
+	assert.Equal(suite.T(), suite.day.Add(8*time.Hour), *report.NextRunAt)
+
+	due, err := repository.NewScheduledReportRepository(suite.db).FindDue(now)
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), due, 1)
+	assert.Equal(suite.T(), suite.own.ID, due[0].OperatorID)
+	assert.Equal(suite.T(), models.ReportFilters{GroupBy: "route"}, due[0].Filters)
+
+	suite.Run("Sent", func() {
+		mail := &fakeMail{}
+		require.NoError(suite.T(), services.NewScheduledReportService(reportRepo, mail).Deliver(&due[0], reports, now))
+
+		require.Len(suite.T(), mail.sent, 1)
+		assert.Equal(suite.T(), []string{"ketoan@nhaxe.vn"}, mail.sent[0].To)
+		assert.Equal(suite.T(), "Doanh thu tuần (02/03/2026 - 08/03/2026)", mail.sent[0].Subject)
+		assert.Equal(suite.T(), "revenue-20260302.xlsx", mail.sent[0].Attachments[0].Name)
+
+		rows, err := utils.ReadXLSX(mail.attachments[0])
+		require.NoError(suite.T(), err)
+		require.Len(suite.T(), rows, 2)
+		assert.Equal(suite.T(), []string{"route_id", "route", "revenue", "bookings"}, rows[0])
+		assert.Equal(suite.T(), "300000", rows[1][2])
+
+		saved, err := reportRepo.FindByID(report.ID)
+		require.NoError(suite.T(), err)
+		assert.WithinDuration(suite.T(), now, *saved.LastRunAt, 0)
+		assert.Empty(suite.T(), saved.LastError)
+		assert.WithinDuration(suite.T(), now.AddDate(0, 0, 7), *saved.NextRunAt, 0)
+
+		due, err := reportRepo.FindDue(now)
+		require.NoError(suite.T(), err)
+		assert.Empty(suite.T(), due)
+	})
+
+	suite.Run("FailedIsRetriedNextTime", func() {
+		later := now.AddDate(0, 0, 7)
+		mail := &fakeMail{err: errors.New("smtp: connection refused")}
+		err := services.NewScheduledReportService(reportRepo, mail).Deliver(report, reports, later)
+		assert.Error(suite.T(), err)
+
+		saved, err := reportRepo.FindByID(report.ID)
+		require.NoError(suite.T(), err)
+		assert.Equal(suite.T(), "smtp: connection refused", saved.LastError)
+		assert.WithinDuration(suite.T(), later.AddDate(0, 0, 7), *saved.NextRunAt, 0)
+	})
+}
@@ -0,0 +1,145 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type SeatEventsTestSuite struct {
+	ServiceTestSuite
+}
+
+func (suite *SeatEventsTestSuite) receiveEvent(events <-chan []byte) services.SeatEvent {
+	select {
+	case payload := <-events:
+		var event services.SeatEvent
+		require.NoError(suite.T(), json.Unmarshal(payload, &event))
+		return event
+	case <-time.After(2 * time.Second):
+		suite.T().Fatal("no seat event received")
+		return services.SeatEvent{}
+	}
+}
+
+func (suite *SeatEventsTestSuite) TestMemoryBroker() {
+	broker := services.NewMemoryBroker(1)
+	first, unsubscribeFirst := broker.Subscribe("trips:1:seats")
+	second, unsubscribeSecond := broker.Subscribe("trips:1:seats")
+	other, unsubscribeOther := broker.Subscribe("trips:2:seats")
+	defer unsubscribeSecond()
+	defer unsubscribeOther()
+
+	require.NoError(suite.T(), broker.Publish("trips:1:seats", []byte("a")))
+	assert.Equal(suite.T(), []byte("a"), <-first)
+	assert.Equal(suite.T(), []byte("a"), <-second)
+	assert.Empty(suite.T(), other)
+
+	// A subscriber that does not keep up loses messages instead of blocking publishers
+	require.NoError(suite.T(), broker.Publish("trips:1:seats", []byte("b")))
+	require.NoError(suite.T(), broker.Publish("trips:1:seats", []byte("c")))
+	assert.Equal(suite.T(), []byte("b"), <-second)
+
+	unsubscribeFirst()
+	unsubscribeFirst()
+	assert.Equal(suite.T(), []byte("b"), <-first)
+	_, open := <-first
+	assert.False(suite.T(), open)
+}
+
+func (suite *SeatEventsTestSuite) TestSeatNotifier() {
+	broker := services.NewMemoryBroker(4)
+	events, unsubscribe := broker.Subscribe(services.SeatTopic(7))
+	defer unsubscribe()
+	notifier := services.NewSeatNotifier(broker)
+
+	lockedUntil := time.Now().Add(15 * time.Minute)
+	require.NoError(suite.T(), notifier.NotifyIDs(7, services.SeatEventLocked, models.SeatStatusLocked, []int64{3, 4}, &lockedUntil))
+	event := suite.receiveEvent(events)
+	assert.Equal(suite.T(), uint(7), event.TripID)
+	assert.Equal(suite.T(), services.SeatEventLocked, event.Reason)
+	require.Len(suite.T(), event.Seats, 2)
+	assert.Equal(suite.T(), models.SeatStatusLocked, event.Seats[1].Status)
+	assert.NotNil(suite.T(), event.Seats[1].LockedUntil)
+
+	seats := []models.Seat{
+		{TripID: 7, Number: "A01", Status: models.SeatStatusAvailable},
+		{TripID: 8, Number: "A01", Status: models.SeatStatusAvailable},
+	}
+	require.NoError(suite.T(), notifier.NotifySeats(services.SeatEventLockExpired, seats))
+	event = suite.receiveEvent(events)
+	require.Len(suite.T(), event.Seats, 1)
+	assert.Equal(suite.T(), "A01", event.Seats[0].Number)
+	assert.Empty(suite.T(), events)
+}
+
+func (suite *SeatEventsTestSuite) TestRedisBrokerAcrossInstances() {
+	server := miniredis.RunT(suite.T())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Two API instances sharing one Redis
+	newInstance := func() *services.RedisBroker {
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		suite.T().Cleanup(func() { client.Close() })
+		broker := services.NewRedisBroker(client, "ticket-events:", 8)
+		ready := make(chan struct{})
+		go broker.Run(ctx, ready)
+		<-ready
+		return broker
+	}
+	publisher := newInstance()
+	receiver := newInstance()
+
+	events, unsubscribe := receiver.Subscribe(services.SeatTopic(3))
+	defer unsubscribe()
+
+	require.NoError(suite.T(), services.NewSeatNotifier(publisher).NotifyIDs(3, services.SeatEventBooked, models.SeatStatusBooked, []int64{11}, nil))
+	event := suite.receiveEvent(events)
+	assert.Equal(suite.T(), services.SeatEventBooked, event.Reason)
+	assert.Equal(suite.T(), uint(11), event.Seats[0].ID)
+}
+
+func (suite *SeatEventsTestSuite) TestUnlockExpiredSeats() {
+	trip := suite.createTrip(suite.outbound, time.Now().Add(24*time.Hour))
+
+	expired := time.Now().Add(-time.Minute)
+	active := time.Now().Add(10 * time.Minute)
+	seats := []models.Seat{
+		{TripID: trip.ID, Number: "A01", Floor: 1, Type: models.SeatTypeSingle, Status: models.SeatStatusLocked, LockedUntil: &expired},
+		{TripID: trip.ID, Number: "A02", Floor: 1, Type: models.SeatTypeSingle, Status: models.SeatStatusLocked, LockedUntil: &active},
+		{TripID: trip.ID, Number: "A03", Floor: 1, Type: models.SeatTypeSingle, Status: models.SeatStatusBooked},
+	}
+	require.NoError(suite.T(), suite.db.Create(&seats).Error)
+
+	seatRepo := repository.NewSeatRepository(suite.db)
+	unlocked, err := seatRepo.UnlockExpiredSeats()
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), unlocked, 1)
+	assert.Equal(suite.T(), "A01", unlocked[0].Number)
+	assert.Equal(suite.T(), models.SeatStatusAvailable, unlocked[0].Status)
+
+	stored, err := seatRepo.FindByID(seats[0].ID)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.SeatStatusAvailable, stored.Status)
+	assert.Nil(suite.T(), stored.LockedUntil)
+
+	unlocked, err = seatRepo.UnlockExpiredSeats()
+	require.NoError(suite.T(), err)
+	assert.Empty(suite.T(), unlocked)
+}
+
+func TestSeatEventsTestSuite(t *testing.T) {
+	suite.Run(t, new(SeatEventsTestSuite))
+}
@@ -0,0 +1,145 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type ShipmentTestSuite struct {
+	ServiceTestSuite
+	service *services.ShipmentService
+}
+
+func (suite *ShipmentTestSuite) SetupTest() {
+	suite.ServiceTestSuite.SetupTest()
+	suite.service = services.NewShipmentService(
+		repository.NewShipmentRepository(suite.db),
+		repository.NewTripRepository(suite.db),
+		services.DefaultShipmentConfig(),
+	)
+}
+
+func newParcel(tripID uint, weightKg float64) *models.Shipment {
+	return &models.Shipment{
+		TripID:        tripID,
+		SenderName:    "Nguyễn Văn A",
+		SenderPhone:   "0987654321",
+		ReceiverName:  "Trần Thị B",
+		ReceiverPhone: "0912345678",
+		Description:   "Thùng hoa quả",
+		WeightKg:      weightKg,
+		Payer:         models.ShipmentPayerReceiver,
+	}
+}
+
+func (suite *ShipmentTestSuite) TestQuote() {
+	// 2.2 kg is charged as 2.5 kg: 20.000 + 2,5 × 5.000
+	quote := suite.service.Quote(2.2, 0, 0, 0)
+	assert.Equal(suite.T(), 2.5, quote.ChargeableKg)
+	assert.Equal(suite.T(), models.Money(33000), quote.Price)
+
+	// A light but bulky box (60×40×50 cm = 20 kg volumetric) is charged by size
+	quote = suite.service.Quote(3, 60, 40, 50)
+	assert.Equal(suite.T(), 20.0, quote.ChargeableKg)
+	assert.Equal(suite.T(), models.Money(120000), quote.Price)
+}
+
+func (suite *ShipmentTestSuite) TestCapacity() {
+	now := time.Now()
+	require.NoError(suite.T(), suite.db.Model(&suite.bus).Updates(map[string]interface{}{"cargo_capacity_kg": 50, "cargo_capacity_m3": 0.5}).Error)
+	trip := suite.createTrip(suite.outbound, now.Add(24*time.Hour))
+
+	first := newParcel(trip.ID, 30)
+	require.NoError(suite.T(), suite.service.Create(first, &suite.driver.ID, now))
+	assert.NotEmpty(suite.T(), first.TrackingCode)
+	assert.Equal(suite.T(), models.ShipmentStatusReceived, first.Status)
+	assert.Equal(suite.T(), models.PaymentStatusUnpaid, first.PaymentStatus)
+
+	assert.ErrorIs(suite.T(), suite.service.Create(newParcel(trip.ID, 25), nil, now), services.ErrCargoCapacityExceeded)
+
+	bulky := newParcel(trip.ID, 5)
+	bulky.LengthCm, bulky.WidthCm, bulky.HeightCm = 100, 100, 60
+	assert.ErrorIs(suite.T(), suite.service.Create(bulky, nil, now), services.ErrCargoCapacityExceeded)
+
+	// Cancelling frees the space again
+	staff := &models.User{Role: models.RoleStaff}
+	staff.ID = 99
+	_, err := suite.service.UpdateStatus(first.ID, models.ShipmentStatusCancelled, staff, "Khách đổi ý", now)
+	require.NoError(suite.T(), err)
+	require.NoError(suite.T(), suite.service.Create(newParcel(trip.ID, 45), nil, now))
+
+	load, err := suite.service.Load(trip.ID)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 50.0, load.CapacityKg)
+	assert.Equal(suite.T(), 45.0, load.WeightKg)
+	assert.Len(suite.T(), load.Shipments, 1)
+
+	departed := suite.createTrip(suite.inbound, now.Add(-time.Hour))
+	assert.ErrorIs(suite.T(), suite.service.Create(newParcel(departed.ID, 1), nil, now), services.ErrShipmentNotAccepted)
+}
+
+func (suite *ShipmentTestSuite) TestStatusFlow() {
+	now := time.Now()
+	trip := suite.createTrip(suite.outbound, now.Add(2*time.Hour))
+
+	parcel := newParcel(trip.ID, 4)
+	require.NoError(suite.T(), suite.service.Create(parcel, nil, now))
+
+	driver := &suite.driver
+	otherDriver := &models.User{Role: models.RoleDriver}
+	otherDriver.ID = driver.ID + 100
+
+	_, err := suite.service.UpdateStatus(parcel.ID, models.ShipmentStatusLoaded, otherDriver, "", now)
+	assert.ErrorIs(suite.T(), err, services.ErrTripNotAssigned)
+	_, err = suite.service.UpdateStatus(parcel.ID, models.ShipmentStatusCancelled, driver, "", now)
+	assert.ErrorIs(suite.T(), err, services.ErrInvalidShipmentStatus)
+	_, err = suite.service.UpdateStatus(parcel.ID, models.ShipmentStatusArrived, driver, "", now)
+	assert.ErrorIs(suite.T(), err, services.ErrInvalidShipmentStatus)
+
+	steps := []models.ShipmentStatus{models.ShipmentStatusLoaded, models.ShipmentStatusInTransit, models.ShipmentStatusArrived, models.ShipmentStatusDelivered}
+	var updated *models.Shipment
+	for i, status := range steps {
+		updated, err = suite.service.UpdateStatus(parcel.ID, status, driver, "", now.Add(time.Duration(i+1)*time.Hour))
+		require.NoError(suite.T(), err, status)
+	}
+	// The receiver paid on delivery
+	assert.Equal(suite.T(), models.PaymentStatusPaid, updated.PaymentStatus)
+
+	_, err = suite.service.UpdateStatus(parcel.ID, models.ShipmentStatusCancelled, &models.User{Role: models.RoleStaff}, "", now)
+	assert.ErrorIs(suite.T(), err, services.ErrInvalidShipmentStatus)
+
+	tracking, err := suite.service.Lookup(parcel.TrackingCode)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.ShipmentStatusDelivered, tracking.Status)
+	assert.Equal(suite.T(), "Hà Nội", tracking.Origin)
+	assert.Equal(suite.T(), "0912***678", tracking.ReceiverPhone)
+	require.Len(suite.T(), tracking.Events, 5)
+	assert.Equal(suite.T(), models.ShipmentStatusReceived, tracking.Events[0].Status)
+	assert.Equal(suite.T(), models.ShipmentStatusDelivered, tracking.Events[4].Status)
+}
+
+func (suite *ShipmentTestSuite) TestRefundOnCancel() {
+	now := time.Now()
+	trip := suite.createTrip(suite.outbound, now.Add(2*time.Hour))
+
+	parcel := newParcel(trip.ID, 1)
+	parcel.Payer = models.ShipmentPayerSender
+	require.NoError(suite.T(), suite.service.Create(parcel, nil, now))
+	assert.Equal(suite.T(), models.PaymentStatusPaid, parcel.PaymentStatus)
+
+	cancelled, err := suite.service.UpdateStatus(parcel.ID, models.ShipmentStatusCancelled, &models.User{Role: models.RoleStaff}, "", now)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.PaymentStatusRefunded, cancelled.PaymentStatus)
+}
+
+func TestShipmentTestSuite(t *testing.T) {
+	suite.Run(t, new(ShipmentTestSuite))
+}
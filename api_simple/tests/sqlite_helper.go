@@ -0,0 +1,144 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// SetupSQLiteDB opens an in-memory sqlite database with the full schema and the operator scope,
+// for service tests that do not need Postgres
+func SetupSQLiteDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&models.Operator{},
+		&models.OperatorPaymentAccount{},
+		&models.User{},
+		&models.Route{},
+		&models.Bus{},
+		&models.Trip{},
+		&models.Seat{},
+		&models.Booking{},
+		&models.OTPCode{},
+		&models.LoginThrottle{},
+		&models.APIClient{},
+		&models.AuditLog{},
+		&models.DriverProfile{},
+		&models.MaintenanceRecord{},
+		&models.BusUnavailability{},
+		&models.VehiclePosition{},
+		&models.RoutePoint{},
+		&models.Shipment{},
+		&models.ShipmentEvent{},
+		&models.CashShift{},
+		&models.CashTransaction{},
+		&models.InvoiceSeries{},
+		&models.Invoice{},
+		&models.InvoiceLine{},
+		&models.LedgerTransaction{},
+		&models.LedgerEntry{},
+		&models.SettlementReport{},
+		&models.SettlementEntry{},
+		&models.TripSearch{},
+		&models.Holiday{},
+		&models.TripForecast{},
+		&models.ScheduledReport{},
+		&models.SupportTicket{},
+		&models.SupportMessage{},
+		&models.SupportAttachment{},
+	))
+	require.NoError(t, repository.RegisterOperatorScope(db))
+	return db
+}
+
+// ServiceTestSuite gives every test a fresh sqlite database seeded with two operators,
+// their routes, a bus and a driver
+type ServiceTestSuite struct {
+	suite.Suite
+	db       *gorm.DB
+	own      models.Operator // Nhà xe chủ quản của tuyến, xe và tài xế mẫu
+	rival    models.Operator
+	outbound models.Route // Hà Nội -> Hải Phòng, 2h
+	inbound  models.Route // Hải Phòng -> Hà Nội, 2h
+	other    models.Route // Đà Nẵng -> Huế, 2h30m, của nhà xe đối thủ
+	bus      models.Bus
+	driver   models.User
+}
+
+func (suite *ServiceTestSuite) SetupTest() {
+	suite.db = SetupSQLiteDB(suite.T())
+
+	suite.own = models.Operator{Code: "sao-viet", Name: "Sao Việt", IsActive: true, FullRefundHours: 24, PartialRefundHours: 4, PartialRefundPercent: 50}
+	suite.rival = models.Operator{Code: "hoang-long", Name: "Hoàng Long", IsActive: true, FullRefundHours: 48, PartialRefundHours: 12, PartialRefundPercent: 30}
+	require.NoError(suite.T(), suite.db.Create(&suite.own).Error)
+	require.NoError(suite.T(), suite.db.Create(&suite.rival).Error)
+
+	suite.outbound = models.Route{OperatorID: suite.own.ID, Origin: "Hà Nội", Destination: "Hải Phòng", Duration: "2h", BasePrice: 150000, IsActive: true}
+	suite.inbound = models.Route{OperatorID: suite.own.ID, Origin: "Hải Phòng", Destination: "Hà Nội", Duration: "2h", BasePrice: 150000, IsActive: true}
+	suite.other = models.Route{OperatorID: suite.rival.ID, Origin: "Đà Nẵng", Destination: "Huế", Duration: "2 giờ 30 phút", BasePrice: 120000, IsActive: true}
+	suite.bus = models.Bus{OperatorID: suite.own.ID, PlateNumber: "29B-12345", SeatCount: 40, FloorCount: 2, IsActive: true}
+	suite.driver = models.User{OperatorID: &suite.own.ID, Phone: "0911111111", Name: "Tài xế A", Role: models.RoleDriver}
+	require.NoError(suite.T(), suite.db.Create(&suite.outbound).Error)
+	require.NoError(suite.T(), suite.db.Create(&suite.inbound).Error)
+	require.NoError(suite.T(), suite.db.Create(&suite.other).Error)
+	require.NoError(suite.T(), suite.db.Create(&suite.bus).Error)
+	require.NoError(suite.T(), suite.db.Create(&suite.driver).Error)
+}
+
+func (suite *ServiceTestSuite) TearDownTest() {
+	if sqlDB, err := suite.db.DB(); err == nil {
+		sqlDB.Close()
+	}
+}
+
+// createTrip creates an active trip on route with the sample bus and driver
+func (suite *ServiceTestSuite) createTrip(route models.Route, departure time.Time) models.Trip {
+	trip := models.Trip{RouteID: route.ID, BusID: suite.bus.ID, DriverID: suite.driver.ID, DepartureTime: departure, Price: 150000, IsActive: true}
+	require.NoError(suite.T(), suite.db.Create(&trip).Error)
+	return trip
+}
+
+// newScheduleService creates a schedule service on db with the default settings
+func newScheduleService(db *gorm.DB) *services.ScheduleService {
+	return services.NewScheduleService(
+		repository.NewTripRepository(db),
+		repository.NewRouteRepository(db),
+		repository.NewBusRepository(db),
+		repository.NewUserRepository(db),
+		repository.NewMaintenanceRepository(db),
+		services.DefaultScheduleConfig(),
+	)
+}
+
+// newDutyService creates a duty service on db with the default settings
+func newDutyService(db *gorm.DB) *services.DutyService {
+	return services.NewDutyService(
+		repository.NewTripRepository(db),
+		repository.NewUserRepository(db),
+		repository.NewDriverProfileRepository(db),
+		repository.NewBusRepository(db),
+		repository.NewRouteRepository(db),
+		services.DefaultDutyConfig(),
+	)
+}
+
+// newOperatorService creates an operator service on db
+func newOperatorService(db *gorm.DB) *services.OperatorService {
+	return services.NewOperatorService(
+		repository.NewOperatorRepository(db),
+		repository.NewPaymentAccountRepository(db),
+		repository.NewRouteRepository(db),
+		repository.NewBusRepository(db),
+		repository.NewUserRepository(db),
+		repository.NewBookingRepository(db),
+	)
+}
@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type StatsTestSuite struct {
+	ServiceTestSuite
+}
+
+func (suite *StatsTestSuite) TestTracker() {
+	tracker := services.NewStatsTracker()
+	tracker.TripChanged(5, 2)
+	tracker.TripChanged(5)
+
+	assert.Equal(suite.T(), []uint{2, 5}, tracker.Drain())
+	assert.Empty(suite.T(), tracker.Drain())
+}
+
+func (suite *StatsTestSuite) TestRefresh() {
+	now := time.Date(2026, 3, 10, 9, 0, 0, 0, time.Local)
+
+	service := services.NewStatsService(
+		repository.NewTripRepository(suite.db),
+		repository.NewRouteRepository(suite.db),
+		repository.NewBusRepository(suite.db),
+	)
+
+	departed := suite.createTrip(suite.outbound, now.Add(-48*time.Hour))
+	upcoming := suite.createTrip(suite.outbound, now.Add(48*time.Hour))
+	require.NoError(suite.T(), suite.db.Model(&upcoming).Update("price", 200000).Error)
+	for i, trip := range []models.Trip{departed, upcoming} {
+		for n := 0; n < 4; n++ {
+			status := models.SeatStatusAvailable
+			if n <= i {
+				status = models.SeatStatusBooked
+			}
+			seat := models.Seat{TripID: trip.ID, Number: fmt.Sprintf("A%02d", n+1), Floor: 1, Type: models.SeatTypeSingle, Status: status}
+			require.NoError(suite.T(), suite.db.Create(&seat).Error)
+		}
+	}
+	for i, status := range []models.BookingStatus{models.BookingStatusConfirmed, models.BookingStatusPending, models.BookingStatusCancelled} {
+		booking := models.Booking{OperatorID: suite.own.ID, BookingCode: fmt.Sprintf("BK-STATS-%d", i), GuestInfo: &models.GuestInfo{Name: "Nguyễn Văn A", Phone: "0987654321"}, TripID: upcoming.ID, SeatIDs: pq.Int64Array{1}, TotalAmount: 200000, Status: status}
+		require.NoError(suite.T(), suite.db.Create(&booking).Error)
+	}
+
+	require.NoError(suite.T(), service.RefreshTrips([]uint{departed.ID, upcoming.ID}, now))
+
+	var trip models.Trip
+	require.NoError(suite.T(), suite.db.First(&trip, upcoming.ID).Error)
+	assert.Equal(suite.T(), 4, trip.TotalSeats)
+	assert.Equal(suite.T(), 2, trip.BookedSeats)
+
+	var route models.Route
+	require.NoError(suite.T(), suite.db.First(&route, suite.outbound.ID).Error)
+	assert.EqualValues(suite.T(), 2, route.TotalTrips)
+	assert.EqualValues(suite.T(), 1, route.UpcomingTrips)
+	assert.Equal(suite.T(), models.Money(150000), route.MinPrice)
+	assert.Equal(suite.T(), models.Money(200000), route.MaxPrice)
+	assert.EqualValues(suite.T(), 2, route.TotalBookings)
+	assert.EqualValues(suite.T(), 8, route.TotalSeats)
+	assert.EqualValues(suite.T(), 3, route.BookedSeats)
+	assert.InDelta(suite.T(), 0.375, route.LoadFactor, 1e-9)
+	require.NotNil(suite.T(), route.StatsUpdatedAt)
+
+	var bus models.Bus
+	require.NoError(suite.T(), suite.db.First(&bus, suite.bus.ID).Error)
+	assert.EqualValues(suite.T(), 2, bus.TotalTrips)
+	assert.EqualValues(suite.T(), 1, bus.UpcomingTrips)
+	assert.EqualValues(suite.T(), 2, bus.TotalBookings)
+
+	popular, err := repository.NewRouteRepository(suite.db).FindPopularRoutes(10)
+	require.NoError(suite.T(), err)
+	require.NotEmpty(suite.T(), popular)
+	assert.Equal(suite.T(), suite.outbound.ID, popular[0].ID)
+
+	// A deleted trip still refreshes the route it belonged to
+	require.NoError(suite.T(), suite.db.Delete(&models.Trip{}, upcoming.ID).Error)
+	require.NoError(suite.T(), service.RefreshTrips([]uint{upcoming.ID}, now))
+	require.NoError(suite.T(), suite.db.First(&route, suite.outbound.ID).Error)
+	assert.EqualValues(suite.T(), 1, route.TotalTrips)
+	assert.Zero(suite.T(), route.UpcomingTrips)
+	assert.Zero(suite.T(), route.TotalBookings)
+	assert.Equal(suite.T(), models.Money(150000), route.MaxPrice)
+
+	// The periodic refresh covers routes without trips and follows the clock
+	require.NoError(suite.T(), service.RefreshAll(now.Add(-72*time.Hour)))
+	require.NoError(suite.T(), suite.db.First(&route, suite.outbound.ID).Error)
+	assert.EqualValues(suite.T(), 1, route.UpcomingTrips)
+	var empty models.Route
+	require.NoError(suite.T(), suite.db.First(&empty, suite.inbound.ID).Error)
+	assert.Zero(suite.T(), empty.TotalTrips)
+	require.NotNil(suite.T(), empty.StatsUpdatedAt)
+}
+
+func TestStatsTestSuite(t *testing.T) {
+	suite.Run(t, new(StatsTestSuite))
+}
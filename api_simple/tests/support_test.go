@@ -0,0 +1,364 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/providers"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+// pngHeader is enough of a PNG file for its type to be detected
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00")
+
+type SupportTestSuite struct {
+	ServiceTestSuite
+	support *services.SupportService
+	store   *providers.DirAttachmentStore
+	staff   models.User // Nhân viên của nhà xe chủ quản
+	booking models.Booking
+	now     time.Time
+}
+
+func (suite *SupportTestSuite) SetupTest() {
+	suite.ServiceTestSuite.SetupTest()
+	suite.store = providers.NewDirAttachmentStore(suite.T().TempDir())
+
+	suite.staff = models.User{Phone: "0922222222", Name: "Nhân viên CSKH", Role: models.RoleStaff, OperatorID: &suite.own.ID}
+	require.NoError(suite.T(), suite.db.Create(&suite.staff).Error)
+
+	trip := suite.createTrip(suite.outbound, time.Date(2026, 3, 2, 8, 0, 0, 0, time.Local))
+	suite.booking = models.Booking{OperatorID: suite.own.ID, BookingCode: "BK-SUPPORT", GuestInfo: &models.GuestInfo{Name: "Nguyễn Văn A", Phone: "0987654321"}, TripID: trip.ID, SeatIDs: pq.Int64Array{1}, TotalAmount: 150000, Status: models.BookingStatusConfirmed, PaymentType: models.PaymentTypeCash, PaymentStatus: models.PaymentStatusPaid}
+	require.NoError(suite.T(), suite.db.Create(&suite.booking).Error)
+
+	suite.support = suite.supportService(suite.db)
+	suite.now = time.Date(2026, 3, 2, 10, 0, 0, 0, time.Local)
+}
+
+// supportService creates a support service on db with the default settings
+func (suite *SupportTestSuite) supportService(db *gorm.DB) *services.SupportService {
+	return services.NewSupportService(
+		repository.NewSupportRepository(db),
+		repository.NewBookingRepository(db),
+		repository.NewOperatorRepository(db),
+		repository.NewUserRepository(db),
+		suite.store,
+		services.DefaultSupportConfig(),
+	)
+}
+
+// open opens a complaint about the fixture booking as its guest customer
+func (suite *SupportTestSuite) open(now time.Time) *models.SupportTicket {
+	ticket := &models.SupportTicket{Category: models.SupportCategoryComplaint, Subject: "Xe xuất bến trễ", ContactName: "Nguyễn Văn A", ContactPhone: "0987654321"}
+	_, err := suite.support.Open(ticket, suite.booking.BookingCode, "Xe xuất bến trễ 1 tiếng", nil, now)
+	require.NoError(suite.T(), err)
+	return ticket
+}
+
+func (suite *SupportTestSuite) TestOpenLinkedToBooking() {
+	ticket := &models.SupportTicket{Category: models.SupportCategoryRefund, Subject: "Hoàn tiền vé", ContactName: "Nguyễn Văn A", ContactPhone: "0987654321"}
+	message, err := suite.support.Open(ticket, suite.booking.BookingCode, "  Tôi muốn hoàn vé  ", nil, suite.now)
+	require.NoError(suite.T(), err)
+
+	assert.True(suite.T(), strings.HasPrefix(ticket.Code, "HT-"))
+	assert.Equal(suite.T(), suite.own.ID, ticket.OperatorID)
+	assert.Equal(suite.T(), suite.booking.ID, *ticket.BookingID)
+	assert.Equal(suite.T(), models.SupportStatusOpen, ticket.Status)
+	assert.Equal(suite.T(), suite.now.Add(4*time.Hour), ticket.FirstResponseDueAt)
+	assert.Equal(suite.T(), suite.now.Add(72*time.Hour), ticket.ResolutionDueAt)
+	assert.Equal(suite.T(), models.SupportAuthorCustomer, message.Author)
+	assert.Equal(suite.T(), "Tôi muốn hoàn vé", message.Body)
+}
+
+func (suite *SupportTestSuite) TestOpenChosenOperator() {
+	ticket := &models.SupportTicket{Category: models.SupportCategoryLostItem, Subject: "Quên ví", ContactName: "Trần Thị B", ContactPhone: "0912345678", OperatorID: suite.rival.ID}
+	_, err := suite.support.Open(ticket, "", "Quên ví trên xe", nil, suite.now)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), suite.rival.ID, ticket.OperatorID)
+	assert.Nil(suite.T(), ticket.BookingID)
+	assert.Equal(suite.T(), suite.now.Add(24*time.Hour), ticket.ResolutionDueAt)
+}
+
+func (suite *SupportTestSuite) TestOpenErrors() {
+	cases := map[string]struct {
+		ticket  models.SupportTicket
+		booking string
+		body    string
+		err     error
+	}{
+		"RefundWithoutBooking": {models.SupportTicket{Category: models.SupportCategoryRefund, OperatorID: suite.own.ID}, "", "Hoàn tiền", services.ErrSupportBookingRequired},
+		"OtherCustomerBooking": {models.SupportTicket{Category: models.SupportCategoryComplaint}, suite.booking.BookingCode, "Khiếu nại", services.ErrSupportBookingMismatch},
+		"NoOperator":           {models.SupportTicket{Category: models.SupportCategoryOther}, "", "Hỏi đáp", services.ErrSupportOperatorRequired},
+		"EmptyMessage":         {models.SupportTicket{Category: models.SupportCategoryOther, OperatorID: suite.own.ID}, "", "   ", services.ErrEmptySupportMessage},
+	}
+	for name, tc := range cases {
+		suite.Run(name, func() {
+			ticket := tc.ticket
+			ticket.Subject = "Cần hỗ trợ"
+			ticket.ContactName = "Trần Thị B"
+			ticket.ContactPhone = "0912345678"
+			_, err := suite.support.Open(&ticket, tc.booking, tc.body, nil, suite.now)
+			assert.ErrorIs(suite.T(), err, tc.err)
+		})
+	}
+
+	ticket := models.SupportTicket{Category: models.SupportCategoryOther, Subject: "Cần hỗ trợ", ContactName: "Trần Thị B", ContactPhone: "0912345678"}
+	_, err := suite.support.Open(&ticket, "BK-UNKNOWN", "Hỏi đáp", nil, suite.now)
+	assert.ErrorIs(suite.T(), err, gorm.ErrRecordNotFound)
+}
+
+func (suite *SupportTestSuite) TestReplies() {
+	ticket := suite.open(suite.now)
+
+	note, err := suite.support.StaffReply(ticket, &suite.staff, "Kiểm tra lại với tài xế", true, nil, suite.now.Add(time.Hour))
+	require.NoError(suite.T(), err)
+	assert.True(suite.T(), note.Internal)
+	assert.Equal(suite.T(), models.SupportStatusOpen, ticket.Status, "internal notes do not answer the customer")
+	assert.Nil(suite.T(), ticket.FirstRespondedAt)
+
+	_, err = suite.support.StaffReply(ticket, &suite.staff, "Xin lỗi quý khách, chúng tôi đang kiểm tra", false, nil, suite.now.Add(5*time.Hour))
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.SupportStatusPending, ticket.Status)
+	require.NotNil(suite.T(), ticket.FirstRespondedAt)
+	assert.True(suite.T(), ticket.FirstResponseBreached, "answered after the 4 hour deadline")
+
+	_, err = suite.support.CustomerReply(ticket, "Cảm ơn", nil, suite.now.Add(6*time.Hour))
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.SupportStatusOpen, ticket.Status)
+	assert.Equal(suite.T(), suite.now.Add(6*time.Hour), ticket.LastMessageAt)
+
+	public, err := suite.support.Thread(ticket, false)
+	require.NoError(suite.T(), err)
+	assert.Len(suite.T(), public, 3)
+	for _, message := range public {
+		assert.False(suite.T(), message.Internal)
+	}
+	all, err := suite.support.Thread(ticket, true)
+	require.NoError(suite.T(), err)
+	assert.Len(suite.T(), all, 4)
+
+	_, err = suite.support.CustomerReply(ticket, "   ", nil, suite.now.Add(7*time.Hour))
+	assert.ErrorIs(suite.T(), err, services.ErrEmptySupportMessage)
+
+	require.NoError(suite.T(), suite.support.UpdateStatus(ticket, models.SupportStatusClosed, suite.now.Add(8*time.Hour)))
+	_, err = suite.support.CustomerReply(ticket, "Còn một việc nữa", nil, suite.now.Add(9*time.Hour))
+	assert.ErrorIs(suite.T(), err, services.ErrSupportTicketClosed)
+}
+
+func (suite *SupportTestSuite) TestStatus() {
+	ticket := suite.open(suite.now)
+	require.NoError(suite.T(), suite.support.UpdateStatus(ticket, models.SupportStatusResolved, suite.now.Add(time.Hour)))
+	assert.Equal(suite.T(), suite.now.Add(time.Hour), *ticket.ResolvedAt)
+	assert.False(suite.T(), ticket.ResolutionBreached)
+
+	// Replying to a resolved ticket reopens it
+	_, err := suite.support.CustomerReply(ticket, "Vẫn chưa nhận được hoàn tiền", nil, suite.now.Add(2*time.Hour))
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.SupportStatusOpen, ticket.Status)
+	assert.Nil(suite.T(), ticket.ResolvedAt)
+
+	require.NoError(suite.T(), suite.support.UpdateStatus(ticket, models.SupportStatusClosed, suite.now.Add(72*time.Hour)))
+	assert.True(suite.T(), ticket.ResolutionBreached, "closed unresolved after the 48 hour deadline")
+	assert.ErrorIs(suite.T(), suite.support.UpdateStatus(ticket, models.SupportStatusOpen, suite.now.Add(73*time.Hour)), services.ErrInvalidSupportStatus)
+
+	saved, err := repository.NewSupportRepository(suite.db).FindByID(ticket.ID)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.SupportStatusClosed, saved.Status)
+	assert.NotNil(suite.T(), saved.ClosedAt)
+}
+
+func (suite *SupportTestSuite) TestAssign() {
+	ticket := suite.open(time.Now())
+
+	require.NoError(suite.T(), suite.support.Assign(ticket, &suite.staff.ID))
+	assert.Equal(suite.T(), suite.staff.ID, *ticket.AssignedTo)
+
+	rivalStaff := models.User{Phone: "0933333333", Name: "Nhân viên Hoàng Long", Role: models.RoleStaff, OperatorID: &suite.rival.ID}
+	customer := models.User{Phone: "0944444444", Name: "Khách hàng", Role: models.RoleCustomer}
+	require.NoError(suite.T(), suite.db.Create(&rivalStaff).Error)
+	require.NoError(suite.T(), suite.db.Create(&customer).Error)
+	unknown := uint(999)
+	for _, id := range []uint{rivalStaff.ID, customer.ID, suite.driver.ID, unknown} {
+		assert.ErrorIs(suite.T(), suite.support.Assign(ticket, &id), services.ErrInvalidSupportAssignee)
+	}
+
+	require.NoError(suite.T(), suite.support.Assign(ticket, nil))
+	saved, err := repository.NewSupportRepository(suite.db).FindByID(ticket.ID)
+	require.NoError(suite.T(), err)
+	assert.Nil(suite.T(), saved.AssignedTo)
+}
+
+func (suite *SupportTestSuite) TestSLA() {
+	late := suite.open(suite.now)
+	answered := suite.open(suite.now)
+	_, err := suite.support.StaffReply(answered, &suite.staff, "Đã tiếp nhận", false, nil, suite.now.Add(time.Hour))
+	require.NoError(suite.T(), err)
+
+	breached, err := suite.support.CheckSLA(suite.now.Add(5 * time.Hour))
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), breached, 1)
+	assert.Equal(suite.T(), late.ID, breached[0].ID)
+	assert.True(suite.T(), breached[0].FirstResponseBreached)
+	assert.False(suite.T(), breached[0].ResolutionBreached)
+
+	// A breach is only reported once per deadline
+	breached, err = suite.support.CheckSLA(suite.now.Add(6 * time.Hour))
+	require.NoError(suite.T(), err)
+	assert.Empty(suite.T(), breached)
+
+	breached, err = suite.support.CheckSLA(suite.now.Add(49 * time.Hour))
+	require.NoError(suite.T(), err)
+	assert.Len(suite.T(), breached, 2)
+
+	flagged, _, err := repository.NewSupportRepository(suite.db).FindAll(repository.SupportFilter{Breached: true}, 1, 20)
+	require.NoError(suite.T(), err)
+	assert.Len(suite.T(), flagged, 2)
+}
+
+func (suite *SupportTestSuite) TestAutoClose() {
+	old := suite.open(suite.now)
+	recent := suite.open(suite.now)
+	require.NoError(suite.T(), suite.support.UpdateStatus(old, models.SupportStatusResolved, suite.now))
+	require.NoError(suite.T(), suite.support.UpdateStatus(recent, models.SupportStatusResolved, suite.now.Add(5*24*time.Hour)))
+
+	closed, err := suite.support.AutoClose(suite.now.Add(8 * 24 * time.Hour))
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(1), closed)
+
+	counts, err := repository.NewSupportRepository(suite.db).CountByStatus()
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(1), counts[models.SupportStatusClosed])
+	assert.Equal(suite.T(), int64(1), counts[models.SupportStatusResolved])
+}
+
+func (suite *SupportTestSuite) TestAttachments() {
+	ticket := suite.open(suite.now)
+
+	suite.Run("Saved", func() {
+		uploads := []services.SupportUpload{{Name: `C:\Users\a\"vé".png`, Content: bytes.NewReader(pngHeader)}}
+		message, err := suite.support.CustomerReply(ticket, "", uploads, suite.now)
+		require.NoError(suite.T(), err)
+		require.Len(suite.T(), message.Attachments, 1)
+
+		attachment := message.Attachments[0]
+		assert.Equal(suite.T(), "vé.png", attachment.FileName)
+		assert.Equal(suite.T(), "image/png", attachment.ContentType)
+		assert.Equal(suite.T(), int64(len(pngHeader)), attachment.Size)
+
+		found, content, err := suite.support.Attachment(ticket, attachment.ID, false)
+		require.NoError(suite.T(), err)
+		defer content.Close()
+		data, err := io.ReadAll(content)
+		require.NoError(suite.T(), err)
+		assert.Equal(suite.T(), pngHeader, data)
+		assert.Equal(suite.T(), attachment.StorageKey, found.StorageKey)
+	})
+
+	suite.Run("InternalHiddenFromCustomer", func() {
+		uploads := []services.SupportUpload{{Name: "ghi-chu.png", Content: bytes.NewReader(pngHeader)}}
+		message, err := suite.support.StaffReply(ticket, &suite.staff, "Ảnh camera", true, uploads, suite.now)
+		require.NoError(suite.T(), err)
+
+		_, _, err = suite.support.Attachment(ticket, message.Attachments[0].ID, false)
+		assert.ErrorIs(suite.T(), err, gorm.ErrRecordNotFound)
+		_, content, err := suite.support.Attachment(ticket, message.Attachments[0].ID, true)
+		require.NoError(suite.T(), err)
+		content.Close()
+	})
+
+	suite.Run("Rejected", func() {
+		_, err := suite.support.CustomerReply(ticket, "", []services.SupportUpload{{Name: "a.png", Content: strings.NewReader("không phải ảnh")}}, suite.now)
+		assert.ErrorIs(suite.T(), err, services.ErrAttachmentType)
+
+		large := append(append([]byte{}, pngHeader...), make([]byte, 10<<20)...)
+		_, err = suite.support.CustomerReply(ticket, "", []services.SupportUpload{{Name: "a.png", Content: bytes.NewReader(large)}}, suite.now)
+		assert.ErrorIs(suite.T(), err, services.ErrAttachmentTooLarge)
+
+		uploads := make([]services.SupportUpload, 6)
+		for i := range uploads {
+			uploads[i] = services.SupportUpload{Name: "a.png", Content: bytes.NewReader(pngHeader)}
+		}
+		_, err = suite.support.CustomerReply(ticket, "", uploads, suite.now)
+		assert.ErrorIs(suite.T(), err, services.ErrTooManyAttachments)
+	})
+
+	suite.Run("StoreKeys", func() {
+		assert.ErrorIs(suite.T(), suite.store.Save("../escape.png", bytes.NewReader(pngHeader)), providers.ErrInvalidAttachmentKey)
+		_, err := suite.store.Open("/etc/passwd")
+		assert.ErrorIs(suite.T(), err, providers.ErrInvalidAttachmentKey)
+	})
+}
+
+func (suite *SupportTestSuite) TestOperatorScope() {
+	ticket := suite.open(time.Now())
+
+	_, err := repository.NewSupportRepository(repository.WithOperator(suite.db, suite.rival.ID)).FindByID(ticket.ID)
+	assert.ErrorIs(suite.T(), err, gorm.ErrRecordNotFound)
+	found, err := repository.NewSupportRepository(repository.WithOperator(suite.db, suite.own.ID)).FindByID(ticket.ID)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), suite.booking.ID, found.Booking.ID)
+}
+
+func (suite *SupportTestSuite) TestNotifier() {
+	ticket := suite.open(time.Now())
+	broker := services.NewMemoryBroker(8)
+	events, unsubscribe := broker.Subscribe(services.SupportTopic(suite.own.ID))
+	defer unsubscribe()
+	sms := &fakeSMSSender{}
+	notifier := services.NewSupportNotifier(broker, sms)
+
+	next := func() services.SupportEvent {
+		var event services.SupportEvent
+		select {
+		case payload := <-events:
+			require.NoError(suite.T(), json.Unmarshal(payload, &event))
+		case <-time.After(time.Second):
+			suite.T().Fatal("no support event published")
+		}
+		return event
+	}
+
+	require.NoError(suite.T(), notifier.Replied(ticket, &models.SupportMessage{Author: models.SupportAuthorStaff, Body: "Ghi chú", Internal: true}))
+	assert.Empty(suite.T(), events)
+	assert.Empty(suite.T(), sms.to)
+
+	require.NoError(suite.T(), notifier.Replied(ticket, &models.SupportMessage{Author: models.SupportAuthorCustomer, Body: "Xin chào"}))
+	assert.Equal(suite.T(), services.SupportEventCustomerReplied, next().Reason)
+	assert.Empty(suite.T(), sms.to, "customers are not told about their own messages")
+
+	require.NoError(suite.T(), notifier.Replied(ticket, &models.SupportMessage{Author: models.SupportAuthorStaff, Body: strings.Repeat("a", 100)}))
+	event := next()
+	assert.Equal(suite.T(), services.SupportEventStaffReplied, event.Reason)
+	assert.Equal(suite.T(), ticket.Code, event.Code)
+	assert.Equal(suite.T(), "0987654321", sms.to)
+	assert.Contains(suite.T(), sms.body, ticket.Code)
+	assert.Contains(suite.T(), sms.body, strings.Repeat("a", 80)+"…")
+	assert.NotContains(suite.T(), sms.body, strings.Repeat("a", 81))
+
+	sms.to = ""
+	ticket.Status = models.SupportStatusPending
+	require.NoError(suite.T(), notifier.StatusChanged(ticket))
+	assert.Equal(suite.T(), models.SupportStatusPending, next().Status)
+	assert.Empty(suite.T(), sms.to)
+
+	ticket.Status = models.SupportStatusResolved
+	require.NoError(suite.T(), notifier.StatusChanged(ticket))
+	assert.Equal(suite.T(), services.SupportEventStatusChanged, next().Reason)
+	assert.Contains(suite.T(), sms.body, "đã được giải quyết")
+}
+
+func TestSupportTestSuite(t *testing.T) {
+	suite.Run(t, new(SupportTestSuite))
+}
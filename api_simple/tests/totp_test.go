@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"ticket-management/api_simple/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type TOTPTestSuite struct {
+	suite.Suite
+}
+
+// RFC 6238 appendix B test vectors (SHA1, 8 digit codes truncated to 6)
+func (suite *TOTPTestSuite) TestTOTPCodeAt() {
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ" // "12345678901234567890"
+
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+
+	for unix, expected := range vectors {
+		code, err := utils.TOTPCodeAt(secret, utils.TOTPStep(time.Unix(unix, 0)))
+		require.NoError(suite.T(), err)
+		assert.Equal(suite.T(), expected, code, "time %d", unix)
+	}
+}
+
+func (suite *TOTPTestSuite) TestValidateTOTP() {
+	secret, err := utils.GenerateTOTPSecret()
+	require.NoError(suite.T(), err)
+
+	now := time.Now()
+	current := utils.TOTPStep(now)
+
+	code, err := utils.TOTPCodeAt(secret, current)
+	require.NoError(suite.T(), err)
+	step, ok := utils.ValidateTOTP(secret, code, now)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), current, step)
+
+	// The previous step is accepted to tolerate clock drift
+	previous, err := utils.TOTPCodeAt(secret, current-1)
+	require.NoError(suite.T(), err)
+	step, ok = utils.ValidateTOTP(secret, previous, now)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), current-1, step)
+
+	// Codes outside the skew window are rejected
+	old, err := utils.TOTPCodeAt(secret, current-3)
+	require.NoError(suite.T(), err)
+	_, ok = utils.ValidateTOTP(secret, old, now)
+	assert.False(suite.T(), ok)
+
+	_, ok = utils.ValidateTOTP(secret, "12345", now)
+	assert.False(suite.T(), ok)
+}
+
+func (suite *TOTPTestSuite) TestTOTPProvisioningURI() {
+	uri := utils.TOTPProvisioningURI("JBSWY3DPEHPK3PXP", "0987654321", "Ticket Management")
+
+	assert.True(suite.T(), strings.HasPrefix(uri, "otpauth://totp/Ticket%20Management:0987654321?"))
+	assert.Contains(suite.T(), uri, "secret=JBSWY3DPEHPK3PXP")
+	assert.Contains(suite.T(), uri, "digits=6")
+	assert.Contains(suite.T(), uri, "period=30")
+}
+
+func TestTOTPTestSuite(t *testing.T) {
+	suite.Run(t, new(TOTPTestSuite))
+}
@@ -0,0 +1,174 @@
+package tests
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"ticket-management/api_simple/models"
+	"ticket-management/api_simple/repository"
+	"ticket-management/api_simple/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type TrackingTestSuite struct {
+	ServiceTestSuite
+	service *services.TrackingService
+	broker  *services.MemoryBroker
+}
+
+func (suite *TrackingTestSuite) SetupTest() {
+	suite.ServiceTestSuite.SetupTest()
+
+	originLat, originLng := 21.0285, 105.8542
+	destinationLat, destinationLng := 20.8449, 106.6881
+	suite.outbound.Distance = 120
+	suite.outbound.OriginLat, suite.outbound.OriginLng = &originLat, &originLng
+	suite.outbound.DestinationLat, suite.outbound.DestinationLng = &destinationLat, &destinationLng
+	require.NoError(suite.T(), suite.db.Save(&suite.outbound).Error)
+
+	suite.broker = services.NewMemoryBroker(64)
+	suite.service = services.NewTrackingService(
+		repository.NewVehiclePositionRepository(suite.db),
+		repository.NewTripRepository(suite.db),
+		repository.NewRoutePointRepository(suite.db),
+		suite.broker,
+		services.DefaultTrackingConfig(),
+	)
+}
+
+// loadTrack reads a recorded track: offset from departure in seconds, latitude, longitude, speed
+func (suite *TrackingTestSuite) loadTrack(path string, departure time.Time) []models.VehiclePosition {
+	file, err := os.Open(path)
+	require.NoError(suite.T(), err)
+	defer file.Close()
+
+	rows, err := csv.NewReader(file).ReadAll()
+	require.NoError(suite.T(), err)
+
+	positions := make([]models.VehiclePosition, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		offset, err := strconv.Atoi(row[0])
+		require.NoError(suite.T(), err)
+		lat, err := strconv.ParseFloat(row[1], 64)
+		require.NoError(suite.T(), err)
+		lng, err := strconv.ParseFloat(row[2], 64)
+		require.NoError(suite.T(), err)
+		speed, err := strconv.ParseFloat(row[3], 64)
+		require.NoError(suite.T(), err)
+
+		positions = append(positions, models.VehiclePosition{
+			Latitude:   lat,
+			Longitude:  lng,
+			SpeedKmh:   &speed,
+			RecordedAt: departure.Add(time.Duration(offset) * time.Second),
+		})
+	}
+	return positions
+}
+
+func (suite *TrackingTestSuite) TestReplay() {
+	departure := time.Date(2024, 3, 15, 1, 0, 0, 0, time.UTC)
+	trip := suite.createTrip(suite.outbound, departure)
+
+	tracking, err := suite.service.Snapshot(&trip, departure.Add(-30*time.Minute))
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), services.TrackingStatusScheduled, tracking.Status)
+	require.Len(suite.T(), tracking.Stops, 2)
+	assert.Equal(suite.T(), departure.Add(2*time.Hour), tracking.Stops[1].EstimatedAt)
+
+	events, unsubscribe := suite.broker.Subscribe(services.TrackingTopic(trip.ID))
+	defer unsubscribe()
+
+	track := suite.loadTrack("testdata/track_hanoi_haiphong.csv", departure)
+	delays := map[time.Duration]int{}
+	for _, point := range track {
+		tracking, err = suite.service.Ingest(suite.driver.ID, trip.ID, []models.VehiclePosition{point}, point.RecordedAt)
+		require.NoError(suite.T(), err)
+		delays[point.RecordedAt.Sub(departure)] = tracking.DelayMinutes
+
+		var published services.TripTracking
+		require.NoError(suite.T(), json.Unmarshal(<-events, &published))
+		assert.Equal(suite.T(), tracking.Status, published.Status)
+	}
+
+	// The 15 minute stop at the rest area pushes the arrival estimate back
+	assert.Greater(suite.T(), delays[75*time.Minute], delays[60*time.Minute])
+	// Before the stop the estimate already follows the bus being slower than planned
+	assert.InDelta(suite.T(), 10, delays[40*time.Minute], 6)
+
+	assert.Equal(suite.T(), services.TrackingStatusArrived, tracking.Status)
+	assert.True(suite.T(), tracking.Stops[0].Passed)
+	assert.True(suite.T(), tracking.Stops[1].Passed)
+	assert.Equal(suite.T(), departure.Add(130*time.Minute), tracking.Stops[1].EstimatedAt)
+	assert.Equal(suite.T(), 10, tracking.DelayMinutes)
+
+	stored, err := suite.service.Track(trip.ID, time.Time{})
+	require.NoError(suite.T(), err)
+	assert.Len(suite.T(), stored, len(track))
+}
+
+func (suite *TrackingTestSuite) TestIngestRules() {
+	departure := time.Date(2024, 3, 15, 1, 0, 0, 0, time.UTC)
+	trip := suite.createTrip(suite.outbound, departure)
+	point := models.VehiclePosition{Latitude: 21.02, Longitude: 105.86, RecordedAt: departure}
+
+	_, err := suite.service.Ingest(suite.driver.ID+100, trip.ID, []models.VehiclePosition{point}, departure)
+	assert.ErrorIs(suite.T(), err, services.ErrTripNotAssigned)
+
+	_, err = suite.service.Ingest(suite.driver.ID, trip.ID, []models.VehiclePosition{point}, departure.Add(-2*time.Hour))
+	assert.ErrorIs(suite.T(), err, services.ErrTripNotTracking)
+
+	future := point
+	future.RecordedAt = departure.Add(time.Hour)
+	_, err = suite.service.Ingest(suite.driver.ID, trip.ID, []models.VehiclePosition{future}, departure)
+	assert.ErrorIs(suite.T(), err, services.ErrPositionOutOfRange)
+
+	invalid := point
+	invalid.Latitude = 120
+	_, err = suite.service.Ingest(suite.driver.ID, trip.ID, []models.VehiclePosition{invalid}, departure)
+	assert.ErrorIs(suite.T(), err, models.ErrInvalidPosition)
+
+	require.NoError(suite.T(), suite.db.Model(&trip).Update("is_completed", true).Error)
+	_, err = suite.service.Ingest(suite.driver.ID, trip.ID, []models.VehiclePosition{point}, departure)
+	assert.ErrorIs(suite.T(), err, services.ErrTripNotTracking)
+}
+
+func (suite *TrackingTestSuite) TestStopsIncludeRoutePoints() {
+	departure := time.Date(2024, 3, 15, 1, 0, 0, 0, time.UTC)
+	trip := suite.createTrip(suite.outbound, departure)
+
+	// A roadside pickup about a quarter of the way and a drop-off without coordinates
+	lat, lng := 20.98, 106.06
+	pickup := models.RoutePoint{RouteID: suite.outbound.ID, Type: models.RoutePointPickup, Name: "Cầu Thanh Trì", Latitude: &lat, Longitude: &lng, OffsetMinutes: 30, IsActive: true}
+	dropoff := models.RoutePoint{RouteID: suite.outbound.ID, Type: models.RoutePointDropoff, Name: "Big C Hải Phòng", OffsetMinutes: 105, IsActive: true}
+	require.NoError(suite.T(), suite.db.Create(&pickup).Error)
+	require.NoError(suite.T(), suite.db.Create(&dropoff).Error)
+
+	tracking, err := suite.service.Snapshot(&trip, departure.Add(-30*time.Minute))
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), tracking.Stops, 4)
+	assert.Equal(suite.T(), "Cầu Thanh Trì", tracking.Stops[1].Name)
+	assert.Equal(suite.T(), pickup.ID, *tracking.Stops[1].PointID)
+	assert.Equal(suite.T(), departure.Add(30*time.Minute), tracking.Stops[1].ScheduledAt)
+	assert.Equal(suite.T(), models.RoutePointDropoff, tracking.Stops[2].Type)
+
+	// Past the pickup point the bus is only waited for at the drop-off and the destination
+	position := models.VehiclePosition{Latitude: 20.93, Longitude: 106.30, RecordedAt: departure.Add(50 * time.Minute)}
+	tracking, err = suite.service.Ingest(suite.driver.ID, trip.ID, []models.VehiclePosition{position}, position.RecordedAt)
+	require.NoError(suite.T(), err)
+	assert.True(suite.T(), tracking.Stops[1].Passed)
+	assert.False(suite.T(), tracking.Stops[2].Passed)
+	require.NotNil(suite.T(), tracking.Stops[2].RemainingKm)
+	assert.Less(suite.T(), *tracking.Stops[2].RemainingKm, *tracking.Stops[3].RemainingKm)
+}
+
+func TestTrackingTestSuite(t *testing.T) {
+	suite.Run(t, new(TrackingTestSuite))
+}
@@ -0,0 +1,151 @@
+package utils
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCron is returned for a schedule that is not a valid cron expression
+var ErrInvalidCron = errors.New("invalid cron expression")
+
+// cronMacros are the shorthand schedules accepted in place of the five fields
+var cronMacros = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 1",
+	"@monthly": "0 0 1 * *",
+}
+
+// Cron is a parsed five-field cron expression: minute, hour, day of month, month and
+// day of week (0-7, Sunday being 0 or 7). Fields accept *, lists, ranges and steps.
+type Cron struct {
+	minute, hour, day, month, weekday uint64 // Bit i is set when value i matches
+	anyDay, anyWeekday                bool
+}
+
+// ParseCron parses a cron expression such as "0 8 * * 1" (Mondays at 08:00)
+func ParseCron(expr string) (*Cron, error) {
+	expr = strings.TrimSpace(expr)
+	if macro, ok := cronMacros[expr]; ok {
+		expr = macro
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, ErrInvalidCron
+	}
+
+	c := &Cron{anyDay: fields[2] == "*", anyWeekday: fields[4] == "*"}
+	var err error
+	if c.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, err
+	}
+	if c.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, err
+	}
+	if c.day, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, err
+	}
+	if c.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, err
+	}
+	if c.weekday, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, err
+	}
+	if c.weekday&(1<<7) != 0 {
+		c.weekday |= 1
+	}
+	return c, nil
+}
+
+// parseCronField parses one field into a bit set of the values it matches
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step < 1 {
+				return 0, ErrInvalidCron
+			}
+			part = part[:i]
+		}
+
+		from, to := min, max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			var err1, err2 error
+			from, err1 = strconv.Atoi(bounds[0])
+			to, err2 = strconv.Atoi(bounds[1])
+			if err1 != nil || err2 != nil {
+				return 0, ErrInvalidCron
+			}
+		default:
+			value, err := strconv.Atoi(part)
+			if err != nil {
+				return 0, ErrInvalidCron
+			}
+			from = value
+			// "5/15" runs from 5 to the end of the range
+			if step == 1 {
+				to = value
+			}
+		}
+		if from < min || to > max || from > to {
+			return 0, ErrInvalidCron
+		}
+
+		for v := from; v <= to; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+// Next returns the first time after t the schedule fires, in the location of t, or the
+// zero time when it never fires (such as on February 30)
+func (c *Cron) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	// Every schedule that can fire does so within the next leap-year cycle
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// matchesDay checks the day of month and day of week of t. As in cron, when both are
+// restricted a day matching either one fires.
+func (c *Cron) matchesDay(t time.Time) bool {
+	day := c.day&(1<<uint(t.Day())) != 0
+	weekday := c.weekday&(1<<uint(t.Weekday())) != 0
+	switch {
+	case c.anyDay && c.anyWeekday:
+		return true
+	case c.anyDay:
+		return weekday
+	case c.anyWeekday:
+		return day
+	}
+	return day || weekday
+}
@@ -0,0 +1,22 @@
+package utils
+
+import "math"
+
+// EarthRadiusKm is the mean radius of the Earth
+const EarthRadiusKm = 6371.0
+
+// HaversineKm returns the great-circle distance in kilometres between two coordinates
+func HaversineKm(lat1, lng1, lat2, lng2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(lat2 - lat1)
+	dLng := toRad(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * EarthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+// ValidCoordinates checks that a latitude and longitude are within range
+func ValidCoordinates(lat, lng float64) bool {
+	return lat >= -90 && lat <= 90 && lng >= -180 && lng <= 180
+}
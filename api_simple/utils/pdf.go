@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// PDFText is a line of text placed on a page. Coordinates are in points from the
+// bottom-left corner of an A4 page (595 x 842).
+type PDFText struct {
+	X, Y float64
+	Size float64
+	Bold bool
+	Text string
+}
+
+// PDFRule is a straight line drawn on a page
+type PDFRule struct {
+	X1, Y1, X2, Y2 float64
+}
+
+// PDFPage is the content of a single A4 page
+type PDFPage struct {
+	Texts []PDFText
+	Rules []PDFRule
+}
+
+// RenderPDF writes a one-page PDF using the standard Helvetica fonts. Those fonts
+// have no Vietnamese glyphs, so tone marks are removed from the text.
+func RenderPDF(page PDFPage) []byte {
+	var content bytes.Buffer
+	for _, rule := range page.Rules {
+		fmt.Fprintf(&content, "%.2f %.2f m %.2f %.2f l S\n", rule.X1, rule.Y1, rule.X2, rule.Y2)
+	}
+	for _, text := range page.Texts {
+		font := "F1"
+		if text.Bold {
+			font = "F2"
+		}
+		fmt.Fprintf(&content, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n",
+			font, text.Size, text.X, text.Y, escapePDFString(RemoveDiacritics(text.Text)))
+	}
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Resources << /Font << /F1 4 0 R /F2 5 0 R >> >> /Contents 6 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+	}
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return out.Bytes()
+}
+
+// escapePDFString escapes a string literal and drops characters outside Latin-1
+func escapePDFString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 32:
+			b.WriteByte(' ')
+		case r < 128:
+			b.WriteRune(r)
+		case r < 256:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
@@ -14,3 +14,11 @@ func NormalizePhone(phone string) string {
 	// Otherwise assume it's missing +84
 	return "+84" + phone
 }
+
+// MaskPhone hides the middle digits of a phone number, e.g. 0987654321 -> 0987***321
+func MaskPhone(phone string) string {
+	if len(phone) < 7 {
+		return phone
+	}
+	return phone[:4] + strings.Repeat("*", len(phone)-7) + phone[len(phone)-3:]
+}
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+)
+
+// TableWriter streams the rows of an export after its header
+type TableWriter interface {
+	WriteRow(cells []interface{}) error
+	Close() error
+}
+
+// CSVWriter streams rows as CSV, each cell written with fmt.Sprint
+type CSVWriter struct {
+	writer *csv.Writer
+	record []string
+}
+
+// NewCSVWriter starts a CSV export on w and writes the header row
+func NewCSVWriter(w io.Writer, header []string) (*CSVWriter, error) {
+	c := &CSVWriter{writer: csv.NewWriter(w)}
+	if err := c.writer.Write(header); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// WriteRow appends a data row
+func (c *CSVWriter) WriteRow(cells []interface{}) error {
+	c.record = c.record[:0]
+	for _, cell := range cells {
+		c.record = append(c.record, fmt.Sprint(cell))
+	}
+	return c.writer.Write(c.record)
+}
+
+// Close flushes the buffered rows. It does not close the underlying writer.
+func (c *CSVWriter) Close() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
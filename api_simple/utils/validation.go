@@ -31,6 +31,10 @@ const (
 	ErrTokenRequired        = "Yêu cầu xác thực token"
 	ErrOTPInvalid           = "Mã OTP không chính xác"
 	ErrOTPExpired           = "Mã OTP đã hết hạn"
+	ErrOTPLocked            = "Nhập sai OTP quá nhiều lần, vui lòng thử lại sau"
+	ErrOTPCooldown          = "Vui lòng chờ trước khi yêu cầu mã OTP mới"
+	ErrLoginLocked          = "Tài khoản tạm thời bị khóa do đăng nhập sai nhiều lần, vui lòng xác minh OTP để mở khóa"
+	ErrLoginBackoff         = "Đăng nhập sai nhiều lần, vui lòng thử lại sau"
 	ErrServerError          = "Có lỗi xảy ra, vui lòng thử lại sau"
 )
 
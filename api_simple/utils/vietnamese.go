@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+var (
+	digitWords = []string{"không", "một", "hai", "ba", "bốn", "năm", "sáu", "bảy", "tám", "chín"}
+	groupWords = []string{"", "nghìn", "triệu"}
+
+	diacriticBases = map[rune]string{
+		'a': "àáảãạăằắẳẵặâầấẩẫậ",
+		'e': "èéẻẽẹêềếểễệ",
+		'i': "ìíỉĩị",
+		'o': "òóỏõọôồốổỗộơờớởỡợ",
+		'u': "ùúủũụưừứửữự",
+		'y': "ỳýỷỹỵ",
+		'd': "đ",
+	}
+	diacriticMap = buildDiacriticMap()
+)
+
+func buildDiacriticMap() map[rune]rune {
+	m := make(map[rune]rune)
+	for base, accented := range diacriticBases {
+		for _, r := range accented {
+			m[r] = base
+			m[unicode.ToUpper(r)] = unicode.ToUpper(base)
+		}
+	}
+	return m
+}
+
+// RemoveDiacritics strips Vietnamese tone marks, e.g. "Hóa đơn" -> "Hoa don"
+func RemoveDiacritics(s string) string {
+	return strings.Map(func(r rune) rune {
+		if base, ok := diacriticMap[r]; ok {
+			return base
+		}
+		return r
+	}, s)
+}
+
+// NumberToVietnameseWords spells out an integer in Vietnamese, e.g.
+// 1250000 -> "một triệu hai trăm năm mươi nghìn"
+func NumberToVietnameseWords(n int64) string {
+	if n == 0 {
+		return digitWords[0]
+	}
+	if n < 0 {
+		return "âm " + NumberToVietnameseWords(-n)
+	}
+
+	var groups []int64
+	for n > 0 {
+		groups = append(groups, n%1000)
+		n /= 1000
+	}
+
+	var words []string
+	for i := len(groups) - 1; i >= 0; i-- {
+		if groups[i] == 0 {
+			continue
+		}
+		words = append(words, readThreeDigits(groups[i], i < len(groups)-1)...)
+		// Past tỷ the group names repeat: nghìn tỷ, triệu tỷ, tỷ tỷ
+		if name := groupWords[i%3]; name != "" {
+			words = append(words, name)
+		}
+		for j := 0; j < i/3; j++ {
+			words = append(words, "tỷ")
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// readThreeDigits reads a group of three digits; full groups after the leading one
+// keep their hundreds ("không trăm") and "linh" before a lone unit
+func readThreeDigits(n int64, full bool) []string {
+	hundreds, tens, units := n/100, n/10%10, n%10
+	var words []string
+
+	if hundreds > 0 || full {
+		words = append(words, digitWords[hundreds], "trăm")
+	}
+
+	switch {
+	case tens == 0 && units > 0 && len(words) > 0:
+		words = append(words, "linh")
+	case tens == 1:
+		words = append(words, "mười")
+	case tens > 1:
+		words = append(words, digitWords[tens], "mươi")
+	}
+
+	switch {
+	case units == 0:
+	case units == 1 && tens > 1:
+		words = append(words, "mốt")
+	case units == 5 && tens > 0:
+		words = append(words, "lăm")
+	default:
+		words = append(words, digitWords[units])
+	}
+	return words
+}
@@ -0,0 +1,346 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidXLSX is returned when a file is not a readable workbook
+var ErrInvalidXLSX = errors.New("invalid xlsx file")
+
+// RenderXLSX writes a workbook with a single sheet. The first row is written in bold as
+// the header. Cells holding int, int64 or float64 values are written as numbers, any
+// other value as text.
+func RenderXLSX(sheet string, header []string, rows [][]interface{}) ([]byte, error) {
+	var out bytes.Buffer
+	w, err := NewXLSXWriter(&out, sheet, header)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		if err := w.WriteRow(row); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// XLSXWriter streams a single-sheet workbook to a writer one row at a time, so large
+// exports are never held in memory. Cells are written as in RenderXLSX.
+type XLSXWriter struct {
+	archive *zip.Writer
+	sheet   io.Writer
+	row     bytes.Buffer
+	rows    int
+}
+
+// NewXLSXWriter starts a workbook on w and writes the header row
+func NewXLSXWriter(w io.Writer, sheet string, header []string) (*XLSXWriter, error) {
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+			`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+			`</Types>`},
+		{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="` + escapeXML(sheetName(sheet)) + `" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+			`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+			`</Relationships>`},
+		{"xl/styles.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+			`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+			`<fills count="1"><fill><patternFill patternType="none"/></fill></fills>` +
+			`<borders count="1"><border/></borders>` +
+			`<cellStyleXfs count="1"><xf/></cellStyleXfs>` +
+			`<cellXfs count="2"><xf fontId="0"/><xf fontId="1" applyFont="1"/></cellXfs>` +
+			`</styleSheet>`},
+	}
+
+	x := &XLSXWriter{archive: zip.NewWriter(w)}
+	for _, part := range parts {
+		pw, err := x.archive.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(pw, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	// The sheet is the last part, so its rows can be written as they come
+	var err error
+	if x.sheet, err = x.archive.Create("xl/worksheets/sheet1.xml"); err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(x.sheet, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`+
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`); err != nil {
+		return nil, err
+	}
+
+	headerCells := make([]interface{}, len(header))
+	for i, title := range header {
+		headerCells[i] = title
+	}
+	if err := x.writeRow(headerCells, true); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// WriteRow appends a data row to the sheet
+func (x *XLSXWriter) WriteRow(cells []interface{}) error {
+	return x.writeRow(cells, false)
+}
+
+func (x *XLSXWriter) writeRow(cells []interface{}, bold bool) error {
+	x.rows++
+	x.row.Reset()
+	writeXLSXRow(&x.row, x.rows, cells, bold)
+	_, err := x.sheet.Write(x.row.Bytes())
+	return err
+}
+
+// Close ends the sheet and the workbook. It does not close the underlying writer.
+func (x *XLSXWriter) Close() error {
+	if _, err := io.WriteString(x.sheet, `</sheetData></worksheet>`); err != nil {
+		return err
+	}
+	return x.archive.Close()
+}
+
+func writeXLSXRow(b *bytes.Buffer, number int, cells []interface{}, bold bool) {
+	style := ""
+	if bold {
+		style = ` s="1"`
+	}
+	fmt.Fprintf(b, `<row r="%d">`, number)
+	for i, cell := range cells {
+		ref := xlsxColumn(i) + strconv.Itoa(number)
+		switch v := cell.(type) {
+		case int:
+			fmt.Fprintf(b, `<c r="%s"%s><v>%d</v></c>`, ref, style, v)
+		case int64:
+			fmt.Fprintf(b, `<c r="%s"%s><v>%d</v></c>`, ref, style, v)
+		case float64:
+			fmt.Fprintf(b, `<c r="%s"%s><v>%s</v></c>`, ref, style, strconv.FormatFloat(v, 'f', -1, 64))
+		default:
+			fmt.Fprintf(b, `<c r="%s"%s t="inlineStr"><is><t>%s</t></is></c>`, ref, style, escapeXML(fmt.Sprint(v)))
+		}
+	}
+	b.WriteString(`</row>`)
+}
+
+// xlsxColumn returns the column letters of a zero-based column index (0 → A, 26 → AA)
+func xlsxColumn(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+// sheetName trims a sheet name to what spreadsheet applications accept
+func sheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '-'
+		}
+		return r
+	}, name)
+	if name == "" {
+		return "Sheet1"
+	}
+	if runes := []rune(name); len(runes) > 31 {
+		name = string(runes[:31])
+	}
+	return name
+}
+
+func escapeXML(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// ReadXLSX returns the rows of the first sheet of a workbook as text. Cells are placed by
+// their reference, so empty cells in the middle of a row come back as "", and rows by
+// their number, so rows[i] is row i+1 of the sheet even when blank rows are left out of
+// the file. Numbers and dates are returned as stored, dates being serial day numbers.
+func ReadXLSX(data []byte) ([][]string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, ErrInvalidXLSX
+	}
+	files := make(map[string]*zip.File)
+	for _, f := range archive.File {
+		files[f.Name] = f
+	}
+
+	var shared []string
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		var sst struct {
+			Items []xlsxText `xml:"si"`
+		}
+		if err := readXLSXPart(f, &sst); err != nil {
+			return nil, err
+		}
+		for _, item := range sst.Items {
+			shared = append(shared, item.String())
+		}
+	}
+
+	f, ok := files[firstSheetPath(files)]
+	if !ok {
+		return nil, ErrInvalidXLSX
+	}
+	var sheet struct {
+		Rows []struct {
+			Ref   int `xml:"r,attr"`
+			Cells []struct {
+				Ref    string   `xml:"r,attr"`
+				Type   string   `xml:"t,attr"`
+				Value  string   `xml:"v"`
+				Inline xlsxText `xml:"is"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := readXLSXPart(f, &sheet); err != nil {
+		return nil, err
+	}
+
+	rows := make([][]string, 0, len(sheet.Rows))
+	for _, row := range sheet.Rows {
+		for row.Ref > len(rows)+1 {
+			rows = append(rows, nil)
+		}
+		var values []string
+		for _, cell := range row.Cells {
+			index := len(values)
+			if column := xlsxColumnIndex(cell.Ref); column >= 0 {
+				index = column
+			}
+			for len(values) <= index {
+				values = append(values, "")
+			}
+
+			switch cell.Type {
+			case "s":
+				i, err := strconv.Atoi(cell.Value)
+				if err != nil || i < 0 || i >= len(shared) {
+					return nil, ErrInvalidXLSX
+				}
+				values[index] = shared[i]
+			case "inlineStr":
+				values[index] = cell.Inline.String()
+			default:
+				values[index] = cell.Value
+			}
+		}
+		rows = append(rows, values)
+	}
+	return rows, nil
+}
+
+// xlsxText is a string item made of plain text or formatted runs
+type xlsxText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var b strings.Builder
+	for _, run := range t.Runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+// firstSheetPath returns the part holding the first sheet listed in the workbook
+func firstSheetPath(files map[string]*zip.File) string {
+	fallback := "xl/worksheets/sheet1.xml"
+	workbook, ok := files["xl/workbook.xml"]
+	rels, relsOK := files["xl/_rels/workbook.xml.rels"]
+	if !ok || !relsOK {
+		return fallback
+	}
+
+	var book struct {
+		Sheets []struct {
+			ID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	var relationships struct {
+		Items []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if readXLSXPart(workbook, &book) != nil || readXLSXPart(rels, &relationships) != nil || len(book.Sheets) == 0 {
+		return fallback
+	}
+	for _, rel := range relationships.Items {
+		if rel.ID != book.Sheets[0].ID {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/")
+		}
+		return path.Join("xl", rel.Target)
+	}
+	return fallback
+}
+
+func readXLSXPart(f *zip.File, v interface{}) error {
+	r, err := f.Open()
+	if err != nil {
+		return ErrInvalidXLSX
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return ErrInvalidXLSX
+	}
+	if err := xml.Unmarshal(data, v); err != nil {
+		return ErrInvalidXLSX
+	}
+	return nil
+}
+
+// xlsxColumnIndex returns the zero-based column of a cell reference (A1 → 0, AA3 → 26)
+func xlsxColumnIndex(ref string) int {
+	index := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		index = index*26 + int(r-'A') + 1
+	}
+	return index - 1
+}